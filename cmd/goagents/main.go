@@ -0,0 +1,120 @@
+// Command goagents runs the goagents server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/runtime"
+	"github.com/goagents/goagents/pkg/server"
+	"github.com/goagents/goagents/pkg/service"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// serviceName is the name the server is installed under as a Windows
+// service.
+const serviceName = "goagents"
+
+// Set when the binary is built for a release.
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	
+	var err error
+	switch os.Args[1] {
+	case "run":
+		err = run(os.Args[2:])
+	case "version":
+		fmt.Printf("goagents %s (commit %s, built %s)\n", version, commit, date)
+	case "help", "-h", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "goagents: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "goagents: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `Usage: goagents <command> [flags]
+
+Commands:
+  run      Run the server
+  version  Print the version`)
+}
+
+// run starts the server and serves until it is told to stop by a signal,
+// systemd or the Windows service control manager.
+func run(args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	configPath := flags.String("config", "", "path to the config file")
+	clusterPath := flags.String("cluster", "", "path to a cluster file to deploy at startup")
+	flags.Parse(args)
+	
+	loader := config.NewLoader()
+	cfg, err := loader.LoadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	var cluster *config.AgentCluster
+	if *clusterPath != "" {
+		cluster, err = loader.LoadAgentCluster(*clusterPath)
+		if err != nil {
+			return err
+		}
+	}
+	
+	logger, err := newLogger(cfg.Server.LogLevel)
+	if err != nil {
+		return err
+	}
+	defer logger.Sync()
+	
+	return service.Run(serviceName, func(ctx context.Context) error {
+		engine, err := runtime.NewEngine(cfg, logger)
+		if err != nil {
+			return fmt.Errorf("failed to start engine: %w", err)
+		}
+		defer engine.Close()
+		
+		if cluster != nil {
+			if err := engine.DeployCluster(cluster); err != nil {
+				return fmt.Errorf("failed to deploy cluster %s: %w", *clusterPath, err)
+			}
+		}
+		
+		err = server.NewServer(cfg, engine, logger).Start(ctx)
+		if err != nil {
+			logger.Error("Server stopped", zap.Error(err))
+		}
+		return err
+	})
+}
+
+func newLogger(level string) (*zap.Logger, error) {
+	zapConfig := zap.NewProductionConfig()
+	if level != "" {
+		parsed, err := zapcore.ParseLevel(level)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log level: %w", err)
+		}
+		zapConfig.Level = zap.NewAtomicLevelAt(parsed)
+	}
+	return zapConfig.Build()
+}
\ No newline at end of file
@@ -0,0 +1,28 @@
+// Command terraform-provider-goagents is the goagents Terraform provider.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/goagents/goagents/pkg/terraform"
+	"github.com/hashicorp/terraform-plugin-framework/providerserver"
+)
+
+// version is set when the provider is built for a release.
+var version = "dev"
+
+func main() {
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "run the provider for a debugger to attach to")
+	flag.Parse()
+	
+	err := providerserver.Serve(context.Background(), terraform.New(version), providerserver.ServeOpts{
+		Address: "registry.terraform.io/goagents/goagents",
+		Debug:   debug,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+}
\ No newline at end of file
@@ -13,7 +13,7 @@ type Manager struct {
 	agents    map[string]*Agent
 	mu        sync.RWMutex
 	logger    *zap.Logger
-	events    chan Event
+	publish   func(Event)
 	idleTimer *time.Timer
 }
 
@@ -21,10 +21,16 @@ func NewManager(logger *zap.Logger) *Manager {
 	return &Manager{
 		agents: make(map[string]*Agent),
 		logger: logger,
-		events: make(chan Event, 100),
 	}
 }
 
+// SetEventPublisher sends the manager's events to publish, such as an
+// event bus. Set it before the manager is used; without it events are
+// discarded.
+func (m *Manager) SetEventPublisher(publish func(Event)) {
+	m.publish = publish
+}
+
 func (m *Manager) CreateAgent(config *AgentConfig) (*Agent, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -75,7 +81,7 @@ func (m *Manager) StartAgent(agentID string) error {
 		AgentID:   agentID,
 		Timestamp: time.Now(),
 		Data: map[string]interface{}{
-			"name": agent.Name,
+			"name": agent.GetName(),
 		},
 	})
 	
@@ -90,13 +96,16 @@ func (m *Manager) StopAgent(agentID string) error {
 		return fmt.Errorf("agent not found: %s", agentID)
 	}
 	
+	// The agent's own goroutine updates its status too
+	agent.mu.Lock()
 	if agent.Status == StatusStopped || agent.Status == StatusStopping {
+		agent.mu.Unlock()
 		m.mu.Unlock()
 		return nil
 	}
-	
 	agent.Status = StatusStopping
 	agent.UpdatedAt = time.Now()
+	agent.mu.Unlock()
 	m.mu.Unlock()
 	
 	agent.cancel()
@@ -110,6 +119,66 @@ func (m *Manager) StopAgent(agentID string) error {
 	return nil
 }
 
+// MarkDegraded flags an agent that is running but not behaving as expected,
+// e.g. because its deploy-time smoke tests failed.
+func (m *Manager) MarkDegraded(agentID, reason string) error {
+	m.mu.Lock()
+	agent, exists := m.agents[agentID]
+	if !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+	
+	agent.mu.Lock()
+	agent.Status = StatusDegraded
+	agent.ErrorMessage = reason
+	agent.UpdatedAt = time.Now()
+	agent.mu.Unlock()
+	m.mu.Unlock()
+	
+	m.publishEvent(Event{
+		Type:      EventAgentDegraded,
+		AgentID:   agentID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"name":   agent.GetName(),
+			"reason": reason,
+		},
+	})
+	
+	return nil
+}
+
+// MarkFailed flags an agent that can no longer serve requests, e.g.
+// because its provider calls keep failing.
+func (m *Manager) MarkFailed(agentID, reason string) error {
+	m.mu.Lock()
+	agent, exists := m.agents[agentID]
+	if !exists {
+		m.mu.Unlock()
+		return fmt.Errorf("agent not found: %s", agentID)
+	}
+	
+	agent.mu.Lock()
+	agent.Status = StatusFailed
+	agent.ErrorMessage = reason
+	agent.UpdatedAt = time.Now()
+	agent.mu.Unlock()
+	m.mu.Unlock()
+	
+	m.publishEvent(Event{
+		Type:      EventAgentFailed,
+		AgentID:   agentID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"name":   agent.GetName(),
+			"reason": reason,
+		},
+	})
+	
+	return nil
+}
+
 func (m *Manager) GetAgent(agentID string) (*Agent, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
@@ -143,7 +212,7 @@ func (m *Manager) DeleteAgent(agentID string) error {
 		return fmt.Errorf("agent not found: %s", agentID)
 	}
 	
-	if agent.Status == StatusRunning {
+	if agent.GetStatus() == StatusRunning {
 		agent.cancel()
 	}
 	
@@ -197,7 +266,7 @@ ready:
 	
 	resp := &Response{
 		ID:      req.ID,
-		Content: "Mock response from agent " + agent.Name,
+		Content: "Mock response from agent " + agent.GetName(),
 	}
 	
 	agent.mu.Lock()
@@ -219,7 +288,7 @@ ready:
 }
 
 func (m *Manager) runAgent(agent *Agent) {
-	m.logger.Info("Starting agent", zap.String("id", agent.ID), zap.String("name", agent.Name))
+	m.logger.Info("Starting agent", zap.String("id", agent.ID), zap.String("name", agent.GetName()))
 	
 	agent.mu.Lock()
 	agent.Status = StatusRunning
@@ -227,7 +296,9 @@ func (m *Manager) runAgent(agent *Agent) {
 	agent.mu.Unlock()
 	
 	idleTimeout := 5 * time.Minute
-	if agent.Config.Resources.Timeout > 0 {
+	if agent.Config.Resources.IdleTimeout > 0 {
+		idleTimeout = agent.Config.Resources.IdleTimeout
+	} else if agent.Config.Resources.Timeout > 0 {
 		idleTimeout = agent.Config.Resources.Timeout
 	}
 	
@@ -247,9 +318,11 @@ func (m *Manager) runAgent(agent *Agent) {
 		case <-idleTimer.C:
 			agent.mu.Lock()
 			lastActivity := agent.LastActivity
+			// Failed and degraded agents keep their status
+			running := agent.Status == StatusRunning
 			agent.mu.Unlock()
 			
-			if time.Since(lastActivity) >= idleTimeout {
+			if running && time.Since(lastActivity) >= idleTimeout {
 				m.logger.Info("Agent going idle", zap.String("id", agent.ID))
 				agent.mu.Lock()
 				agent.Status = StatusIdle
@@ -269,17 +342,11 @@ func (m *Manager) runAgent(agent *Agent) {
 }
 
 func (m *Manager) publishEvent(event Event) {
-	select {
-	case m.events <- event:
-	default:
-		m.logger.Warn("Event channel full, dropping event", zap.String("type", string(event.Type)))
+	if m.publish != nil {
+		m.publish(event)
 	}
 }
 
-func (m *Manager) Events() <-chan Event {
-	return m.events
-}
-
 func generateAgentID() string {
 	return fmt.Sprintf("agent-%d", time.Now().UnixNano())
 }
@@ -290,6 +357,43 @@ func (a *Agent) GetStatus() Status {
 	return a.Status
 }
 
+// GetName returns the agent's name, which changes when it is renamed.
+func (a *Agent) GetName() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Name
+}
+
+// SetName renames the agent.
+func (a *Agent) SetName(name string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Name = name
+	a.UpdatedAt = time.Now()
+}
+
+// GetErrorMessage says why the agent failed or is degraded.
+func (a *Agent) GetErrorMessage() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.ErrorMessage
+}
+
+// ToolDefinitions returns the tools the agent advertises to the model.
+func (a *Agent) ToolDefinitions() []ToolDefinition {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.Config.ToolDefinitions
+}
+
+// SetToolDefinitions replaces the tools the agent advertises, such as when
+// a tool is added while it runs.
+func (a *Agent) SetToolDefinitions(definitions []ToolDefinition) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.Config.ToolDefinitions = definitions
+}
+
 func (a *Agent) GetMetrics() *AgentMetrics {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
@@ -302,4 +406,16 @@ func (a *Agent) UpdateLastActivity() {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 	a.LastActivity = time.Now()
+}
+// Wake marks an idle agent as running again and reports whether it was idle.
+func (a *Agent) Wake() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	
+	if a.Status != StatusIdle {
+		return false
+	}
+	a.Status = StatusRunning
+	a.UpdatedAt = time.Now()
+	return true
 }
\ No newline at end of file
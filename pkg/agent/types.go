@@ -2,8 +2,15 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
+	"text/template"
 	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/guardrails"
+	"github.com/goagents/goagents/pkg/providers"
+	"github.com/goagents/goagents/pkg/tools"
 )
 
 type Status string
@@ -16,12 +23,14 @@ const (
 	StatusStopping   Status = "stopping"
 	StatusStopped    Status = "stopped"
 	StatusFailed     Status = "failed"
+	StatusDegraded   Status = "degraded"
 )
 
 type Agent struct {
 	ID           string
 	Name         string
 	ClusterName  string
+	Version      int
 	Config       *AgentConfig
 	Status       Status
 	CreatedAt    time.Time
@@ -36,13 +45,113 @@ type Agent struct {
 }
 
 type AgentConfig struct {
-	Provider     string
-	Model        string
-	SystemPrompt string
+	Provider       string
+	Model          string
+	SystemPrompt   string
+	// PromptLayers are the parts SystemPrompt was composed from
+	PromptLayers   []PromptLayer
+	// PromptTemplate is SystemPrompt parsed, when it uses variables, and is
+	// rendered for each request
+	PromptTemplate  *template.Template
+	PromptVariables map[string]PromptVariable
+	ThinkingBudget int
+	MaxTurns       int
 	Tools        []ToolConfig
+	// ToolDefinitions are the tools advertised to the model
+	ToolDefinitions []ToolDefinition
 	Resources    ResourceConfig
 	Scaling      ScalingConfig
 	Environment  map[string]string
+	Cache        CacheConfig
+	Fallback     FallbackConfig
+	Memory       MemoryConfig
+	Knowledge    KnowledgeConfig
+	// Output is the JSON object the agent answers with, if it has one
+	Output *OutputConfig
+	// Router is set for agents that hand requests to other agents
+	Router *RouterConfig
+	// Guardrails check the agent's requests and answers
+	Guardrails *guardrails.Pipeline
+	// Budget caps the turns, tokens and cost the agent may use
+	Budget *config.AgentBudget
+	// Experiment splits the agent's requests across models
+	Experiment *config.AgentExperiment
+}
+
+// PromptVariable is a variable the system prompt template may use.
+type PromptVariable struct {
+	Default  string
+	Required bool
+}
+
+// PromptLayer is one part of a composed system prompt and its length in
+// characters.
+type PromptLayer struct {
+	Name   string `json:"name"`
+	Length int    `json:"length"`
+}
+
+type CacheConfig struct {
+	Enabled bool
+	TTL     time.Duration
+}
+
+// FallbackConfig is used when the primary provider has no valid credentials
+// left. An empty Provider disables failover.
+type FallbackConfig struct {
+	Provider string
+	Model    string
+}
+
+// MemoryConfig sets how an agent's long-term memories are embedded and
+// recalled.
+type MemoryConfig struct {
+	Enabled        bool
+	Provider       string
+	EmbeddingModel string
+	TopK           int
+	ScoreThreshold float64
+}
+
+// KnowledgeConfig sets how an agent's documents are chunked, embedded and
+// retrieved.
+type KnowledgeConfig struct {
+	Enabled        bool
+	Provider       string
+	EmbeddingModel string
+	ChunkStrategy  string
+	ChunkSize      int
+	ChunkOverlap   int
+	TopK           int
+	ScoreThreshold float64
+}
+
+// OutputConfig is an agent's output contract. Validator is Schema
+// compiled.
+type OutputConfig struct {
+	Name       string
+	Schema     map[string]interface{}
+	Strict     bool
+	MaxRetries int
+	Validator  *tools.Schema
+}
+
+// RouterConfig lists the agents a router hands requests to, with what
+// each handles, and the agent for requests none of them suit.
+type RouterConfig struct {
+	Routes  []Route
+	Default string
+}
+
+type Route struct {
+	Agent       string
+	Description string
+}
+
+type ToolDefinition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
 }
 
 type ToolConfig struct {
@@ -66,11 +175,16 @@ type ResourceConfig struct {
 	MemoryLimit string
 	CPULimit    string
 	Timeout     time.Duration
+	IdleTimeout time.Duration
 }
 
 type ScalingConfig struct {
-	MinInstances int
-	MaxInstances int
+	MinInstances   int
+	MaxInstances   int
+	MaxConcurrency int
+	QueueTimeout   time.Duration
+	MaxQueueDepth  int
+	Autoscale      *config.Autoscaling
 }
 
 type AgentMetrics struct {
@@ -84,47 +198,104 @@ type AgentMetrics struct {
 }
 
 type Message struct {
-	ID        string                 `json:"id"`
-	Role      string                 `json:"role"`
-	Content   string                 `json:"content"`
-	Timestamp time.Time              `json:"timestamp"`
-	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	ID          string                 `json:"id"`
+	Role        string                 `json:"role"`
+	Content     string                 `json:"content"`
+	Attachments []Attachment           `json:"attachments,omitempty"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Attachment references a file in the file store, such as one returned by
+// an HTTP tool, to send to the model with a message.
+type Attachment struct {
+	FileID string `json:"file_id"`
 }
 
 type Request struct {
-	ID       string                 `json:"id"`
-	Messages []Message              `json:"messages"`
-	Tools    []string               `json:"tools,omitempty"`
-	Context  map[string]interface{} `json:"context,omitempty"`
-	Timeout  time.Duration          `json:"timeout,omitempty"`
+	ID              string                 `json:"id"`
+	Messages        []Message              `json:"messages"`
+	Tools           []string               `json:"tools,omitempty"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+	Timeout         time.Duration          `json:"timeout,omitempty"`
+	IncludeThinking bool                   `json:"include_thinking,omitempty"`
+	// Priority orders the request among those queued for the agent: high,
+	// normal, which is the default, or low
+	Priority string `json:"priority,omitempty"`
+	// SessionID is the session the request is sent in, which keeps it on
+	// the experiment variant of the session's earlier requests
+	SessionID string `json:"-"`
+	// SessionMetadata is the metadata of the session the request is sent
+	// in, which system prompt templates can use
+	SessionMetadata map[string]string `json:"-"`
+	// Delegation lists the agents that delegated the request, starting with
+	// the one the client called
+	Delegation []string `json:"-"`
+	// SessionTokens and SessionCost are what the session the request is
+	// sent in has used before it, which session budgets count from
+	SessionTokens int     `json:"-"`
+	SessionCost   float64 `json:"-"`
 }
 
 type Response struct {
-	ID       string                 `json:"id"`
-	Content  string                 `json:"content"`
+	ID      string `json:"id"`
+	Content string `json:"content"`
+	// Output is Content decoded, for agents with an output schema
+	Output   json.RawMessage        `json:"output,omitempty"`
+	Thinking string                 `json:"thinking,omitempty"`
 	ToolUses []ToolUse              `json:"tool_uses,omitempty"`
 	Error    string                 `json:"error,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	// ProviderMeta says why the model stopped, as the provider reported it
+	ProviderMeta *providers.ProviderMeta `json:"provider_meta,omitempty"`
 }
 
+// ToolUse is a tool call the model made. Result and Error are set once the
+// runtime has run it; calls left when the request ran out of turns have
+// neither.
 type ToolUse struct {
-	ID   string                 `json:"id"`
-	Name string                 `json:"name"`
-	Args map[string]interface{} `json:"args"`
+	ID     string                 `json:"id"`
+	Name   string                 `json:"name"`
+	Args   map[string]interface{} `json:"args"`
+	Result interface{}            `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
 }
 
 type EventType string
 
 const (
+	EventEngineStarted  EventType = "engine.started"
+	EventEngineStopping EventType = "engine.stopping"
+	
+	EventClusterDeployed EventType = "cluster.deployed"
+	EventClusterUpdated  EventType = "cluster.updated"
+	EventClusterDeleted  EventType = "cluster.deleted"
+	EventClusterFailed   EventType = "cluster.failed"
+	
 	EventAgentStarted   EventType = "agent.started"
 	EventAgentStopped   EventType = "agent.stopped"
 	EventAgentFailed    EventType = "agent.failed"
 	EventAgentIdle      EventType = "agent.idle"
+	EventAgentDegraded  EventType = "agent.degraded"
+	EventAgentScaled    EventType = "agent.scaled"
 	EventRequestStarted EventType = "request.started"
 	EventRequestEnded   EventType = "request.ended"
+	EventBudgetExceeded EventType = "request.budget_exceeded"
+	EventQuotaExceeded  EventType = "request.quota_exceeded"
+	// EventRequestTranscript carries the transcript of a request, when
+	// transcripts are exported
+	EventRequestTranscript EventType = "request.transcript"
+	
+	EventJobCompleted EventType = "job.completed"
+	
+	EventCredentialRevoked   EventType = "provider.credential_revoked"
+	EventProviderUnavailable EventType = "provider.unavailable"
 )
 
+// Event is something that happened to the engine, a cluster, an agent or
+// a request. ID is unique to the event, for sinks to tell duplicates.
 type Event struct {
+	ID        string                 `json:"id"`
 	Type      EventType              `json:"type"`
 	AgentID   string                 `json:"agent_id"`
 	Timestamp time.Time              `json:"timestamp"`
@@ -0,0 +1,784 @@
+// Package client is a Go SDK for the goagents HTTP API. It is the basis for
+// automation such as infrastructure-as-code providers.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+)
+
+type Config struct {
+	BaseURL string
+	// APIKey is sent as a bearer token when the server requires API keys
+	APIKey     string
+	Timeout    time.Duration
+	Headers    map[string]string
+	HTTPClient *http.Client
+}
+
+type Client struct {
+	baseURL    string
+	headers    map[string]string
+	httpClient *http.Client
+}
+
+// APIError is returned for non-2xx responses.
+type APIError struct {
+	StatusCode int
+	Message    string `json:"error"`
+	Details    string `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	if e.Details != "" {
+		return fmt.Sprintf("goagents API error %d: %s: %s", e.StatusCode, e.Message, e.Details)
+	}
+	return fmt.Sprintf("goagents API error %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is an API 404, e.g. a cluster that was
+// deleted outside of the caller's control.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
+
+// IsConflict reports whether err is an API 409.
+func IsConflict(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusConflict
+}
+
+type ClusterSummary struct {
+	Name            string    `json:"name"`
+	Namespace       string    `json:"namespace"`
+	Status          string    `json:"status"`
+	Agents          int       `json:"agents"`
+	ResourceVersion string    `json:"resource_version"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	// Node runs the cluster, in distributed mode
+	Node string `json:"node,omitempty"`
+}
+
+type Cluster struct {
+	Name            string               `json:"name"`
+	Namespace       string               `json:"namespace"`
+	Status          string               `json:"status"`
+	ResourceVersion string               `json:"resource_version"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+	Agents          []Agent              `json:"agents"`
+	SmokeTests      []SmokeTestResult    `json:"smoke_tests,omitempty"`
+	Conditions      []ClusterCondition   `json:"conditions,omitempty"`
+	Config          *config.AgentCluster `json:"config"`
+}
+
+// ClusterCondition is one aspect of a cluster's state, such as "Ready",
+// as the server's reconcile loop last found it.
+type ClusterCondition struct {
+	Type               string    `json:"type"`
+	Status             string    `json:"status"`
+	Reason             string    `json:"reason"`
+	Message            string    `json:"message,omitempty"`
+	LastTransitionTime time.Time `json:"last_transition_time"`
+}
+
+type SmokeTestResult struct {
+	Agent    string        `json:"agent"`
+	Test     string        `json:"test"`
+	Passed   bool          `json:"passed"`
+	Failures []string      `json:"failures,omitempty"`
+	Latency  time.Duration `json:"latency"`
+	RanAt    time.Time     `json:"ran_at"`
+}
+
+// ClusterDiff is what deploying a candidate spec over a cluster would change.
+type ClusterDiff struct {
+	Cluster         string        `json:"cluster"`
+	Changed         bool          `json:"changed"`
+	Fields          []FieldChange `json:"fields"`
+	AgentsAdded     []string      `json:"agents_added"`
+	AgentsRemoved   []string      `json:"agents_removed"`
+	AgentsChanged   []AgentDiff   `json:"agents_changed"`
+	PolicyViolation string        `json:"policy_violation,omitempty"`
+}
+
+type AgentDiff struct {
+	Name   string        `json:"name"`
+	Fields []FieldChange `json:"fields"`
+}
+
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// ClusterApply is what applying a spec to a cluster changed or, for a dry
+// run, would change.
+type ClusterApply struct {
+	ClusterDiff
+	DryRun          bool     `json:"dry_run"`
+	AgentsRecreated []string `json:"agents_recreated"`
+	Restarted       bool     `json:"restarted"`
+	ResourceVersion string   `json:"resource_version"`
+}
+
+type Agent struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	Cluster      string                 `json:"cluster,omitempty"`
+	Namespace    string                 `json:"namespace,omitempty"`
+	Status       string                 `json:"status"`
+	Provider     string                 `json:"provider"`
+	Model        string                 `json:"model"`
+	SystemPrompt string                 `json:"system_prompt,omitempty"`
+	PromptLayers []agent.PromptLayer    `json:"prompt_layers,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+	LastActivity time.Time              `json:"last_activity"`
+	Metrics      map[string]interface{} `json:"metrics,omitempty"`
+	// Node runs the agent, in distributed mode
+	Node string `json:"node,omitempty"`
+}
+
+type ChatRequest struct {
+	Messages        []agent.Message        `json:"messages"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+	Timeout         int                    `json:"timeout,omitempty"`
+	IncludeThinking bool                   `json:"include_thinking,omitempty"`
+	// Priority orders the request among those queued for the agent: high,
+	// normal or low
+	Priority string `json:"priority,omitempty"`
+}
+
+type AgentOverrides struct {
+	Provider     string            `json:"provider,omitempty"`
+	Model        string            `json:"model,omitempty"`
+	SystemPrompt *string           `json:"system_prompt,omitempty"`
+	Environment  map[string]string `json:"environment,omitempty"`
+}
+
+type CredentialStatus struct {
+	Fingerprint   string     `json:"fingerprint"`
+	Active        bool       `json:"active"`
+	Valid         bool       `json:"valid"`
+	InvalidatedAt *time.Time `json:"invalidated_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+// Node is a server of a distributed deployment.
+type Node struct {
+	ID        string    `json:"id"`
+	Address   string    `json:"address"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Clusters  int       `json:"clusters"`
+	Self      bool      `json:"self,omitempty"`
+	Leader    bool      `json:"leader,omitempty"`
+}
+
+type EventSink struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Events      []string   `json:"events,omitempty"`
+	Pending     int        `json:"pending"`
+	Delivered   uint64     `json:"delivered"`
+	Failed      uint64     `json:"failed"`
+	Dropped     uint64     `json:"dropped"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+type Webhook struct {
+	Name       string    `json:"name"`
+	URL        string    `json:"url"`
+	Events     []string  `json:"events"`
+	Deliveries EventSink `json:"deliveries"`
+}
+
+// WebhookSpec is a webhook to set. Durations are written as strings such
+// as 30s.
+type WebhookSpec struct {
+	URL          string            `json:"url"`
+	Secret       string            `json:"secret"`
+	Events       []string          `json:"events,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	MaxRetries   int               `json:"max_retries,omitempty"`
+	RetryBackoff string            `json:"retry_backoff,omitempty"`
+	Timeout      string            `json:"timeout,omitempty"`
+}
+
+// APIKey is an API key and the namespaces it may reach. Key is only set
+// when the key is created.
+type APIKey struct {
+	Name       string   `json:"name"`
+	Key        string   `json:"key,omitempty"`
+	Namespaces []string `json:"namespaces"`
+}
+
+type DeadLetter struct {
+	ID       string      `json:"id"`
+	Webhook  string      `json:"webhook"`
+	Event    agent.Event `json:"event"`
+	Attempts int         `json:"attempts"`
+	Error    string      `json:"error"`
+	FailedAt time.Time   `json:"failed_at"`
+}
+
+// Template is a cluster template in the server's registry.
+type Template struct {
+	config.ClusterTemplate
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateInstance is a cluster to instantiate from a template. Name and
+// Namespace override those the template gives.
+type TemplateInstance struct {
+	Name      string                 `json:"name,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+}
+
+type DrainStatus struct {
+	Draining       bool       `json:"draining"`
+	Since          *time.Time `json:"since,omitempty"`
+	ActiveRequests int        `json:"active_requests"`
+	Jobs           int        `json:"jobs"`
+	Drained        bool       `json:"drained"`
+}
+
+type Provider struct {
+	Name        string             `json:"name"`
+	Available   bool               `json:"available"`
+	Credentials []CredentialStatus `json:"credentials"`
+}
+
+// FeatureFlag is a runtime feature flag and its overrides.
+type FeatureFlag struct {
+	Name      string                       `json:"name"`
+	Enabled   bool                         `json:"enabled"`
+	Overrides []config.FeatureFlagOverride `json:"overrides"`
+	UpdatedAt time.Time                    `json:"updated_at"`
+}
+
+// ClusterID returns the ID the API knows a cluster by: its name, preceded
+// by its namespace and a colon outside the default namespace.
+func ClusterID(namespace, name string) string {
+	if namespace == "" || namespace == config.DefaultNamespace {
+		return name
+	}
+	return namespace + ":" + name
+}
+
+func NewClient(cfg *Config) (*Client, error) {
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("base URL is required")
+	}
+	if _, err := url.Parse(cfg.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+	
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		timeout := 30 * time.Second
+		if cfg.Timeout > 0 {
+			timeout = cfg.Timeout
+		}
+		httpClient = &http.Client{Timeout: timeout}
+	}
+	
+	headers := cfg.Headers
+	if cfg.APIKey != "" {
+		headers = make(map[string]string, len(cfg.Headers)+1)
+		for key, value := range cfg.Headers {
+			headers[key] = value
+		}
+		headers["Authorization"] = "Bearer " + cfg.APIKey
+	}
+	
+	return &Client{
+		baseURL:    strings.TrimSuffix(cfg.BaseURL, "/"),
+		headers:    headers,
+		httpClient: httpClient,
+	}, nil
+}
+
+// Clusters
+
+func (c *Client) ListClusters(ctx context.Context) ([]ClusterSummary, error) {
+	var resp struct {
+		Clusters []ClusterSummary `json:"clusters"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/clusters", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Clusters, nil
+}
+
+func (c *Client) GetCluster(ctx context.Context, name string) (*Cluster, error) {
+	var cluster Cluster
+	if err := c.do(ctx, http.MethodGet, "/api/v1/clusters/"+url.PathEscape(name), nil, &cluster); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+func (c *Client) CreateCluster(ctx context.Context, cluster *config.AgentCluster) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/clusters", cluster, nil)
+}
+
+// UpdateCluster replaces a cluster's spec. If cluster.Metadata.ResourceVersion
+// is set, the update fails with a conflict (see IsConflict) when the cluster
+// has changed since that version was read.
+func (c *Client) UpdateCluster(ctx context.Context, name string, cluster *config.AgentCluster) error {
+	return c.do(ctx, http.MethodPut, "/api/v1/clusters/"+url.PathEscape(name), cluster, nil)
+}
+
+// ApplyCluster replaces a cluster's spec like UpdateCluster and reports the
+// changes made. With dryRun set, nothing changes and the result is the plan.
+func (c *Client) ApplyCluster(ctx context.Context, name string, cluster *config.AgentCluster, dryRun bool) (*ClusterApply, error) {
+	path := "/api/v1/clusters/" + url.PathEscape(name)
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	
+	var result ClusterApply
+	if err := c.do(ctx, http.MethodPut, path, cluster, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PatchCluster applies a JSON merge patch to a cluster's spec, such as
+// {"spec": {"system_prompt": "..."}}, and reports the changes made.
+func (c *Client) PatchCluster(ctx context.Context, name string, patch interface{}, dryRun bool) (*ClusterApply, error) {
+	path := "/api/v1/clusters/" + url.PathEscape(name)
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	
+	var result ClusterApply
+	if err := c.do(ctx, http.MethodPatch, path, patch, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DiffCluster previews the changes a candidate spec would make to a cluster.
+func (c *Client) DiffCluster(ctx context.Context, name string, candidate *config.AgentCluster) (*ClusterDiff, error) {
+	var diff ClusterDiff
+	if err := c.do(ctx, http.MethodPost, "/api/v1/clusters/"+url.PathEscape(name)+"/diff", candidate, &diff); err != nil {
+		return nil, err
+	}
+	return &diff, nil
+}
+
+// ExportCluster returns the spec a cluster runs, with its credentials
+// redacted.
+func (c *Client) ExportCluster(ctx context.Context, name string) (*config.AgentCluster, error) {
+	var cluster config.AgentCluster
+	if err := c.do(ctx, http.MethodGet, "/api/v1/clusters/"+url.PathEscape(name)+"/export?format=json", nil, &cluster); err != nil {
+		return nil, err
+	}
+	return &cluster, nil
+}
+
+// ImportCluster deploys a cluster from an exported spec, whose redacted
+// credentials must have been filled in first.
+func (c *Client) ImportCluster(ctx context.Context, cluster *config.AgentCluster) error {
+	return c.do(ctx, http.MethodPost, "/api/v1/clusters/import", cluster, nil)
+}
+
+func (c *Client) DeleteCluster(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/clusters/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) ScaleAgent(ctx context.Context, cluster, agentName string, instances int) error {
+	body := map[string]interface{}{
+		"agent":     agentName,
+		"instances": instances,
+	}
+	return c.do(ctx, http.MethodPost, "/api/v1/clusters/"+url.PathEscape(cluster)+"/scale", body, nil)
+}
+
+// Agents
+
+func (c *Client) ListAgents(ctx context.Context, cluster string) ([]Agent, error) {
+	path := "/api/v1/agents"
+	if cluster != "" {
+		path += "?cluster=" + url.QueryEscape(cluster)
+	}
+	
+	var resp struct {
+		Agents []Agent `json:"agents"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Agents, nil
+}
+
+func (c *Client) GetAgent(ctx context.Context, id string) (*Agent, error) {
+	var a Agent
+	if err := c.do(ctx, http.MethodGet, "/api/v1/agents/"+url.PathEscape(id), nil, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (c *Client) CloneAgent(ctx context.Context, cluster, agentName, newName string, overrides *AgentOverrides) (*config.Agent, error) {
+	body := map[string]interface{}{
+		"name": newName,
+	}
+	if overrides != nil {
+		body["overrides"] = overrides
+	}
+	
+	var resp struct {
+		Agent *config.Agent `json:"agent"`
+	}
+	path := fmt.Sprintf("/api/v1/clusters/%s/agents/%s/clone", url.PathEscape(cluster), url.PathEscape(agentName))
+	if err := c.do(ctx, http.MethodPost, path, body, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Agent, nil
+}
+
+func (c *Client) RenameAgent(ctx context.Context, cluster, agentName, newName string) error {
+	path := fmt.Sprintf("/api/v1/clusters/%s/agents/%s/rename", url.PathEscape(cluster), url.PathEscape(agentName))
+	return c.do(ctx, http.MethodPost, path, map[string]string{"name": newName}, nil)
+}
+
+// Chat sends a non-streaming chat request to an agent.
+func (c *Client) Chat(ctx context.Context, agentID string, req *ChatRequest) (*agent.Response, error) {
+	var resp agent.Response
+	if err := c.do(ctx, http.MethodPost, "/api/v1/agents/"+url.PathEscape(agentID)+"/chat", req, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Providers and administration
+
+func (c *Client) ListProviders(ctx context.Context) ([]Provider, error) {
+	var resp struct {
+		Providers []Provider `json:"providers"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/providers", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Providers, nil
+}
+
+func (c *Client) ListTemplates(ctx context.Context) ([]Template, error) {
+	var resp struct {
+		Templates []Template `json:"templates"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/templates", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Templates, nil
+}
+
+func (c *Client) GetTemplate(ctx context.Context, name string) (*Template, error) {
+	var tmpl Template
+	if err := c.do(ctx, http.MethodGet, "/api/v1/templates/"+url.PathEscape(name), nil, &tmpl); err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// CreateTemplate adds a cluster template. Durations in its cluster are
+// written as strings, such as "30s", as in a cluster file.
+func (c *Client) CreateTemplate(ctx context.Context, tmpl *config.ClusterTemplate) (*Template, error) {
+	var created Template
+	if err := c.do(ctx, http.MethodPost, "/api/v1/templates", tmpl, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) UpdateTemplate(ctx context.Context, tmpl *config.ClusterTemplate) (*Template, error) {
+	var updated Template
+	if err := c.do(ctx, http.MethodPut, "/api/v1/templates/"+url.PathEscape(tmpl.Name), tmpl, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client) DeleteTemplate(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/templates/"+url.PathEscape(name), nil, nil)
+}
+
+// InstantiateTemplate deploys a cluster from a template and returns its
+// spec. With dryRun set, nothing is deployed and the result is the spec
+// that would be.
+func (c *Client) InstantiateTemplate(ctx context.Context, name string, instance *TemplateInstance, dryRun bool) (*config.AgentCluster, error) {
+	path := "/api/v1/templates/" + url.PathEscape(name) + "/instantiate"
+	if dryRun {
+		path += "?dry_run=true"
+	}
+	
+	var resp struct {
+		Cluster *config.AgentCluster `json:"cluster"`
+	}
+	if err := c.do(ctx, http.MethodPost, path, instance, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Cluster, nil
+}
+
+// ListNodes returns the live nodes of a distributed deployment.
+func (c *Client) ListNodes(ctx context.Context) ([]Node, error) {
+	var resp struct {
+		Nodes []Node `json:"nodes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/nodes", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Nodes, nil
+}
+
+func (c *Client) ListEventSinks(ctx context.Context) ([]EventSink, error) {
+	var resp struct {
+		Sinks []EventSink `json:"sinks"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/events/sinks", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Sinks, nil
+}
+
+func (c *Client) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var resp struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/webhooks", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Webhooks, nil
+}
+
+func (c *Client) GetWebhook(ctx context.Context, name string) (*Webhook, error) {
+	var webhook Webhook
+	if err := c.do(ctx, http.MethodGet, "/api/v1/webhooks/"+url.PathEscape(name), nil, &webhook); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// SetWebhook adds a webhook or replaces the webhook of its name.
+func (c *Client) SetWebhook(ctx context.Context, name string, spec *WebhookSpec) error {
+	return c.do(ctx, http.MethodPut, "/api/v1/webhooks/"+url.PathEscape(name), spec, nil)
+}
+
+func (c *Client) DeleteWebhook(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/webhooks/"+url.PathEscape(name), nil, nil)
+}
+
+// ListDeadLetters lists the events webhooks could not be given, newest
+// first, for one webhook when webhook is set.
+func (c *Client) ListDeadLetters(ctx context.Context, webhook string, limit int) ([]DeadLetter, error) {
+	query := url.Values{}
+	if webhook != "" {
+		query.Set("webhook", webhook)
+	}
+	if limit > 0 {
+		query.Set("limit", fmt.Sprint(limit))
+	}
+	path := "/api/v1/webhooks/dead-letters"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var resp struct {
+		DeadLetters []DeadLetter `json:"dead_letters"`
+	}
+	if err := c.do(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.DeadLetters, nil
+}
+
+func (c *Client) GetReadOnly(ctx context.Context) (bool, error) {
+	var resp struct {
+		ReadOnly bool `json:"read_only"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/read-only", nil, &resp); err != nil {
+		return false, err
+	}
+	return resp.ReadOnly, nil
+}
+
+func (c *Client) SetReadOnly(ctx context.Context, enabled bool) error {
+	return c.do(ctx, http.MethodPut, "/api/v1/admin/read-only", map[string]bool{"enabled": enabled}, nil)
+}
+
+// Drain stops the server taking new agent work and waits up to wait for the
+// work in flight to finish. The status reports whether it has.
+func (c *Client) Drain(ctx context.Context, wait time.Duration) (*DrainStatus, error) {
+	path := "/api/v1/admin/drain"
+	if wait > 0 {
+		path += "?wait=" + url.QueryEscape(wait.String())
+	}
+	var status DrainStatus
+	if err := c.do(ctx, http.MethodPost, path, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) GetDrainStatus(ctx context.Context) (*DrainStatus, error) {
+	var status DrainStatus
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/drain", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) StopDrain(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/admin/drain", nil, nil)
+}
+
+func (c *Client) ListFeatureFlags(ctx context.Context) ([]FeatureFlag, error) {
+	var resp struct {
+		Features []FeatureFlag `json:"features"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/features", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Features, nil
+}
+
+// SetFeatureFlag sets a flag's default, or, when cluster is set, an override
+// for that cluster or one of its agents.
+func (c *Client) SetFeatureFlag(ctx context.Context, name string, enabled bool, cluster, agentName string) (*FeatureFlag, error) {
+	body := map[string]interface{}{
+		"enabled": enabled,
+		"cluster": cluster,
+		"agent":   agentName,
+	}
+	var flag FeatureFlag
+	if err := c.do(ctx, http.MethodPut, "/api/v1/admin/features/"+url.PathEscape(name), body, &flag); err != nil {
+		return nil, err
+	}
+	return &flag, nil
+}
+
+func (c *Client) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	var resp struct {
+		APIKeys []APIKey `json:"api_keys"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/api-keys", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.APIKeys, nil
+}
+
+func (c *Client) GetAPIKey(ctx context.Context, name string) (*APIKey, error) {
+	var apiKey APIKey
+	if err := c.do(ctx, http.MethodGet, "/api/v1/admin/api-keys/"+url.PathEscape(name), nil, &apiKey); err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// CreateAPIKey has the server generate an API key, which is returned only
+// here.
+func (c *Client) CreateAPIKey(ctx context.Context, name string, namespaces []string) (*APIKey, error) {
+	body := map[string]interface{}{
+		"name":       name,
+		"namespaces": namespaces,
+	}
+	var apiKey APIKey
+	if err := c.do(ctx, http.MethodPost, "/api/v1/admin/api-keys", body, &apiKey); err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+// UpdateAPIKey changes the namespaces an API key may reach.
+func (c *Client) UpdateAPIKey(ctx context.Context, name string, namespaces []string) (*APIKey, error) {
+	body := map[string]interface{}{
+		"namespaces": namespaces,
+	}
+	var apiKey APIKey
+	if err := c.do(ctx, http.MethodPut, "/api/v1/admin/api-keys/"+url.PathEscape(name), body, &apiKey); err != nil {
+		return nil, err
+	}
+	return &apiKey, nil
+}
+
+func (c *Client) DeleteAPIKey(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/api/v1/admin/api-keys/"+url.PathEscape(name), nil, nil)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "goagents-client/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range c.headers {
+		req.Header.Set(key, value)
+	}
+	
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if err := json.Unmarshal(data, apiErr); err != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(data))
+			if apiErr.Message == "" {
+				apiErr.Message = http.StatusText(resp.StatusCode)
+			}
+		}
+		return apiErr
+	}
+	
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ModelPrice is what a model costs, in US dollars per million tokens.
+type ModelPrice struct {
+	Input  float64 `yaml:"input" json:"input"`
+	Output float64 `yaml:"output" json:"output"`
+}
+
+// ModelPrices are the list prices of common models. Versioned names, such
+// as "gpt-4o-2024-08-06", are priced by the longest name they start with.
+var ModelPrices = map[string]ModelPrice{
+	"claude-opus-4":     {Input: 15, Output: 75},
+	"claude-sonnet-4":   {Input: 3, Output: 15},
+	"claude-3-7-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-sonnet": {Input: 3, Output: 15},
+	"claude-3-5-haiku":  {Input: 0.8, Output: 4},
+	"claude-3-opus":     {Input: 15, Output: 75},
+	"claude-3-sonnet":   {Input: 3, Output: 15},
+	"claude-3-haiku":    {Input: 0.25, Output: 1.25},
+	"gpt-4.1":           {Input: 2, Output: 8},
+	"gpt-4.1-mini":      {Input: 0.4, Output: 1.6},
+	"gpt-4.1-nano":      {Input: 0.1, Output: 0.4},
+	"gpt-4o":            {Input: 2.5, Output: 10},
+	"gpt-4o-mini":       {Input: 0.15, Output: 0.6},
+	"gpt-4-turbo":       {Input: 10, Output: 30},
+	"gpt-4":             {Input: 30, Output: 60},
+	"gpt-3.5-turbo":     {Input: 0.5, Output: 1.5},
+	"gemini-2.5-pro":    {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":  {Input: 0.3, Output: 2.5},
+	"gemini-2.0-flash":  {Input: 0.1, Output: 0.4},
+	"gemini-1.5-pro":    {Input: 1.25, Output: 5},
+	"gemini-1.5-flash":  {Input: 0.075, Output: 0.3},
+}
+
+// AgentBudget caps what an agent spends. Request limits apply to each
+// request, Session limits to each session over its lifetime and Daily
+// limits to everything the agent serves in a UTC day.
+type AgentBudget struct {
+	Request *BudgetLimits `yaml:"request,omitempty" json:"request,omitempty"`
+	Session *BudgetLimits `yaml:"session,omitempty" json:"session,omitempty"`
+	Daily   *BudgetLimits `yaml:"daily,omitempty" json:"daily,omitempty"`
+	// Pricing prices the agent's models in place of ModelPrices, such as
+	// for models that are not listed or negotiated rates
+	Pricing *ModelPrice `yaml:"pricing,omitempty" json:"pricing,omitempty"`
+}
+
+// BudgetLimits are the most a request, session or day may use. MaxTurns
+// bounds the model calls of a request; unlike an agent's max_turns, running
+// out fails the request. MaxCost is in US dollars.
+type BudgetLimits struct {
+	MaxTurns  int     `yaml:"max_turns,omitempty" json:"max_turns,omitempty"`
+	MaxTokens int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+	MaxCost   float64 `yaml:"max_cost,omitempty" json:"max_cost,omitempty"`
+}
+
+// Price returns the price of model, if it is known.
+func (b *AgentBudget) Price(model string) (ModelPrice, bool) {
+	if b != nil && b.Pricing != nil {
+		return *b.Pricing, true
+	}
+	
+	best := ""
+	for name := range ModelPrices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return ModelPrice{}, false
+	}
+	return ModelPrices[best], true
+}
+
+// Cost is what the tokens cost at the price.
+func (p ModelPrice) Cost(promptTokens, completionTokens int) float64 {
+	return (float64(promptTokens)*p.Input + float64(completionTokens)*p.Output) / 1e6
+}
+
+func (b *AgentBudget) validate(model string) error {
+	if b.Pricing != nil && (b.Pricing.Input < 0 || b.Pricing.Output < 0) {
+		return fmt.Errorf("pricing must not be negative")
+	}
+	
+	costs := false
+	for _, scope := range []struct {
+		name   string
+		limits *BudgetLimits
+	}{
+		{"request", b.Request},
+		{"session", b.Session},
+		{"daily", b.Daily},
+	} {
+		limits := scope.limits
+		if limits == nil {
+			continue
+		}
+		if limits.MaxTurns < 0 || limits.MaxTokens < 0 || limits.MaxCost < 0 {
+			return fmt.Errorf("%s: limits must not be negative", scope.name)
+		}
+		if limits.MaxTurns > 0 && scope.name != "request" {
+			return fmt.Errorf("%s: max_turns applies to requests only", scope.name)
+		}
+		costs = costs || limits.MaxCost > 0
+	}
+	if _, ok := b.Price(model); costs && !ok {
+		return fmt.Errorf("model %s has no known price; set pricing to limit its cost", model)
+	}
+	return nil
+}
\ No newline at end of file
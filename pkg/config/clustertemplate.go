@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateAnnotation names the template a cluster was instantiated from.
+const TemplateAnnotation = "goagents.dev/template"
+
+// templateReference matches ${name} references to template parameters,
+// and $$, which stands for a literal $.
+var templateReference = regexp.MustCompile(`\$\$|\$\{([^}]*)\}`)
+
+var templateParameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ClusterTemplate is an AgentCluster with parameters, from which clusters
+// are instantiated with values for them. Cluster is the cluster spec, as
+// it would be written in a cluster file, in which any string may refer to
+// a parameter as ${name}. A string that is nothing but a reference takes
+// the value as it is, so ${max_agents} can stand for a number; otherwise
+// the value is written into the string.
+type ClusterTemplate struct {
+	Name        string                       `yaml:"name" json:"name"`
+	Description string                       `yaml:"description,omitempty" json:"description,omitempty"`
+	Parameters  map[string]TemplateParameter `yaml:"parameters,omitempty" json:"parameters,omitempty"`
+	Cluster     map[string]interface{}       `yaml:"cluster" json:"cluster"`
+}
+
+// TemplateParameter declares a parameter of a cluster template. Values for
+// Required parameters must be given; other parameters fall back to
+// Default.
+type TemplateParameter struct {
+	Description string      `yaml:"description,omitempty" json:"description,omitempty"`
+	Default     interface{} `yaml:"default,omitempty" json:"default,omitempty"`
+	Required    bool        `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+// ValidateClusterTemplate checks a template's name and parameters, and
+// that it refers to no parameter it does not declare.
+func ValidateClusterTemplate(tmpl *ClusterTemplate) error {
+	if tmpl.Name == "" {
+		return fmt.Errorf("template name is required")
+	}
+	if strings.ContainsAny(tmpl.Name, ":/") {
+		return fmt.Errorf("template name %q must not contain ':' or '/'", tmpl.Name)
+	}
+	if len(tmpl.Cluster) == 0 {
+		return fmt.Errorf("cluster is required")
+	}
+	for name := range tmpl.Parameters {
+		if !templateParameterName.MatchString(name) {
+			return fmt.Errorf("invalid parameter name %q", name)
+		}
+	}
+	
+	var undeclared []string
+	for _, name := range templateReferences(tmpl.Cluster) {
+		if _, ok := tmpl.Parameters[name]; !ok {
+			undeclared = append(undeclared, "${"+name+"}")
+		}
+	}
+	if len(undeclared) > 0 {
+		return fmt.Errorf("cluster refers to undeclared parameters: %s", strings.Join(undeclared, ", "))
+	}
+	return nil
+}
+
+// InstantiateClusterTemplate returns the cluster a template describes with
+// values for its parameters. Values for parameters the template does not
+// declare are refused, as they are most likely misspelled.
+func InstantiateClusterTemplate(tmpl *ClusterTemplate, values map[string]interface{}) (*AgentCluster, error) {
+	var unknown, missing []string
+	for name := range values {
+		if _, ok := tmpl.Parameters[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	resolved := make(map[string]interface{}, len(tmpl.Parameters))
+	for name, parameter := range tmpl.Parameters {
+		value, ok := values[name]
+		switch {
+		case ok:
+			resolved[name] = value
+		case parameter.Required:
+			missing = append(missing, name)
+		default:
+			resolved[name] = parameter.Default
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameters: %s", strings.Join(unknown, ", "))
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing values for required parameters: %s", strings.Join(missing, ", "))
+	}
+	
+	// The cluster is decoded as a cluster file is, so durations are
+	// written as strings such as 30s
+	data, err := yaml.Marshal(substituteTemplate(tmpl.Cluster, resolved))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cluster: %w", err)
+	}
+	var cluster AgentCluster
+	if err := yaml.Unmarshal(data, &cluster); err != nil {
+		return nil, fmt.Errorf("invalid cluster: %w", err)
+	}
+	return &cluster, nil
+}
+
+// substituteTemplate copies a decoded YAML or JSON value with the
+// parameter references in its strings replaced by their values.
+func substituteTemplate(node interface{}, values map[string]interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		substituted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			substituted[key] = substituteTemplate(item, values)
+		}
+		return substituted
+	case []interface{}:
+		substituted := make([]interface{}, len(v))
+		for i, item := range v {
+			substituted[i] = substituteTemplate(item, values)
+		}
+		return substituted
+	case string:
+		if match := templateReference.FindStringSubmatch(v); match != nil && match[0] == v && match[1] != "" {
+			return values[match[1]]
+		}
+		return templateReference.ReplaceAllStringFunc(v, func(reference string) string {
+			if reference == "$$" {
+				return "$"
+			}
+			value := values[reference[2:len(reference)-1]]
+			if value == nil {
+				return ""
+			}
+			return fmt.Sprint(value)
+		})
+	default:
+		return node
+	}
+}
+
+// templateReferences returns the parameters a decoded value refers to, in
+// alphabetical order.
+func templateReferences(node interface{}) []string {
+	names := make(map[string]bool)
+	collectTemplateReferences(node, names)
+	references := make([]string, 0, len(names))
+	for name := range names {
+		references = append(references, name)
+	}
+	sort.Strings(references)
+	return references
+}
+
+func collectTemplateReferences(node interface{}, names map[string]bool) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for _, item := range v {
+			collectTemplateReferences(item, names)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectTemplateReferences(item, names)
+		}
+	case string:
+		for _, match := range templateReference.FindAllStringSubmatch(v, -1) {
+			if match[0] != "$$" {
+				names[match[1]] = true
+			}
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// DistributedConfig runs the server as one node of several that share the
+// state store, which must then be Postgres, or SQLite for nodes on one
+// host. Cluster definitions are global: each cluster is run by one node,
+// and any node answers API calls for it by forwarding them to that node at
+// its Address. Nodes heartbeat every HeartbeatInterval, 5s by default; a
+// node that has not for NodeTimeout, 15s by default, is presumed down and
+// its clusters are scheduled onto the others. NodeID is the host name
+// unless set.
+type DistributedConfig struct {
+	Enabled           bool          `yaml:"enabled" json:"enabled"`
+	NodeID            string        `yaml:"node_id,omitempty" json:"node_id,omitempty"`
+	Address           string        `yaml:"address" json:"address"`
+	HeartbeatInterval time.Duration `yaml:"heartbeat_interval,omitempty" json:"heartbeat_interval,omitempty"`
+	NodeTimeout       time.Duration `yaml:"node_timeout,omitempty" json:"node_timeout,omitempty"`
+}
+
+func validateDistributedConfig(distributed *DistributedConfig, state *StateConfig) error {
+	if !distributed.Enabled {
+		return nil
+	}
+	switch state.Backend {
+	case "sqlite", "postgres":
+	default:
+		return fmt.Errorf("requires the sqlite or postgres state backend, not %q", state.Backend)
+	}
+	
+	address, err := url.Parse(distributed.Address)
+	if err != nil || (address.Scheme != "http" && address.Scheme != "https") || address.Host == "" {
+		return fmt.Errorf("address must be the http or https URL other nodes reach this one at")
+	}
+	if distributed.HeartbeatInterval < 0 || distributed.NodeTimeout < 0 {
+		return fmt.Errorf("heartbeat_interval and node_timeout must not be negative")
+	}
+	if distributed.NodeTimeout > 0 && distributed.NodeTimeout <= distributed.HeartbeatInterval {
+		return fmt.Errorf("node_timeout must be longer than heartbeat_interval")
+	}
+	return nil
+}
\ No newline at end of file
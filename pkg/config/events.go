@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// EventsConfig sends the events of the engine, its clusters, agents and
+// requests to sinks.
+type EventsConfig struct {
+	Sinks []EventSinkConfig `yaml:"sinks,omitempty" json:"sinks,omitempty"`
+}
+
+// EventSinkConfig is a sink events are sent to: the log, a webhook, NATS,
+// or Kafka through a REST proxy. Events selects the event types it gets,
+// each a type or a prefix ending in "*" such as "agent.*"; without any it
+// gets every event. Up to BufferSize events, 1000 by default, wait for the
+// sink and are sent in batches of up to BatchSize, 100 by default; events
+// that find the buffer full are dropped and counted. Timeout bounds each
+// batch, 10s by default. Name defaults to the type.
+type EventSinkConfig struct {
+	Name       string        `yaml:"name,omitempty" json:"name,omitempty"`
+	Type       string        `yaml:"type" json:"type"`
+	Events     []string      `yaml:"events,omitempty" json:"events,omitempty"`
+	BufferSize int           `yaml:"buffer_size,omitempty" json:"buffer_size,omitempty"`
+	BatchSize  int           `yaml:"batch_size,omitempty" json:"batch_size,omitempty"`
+	Timeout    time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// URL is where webhook sinks post, the nats:// or tls:// URL of the
+	// NATS server, or the Kafka REST proxy's URL
+	URL string `yaml:"url,omitempty" json:"url,omitempty"`
+	// Headers are added to the requests of webhook and Kafka sinks
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	// Subject prefixes the subjects NATS sinks publish to, and Token
+	// authenticates them
+	Subject string `yaml:"subject,omitempty" json:"subject,omitempty"`
+	Token   string `yaml:"token,omitempty" json:"token,omitempty"`
+	// Topic is the topic Kafka sinks produce to
+	Topic string `yaml:"topic,omitempty" json:"topic,omitempty"`
+}
+
+// SinkName returns the sink's name, which defaults to its type.
+func (s *EventSinkConfig) SinkName() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.Type
+}
+
+func validateEventsConfig(events *EventsConfig) error {
+	names := make(map[string]bool, len(events.Sinks))
+	for i := range events.Sinks {
+		sink := &events.Sinks[i]
+		name := sink.SinkName()
+		if name == "" {
+			return fmt.Errorf("sink %d: type is required", i)
+		}
+		if names[name] {
+			return fmt.Errorf("sink %s: duplicate name", name)
+		}
+		names[name] = true
+		
+		if sink.BufferSize < 0 || sink.BatchSize < 0 || sink.Timeout < 0 {
+			return fmt.Errorf("sink %s: buffer_size, batch_size and timeout must not be negative", name)
+		}
+		
+		switch sink.Type {
+		case "log":
+		case "webhook":
+			if !isURL(sink.URL, "http", "https") {
+				return fmt.Errorf("sink %s: url must be an http or https URL", name)
+			}
+		case "nats":
+			if !isURL(sink.URL, "nats", "tls") {
+				return fmt.Errorf("sink %s: url must be a nats:// or tls:// URL", name)
+			}
+		case "kafka":
+			if !isURL(sink.URL, "http", "https") {
+				return fmt.Errorf("sink %s: url must be the http or https URL of a Kafka REST proxy", name)
+			}
+			if sink.Topic == "" {
+				return fmt.Errorf("sink %s: topic is required", name)
+			}
+		default:
+			return fmt.Errorf("sink %s: unsupported type %q", name, sink.Type)
+		}
+	}
+	return nil
+}
+
+// isURL reports whether value is an absolute URL with a host and one of
+// schemes.
+func isURL(value string, schemes ...string) bool {
+	parsed, err := url.Parse(value)
+	if err != nil || parsed.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if parsed.Scheme == scheme {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,84 @@
+package config
+
+import "fmt"
+
+// AgentExperiment splits an agent's traffic across models, so their
+// quality and cost can be compared on real requests. Each request goes to
+// a variant picked at random in proportion to the weights, except that
+// requests in a session, or with the same value for StickyKey in their
+// context, always get the same variant.
+type AgentExperiment struct {
+	Name     string              `yaml:"name" json:"name"`
+	Variants []ExperimentVariant `yaml:"variants" json:"variants"`
+	// StickyKey is the request context field, such as user_id, that keeps
+	// callers outside sessions on one variant
+	StickyKey string `yaml:"sticky_key,omitempty" json:"sticky_key,omitempty"`
+}
+
+// ExperimentVariant is a model an experiment sends a share of traffic to.
+// Without a provider and model it is the agent's own.
+type ExperimentVariant struct {
+	Name     string `yaml:"name" json:"name"`
+	Weight   int    `yaml:"weight" json:"weight"`
+	Provider string `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Model    string `yaml:"model,omitempty" json:"model,omitempty"`
+}
+
+// TotalWeight returns the sum of the variants' weights.
+func (x *AgentExperiment) TotalWeight() int {
+	total := 0
+	for _, variant := range x.Variants {
+		total += variant.Weight
+	}
+	return total
+}
+
+// Target returns the provider and model the variant uses, given those of
+// its agent.
+func (v *ExperimentVariant) Target(provider, model string) (string, string) {
+	if v.Provider == "" {
+		return provider, model
+	}
+	return v.Provider, v.Model
+}
+
+// checkExperiment checks an agent's experiment: the variants' names and
+// weights, and that each variant's model is allowed and, for agents with
+// cost limits, has a price.
+func (p *PolicyConfig) checkExperiment(agent *Agent) error {
+	experiment := agent.Experiment
+	if !workflowNamePattern.MatchString(experiment.Name) {
+		return fmt.Errorf("name must be letters, digits, '-' and '_'")
+	}
+	if len(experiment.Variants) < 2 {
+		return fmt.Errorf("at least two variants are required")
+	}
+	
+	names := make(map[string]bool)
+	for i, variant := range experiment.Variants {
+		switch {
+		case !workflowNamePattern.MatchString(variant.Name):
+			return fmt.Errorf("variant %d: name must be letters, digits, '-' and '_'", i)
+		case names[variant.Name]:
+			return fmt.Errorf("variant %s: defined more than once", variant.Name)
+		case variant.Weight <= 0:
+			return fmt.Errorf("variant %s: weight must be positive", variant.Name)
+		case (variant.Provider == "") != (variant.Model == ""):
+			return fmt.Errorf("variant %s: provider and model are set together", variant.Name)
+		case variant.Provider != "" && !isValidProvider(variant.Provider):
+			return fmt.Errorf("variant %s: unsupported provider %s", variant.Name, variant.Provider)
+		}
+		names[variant.Name] = true
+		
+		provider, model := variant.Target(agent.Provider, agent.Model)
+		if err := p.CheckModel(provider, model); err != nil {
+			return fmt.Errorf("variant %s: %w", variant.Name, err)
+		}
+		if agent.Budget != nil {
+			if err := agent.Budget.validate(model); err != nil {
+				return fmt.Errorf("variant %s: budget: %w", variant.Name, err)
+			}
+		}
+	}
+	return nil
+}
\ No newline at end of file
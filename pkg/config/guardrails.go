@@ -0,0 +1,140 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// Guardrail types
+const (
+	GuardrailMaxLength    = "max_length"
+	GuardrailBannedTopics = "banned_topics"
+	GuardrailRegex        = "regex"
+	GuardrailPII          = "pii"
+	GuardrailSchema       = "schema"
+	GuardrailModeration   = "moderation"
+)
+
+// Guardrail actions
+const (
+	GuardrailBlock  = "block"
+	GuardrailRedact = "redact"
+	GuardrailRetry  = "retry"
+)
+
+// PIIKinds are the kinds of personal data pii guardrails detect.
+var PIIKinds = []string{"email", "phone", "credit_card", "ssn", "ip_address", "iban"}
+
+// AgentGuardrails check the last user message of each request before the
+// model sees it, and each answer before the client does.
+type AgentGuardrails struct {
+	Input  []Guardrail `yaml:"input,omitempty" json:"input,omitempty"`
+	Output []Guardrail `yaml:"output,omitempty" json:"output,omitempty"`
+	// MaxRetries bounds the answers output guardrails with the retry action
+	// send back to the model, 2 by default; after that they block
+	MaxRetries int `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+}
+
+// Guardrail is one check and what to do when text fails it: block the
+// request or answer, redact the offending text, or, for answers, ask the
+// model for another one.
+type Guardrail struct {
+	Name   string `yaml:"name,omitempty" json:"name,omitempty"`
+	Type   string `yaml:"type" json:"type"`
+	Action string `yaml:"action,omitempty" json:"action,omitempty"`
+	// Message replaces a blocked answer, or explains a blocked request
+	Message   string                 `yaml:"message,omitempty" json:"message,omitempty"`
+	MaxLength int                    `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	Topics    []string               `yaml:"topics,omitempty" json:"topics,omitempty"`
+	Patterns  []string               `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+	PII       []string               `yaml:"pii,omitempty" json:"pii,omitempty"`
+	Schema    map[string]interface{} `yaml:"schema,omitempty" json:"schema,omitempty"`
+	// URL is the moderation hook, called with the text to check
+	URL     string            `yaml:"url,omitempty" json:"url,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	Timeout time.Duration     `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+func (g *AgentGuardrails) validate() error {
+	if g.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	for i := range g.Input {
+		if err := g.Input[i].validate("input"); err != nil {
+			return fmt.Errorf("input %d: %w", i, err)
+		}
+	}
+	for i := range g.Output {
+		if err := g.Output[i].validate("output"); err != nil {
+			return fmt.Errorf("output %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (g *Guardrail) validate(stage string) error {
+	redactable := false
+	switch g.Type {
+	case GuardrailMaxLength:
+		if g.MaxLength <= 0 {
+			return fmt.Errorf("max_length must be positive")
+		}
+		redactable = true
+	case GuardrailBannedTopics:
+		if len(g.Topics) == 0 {
+			return fmt.Errorf("at least one topic is required")
+		}
+		redactable = true
+	case GuardrailRegex:
+		if len(g.Patterns) == 0 {
+			return fmt.Errorf("at least one pattern is required")
+		}
+		for _, pattern := range g.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+		redactable = true
+	case GuardrailPII:
+		for _, kind := range g.PII {
+			if !contains(PIIKinds, kind) {
+				return fmt.Errorf("unsupported pii kind %s", kind)
+			}
+		}
+		redactable = true
+	case GuardrailSchema:
+		if stage != "output" {
+			return fmt.Errorf("schema guardrails check answers only")
+		}
+		if err := checkObjectSchema(g.Schema); err != nil {
+			return err
+		}
+	case GuardrailModeration:
+		u, err := url.Parse(g.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("url must be an http or https URL")
+		}
+		if g.Timeout < 0 {
+			return fmt.Errorf("timeout must not be negative")
+		}
+	default:
+		return fmt.Errorf("unsupported guardrail type %q", g.Type)
+	}
+	
+	switch g.Action {
+	case "", GuardrailBlock:
+	case GuardrailRedact:
+		if !redactable {
+			return fmt.Errorf("%s guardrails cannot redact", g.Type)
+		}
+	case GuardrailRetry:
+		if stage != "output" {
+			return fmt.Errorf("only output guardrails can retry")
+		}
+	default:
+		return fmt.Errorf("unsupported action %q, expected block, redact or retry", g.Action)
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Restart policies
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// AgentHealth watches whether an agent can still serve requests. Failed
+// requests and, every Interval, a probe that calls the agent's provider
+// count towards FailureThreshold; once that many fail in a row the agent
+// is marked failed and refuses requests until Restart brings it back.
+type AgentHealth struct {
+	// Interval between probes; without one only requests are counted
+	Interval time.Duration `yaml:"interval,omitempty" json:"interval,omitempty"`
+	Timeout  time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	// FailureThreshold is 3 unless set
+	FailureThreshold int `yaml:"failure_threshold,omitempty" json:"failure_threshold,omitempty"`
+	// Restart is on-failure, with the default backoff, unless set
+	Restart *RestartPolicy `yaml:"restart,omitempty" json:"restart,omitempty"`
+}
+
+// RestartPolicy says when a failed agent is recreated. Restarts wait
+// Backoff, doubling with each restart the agent has not recovered from up
+// to MaxBackoff. on-failure gives up after MaxRestarts of them, if set;
+// always never does.
+type RestartPolicy struct {
+	Policy      string        `yaml:"policy" json:"policy"`
+	Backoff     time.Duration `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+	MaxBackoff  time.Duration `yaml:"max_backoff,omitempty" json:"max_backoff,omitempty"`
+	MaxRestarts int           `yaml:"max_restarts,omitempty" json:"max_restarts,omitempty"`
+}
+
+func (h *AgentHealth) validate() error {
+	if h.Interval < 0 || h.Timeout < 0 {
+		return fmt.Errorf("interval and timeout must not be negative")
+	}
+	if h.FailureThreshold < 0 {
+		return fmt.Errorf("failure_threshold must not be negative")
+	}
+	
+	restart := h.Restart
+	if restart == nil {
+		return nil
+	}
+	switch restart.Policy {
+	case RestartNever, RestartOnFailure, RestartAlways:
+	default:
+		return fmt.Errorf("unsupported restart policy %q, expected never, on-failure or always", restart.Policy)
+	}
+	if restart.Backoff < 0 || restart.MaxBackoff < 0 || restart.MaxRestarts < 0 {
+		return fmt.Errorf("restart backoff, max_backoff and max_restarts must not be negative")
+	}
+	if restart.MaxBackoff > 0 && restart.MaxBackoff < restart.Backoff {
+		return fmt.Errorf("restart max_backoff must not be less than backoff")
+	}
+	return nil
+}
\ No newline at end of file
@@ -3,11 +3,17 @@ package config
 import (
 	"encoding/json"
 	"fmt"
+	"net/mail"
+	"net/url"
 	"os"
 	"path/filepath"
+	"regexp"
+	goruntime "runtime"
 	"strings"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/goagents/goagents/pkg/vault"
+	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -32,10 +38,61 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("server.host", "0.0.0.0")
 	v.SetDefault("server.port", 8080)
 	v.SetDefault("server.timeout", "30s")
+	v.SetDefault("server.shutdown_timeout", "30s")
 	v.SetDefault("server.log_level", "info")
+	v.SetDefault("server.read_only", false)
 	v.SetDefault("server.metrics.enabled", true)
 	v.SetDefault("server.metrics.path", "/metrics")
 	v.SetDefault("server.metrics.port", 9090)
+	v.SetDefault("server.metrics.cold_start_slo.threshold", "5s")
+	v.SetDefault("server.metrics.cold_start_slo.objective", 0.99)
+	v.SetDefault("server.tracing.enabled", false)
+	v.SetDefault("server.tracing.service_name", "goagents")
+	v.SetDefault("server.tracing.sample_ratio", 1.0)
+	v.SetDefault("gateways.max_history", 20)
+	v.SetDefault("gateways.session_ttl", "1h")
+	v.SetDefault("policy.exec.max_timeout", "60s")
+	v.SetDefault("policy.exec.max_memory", "512Mi")
+	v.SetDefault("policy.exec.max_output", "1Mi")
+	v.SetDefault("policy.prompt_injection.mode", "wrap")
+	v.SetDefault("cache.backend", "memory")
+	v.SetDefault("cache.max_entries", 1000)
+	v.SetDefault("cache.ttl", "5m")
+	v.SetDefault("files.backend", "memory")
+	v.SetDefault("files.max_size", "25Mi")
+	v.SetDefault("files.ttl", "24h")
+	v.SetDefault("feedback.max_responses", 10000)
+	v.SetDefault("feedback.max_score", 5)
+	v.SetDefault("sessions.backend", "memory")
+	v.SetDefault("sessions.ttl", "24h")
+	v.SetDefault("sessions.max_messages", 200)
+	v.SetDefault("sessions.max_history_tokens", 16000)
+	v.SetDefault("state.backend", "sqlite")
+	v.SetDefault("state.path", filepath.Join(defaultDataDir(), "state.db"))
+}
+
+// defaultDataDir is where the server keeps its data unless configured
+// otherwise: the directory systemd creates for the unit's StateDirectory,
+// the system data directory when running as root or on Windows, and the
+// user's data directory otherwise.
+func defaultDataDir() string {
+	// systemd lists the unit's state directories separated by colons
+	if dirs := os.Getenv("STATE_DIRECTORY"); dirs != "" {
+		return strings.Split(dirs, ":")[0]
+	}
+	if goruntime.GOOS == "windows" {
+		return filepath.Join(os.Getenv("ProgramData"), "goagents")
+	}
+	if os.Geteuid() == 0 {
+		return "/var/lib/goagents"
+	}
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return filepath.Join(dir, "goagents")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, ".local", "share", "goagents")
+	}
+	return "/var/lib/goagents"
 }
 
 func (l *Loader) LoadConfig(configPath string) (*Config, error) {
@@ -46,7 +103,7 @@ func (l *Loader) LoadConfig(configPath string) (*Config, error) {
 	}
 	
 	var config Config
-	if err := l.viper.Unmarshal(&config); err != nil {
+	if err := l.viper.Unmarshal(&config, useYAMLTags); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 	
@@ -86,6 +143,12 @@ func (l *Loader) LoadAgentCluster(clusterPath string) (*AgentCluster, error) {
 	return &cluster, nil
 }
 
+// The config structs are tagged for yaml/json; viper decodes through
+// mapstructure, so point it at the yaml tags to get snake_case keys.
+func useYAMLTags(dc *mapstructure.DecoderConfig) {
+	dc.TagName = "yaml"
+}
+
 func (l *Loader) loadFromFile(configPath string) error {
 	l.viper.SetConfigFile(configPath)
 	
@@ -108,10 +171,286 @@ func (l *Loader) validateConfig(config *Config) error {
 		return fmt.Errorf("invalid metrics port: %d", config.Server.Metrics.Port)
 	}
 	
+	if objective := config.Server.Metrics.ColdStartSLO.Objective; objective < 0 || objective >= 1 {
+		return fmt.Errorf("invalid cold start SLO objective: %v (must be between 0 and 1)", objective)
+	}
+	
+	if ratio := config.Server.Tracing.SampleRatio; ratio < 0 || ratio > 1 {
+		return fmt.Errorf("invalid tracing sample ratio: %v (must be between 0 and 1)", ratio)
+	}
+	
+	if err := validateAuthConfig(&config.Security.Auth); err != nil {
+		return fmt.Errorf("security.auth: %w", err)
+	}
+	
+	switch config.Cache.Backend {
+	case "", "memory":
+	case "redis":
+		if config.Cache.Redis == nil || config.Cache.Redis.Addr == "" {
+			return fmt.Errorf("cache: redis backend requires redis.addr")
+		}
+	default:
+		return fmt.Errorf("cache: unsupported backend %s", config.Cache.Backend)
+	}
+	
+	switch config.Files.Backend {
+	case "", "memory":
+	case "disk":
+		if config.Files.Dir == "" {
+			return fmt.Errorf("files: disk backend requires dir")
+		}
+	default:
+		return fmt.Errorf("files: unsupported backend %s", config.Files.Backend)
+	}
+	if _, err := ParseByteSize(config.Files.MaxSize); err != nil {
+		return fmt.Errorf("files: invalid max_size: %w", err)
+	}
+	
+	switch config.Sessions.Backend {
+	case "", "memory":
+	case "sqlite":
+		if config.Sessions.Path == "" {
+			return fmt.Errorf("sessions: sqlite backend requires path")
+		}
+	case "postgres":
+		if config.Sessions.DSN == "" {
+			return fmt.Errorf("sessions: postgres backend requires dsn")
+		}
+	case "redis":
+		if config.Sessions.Redis == nil || config.Sessions.Redis.Addr == "" {
+			return fmt.Errorf("sessions: redis backend requires redis.addr")
+		}
+	default:
+		return fmt.Errorf("sessions: unsupported backend %s", config.Sessions.Backend)
+	}
+	if config.Sessions.MaxMessages < 0 || config.Sessions.MaxHistoryTokens < 0 {
+		return fmt.Errorf("sessions: max_messages and max_history_tokens must not be negative")
+	}
+	if summarize := config.Sessions.Summarize; summarize.Enabled {
+		if summarize.Provider != "" && !isValidProvider(summarize.Provider) {
+			return fmt.Errorf("sessions: unsupported summarize provider %s", summarize.Provider)
+		}
+		if (summarize.Provider == "") != (summarize.Model == "") {
+			return fmt.Errorf("sessions: summarize provider and model must be set together")
+		}
+		if summarize.Threshold < 0 || summarize.Threshold > 1 {
+			return fmt.Errorf("sessions: summarize threshold must be between 0 and 1")
+		}
+		if summarize.KeepMessages < 0 || summarize.MaxTokens < 0 {
+			return fmt.Errorf("sessions: summarize keep_messages and max_tokens must not be negative")
+		}
+	}
+	if config.Memory.MaxEntries < 0 {
+		return fmt.Errorf("memory: max_entries must not be negative")
+	}
+	if config.Knowledge.MaxDocumentSize < 0 {
+		return fmt.Errorf("knowledge: max_document_size must not be negative")
+	}
+	
+	switch config.State.Backend {
+	case "", "memory":
+	case "sqlite":
+		if config.State.Path == "" {
+			return fmt.Errorf("state: sqlite backend requires path")
+		}
+	case "postgres":
+		if config.State.DSN == "" {
+			return fmt.Errorf("state: postgres backend requires dsn")
+		}
+	default:
+		return fmt.Errorf("state: unsupported backend %s", config.State.Backend)
+	}
+	if err := validateDistributedConfig(&config.Distributed, &config.State); err != nil {
+		return fmt.Errorf("distributed: %w", err)
+	}
+	if err := validateEventsConfig(&config.Events); err != nil {
+		return fmt.Errorf("events: %w", err)
+	}
+	if err := validateWebhooksConfig(&config.Webhooks, &config.Events); err != nil {
+		return fmt.Errorf("webhooks: %w", err)
+	}
+	if err := validateTranscriptsConfig(&config.Transcripts); err != nil {
+		return fmt.Errorf("transcripts: %w", err)
+	}
+	
+	if config.Vault.Enabled {
+		if err := vault.ValidateMasterKey(config.Vault.MasterKey); err != nil {
+			return fmt.Errorf("vault: %w", err)
+		}
+	}
+	
+	providerHTTP := map[string]*HTTPClientConfig{}
+	if config.Providers.Anthropic != nil {
+		providerHTTP["anthropic"] = config.Providers.Anthropic.HTTP
+	}
+	if config.Providers.OpenAI != nil {
+		providerHTTP["openai"] = config.Providers.OpenAI.HTTP
+	}
+	if config.Providers.Gemini != nil {
+		providerHTTP["gemini"] = config.Providers.Gemini.HTTP
+	}
+	for name, httpConfig := range providerHTTP {
+		if err := validateHTTPClientConfig(httpConfig); err != nil {
+			return fmt.Errorf("provider %s: %w", name, err)
+		}
+	}
+	
+	switch config.Tools.Audit.Args {
+	case "", "redacted", "hash":
+	default:
+		return fmt.Errorf("tools.audit: invalid args %q, expected redacted or hash", config.Tools.Audit.Args)
+	}
+	
+	if err := validateGatewaysConfig(&config.Gateways); err != nil {
+		return err
+	}
+	
+	if err := validateFeaturesConfig(config.Features); err != nil {
+		return err
+	}
+	
+	if err := validateNamespacesConfig(config.Namespaces); err != nil {
+		return fmt.Errorf("namespaces: %w", err)
+	}
+	
+	if err := config.Policy.Validate(); err != nil {
+		return err
+	}
+	if err := config.Policy.CheckEndpoints(&config.Providers); err != nil {
+		return err
+	}
+	
 	for i, cluster := range config.Clusters {
 		if err := l.validateAgentCluster(&cluster); err != nil {
 			return fmt.Errorf("cluster %d validation failed: %w", i, err)
 		}
+		if err := config.Policy.CheckCluster(&cluster); err != nil {
+			return fmt.Errorf("cluster %d validation failed: %w", i, err)
+		}
+	}
+	
+	return nil
+}
+
+func validateAuthConfig(auth *APIAuthConfig) error {
+	if !auth.Enabled {
+		return nil
+	}
+	if auth.Method != "" && auth.Method != "api_key" {
+		return fmt.Errorf("unsupported method %s", auth.Method)
+	}
+	if len(auth.APIKeys) == 0 {
+		return fmt.Errorf("at least one API key is required")
+	}
+	
+	keys := make(map[string]bool)
+	for i, apiKey := range auth.APIKeys {
+		if apiKey.Key == "" {
+			return fmt.Errorf("api key %d: key is required", i)
+		}
+		if keys[apiKey.Key] {
+			return fmt.Errorf("api key %d: duplicate key", i)
+		}
+		keys[apiKey.Key] = true
+		if len(apiKey.Namespaces) == 0 {
+			return fmt.Errorf("api key %d: at least one namespace is required", i)
+		}
+	}
+	return nil
+}
+
+func validateGatewaysConfig(gateways *GatewaysConfig) error {
+	if gateways.Discord != nil {
+		if gateways.Discord.Token == "" {
+			return fmt.Errorf("gateways.discord: token is required")
+		}
+		if err := validateGatewayBindings(gateways.Discord.Bindings); err != nil {
+			return fmt.Errorf("gateways.discord: %w", err)
+		}
+	}
+	
+	if gateways.Teams != nil {
+		if gateways.Teams.AppID == "" || gateways.Teams.AppPassword == "" {
+			return fmt.Errorf("gateways.teams: app_id and app_password are required")
+		}
+		if err := validateGatewayBindings(gateways.Teams.Bindings); err != nil {
+			return fmt.Errorf("gateways.teams: %w", err)
+		}
+	}
+	
+	if gateways.Email != nil {
+		if gateways.Email.IMAP.Addr == "" || gateways.Email.SMTP.Addr == "" {
+			return fmt.Errorf("gateways.email: imap.addr and smtp.addr are required")
+		}
+		if _, err := mail.ParseAddress(gateways.Email.From); err != nil {
+			return fmt.Errorf("gateways.email: invalid from address: %w", err)
+		}
+		if err := validateGatewayBindings(gateways.Email.Bindings); err != nil {
+			return fmt.Errorf("gateways.email: %w", err)
+		}
+	}
+	
+	return nil
+}
+
+// featureNamePattern matches flag names such as semantic_cache.
+var featureNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// ValidateFeatureFlag checks a flag name and its overrides.
+func ValidateFeatureFlag(name string, flag *FeatureFlagConfig) error {
+	if !featureNamePattern.MatchString(name) {
+		return fmt.Errorf("invalid feature flag name %q: use lowercase letters, digits and underscores", name)
+	}
+	for i, override := range flag.Overrides {
+		if override.Cluster == "" {
+			return fmt.Errorf("feature %s: override %d: cluster is required", name, i)
+		}
+	}
+	return nil
+}
+
+func validateFeaturesConfig(features map[string]FeatureFlagConfig) error {
+	for name, flag := range features {
+		if err := ValidateFeatureFlag(name, &flag); err != nil {
+			return fmt.Errorf("features: %w", err)
+		}
+	}
+	return nil
+}
+
+func validateGatewayBindings(bindings []GatewayBinding) error {
+	if len(bindings) == 0 {
+		return fmt.Errorf("at least one binding is required")
+	}
+	
+	for i, binding := range bindings {
+		if binding.Channel == "" || binding.Cluster == "" || binding.Agent == "" {
+			return fmt.Errorf("binding %d: channel, cluster and agent are required", i)
+		}
+	}
+	
+	return nil
+}
+
+func validateHTTPClientConfig(httpConfig *HTTPClientConfig) error {
+	if httpConfig == nil {
+		return nil
+	}
+	
+	if httpConfig.ProxyURL != "" {
+		if _, err := url.Parse(httpConfig.ProxyURL); err != nil {
+			return fmt.Errorf("invalid proxy_url: %w", err)
+		}
+	}
+	
+	if httpConfig.CABundle != "" {
+		if _, err := os.Stat(httpConfig.CABundle); err != nil {
+			return fmt.Errorf("invalid ca_bundle: %w", err)
+		}
+	}
+	
+	if httpConfig.MaxIdleConns < 0 {
+		return fmt.Errorf("invalid max_idle_conns: %d", httpConfig.MaxIdleConns)
 	}
 	
 	return nil
@@ -131,7 +470,10 @@ func (l *Loader) validateAgentCluster(cluster *AgentCluster) error {
 	}
 	
 	if cluster.Metadata.Namespace == "" {
-		cluster.Metadata.Namespace = "default"
+		cluster.Metadata.Namespace = DefaultNamespace
+	}
+	if err := ValidateClusterNames(cluster); err != nil {
+		return err
 	}
 	
 	if len(cluster.Spec.Agents) == 0 {
@@ -161,6 +503,51 @@ func (l *Loader) validateAgentCluster(cluster *AgentCluster) error {
 			return fmt.Errorf("agent %s: unsupported provider %s", agent.Name, agent.Provider)
 		}
 		
+		if agent.MaxTurns < 0 {
+			return fmt.Errorf("agent %s: max_turns must not be negative", agent.Name)
+		}
+		
+		if memory := agent.Memory; memory != nil && memory.Enabled {
+			if memory.Provider != "" && !isValidProvider(memory.Provider) {
+				return fmt.Errorf("agent %s: unsupported memory provider %s", agent.Name, memory.Provider)
+			}
+			if memory.EmbeddingModel == "" {
+				return fmt.Errorf("agent %s: memory embedding_model is required", agent.Name)
+			}
+			if memory.TopK < 0 {
+				return fmt.Errorf("agent %s: memory top_k must not be negative", agent.Name)
+			}
+			if memory.ScoreThreshold < -1 || memory.ScoreThreshold > 1 {
+				return fmt.Errorf("agent %s: memory score_threshold must be between -1 and 1", agent.Name)
+			}
+		}
+		
+		if knowledge := agent.Knowledge; knowledge != nil && knowledge.Enabled {
+			if err := validateKnowledge(knowledge); err != nil {
+				return fmt.Errorf("agent %s: knowledge: %w", agent.Name, err)
+			}
+		}
+		
+		if agent.Fallback != nil {
+			if !isValidProvider(agent.Fallback.Provider) {
+				return fmt.Errorf("agent %s: unsupported fallback provider %s", agent.Name, agent.Fallback.Provider)
+			}
+			if agent.Fallback.Model == "" {
+				return fmt.Errorf("agent %s: fallback model is required", agent.Name)
+			}
+		}
+		
+		for j, test := range agent.SmokeTests {
+			if test.Prompt == "" {
+				return fmt.Errorf("agent %s: smoke test %d: prompt is required", agent.Name, j)
+			}
+			if test.Expect.Matches != "" {
+				if _, err := regexp.Compile(test.Expect.Matches); err != nil {
+					return fmt.Errorf("agent %s: smoke test %d: invalid matches pattern: %w", agent.Name, j, err)
+				}
+			}
+		}
+		
 		for _, dep := range agent.DependsOn {
 			if !agentNames[dep] && dep != agent.Name {
 				return fmt.Errorf("agent %s: dependency %s not found", agent.Name, dep)
@@ -171,6 +558,52 @@ func (l *Loader) validateAgentCluster(cluster *AgentCluster) error {
 	return nil
 }
 
+// ValidateClusterNames refuses cluster, namespace and agent names that
+// cannot be told apart in IDs and URLs: a cluster outside the default
+// namespace is known as "namespace:name", and agents are addressed by
+// path.
+func ValidateClusterNames(cluster *AgentCluster) error {
+	if strings.ContainsAny(cluster.Metadata.Namespace, ":/") {
+		return fmt.Errorf("namespace %q must not contain ':' or '/'", cluster.Metadata.Namespace)
+	}
+	if strings.ContainsAny(cluster.Metadata.Name, ":/") {
+		return fmt.Errorf("cluster name %q must not contain ':' or '/'", cluster.Metadata.Name)
+	}
+	for _, agent := range cluster.Spec.Agents {
+		if strings.Contains(agent.Name, "/") {
+			return fmt.Errorf("agent name %q must not contain '/'", agent.Name)
+		}
+	}
+	return nil
+}
+
+func validateKnowledge(knowledge *AgentKnowledge) error {
+	if knowledge.Provider != "" && !isValidProvider(knowledge.Provider) {
+		return fmt.Errorf("unsupported provider %s", knowledge.Provider)
+	}
+	if knowledge.EmbeddingModel == "" {
+		return fmt.Errorf("embedding_model is required")
+	}
+	switch knowledge.Chunking.Strategy {
+	case "", "fixed", "markdown", "paragraph", "sentence":
+	default:
+		return fmt.Errorf("unsupported chunking strategy %s", knowledge.Chunking.Strategy)
+	}
+	if knowledge.Chunking.Size < 0 || knowledge.Chunking.Overlap < 0 {
+		return fmt.Errorf("chunking size and overlap must not be negative")
+	}
+	if knowledge.Chunking.Size > 0 && knowledge.Chunking.Overlap >= knowledge.Chunking.Size {
+		return fmt.Errorf("chunking overlap must be less than the size")
+	}
+	if knowledge.TopK < 0 {
+		return fmt.Errorf("top_k must not be negative")
+	}
+	if knowledge.ScoreThreshold < -1 || knowledge.ScoreThreshold > 1 {
+		return fmt.Errorf("score_threshold must be between -1 and 1")
+	}
+	return nil
+}
+
 func isValidProvider(provider string) bool {
 	validProviders := map[string]bool{
 		"anthropic": true,
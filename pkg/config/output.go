@@ -0,0 +1,57 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+var outputNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// AgentOutput makes an agent answer with a JSON object matching Schema.
+// Providers with a structured output mode are held to the schema, and
+// every reply is validated; one that does not match is sent back to the
+// model with the problems, up to MaxRetries times. Name identifies the
+// schema to the provider.
+type AgentOutput struct {
+	Name       string                 `yaml:"name,omitempty" json:"name,omitempty"`
+	Schema     map[string]interface{} `yaml:"schema" json:"schema"`
+	Strict     bool                   `yaml:"strict,omitempty" json:"strict,omitempty"`
+	MaxRetries int                    `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+}
+
+func (o *AgentOutput) validate() error {
+	if o.Name != "" && !outputNamePattern.MatchString(o.Name) {
+		return fmt.Errorf("name %q must be 1 to 64 letters, digits, underscores or dashes", o.Name)
+	}
+	if o.MaxRetries < 0 {
+		return fmt.Errorf("max_retries must not be negative")
+	}
+	return checkObjectSchema(o.Schema)
+}
+
+// checkObjectSchema checks that schema is a JSON schema for an object.
+func checkObjectSchema(schema map[string]interface{}) error {
+	if len(schema) == 0 {
+		return fmt.Errorf("schema is required")
+	}
+	if schemaType, ok := schema["type"]; ok && schemaType != "object" {
+		return fmt.Errorf("schema must describe an object, not %v", schemaType)
+	}
+	
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode schema: %w", err)
+	}
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("mem:///output.json", bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	if _, err := compiler.Compile("mem:///output.json"); err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,518 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrPolicyViolation is returned when a cluster or request uses a provider,
+// model or endpoint that the policy does not allow.
+var ErrPolicyViolation = errors.New("policy violation")
+
+// defaultProviderEndpoints are used for endpoint checks when a provider has
+// no base_url.
+var defaultProviderEndpoints = map[string]string{
+	"anthropic": "https://api.anthropic.com",
+	"openai":    "https://api.openai.com/v1",
+	"gemini":    "https://generativelanguage.googleapis.com",
+}
+
+// Validate checks that all patterns are well formed.
+func (p *PolicyConfig) Validate() error {
+	for _, list := range [][]string{p.AllowedModels, p.DeniedModels, p.AllowedEndpoints} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("policy: invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	
+	for _, provider := range p.AllowedProviders {
+		if !isValidProvider(provider) {
+			return fmt.Errorf("policy: unsupported provider %s", provider)
+		}
+	}
+	
+	if err := p.Exec.validate(); err != nil {
+		return fmt.Errorf("policy: exec: %w", err)
+	}
+	
+	if err := p.PromptInjection.validate(); err != nil {
+		return fmt.Errorf("policy: prompt_injection: %w", err)
+	}
+	
+	if err := p.SystemPrompt.validate(); err != nil {
+		return fmt.Errorf("policy: system_prompt: %w", err)
+	}
+	
+	if err := p.Outbound.validate(); err != nil {
+		return fmt.Errorf("policy: outbound: %w", err)
+	}
+	
+	if err := p.Secrets.validate(); err != nil {
+		return fmt.Errorf("policy: secrets: %w", err)
+	}
+	
+	return nil
+}
+
+// CheckProvider reports whether provider may be used.
+func (p *PolicyConfig) CheckProvider(provider string) error {
+	if len(p.AllowedProviders) > 0 && !containsFold(p.AllowedProviders, provider) {
+		return fmt.Errorf("%w: provider %s is not allowed", ErrPolicyViolation, provider)
+	}
+	return nil
+}
+
+// CheckModel reports whether provider and model may be used.
+func (p *PolicyConfig) CheckModel(provider, model string) error {
+	if err := p.CheckProvider(provider); err != nil {
+		return err
+	}
+	
+	if pattern, ok := matchAny(p.DeniedModels, model); ok {
+		return fmt.Errorf("%w: model %s is denied by pattern %q", ErrPolicyViolation, model, pattern)
+	}
+	
+	if len(p.AllowedModels) > 0 {
+		if _, ok := matchAny(p.AllowedModels, model); !ok {
+			return fmt.Errorf("%w: model %s is not allowed", ErrPolicyViolation, model)
+		}
+	}
+	
+	return nil
+}
+
+// CheckCluster checks the cluster's outbound and resource policies, its
+// quota, its workflows and schedules and every agent in the cluster,
+// including the length of its composed system prompt, the variables the
+// prompt uses, its output schema, guardrails, budget, health checks and
+// routes.
+func (p *PolicyConfig) CheckCluster(cluster *AgentCluster) error {
+	if cluster.Spec.Outbound != nil {
+		if err := cluster.Spec.Outbound.validate(); err != nil {
+			return fmt.Errorf("outbound: %w", err)
+		}
+	}
+	if err := cluster.Spec.ResourcePolicy.validate(cluster.Spec.Agents); err != nil {
+		return fmt.Errorf("resource_policy: %w", err)
+	}
+	if cluster.Spec.Quota != nil {
+		if err := cluster.Spec.Quota.validate(); err != nil {
+			return fmt.Errorf("quota: %w", err)
+		}
+	}
+	for _, agent := range cluster.Spec.Agents {
+		if err := p.CheckAgent(&agent); err != nil {
+			return err
+		}
+		for _, tool := range agent.Tools {
+			if err := p.Secrets.CheckAuth(cluster.Metadata.Namespace, tool.Auth); err != nil {
+				return fmt.Errorf("agent %s: tool %s: %w", agent.Name, tool.Name, err)
+			}
+		}
+		prompt, _, err := p.SystemPrompt.Compose(cluster.Spec.SystemPrompt, agent.SystemPrompt)
+		if err != nil {
+			return fmt.Errorf("agent %s: %w", agent.Name, err)
+		}
+		if err := checkPromptTemplate(cluster, &agent, prompt); err != nil {
+			return fmt.Errorf("agent %s: %w", agent.Name, err)
+		}
+		if agent.Output != nil {
+			if err := agent.Output.validate(); err != nil {
+				return fmt.Errorf("agent %s: output: %w", agent.Name, err)
+			}
+		}
+		if agent.Guardrails != nil {
+			if err := agent.Guardrails.validate(); err != nil {
+				return fmt.Errorf("agent %s: guardrails: %w", agent.Name, err)
+			}
+		}
+		if agent.Budget != nil {
+			if err := agent.Budget.validate(agent.Model); err != nil {
+				return fmt.Errorf("agent %s: budget: %w", agent.Name, err)
+			}
+		}
+		if agent.Health != nil {
+			if err := agent.Health.validate(); err != nil {
+				return fmt.Errorf("agent %s: health: %w", agent.Name, err)
+			}
+		}
+		if err := agent.Scaling.validate(); err != nil {
+			return fmt.Errorf("agent %s: scaling: %w", agent.Name, err)
+		}
+		if agent.Router != nil {
+			if err := checkRouter(cluster, &agent); err != nil {
+				return fmt.Errorf("agent %s: router: %w", agent.Name, err)
+			}
+		}
+	}
+	if err := checkWorkflows(cluster); err != nil {
+		return err
+	}
+	return checkSchedules(cluster)
+}
+
+// CheckAgent checks an agent's provider and model and those of its fallback
+// and experiment variants.
+func (p *PolicyConfig) CheckAgent(agent *Agent) error {
+	if err := p.CheckModel(agent.Provider, agent.Model); err != nil {
+		return fmt.Errorf("agent %s: %w", agent.Name, err)
+	}
+	if agent.Fallback != nil {
+		if err := p.CheckModel(agent.Fallback.Provider, agent.Fallback.Model); err != nil {
+			return fmt.Errorf("agent %s: fallback: %w", agent.Name, err)
+		}
+	}
+	if agent.Experiment != nil {
+		if err := p.checkExperiment(agent); err != nil {
+			return fmt.Errorf("agent %s: experiment: %w", agent.Name, err)
+		}
+	}
+	for _, tool := range agent.Tools {
+		if tool.Type != "exec" {
+			continue
+		}
+		if err := p.Exec.CheckTool(&tool); err != nil {
+			return fmt.Errorf("agent %s: tool %s: %w", agent.Name, tool.Name, err)
+		}
+	}
+	return nil
+}
+
+// CheckEndpoints checks the host of every configured provider's endpoint
+// against AllowedEndpoints.
+func (p *PolicyConfig) CheckEndpoints(providers *ProviderConfig) error {
+	if len(p.AllowedEndpoints) == 0 {
+		return nil
+	}
+	
+	endpoints := map[string]string{}
+	if providers.Anthropic != nil {
+		endpoints["anthropic"] = providers.Anthropic.BaseURL
+	}
+	if providers.OpenAI != nil {
+		endpoints["openai"] = providers.OpenAI.BaseURL
+	}
+	if providers.Gemini != nil {
+		endpoints["gemini"] = ""
+	}
+	
+	for name, endpoint := range endpoints {
+		if endpoint == "" {
+			endpoint = defaultProviderEndpoints[name]
+		}
+		
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return fmt.Errorf("provider %s: invalid base_url: %w", name, err)
+		}
+		if _, ok := matchAny(p.AllowedEndpoints, u.Hostname()); !ok {
+			return fmt.Errorf("provider %s: %w: endpoint %s is not allowed", name, ErrPolicyViolation, u.Hostname())
+		}
+	}
+	
+	return nil
+}
+
+func (p *OutboundPolicyConfig) validate() error {
+	for _, list := range [][]string{p.AllowedHosts, p.DeniedHosts} {
+		for _, pattern := range list {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *SecretsPolicyConfig) validate() error {
+	for _, dir := range p.FileDirs {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("file_dirs: %s is not an absolute path", dir)
+		}
+	}
+	for _, prefix := range p.EnvPrefixes {
+		if prefix == "" {
+			return fmt.Errorf("env_prefixes: empty prefix would allow every variable")
+		}
+	}
+	return nil
+}
+
+// CheckAuth checks the secret references in the auth of a tool of a
+// cluster in namespace.
+func (p *SecretsPolicyConfig) CheckAuth(namespace string, auth *AuthConfig) error {
+	if auth == nil {
+		return nil
+	}
+	for field, value := range map[string]string{
+		"token":   auth.Token,
+		"api_key": auth.APIKey,
+		"secret":  auth.Secret,
+	} {
+		if err := p.CheckRef(namespace, value); err != nil {
+			return fmt.Errorf("auth %s: %w", field, err)
+		}
+	}
+	return nil
+}
+
+// CheckRef checks a secret reference of a cluster in namespace. Values
+// that are not references are allowed.
+func (p *SecretsPolicyConfig) CheckRef(namespace, ref string) error {
+	if namespace == "" {
+		namespace = DefaultNamespace
+	}
+	
+	switch {
+	case strings.HasPrefix(ref, "env://"):
+		name := strings.TrimPrefix(ref, "env://")
+		for _, prefix := range p.EnvPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: environment variable %s is not allowed as a secret", ErrPolicyViolation, name)
+	case strings.HasPrefix(ref, "file://"):
+		// Dot-dot segments are refused rather than cleaned away, since
+		// the file is opened by the path as given
+		file := strings.TrimPrefix(ref, "file://")
+		if filepath.IsAbs(file) && !strings.Contains("/"+filepath.ToSlash(file)+"/", "/../") {
+			for _, dir := range p.FileDirs {
+				if rel, err := filepath.Rel(filepath.Clean(dir), file); err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+					return nil
+				}
+			}
+		}
+		return fmt.Errorf("%w: file %s is not allowed as a secret", ErrPolicyViolation, file)
+	case strings.HasPrefix(ref, "vault://"):
+		path, _, _ := strings.Cut(strings.TrimPrefix(ref, "vault://"), "#")
+		if owner, _, _ := strings.Cut(path, "/"); owner != namespace {
+			return fmt.Errorf("%w: vault credentials of namespace %s are not available to namespace %s", ErrPolicyViolation, owner, namespace)
+		}
+	}
+	return nil
+}
+
+func (p *PromptInjectionPolicyConfig) validate() error {
+	switch p.Mode {
+	case "", "off", "wrap", "sanitize", "block":
+	default:
+		return fmt.Errorf("invalid mode %q, expected off, wrap, sanitize or block", p.Mode)
+	}
+	
+	for _, pattern := range p.Patterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	
+	return nil
+}
+
+// PromptLayer reports the size of one part of a composed system prompt.
+type PromptLayer struct {
+	Name   string `json:"name"`
+	Length int    `json:"length"`
+}
+
+func (p *SystemPromptPolicyConfig) validate() error {
+	if p.MaxLength < 0 {
+		return fmt.Errorf("invalid max_length %d", p.MaxLength)
+	}
+	if length := utf8.RuneCountInString(p.Preamble); p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("preamble is %d characters, longer than max_length %d", length, p.MaxLength)
+	}
+	return nil
+}
+
+// Compose joins the preamble, the cluster's prompt and the agent's prompt,
+// in that order, separated by blank lines. Blank layers are left out, so an
+// agent with no other layers keeps its prompt unchanged. The layers are
+// returned with their lengths in characters, and the composed prompt is
+// refused if it is longer than MaxLength.
+func (p *SystemPromptPolicyConfig) Compose(clusterPrompt, agentPrompt string) (string, []PromptLayer, error) {
+	var parts []string
+	var layers []PromptLayer
+	for _, layer := range []struct {
+		name string
+		text string
+	}{
+		{"preamble", p.Preamble},
+		{"cluster", clusterPrompt},
+		{"agent", agentPrompt},
+	} {
+		if strings.TrimSpace(layer.text) == "" {
+			continue
+		}
+		if len(parts) > 0 {
+			parts[len(parts)-1] = strings.TrimRight(parts[len(parts)-1], "\n") + "\n\n"
+		}
+		parts = append(parts, layer.text)
+		layers = append(layers, PromptLayer{Name: layer.name, Length: utf8.RuneCountInString(layer.text)})
+	}
+	
+	prompt := strings.Join(parts, "")
+	if length := utf8.RuneCountInString(prompt); p.MaxLength > 0 && length > p.MaxLength {
+		return "", nil, fmt.Errorf("%w: system prompt is %d characters, longer than max_length %d", ErrPolicyViolation, length, p.MaxLength)
+	}
+	
+	return prompt, layers, nil
+}
+
+func (p *ExecPolicyConfig) validate() error {
+	if !p.Enabled {
+		return nil
+	}
+	
+	if len(p.AllowedCommands) == 0 || len(p.AllowedDirs) == 0 {
+		return fmt.Errorf("allowed_commands and allowed_dirs are required")
+	}
+	for _, dir := range p.AllowedDirs {
+		if !filepath.IsAbs(dir) {
+			return fmt.Errorf("allowed dir %s is not absolute", dir)
+		}
+	}
+	for _, size := range []string{p.MaxMemory, p.MaxOutput} {
+		if _, err := ParseByteSize(size); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+// CheckTool checks an exec tool's command, working directory and limits.
+// The tool's own limits are read from its config map: working_dir,
+// memory_limit, cpu_time and max_output.
+func (p *ExecPolicyConfig) CheckTool(tool *Tool) error {
+	if !p.Enabled {
+		return fmt.Errorf("%w: exec tools are disabled", ErrPolicyViolation)
+	}
+	
+	if len(tool.Command) == 0 {
+		return fmt.Errorf("command is required")
+	}
+	if !contains(p.AllowedCommands, tool.Command[0]) {
+		return fmt.Errorf("%w: command %s is not allowed", ErrPolicyViolation, tool.Command[0])
+	}
+	
+	dir := tool.Config["working_dir"]
+	if dir == "" || !filepath.IsAbs(dir) {
+		return fmt.Errorf("working_dir must be an absolute path")
+	}
+	if !WithinDirs(p.AllowedDirs, dir) {
+		return fmt.Errorf("%w: working_dir %s is outside the allowed dirs", ErrPolicyViolation, dir)
+	}
+	
+	if p.MaxTimeout > 0 && tool.Timeout > p.MaxTimeout {
+		return fmt.Errorf("%w: timeout %s exceeds %s", ErrPolicyViolation, tool.Timeout, p.MaxTimeout)
+	}
+	
+	if value := tool.Config["cpu_time"]; value != "" {
+		cpuTime, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid cpu_time: %w", err)
+		}
+		if p.MaxCPUTime > 0 && cpuTime > p.MaxCPUTime {
+			return fmt.Errorf("%w: cpu_time %s exceeds %s", ErrPolicyViolation, cpuTime, p.MaxCPUTime)
+		}
+	}
+	
+	for key, max := range map[string]string{"memory_limit": p.MaxMemory, "max_output": p.MaxOutput} {
+		value := tool.Config[key]
+		if value == "" {
+			continue
+		}
+		size, err := ParseByteSize(value)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", key, err)
+		}
+		maxSize, _ := ParseByteSize(max)
+		if maxSize > 0 && size > maxSize {
+			return fmt.Errorf("%w: %s %s exceeds %s", ErrPolicyViolation, key, value, max)
+		}
+	}
+	
+	return nil
+}
+
+// WithinDirs reports whether dir is one of dirs or below one of them.
+func WithinDirs(dirs []string, dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, root := range dirs {
+		rel, err := filepath.Rel(filepath.Clean(root), dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseByteSize parses a size such as "512Mi", "1G" or "65536". An empty
+// string is zero.
+func ParseByteSize(s string) (int64, error) {
+	original := s
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		value  int64
+	}{
+		{"Ki", 1 << 10}, {"Mi", 1 << 20}, {"Gi", 1 << 30},
+		{"K", 1000}, {"M", 1000 * 1000}, {"G", 1000 * 1000 * 1000},
+	} {
+		if strings.HasSuffix(s, unit.suffix) {
+			multiplier = unit.value
+			s = strings.TrimSuffix(s, unit.suffix)
+			break
+		}
+	}
+	
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", original)
+	}
+	return n * multiplier, nil
+}
+
+func matchAny(patterns []string, value string) (string, bool) {
+	value = strings.ToLower(value)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), value); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Quota caps what a cluster, or all the clusters of a namespace, may use.
+// MaxAgents bounds the agents in their specs, MaxRequestsPerMinute the
+// requests started in a minute, and MaxTokensPerDay and MaxCostPerDay what
+// their requests use in a UTC day. MaxCost is in US dollars and counts
+// models with a known price only. Zero leaves a limit off.
+type Quota struct {
+	MaxAgents            int     `yaml:"max_agents,omitempty" json:"max_agents,omitempty"`
+	MaxRequestsPerMinute int     `yaml:"max_requests_per_minute,omitempty" json:"max_requests_per_minute,omitempty"`
+	MaxTokensPerDay      int     `yaml:"max_tokens_per_day,omitempty" json:"max_tokens_per_day,omitempty"`
+	MaxCostPerDay        float64 `yaml:"max_cost_per_day,omitempty" json:"max_cost_per_day,omitempty"`
+}
+
+// NamespaceConfig configures a namespace. Its Quota covers all of its
+// clusters together, on top of each cluster's own.
+type NamespaceConfig struct {
+	Quota *Quota `yaml:"quota,omitempty" json:"quota,omitempty"`
+}
+
+func (q *Quota) validate() error {
+	if q.MaxAgents < 0 || q.MaxRequestsPerMinute < 0 || q.MaxTokensPerDay < 0 || q.MaxCostPerDay < 0 {
+		return fmt.Errorf("limits must not be negative")
+	}
+	return nil
+}
+
+func validateNamespacesConfig(namespaces map[string]NamespaceConfig) error {
+	for name, namespace := range namespaces {
+		if name == "" || strings.ContainsAny(name, ":/") {
+			return fmt.Errorf("namespace %q must be set and must not contain ':' or '/'", name)
+		}
+		if namespace.Quota != nil {
+			if err := namespace.Quota.validate(); err != nil {
+				return fmt.Errorf("%s: quota: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,51 @@
+package config
+
+import "fmt"
+
+// AgentRouter makes an agent a router. Each request sent to it is
+// classified by its model and handed, with the whole conversation, to the
+// route that suits it best. Requests that suit no route go to Default or,
+// without one, are answered by the router itself.
+type AgentRouter struct {
+	Routes  []Route `yaml:"routes" json:"routes"`
+	Default string  `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// Route is an agent a router can hand requests to. Description tells the
+// router's model what the agent handles; it defaults to the start of the
+// agent's system prompt.
+type Route struct {
+	Agent       string `yaml:"agent" json:"agent"`
+	Description string `yaml:"description,omitempty" json:"description,omitempty"`
+}
+
+// checkRouter checks that an agent's routes and default name other agents
+// of the cluster.
+func checkRouter(cluster *AgentCluster, agent *Agent) error {
+	agents := make(map[string]bool)
+	for _, other := range cluster.Spec.Agents {
+		agents[other.Name] = true
+	}
+	
+	if len(agent.Router.Routes) == 0 {
+		return fmt.Errorf("at least one route is required")
+	}
+	routes := make(map[string]bool)
+	for i, route := range agent.Router.Routes {
+		switch {
+		case route.Agent == "":
+			return fmt.Errorf("route %d: agent is required", i)
+		case route.Agent == agent.Name:
+			return fmt.Errorf("route %d: a router cannot route to itself", i)
+		case !agents[route.Agent]:
+			return fmt.Errorf("route %d: agent %s not found", i, route.Agent)
+		case routes[route.Agent]:
+			return fmt.Errorf("route %d: agent %s is routed to more than once", i, route.Agent)
+		}
+		routes[route.Agent] = true
+	}
+	if agent.Router.Default != "" && !agents[agent.Router.Default] {
+		return fmt.Errorf("default agent %s not found", agent.Router.Default)
+	}
+	return nil
+}
\ No newline at end of file
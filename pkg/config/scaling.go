@@ -0,0 +1,110 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// ResourcePolicy bounds the agents of a cluster. MaxConcurrentAgents caps
+// how many of them have instances at once; agents scaled to zero do not
+// count. Agents going IdleTimeout without requests are marked idle and,
+// with ScaleToZero, scaled to zero until their next request. Agents scaled
+// to zero release their tool connections, except the WarmPool agents that
+// had a request most recently.
+type ResourcePolicy struct {
+	MaxConcurrentAgents int           `yaml:"max_concurrent_agents" json:"max_concurrent_agents"`
+	IdleTimeout         time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
+	ScaleToZero         bool          `yaml:"scale_to_zero" json:"scale_to_zero"`
+	WarmPool            int           `yaml:"warm_pool,omitempty" json:"warm_pool,omitempty"`
+}
+
+func (r *ResourcePolicy) validate(agents []Agent) error {
+	if r.MaxConcurrentAgents < 0 || r.IdleTimeout < 0 || r.WarmPool < 0 {
+		return fmt.Errorf("max_concurrent_agents, idle_timeout and warm_pool must not be negative")
+	}
+	if r.WarmPool > 0 && !r.ScaleToZero {
+		return fmt.Errorf("warm_pool requires scale_to_zero")
+	}
+	if r.MaxConcurrentAgents == 0 {
+		return nil
+	}
+	
+	pinned := 0
+	for _, agent := range agents {
+		if agent.Scaling.MinInstances > 0 {
+			pinned++
+		}
+	}
+	if pinned > r.MaxConcurrentAgents {
+		return fmt.Errorf("%d agents set min_instances, more than max_concurrent_agents %d", pinned, r.MaxConcurrentAgents)
+	}
+	if len(agents) > r.MaxConcurrentAgents && !r.ScaleToZero {
+		return fmt.Errorf("%d agents are more than max_concurrent_agents %d, which requires scale_to_zero", len(agents), r.MaxConcurrentAgents)
+	}
+	return nil
+}
+
+// Scaling sets how many instances of an agent serve its requests. Each
+// instance takes up to MaxConcurrency requests at a time; requests that no
+// instance can take wait up to QueueTimeout for one to free up, at most
+// MaxQueueDepth of them at once.
+type Scaling struct {
+	MinInstances int `yaml:"min_instances,omitempty" json:"min_instances,omitempty"`
+	// MaxInstances caps scaling up; without it agents scale freely
+	MaxInstances int `yaml:"max_instances,omitempty" json:"max_instances,omitempty"`
+	// MaxConcurrency is the number of requests an instance serves at once;
+	// without it instances take any number and only spread the load
+	MaxConcurrency int           `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"`
+	QueueTimeout   time.Duration `yaml:"queue_timeout,omitempty" json:"queue_timeout,omitempty"`
+	// MaxQueueDepth bounds the requests waiting for an instance; 100 by
+	// default
+	MaxQueueDepth int `yaml:"max_queue_depth,omitempty" json:"max_queue_depth,omitempty"`
+	// Autoscale resizes the instances with the load, between MinInstances
+	// and MaxInstances
+	Autoscale *Autoscaling `yaml:"autoscale,omitempty" json:"autoscale,omitempty"`
+}
+
+// Autoscaling grows an agent's instances when the load of the last minute
+// exceeds any of its targets, in proportion to how far it does, and
+// shrinks them one at a time once the load would fit on fewer. Targets
+// left unset are not watched.
+type Autoscaling struct {
+	// QueueDepth is the number of queued requests per instance to stay
+	// within; 1 by default
+	QueueDepth int `yaml:"queue_depth,omitempty" json:"queue_depth,omitempty"`
+	// Latency is the 95th percentile request latency to stay within
+	Latency time.Duration `yaml:"p95_latency,omitempty" json:"p95_latency,omitempty"`
+	// TokensPerSecond is the token throughput per instance to stay within
+	TokensPerSecond float64 `yaml:"tokens_per_second,omitempty" json:"tokens_per_second,omitempty"`
+	// ScaleUpCooldown and ScaleDownCooldown are how long after the agent
+	// was last scaled it may grow or shrink again; 30s and 5m by default
+	ScaleUpCooldown   time.Duration `yaml:"scale_up_cooldown,omitempty" json:"scale_up_cooldown,omitempty"`
+	ScaleDownCooldown time.Duration `yaml:"scale_down_cooldown,omitempty" json:"scale_down_cooldown,omitempty"`
+}
+
+func (s *Scaling) validate() error {
+	if s.MinInstances < 0 || s.MaxInstances < 0 {
+		return fmt.Errorf("min_instances and max_instances must not be negative")
+	}
+	if s.MaxInstances > 0 && s.MaxInstances < s.MinInstances {
+		return fmt.Errorf("max_instances must not be less than min_instances")
+	}
+	if s.MaxConcurrency < 0 || s.QueueTimeout < 0 || s.MaxQueueDepth < 0 {
+		return fmt.Errorf("max_concurrency, queue_timeout and max_queue_depth must not be negative")
+	}
+	
+	autoscale := s.Autoscale
+	if autoscale == nil {
+		return nil
+	}
+	if s.MaxInstances == 0 {
+		return fmt.Errorf("autoscale requires max_instances")
+	}
+	if autoscale.QueueDepth < 0 || autoscale.Latency < 0 || autoscale.TokensPerSecond < 0 {
+		return fmt.Errorf("autoscale targets must not be negative")
+	}
+	if autoscale.ScaleUpCooldown < 0 || autoscale.ScaleDownCooldown < 0 {
+		return fmt.Errorf("autoscale cooldowns must not be negative")
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goagents/goagents/pkg/cron"
+)
+
+// Overlap policies
+const (
+	OverlapSkip    = "skip"
+	OverlapAllow   = "allow"
+	OverlapReplace = "replace"
+)
+
+// Schedule sends a prompt to one of the cluster's agents, or runs one of
+// its workflows, whenever its cron expression fires.
+type Schedule struct {
+	Name string `yaml:"name" json:"name"`
+	// Cron has five fields, minute, hour, day of month, month and day of
+	// week, or is a descriptor such as @daily or "@every 15m"
+	Cron string `yaml:"cron" json:"cron"`
+	// Timezone is an IANA name such as "Europe/Paris"; it defaults to UTC
+	Timezone string                 `yaml:"timezone,omitempty" json:"timezone,omitempty"`
+	Agent    string                 `yaml:"agent,omitempty" json:"agent,omitempty"`
+	Prompt   string                 `yaml:"prompt,omitempty" json:"prompt,omitempty"`
+	Context  map[string]interface{} `yaml:"context,omitempty" json:"context,omitempty"`
+	Workflow string                 `yaml:"workflow,omitempty" json:"workflow,omitempty"`
+	Input    map[string]interface{} `yaml:"input,omitempty" json:"input,omitempty"`
+	// Overlap decides what happens when a run is due while an earlier one
+	// is still running: skip the new run, the default, allow both, or
+	// replace the earlier one
+	Overlap string        `yaml:"overlap,omitempty" json:"overlap,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Location returns the time zone the schedule's cron expression is read in.
+func (s *Schedule) Location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(s.Timezone)
+}
+
+// checkSchedules checks the cluster's schedules: their names, cron
+// expressions and time zones, and that each runs an agent or a workflow
+// of the cluster.
+func checkSchedules(cluster *AgentCluster) error {
+	agents := make(map[string]bool)
+	for _, agent := range cluster.Spec.Agents {
+		agents[agent.Name] = true
+	}
+	workflows := make(map[string]bool)
+	for _, workflow := range cluster.Spec.Workflows {
+		workflows[workflow.Name] = true
+	}
+	
+	names := make(map[string]bool)
+	for i := range cluster.Spec.Schedules {
+		schedule := &cluster.Spec.Schedules[i]
+		if !workflowNamePattern.MatchString(schedule.Name) {
+			return fmt.Errorf("schedule %d: name must be letters, digits, '-' and '_'", i)
+		}
+		if names[schedule.Name] {
+			return fmt.Errorf("schedule %s: defined more than once", schedule.Name)
+		}
+		names[schedule.Name] = true
+		
+		if err := schedule.validate(agents, workflows); err != nil {
+			return fmt.Errorf("schedule %s: %w", schedule.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Schedule) validate(agents, workflows map[string]bool) error {
+	if _, err := cron.Parse(s.Cron); err != nil {
+		return fmt.Errorf("invalid cron expression: %w", err)
+	}
+	if _, err := s.Location(); err != nil {
+		return fmt.Errorf("invalid timezone: %w", err)
+	}
+	
+	switch {
+	case s.Agent != "" && s.Workflow != "":
+		return fmt.Errorf("agent and workflow are exclusive")
+	case s.Agent != "":
+		if !agents[s.Agent] {
+			return fmt.Errorf("agent %s not found", s.Agent)
+		}
+		if s.Prompt == "" {
+			return fmt.Errorf("prompt is required with agent")
+		}
+		if len(s.Input) > 0 {
+			return fmt.Errorf("input is only used with workflow")
+		}
+	case s.Workflow != "":
+		if !workflows[s.Workflow] {
+			return fmt.Errorf("workflow %s not found", s.Workflow)
+		}
+		if s.Prompt != "" || len(s.Context) > 0 {
+			return fmt.Errorf("prompt and context are only used with agent")
+		}
+	default:
+		return fmt.Errorf("agent or workflow is required")
+	}
+	
+	switch s.Overlap {
+	case "", OverlapSkip, OverlapAllow, OverlapReplace:
+	default:
+		return fmt.Errorf("unsupported overlap policy %q, expected skip, allow or replace", s.Overlap)
+	}
+	if s.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	return nil
+}
\ No newline at end of file
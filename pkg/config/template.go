@@ -0,0 +1,117 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// PromptVariable declares a variable system prompts can use. A request
+// must supply a Required variable through its context or session metadata,
+// or the agent's environment; other variables fall back to Default.
+type PromptVariable struct {
+	Default  string `yaml:"default,omitempty" json:"default,omitempty"`
+	Required bool   `yaml:"required,omitempty" json:"required,omitempty"`
+}
+
+// ParsePromptTemplate parses a system prompt written as a Go template,
+// such as "Answer in {{.locale}}.", and returns the variables it uses in
+// alphabetical order. Prompts without "{{" are not templates, and a nil
+// template is returned for them.
+func ParsePromptTemplate(prompt string) (*template.Template, []string, error) {
+	if !strings.Contains(prompt, "{{") {
+		return nil, nil, nil
+	}
+	
+	tmpl, err := template.New("system_prompt").Option("missingkey=zero").Parse(prompt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid system prompt template: %w", err)
+	}
+	
+	names := make(map[string]bool)
+	collectVariables(tmpl.Tree.Root, names)
+	variables := make([]string, 0, len(names))
+	for name := range names {
+		variables = append(variables, name)
+	}
+	sort.Strings(variables)
+	return tmpl, variables, nil
+}
+
+// collectVariables finds the fields of dot a template uses. Inside range
+// and with, dot is something else, so only their pipelines are searched.
+func collectVariables(node parse.Node, names map[string]bool) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			collectVariables(child, names)
+		}
+	case *parse.ActionNode:
+		collectVariables(n.Pipe, names)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, cmd := range n.Cmds {
+			collectVariables(cmd, names)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			collectVariables(arg, names)
+		}
+	case *parse.FieldNode:
+		names[n.Ident[0]] = true
+	case *parse.IfNode:
+		collectVariables(n.Pipe, names)
+		collectVariables(n.List, names)
+		collectVariables(n.ElseList, names)
+	case *parse.RangeNode:
+		collectVariables(n.Pipe, names)
+	case *parse.WithNode:
+		collectVariables(n.Pipe, names)
+	}
+}
+
+// PromptVariables merges the variables a cluster declares for all of its
+// agents with the agent's own, which take precedence.
+func PromptVariables(cluster *AgentCluster, agent *Agent) map[string]PromptVariable {
+	variables := make(map[string]PromptVariable, len(cluster.Spec.PromptVariables)+len(agent.PromptVariables))
+	for name, variable := range cluster.Spec.PromptVariables {
+		variables[name] = variable
+	}
+	for name, variable := range agent.PromptVariables {
+		variables[name] = variable
+	}
+	return variables
+}
+
+// checkPromptTemplate refuses a composed system prompt that does not parse
+// or uses variables that are neither declared nor in the agent's
+// environment, so a typo fails the deployment rather than a request.
+func checkPromptTemplate(cluster *AgentCluster, agent *Agent, prompt string) error {
+	_, used, err := ParsePromptTemplate(prompt)
+	if err != nil {
+		return err
+	}
+	
+	variables := PromptVariables(cluster, agent)
+	var undefined []string
+	for _, name := range used {
+		if _, declared := variables[name]; declared {
+			continue
+		}
+		if _, inEnv := agent.Environment[name]; inEnv {
+			continue
+		}
+		undefined = append(undefined, name)
+	}
+	if len(undefined) > 0 {
+		return fmt.Errorf("system prompt uses undeclared variables: %s", strings.Join(undefined, ", "))
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,45 @@
+package config
+
+import "fmt"
+
+// TranscriptsConfig captures transcripts of requests for debugging and
+// offline evaluation: the messages sent to the model, each model call with
+// its completion, usage and latency, and the tool calls made in between
+// with their results. SampleRate is the share of requests captured; 0
+// captures them all. Transcripts are appended to Path as JSON lines when it
+// is set, and the MaxEntries most recent, 1000 by default, are kept in
+// memory for the API. With Export, each transcript is also published to the
+// event sinks as a request.transcript event.
+type TranscriptsConfig struct {
+	Enabled    bool    `yaml:"enabled" json:"enabled"`
+	SampleRate float64 `yaml:"sample_rate,omitempty" json:"sample_rate,omitempty"`
+	// Redact lists what is hidden before a transcript is kept: secrets,
+	// such as API keys and tokens, pii for every kind of personal data, or
+	// single kinds of it such as email. Empty hides secrets and all
+	// personal data, and none keeps transcripts as they are
+	Redact     []string `yaml:"redact,omitempty" json:"redact,omitempty"`
+	Path       string   `yaml:"path,omitempty" json:"path,omitempty"`
+	MaxEntries int      `yaml:"max_entries,omitempty" json:"max_entries,omitempty"`
+	Export     bool     `yaml:"export,omitempty" json:"export,omitempty"`
+}
+
+func validateTranscriptsConfig(transcripts *TranscriptsConfig) error {
+	if transcripts.SampleRate < 0 || transcripts.SampleRate > 1 {
+		return fmt.Errorf("invalid sample_rate %v, must be between 0 and 1", transcripts.SampleRate)
+	}
+	if transcripts.MaxEntries < 0 {
+		return fmt.Errorf("max_entries must not be negative")
+	}
+	for _, redact := range transcripts.Redact {
+		switch {
+		case redact == "none":
+			if len(transcripts.Redact) > 1 {
+				return fmt.Errorf("redact none cannot be combined with other values")
+			}
+		case redact == "secrets", redact == "pii", contains(PIIKinds, redact):
+		default:
+			return fmt.Errorf("unsupported redact value %s, expected secrets, pii, none or a pii kind", redact)
+		}
+	}
+	return nil
+}
\ No newline at end of file
@@ -11,43 +11,156 @@ type AgentCluster struct {
 	Spec       AgentClusterSpec  `yaml:"spec" json:"spec"`
 }
 
+// DefaultNamespace holds the clusters that do not name a namespace.
+const DefaultNamespace = "default"
+
 type Metadata struct {
-	Name      string            `yaml:"name" json:"name"`
-	Namespace string            `yaml:"namespace" json:"namespace"`
-	Labels    map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Name string `yaml:"name" json:"name"`
+	// Namespace scopes the cluster's name, its tenant credentials and the
+	// API keys allowed to reach it
+	Namespace   string            `yaml:"namespace" json:"namespace"`
+	Labels      map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Annotations map[string]string `yaml:"annotations,omitempty" json:"annotations,omitempty"`
+	// ResourceVersion is set by the server and changes on every write to
+	// the cluster
+	ResourceVersion string `yaml:"resourceVersion,omitempty" json:"resourceVersion,omitempty"`
 }
 
 type AgentClusterSpec struct {
 	ResourcePolicy ResourcePolicy `yaml:"resource_policy" json:"resource_policy"`
-	Agents         []Agent        `yaml:"agents" json:"agents"`
+	RunSmokeTests  bool           `yaml:"run_smoke_tests,omitempty" json:"run_smoke_tests,omitempty"`
+	// StartupTimeout is how long agents wait for the agents they depend
+	// on to be ready when the cluster starts
+	StartupTimeout time.Duration `yaml:"startup_timeout,omitempty" json:"startup_timeout,omitempty"`
+	// SystemPrompt is placed after the policy preamble and before each
+	// agent's own system prompt
+	SystemPrompt string `yaml:"system_prompt,omitempty" json:"system_prompt,omitempty"`
+	// PromptVariables are declared for the system prompts of every agent
+	PromptVariables map[string]PromptVariable `yaml:"prompt_variables,omitempty" json:"prompt_variables,omitempty"`
+	// Outbound narrows the server's outbound policy for the cluster's tools
+	Outbound *OutboundPolicyConfig `yaml:"outbound,omitempty" json:"outbound,omitempty"`
+	Agents   []Agent               `yaml:"agents" json:"agents"`
+	// Workflows chain the cluster's agents and tools into runs started
+	// through the API
+	Workflows []Workflow `yaml:"workflows,omitempty" json:"workflows,omitempty"`
+	// Schedules run prompts or workflows on cron schedules
+	Schedules []Schedule `yaml:"schedules,omitempty" json:"schedules,omitempty"`
+	// Quota caps the cluster's agents, request rate and daily usage
+	Quota *Quota `yaml:"quota,omitempty" json:"quota,omitempty"`
+}
+
+type Agent struct {
+	Name           string            `yaml:"name" json:"name"`
+	Provider       string            `yaml:"provider" json:"provider"`
+	Model          string            `yaml:"model" json:"model"`
+	SystemPrompt   string            `yaml:"system_prompt,omitempty" json:"system_prompt,omitempty"`
+	// PromptVariables are the variables the system prompt may use, when it
+	// is a template
+	PromptVariables map[string]PromptVariable `yaml:"prompt_variables,omitempty" json:"prompt_variables,omitempty"`
+	ThinkingBudget int               `yaml:"thinking_budget,omitempty" json:"thinking_budget,omitempty"`
+	// MaxTurns bounds the model calls of a request; the model is called
+	// again with the results each time it uses tools
+	MaxTurns       int               `yaml:"max_turns,omitempty" json:"max_turns,omitempty"`
+	Tools          []Tool            `yaml:"tools,omitempty" json:"tools,omitempty"`
+	Resources      Resources         `yaml:"resources,omitempty" json:"resources,omitempty"`
+	Scaling        Scaling           `yaml:"scaling,omitempty" json:"scaling,omitempty"`
+	DependsOn      []string          `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	Environment    map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+	Cache          *AgentCache       `yaml:"cache,omitempty" json:"cache,omitempty"`
+	Fallback       *AgentFallback    `yaml:"fallback,omitempty" json:"fallback,omitempty"`
+	Memory         *AgentMemory      `yaml:"memory,omitempty" json:"memory,omitempty"`
+	Knowledge      *AgentKnowledge   `yaml:"knowledge,omitempty" json:"knowledge,omitempty"`
+	Output         *AgentOutput      `yaml:"output,omitempty" json:"output,omitempty"`
+	Router         *AgentRouter      `yaml:"router,omitempty" json:"router,omitempty"`
+	Guardrails     *AgentGuardrails  `yaml:"guardrails,omitempty" json:"guardrails,omitempty"`
+	Budget         *AgentBudget      `yaml:"budget,omitempty" json:"budget,omitempty"`
+	Health         *AgentHealth      `yaml:"health,omitempty" json:"health,omitempty"`
+	Experiment     *AgentExperiment  `yaml:"experiment,omitempty" json:"experiment,omitempty"`
+	SmokeTests     []SmokeTest       `yaml:"smoke_tests,omitempty" json:"smoke_tests,omitempty"`
 }
 
-type ResourcePolicy struct {
-	MaxConcurrentAgents int           `yaml:"max_concurrent_agents" json:"max_concurrent_agents"`
-	IdleTimeout         time.Duration `yaml:"idle_timeout" json:"idle_timeout"`
-	ScaleToZero         bool          `yaml:"scale_to_zero" json:"scale_to_zero"`
+type AgentCache struct {
+	Enabled bool          `yaml:"enabled" json:"enabled"`
+	TTL     time.Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`
 }
 
-type Agent struct {
-	Name         string            `yaml:"name" json:"name"`
-	Provider     string            `yaml:"provider" json:"provider"`
-	Model        string            `yaml:"model" json:"model"`
-	SystemPrompt string            `yaml:"system_prompt,omitempty" json:"system_prompt,omitempty"`
-	Tools        []Tool            `yaml:"tools,omitempty" json:"tools,omitempty"`
-	Resources    Resources         `yaml:"resources,omitempty" json:"resources,omitempty"`
-	Scaling      Scaling           `yaml:"scaling,omitempty" json:"scaling,omitempty"`
-	DependsOn    []string          `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
-	Environment  map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+// AgentFallback names the provider and model to use when every credential
+// of the agent's primary provider has been revoked.
+type AgentFallback struct {
+	Provider string `yaml:"provider" json:"provider"`
+	Model    string `yaml:"model" json:"model"`
+}
+
+// AgentMemory turns on long-term memory for an agent. Facts worth keeping
+// are taken from its conversations and stored with their embeddings, and
+// the TopK closest to a new request that score at least ScoreThreshold are
+// added to the prompt. Embeddings come from EmbeddingModel on Provider, or
+// on the agent's provider when Provider is empty.
+type AgentMemory struct {
+	Enabled        bool    `yaml:"enabled" json:"enabled"`
+	Provider       string  `yaml:"provider,omitempty" json:"provider,omitempty"`
+	EmbeddingModel string  `yaml:"embedding_model" json:"embedding_model"`
+	TopK           int     `yaml:"top_k,omitempty" json:"top_k,omitempty"`
+	ScoreThreshold float64 `yaml:"score_threshold,omitempty" json:"score_threshold,omitempty"`
+}
+
+// AgentKnowledge gives an agent a knowledge base. Ingested documents are
+// split into chunks by Chunking and embedded with EmbeddingModel on
+// Provider, or on the agent's provider when Provider is empty. The TopK
+// chunks closest to a request that score at least ScoreThreshold are added
+// to the prompt as numbered sources the model can cite.
+type AgentKnowledge struct {
+	Enabled        bool              `yaml:"enabled" json:"enabled"`
+	Provider       string            `yaml:"provider,omitempty" json:"provider,omitempty"`
+	EmbeddingModel string            `yaml:"embedding_model" json:"embedding_model"`
+	Chunking       KnowledgeChunking `yaml:"chunking,omitempty" json:"chunking,omitempty"`
+	TopK           int               `yaml:"top_k,omitempty" json:"top_k,omitempty"`
+	ScoreThreshold float64           `yaml:"score_threshold,omitempty" json:"score_threshold,omitempty"`
+}
+
+// KnowledgeChunking sets how documents are split: fixed windows of
+// characters, or paragraphs, sentences or markdown sections packed into
+// chunks of up to Size characters. Overlap is how much of a chunk is
+// repeated at the start of the next.
+type KnowledgeChunking struct {
+	Strategy string `yaml:"strategy,omitempty" json:"strategy,omitempty"`
+	Size     int    `yaml:"size,omitempty" json:"size,omitempty"`
+	Overlap  int    `yaml:"overlap,omitempty" json:"overlap,omitempty"`
+}
+
+// SmokeTest is a prompt sent to an agent right after deployment. The
+// response must satisfy Expect or the agent is marked degraded.
+type SmokeTest struct {
+	Name    string          `yaml:"name,omitempty" json:"name,omitempty"`
+	Prompt  string          `yaml:"prompt" json:"prompt"`
+	Timeout time.Duration   `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Expect  SmokeTestExpect `yaml:"expect,omitempty" json:"expect,omitempty"`
+}
+
+type SmokeTestExpect struct {
+	Contains    []string      `yaml:"contains,omitempty" json:"contains,omitempty"`
+	NotContains []string      `yaml:"not_contains,omitempty" json:"not_contains,omitempty"`
+	Matches     string        `yaml:"matches,omitempty" json:"matches,omitempty"`
+	MaxLength   int           `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+	MaxLatency  time.Duration `yaml:"max_latency,omitempty" json:"max_latency,omitempty"`
 }
 
 type Tool struct {
-	Type     string            `yaml:"type" json:"type"`
-	Name     string            `yaml:"name" json:"name"`
-	URL      string            `yaml:"url,omitempty" json:"url,omitempty"`
-	Endpoint string            `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
-	Server   string            `yaml:"server,omitempty" json:"server,omitempty"`
-	Auth     *AuthConfig       `yaml:"auth,omitempty" json:"auth,omitempty"`
-	Config   map[string]string `yaml:"config,omitempty" json:"config,omitempty"`
+	Type      string            `yaml:"type" json:"type"`
+	Name      string            `yaml:"name" json:"name"`
+	URL       string            `yaml:"url,omitempty" json:"url,omitempty"`
+	Endpoint  string            `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Server    string            `yaml:"server,omitempty" json:"server,omitempty"`
+	Transport string            `yaml:"transport,omitempty" json:"transport,omitempty"`
+	Command   []string          `yaml:"command,omitempty" json:"command,omitempty"`
+	Args      []string          `yaml:"args,omitempty" json:"args,omitempty"`
+	Env       map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	Timeout   time.Duration     `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	Auth      *AuthConfig       `yaml:"auth,omitempty" json:"auth,omitempty"`
+	Config    map[string]string `yaml:"config,omitempty" json:"config,omitempty"`
+	// Parameters is the JSON schema of the tool's arguments, sent to the
+	// model in place of the tool's own and checked on every call
+	Parameters map[string]interface{} `yaml:"parameters,omitempty" json:"parameters,omitempty"`
 }
 
 type AuthConfig struct {
@@ -63,23 +176,55 @@ type Resources struct {
 	Timeout     time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
 }
 
-type Scaling struct {
-	MinInstances int `yaml:"min_instances,omitempty" json:"min_instances,omitempty"`
-	MaxInstances int `yaml:"max_instances,omitempty" json:"max_instances,omitempty"`
+type ServerConfig struct {
+	Host            string        `yaml:"host" json:"host"`
+	Port            int           `yaml:"port" json:"port"`
+	Timeout         time.Duration `yaml:"timeout" json:"timeout"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" json:"shutdown_timeout"`
+	LogLevel        string        `yaml:"log_level" json:"log_level"`
+	ReadOnly        bool          `yaml:"read_only" json:"read_only"`
+	Metrics         MetricsConfig `yaml:"metrics" json:"metrics"`
+	MCP             MCPConfig     `yaml:"mcp" json:"mcp"`
+	Tracing         TracingConfig `yaml:"tracing" json:"tracing"`
 }
 
-type ServerConfig struct {
-	Host     string        `yaml:"host" json:"host"`
-	Port     int           `yaml:"port" json:"port"`
-	Timeout  time.Duration `yaml:"timeout" json:"timeout"`
-	LogLevel string        `yaml:"log_level" json:"log_level"`
-	Metrics  MetricsConfig `yaml:"metrics" json:"metrics"`
+// MCPConfig publishes deployed agents as MCP tools on the /mcp endpoint.
+type MCPConfig struct {
+	Enabled bool          `yaml:"enabled" json:"enabled"`
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// TracingConfig exports OpenTelemetry traces of requests, from the API
+// handler through the engine to model calls and tool executions, to a
+// collector over OTLP/HTTP.
+type TracingConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Endpoint is the collector's host:port, or a URL with the path to
+	// post traces to. Empty leaves it to OTEL_EXPORTER_OTLP_ENDPOINT, or
+	// localhost:4318
+	Endpoint    string            `yaml:"endpoint,omitempty" json:"endpoint,omitempty"`
+	Insecure    bool              `yaml:"insecure,omitempty" json:"insecure,omitempty"`
+	Headers     map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	ServiceName string            `yaml:"service_name,omitempty" json:"service_name,omitempty"`
+	// SampleRatio is the share of new traces recorded; traces continued
+	// from a caller follow its decision. 0 records them all
+	SampleRatio float64 `yaml:"sample_ratio,omitempty" json:"sample_ratio,omitempty"`
 }
 
 type MetricsConfig struct {
-	Enabled bool   `yaml:"enabled" json:"enabled"`
-	Path    string `yaml:"path" json:"path"`
-	Port    int    `yaml:"port" json:"port"`
+	Enabled      bool               `yaml:"enabled" json:"enabled"`
+	Path         string             `yaml:"path" json:"path"`
+	Port         int                `yaml:"port" json:"port"`
+	ColdStartSLO ColdStartSLOConfig `yaml:"cold_start_slo" json:"cold_start_slo"`
+}
+
+// ColdStartSLOConfig sets the latency objective for cold starts. A cold start
+// slower than its threshold counts against the error budget.
+type ColdStartSLOConfig struct {
+	Threshold time.Duration `yaml:"threshold" json:"threshold"`
+	Objective float64       `yaml:"objective" json:"objective"`
+	// Thresholds overrides Threshold per kind, e.g. mcp_handshake
+	Thresholds map[string]time.Duration `yaml:"thresholds,omitempty" json:"thresholds,omitempty"`
 }
 
 type ProviderConfig struct {
@@ -89,24 +234,364 @@ type ProviderConfig struct {
 }
 
 type AnthropicConfig struct {
-	APIKey  string `yaml:"api_key" json:"api_key"`
-	BaseURL string `yaml:"base_url,omitempty" json:"base_url,omitempty"`
-	Version string `yaml:"version,omitempty" json:"version,omitempty"`
+	APIKey        string            `yaml:"api_key" json:"api_key"`
+	BackupAPIKeys []string          `yaml:"backup_api_keys,omitempty" json:"backup_api_keys,omitempty"`
+	BaseURL       string            `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+	Version       string            `yaml:"version,omitempty" json:"version,omitempty"`
+	HTTP          *HTTPClientConfig `yaml:"http,omitempty" json:"http,omitempty"`
 }
 
 type OpenAIConfig struct {
-	APIKey  string `yaml:"api_key" json:"api_key"`
-	BaseURL string `yaml:"base_url,omitempty" json:"base_url,omitempty"`
-	OrgID   string `yaml:"org_id,omitempty" json:"org_id,omitempty"`
+	APIKey        string            `yaml:"api_key" json:"api_key"`
+	BackupAPIKeys []string          `yaml:"backup_api_keys,omitempty" json:"backup_api_keys,omitempty"`
+	BaseURL       string            `yaml:"base_url,omitempty" json:"base_url,omitempty"`
+	OrgID         string            `yaml:"org_id,omitempty" json:"org_id,omitempty"`
+	HTTP          *HTTPClientConfig `yaml:"http,omitempty" json:"http,omitempty"`
 }
 
 type GeminiConfig struct {
-	APIKey    string `yaml:"api_key" json:"api_key"`
-	ProjectID string `yaml:"project_id,omitempty" json:"project_id,omitempty"`
+	APIKey        string            `yaml:"api_key" json:"api_key"`
+	BackupAPIKeys []string          `yaml:"backup_api_keys,omitempty" json:"backup_api_keys,omitempty"`
+	ProjectID     string            `yaml:"project_id,omitempty" json:"project_id,omitempty"`
+	HTTP          *HTTPClientConfig `yaml:"http,omitempty" json:"http,omitempty"`
+}
+
+type HTTPClientConfig struct {
+	ProxyURL           string        `yaml:"proxy_url,omitempty" json:"proxy_url,omitempty"`
+	CABundle           string        `yaml:"ca_bundle,omitempty" json:"ca_bundle,omitempty"`
+	InsecureSkipVerify bool          `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+	ConnectTimeout     time.Duration `yaml:"connect_timeout,omitempty" json:"connect_timeout,omitempty"`
+	ReadTimeout        time.Duration `yaml:"read_timeout,omitempty" json:"read_timeout,omitempty"`
+	Timeout            time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	MaxIdleConns       int           `yaml:"max_idle_conns,omitempty" json:"max_idle_conns,omitempty"`
+}
+
+type CacheConfig struct {
+	Backend    string        `yaml:"backend" json:"backend"`
+	MaxEntries int           `yaml:"max_entries,omitempty" json:"max_entries,omitempty"`
+	TTL        time.Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	Redis      *RedisConfig  `yaml:"redis,omitempty" json:"redis,omitempty"`
+}
+
+// FilesConfig configures the store for binary content returned by tools,
+// such as PDFs and images.
+type FilesConfig struct {
+	Backend string        `yaml:"backend" json:"backend"`
+	Dir     string        `yaml:"dir,omitempty" json:"dir,omitempty"`
+	MaxSize string        `yaml:"max_size,omitempty" json:"max_size,omitempty"`
+	TTL     time.Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+// VaultConfig enables tenants to store their own provider API keys,
+// encrypted per namespace. Clusters use their namespace's keys in place of
+// the server's keys for providers the namespace has credentials for.
+type VaultConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// MasterKey is a base64 encoded 32 byte key that wraps each namespace's
+	// data key
+	MasterKey string `yaml:"master_key,omitempty" json:"-"`
+	// Path persists the vault; without it credentials are lost on restart
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+}
+
+// SessionsConfig configures the conversations whose history the server
+// keeps, and the state agents keep across them. Sessions are kept in
+// memory, in the SQLite database at Path, in Redis, or in the Postgres
+// database at DSN. Sessions expire after TTL without a message and keep
+// their MaxMessages most recent messages. MaxHistoryTokens bounds the
+// estimated size of the history sent to the model with each message; older
+// messages are left out to fit.
+type SessionsConfig struct {
+	Backend          string               `yaml:"backend" json:"backend"`
+	Path             string               `yaml:"path,omitempty" json:"path,omitempty"`
+	DSN              string               `yaml:"dsn,omitempty" json:"dsn,omitempty"`
+	Redis            *RedisConfig         `yaml:"redis,omitempty" json:"redis,omitempty"`
+	TTL              time.Duration        `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+	MaxMessages      int                  `yaml:"max_messages,omitempty" json:"max_messages,omitempty"`
+	MaxHistoryTokens int                  `yaml:"max_history_tokens,omitempty" json:"max_history_tokens,omitempty"`
+	Summarize        SessionSummaryConfig `yaml:"summarize" json:"summarize"`
+}
+
+
+// SessionSummaryConfig compresses the earlier turns of a session into a
+// summary once its history reaches Threshold, a fraction of
+// MaxHistoryTokens. The KeepMessages most recent messages are kept as they
+// are. The summary is written by Provider and Model, or by the session's
+// agent when they are not set, in at most MaxTokens tokens.
+type SessionSummaryConfig struct {
+	Enabled      bool    `yaml:"enabled" json:"enabled"`
+	Provider     string  `yaml:"provider,omitempty" json:"provider,omitempty"`
+	Model        string  `yaml:"model,omitempty" json:"model,omitempty"`
+	Threshold    float64 `yaml:"threshold,omitempty" json:"threshold,omitempty"`
+	KeepMessages int     `yaml:"keep_messages,omitempty" json:"keep_messages,omitempty"`
+	MaxTokens    int     `yaml:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+}
+
+// FeedbackConfig configures how user feedback on responses is kept. Feedback
+// is appended to Path when set; otherwise it lives in memory only.
+type FeedbackConfig struct {
+	Path string `yaml:"path,omitempty" json:"path,omitempty"`
+	// MaxResponses is how many recent responses can receive feedback
+	MaxResponses int `yaml:"max_responses,omitempty" json:"max_responses,omitempty"`
+	// Rubric limits scores to these criteria when set
+	Rubric   []string `yaml:"rubric,omitempty" json:"rubric,omitempty"`
+	MaxScore float64  `yaml:"max_score,omitempty" json:"max_score,omitempty"`
+}
+
+// MemoryConfig configures where the long-term memories of agents are kept.
+// Memories are saved to Path when set; otherwise they live in memory only.
+// MaxEntries bounds the memories of each agent, dropping the least recently
+// used first.
+type MemoryConfig struct {
+	Path       string `yaml:"path,omitempty" json:"path,omitempty"`
+	MaxEntries int    `yaml:"max_entries,omitempty" json:"max_entries,omitempty"`
+}
+
+// KnowledgeConfig configures where agents' knowledge bases are kept.
+// Documents are saved to Path when set; otherwise they live in memory
+// only. MaxDocumentSize bounds the documents that can be ingested, in
+// bytes.
+type KnowledgeConfig struct {
+	Path            string `yaml:"path,omitempty" json:"path,omitempty"`
+	MaxDocumentSize int64  `yaml:"max_document_size,omitempty" json:"max_document_size,omitempty"`
+}
+
+// JobsConfig configures the queue of asynchronous agent jobs. Workers jobs
+// run at once and up to MaxQueued more wait their turn. Jobs are saved to
+// Path when set, so results survive restarts; otherwise they live in memory
+// only. Finished jobs are kept for TTL.
+type JobsConfig struct {
+	Path      string        `yaml:"path,omitempty" json:"path,omitempty"`
+	Workers   int           `yaml:"workers,omitempty" json:"workers,omitempty"`
+	MaxQueued int           `yaml:"max_queued,omitempty" json:"max_queued,omitempty"`
+	TTL       time.Duration `yaml:"ttl,omitempty" json:"ttl,omitempty"`
+}
+
+// StateConfig configures where deployed clusters are saved, with their
+// agents and statuses, so a restarted server restores them. Clusters are
+// saved in the SQLite database at Path, by default, or in the Postgres
+// database at DSN; with the memory backend they are lost on restart.
+type StateConfig struct {
+	Backend string `yaml:"backend" json:"backend"`
+	Path    string `yaml:"path,omitempty" json:"path,omitempty"`
+	DSN     string `yaml:"dsn,omitempty" json:"dsn,omitempty"`
+}
+
+type RedisConfig struct {
+	Addr      string `yaml:"addr" json:"addr"`
+	Password  string `yaml:"password,omitempty" json:"password,omitempty"`
+	DB        int    `yaml:"db,omitempty" json:"db,omitempty"`
+	KeyPrefix string `yaml:"key_prefix,omitempty" json:"key_prefix,omitempty"`
+}
+
+// SecurityConfig configures who may call the API.
+type SecurityConfig struct {
+	Auth APIAuthConfig `yaml:"auth" json:"auth"`
+}
+
+// APIAuthConfig requires API requests to carry one of APIKeys, in an
+// "Authorization: Bearer" or X-API-Key header, once Enabled.
+type APIAuthConfig struct {
+	Enabled bool `yaml:"enabled" json:"enabled"`
+	// Method is how requests authenticate; only api_key is supported
+	Method  string         `yaml:"method,omitempty" json:"method,omitempty"`
+	APIKeys []APIKeyConfig `yaml:"api_keys,omitempty" json:"api_keys,omitempty"`
+}
+
+// APIKeyConfig is an API key and the namespaces it may reach. "*" stands
+// for every namespace and is needed for the endpoints, such as
+// administration, that belong to no namespace.
+type APIKeyConfig struct {
+	Name       string   `yaml:"name" json:"name"`
+	Key        string   `yaml:"key" json:"-"`
+	Namespaces []string `yaml:"namespaces" json:"namespaces"`
 }
 
 type Config struct {
-	Server    ServerConfig    `yaml:"server" json:"server"`
-	Providers ProviderConfig  `yaml:"providers" json:"providers"`
-	Clusters  []AgentCluster  `yaml:"clusters" json:"clusters"`
+	Server    ServerConfig                 `yaml:"server" json:"server"`
+	Security  SecurityConfig               `yaml:"security" json:"security"`
+	Providers ProviderConfig               `yaml:"providers" json:"providers"`
+	Cache     CacheConfig                  `yaml:"cache" json:"cache"`
+	Files     FilesConfig                  `yaml:"files" json:"files"`
+	Vault     VaultConfig                  `yaml:"vault" json:"vault"`
+	Feedback  FeedbackConfig               `yaml:"feedback" json:"feedback"`
+	Sessions  SessionsConfig               `yaml:"sessions" json:"sessions"`
+	Memory    MemoryConfig                 `yaml:"memory" json:"memory"`
+	Knowledge KnowledgeConfig              `yaml:"knowledge" json:"knowledge"`
+	Jobs      JobsConfig                   `yaml:"jobs" json:"jobs"`
+	State     StateConfig                  `yaml:"state" json:"state"`
+	Gateways  GatewaysConfig               `yaml:"gateways" json:"gateways"`
+	Policy    PolicyConfig                 `yaml:"policy" json:"policy"`
+	Tools     ToolsConfig                  `yaml:"tools" json:"tools"`
+	Features  map[string]FeatureFlagConfig `yaml:"features,omitempty" json:"features,omitempty"`
+	// Events sends engine, cluster, agent and request events to sinks
+	Events EventsConfig `yaml:"events" json:"events"`
+	// Webhooks posts signed lifecycle events to webhooks
+	Webhooks WebhooksConfig `yaml:"webhooks" json:"webhooks"`
+	// Transcripts captures the model and tool calls of sampled requests
+	Transcripts TranscriptsConfig `yaml:"transcripts" json:"transcripts"`
+	// Distributed runs the server as one node of several
+	Distributed DistributedConfig `yaml:"distributed" json:"distributed"`
+	// Namespaces configures namespaces by name, such as their quotas
+	Namespaces map[string]NamespaceConfig `yaml:"namespaces,omitempty" json:"namespaces,omitempty"`
+	Clusters   []AgentCluster             `yaml:"clusters" json:"clusters"`
+}
+
+// ToolsConfig sets how tool calls run across all agents. MaxConcurrency
+// bounds the calls running at once; zero uses the default of 64 and a
+// negative value removes the bound. SecretRefreshInterval is how often
+// secret references in tool auth are resolved again to pick up rotated
+// secrets; zero uses the default of a minute and a negative value turns
+// refreshing off.
+type ToolsConfig struct {
+	MaxConcurrency        int             `yaml:"max_concurrency,omitempty" json:"max_concurrency,omitempty"`
+	SecretRefreshInterval time.Duration   `yaml:"secret_refresh_interval,omitempty" json:"secret_refresh_interval,omitempty"`
+	Audit                 ToolAuditConfig `yaml:"audit" json:"audit"`
+}
+
+// ToolAuditConfig configures the record kept of every tool call. Records
+// are appended to Path when set, and the MaxEntries most recent are kept in
+// memory for the API. Args is redacted, to keep arguments with sensitive
+// values hidden, or hash, to keep only a hash of them.
+type ToolAuditConfig struct {
+	Path       string `yaml:"path,omitempty" json:"path,omitempty"`
+	MaxEntries int    `yaml:"max_entries,omitempty" json:"max_entries,omitempty"`
+	Args       string `yaml:"args,omitempty" json:"args,omitempty"`
+}
+
+// FeatureFlagConfig turns an experimental behavior on or off. Overrides
+// apply to one cluster, or one agent in it, and take precedence over
+// Enabled; an agent override wins over a cluster override.
+type FeatureFlagConfig struct {
+	Enabled   bool                  `yaml:"enabled" json:"enabled"`
+	Overrides []FeatureFlagOverride `yaml:"overrides,omitempty" json:"overrides,omitempty"`
+}
+
+type FeatureFlagOverride struct {
+	Cluster string `yaml:"cluster" json:"cluster"`
+	Agent   string `yaml:"agent,omitempty" json:"agent,omitempty"`
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+}
+
+// PolicyConfig restricts the providers, models and provider endpoints that
+// any cluster may use. Model and endpoint patterns use shell glob syntax,
+// e.g. "claude-*" or "*preview*". Empty lists impose no restriction.
+type PolicyConfig struct {
+	AllowedProviders []string         `yaml:"allowed_providers,omitempty" json:"allowed_providers,omitempty"`
+	AllowedModels    []string         `yaml:"allowed_models,omitempty" json:"allowed_models,omitempty"`
+	DeniedModels     []string         `yaml:"denied_models,omitempty" json:"denied_models,omitempty"`
+	AllowedEndpoints []string         `yaml:"allowed_endpoints,omitempty" json:"allowed_endpoints,omitempty"`
+	Exec             ExecPolicyConfig `yaml:"exec" json:"exec"`
+	// PromptInjection screens tool results before they reach a model
+	PromptInjection PromptInjectionPolicyConfig `yaml:"prompt_injection" json:"prompt_injection"`
+	SystemPrompt    SystemPromptPolicyConfig    `yaml:"system_prompt" json:"system_prompt"`
+	// Outbound limits the hosts that http and browser tools may reach
+	Outbound OutboundPolicyConfig `yaml:"outbound" json:"outbound"`
+	// Secrets limits the secrets tool auth may reference
+	Secrets SecretsPolicyConfig `yaml:"secrets" json:"secrets"`
+}
+
+// SecretsPolicyConfig limits the secrets tool auth may reference, since a
+// tool sends the secrets it resolves wherever it calls. env:// references
+// must name a variable starting with one of EnvPrefixes, and file://
+// references a file inside one of FileDirs; without them, such references
+// are refused. vault:// references may only name credentials of the
+// cluster's own namespace.
+type SecretsPolicyConfig struct {
+	EnvPrefixes []string `yaml:"env_prefixes,omitempty" json:"env_prefixes,omitempty"`
+	FileDirs    []string `yaml:"file_dirs,omitempty" json:"file_dirs,omitempty"`
+}
+
+// ExecPolicyConfig gates the exec tool. Exec tools are refused unless
+// Enabled is set, and may only run AllowedCommands from a working directory
+// inside AllowedDirs. Tool limits may not exceed the Max values, which also
+// apply to tools that set no limit of their own.
+type ExecPolicyConfig struct {
+	Enabled         bool          `yaml:"enabled" json:"enabled"`
+	AllowedCommands []string      `yaml:"allowed_commands,omitempty" json:"allowed_commands,omitempty"`
+	AllowedDirs     []string      `yaml:"allowed_dirs,omitempty" json:"allowed_dirs,omitempty"`
+	MaxTimeout      time.Duration `yaml:"max_timeout" json:"max_timeout"`
+	MaxCPUTime      time.Duration `yaml:"max_cpu_time,omitempty" json:"max_cpu_time,omitempty"`
+	MaxMemory       string        `yaml:"max_memory" json:"max_memory"`
+	MaxOutput       string        `yaml:"max_output" json:"max_output"`
+}
+
+// PromptInjectionPolicyConfig sets what happens to tool results that look
+// like instructions aimed at the model: off, wrap, sanitize or block. Tools
+// may choose a stricter mode with their prompt_injection config key, never a
+// weaker one. Patterns are regular expressions checked in addition to the
+// built-in ones.
+type PromptInjectionPolicyConfig struct {
+	Mode     string   `yaml:"mode" json:"mode"`
+	Patterns []string `yaml:"patterns,omitempty" json:"patterns,omitempty"`
+}
+
+// SystemPromptPolicyConfig sets text that every agent's system prompt starts
+// with and caps the length, in characters, of the composed prompt. Zero
+// MaxLength imposes no limit.
+type SystemPromptPolicyConfig struct {
+	Preamble  string `yaml:"preamble,omitempty" json:"preamble,omitempty"`
+	MaxLength int    `yaml:"max_length,omitempty" json:"max_length,omitempty"`
+}
+
+// OutboundPolicyConfig limits the hosts that tools fetching URLs may reach.
+// Private, loopback and link-local addresses are refused unless
+// AllowPrivate is set; cloud metadata endpoints are always refused. Host
+// patterns use shell glob syntax, e.g. "*.example.com", and an empty
+// AllowedHosts allows any host that is not denied.
+type OutboundPolicyConfig struct {
+	AllowPrivate bool     `yaml:"allow_private,omitempty" json:"allow_private,omitempty"`
+	AllowedHosts []string `yaml:"allowed_hosts,omitempty" json:"allowed_hosts,omitempty"`
+	DeniedHosts  []string `yaml:"denied_hosts,omitempty" json:"denied_hosts,omitempty"`
+}
+
+// GatewaysConfig configures chat platform adapters that relay channel
+// messages to agents.
+type GatewaysConfig struct {
+	MaxHistory int                   `yaml:"max_history,omitempty" json:"max_history,omitempty"`
+	SessionTTL time.Duration         `yaml:"session_ttl,omitempty" json:"session_ttl,omitempty"`
+	Discord    *DiscordGatewayConfig `yaml:"discord,omitempty" json:"discord,omitempty"`
+	Teams      *TeamsGatewayConfig   `yaml:"teams,omitempty" json:"teams,omitempty"`
+	Email      *EmailGatewayConfig   `yaml:"email,omitempty" json:"email,omitempty"`
+}
+
+// GatewayBinding routes messages from a channel to an agent. A channel of
+// "*" matches any channel without a more specific binding.
+type GatewayBinding struct {
+	Channel string `yaml:"channel" json:"channel"`
+	Cluster string `yaml:"cluster" json:"cluster"`
+	Agent   string `yaml:"agent" json:"agent"`
+}
+
+type DiscordGatewayConfig struct {
+	Token          string           `yaml:"token" json:"token"`
+	RequireMention bool             `yaml:"require_mention,omitempty" json:"require_mention,omitempty"`
+	Bindings       []GatewayBinding `yaml:"bindings" json:"bindings"`
+}
+
+type TeamsGatewayConfig struct {
+	AppID              string           `yaml:"app_id" json:"app_id"`
+	AppPassword        string           `yaml:"app_password" json:"app_password"`
+	TenantID           string           `yaml:"tenant_id,omitempty" json:"tenant_id,omitempty"`
+	InsecureSkipVerify bool             `yaml:"insecure_skip_verify,omitempty" json:"insecure_skip_verify,omitempty"`
+	Bindings           []GatewayBinding `yaml:"bindings" json:"bindings"`
+}
+
+// EmailGatewayConfig polls an IMAP mailbox and replies over SMTP. Bindings
+// match on the address a message was sent to.
+type EmailGatewayConfig struct {
+	IMAP         MailServerConfig `yaml:"imap" json:"imap"`
+	SMTP         MailServerConfig `yaml:"smtp" json:"smtp"`
+	Mailbox      string           `yaml:"mailbox,omitempty" json:"mailbox,omitempty"`
+	From         string           `yaml:"from" json:"from"`
+	PollInterval time.Duration    `yaml:"poll_interval,omitempty" json:"poll_interval,omitempty"`
+	Bindings     []GatewayBinding `yaml:"bindings" json:"bindings"`
+}
+
+type MailServerConfig struct {
+	Addr     string `yaml:"addr" json:"addr"`
+	Username string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password string `yaml:"password,omitempty" json:"password,omitempty"`
+	// Insecure disables TLS, for local test servers only
+	Insecure bool `yaml:"insecure,omitempty" json:"insecure,omitempty"`
 }
\ No newline at end of file
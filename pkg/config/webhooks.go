@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// DefaultWebhookEvents are the events webhooks get when they select none.
+var DefaultWebhookEvents = []string{
+	"cluster.deployed",
+	"cluster.failed",
+	"agent.started",
+	"agent.stopped",
+	"agent.failed",
+	"request.budget_exceeded",
+	"job.completed",
+}
+
+// WebhooksConfig posts lifecycle events to webhooks, each event as a JSON
+// payload signed with the webhook's secret. Deliveries that still fail
+// after their retries are kept as dead letters: the MaxDeadLetters most
+// recent in memory, 1000 by default, and all of them appended to
+// DeadLetterPath as JSON lines when it is set.
+type WebhooksConfig struct {
+	Endpoints      []WebhookConfig `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+	DeadLetterPath string          `yaml:"dead_letter_path,omitempty" json:"dead_letter_path,omitempty"`
+	MaxDeadLetters int             `yaml:"max_dead_letters,omitempty" json:"max_dead_letters,omitempty"`
+}
+
+// WebhookConfig is a webhook lifecycle events are posted to. Events selects
+// them as it does for event sinks, and defaults to DefaultWebhookEvents. A
+// failed delivery is tried MaxRetries more times, waiting RetryBackoff
+// before the first retry and twice as long before each next one;
+// MaxRetries of zero uses the default of 3 and a negative value turns
+// retries off. RetryBackoff defaults to 1s, and Timeout, which bounds each
+// attempt, to 10s.
+type WebhookConfig struct {
+	Name         string            `yaml:"name" json:"name"`
+	URL          string            `yaml:"url" json:"url"`
+	Secret       string            `yaml:"secret" json:"secret"`
+	Events       []string          `yaml:"events,omitempty" json:"events,omitempty"`
+	Headers      map[string]string `yaml:"headers,omitempty" json:"headers,omitempty"`
+	MaxRetries   int               `yaml:"max_retries,omitempty" json:"max_retries,omitempty"`
+	RetryBackoff time.Duration     `yaml:"retry_backoff,omitempty" json:"retry_backoff,omitempty"`
+	Timeout      time.Duration     `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// validateWebhooksConfig checks the webhooks, whose names must differ from
+// each other and from those of the event sinks, since they share the bus.
+func validateWebhooksConfig(webhooks *WebhooksConfig, events *EventsConfig) error {
+	if webhooks.MaxDeadLetters < 0 {
+		return fmt.Errorf("max_dead_letters must not be negative")
+	}
+	names := make(map[string]bool, len(webhooks.Endpoints)+len(events.Sinks))
+	for i := range events.Sinks {
+		names[events.Sinks[i].SinkName()] = true
+	}
+	for i := range webhooks.Endpoints {
+		webhook := &webhooks.Endpoints[i]
+		if webhook.Name == "" {
+			return fmt.Errorf("endpoint %d: name is required", i)
+		}
+		if names[webhook.Name] {
+			return fmt.Errorf("endpoint %s: name is already used by another webhook or event sink", webhook.Name)
+		}
+		names[webhook.Name] = true
+		
+		if err := ValidateWebhook(webhook); err != nil {
+			return fmt.Errorf("endpoint %s: %w", webhook.Name, err)
+		}
+	}
+	return nil
+}
+
+// ValidateWebhook checks a webhook on its own; the names of webhooks and
+// event sinks are checked against each other by their callers.
+func ValidateWebhook(webhook *WebhookConfig) error {
+	if webhook.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if !isURL(webhook.URL, "http", "https") {
+		return fmt.Errorf("url must be an http or https URL")
+	}
+	if webhook.Secret == "" {
+		return fmt.Errorf("secret is required to sign payloads")
+	}
+	if webhook.RetryBackoff < 0 || webhook.Timeout < 0 {
+		return fmt.Errorf("retry_backoff and timeout must not be negative")
+	}
+	return nil
+}
\ No newline at end of file
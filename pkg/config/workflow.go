@@ -0,0 +1,214 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"text/template"
+	"time"
+)
+
+var workflowNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// Workflow is a graph of steps that call the cluster's agents and tools.
+// A step starts once the steps it depends on have finished, so steps that
+// do not depend on each other run in parallel.
+type Workflow struct {
+	Name        string         `yaml:"name" json:"name"`
+	Description string         `yaml:"description,omitempty" json:"description,omitempty"`
+	Steps       []WorkflowStep `yaml:"steps" json:"steps"`
+	// Output is a template for the run's result. Without one, the result
+	// is the output of the step no other step depends on, or a map of
+	// outputs by step name when there are several.
+	Output  string        `yaml:"output,omitempty" json:"output,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// WorkflowStep asks an agent, or calls one of its tools when Tool is set.
+// Input, Args strings, When and ForEach are templates over the run's input,
+// the outputs of earlier steps and, in fan-out steps, the current item.
+type WorkflowStep struct {
+	Name  string `yaml:"name" json:"name"`
+	Agent string `yaml:"agent" json:"agent"`
+	Tool  string `yaml:"tool,omitempty" json:"tool,omitempty"`
+	// Input is the message sent to the agent; it defaults to the run's
+	// input as JSON
+	Input     string                 `yaml:"input,omitempty" json:"input,omitempty"`
+	Args      map[string]interface{} `yaml:"args,omitempty" json:"args,omitempty"`
+	DependsOn []string               `yaml:"depends_on,omitempty" json:"depends_on,omitempty"`
+	// When skips the step if it renders empty, "false" or "0"
+	When string `yaml:"when,omitempty" json:"when,omitempty"`
+	// ForEach renders a JSON array; the step runs once per item, in
+	// parallel, and its output is the list of results
+	ForEach string         `yaml:"for_each,omitempty" json:"for_each,omitempty"`
+	Retry   *WorkflowRetry `yaml:"retry,omitempty" json:"retry,omitempty"`
+}
+
+type WorkflowRetry struct {
+	// MaxAttempts counts the first attempt
+	MaxAttempts int `yaml:"max_attempts" json:"max_attempts"`
+	// Backoff is the wait before the second attempt, doubled for each one
+	// after it
+	Backoff time.Duration `yaml:"backoff,omitempty" json:"backoff,omitempty"`
+}
+
+// ParseWorkflowTemplate parses a workflow template. Templates can use the
+// json function to write a value as JSON, such as {{json .steps.search.output}}.
+func ParseWorkflowTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+	}).Parse(text)
+}
+
+// checkWorkflows checks the cluster's workflows: their names, that each
+// step names an agent of the cluster, that dependencies exist and do not
+// form a cycle, and that templates parse.
+func checkWorkflows(cluster *AgentCluster) error {
+	agents := make(map[string]bool)
+	for _, agent := range cluster.Spec.Agents {
+		agents[agent.Name] = true
+	}
+	
+	names := make(map[string]bool)
+	for i := range cluster.Spec.Workflows {
+		workflow := &cluster.Spec.Workflows[i]
+		if !workflowNamePattern.MatchString(workflow.Name) {
+			return fmt.Errorf("workflow %d: name must be letters, digits, '-' and '_'", i)
+		}
+		if names[workflow.Name] {
+			return fmt.Errorf("workflow %s: defined more than once", workflow.Name)
+		}
+		names[workflow.Name] = true
+		
+		if err := workflow.validate(agents); err != nil {
+			return fmt.Errorf("workflow %s: %w", workflow.Name, err)
+		}
+	}
+	return nil
+}
+
+func (w *Workflow) validate(agents map[string]bool) error {
+	if len(w.Steps) == 0 {
+		return fmt.Errorf("at least one step is required")
+	}
+	if w.Timeout < 0 {
+		return fmt.Errorf("timeout must not be negative")
+	}
+	if _, err := ParseWorkflowTemplate("output", w.Output); err != nil {
+		return fmt.Errorf("invalid output template: %w", err)
+	}
+	
+	steps := make(map[string]*WorkflowStep)
+	for i := range w.Steps {
+		step := &w.Steps[i]
+		if step.Name == "" {
+			return fmt.Errorf("step %d: name is required", i)
+		}
+		if steps[step.Name] != nil {
+			return fmt.Errorf("step %s: defined more than once", step.Name)
+		}
+		steps[step.Name] = step
+		
+		if err := step.validate(agents); err != nil {
+			return fmt.Errorf("step %s: %w", step.Name, err)
+		}
+	}
+	
+	for _, step := range w.Steps {
+		for _, dep := range step.DependsOn {
+			if steps[dep] == nil {
+				return fmt.Errorf("step %s: dependency %s not found", step.Name, dep)
+			}
+		}
+	}
+	
+	// Depth-first search, in step order so the cycle reported is stable
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("step %s: dependency cycle", name)
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		for _, dep := range steps[name].DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[name] = visited
+		return nil
+	}
+	for _, step := range w.Steps {
+		if err := visit(step.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *WorkflowStep) validate(agents map[string]bool) error {
+	if s.Agent == "" {
+		return fmt.Errorf("agent is required")
+	}
+	if !agents[s.Agent] {
+		return fmt.Errorf("agent %s not found", s.Agent)
+	}
+	if s.Tool == "" && len(s.Args) > 0 {
+		return fmt.Errorf("args are only used with tool")
+	}
+	if s.Tool != "" && s.Input != "" {
+		return fmt.Errorf("input is only used without tool; tools take args")
+	}
+	
+	templates := map[string]string{"input": s.Input, "when": s.When, "for_each": s.ForEach}
+	for name, text := range templates {
+		if _, err := ParseWorkflowTemplate(name, text); err != nil {
+			return fmt.Errorf("invalid %s template: %w", name, err)
+		}
+	}
+	if err := checkArgTemplates(s.Args); err != nil {
+		return err
+	}
+	
+	if s.Retry != nil {
+		if s.Retry.MaxAttempts < 1 {
+			return fmt.Errorf("retry: max_attempts must be at least 1")
+		}
+		if s.Retry.Backoff < 0 {
+			return fmt.Errorf("retry: backoff must not be negative")
+		}
+	}
+	return nil
+}
+
+func checkArgTemplates(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		if _, err := ParseWorkflowTemplate("args", v); err != nil {
+			return fmt.Errorf("invalid args template: %w", err)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			if err := checkArgTemplates(item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := checkArgTemplates(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,324 @@
+// Package events delivers the events of the engine, its clusters, agents
+// and requests to sinks such as the log, webhooks, NATS and Kafka. Each
+// sink has a queue of its own, so a slow or unreachable sink holds up
+// neither the others nor the code publishing events.
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultBufferSize = 1000
+	defaultBatchSize  = 100
+	defaultTimeout    = 10 * time.Second
+	// dropWarnInterval spaces the warnings about events dropped by a sink
+	// that cannot keep up
+	dropWarnInterval = 10 * time.Second
+)
+
+// Sink delivers events. Send is given a batch of events in the order they
+// were published, and is not called again until it returns.
+type Sink interface {
+	Send(ctx context.Context, events []agent.Event) error
+	Close() error
+}
+
+// SinkOptions sets how a bus feeds a sink. Events selects the event types
+// the sink gets, each a type or a prefix ending in "*" such as "agent.*";
+// without any the sink gets every event. Up to BufferSize events wait for
+// the sink, 1000 by default, and are sent in batches of up to BatchSize,
+// 100 by default. Timeout bounds each batch, 10s by default.
+type SinkOptions struct {
+	Name       string
+	Type       string
+	Events     []string
+	BufferSize int
+	BatchSize  int
+	Timeout    time.Duration
+}
+
+// SinkStatus reports on the events a sink was given. Dropped counts those
+// that found its queue full, and Failed those in batches it could not send.
+type SinkStatus struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	Events      []string   `json:"events,omitempty"`
+	Pending     int        `json:"pending"`
+	Delivered   uint64     `json:"delivered"`
+	Failed      uint64     `json:"failed"`
+	Dropped     uint64     `json:"dropped"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+}
+
+// Bus publishes events to its sinks.
+type Bus struct {
+	logger *zap.Logger
+	sinks  []*sinkQueue
+	nextID uint64
+	closed bool
+	mu     sync.RWMutex
+}
+
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{logger: logger}
+}
+
+// AddSink starts sending events to sink.
+func (b *Bus) AddSink(sink Sink, options SinkOptions) {
+	if options.BufferSize <= 0 {
+		options.BufferSize = defaultBufferSize
+	}
+	if options.BatchSize <= 0 {
+		options.BatchSize = defaultBatchSize
+	}
+	if options.Timeout <= 0 {
+		options.Timeout = defaultTimeout
+	}
+	
+	queue := &sinkQueue{
+		options: options,
+		sink:    sink,
+		logger:  b.logger,
+		events:  make(chan agent.Event, options.BufferSize),
+		done:    make(chan struct{}),
+	}
+	
+	b.mu.Lock()
+	b.sinks = append(b.sinks, queue)
+	b.mu.Unlock()
+	
+	go queue.run()
+}
+
+// RemoveSink stops sending events to the sink named name, and reports
+// whether there was one. The events already queued for it are still sent,
+// in the background, before it is closed.
+func (b *Bus) RemoveSink(name string) bool {
+	b.mu.Lock()
+	var queue *sinkQueue
+	for i := range b.sinks {
+		if b.sinks[i].options.Name == name {
+			queue = b.sinks[i]
+			b.sinks = append(b.sinks[:i:i], b.sinks[i+1:]...)
+			break
+		}
+	}
+	closed := b.closed
+	b.mu.Unlock()
+	if queue == nil || closed {
+		return queue != nil
+	}
+	
+	close(queue.events)
+	go func() {
+		<-queue.done
+		if err := queue.sink.Close(); err != nil {
+			b.logger.Warn("Failed to close event sink",
+				zap.String("sink", name),
+				zap.Error(err))
+		}
+	}()
+	return true
+}
+
+// Publish queues event for the sinks that take it, giving it an ID and
+// timestamp when it has none. It never waits: an event that finds a sink's
+// queue full is dropped for that sink, and counted.
+func (b *Bus) Publish(event agent.Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.ID == "" {
+		event.ID = fmt.Sprintf("evt-%d-%d", event.Timestamp.UnixNano(), atomic.AddUint64(&b.nextID, 1))
+	}
+	
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	
+	if b.closed {
+		return
+	}
+	for _, queue := range b.sinks {
+		queue.offer(event)
+	}
+}
+
+// Status reports on each sink, in the order they were added.
+func (b *Bus) Status() []SinkStatus {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	
+	statuses := make([]SinkStatus, 0, len(b.sinks))
+	for _, queue := range b.sinks {
+		statuses = append(statuses, queue.status())
+	}
+	return statuses
+}
+
+// Close stops taking events and waits, until ctx is done, for the sinks to
+// send those queued before closing them.
+func (b *Bus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return nil
+	}
+	b.closed = true
+	sinks := b.sinks
+	b.mu.Unlock()
+	
+	for _, queue := range sinks {
+		close(queue.events)
+	}
+	
+	var unsent []string
+	for _, queue := range sinks {
+		select {
+		case <-queue.done:
+		case <-ctx.Done():
+			unsent = append(unsent, queue.options.Name)
+		}
+		if err := queue.sink.Close(); err != nil {
+			b.logger.Warn("Failed to close event sink",
+				zap.String("sink", queue.options.Name),
+				zap.Error(err))
+		}
+	}
+	if len(unsent) > 0 {
+		return fmt.Errorf("%w: events left unsent to %s", ctx.Err(), strings.Join(unsent, ", "))
+	}
+	return nil
+}
+
+// sinkQueue holds the events waiting for a sink, and sends them from a
+// goroutine of its own.
+type sinkQueue struct {
+	options   SinkOptions
+	sink      Sink
+	logger    *zap.Logger
+	events    chan agent.Event
+	done      chan struct{}
+	delivered atomic.Uint64
+	failed    atomic.Uint64
+	dropped   atomic.Uint64
+	
+	mu          sync.Mutex
+	lastError   string
+	lastErrorAt time.Time
+	lastWarned  time.Time
+}
+
+// accepts reports whether the sink takes events of type eventType.
+func (q *sinkQueue) accepts(eventType agent.EventType) bool {
+	if len(q.options.Events) == 0 {
+		return true
+	}
+	for _, pattern := range q.options.Events {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			if strings.HasPrefix(string(eventType), prefix) {
+				return true
+			}
+		} else if pattern == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (q *sinkQueue) offer(event agent.Event) {
+	if !q.accepts(event.Type) {
+		return
+	}
+	select {
+	case q.events <- event:
+		return
+	default:
+	}
+	
+	dropped := q.dropped.Add(1)
+	q.mu.Lock()
+	warn := time.Since(q.lastWarned) >= dropWarnInterval
+	if warn {
+		q.lastWarned = time.Now()
+	}
+	q.mu.Unlock()
+	if warn {
+		q.logger.Warn("Event sink queue full, dropping events",
+			zap.String("sink", q.options.Name),
+			zap.String("type", string(event.Type)),
+			zap.Uint64("dropped", dropped))
+	}
+}
+
+// run sends the queued events, as many at once as there are waiting up to
+// the batch size, until the queue is closed and empty.
+func (q *sinkQueue) run() {
+	defer close(q.done)
+	
+	for event := range q.events {
+		batch := []agent.Event{event}
+	fill:
+		for len(batch) < q.options.BatchSize {
+			select {
+			case next, ok := <-q.events:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, next)
+			default:
+				break fill
+			}
+		}
+		q.send(batch)
+	}
+}
+
+func (q *sinkQueue) send(batch []agent.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), q.options.Timeout)
+	err := q.sink.Send(ctx, batch)
+	cancel()
+	
+	if err == nil {
+		q.delivered.Add(uint64(len(batch)))
+		return
+	}
+	q.failed.Add(uint64(len(batch)))
+	q.mu.Lock()
+	q.lastError = err.Error()
+	q.lastErrorAt = time.Now()
+	q.mu.Unlock()
+	q.logger.Warn("Failed to send events",
+		zap.String("sink", q.options.Name),
+		zap.Int("events", len(batch)),
+		zap.Error(err))
+}
+
+func (q *sinkQueue) status() SinkStatus {
+	status := SinkStatus{
+		Name:      q.options.Name,
+		Type:      q.options.Type,
+		Events:    q.options.Events,
+		Pending:   len(q.events),
+		Delivered: q.delivered.Load(),
+		Failed:    q.failed.Load(),
+		Dropped:   q.dropped.Load(),
+	}
+	q.mu.Lock()
+	if !q.lastErrorAt.IsZero() {
+		status.LastError = q.lastError
+		lastErrorAt := q.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	q.mu.Unlock()
+	return status
+}
\ No newline at end of file
@@ -0,0 +1,121 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/agent"
+)
+
+// KafkaConfig produces events to Topic through the Kafka REST Proxy at
+// URL, using its v2 JSON API. Headers are added to each request, such as
+// one carrying credentials.
+type KafkaConfig struct {
+	URL     string
+	Topic   string
+	Headers map[string]string
+}
+
+// KafkaSink produces each event as a record whose value is the event. An
+// agent's events are keyed by its ID, so they keep their order in one
+// partition; other events are keyed by type.
+type KafkaSink struct {
+	config   *KafkaConfig
+	endpoint string
+	client   *http.Client
+}
+
+type kafkaRecord struct {
+	Key   string      `json:"key"`
+	Value agent.Event `json:"value"`
+}
+
+type kafkaOffset struct {
+	ErrorCode *int   `json:"error_code"`
+	Error     string `json:"error"`
+}
+
+func NewKafkaSink(config *KafkaConfig) (*KafkaSink, error) {
+	proxy, err := url.Parse(config.URL)
+	if err != nil || (proxy.Scheme != "http" && proxy.Scheme != "https") || proxy.Host == "" {
+		return nil, fmt.Errorf("kafka url must be the http or https URL of a REST proxy")
+	}
+	if config.Topic == "" {
+		return nil, fmt.Errorf("kafka topic is required")
+	}
+	return &KafkaSink{
+		config:   config,
+		endpoint: strings.TrimSuffix(config.URL, "/") + "/topics/" + url.PathEscape(config.Topic),
+		client:   &http.Client{},
+	}, nil
+}
+
+func (s *KafkaSink) Send(ctx context.Context, events []agent.Event) error {
+	records := make([]kafkaRecord, 0, len(events))
+	for _, event := range events {
+		key := event.AgentID
+		if key == "" {
+			key = string(event.Type)
+		}
+		records = append(records, kafkaRecord{Key: key, Value: event})
+	}
+	body, err := json.Marshal(map[string]interface{}{"records": records})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+	
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("kafka rest proxy returned %s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	
+	// The proxy answers 200 even when some records were not produced
+	var produced struct {
+		Offsets []kafkaOffset `json:"offsets"`
+	}
+	if err := json.Unmarshal(data, &produced); err != nil {
+		return fmt.Errorf("failed to decode kafka rest proxy response: %w", err)
+	}
+	failed := 0
+	var reason string
+	for _, offset := range produced.Offsets {
+		if offset.ErrorCode != nil || offset.Error != "" {
+			failed++
+			reason = offset.Error
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("kafka rejected %d of %d events: %s", failed, len(events), reason)
+	}
+	return nil
+}
+
+func (s *KafkaSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
\ No newline at end of file
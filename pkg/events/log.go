@@ -0,0 +1,33 @@
+package events
+
+import (
+	"context"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"go.uber.org/zap"
+)
+
+// LogSink writes events to a logger.
+type LogSink struct {
+	logger *zap.Logger
+}
+
+func NewLogSink(logger *zap.Logger) *LogSink {
+	return &LogSink{logger: logger}
+}
+
+func (s *LogSink) Send(ctx context.Context, events []agent.Event) error {
+	for _, event := range events {
+		s.logger.Info("Event",
+			zap.String("id", event.ID),
+			zap.String("type", string(event.Type)),
+			zap.String("agent_id", event.AgentID),
+			zap.Time("timestamp", event.Timestamp),
+			zap.Any("data", event.Data))
+	}
+	return nil
+}
+
+func (s *LogSink) Close() error {
+	return nil
+}
\ No newline at end of file
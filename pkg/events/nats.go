@@ -0,0 +1,228 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/goagents/goagents/pkg/agent"
+)
+
+const (
+	defaultNATSPort    = "4222"
+	defaultNATSSubject = "goagents.events"
+)
+
+// NATSConfig publishes events to the NATS server at URL, nats://host:port
+// or tls://host:port, with a user and password in the URL or Token when the
+// server requires them. Each event goes to Subject, goagents.events by
+// default, followed by its type, such as goagents.events.agent.started.
+type NATSConfig struct {
+	URL     string
+	Subject string
+	Token   string
+}
+
+// NATSSink publishes events with core NATS. It connects when it first
+// sends, and again after a failure. Each batch ends with a PING, so Send
+// returns once the server has taken the events, or with the error it sent.
+type NATSSink struct {
+	config  *NATSConfig
+	server  *url.URL
+	subject string
+	conn    net.Conn
+	reader  *bufio.Reader
+	mu      sync.Mutex
+}
+
+// natsInfo is the part of the INFO the server greets clients with that
+// the sink needs.
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+func NewNATSSink(config *NATSConfig) (*NATSSink, error) {
+	server, err := url.Parse(config.URL)
+	if err != nil || (server.Scheme != "nats" && server.Scheme != "tls") || server.Hostname() == "" {
+		return nil, fmt.Errorf("nats url must be a nats:// or tls:// URL")
+	}
+	subject := config.Subject
+	if subject == "" {
+		subject = defaultNATSSubject
+	}
+	if strings.ContainsAny(subject, " \t\r\n*>") {
+		return nil, fmt.Errorf("nats subject %q must not contain whitespace or wildcards", subject)
+	}
+	return &NATSSink{
+		config:  config,
+		server:  server,
+		subject: subject,
+	}, nil
+}
+
+func (s *NATSSink) Send(ctx context.Context, events []agent.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect to nats: %w", err)
+		}
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		s.conn.SetDeadline(deadline)
+	}
+	
+	var buf bytes.Buffer
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		fmt.Fprintf(&buf, "PUB %s.%s %d\r\n", s.subject, event.Type, len(payload))
+		buf.Write(payload)
+		buf.WriteString("\r\n")
+	}
+	buf.WriteString("PING\r\n")
+	
+	if _, err := s.conn.Write(buf.Bytes()); err != nil {
+		s.disconnect()
+		return err
+	}
+	if err := s.awaitPong(); err != nil {
+		s.disconnect()
+		return err
+	}
+	return nil
+}
+
+// connect dials the server, upgrading to TLS when the URL or the server
+// asks for it, and sends the client's options; the caller holds the lock.
+func (s *NATSSink) connect(ctx context.Context) error {
+	address := s.server.Host
+	if s.server.Port() == "" {
+		address = net.JoinHostPort(s.server.Hostname(), defaultNATSPort)
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	
+	reader := bufio.NewReader(conn)
+	line, err := readNATSLine(reader)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	infoJSON, ok := strings.CutPrefix(line, "INFO ")
+	if !ok {
+		conn.Close()
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(infoJSON), &info); err != nil {
+		conn.Close()
+		return fmt.Errorf("invalid INFO: %w", err)
+	}
+	
+	if s.server.Scheme == "tls" || info.TLSRequired {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: s.server.Hostname()})
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+		reader = bufio.NewReader(conn)
+	}
+	
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "goagents",
+		"lang":     "go",
+		"version":  "1.0.0",
+	}
+	if user := s.server.User; user != nil {
+		options["user"] = user.Username()
+		if password, ok := user.Password(); ok {
+			options["pass"] = password
+		}
+	}
+	if s.config.Token != "" {
+		options["auth_token"] = s.config.Token
+	}
+	connectJSON, err := json.Marshal(options)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if _, err := fmt.Fprintf(conn, "CONNECT %s\r\n", connectJSON); err != nil {
+		conn.Close()
+		return err
+	}
+	
+	s.conn = conn
+	s.reader = reader
+	return nil
+}
+
+// awaitPong reads until the server answers the PING that ends a batch,
+// answering its own PINGs on the way; the caller holds the lock.
+func (s *NATSSink) awaitPong() error {
+	for {
+		line, err := readNATSLine(s.reader)
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := s.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return fmt.Errorf("nats: %s", strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+	}
+}
+
+// disconnect drops the connection after a failure, for the next batch to
+// connect again; the caller holds the lock.
+func (s *NATSSink) disconnect() {
+	s.conn.Close()
+	s.conn = nil
+	s.reader = nil
+}
+
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	s.reader = nil
+	return err
+}
+
+func readNATSLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
\ No newline at end of file
@@ -0,0 +1,223 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+)
+
+const (
+	defaultWebhookRetries = 3
+	defaultWebhookBackoff = time.Second
+	defaultWebhookTimeout = 10 * time.Second
+	// maxWebhookBackoff caps the wait between attempts, however many
+	maxWebhookBackoff = time.Minute
+)
+
+// Headers of signed webhook deliveries. The signature header has the form
+// t=<unix seconds>,v1=<hex HMAC-SHA256>; see SignPayload.
+const (
+	SignatureHeader = "X-GoAgents-Signature"
+	EventHeader     = "X-GoAgents-Event"
+	DeliveryHeader  = "X-GoAgents-Delivery"
+)
+
+// SignedWebhookConfig posts each event to URL as a JSON payload signed with
+// Secret. A delivery that fails is tried MaxRetries more times, waiting
+// RetryBackoff before the first retry and twice as long before each next
+// one; MaxRetries of zero uses the default of 3 and a negative value turns
+// retries off. RetryBackoff defaults to 1s, and Timeout, which bounds each
+// attempt, to 10s. DeadLetter, when set, is given each event that could
+// not be delivered.
+type SignedWebhookConfig struct {
+	URL          string
+	Secret       string
+	Headers      map[string]string
+	MaxRetries   int
+	RetryBackoff time.Duration
+	Timeout      time.Duration
+	DeadLetter   func(event agent.Event, attempts int, err error)
+}
+
+// SignedWebhookSink delivers events one at a time, retrying on network
+// errors, timeouts, 408, 429 and 5xx statuses. Other statuses but 2xx fail
+// the delivery at once.
+type SignedWebhookSink struct {
+	config  SignedWebhookConfig
+	retries int
+	client  *http.Client
+}
+
+// permanentError is a failed delivery that retrying would not fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func NewSignedWebhookSink(config *SignedWebhookConfig) (*SignedWebhookSink, error) {
+	target, err := url.Parse(config.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("webhook url must be an http or https URL")
+	}
+	if config.Secret == "" {
+		return nil, fmt.Errorf("webhook secret is required")
+	}
+	
+	sink := &SignedWebhookSink{
+		config:  *config,
+		retries: config.MaxRetries,
+		client:  &http.Client{},
+	}
+	if sink.retries == 0 {
+		sink.retries = defaultWebhookRetries
+	} else if sink.retries < 0 {
+		sink.retries = 0
+	}
+	if sink.config.RetryBackoff <= 0 {
+		sink.config.RetryBackoff = defaultWebhookBackoff
+	}
+	if sink.config.Timeout <= 0 {
+		sink.config.Timeout = defaultWebhookTimeout
+	}
+	return sink, nil
+}
+
+// MaxDeliveryTime is the longest one event's delivery takes, every attempt
+// timing out and every wait between them included.
+func (s *SignedWebhookSink) MaxDeliveryTime() time.Duration {
+	total := time.Duration(s.retries+1) * s.config.Timeout
+	for i := 0; i < s.retries; i++ {
+		total += s.backoff(i)
+	}
+	return total
+}
+
+// Send delivers each event in turn. Events that cannot be delivered are
+// handed to DeadLetter, and make Send fail once the others are delivered.
+func (s *SignedWebhookSink) Send(ctx context.Context, events []agent.Event) error {
+	failed := 0
+	var lastErr error
+	for _, event := range events {
+		attempts, err := s.deliver(ctx, event)
+		if err == nil {
+			continue
+		}
+		failed++
+		lastErr = err
+		if s.config.DeadLetter != nil {
+			s.config.DeadLetter(event, attempts, err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d events not delivered: %w", failed, len(events), lastErr)
+	}
+	return nil
+}
+
+// deliver posts event until it is taken, the attempts run out or ctx is
+// done, and returns the attempts made.
+func (s *SignedWebhookSink) deliver(ctx context.Context, event agent.Event) (int, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+	
+	attempts := 0
+	for {
+		attempts++
+		err = s.post(ctx, event, body)
+		if err == nil {
+			return attempts, nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || attempts > s.retries {
+			return attempts, err
+		}
+		
+		wait := time.NewTimer(s.backoff(attempts - 1))
+		select {
+		case <-ctx.Done():
+			wait.Stop()
+			return attempts, fmt.Errorf("%w (gave up: %v)", err, ctx.Err())
+		case <-wait.C:
+		}
+	}
+}
+
+func (s *SignedWebhookSink) post(ctx context.Context, event agent.Event, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, s.config.Timeout)
+	defer cancel()
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{err: err}
+	}
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(event.Type))
+	req.Header.Set(DeliveryHeader, event.ID)
+	req.Header.Set(SignatureHeader, fmt.Sprintf("t=%d,v1=%s", timestamp, SignPayload(s.config.Secret, timestamp, body)))
+	
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	default:
+		return &permanentError{err: fmt.Errorf("webhook returned %s", resp.Status)}
+	}
+}
+
+// backoff is the wait after the attempt numbered attempt, from zero, fails.
+func (s *SignedWebhookSink) backoff(attempt int) time.Duration {
+	wait := s.config.RetryBackoff
+	for i := 0; i < attempt && wait < maxWebhookBackoff; i++ {
+		wait *= 2
+	}
+	if wait > maxWebhookBackoff {
+		wait = maxWebhookBackoff
+	}
+	return wait
+}
+
+func (s *SignedWebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// SignPayload returns the hex HMAC-SHA256, keyed by secret, of timestamp
+// and body joined by a dot, as sent in the v1 part of SignatureHeader.
+// Receivers compute it from the t part and the raw body to check a
+// delivery, and reject old timestamps to stop replays.
+func SignPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
\ No newline at end of file
@@ -0,0 +1,71 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/goagents/goagents/pkg/agent"
+)
+
+// WebhookConfig posts events to URL, with Headers added to each request,
+// such as one carrying a token.
+type WebhookConfig struct {
+	URL     string
+	Headers map[string]string
+}
+
+// WebhookSink posts each batch of events as one JSON object, with the
+// events in its "events" array. Any status but 2xx fails the batch.
+type WebhookSink struct {
+	config *WebhookConfig
+	client *http.Client
+}
+
+func NewWebhookSink(config *WebhookConfig) (*WebhookSink, error) {
+	target, err := url.Parse(config.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("webhook url must be an http or https URL")
+	}
+	return &WebhookSink{
+		config: config,
+		client: &http.Client{},
+	}, nil
+}
+
+func (s *WebhookSink) Send(ctx context.Context, events []agent.Event) error {
+	body, err := json.Marshal(map[string]interface{}{"events": events})
+	if err != nil {
+		return fmt.Errorf("failed to encode events: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range s.config.Headers {
+		req.Header.Set(name, value)
+	}
+	
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *WebhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,122 @@
+package files
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DiskStore keeps each file in a directory as <id> with its description in
+// <id>.json, so files survive restarts.
+type DiskStore struct {
+	dir    string
+	limits Limits
+}
+
+func NewDiskStore(dir string, limits Limits) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create file store directory: %w", err)
+	}
+	
+	store := &DiskStore{dir: dir, limits: limits}
+	store.sweep(time.Now())
+	return store, nil
+}
+
+func (s *DiskStore) Put(ctx context.Context, name, mimeType string, data []byte) (*File, error) {
+	file, err := s.limits.newFile(name, mimeType, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	
+	meta, err := json.Marshal(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode file metadata: %w", err)
+	}
+	
+	if err := os.WriteFile(s.path(file.ID), data, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := os.WriteFile(s.path(file.ID)+".json", meta, 0o600); err != nil {
+		os.Remove(s.path(file.ID))
+		return nil, fmt.Errorf("failed to write file metadata: %w", err)
+	}
+	
+	return file, nil
+}
+
+func (s *DiskStore) Get(ctx context.Context, id string) (*File, []byte, error) {
+	file, err := s.stat(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if file.expired(time.Now()) {
+		s.remove(id)
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	
+	return file, data, nil
+}
+
+func (s *DiskStore) Delete(ctx context.Context, id string) error {
+	if _, err := s.stat(id); err != nil {
+		return err
+	}
+	s.remove(id)
+	return nil
+}
+
+func (s *DiskStore) Close() error {
+	return nil
+}
+
+func (s *DiskStore) stat(id string) (*File, error) {
+	// IDs come from API callers; never let one name a path
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	
+	meta, err := os.ReadFile(s.path(id) + ".json")
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file metadata: %w", err)
+	}
+	
+	var file File
+	if err := json.Unmarshal(meta, &file); err != nil {
+		return nil, fmt.Errorf("failed to decode file metadata: %w", err)
+	}
+	return &file, nil
+}
+
+func (s *DiskStore) path(id string) string {
+	return filepath.Join(s.dir, id)
+}
+
+func (s *DiskStore) remove(id string) {
+	os.Remove(s.path(id))
+	os.Remove(s.path(id) + ".json")
+}
+
+// sweep removes files that expired while the server was down.
+func (s *DiskStore) sweep(now time.Time) {
+	matches, _ := filepath.Glob(filepath.Join(s.dir, "file-*.json"))
+	for _, match := range matches {
+		id := strings.TrimSuffix(filepath.Base(match), ".json")
+		if file, err := s.stat(id); err == nil && file.expired(now) {
+			s.remove(id)
+		}
+	}
+}
\ No newline at end of file
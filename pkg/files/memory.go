@@ -0,0 +1,79 @@
+package files
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	file *File
+	data []byte
+}
+
+type MemoryStore struct {
+	limits Limits
+	files  map[string]*memoryEntry
+	mu     sync.Mutex
+}
+
+func NewMemoryStore(limits Limits) *MemoryStore {
+	return &MemoryStore{
+		limits: limits,
+		files:  make(map[string]*memoryEntry),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, name, mimeType string, data []byte) (*File, error) {
+	file, err := s.limits.newFile(name, mimeType, int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.sweep(time.Now())
+	s.files[file.ID] = &memoryEntry{file: file, data: append([]byte(nil), data...)}
+	
+	copied := *file
+	return &copied, nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*File, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	entry, ok := s.files[id]
+	if !ok || entry.file.expired(time.Now()) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	
+	file := *entry.file
+	return &file, entry.data, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if _, ok := s.files[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(s.files, id)
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// sweep drops expired files. The caller must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	for id, entry := range s.files {
+		if entry.file.expired(now) {
+			delete(s.files, id)
+		}
+	}
+}
\ No newline at end of file
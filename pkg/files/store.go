@@ -0,0 +1,79 @@
+// Package files stores binary content, such as documents and images fetched
+// by tools, so it can be passed around by reference instead of being
+// inlined into JSON.
+package files
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrNotFound = errors.New("file not found")
+	ErrTooLarge = errors.New("file too large")
+)
+
+// File describes a stored file. It is what tools return in place of the
+// content.
+type File struct {
+	ID        string    `json:"file_id"`
+	Name      string    `json:"name,omitempty"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"`
+}
+
+type Store interface {
+	Put(ctx context.Context, name, mimeType string, data []byte) (*File, error)
+	Get(ctx context.Context, id string) (*File, []byte, error)
+	Delete(ctx context.Context, id string) error
+	Close() error
+}
+
+// Limits bounds what a store accepts and keeps. Zero values are not
+// enforced.
+type Limits struct {
+	MaxSize int64
+	TTL     time.Duration
+}
+
+func (l Limits) newFile(name, mimeType string, size int64) (*File, error) {
+	if l.MaxSize > 0 && size > l.MaxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the %d byte limit", ErrTooLarge, size, l.MaxSize)
+	}
+	
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	
+	file := &File{
+		ID:        id,
+		Name:      name,
+		MimeType:  mimeType,
+		Size:      size,
+		CreatedAt: time.Now().UTC(),
+	}
+	if l.TTL > 0 {
+		file.ExpiresAt = file.CreatedAt.Add(l.TTL)
+	}
+	return file, nil
+}
+
+func (f *File) expired(now time.Time) bool {
+	return !f.ExpiresAt.IsZero() && now.After(f.ExpiresAt)
+}
+
+// newID returns an unguessable ID, since files can be downloaded by ID.
+func newID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate file ID: %w", err)
+	}
+	return "file-" + hex.EncodeToString(buf), nil
+}
\ No newline at end of file
@@ -0,0 +1,133 @@
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+const (
+	discordMessageLimit = 2000
+	discordReplyTimeout = 2 * time.Minute
+)
+
+// DiscordGateway relays messages from Discord channels and threads over the
+// bot gateway websocket.
+type DiscordGateway struct {
+	config     *config.DiscordGatewayConfig
+	session    *discordgo.Session
+	dispatcher *dispatcher
+	logger     *zap.Logger
+	ctx        context.Context
+}
+
+func NewDiscordGateway(cfg *config.DiscordGatewayConfig, dispatcher *dispatcher, logger *zap.Logger) (*DiscordGateway, error) {
+	session, err := discordgo.New("Bot " + cfg.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discord session: %w", err)
+	}
+	session.Identify.Intents = discordgo.IntentsGuildMessages |
+		discordgo.IntentsDirectMessages |
+		discordgo.IntentMessageContent
+		
+	gateway := &DiscordGateway{
+		config:     cfg,
+		session:    session,
+		dispatcher: dispatcher,
+		logger:     logger,
+		ctx:        context.Background(),
+	}
+	session.AddHandler(gateway.onMessageCreate)
+	
+	return gateway, nil
+}
+
+func (g *DiscordGateway) Name() string {
+	return "discord"
+}
+
+func (g *DiscordGateway) Start(ctx context.Context) error {
+	g.ctx = ctx
+	return g.session.Open()
+}
+
+func (g *DiscordGateway) Close() error {
+	return g.session.Close()
+}
+
+func (g *DiscordGateway) onMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.Bot || (s.State.User != nil && m.Author.ID == s.State.User.ID) {
+		return
+	}
+	
+	text := m.Content
+	if s.State.User != nil {
+		mentioned := false
+		for _, user := range m.Mentions {
+			if user.ID == s.State.User.ID {
+				mentioned = true
+				break
+			}
+		}
+		// Direct messages are always addressed to the bot
+		if g.config.RequireMention && !mentioned && m.GuildID != "" {
+			return
+		}
+		text = strings.NewReplacer("<@"+s.State.User.ID+">", "", "<@!"+s.State.User.ID+">", "").Replace(text)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	
+	// Threads are bound through their parent channel but keep their own
+	// conversation
+	channelID, threadID := m.ChannelID, ""
+	if channel, err := g.channel(m.ChannelID); err == nil && channel.IsThread() {
+		channelID, threadID = channel.ParentID, channel.ID
+	}
+	
+	binding, ok := g.dispatcher.resolve(channelID)
+	if !ok {
+		return
+	}
+	
+	go g.reply(m.Message, binding, channelID, threadID, text)
+}
+
+func (g *DiscordGateway) reply(message *discordgo.Message, binding config.GatewayBinding, channelID, threadID, text string) {
+	ctx, cancel := context.WithTimeout(g.ctx, discordReplyTimeout)
+	defer cancel()
+	
+	g.session.ChannelTyping(message.ChannelID)
+	
+	content, err := g.dispatcher.handle(ctx, binding, channelID, threadID, text)
+	if err != nil {
+		g.logger.Error("Discord message processing failed",
+			zap.String("channel", channelID),
+			zap.String("agent", binding.Agent),
+			zap.Error(err))
+		content = "Sorry, I couldn't process that message."
+	}
+	
+	for _, chunk := range splitMessage(content, discordMessageLimit) {
+		if _, err := g.session.ChannelMessageSendReply(message.ChannelID, chunk, message.Reference()); err != nil {
+			g.logger.Error("Failed to send Discord reply",
+				zap.String("channel", message.ChannelID),
+				zap.Error(err))
+			return
+		}
+	}
+}
+
+func (g *DiscordGateway) channel(id string) (*discordgo.Channel, error) {
+	if channel, err := g.session.State.Channel(id); err == nil {
+		return channel, nil
+	}
+	return g.session.Channel(id)
+}
\ No newline at end of file
@@ -0,0 +1,438 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	_ "github.com/emersion/go-message/charset"
+	"github.com/emersion/go-message/mail"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+const (
+	emailDefaultPollInterval = time.Minute
+	emailReplyTimeout        = 5 * time.Minute
+	emailMaxBodySize         = 256 * 1024
+)
+
+// EmailGateway polls an IMAP mailbox for unread messages, answers each with
+// the bound agent and replies over SMTP. Conversations follow the message
+// thread, so a reply to the agent's answer continues the same session.
+type EmailGateway struct {
+	config     *config.EmailGatewayConfig
+	dispatcher *dispatcher
+	logger     *zap.Logger
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+type emailMessage struct {
+	messageID  string
+	references []string
+	from       *mail.Address
+	replyTo    *mail.Address
+	recipients []string
+	subject    string
+	body       string
+}
+
+func NewEmailGateway(cfg *config.EmailGatewayConfig, dispatcher *dispatcher, logger *zap.Logger) *EmailGateway {
+	return &EmailGateway{
+		config:     cfg,
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+func (g *EmailGateway) Name() string {
+	return "email"
+}
+
+func (g *EmailGateway) Start(ctx context.Context) error {
+	ctx, g.cancel = context.WithCancel(ctx)
+	
+	interval := g.config.PollInterval
+	if interval <= 0 {
+		interval = emailDefaultPollInterval
+	}
+	
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		
+		for {
+			g.poll(ctx)
+			
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	
+	return nil
+}
+
+func (g *EmailGateway) Close() error {
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.wg.Wait()
+	return nil
+}
+
+func (g *EmailGateway) poll(ctx context.Context) {
+	messages, err := g.fetchUnread()
+	if err != nil {
+		g.logger.Error("Failed to poll mailbox",
+			zap.String("server", g.config.IMAP.Addr),
+			zap.Error(err))
+		return
+	}
+	
+	for _, message := range messages {
+		if ctx.Err() != nil {
+			return
+		}
+		g.handle(ctx, message)
+	}
+}
+
+// fetchUnread downloads unseen messages and marks them seen, so a message
+// that fails to process is not answered twice.
+func (g *EmailGateway) fetchUnread() ([]*emailMessage, error) {
+	c, err := g.dialIMAP()
+	if err != nil {
+		return nil, err
+	}
+	defer c.Logout()
+	
+	if g.config.IMAP.Username != "" {
+		if err := c.Login(g.config.IMAP.Username, g.config.IMAP.Password); err != nil {
+			return nil, fmt.Errorf("failed to log in: %w", err)
+		}
+	}
+	
+	mailbox := g.config.Mailbox
+	if mailbox == "" {
+		mailbox = "INBOX"
+	}
+	if _, err := c.Select(mailbox, false); err != nil {
+		return nil, fmt.Errorf("failed to select %s: %w", mailbox, err)
+	}
+	
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search mailbox: %w", err)
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+	
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uids...)
+	
+	section := &imap.BodySectionName{Peek: true}
+	fetched := make(chan *imap.Message, len(uids))
+	if err := c.UidFetch(seqset, []imap.FetchItem{imap.FetchUid, section.FetchItem()}, fetched); err != nil {
+		return nil, fmt.Errorf("failed to fetch messages: %w", err)
+	}
+	
+	var messages []*emailMessage
+	for msg := range fetched {
+		body := msg.GetBody(section)
+		if body == nil {
+			continue
+		}
+		
+		message, err := parseEmail(body)
+		if err != nil {
+			g.logger.Warn("Skipping unparseable email", zap.Uint32("uid", msg.Uid), zap.Error(err))
+			continue
+		}
+		messages = append(messages, message)
+	}
+	
+	flags := []interface{}{imap.SeenFlag}
+	if err := c.UidStore(seqset, imap.FormatFlagsOp(imap.AddFlags, true), flags, nil); err != nil {
+		return nil, fmt.Errorf("failed to mark messages seen: %w", err)
+	}
+	
+	return messages, nil
+}
+
+func (g *EmailGateway) dialIMAP() (*client.Client, error) {
+	if g.config.IMAP.Insecure {
+		return client.Dial(g.config.IMAP.Addr)
+	}
+	return client.DialTLS(g.config.IMAP.Addr, nil)
+}
+
+func (g *EmailGateway) handle(ctx context.Context, message *emailMessage) {
+	if message.from == nil || g.isOwnAddress(message.from.Address) || message.body == "" {
+		return
+	}
+	
+	binding, channel, ok := g.resolve(message.recipients)
+	if !ok {
+		return
+	}
+	
+	// The first referenced message is the root of the thread
+	thread := message.messageID
+	if len(message.references) > 0 {
+		thread = message.references[0]
+	}
+	
+	ctx, cancel := context.WithTimeout(ctx, emailReplyTimeout)
+	defer cancel()
+	
+	content, err := g.dispatcher.handle(ctx, binding, channel, thread, message.body)
+	if err != nil {
+		g.logger.Error("Email processing failed",
+			zap.String("from", message.from.Address),
+			zap.String("agent", binding.Agent),
+			zap.Error(err))
+		return
+	}
+	
+	if err := g.reply(message, content); err != nil {
+		g.logger.Error("Failed to send email reply",
+			zap.String("to", message.from.Address),
+			zap.Error(err))
+	}
+}
+
+// resolve picks the binding for the first recipient address that has one.
+func (g *EmailGateway) resolve(recipients []string) (config.GatewayBinding, string, bool) {
+	for _, recipient := range recipients {
+		if binding, ok := g.dispatcher.bindings[recipient]; ok {
+			return binding, recipient, true
+		}
+	}
+	
+	binding, ok := g.dispatcher.resolve("*")
+	if !ok {
+		return config.GatewayBinding{}, "", false
+	}
+	
+	channel := "*"
+	if len(recipients) > 0 {
+		channel = recipients[0]
+	}
+	return binding, channel, true
+}
+
+func (g *EmailGateway) isOwnAddress(address string) bool {
+	from, err := mail.ParseAddress(g.config.From)
+	return err == nil && strings.EqualFold(from.Address, address)
+}
+
+func (g *EmailGateway) reply(original *emailMessage, content string) error {
+	from, err := mail.ParseAddress(g.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid from address: %w", err)
+	}
+	
+	to := original.from
+	if original.replyTo != nil {
+		to = original.replyTo
+	}
+	
+	subject := original.subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	
+	var header mail.Header
+	header.SetDate(time.Now())
+	header.SetAddressList("From", []*mail.Address{from})
+	header.SetAddressList("To", []*mail.Address{to})
+	header.SetSubject(subject)
+	if err := header.GenerateMessageID(); err != nil {
+		return fmt.Errorf("failed to generate message ID: %w", err)
+	}
+	if original.messageID != "" {
+		header.SetMsgIDList("In-Reply-To", []string{original.messageID})
+		header.SetMsgIDList("References", append(append([]string(nil), original.references...), original.messageID))
+	}
+	// Keeps other auto-responders from answering the agent's reply
+	header.Set("Auto-Submitted", "auto-replied")
+	header.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+	
+	var buf bytes.Buffer
+	w, err := mail.CreateSingleInlineWriter(&buf, header)
+	if err != nil {
+		return fmt.Errorf("failed to create message: %w", err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+	
+	return g.sendMail(from.Address, to.Address, buf.Bytes())
+}
+
+// sendMail delivers a message over SMTP. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it.
+func (g *EmailGateway) sendMail(from, to string, message []byte) error {
+	cfg := g.config.SMTP
+	host, port, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return fmt.Errorf("invalid smtp address: %w", err)
+	}
+	
+	var conn net.Conn
+	if port == "465" && !cfg.Insecure {
+		conn, err = tls.Dial("tcp", cfg.Addr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = net.DialTimeout("tcp", cfg.Addr, 30*time.Second)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", cfg.Addr, err)
+	}
+	
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+	
+	if ok, _ := c.Extension("STARTTLS"); ok && !cfg.Insecure && port != "465" {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	
+	return c.Quit()
+}
+
+// parseEmail extracts the headers used for routing and threading and the
+// text of a message, without the quoted text of earlier messages.
+func parseEmail(r io.Reader) (*emailMessage, error) {
+	reader, err := mail.CreateReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	
+	header := reader.Header
+	
+	// Skip automatic messages such as bounces and out-of-office replies to
+	// avoid mail loops
+	if auto := header.Get("Auto-Submitted"); auto != "" && !strings.EqualFold(auto, "no") {
+		return &emailMessage{}, nil
+	}
+	
+	message := &emailMessage{}
+	message.messageID, _ = header.MessageID()
+	message.references, _ = header.MsgIDList("References")
+	if len(message.references) == 0 {
+		message.references, _ = header.MsgIDList("In-Reply-To")
+	}
+	message.subject, _ = header.Subject()
+	
+	if from, err := header.AddressList("From"); err == nil && len(from) > 0 {
+		message.from = from[0]
+	}
+	if replyTo, err := header.AddressList("Reply-To"); err == nil && len(replyTo) > 0 {
+		message.replyTo = replyTo[0]
+	}
+	for _, key := range []string{"Delivered-To", "To", "Cc"} {
+		addresses, _ := header.AddressList(key)
+		for _, address := range addresses {
+			message.recipients = append(message.recipients, strings.ToLower(address.Address))
+		}
+	}
+	
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		
+		inline, ok := part.Header.(*mail.InlineHeader)
+		if !ok {
+			continue
+		}
+		if contentType, _, _ := inline.ContentType(); contentType != "" && contentType != "text/plain" {
+			continue
+		}
+		
+		body, err := io.ReadAll(io.LimitReader(part.Body, emailMaxBodySize))
+		if err != nil {
+			return nil, err
+		}
+		message.body = stripQuotedReply(string(body))
+		break
+	}
+	
+	return message, nil
+}
+
+// stripQuotedReply drops quoted lines and everything after the "On ...
+// wrote:" attribution line that most clients add above them.
+func stripQuotedReply(body string) string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "On ") && strings.HasSuffix(trimmed, "wrote:") {
+			break
+		}
+		if strings.HasPrefix(trimmed, ">") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
\ No newline at end of file
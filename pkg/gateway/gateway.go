@@ -0,0 +1,239 @@
+// Package gateway connects chat platforms such as Discord, Microsoft Teams
+// and email to agents. Each channel is bound to an agent in config, and each
+// channel or thread keeps its own conversation history.
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+type Gateway interface {
+	Name() string
+	Start(ctx context.Context) error
+	Close() error
+}
+
+// Processor sends a request to an agent, and reports whether it is draining
+// and refusing new work; implemented by runtime.Engine.
+type Processor interface {
+	ProcessRequest(clusterName, agentName string, req *agent.Request) (*agent.Response, error)
+	Draining() bool
+}
+
+type Manager struct {
+	gateways []Gateway
+	teams    *TeamsGateway
+	logger   *zap.Logger
+}
+
+func NewManager(cfg *config.GatewaysConfig, processor Processor, logger *zap.Logger) (*Manager, error) {
+	manager := &Manager{logger: logger}
+	sessions := newSessionStore(cfg.MaxHistory, cfg.SessionTTL)
+	
+	if cfg.Discord != nil {
+		discord, err := NewDiscordGateway(cfg.Discord, newDispatcher("discord", cfg.Discord.Bindings, processor, sessions), logger)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create discord gateway: %w", err)
+		}
+		manager.gateways = append(manager.gateways, discord)
+	}
+	
+	if cfg.Teams != nil {
+		teams := NewTeamsGateway(cfg.Teams, newDispatcher("teams", cfg.Teams.Bindings, processor, sessions), logger)
+		manager.gateways = append(manager.gateways, teams)
+		manager.teams = teams
+	}
+	
+	if cfg.Email != nil {
+		email := NewEmailGateway(cfg.Email, newDispatcher("email", emailBindings(cfg.Email.Bindings), processor, sessions), logger)
+		manager.gateways = append(manager.gateways, email)
+	}
+	
+	return manager, nil
+}
+
+func (m *Manager) Start(ctx context.Context) error {
+	for _, gateway := range m.gateways {
+		if err := gateway.Start(ctx); err != nil {
+			return fmt.Errorf("failed to start %s gateway: %w", gateway.Name(), err)
+		}
+		m.logger.Info("Gateway started", zap.String("gateway", gateway.Name()))
+	}
+	return nil
+}
+
+// TeamsHandler returns the Bot Framework messaging endpoint, or nil when
+// Teams is not configured.
+func (m *Manager) TeamsHandler() http.Handler {
+	if m.teams == nil {
+		return nil
+	}
+	return m.teams
+}
+
+func (m *Manager) Close() error {
+	var firstErr error
+	for _, gateway := range m.gateways {
+		if err := gateway.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// dispatcher resolves channel bindings and keeps per-channel or per-thread
+// conversation history for one platform.
+type dispatcher struct {
+	platform  string
+	bindings  map[string]config.GatewayBinding
+	processor Processor
+	sessions  *sessionStore
+}
+
+func newDispatcher(platform string, bindings []config.GatewayBinding, processor Processor, sessions *sessionStore) *dispatcher {
+	d := &dispatcher{
+		platform:  platform,
+		bindings:  make(map[string]config.GatewayBinding, len(bindings)),
+		processor: processor,
+		sessions:  sessions,
+	}
+	for _, binding := range bindings {
+		d.bindings[binding.Channel] = binding
+	}
+	return d
+}
+
+func (d *dispatcher) resolve(channelID string) (config.GatewayBinding, bool) {
+	if binding, ok := d.bindings[channelID]; ok {
+		return binding, true
+	}
+	binding, ok := d.bindings["*"]
+	return binding, ok
+}
+
+// handle sends text to the agent bound to channelID and returns its reply.
+// threadID separates conversations within a channel and may be empty.
+func (d *dispatcher) handle(ctx context.Context, binding config.GatewayBinding, channelID, threadID, text string) (string, error) {
+	if d.processor.Draining() {
+		return "", fmt.Errorf("server is draining")
+	}
+	key := strings.Join([]string{d.platform, channelID, threadID}, ":")
+	messages := d.sessions.append(key, agent.Message{Role: "user", Content: text})
+	
+	req := &agent.Request{
+		ID:       fmt.Sprintf("%s-%d", d.platform, time.Now().UnixNano()),
+		Messages: messages,
+		Context: map[string]interface{}{
+			"gateway": d.platform,
+			"channel": channelID,
+			"thread":  threadID,
+		},
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Timeout = time.Until(deadline)
+	}
+	
+	resp, err := d.processor.ProcessRequest(binding.Cluster, binding.Agent, req)
+	if err != nil {
+		return "", err
+	}
+	if resp.Error != "" {
+		return "", fmt.Errorf("%s", resp.Error)
+	}
+	
+	d.sessions.append(key, agent.Message{Role: "assistant", Content: resp.Content})
+	return resp.Content, nil
+}
+
+type session struct {
+	messages  []agent.Message
+	updatedAt time.Time
+}
+
+// sessionStore holds recent conversation history keyed by platform, channel
+// and thread. Idle sessions are dropped after ttl.
+type sessionStore struct {
+	maxHistory int
+	ttl        time.Duration
+	sessions   map[string]*session
+	mu         sync.Mutex
+}
+
+func newSessionStore(maxHistory int, ttl time.Duration) *sessionStore {
+	if maxHistory <= 0 {
+		maxHistory = 20
+	}
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &sessionStore{
+		maxHistory: maxHistory,
+		ttl:        ttl,
+		sessions:   make(map[string]*session),
+	}
+}
+
+// append adds a message to a session and returns a copy of its history.
+func (s *sessionStore) append(key string, message agent.Message) []agent.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	now := time.Now()
+	for k, sess := range s.sessions {
+		if now.Sub(sess.updatedAt) > s.ttl {
+			delete(s.sessions, k)
+		}
+	}
+	
+	sess, exists := s.sessions[key]
+	if !exists {
+		sess = &session{}
+		s.sessions[key] = sess
+	}
+	
+	sess.messages = append(sess.messages, message)
+	if len(sess.messages) > s.maxHistory {
+		sess.messages = sess.messages[len(sess.messages)-s.maxHistory:]
+	}
+	sess.updatedAt = now
+	
+	return append([]agent.Message(nil), sess.messages...)
+}
+
+// emailBindings lowercases bound addresses, which are matched against
+// lowercased recipients.
+func emailBindings(bindings []config.GatewayBinding) []config.GatewayBinding {
+	normalized := make([]config.GatewayBinding, len(bindings))
+	for i, binding := range bindings {
+		binding.Channel = strings.ToLower(binding.Channel)
+		normalized[i] = binding
+	}
+	return normalized
+}
+
+// splitMessage breaks text into chunks no longer than limit bytes,
+// preferring to split at line breaks.
+func splitMessage(text string, limit int) []string {
+	var chunks []string
+	for len(text) > limit {
+		cut := strings.LastIndex(text[:limit], "\n")
+		if cut <= 0 {
+			cut = limit
+		}
+		chunks = append(chunks, text[:cut])
+		text = strings.TrimLeft(text[cut:], "\n")
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
\ No newline at end of file
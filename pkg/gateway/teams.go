@@ -0,0 +1,385 @@
+package gateway
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+const (
+	botFrameworkOpenIDURL = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+	botFrameworkIssuer    = "https://api.botframework.com"
+	botFrameworkScope     = "https://api.botframework.com/.default"
+	botFrameworkTenant    = "botframework.com"
+	
+	teamsMaxActivitySize = 1 << 20
+	teamsReplyTimeout    = 2 * time.Minute
+	teamsKeyCacheTTL     = 24 * time.Hour
+)
+
+var teamsMentionPattern = regexp.MustCompile(`<at>[^<]*</at>`)
+
+// TeamsGateway receives Bot Framework activities from Microsoft Teams on an
+// HTTP endpoint and replies through the Bot Connector API.
+type TeamsGateway struct {
+	config     *config.TeamsGatewayConfig
+	dispatcher *dispatcher
+	client     *http.Client
+	logger     *zap.Logger
+	ctx        context.Context
+	
+	keys        map[string]*rsa.PublicKey
+	keysFetched time.Time
+	keysMu      sync.Mutex
+	
+	token       string
+	tokenExpiry time.Time
+	tokenMu     sync.Mutex
+}
+
+type teamsActivity struct {
+	Type         string                 `json:"type"`
+	ID           string                 `json:"id"`
+	Text         string                 `json:"text,omitempty"`
+	ServiceURL   string                 `json:"serviceUrl,omitempty"`
+	ReplyToID    string                 `json:"replyToId,omitempty"`
+	From         *teamsAccount          `json:"from,omitempty"`
+	Recipient    *teamsAccount          `json:"recipient,omitempty"`
+	Conversation *teamsConversation     `json:"conversation,omitempty"`
+	ChannelData  map[string]interface{} `json:"channelData,omitempty"`
+}
+
+type teamsAccount struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type teamsConversation struct {
+	ID string `json:"id"`
+}
+
+func NewTeamsGateway(cfg *config.TeamsGatewayConfig, dispatcher *dispatcher, logger *zap.Logger) *TeamsGateway {
+	return &TeamsGateway{
+		config:     cfg,
+		dispatcher: dispatcher,
+		client:     &http.Client{Timeout: 30 * time.Second},
+		logger:     logger,
+		ctx:        context.Background(),
+	}
+}
+
+func (g *TeamsGateway) Name() string {
+	return "teams"
+}
+
+func (g *TeamsGateway) Start(ctx context.Context) error {
+	g.ctx = ctx
+	return nil
+}
+
+func (g *TeamsGateway) Close() error {
+	return nil
+}
+
+// ServeHTTP handles the Bot Framework messaging endpoint. Activities are
+// acknowledged immediately and answered asynchronously, since agents
+// routinely take longer than the connector's request timeout.
+func (g *TeamsGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	
+	body, err := io.ReadAll(io.LimitReader(r.Body, teamsMaxActivitySize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	
+	var activity teamsActivity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		http.Error(w, "invalid activity", http.StatusBadRequest)
+		return
+	}
+	
+	if !g.config.InsecureSkipVerify {
+		if err := g.authenticate(r.Context(), r.Header.Get("Authorization"), activity.ServiceURL); err != nil {
+			g.logger.Warn("Rejected Teams activity", zap.Error(err))
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+	
+	w.WriteHeader(http.StatusOK)
+	
+	if activity.Type != "message" || activity.Conversation == nil || activity.ServiceURL == "" {
+		return
+	}
+	
+	text := strings.TrimSpace(teamsMentionPattern.ReplaceAllString(activity.Text, ""))
+	if text == "" {
+		return
+	}
+	
+	channelID := activity.Conversation.ID
+	if channel, ok := activity.ChannelData["channel"].(map[string]interface{}); ok {
+		if id, ok := channel["id"].(string); ok && id != "" {
+			channelID = id
+		}
+	}
+	
+	binding, ok := g.dispatcher.resolve(channelID)
+	if !ok {
+		return
+	}
+	
+	go g.reply(&activity, binding, channelID, text)
+}
+
+func (g *TeamsGateway) reply(activity *teamsActivity, binding config.GatewayBinding, channelID, text string) {
+	ctx, cancel := context.WithTimeout(g.ctx, teamsReplyTimeout)
+	defer cancel()
+	
+	g.send(ctx, activity, &teamsActivity{Type: "typing"})
+	
+	// A channel conversation ID identifies the reply thread, so each thread
+	// keeps its own history
+	content, err := g.dispatcher.handle(ctx, binding, channelID, activity.Conversation.ID, text)
+	if err != nil {
+		g.logger.Error("Teams message processing failed",
+			zap.String("channel", channelID),
+			zap.String("agent", binding.Agent),
+			zap.Error(err))
+		content = "Sorry, I couldn't process that message."
+	}
+	
+	if err := g.send(ctx, activity, &teamsActivity{Type: "message", Text: content}); err != nil {
+		g.logger.Error("Failed to send Teams reply",
+			zap.String("conversation", activity.Conversation.ID),
+			zap.Error(err))
+	}
+}
+
+// send posts an activity as a reply to incoming.
+func (g *TeamsGateway) send(ctx context.Context, incoming, outgoing *teamsActivity) error {
+	outgoing.ReplyToID = incoming.ID
+	outgoing.Conversation = incoming.Conversation
+	outgoing.From = incoming.Recipient
+	outgoing.Recipient = incoming.From
+	
+	data, err := json.Marshal(outgoing)
+	if err != nil {
+		return fmt.Errorf("failed to marshal activity: %w", err)
+	}
+	
+	endpoint := fmt.Sprintf("%s/v3/conversations/%s/activities/%s",
+		strings.TrimSuffix(incoming.ServiceURL, "/"),
+		url.PathEscape(incoming.Conversation.ID),
+		url.PathEscape(incoming.ID))
+		
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("bot connector returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// accessToken returns a cached client-credentials token for the Bot
+// Connector API, refreshing it shortly before it expires.
+func (g *TeamsGateway) accessToken(ctx context.Context) (string, error) {
+	g.tokenMu.Lock()
+	defer g.tokenMu.Unlock()
+	
+	if g.token != "" && time.Until(g.tokenExpiry) > time.Minute {
+		return g.token, nil
+	}
+	
+	tenant := g.config.TenantID
+	if tenant == "" {
+		tenant = botFrameworkTenant
+	}
+	
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {g.config.AppID},
+		"client_secret": {g.config.AppPassword},
+		"scope":         {botFrameworkScope},
+	}
+	
+	tokenURL := fmt.Sprintf("https://login.microsoftonline.com/%s/oauth2/v2.0/token", url.PathEscape(tenant))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request access token: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode token response: %w", err)
+	}
+	if resp.StatusCode >= 400 || result.AccessToken == "" {
+		return "", fmt.Errorf("failed to obtain access token: HTTP %d: %s", resp.StatusCode, result.ErrorDescription)
+	}
+	
+	g.token = result.AccessToken
+	g.tokenExpiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return g.token, nil
+}
+
+// authenticate validates the JWT the Bot Connector attaches to every
+// activity, as described in the Bot Framework authentication spec.
+func (g *TeamsGateway) authenticate(ctx context.Context, header, serviceURL string) error {
+	tokenString := strings.TrimPrefix(header, "Bearer ")
+	if tokenString == "" || tokenString == header {
+		return fmt.Errorf("missing bearer token")
+	}
+	
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return g.signingKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(botFrameworkIssuer),
+		jwt.WithAudience(g.config.AppID),
+		jwt.WithLeeway(5*time.Minute),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+	
+	// The token is bound to the connector instance that sent it
+	if claimed, ok := claims["serviceurl"].(string); ok && claimed != "" {
+		if strings.TrimSuffix(claimed, "/") != strings.TrimSuffix(serviceURL, "/") {
+			return fmt.Errorf("service URL %q does not match token", serviceURL)
+		}
+	}
+	
+	return nil
+}
+
+func (g *TeamsGateway) signingKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	g.keysMu.Lock()
+	defer g.keysMu.Unlock()
+	
+	if key, ok := g.keys[kid]; ok && time.Since(g.keysFetched) < teamsKeyCacheTTL {
+		return key, nil
+	}
+	
+	// Unknown key IDs trigger a refresh since keys are rotated regularly
+	keys, err := g.fetchSigningKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	g.keys = keys
+	g.keysFetched = time.Now()
+	
+	key, ok := keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	return key, nil
+}
+
+func (g *TeamsGateway) fetchSigningKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var openID struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := g.getJSON(ctx, botFrameworkOpenIDURL, &openID); err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenID configuration: %w", err)
+	}
+	
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := g.getJSON(ctx, openID.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch signing keys: %w", err)
+	}
+	
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	
+	return keys, nil
+}
+
+func (g *TeamsGateway) getJSON(ctx context.Context, target string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
\ No newline at end of file
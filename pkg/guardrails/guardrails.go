@@ -0,0 +1,271 @@
+// Package guardrails checks the messages sent to agents and the answers
+// they give against configured rules.
+package guardrails
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/tools"
+)
+
+const (
+	redactedText      = "[redacted]"
+	defaultMaxRetries = 2
+)
+
+type Stage string
+
+const (
+	StageInput  Stage = "input"
+	StageOutput Stage = "output"
+)
+
+// Violation is a rule that text failed, with what was done about it.
+// Reasons never quote the offending text, so violations can be logged.
+type Violation struct {
+	Guardrail string `json:"guardrail"`
+	Type      string `json:"type"`
+	Stage     Stage  `json:"stage"`
+	Action    string `json:"action"`
+	Reason    string `json:"reason"`
+	// Message is the guardrail's message for blocked text
+	Message string `json:"-"`
+}
+
+// Result is text after the rules have been applied. Blocked is the
+// violation that stopped the checks, for block and retry actions.
+type Result struct {
+	Text       string
+	Violations []Violation
+	Blocked    *Violation
+}
+
+// rule is a compiled guardrail. match returns why text breaks the rule, or
+// "" if it does not; redact is nil for rules that cannot redact.
+type rule struct {
+	name    string
+	kind    string
+	action  string
+	message string
+	match   func(ctx context.Context, stage Stage, text string) (string, error)
+	redact  func(text string) string
+}
+
+// Pipeline is an agent's guardrails, applied in order.
+type Pipeline struct {
+	input      []*rule
+	output     []*rule
+	maxRetries int
+}
+
+// New compiles an agent's guardrails.
+func New(cfg *config.AgentGuardrails) (*Pipeline, error) {
+	p := &Pipeline{maxRetries: cfg.MaxRetries}
+	for i := range cfg.Input {
+		r, err := compile(&cfg.Input[i])
+		if err != nil {
+			return nil, fmt.Errorf("input %d: %w", i, err)
+		}
+		p.input = append(p.input, r)
+	}
+	for i := range cfg.Output {
+		r, err := compile(&cfg.Output[i])
+		if err != nil {
+			return nil, fmt.Errorf("output %d: %w", i, err)
+		}
+		p.output = append(p.output, r)
+	}
+	return p, nil
+}
+
+// HasInput reports whether the pipeline checks messages.
+func (p *Pipeline) HasInput() bool {
+	return p != nil && len(p.input) > 0
+}
+
+// HasOutput reports whether the pipeline checks answers.
+func (p *Pipeline) HasOutput() bool {
+	return p != nil && len(p.output) > 0
+}
+
+// MaxRetries is how many answers retry actions may send back, 2 unless
+// configured.
+func (p *Pipeline) MaxRetries() int {
+	if p.maxRetries > 0 {
+		return p.maxRetries
+	}
+	return defaultMaxRetries
+}
+
+// Check applies the stage's rules to text in order. Redactions carry over
+// to later rules; a block or retry stops the checks. A rule that cannot be
+// checked, such as an unreachable moderation hook, blocks the text.
+func (p *Pipeline) Check(ctx context.Context, stage Stage, text string) *Result {
+	rules := p.input
+	if stage == StageOutput {
+		rules = p.output
+	}
+	
+	result := &Result{Text: text}
+	for _, r := range rules {
+		reason, err := r.match(ctx, stage, result.Text)
+		action := r.action
+		if err != nil {
+			reason, action = fmt.Sprintf("check failed: %v", err), config.GuardrailBlock
+		}
+		if reason == "" {
+			continue
+		}
+		
+		violation := Violation{
+			Guardrail: r.name,
+			Type:      r.kind,
+			Stage:     stage,
+			Action:    action,
+			Reason:    reason,
+			Message:   r.message,
+		}
+		result.Violations = append(result.Violations, violation)
+		if action == config.GuardrailRedact {
+			result.Text = r.redact(result.Text)
+			continue
+		}
+		result.Blocked = &result.Violations[len(result.Violations)-1]
+		return result
+	}
+	return result
+}
+
+func compile(cfg *config.Guardrail) (*rule, error) {
+	r := &rule{
+		name:    cfg.Name,
+		kind:    cfg.Type,
+		action:  cfg.Action,
+		message: cfg.Message,
+	}
+	if r.name == "" {
+		r.name = cfg.Type
+	}
+	if r.action == "" {
+		r.action = config.GuardrailBlock
+	}
+	
+	switch cfg.Type {
+	case config.GuardrailMaxLength:
+		r.match = func(_ context.Context, _ Stage, text string) (string, error) {
+			if n := len([]rune(text)); n > cfg.MaxLength {
+				return fmt.Sprintf("%d characters is over the limit of %d", n, cfg.MaxLength), nil
+			}
+			return "", nil
+		}
+		r.redact = func(text string) string {
+			return string([]rune(text)[:cfg.MaxLength])
+		}
+	case config.GuardrailBannedTopics:
+		quoted := make([]string, len(cfg.Topics))
+		for i, topic := range cfg.Topics {
+			quoted[i] = regexp.QuoteMeta(topic)
+		}
+		re := regexp.MustCompile(`(?i)\b(` + strings.Join(quoted, "|") + `)\b`)
+		r.match = func(_ context.Context, _ Stage, text string) (string, error) {
+			found := distinct(re.FindAllString(text, -1), strings.ToLower)
+			if len(found) == 0 {
+				return "", nil
+			}
+			return fmt.Sprintf("mentions banned topics: %s", strings.Join(found, ", ")), nil
+		}
+		r.redact = func(text string) string {
+			return re.ReplaceAllLiteralString(text, redactedText)
+		}
+	case config.GuardrailRegex:
+		patterns := make([]*regexp.Regexp, 0, len(cfg.Patterns))
+		for _, pattern := range cfg.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+			}
+			patterns = append(patterns, re)
+		}
+		r.match = func(_ context.Context, _ Stage, text string) (string, error) {
+			var matched []string
+			for _, re := range patterns {
+				if re.MatchString(text) {
+					matched = append(matched, fmt.Sprintf("%q", re.String()))
+				}
+			}
+			if len(matched) == 0 {
+				return "", nil
+			}
+			return fmt.Sprintf("matches %s", strings.Join(matched, ", ")), nil
+		}
+		r.redact = func(text string) string {
+			for _, re := range patterns {
+				text = re.ReplaceAllLiteralString(text, redactedText)
+			}
+			return text
+		}
+	case config.GuardrailPII:
+		detector := newPIIDetector(cfg.PII)
+		r.match = func(_ context.Context, _ Stage, text string) (string, error) {
+			kinds := detector.find(text)
+			if len(kinds) == 0 {
+				return "", nil
+			}
+			return fmt.Sprintf("contains personal data: %s", strings.Join(kinds, ", ")), nil
+		}
+		r.redact = detector.redact
+	case config.GuardrailSchema:
+		schema, err := tools.CompileSchema(cfg.Schema)
+		if err != nil {
+			return nil, err
+		}
+		r.match = func(_ context.Context, _ Stage, text string) (string, error) {
+			var object map[string]interface{}
+			if err := json.Unmarshal([]byte(trimFences(text)), &object); err != nil {
+				return "not a JSON object", nil
+			}
+			if err := schema.Validate(object); err != nil {
+				return fmt.Sprintf("does not match the schema: %v", err), nil
+			}
+			return "", nil
+		}
+	case config.GuardrailModeration:
+		hook := newModerationHook(cfg)
+		r.match = hook.check
+	default:
+		return nil, fmt.Errorf("unsupported guardrail type %q", cfg.Type)
+	}
+	return r, nil
+}
+
+// trimFences strips the code fence models sometimes put around JSON.
+func trimFences(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```json")
+		text = strings.TrimPrefix(text, "```")
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	return text
+}
+
+// distinct returns the values, normalized, without repeats and sorted.
+func distinct(values []string, normalize func(string) string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, value := range values {
+		value = normalize(value)
+		if !seen[value] {
+			seen[value] = true
+			out = append(out, value)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
\ No newline at end of file
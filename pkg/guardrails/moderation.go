@@ -0,0 +1,85 @@
+package guardrails
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+)
+
+const defaultModerationTimeout = 10 * time.Second
+
+// moderationHook asks an external service whether text is acceptable. It
+// is sent {"text": ..., "stage": "input"} and answers
+// {"flagged": true, "categories": ["harassment"], "reason": "..."}.
+type moderationHook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newModerationHook(cfg *config.Guardrail) *moderationHook {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultModerationTimeout
+	}
+	return &moderationHook{
+		url:     cfg.URL,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+func (h *moderationHook) check(ctx context.Context, stage Stage, text string) (string, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"text":  text,
+		"stage": stage,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range h.headers {
+		req.Header.Set(name, value)
+	}
+	
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("moderation hook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("moderation hook returned %s", resp.Status)
+	}
+	
+	var verdict struct {
+		Flagged    bool     `json:"flagged"`
+		Categories []string `json:"categories"`
+		Reason     string   `json:"reason"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&verdict); err != nil {
+		return "", fmt.Errorf("moderation hook: invalid response: %w", err)
+	}
+	if !verdict.Flagged {
+		return "", nil
+	}
+	
+	reason := "flagged by moderation"
+	if len(verdict.Categories) > 0 {
+		reason += ": " + strings.Join(verdict.Categories, ", ")
+	}
+	if verdict.Reason != "" {
+		reason += " (" + verdict.Reason + ")"
+	}
+	return reason, nil
+}
\ No newline at end of file
@@ -0,0 +1,91 @@
+package guardrails
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/config"
+)
+
+// piiPatterns find personal data by kind. They favor precision over recall:
+// card numbers must pass the Luhn check, and phone numbers need separators.
+var piiPatterns = map[string]*regexp.Regexp{
+	"email":       regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	"phone":       regexp.MustCompile(`(?:\+\d{1,3}[\s.-]?)?(?:\(\d{3}\)|\b\d{3})[\s.-]\d{3}[\s.-]\d{4}\b`),
+	"credit_card": regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+	"ssn":         regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+	"ip_address":  regexp.MustCompile(`\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b`),
+	"iban":        regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,4})?\b`),
+}
+
+type piiDetector struct {
+	kinds []string
+}
+
+// newPIIDetector detects the given kinds of personal data, or every kind
+// when none are given.
+func newPIIDetector(kinds []string) *piiDetector {
+	if len(kinds) == 0 {
+		kinds = config.PIIKinds
+	}
+	sorted := append([]string(nil), kinds...)
+	sort.Strings(sorted)
+	return &piiDetector{kinds: sorted}
+}
+
+// find returns the kinds of personal data in text.
+func (d *piiDetector) find(text string) []string {
+	var found []string
+	for _, kind := range d.kinds {
+		for _, match := range piiPatterns[kind].FindAllString(text, -1) {
+			if kind != "credit_card" || luhnValid(match) {
+				found = append(found, kind)
+				break
+			}
+		}
+	}
+	return found
+}
+
+func (d *piiDetector) redact(text string) string {
+	for _, kind := range d.kinds {
+		text = piiPatterns[kind].ReplaceAllStringFunc(text, func(match string) string {
+			if kind == "credit_card" && !luhnValid(match) {
+				return match
+			}
+			return "[redacted " + strings.ReplaceAll(kind, "_", " ") + "]"
+		})
+	}
+	return text
+}
+
+// RedactPII replaces the given kinds of personal data in text, or every
+// kind when none are given, as pii guardrails with the redact action do.
+func RedactPII(text string, kinds []string) string {
+	return newPIIDetector(kinds).redact(text)
+}
+
+// luhnValid reports whether the digits of number pass the Luhn checksum
+// card numbers carry.
+func luhnValid(number string) bool {
+	sum, digits := 0, 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && sum%10 == 0
+}
\ No newline at end of file
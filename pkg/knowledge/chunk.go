@@ -0,0 +1,213 @@
+package knowledge
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	DefaultChunkSize    = 1000
+	DefaultChunkOverlap = 100
+)
+
+// ChunkOptions sets how documents are split. Size and Overlap are in
+// characters; chunks of the paragraph, sentence and markdown strategies
+// repeat the trailing paragraphs or sentences of the chunk before them
+// that fit in Overlap.
+type ChunkOptions struct {
+	Strategy string
+	Size     int
+	Overlap  int
+}
+
+var sentenceEnd = regexp.MustCompile(`[.!?]+["')\]]*\s+`)
+
+// ChunkStrategies lists the supported strategies in alphabetical order.
+func ChunkStrategies() []string {
+	return []string{"fixed", "markdown", "paragraph", "sentence"}
+}
+
+// Split divides text into chunks with the given strategy, paragraph when
+// none is set.
+func Split(text string, opts ChunkOptions) ([]string, error) {
+	if opts.Size <= 0 {
+		opts.Size = DefaultChunkSize
+	}
+	if opts.Overlap < 0 || opts.Overlap >= opts.Size {
+		return nil, fmt.Errorf("chunk overlap must be at least 0 and less than the chunk size")
+	}
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil, nil
+	}
+	
+	switch opts.Strategy {
+	case "fixed":
+		return splitFixed(text, opts.Size, opts.Overlap), nil
+	case "", "paragraph":
+		return pack(paragraphs(text), "\n\n", opts), nil
+	case "sentence":
+		return pack(sentences(text), " ", opts), nil
+	case "markdown":
+		return splitMarkdown(text, opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported chunk strategy %q, expected one of %s", opts.Strategy, strings.Join(ChunkStrategies(), ", "))
+	}
+}
+
+// splitFixed cuts text into windows of size characters, each starting
+// overlap characters before the end of the one before. Windows end at the
+// last whitespace in their second half, so words are not cut.
+func splitFixed(text string, size, overlap int) []string {
+	runes := []rune(text)
+	var chunks []string
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			for cut := end; cut > start+size/2; cut-- {
+				if unicode.IsSpace(runes[cut]) {
+					end = cut
+					break
+				}
+			}
+		}
+		if chunk := strings.TrimSpace(string(runes[start:end])); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+		if end == len(runes) {
+			break
+		}
+		next := end - overlap
+		if next <= start {
+			next = end
+		}
+		start = next
+	}
+	return chunks
+}
+
+func paragraphs(text string) []string {
+	var units []string
+	for _, paragraph := range strings.Split(text, "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			units = append(units, paragraph)
+		}
+	}
+	return units
+}
+
+func sentences(text string) []string {
+	var units []string
+	for _, paragraph := range paragraphs(text) {
+		start := 0
+		for _, loc := range sentenceEnd.FindAllStringIndex(paragraph, -1) {
+			units = append(units, strings.TrimSpace(paragraph[start:loc[1]]))
+			start = loc[1]
+		}
+		if rest := strings.TrimSpace(paragraph[start:]); rest != "" {
+			units = append(units, rest)
+		}
+	}
+	return units
+}
+
+// splitMarkdown chunks each section under a heading on its own, starting
+// every chunk with the section's heading so it keeps its context.
+func splitMarkdown(text string, opts ChunkOptions) []string {
+	var chunks []string
+	var heading string
+	var body []string
+	flush := func() {
+		section := strings.TrimSpace(strings.Join(body, "\n"))
+		body = nil
+		if section == "" {
+			return
+		}
+		sectionOpts := opts
+		if heading != "" {
+			sectionOpts.Size -= len([]rune(heading)) + 1
+			if sectionOpts.Size <= opts.Overlap {
+				sectionOpts.Size = opts.Size
+			}
+		}
+		for _, chunk := range pack(paragraphs(section), "\n\n", sectionOpts) {
+			if heading != "" {
+				chunk = heading + "\n" + chunk
+			}
+			chunks = append(chunks, chunk)
+		}
+	}
+	
+	inFence := false
+	for _, line := range strings.Split(text, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+		}
+		if !inFence && strings.HasPrefix(line, "#") {
+			flush()
+			heading = strings.TrimSpace(line)
+			continue
+		}
+		body = append(body, line)
+	}
+	flush()
+	return chunks
+}
+
+// pack joins units into chunks of up to opts.Size characters. Units longer
+// than that are split as with the fixed strategy.
+func pack(units []string, sep string, opts ChunkOptions) []string {
+	var chunks []string
+	var current []string
+	length := 0
+	for _, unit := range units {
+		n := len([]rune(unit))
+		if n > opts.Size {
+			if len(current) > 0 {
+				chunks = append(chunks, strings.Join(current, sep))
+				current, length = nil, 0
+			}
+			chunks = append(chunks, splitFixed(unit, opts.Size, opts.Overlap)...)
+			continue
+		}
+		if len(current) > 0 && length+len(sep)+n > opts.Size {
+			chunks = append(chunks, strings.Join(current, sep))
+			current, length = overlapTail(current, sep, opts.Overlap, opts.Size-n-len(sep))
+		}
+		if len(current) > 0 {
+			length += len(sep)
+		}
+		current = append(current, unit)
+		length += n
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, sep))
+	}
+	return chunks
+}
+
+// overlapTail returns the trailing units that fit in overlap characters,
+// and in the room left for the next unit, with their joined length.
+func overlapTail(units []string, sep string, overlap, room int) ([]string, int) {
+	if room < overlap {
+		overlap = room
+	}
+	length := 0
+	start := len(units)
+	for start > 0 {
+		n := len([]rune(units[start-1]))
+		if length > 0 {
+			n += len(sep)
+		}
+		if length+n > overlap {
+			break
+		}
+		length += n
+		start--
+	}
+	return append([]string(nil), units[start:]...), length
+}
\ No newline at end of file
@@ -0,0 +1,56 @@
+package knowledge
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/goagents/goagents/pkg/tools"
+)
+
+// Extract returns the title, if it has one, and text of a document. HTML
+// is read for its readable text; other text types are used as they are.
+func Extract(data []byte, mimeType, name string) (string, string, error) {
+	mediaType := DetectType(data, mimeType, name)
+	
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		return tools.PageText(bytes.NewReader(data), mimeType)
+	case strings.HasPrefix(mediaType, "text/"), mediaType == "application/json",
+		mediaType == "application/xml", mediaType == "application/x-yaml":
+		if !utf8.Valid(data) {
+			return "", "", fmt.Errorf("%w: not valid UTF-8 text", ErrUnsupported)
+		}
+		return "", string(data), nil
+	default:
+		return "", "", fmt.Errorf("%w: type %s", ErrUnsupported, mediaType)
+	}
+}
+
+// DetectType returns the media type of a document, guessed from its name
+// and content when mimeType is empty or generic.
+func DetectType(data []byte, mimeType, name string) string {
+	mediaType, _, _ := mime.ParseMediaType(mimeType)
+	if mediaType != "" && mediaType != "application/octet-stream" {
+		return mediaType
+	}
+	
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return "text/markdown"
+	case ".txt", ".rst":
+		return "text/plain"
+	case ".html", ".htm":
+		return "text/html"
+	case ".json":
+		return "application/json"
+	case ".yaml", ".yml":
+		return "application/x-yaml"
+	}
+	mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	return mediaType
+}
\ No newline at end of file
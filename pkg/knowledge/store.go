@@ -0,0 +1,243 @@
+// Package knowledge keeps the documents agents answer from. Documents are
+// split into chunks, which are stored with their embeddings and ranked by
+// cosine similarity to a query.
+package knowledge
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/tools"
+)
+
+var (
+	ErrNotFound    = errors.New("document not found")
+	ErrTooLarge    = errors.New("document too large")
+	ErrUnsupported = errors.New("unsupported document")
+)
+
+// Document describes an ingested document. Documents belong to an agent's
+// cluster and name.
+type Document struct {
+	ID      string `json:"id"`
+	Cluster string `json:"cluster"`
+	Agent   string `json:"agent"`
+	Title   string `json:"title,omitempty"`
+	// Source is the URL or file name the document came from
+	Source    string            `json:"source,omitempty"`
+	MimeType  string            `json:"mime_type"`
+	Size      int               `json:"size"`
+	Chunks    int               `json:"chunks"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// Chunk is a part of a document and its embedding.
+type Chunk struct {
+	Index     int       `json:"index"`
+	Content   string    `json:"content"`
+	Embedding []float32 `json:"embedding"`
+}
+
+// Match is a chunk found by a search, with the document it is from.
+type Match struct {
+	Document Document `json:"document"`
+	Index    int      `json:"index"`
+	Content  string   `json:"content"`
+	Score    float64  `json:"score"`
+}
+
+type entry struct {
+	Document *Document `json:"document"`
+	Chunks   []Chunk   `json:"chunks"`
+}
+
+// Store keeps documents in memory, writing them all to a JSON file when a
+// path is set.
+type Store struct {
+	path    string
+	entries map[string][]*entry
+	mu      sync.RWMutex
+}
+
+func NewStore(path string) (*Store, error) {
+	store := &Store{
+		path:    path,
+		entries: make(map[string][]*entry),
+	}
+	if path == "" {
+		return store, nil
+	}
+	
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read knowledge file: %w", err)
+	}
+	var entries []*entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse knowledge file: %w", err)
+	}
+	for _, e := range entries {
+		key := agentKey(e.Document.Cluster, e.Document.Agent)
+		store.entries[key] = append(store.entries[key], e)
+	}
+	
+	return store, nil
+}
+
+func agentKey(cluster, agentName string) string {
+	return cluster + "/" + agentName
+}
+
+// NewDocument returns a document with a new ID.
+func NewDocument(cluster, agentName string) (*Document, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate document ID: %w", err)
+	}
+	return &Document{
+		ID:        "doc-" + hex.EncodeToString(buf),
+		Cluster:   cluster,
+		Agent:     agentName,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// Add stores a document and its chunks. A document from the same source
+// as one the agent already has replaces it, so ingesting a URL again
+// refreshes it.
+func (s *Store) Add(doc *Document, chunks []Chunk) error {
+	doc.Chunks = len(chunks)
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	key := agentKey(doc.Cluster, doc.Agent)
+	entries := s.entries[key]
+	replaced := false
+	for i, e := range entries {
+		if doc.Source != "" && e.Document.Source == doc.Source {
+			entries[i] = &entry{Document: doc, Chunks: chunks}
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		entries = append(entries, &entry{Document: doc, Chunks: chunks})
+	}
+	s.entries[key] = entries
+	
+	return s.save()
+}
+
+// Documents returns an agent's documents, most recently ingested first.
+func (s *Store) Documents(cluster, agentName string) []Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	entries := s.entries[agentKey(cluster, agentName)]
+	docs := make([]Document, 0, len(entries))
+	for _, e := range entries {
+		docs = append(docs, *e.Document)
+	}
+	sort.SliceStable(docs, func(i, j int) bool {
+		return docs[i].CreatedAt.After(docs[j].CreatedAt)
+	})
+	return docs
+}
+
+func (s *Store) Delete(cluster, agentName, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	key := agentKey(cluster, agentName)
+	entries := s.entries[key]
+	for i, e := range entries {
+		if e.Document.ID == id {
+			s.entries[key] = append(entries[:i:i], entries[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Search returns an agent's chunks closest to vector, best first, that
+// score at least threshold.
+func (s *Store) Search(cluster, agentName string, vector []float32, topK int, threshold float64) []Match {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	var matches []Match
+	for _, e := range s.entries[agentKey(cluster, agentName)] {
+		for _, chunk := range e.Chunks {
+			if len(chunk.Embedding) != len(vector) {
+				continue
+			}
+			score := tools.CosineSimilarity(vector, chunk.Embedding)
+			if score < threshold {
+				continue
+			}
+			matches = append(matches, Match{
+				Document: *e.Document,
+				Index:    chunk.Index,
+				Content:  chunk.Content,
+				Score:    score,
+			})
+		}
+	}
+	
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches
+}
+
+// save writes every document to the file atomically. The caller must hold
+// the write lock.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	
+	var entries []*entry
+	for _, list := range s.entries {
+		entries = append(entries, list...)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode knowledge: %w", err)
+	}
+	
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".knowledge-*")
+	if err != nil {
+		return fmt.Errorf("failed to write knowledge file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write knowledge file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write knowledge file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write knowledge file: %w", err)
+	}
+	
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,139 @@
+package mcpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	sessionHeader = "Mcp-Session-Id"
+	
+	// Sessions unused for this long are forgotten; their clients are asked
+	// to initialize again
+	sessionIdleTimeout = 30 * time.Minute
+	// maxSessions bounds the sessions kept, the least recently used being
+	// forgotten first
+	maxSessions = 10000
+)
+
+// ServeHTTP implements the MCP Streamable HTTP transport. Every request is
+// answered with a JSON body; the server never initiates messages, so the
+// optional GET stream is not offered.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Browsers may be tricked into calling a local server (DNS rebinding),
+	// so cross-origin requests are refused
+	if origin := r.Header.Get("Origin"); origin != "" {
+		if u, err := url.Parse(origin); err != nil || u.Host != r.Host {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+	}
+	
+	switch r.Method {
+	case http.MethodPost:
+		s.handlePost(w, r)
+	case http.MethodDelete:
+		s.mu.Lock()
+		delete(s.sessions, r.Header.Get(sessionHeader))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handlePost(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read request", http.StatusBadRequest)
+		return
+	}
+	
+	sessionID := r.Header.Get(sessionHeader)
+	if isInitialize(body) {
+		sessionID = s.openSession()
+	} else {
+		switch {
+		case sessionID == "":
+			http.Error(w, "Mcp-Session-Id header is required", http.StatusBadRequest)
+			return
+		case !s.touchSession(sessionID):
+			// Tells the client to initialize a new session
+			http.Error(w, "session not found", http.StatusNotFound)
+			return
+		}
+	}
+	
+	w.Header().Set(sessionHeader, sessionID)
+	
+	reply := s.handleMessage(r.Context(), body)
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(reply)
+}
+
+// openSession starts a session, after forgetting the idle ones and, when
+// there are still too many, the least recently used.
+func (s *Server) openSession() string {
+	id := newSessionID()
+	now := time.Now()
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for sessionID, lastSeen := range s.sessions {
+		if now.Sub(lastSeen) > sessionIdleTimeout {
+			delete(s.sessions, sessionID)
+		}
+	}
+	for len(s.sessions) >= maxSessions {
+		var oldest string
+		for sessionID, lastSeen := range s.sessions {
+			if oldest == "" || lastSeen.Before(s.sessions[oldest]) {
+				oldest = sessionID
+			}
+		}
+		delete(s.sessions, oldest)
+	}
+	
+	s.sessions[id] = now
+	return id
+}
+
+// touchSession records that a session is in use and reports whether it is
+// known and was not idle for too long.
+func (s *Server) touchSession(id string) bool {
+	now := time.Now()
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	lastSeen, ok := s.sessions[id]
+	if !ok || now.Sub(lastSeen) > sessionIdleTimeout {
+		delete(s.sessions, id)
+		return false
+	}
+	s.sessions[id] = now
+	return true
+}
+
+func isInitialize(body []byte) bool {
+	var req request
+	return json.Unmarshal(body, &req) == nil && req.Method == "initialize"
+}
+
+func newSessionID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
\ No newline at end of file
@@ -0,0 +1,368 @@
+// Package mcpserver publishes deployed agents as Model Context Protocol
+// tools, one tool per agent, so MCP clients such as Claude Desktop or Cursor
+// can call them. It serves over stdio or the Streamable HTTP transport.
+package mcpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/runtime"
+	"go.uber.org/zap"
+)
+
+const (
+	latestProtocolVersion = "2025-03-26"
+	maxMessageSize        = 16 * 1024 * 1024
+	
+	// JSON-RPC error codes
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+var supportedProtocolVersions = map[string]bool{
+	"2024-11-05": true,
+	"2025-03-26": true,
+	"2025-06-18": true,
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+type Config struct {
+	// Timeout bounds each agent call. Zero leaves it to the engine.
+	Timeout time.Duration
+}
+
+type Server struct {
+	engine *runtime.Engine
+	config *Config
+	logger *zap.Logger
+	
+	// sessions holds when each Streamable HTTP session was last used
+	sessions map[string]time.Time
+	mu       sync.Mutex
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	
+	cluster string
+	agent   string
+}
+
+func NewServer(engine *runtime.Engine, config *Config, logger *zap.Logger) *Server {
+	if config == nil {
+		config = &Config{}
+	}
+	
+	return &Server{
+		engine:   engine,
+		config:   config,
+		logger:   logger,
+		sessions: make(map[string]time.Time),
+	}
+}
+
+// ServeStdio reads newline-delimited JSON-RPC messages from r and writes
+// responses to w until r is closed or ctx is cancelled. Requests are handled
+// concurrently, since agent calls can be slow.
+func (s *Server) ServeStdio(ctx context.Context, r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	
+	var writeMu sync.Mutex
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	
+	lines := make(chan []byte)
+	errCh := make(chan error, 1)
+	go func() {
+		for scanner.Scan() {
+			line := append([]byte(nil), scanner.Bytes()...)
+			select {
+			case lines <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		errCh <- scanner.Err()
+	}()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-errCh:
+			return err
+		case line := <-lines:
+			if len(strings.TrimSpace(string(line))) == 0 {
+				continue
+			}
+			
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				
+				reply := s.handleMessage(ctx, line)
+				if reply == nil {
+					return
+				}
+				
+				writeMu.Lock()
+				defer writeMu.Unlock()
+				w.Write(append(reply, '\n'))
+			}()
+		}
+	}
+}
+
+// handleMessage processes a single message or a batch and returns the
+// encoded reply, or nil when nothing needs to be sent.
+func (s *Server) handleMessage(ctx context.Context, data []byte) []byte {
+	data = []byte(strings.TrimSpace(string(data)))
+	
+	if len(data) > 0 && data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return encode(errorResponse(nil, codeParseError, "Parse error"))
+		}
+		
+		var replies []*response
+		for _, item := range batch {
+			if reply := s.handleRequest(ctx, item); reply != nil {
+				replies = append(replies, reply)
+			}
+		}
+		if len(replies) == 0 {
+			return nil
+		}
+		return encode(replies)
+	}
+	
+	reply := s.handleRequest(ctx, data)
+	if reply == nil {
+		return nil
+	}
+	return encode(reply)
+}
+
+func (s *Server) handleRequest(ctx context.Context, data []byte) *response {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return errorResponse(nil, codeParseError, "Parse error")
+	}
+	if req.Method == "" {
+		// Responses to server requests; none are sent, so ignore them
+		return nil
+	}
+	if req.JSONRPC != "2.0" {
+		return errorResponse(req.ID, codeInvalidRequest, "Invalid Request")
+	}
+	
+	isNotification := len(req.ID) == 0
+	
+	var result interface{}
+	var rpcErr *rpcError
+	switch req.Method {
+	case "initialize":
+		result, rpcErr = s.initialize(req.Params)
+	case "ping":
+		result = map[string]interface{}{}
+	case "tools/list":
+		result = map[string]interface{}{"tools": s.listTools()}
+	case "tools/call":
+		result, rpcErr = s.callTool(ctx, req.Params)
+	default:
+		if strings.HasPrefix(req.Method, "notifications/") {
+			return nil
+		}
+		rpcErr = &rpcError{Code: codeMethodNotFound, Message: fmt.Sprintf("Method not found: %s", req.Method)}
+	}
+	
+	if isNotification {
+		return nil
+	}
+	if rpcErr != nil {
+		return errorResponse(req.ID, rpcErr.Code, rpcErr.Message)
+	}
+	return &response{JSONRPC: "2.0", ID: req.ID, Result: result}
+}
+
+func (s *Server) initialize(params json.RawMessage) (interface{}, *rpcError) {
+	var init struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &init); err != nil {
+			return nil, &rpcError{Code: codeInvalidParams, Message: "Invalid initialize params"}
+		}
+	}
+	
+	// Agree to the client's version if we speak it, otherwise offer ours
+	version := latestProtocolVersion
+	if supportedProtocolVersions[init.ProtocolVersion] {
+		version = init.ProtocolVersion
+	}
+	
+	return map[string]interface{}{
+		"protocolVersion": version,
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+		"serverInfo": map[string]interface{}{
+			"name":    "goagents",
+			"version": "1.0.0",
+		},
+	}, nil
+}
+
+// listTools returns one tool per agent of every running cluster, sorted by
+// name so clients see a stable list.
+func (s *Server) listTools() []*tool {
+	tools := make([]*tool, 0)
+	for _, a := range s.engine.RunningAgents() {
+		tools = append(tools, &tool{
+			Name: toolName(a.Cluster, a.Name),
+			Description: fmt.Sprintf("Send a message to the %q agent in the %q cluster (%s %s) and return its reply.",
+				a.Name, a.Cluster, a.Provider, a.Model),
+			InputSchema: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"message": map[string]interface{}{
+						"type":        "string",
+						"description": "The message to send to the agent",
+					},
+					"context": map[string]interface{}{
+						"type":        "object",
+						"description": "Optional context passed with the request",
+					},
+				},
+				"required": []string{"message"},
+			},
+			cluster: a.Cluster,
+			agent:   a.Name,
+		})
+	}
+	
+	sort.Slice(tools, func(i, j int) bool {
+		return tools[i].Name < tools[j].Name
+	})
+	return tools
+}
+
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (interface{}, *rpcError) {
+	var call struct {
+		Name      string `json:"name"`
+		Arguments struct {
+			Message string                 `json:"message"`
+			Context map[string]interface{} `json:"context"`
+		} `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &call); err != nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "Invalid tools/call params"}
+	}
+	
+	var target *tool
+	for _, t := range s.listTools() {
+		if t.Name == call.Name {
+			target = t
+			break
+		}
+	}
+	if target == nil {
+		return nil, &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("Unknown tool: %s", call.Name)}
+	}
+	if call.Arguments.Message == "" {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "message is required"}
+	}
+	
+	req := &agent.Request{
+		ID:       fmt.Sprintf("mcp-%d", time.Now().UnixNano()),
+		Messages: []agent.Message{{Role: "user", Content: call.Arguments.Message}},
+		Context:  call.Arguments.Context,
+		Timeout:  s.config.Timeout,
+	}
+	
+	resp, err := s.engine.ProcessRequest(target.cluster, target.agent, req)
+	if err == nil && resp.Error != "" {
+		err = fmt.Errorf("%s", resp.Error)
+	}
+	
+	// Agent failures are tool results, so the calling model can see them
+	if err != nil {
+		s.logger.Warn("MCP tool call failed",
+			zap.String("cluster", target.cluster),
+			zap.String("agent", target.agent),
+			zap.Error(err))
+		return toolResult(err.Error(), true), nil
+	}
+	
+	return toolResult(resp.Content, false), nil
+}
+
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{
+			{"type": "text", "text": text},
+		},
+		"isError": isError,
+	}
+}
+
+// toolName joins cluster and agent names into a name accepted by model
+// providers, which allow only letters, digits, '_' and '-'.
+func toolName(cluster, agentName string) string {
+	name := invalidToolNameChars.ReplaceAllString(cluster+"__"+agentName, "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	return &response{
+		JSONRPC: "2.0",
+		ID:      id,
+		Error:   &rpcError{Code: code, Message: message},
+	}
+}
+
+func encode(v interface{}) []byte {
+	data, _ := json.Marshal(v)
+	return data
+}
\ No newline at end of file
@@ -3,6 +3,7 @@ package providers
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
@@ -14,7 +15,7 @@ type AnthropicProvider struct {
 	client *anthropic.Client
 }
 
-func NewAnthropicProvider(config *AnthropicConfig) *AnthropicProvider {
+func NewAnthropicProvider(config *AnthropicConfig) (*AnthropicProvider, error) {
 	baseURL := "https://api.anthropic.com"
 	if config.BaseURL != "" {
 		baseURL = config.BaseURL
@@ -35,12 +36,20 @@ func NewAnthropicProvider(config *AnthropicConfig) *AnthropicProvider {
 		opts = append(opts, option.WithBaseURL(config.BaseURL))
 	}
 	
+	if config.HTTP != nil {
+		httpClient, err := NewHTTPClient(config.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure anthropic HTTP client: %w", err)
+		}
+		opts = append(opts, option.WithHTTPClient(httpClient))
+	}
+	
 	client := anthropic.NewClient(opts...)
 	
 	return &AnthropicProvider{
 		config: config,
 		client: &client,
-	}
+	}, nil
 }
 
 func (p *AnthropicProvider) Name() string {
@@ -50,12 +59,15 @@ func (p *AnthropicProvider) Name() string {
 func (p *AnthropicProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
 	messageReq := p.convertToMessageRequest(req)
 	
-	resp, err := p.client.Messages.New(ctx, messageReq)
+	var httpResp *http.Response
+	resp, err := p.client.Messages.New(ctx, messageReq, option.WithResponseInto(&httpResp))
 	if err != nil {
 		return nil, fmt.Errorf("anthropic API error: %w", err)
 	}
 	
-	return p.convertFromMessageResponse(resp, req.Model), nil
+	chatResp := p.convertFromMessageResponse(resp, req.Model)
+	chatResp.ProviderMeta.RequestID = responseHeader(httpResp, "request-id")
+	return chatResp, nil
 }
 
 func (p *AnthropicProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *StreamChunk, error) {
@@ -66,9 +78,11 @@ func (p *AnthropicProvider) Stream(ctx context.Context, req *ChatRequest) (<-cha
 		
 		messageReq := p.convertToMessageRequest(req)
 		
-		stream := p.client.Messages.NewStreaming(ctx, messageReq)
+		var httpResp *http.Response
+		stream := p.client.Messages.NewStreaming(ctx, messageReq, option.WithResponseInto(&httpResp))
 		
 		var fullContent strings.Builder
+		var fullThinking strings.Builder
 		chunkIndex := 0
 		message := anthropic.Message{}
 		
@@ -99,24 +113,52 @@ func (p *AnthropicProvider) Stream(ctx context.Context, req *ChatRequest) (<-cha
 							chunkIndex++
 						}
 					}
+				case anthropic.ThinkingDelta:
+					if deltaVariant.Thinking != "" {
+						fullThinking.WriteString(deltaVariant.Thinking)
+						
+						select {
+						case <-ctx.Done():
+							return
+						case chunks <- &StreamChunk{
+							ID:            fmt.Sprintf("chunk_%d", chunkIndex),
+							Content:       fullContent.String(),
+							Thinking:      fullThinking.String(),
+							ThinkingDelta: deltaVariant.Thinking,
+							Done:          false,
+						}:
+							chunkIndex++
+						}
+					}
 				}
 			}
 		}
 		
 		if err := stream.Err(); err != nil {
-			chunks <- &StreamChunk{Error: fmt.Sprintf("streaming error: %v", err)}
+			chunks <- &StreamChunk{Error: fmt.Sprintf("streaming error: %v", err), err: err}
 			return
 		}
 		
-		// Send final chunk
+		meta := anthropicProviderMeta(&message)
+		meta.RequestID = responseHeader(httpResp, "request-id")
+		
+		// Send final chunk with usage accumulated from message_start/message_delta
 		select {
 		case <-ctx.Done():
 			return
 		case chunks <- &StreamChunk{
-			ID:      fmt.Sprintf("final_chunk_%d", chunkIndex),
-			Delta:   "",
-			Content: fullContent.String(),
-			Done:    true,
+			ID:       fmt.Sprintf("final_chunk_%d", chunkIndex),
+			Delta:    "",
+			Content:  fullContent.String(),
+			Thinking: fullThinking.String(),
+			Done:     true,
+			ToolUse:  anthropicToolUses(&message),
+			Usage: &Usage{
+				PromptTokens:     int(message.Usage.InputTokens),
+				CompletionTokens: int(message.Usage.OutputTokens),
+				TotalTokens:      int(message.Usage.InputTokens + message.Usage.OutputTokens),
+			},
+			ProviderMeta: meta,
 		}:
 		}
 	}()
@@ -149,12 +191,25 @@ func (p *AnthropicProvider) convertToMessageRequest(req *ChatRequest) anthropic.
 		MaxTokens: maxTokens,
 	}
 	
-	if req.Temperature > 0 {
-		messageReq.Temperature = anthropic.Float(req.Temperature)
-	}
-	
-	if req.TopP > 0 {
-		messageReq.TopP = anthropic.Float(req.TopP)
+	if req.ThinkingBudget > 0 {
+		// Extended thinking requires max_tokens above the budget and does not
+		// accept custom temperature/top_p, so those are left unset.
+		budget := int64(req.ThinkingBudget)
+		if budget < 1024 {
+			budget = 1024
+		}
+		if maxTokens <= budget {
+			messageReq.MaxTokens = budget + maxTokens
+		}
+		messageReq.Thinking = anthropic.ThinkingConfigParamOfEnabled(budget)
+	} else {
+		if req.Temperature > 0 {
+			messageReq.Temperature = anthropic.Float(req.Temperature)
+		}
+		
+		if req.TopP > 0 {
+			messageReq.TopP = anthropic.Float(req.TopP)
+		}
 	}
 	
 	// Convert messages
@@ -163,13 +218,26 @@ func (p *AnthropicProvider) convertToMessageRequest(req *ChatRequest) anthropic.
 	
 	for _, msg := range req.Messages {
 		if msg.Role == "system" {
-			systemMessage = msg.Content
+			// Later system messages, such as a conversation summary, add to
+			// the agent's prompt rather than replace it
+			if systemMessage != "" {
+				systemMessage += "\n\n"
+			}
+			systemMessage += msg.Content
+		} else if msg.Role == "tool" && msg.ToolResult != nil {
+			// Results of the calls of one turn go back in a single user message
+			block := anthropic.NewToolResultBlock(msg.ToolResult.ToolUseID, msg.Content, msg.ToolResult.IsError)
+			if last := len(messages) - 1; last >= 0 && anthropicToolResults(&messages[last]) {
+				messages[last].Content = append(messages[last].Content, block)
+			} else {
+				messages = append(messages, anthropic.NewUserMessage(block))
+			}
 		} else {
 			var messageParam anthropic.MessageParam
 			if msg.Role == "user" {
-				messageParam = anthropic.NewUserMessage(anthropic.NewTextBlock(msg.Content))
+				messageParam = anthropic.NewUserMessage(anthropicContentBlocks(&msg)...)
 			} else if msg.Role == "assistant" {
-				messageParam = anthropic.NewAssistantMessage(anthropic.NewTextBlock(msg.Content))
+				messageParam = anthropic.NewAssistantMessage(anthropicAssistantBlocks(&msg)...)
 			}
 			messages = append(messages, messageParam)
 		}
@@ -184,11 +252,116 @@ func (p *AnthropicProvider) convertToMessageRequest(req *ChatRequest) anthropic.
 		}}
 	}
 	
-	// Convert tools - skip for now to get basic functionality working
+	messageReq.Tools = anthropicTools(req.Tools)
 	
 	return messageReq
 }
 
+func anthropicTools(tools []Tool) []anthropic.ToolUnionParam {
+	if len(tools) == 0 {
+		return nil
+	}
+	
+	params := make([]anthropic.ToolUnionParam, 0, len(tools))
+	for i := range tools {
+		tool := &tools[i]
+		inputSchema := anthropic.ToolInputSchemaParam{ExtraFields: map[string]interface{}{}}
+		for key, value := range tool.schema() {
+			switch key {
+			case "type":
+			case "properties":
+				inputSchema.Properties = value
+			case "required":
+				inputSchema.Required = schemaStrings(value)
+			default:
+				inputSchema.ExtraFields[key] = value
+			}
+		}
+		
+		param := anthropic.ToolUnionParamOfTool(inputSchema, tool.Name)
+		if tool.Description != "" {
+			param.OfTool.Description = anthropic.String(tool.Description)
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func anthropicToolUses(message *anthropic.Message) []ToolUse {
+	var toolUses []ToolUse
+	for _, block := range message.Content {
+		if toolUse, ok := block.AsAny().(anthropic.ToolUseBlock); ok {
+			toolUses = append(toolUses, ToolUse{
+				ID:   toolUse.ID,
+				Name: toolUse.Name,
+				Args: parseToolArgs(toolUse.Input),
+			})
+		}
+	}
+	return toolUses
+}
+
+// anthropicAssistantBlocks converts an assistant message's text and the
+// tool calls it made, after the thinking blocks that led to them, which
+// extended thinking requires to be sent back as they were returned.
+func anthropicAssistantBlocks(msg *Message) []anthropic.ContentBlockParamUnion {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(msg.ThinkingBlocks)+len(msg.ToolUse)+1)
+	for _, thinking := range msg.ThinkingBlocks {
+		if thinking.Type == "redacted_thinking" {
+			blocks = append(blocks, anthropic.NewRedactedThinkingBlock(thinking.Data))
+		} else {
+			blocks = append(blocks, anthropic.NewThinkingBlock(thinking.Signature, thinking.Thinking))
+		}
+	}
+	if msg.Content != "" || len(msg.ToolUse) == 0 {
+		blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
+	}
+	for _, toolUse := range msg.ToolUse {
+		var input interface{} = toolUse.Args
+		if toolUse.Args == nil {
+			input = map[string]interface{}{}
+		}
+		blocks = append(blocks, anthropic.NewToolUseBlock(toolUse.ID, input, toolUse.Name))
+	}
+	return blocks
+}
+
+// anthropicToolResults reports whether a message carries only tool results.
+func anthropicToolResults(message *anthropic.MessageParam) bool {
+	if message.Role != anthropic.MessageParamRoleUser || len(message.Content) == 0 {
+		return false
+	}
+	for _, block := range message.Content {
+		if block.OfToolResult == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// anthropicContentBlocks converts a message's attachments to image and
+// document blocks placed before its text, as Anthropic recommends.
+func anthropicContentBlocks(msg *Message) []anthropic.ContentBlockParamUnion {
+	blocks := make([]anthropic.ContentBlockParamUnion, 0, len(msg.Attachments)+1)
+	for i := range msg.Attachments {
+		attachment := &msg.Attachments[i]
+		switch {
+		case attachment.isImage():
+			blocks = append(blocks, anthropic.NewImageBlockBase64(attachment.mediaType(), attachment.base64()))
+		case attachment.isPDF():
+			blocks = append(blocks, anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{Data: attachment.base64()}))
+		case strings.HasPrefix(attachment.mediaType(), "text/"):
+			blocks = append(blocks, anthropic.NewDocumentBlock(anthropic.PlainTextSourceParam{Data: string(attachment.Data)}))
+		default:
+			blocks = append(blocks, anthropic.NewTextBlock(attachment.unsupportedNote()))
+		}
+	}
+	
+	if msg.Content != "" || len(blocks) == 0 {
+		blocks = append(blocks, anthropic.NewTextBlock(msg.Content))
+	}
+	return blocks
+}
 
 func (p *AnthropicProvider) convertFromMessageResponse(resp *anthropic.Message, model string) *ChatResponse {
 	chatResp := &ChatResponse{
@@ -199,17 +372,42 @@ func (p *AnthropicProvider) convertFromMessageResponse(resp *anthropic.Message,
 			CompletionTokens: int(resp.Usage.OutputTokens),
 			TotalTokens:      int(resp.Usage.InputTokens + resp.Usage.OutputTokens),
 		},
+		ProviderMeta: anthropicProviderMeta(resp),
 	}
 	
 	// Extract content from response
 	var content strings.Builder
+	var thinking strings.Builder
 	for _, block := range resp.Content {
 		switch contentBlock := block.AsAny().(type) {
 		case anthropic.TextBlock:
 			content.WriteString(contentBlock.Text)
+		case anthropic.ThinkingBlock:
+			thinking.WriteString(contentBlock.Thinking)
+			chatResp.ThinkingBlocks = append(chatResp.ThinkingBlocks, ThinkingBlock{
+				Type:      "thinking",
+				Thinking:  contentBlock.Thinking,
+				Signature: contentBlock.Signature,
+			})
+		case anthropic.RedactedThinkingBlock:
+			chatResp.ThinkingBlocks = append(chatResp.ThinkingBlocks, ThinkingBlock{
+				Type: "redacted_thinking",
+				Data: contentBlock.Data,
+			})
 		}
 	}
 	chatResp.Content = content.String()
+	chatResp.Thinking = thinking.String()
+	chatResp.ToolUse = anthropicToolUses(resp)
 	
 	return chatResp
+}
+
+func anthropicProviderMeta(message *anthropic.Message) *ProviderMeta {
+	return &ProviderMeta{
+		FinishReason: string(message.StopReason),
+		StopSequence: message.StopSequence,
+		Refusal:      message.StopReason == anthropic.StopReasonRefusal,
+		ServiceTier:  string(message.Usage.ServiceTier),
+	}
 }
\ No newline at end of file
@@ -0,0 +1,49 @@
+package providers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// mediaType returns the attachment's MIME type without parameters.
+func (a *Attachment) mediaType() string {
+	mediaType, _, err := mime.ParseMediaType(a.MimeType)
+	if err != nil {
+		return strings.ToLower(a.MimeType)
+	}
+	return mediaType
+}
+
+// isImage reports whether the attachment is an image format that all
+// supported providers accept.
+func (a *Attachment) isImage() bool {
+	switch a.mediaType() {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+func (a *Attachment) isPDF() bool {
+	return a.mediaType() == "application/pdf"
+}
+
+func (a *Attachment) base64() string {
+	return base64.StdEncoding.EncodeToString(a.Data)
+}
+
+func (a *Attachment) dataURL() string {
+	return "data:" + a.mediaType() + ";base64," + a.base64()
+}
+
+// unsupportedNote stands in for an attachment the provider cannot accept,
+// so the model knows it was there.
+func (a *Attachment) unsupportedNote() string {
+	name := a.Name
+	if name == "" {
+		name = "file"
+	}
+	return fmt.Sprintf("[Attachment %s (%s, %d bytes) is not supported by this model]", name, a.mediaType(), len(a.Data))
+}
\ No newline at end of file
@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+type ResponseCache interface {
+	Get(ctx context.Context, key string) (*ChatResponse, bool, error)
+	Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error
+	Close() error
+}
+
+// CacheKey hashes everything that influences the completion. Metadata and
+// the stream flag are excluded so equivalent requests share an entry.
+func CacheKey(req *ChatRequest) (string, error) {
+	keyData := struct {
+		Model          string          `json:"model"`
+		Messages       []Message       `json:"messages"`
+		Tools          []Tool          `json:"tools,omitempty"`
+		MaxTokens      int             `json:"max_tokens,omitempty"`
+		Temperature    float64         `json:"temperature,omitempty"`
+		TopP           float64         `json:"top_p,omitempty"`
+		ThinkingBudget int             `json:"thinking_budget,omitempty"`
+		ResponseSchema *ResponseSchema `json:"response_schema,omitempty"`
+	}{
+		Model:          req.Model,
+		Messages:       req.Messages,
+		Tools:          req.Tools,
+		MaxTokens:      req.MaxTokens,
+		Temperature:    req.Temperature,
+		TopP:           req.TopP,
+		ThinkingBudget: req.ThinkingBudget,
+		ResponseSchema: req.ResponseSchema,
+	}
+	
+	data, err := json.Marshal(keyData)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal cache key: %w", err)
+	}
+	
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+type MemoryCache struct {
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	mu         sync.Mutex
+}
+
+type memoryCacheEntry struct {
+	key       string
+	resp      *ChatResponse
+	expiresAt time.Time
+}
+
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+	
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (*ChatResponse, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	elem, exists := c.items[key]
+	if !exists {
+		return nil, false, nil
+	}
+	
+	entry := elem.Value.(*memoryCacheEntry)
+	if !entry.expiresAt.IsZero() && time.Now().After(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false, nil
+	}
+	
+	c.ll.MoveToFront(elem)
+	resp := *entry.resp
+	return &resp, true, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	
+	stored := *resp
+	if elem, exists := c.items[key]; exists {
+		entry := elem.Value.(*memoryCacheEntry)
+		entry.resp = &stored
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return nil
+	}
+	
+	c.items[key] = c.ll.PushFront(&memoryCacheEntry{
+		key:       key,
+		resp:      &stored,
+		expiresAt: expiresAt,
+	})
+	
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*memoryCacheEntry).key)
+	}
+	
+	return nil
+}
+
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *MemoryCache) Close() error {
+	return nil
+}
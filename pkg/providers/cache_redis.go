@@ -0,0 +1,79 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisCacheConfig struct {
+	Addr      string `json:"addr"`
+	Password  string `json:"password,omitempty"`
+	DB        int    `json:"db,omitempty"`
+	KeyPrefix string `json:"key_prefix,omitempty"`
+}
+
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisCache(config *RedisCacheConfig) (*RedisCache, error) {
+	if config.Addr == "" {
+		return nil, fmt.Errorf("redis address is required")
+	}
+	
+	prefix := "goagents:cache:"
+	if config.KeyPrefix != "" {
+		prefix = config.KeyPrefix
+	}
+	
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+	
+	return &RedisCache{
+		client: client,
+		prefix: prefix,
+	}, nil
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (*ChatResponse, bool, error) {
+	data, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("redis get failed: %w", err)
+	}
+	
+	var resp ChatResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, false, fmt.Errorf("failed to decode cached response: %w", err)
+	}
+	
+	return &resp, true, nil
+}
+
+func (c *RedisCache) Set(ctx context.Context, key string, resp *ChatResponse, ttl time.Duration) error {
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return fmt.Errorf("failed to encode response: %w", err)
+	}
+	
+	if err := c.client.Set(ctx, c.prefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	
+	return nil
+}
+
+func (c *RedisCache) Close() error {
+	return c.client.Close()
+}
@@ -0,0 +1,295 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/googleapis/gax-go/v2/apierror"
+	"github.com/openai/openai-go"
+	"google.golang.org/grpc/codes"
+)
+
+// ErrCredentialsRevoked is returned once every API key configured for a
+// provider has been rejected with 401.
+var ErrCredentialsRevoked = errors.New("provider credentials revoked")
+
+// IsAuthError reports whether err is a provider rejection of the API key,
+// i.e. HTTP 401 or gRPC Unauthenticated. A 403 is not one: it denies the
+// request, such as for a model or region the key may not use, and the key
+// still works for others.
+func IsAuthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		return isAuthStatus(anthropicErr.StatusCode)
+	}
+	
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		return isAuthStatus(openaiErr.StatusCode)
+	}
+	
+	var googleErr *apierror.APIError
+	if errors.As(err, &googleErr) {
+		if googleErr.HTTPCode() > 0 {
+			return isAuthStatus(googleErr.HTTPCode())
+		}
+		if status := googleErr.GRPCStatus(); status != nil {
+			return status.Code() == codes.Unauthenticated
+		}
+	}
+	
+	return false
+}
+
+func isAuthStatus(code int) bool {
+	return code == http.StatusUnauthorized
+}
+
+// CredentialEvent describes a key that was rejected by a provider.
+type CredentialEvent struct {
+	Provider    string
+	Fingerprint string
+	Err         error
+	Remaining   int
+}
+
+type CredentialStatus struct {
+	Fingerprint   string     `json:"fingerprint"`
+	Active        bool       `json:"active"`
+	Valid         bool       `json:"valid"`
+	InvalidatedAt *time.Time `json:"invalidated_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+}
+
+type credential struct {
+	fingerprint   string
+	provider      Provider
+	valid         bool
+	invalidatedAt time.Time
+	lastError     string
+}
+
+// CredentialPool is a Provider backed by one client per API key. When the
+// active key is rejected it is marked invalid, onRevoked is invoked and the
+// call is retried with the next valid key. Invalid keys are never retried,
+// so a revoked key does not keep hitting the provider.
+type CredentialPool struct {
+	name        string
+	credentials []*credential
+	current     int
+	onRevoked   func(CredentialEvent)
+	mu          sync.RWMutex
+}
+
+func NewCredentialPool(name string, apiKeys []string, factory func(apiKey string) (Provider, error), onRevoked func(CredentialEvent)) (*CredentialPool, error) {
+	if len(apiKeys) == 0 {
+		return nil, fmt.Errorf("no API keys configured for %s", name)
+	}
+	
+	pool := &CredentialPool{
+		name:      name,
+		onRevoked: onRevoked,
+	}
+	
+	for _, apiKey := range apiKeys {
+		provider, err := factory(apiKey)
+		if err != nil {
+			pool.Close()
+			return nil, err
+		}
+		pool.credentials = append(pool.credentials, &credential{
+			fingerprint: KeyFingerprint(apiKey),
+			provider:    provider,
+			valid:       true,
+		})
+	}
+	
+	return pool, nil
+}
+
+// KeyFingerprint returns a non-secret identifier for an API key that is
+// safe to log.
+func KeyFingerprint(apiKey string) string {
+	if len(apiKey) <= 4 {
+		return "****"
+	}
+	return "****" + apiKey[len(apiKey)-4:]
+}
+
+func (p *CredentialPool) Name() string {
+	return p.credentials[0].provider.Name()
+}
+
+func (p *CredentialPool) Models() []string {
+	return p.credentials[0].provider.Models()
+}
+
+func (p *CredentialPool) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	for {
+		index, cred, err := p.active()
+		if err != nil {
+			return nil, err
+		}
+		
+		resp, err := cred.provider.Chat(ctx, req)
+		if err != nil && IsAuthError(err) {
+			p.revoke(index, err)
+			continue
+		}
+		
+		return resp, err
+	}
+}
+
+// Stream waits for the first chunk before handing the stream to the caller
+// so that a key rejected at connection time can be rotated transparently.
+func (p *CredentialPool) Stream(ctx context.Context, req *ChatRequest) (<-chan *StreamChunk, error) {
+	for {
+		index, cred, err := p.active()
+		if err != nil {
+			return nil, err
+		}
+		
+		upstream, err := cred.provider.Stream(ctx, req)
+		if err != nil {
+			if IsAuthError(err) {
+				p.revoke(index, err)
+				continue
+			}
+			return nil, err
+		}
+		
+		first, ok := <-upstream
+		if ok && first.Error != "" && IsAuthError(first.err) {
+			p.revoke(index, first.err)
+			continue
+		}
+		
+		chunks := make(chan *StreamChunk, 10)
+		go func() {
+			defer close(chunks)
+			
+			if !ok {
+				return
+			}
+			
+			chunks <- first
+			for chunk := range upstream {
+				select {
+				case <-ctx.Done():
+					return
+				case chunks <- chunk:
+				}
+			}
+		}()
+		
+		return chunks, nil
+	}
+}
+
+// Embed rotates rejected keys the same way Chat does.
+func (p *CredentialPool) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	for {
+		index, cred, err := p.active()
+		if err != nil {
+			return nil, err
+		}
+		
+		resp, err := Embed(ctx, cred.provider, req)
+		if err != nil && IsAuthError(err) {
+			p.revoke(index, err)
+			continue
+		}
+		
+		return resp, err
+	}
+}
+
+func (p *CredentialPool) Close() error {
+	var firstErr error
+	for _, cred := range p.credentials {
+		if err := cred.provider.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Status reports the state of every key in the pool.
+func (p *CredentialPool) Status() []CredentialStatus {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	
+	statuses := make([]CredentialStatus, len(p.credentials))
+	for i, cred := range p.credentials {
+		statuses[i] = CredentialStatus{
+			Fingerprint: cred.fingerprint,
+			Active:      cred.valid && i == p.current,
+			Valid:       cred.valid,
+			LastError:   cred.lastError,
+		}
+		if !cred.valid {
+			invalidatedAt := cred.invalidatedAt
+			statuses[i].InvalidatedAt = &invalidatedAt
+		}
+	}
+	
+	return statuses
+}
+
+func (p *CredentialPool) active() (int, *credential, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	
+	for offset := 0; offset < len(p.credentials); offset++ {
+		index := (p.current + offset) % len(p.credentials)
+		if p.credentials[index].valid {
+			return index, p.credentials[index], nil
+		}
+	}
+	
+	return 0, nil, fmt.Errorf("%w: all API keys for %s were rejected", ErrCredentialsRevoked, p.name)
+}
+
+func (p *CredentialPool) revoke(index int, err error) {
+	p.mu.Lock()
+	cred := p.credentials[index]
+	if !cred.valid {
+		// Another request already invalidated this key
+		p.mu.Unlock()
+		return
+	}
+	
+	cred.valid = false
+	cred.invalidatedAt = time.Now()
+	cred.lastError = err.Error()
+	
+	remaining := 0
+	for i, other := range p.credentials {
+		if other.valid {
+			if remaining == 0 {
+				p.current = i
+			}
+			remaining++
+		}
+	}
+	p.mu.Unlock()
+	
+	if p.onRevoked != nil {
+		p.onRevoked(CredentialEvent{
+			Provider:    p.name,
+			Fingerprint: cred.fingerprint,
+			Err:         err,
+			Remaining:   remaining,
+		})
+	}
+}
\ No newline at end of file
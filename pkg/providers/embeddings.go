@@ -0,0 +1,62 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrEmbeddingsUnsupported is returned by providers without an embeddings
+// API, such as Anthropic.
+var ErrEmbeddingsUnsupported = errors.New("provider does not support embeddings")
+
+// Embedder is implemented by providers that can turn text into vectors.
+type Embedder interface {
+	Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error)
+}
+
+type EmbeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+// EmbeddingResponse holds one vector per input, in the order of the inputs.
+type EmbeddingResponse struct {
+	Embeddings [][]float32 `json:"embeddings"`
+	Model      string      `json:"model"`
+	Usage      *Usage      `json:"usage,omitempty"`
+}
+
+// Embed calls provider's embeddings API, or fails with
+// ErrEmbeddingsUnsupported if it has none.
+func Embed(ctx context.Context, provider Provider, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	embedder, ok := provider.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEmbeddingsUnsupported, provider.Name())
+	}
+	if len(req.Input) == 0 {
+		return &EmbeddingResponse{Model: req.Model}, nil
+	}
+	
+	resp, err := embedder.Embed(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(req.Input) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d inputs", provider.Name(), len(resp.Embeddings), len(req.Input))
+	}
+	return resp, nil
+}
+
+// SupportsEmbeddings reports whether the provider behind any middleware and
+// credential pool has an embeddings API.
+func SupportsEmbeddings(provider Provider) bool {
+	switch p := provider.(type) {
+	case *wrappedProvider:
+		return SupportsEmbeddings(p.Provider)
+	case *CredentialPool:
+		return SupportsEmbeddings(p.credentials[0].provider)
+	}
+	_, ok := provider.(Embedder)
+	return ok
+}
\ No newline at end of file
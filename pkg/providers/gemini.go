@@ -2,9 +2,12 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
+	pb "cloud.google.com/go/ai/generativelanguage/apiv1beta/generativelanguagepb"
 	"github.com/google/generative-ai-go/genai"
 	"google.golang.org/api/option"
 )
@@ -14,21 +17,38 @@ type GeminiProvider struct {
 	client *genai.Client
 }
 
-func NewGeminiProvider(config *GeminiConfig) *GeminiProvider {
+func NewGeminiProvider(config *GeminiConfig) (*GeminiProvider, error) {
+	opts := []option.ClientOption{
+		option.WithAPIKey(config.APIKey),
+	}
+	
+	if config.HTTP != nil {
+		httpClient, err := NewHTTPClient(config.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure gemini HTTP client: %w", err)
+		}
+		// A custom HTTP client bypasses the SDK's API key handling
+		httpClient.Transport = &apiKeyTransport{
+			apiKey: config.APIKey,
+			base:   httpClient.Transport,
+		}
+		opts = []option.ClientOption{option.WithHTTPClient(httpClient)}
+	}
+	
 	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(config.APIKey))
+	client, err := genai.NewClient(ctx, opts...)
 	if err != nil {
 		// For now, return a provider with nil client - errors will be handled in methods
 		return &GeminiProvider{
 			config: config,
 			client: nil,
-		}
+		}, nil
 	}
 	
 	return &GeminiProvider{
 		config: config,
 		client: client,
-	}
+	}, nil
 }
 
 func (p *GeminiProvider) Name() string {
@@ -55,11 +75,22 @@ func (p *GeminiProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatRespo
 		maxTokens := int32(req.MaxTokens)
 		model.MaxOutputTokens = &maxTokens
 	}
+	model.Tools = geminiTools(req.Tools)
+	setGeminiResponseFormat(model, req)
 	
 	// Convert messages to parts
 	parts := p.convertMessagesToParts(req.Messages)
 	
 	resp, err := model.GenerateContent(ctx, parts...)
+	// The SDK reports blocked prompts and answers as errors; they are
+	// returned as empty responses whose metadata says why
+	var blocked *genai.BlockedError
+	if errors.As(err, &blocked) {
+		return &ChatResponse{
+			Model:        req.Model,
+			ProviderMeta: geminiProviderMeta(blocked.Candidate, blocked.PromptFeedback),
+		}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("gemini API error: %w", err)
 	}
@@ -93,6 +124,8 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *
 			maxTokens := int32(req.MaxTokens)
 			model.MaxOutputTokens = &maxTokens
 		}
+		model.Tools = geminiTools(req.Tools)
+		setGeminiResponseFormat(model, req)
 		
 		// Convert messages to parts
 		parts := p.convertMessagesToParts(req.Messages)
@@ -100,6 +133,9 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *
 		iter := model.GenerateContentStream(ctx, parts...)
 		
 		var fullContent strings.Builder
+		var toolUses []ToolUse
+		var usage *Usage
+		var meta *ProviderMeta
 		chunkIndex := 0
 		
 		for {
@@ -108,13 +144,35 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *
 				if err.Error() == "iterator done" {
 					break
 				}
-				chunks <- &StreamChunk{Error: fmt.Sprintf("streaming error: %v", err)}
+				var blocked *genai.BlockedError
+				if errors.As(err, &blocked) {
+					meta = geminiProviderMeta(blocked.Candidate, blocked.PromptFeedback)
+					break
+				}
+				chunks <- &StreamChunk{Error: fmt.Sprintf("streaming error: %v", err), err: err}
 				return
 			}
 			
+			// The last response carries the finish reason and final ratings
+			if len(resp.Candidates) > 0 {
+				meta = geminiProviderMeta(resp.Candidates[0], resp.PromptFeedback)
+			}
+			
+			// Each response carries cumulative usage; keep the latest
+			if resp.UsageMetadata != nil {
+				usage = &Usage{
+					PromptTokens:     int(resp.UsageMetadata.PromptTokenCount),
+					CompletionTokens: int(resp.UsageMetadata.CandidatesTokenCount),
+					TotalTokens:      int(resp.UsageMetadata.TotalTokenCount),
+				}
+			}
+			
 			for _, candidate := range resp.Candidates {
 				if candidate.Content != nil {
 					for _, part := range candidate.Content.Parts {
+						if call, ok := part.(genai.FunctionCall); ok {
+							toolUses = append(toolUses, geminiToolUse(call, len(toolUses)))
+						}
 						if textPart, ok := part.(genai.Text); ok {
 							text := string(textPart)
 							fullContent.WriteString(text)
@@ -141,10 +199,13 @@ func (p *GeminiProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *
 		case <-ctx.Done():
 			return
 		case chunks <- &StreamChunk{
-			ID:      fmt.Sprintf("final_chunk_%d", chunkIndex),
-			Delta:   "",
-			Content: fullContent.String(),
-			Done:    true,
+			ID:           fmt.Sprintf("final_chunk_%d", chunkIndex),
+			Delta:        "",
+			Content:      fullContent.String(),
+			Done:         true,
+			Usage:        usage,
+			ToolUse:      toolUses,
+			ProviderMeta: meta,
 		}:
 		}
 	}()
@@ -167,6 +228,37 @@ func (p *GeminiProvider) Close() error {
 	return nil
 }
 
+func (p *GeminiProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	if p.client == nil {
+		return nil, fmt.Errorf("gemini client not initialized")
+	}
+	
+	model := p.client.EmbeddingModel(req.Model)
+	batch := model.NewBatch()
+	for _, input := range req.Input {
+		batch.AddContent(genai.Text(input))
+	}
+	
+	resp, err := model.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, fmt.Errorf("gemini API error: %w", err)
+	}
+	
+	embeddings := make([][]float32, 0, len(resp.Embeddings))
+	for _, embedding := range resp.Embeddings {
+		var values []float32
+		if embedding != nil {
+			values = embedding.Values
+		}
+		embeddings = append(embeddings, values)
+	}
+	
+	return &EmbeddingResponse{
+		Embeddings: embeddings,
+		Model:      req.Model,
+	}, nil
+}
+
 func (p *GeminiProvider) convertMessagesToParts(messages []Message) []genai.Part {
 	var parts []genai.Part
 	
@@ -177,14 +269,40 @@ func (p *GeminiProvider) convertMessagesToParts(messages []Message) []genai.Part
 			parts = append(parts, genai.Text(fmt.Sprintf("System: %s", msg.Content)))
 		} else if msg.Role == "user" {
 			parts = append(parts, genai.Text(msg.Content))
+			for _, attachment := range msg.Attachments {
+				parts = append(parts, geminiAttachmentPart(&attachment))
+			}
 		} else if msg.Role == "assistant" {
-			parts = append(parts, genai.Text(fmt.Sprintf("Assistant: %s", msg.Content)))
+			if msg.Content != "" || len(msg.ToolUse) == 0 {
+				parts = append(parts, genai.Text(fmt.Sprintf("Assistant: %s", msg.Content)))
+			}
+			for _, toolUse := range msg.ToolUse {
+				args, _ := json.Marshal(toolUse.Args)
+				parts = append(parts, genai.Text(fmt.Sprintf("Assistant called tool %s with %s", toolUse.Name, args)))
+			}
+		} else if msg.Role == "tool" && msg.ToolResult != nil {
+			outcome := "returned"
+			if msg.ToolResult.IsError {
+				outcome = "failed"
+			}
+			parts = append(parts, genai.Text(fmt.Sprintf("Tool %s %s: %s", msg.ToolResult.Name, outcome, msg.Content)))
 		}
 	}
 	
 	return parts
 }
 
+// geminiAttachmentPart sends images, PDFs, audio, video and text inline.
+func geminiAttachmentPart(attachment *Attachment) genai.Part {
+	mediaType := attachment.mediaType()
+	for _, prefix := range []string{"image/", "audio/", "video/", "text/", "application/pdf"} {
+		if strings.HasPrefix(mediaType, prefix) {
+			return genai.Blob{MIMEType: mediaType, Data: attachment.Data}
+		}
+	}
+	return genai.Text(attachment.unsupportedNote())
+}
+
 func (p *GeminiProvider) convertFromGeminiResponse(resp *genai.GenerateContentResponse, model string) *ChatResponse {
 	chatResp := &ChatResponse{
 		ID:    fmt.Sprintf("gemini-%d", resp.UsageMetadata.TotalTokenCount),
@@ -204,13 +322,148 @@ func (p *GeminiProvider) convertFromGeminiResponse(resp *genai.GenerateContentRe
 	for _, candidate := range resp.Candidates {
 		if candidate.Content != nil {
 			for _, part := range candidate.Content.Parts {
-				if textPart, ok := part.(genai.Text); ok {
-					content.WriteString(string(textPart))
+				switch part := part.(type) {
+				case genai.Text:
+					content.WriteString(string(part))
+				case genai.FunctionCall:
+					chatResp.ToolUse = append(chatResp.ToolUse, geminiToolUse(part, len(chatResp.ToolUse)))
 				}
 			}
 		}
 	}
 	chatResp.Content = content.String()
 	
+	var candidate *genai.Candidate
+	if len(resp.Candidates) > 0 {
+		candidate = resp.Candidates[0]
+	}
+	chatResp.ProviderMeta = geminiProviderMeta(candidate, resp.PromptFeedback)
+	
 	return chatResp
+}
+
+func geminiTools(tools []Tool) []*genai.Tool {
+	if len(tools) == 0 {
+		return nil
+	}
+	
+	declarations := make([]*genai.FunctionDeclaration, 0, len(tools))
+	for i := range tools {
+		tool := &tools[i]
+		declarations = append(declarations, &genai.FunctionDeclaration{
+			Name:        tool.Name,
+			Description: tool.Description,
+			Parameters:  geminiSchema(tool.schema()),
+		})
+	}
+	return []*genai.Tool{{FunctionDeclarations: declarations}}
+}
+
+// geminiSchema converts a JSON schema to the OpenAPI subset Gemini accepts.
+// Keywords it has no equivalent for are dropped; arguments are still
+// validated against the full schema before a tool runs.
+func geminiSchema(schema map[string]interface{}) *genai.Schema {
+	description, _ := schema["description"].(string)
+	format, _ := schema["format"].(string)
+	converted := &genai.Schema{
+		Description: description,
+		Format:      format,
+		Enum:        schemaStrings(schema["enum"]),
+		Required:    schemaStrings(schema["required"]),
+	}
+	
+	// A type list such as ["string", "null"] becomes a nullable type
+	types := schemaStrings(schema["type"])
+	if t, ok := schema["type"].(string); ok {
+		types = []string{t}
+	}
+	for _, t := range types {
+		switch t {
+		case "string":
+			converted.Type = genai.TypeString
+		case "number":
+			converted.Type = genai.TypeNumber
+		case "integer":
+			converted.Type = genai.TypeInteger
+		case "boolean":
+			converted.Type = genai.TypeBoolean
+		case "array":
+			converted.Type = genai.TypeArray
+		case "object":
+			converted.Type = genai.TypeObject
+		case "null":
+			converted.Nullable = true
+		}
+	}
+	
+	if items, ok := schema["items"].(map[string]interface{}); ok {
+		converted.Items = geminiSchema(items)
+	}
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		converted.Properties = make(map[string]*genai.Schema, len(properties))
+		for name, property := range properties {
+			if property, ok := property.(map[string]interface{}); ok {
+				converted.Properties[name] = geminiSchema(property)
+			}
+		}
+	}
+	return converted
+}
+
+// geminiToolUse converts a function call. Gemini does not identify calls,
+// so they are numbered in the order the model made them.
+func geminiToolUse(call genai.FunctionCall, index int) ToolUse {
+	args := call.Args
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	return ToolUse{
+		ID:   fmt.Sprintf("call_%d", index),
+		Name: call.Name,
+		Args: args,
+	}
+}
+
+// geminiProviderMeta reports enums by their API names, such as SAFETY and
+// HARM_CATEGORY_HARASSMENT, rather than the SDK's Go names.
+func geminiProviderMeta(candidate *genai.Candidate, feedback *genai.PromptFeedback) *ProviderMeta {
+	meta := &ProviderMeta{}
+	if feedback != nil {
+		if feedback.BlockReason != genai.BlockReasonUnspecified {
+			meta.BlockReason = pb.GenerateContentResponse_PromptFeedback_BlockReason(feedback.BlockReason).String()
+			meta.Refusal = true
+		}
+		meta.SafetyRatings = append(meta.SafetyRatings, geminiSafetyRatings(feedback.SafetyRatings)...)
+	}
+	if candidate != nil {
+		if candidate.FinishReason != genai.FinishReasonUnspecified {
+			meta.FinishReason = pb.Candidate_FinishReason(candidate.FinishReason).String()
+		}
+		if candidate.FinishReason == genai.FinishReasonSafety || candidate.FinishReason == genai.FinishReasonRecitation {
+			meta.Refusal = true
+		}
+		meta.SafetyRatings = append(meta.SafetyRatings, geminiSafetyRatings(candidate.SafetyRatings)...)
+	}
+	return meta
+}
+
+func geminiSafetyRatings(ratings []*genai.SafetyRating) []SafetyRating {
+	converted := make([]SafetyRating, 0, len(ratings))
+	for _, rating := range ratings {
+		converted = append(converted, SafetyRating{
+			Category:    pb.HarmCategory(rating.Category).String(),
+			Probability: pb.SafetyRating_HarmProbability(rating.Probability).String(),
+			Blocked:     rating.Blocked,
+		})
+	}
+	return converted
+}
+
+// setGeminiResponseFormat asks for JSON when the request has a response
+// schema. Gemini cannot combine JSON output with function calling, so
+// requests that advertise tools are left to the schema instructions.
+func setGeminiResponseFormat(model *genai.GenerativeModel, req *ChatRequest) {
+	if req.ResponseSchema != nil && len(req.Tools) == 0 {
+		model.ResponseMIMEType = "application/json"
+	}
 }
\ No newline at end of file
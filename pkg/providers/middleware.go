@@ -0,0 +1,75 @@
+package providers
+
+import "context"
+
+type ChatFunc func(ctx context.Context, req *ChatRequest) (*ChatResponse, error)
+
+type StreamFunc func(ctx context.Context, req *ChatRequest) (<-chan *StreamChunk, error)
+
+// ProviderMiddleware wraps the Chat and Stream calls of every provider
+// registered with a Manager. The provider name is passed so middleware can
+// behave differently per backend.
+type ProviderMiddleware interface {
+	WrapChat(provider string, next ChatFunc) ChatFunc
+	WrapStream(provider string, next StreamFunc) StreamFunc
+}
+
+// MiddlewareFuncs adapts plain functions to ProviderMiddleware. Nil fields
+// pass calls through unchanged.
+type MiddlewareFuncs struct {
+	Chat   func(provider string, next ChatFunc) ChatFunc
+	Stream func(provider string, next StreamFunc) StreamFunc
+}
+
+func (m MiddlewareFuncs) WrapChat(provider string, next ChatFunc) ChatFunc {
+	if m.Chat == nil {
+		return next
+	}
+	return m.Chat(provider, next)
+}
+
+func (m MiddlewareFuncs) WrapStream(provider string, next StreamFunc) StreamFunc {
+	if m.Stream == nil {
+		return next
+	}
+	return m.Stream(provider, next)
+}
+
+type wrappedProvider struct {
+	Provider
+	chat   ChatFunc
+	stream StreamFunc
+}
+
+func (p *wrappedProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
+	return p.chat(ctx, req)
+}
+
+func (p *wrappedProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *StreamChunk, error) {
+	return p.stream(ctx, req)
+}
+
+// Embed is not passed through the middleware, which works on chat requests.
+func (p *wrappedProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	return Embed(ctx, p.Provider, req)
+}
+
+// wrapProvider applies middleware so that the first entry is the outermost.
+func wrapProvider(name string, provider Provider, middleware []ProviderMiddleware) Provider {
+	if len(middleware) == 0 {
+		return provider
+	}
+	
+	chat := ChatFunc(provider.Chat)
+	stream := StreamFunc(provider.Stream)
+	for i := len(middleware) - 1; i >= 0; i-- {
+		chat = middleware[i].WrapChat(name, chat)
+		stream = middleware[i].WrapStream(name, stream)
+	}
+	
+	return &wrappedProvider{
+		Provider: provider,
+		chat:     chat,
+		stream:   stream,
+	}
+}
@@ -2,11 +2,14 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
+	"github.com/openai/openai-go/shared"
 )
 
 type OpenAIProvider struct {
@@ -14,7 +17,7 @@ type OpenAIProvider struct {
 	client *openai.Client
 }
 
-func NewOpenAIProvider(config *OpenAIConfig) *OpenAIProvider {
+func NewOpenAIProvider(config *OpenAIConfig) (*OpenAIProvider, error) {
 	baseURL := "https://api.openai.com"
 	if config.BaseURL != "" {
 		baseURL = config.BaseURL
@@ -29,12 +32,20 @@ func NewOpenAIProvider(config *OpenAIConfig) *OpenAIProvider {
 		opts = append(opts, option.WithBaseURL(config.BaseURL))
 	}
 	
+	if config.HTTP != nil {
+		httpClient, err := NewHTTPClient(config.HTTP)
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure openai HTTP client: %w", err)
+		}
+		opts = append(opts, option.WithHTTPClient(httpClient))
+	}
+	
 	client := openai.NewClient(opts...)
 	
 	return &OpenAIProvider{
 		config: config,
 		client: &client,
-	}
+	}, nil
 }
 
 func (p *OpenAIProvider) Name() string {
@@ -44,12 +55,15 @@ func (p *OpenAIProvider) Name() string {
 func (p *OpenAIProvider) Chat(ctx context.Context, req *ChatRequest) (*ChatResponse, error) {
 	params := p.convertToChatCompletionParams(req)
 	
-	resp, err := p.client.Chat.Completions.New(ctx, params)
+	var httpResp *http.Response
+	resp, err := p.client.Chat.Completions.New(ctx, params, option.WithResponseInto(&httpResp))
 	if err != nil {
 		return nil, fmt.Errorf("openai API error: %w", err)
 	}
 	
-	return p.convertFromChatCompletion(resp), nil
+	chatResp := p.convertFromChatCompletion(resp)
+	chatResp.ProviderMeta.RequestID = responseHeader(httpResp, "x-request-id")
+	return chatResp, nil
 }
 
 func (p *OpenAIProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *StreamChunk, error) {
@@ -58,17 +72,37 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *
 	go func() {
 		defer close(chunks)
 		
-		params := p.convertToChatCompletionParams(req)
+		streamReq := *req
+		streamReq.Stream = true
+		params := p.convertToChatCompletionParams(&streamReq)
 		
-		stream := p.client.Chat.Completions.NewStreaming(ctx, params)
+		var httpResp *http.Response
+		stream := p.client.Chat.Completions.NewStreaming(ctx, params, option.WithResponseInto(&httpResp))
 		
 		var fullContent strings.Builder
+		var usage *Usage
 		chunkIndex := 0
 		acc := openai.ChatCompletionAccumulator{}
 		
 		for stream.Next() {
 			chunk := stream.Current()
 			acc.AddChunk(chunk)
+			// The accumulator does not keep these
+			if chunk.SystemFingerprint != "" {
+				acc.SystemFingerprint = chunk.SystemFingerprint
+			}
+			if chunk.ServiceTier != "" {
+				acc.ServiceTier = openai.ChatCompletionServiceTier(chunk.ServiceTier)
+			}
+			
+			// The usage chunk arrives last, with no choices
+			if chunk.Usage.TotalTokens > 0 {
+				usage = &Usage{
+					PromptTokens:     int(chunk.Usage.PromptTokens),
+					CompletionTokens: int(chunk.Usage.CompletionTokens),
+					TotalTokens:      int(chunk.Usage.TotalTokens),
+				}
+			}
 			
 			if len(chunk.Choices) > 0 {
 				delta := chunk.Choices[0].Delta.Content
@@ -91,19 +125,30 @@ func (p *OpenAIProvider) Stream(ctx context.Context, req *ChatRequest) (<-chan *
 		}
 		
 		if err := stream.Err(); err != nil {
-			chunks <- &StreamChunk{Error: fmt.Sprintf("streaming error: %v", err)}
+			chunks <- &StreamChunk{Error: fmt.Sprintf("streaming error: %v", err), err: err}
 			return
 		}
 		
+		meta := openAIProviderMeta(&acc.ChatCompletion)
+		meta.RequestID = responseHeader(httpResp, "x-request-id")
+		
+		var toolUses []ToolUse
+		if len(acc.Choices) > 0 {
+			toolUses = openAIToolUses(acc.Choices[0].Message.ToolCalls)
+		}
+		
 		// Send final chunk
 		select {
 		case <-ctx.Done():
 			return
 		case chunks <- &StreamChunk{
-			ID:      fmt.Sprintf("final_chunk_%d", chunkIndex),
-			Delta:   "",
-			Content: fullContent.String(),
-			Done:    true,
+			ID:           fmt.Sprintf("final_chunk_%d", chunkIndex),
+			Delta:        "",
+			Content:      fullContent.String(),
+			Done:         true,
+			Usage:        usage,
+			ToolUse:      toolUses,
+			ProviderMeta: meta,
 		}:
 		}
 	}()
@@ -125,6 +170,37 @@ func (p *OpenAIProvider) Close() error {
 	return nil
 }
 
+func (p *OpenAIProvider) Embed(ctx context.Context, req *EmbeddingRequest) (*EmbeddingResponse, error) {
+	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Model: req.Model,
+		Input: openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: req.Input},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("openai API error: %w", err)
+	}
+	
+	embeddings := make([][]float32, len(req.Input))
+	for _, data := range resp.Data {
+		if data.Index < 0 || int(data.Index) >= len(embeddings) {
+			continue
+		}
+		vector := make([]float32, len(data.Embedding))
+		for i, value := range data.Embedding {
+			vector[i] = float32(value)
+		}
+		embeddings[data.Index] = vector
+	}
+	
+	return &EmbeddingResponse{
+		Embeddings: embeddings,
+		Model:      resp.Model,
+		Usage: &Usage{
+			PromptTokens: int(resp.Usage.PromptTokens),
+			TotalTokens:  int(resp.Usage.TotalTokens),
+		},
+	}, nil
+}
+
 func (p *OpenAIProvider) convertToChatCompletionParams(req *ChatRequest) openai.ChatCompletionNewParams {
 	params := openai.ChatCompletionNewParams{
 		Model: req.Model,
@@ -142,6 +218,24 @@ func (p *OpenAIProvider) convertToChatCompletionParams(req *ChatRequest) openai.
 		params.TopP = openai.Float(req.TopP)
 	}
 	
+	if req.Stream {
+		params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
+			IncludeUsage: openai.Bool(true),
+		}
+	}
+	
+	if req.ResponseSchema != nil {
+		params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+			OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+				JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+					Name:   req.ResponseSchema.Name,
+					Schema: req.ResponseSchema.Schema,
+					Strict: openai.Bool(req.ResponseSchema.Strict),
+				},
+			},
+		}
+	}
+	
 	// Convert messages
 	messages := []openai.ChatCompletionMessageParamUnion{}
 	for _, msg := range req.Messages {
@@ -149,22 +243,41 @@ func (p *OpenAIProvider) convertToChatCompletionParams(req *ChatRequest) openai.
 		case "system":
 			messages = append(messages, openai.SystemMessage(msg.Content))
 		case "user":
-			messages = append(messages, openai.UserMessage(msg.Content))
+			if len(msg.Attachments) > 0 {
+				messages = append(messages, openai.UserMessage(openAIContentParts(&msg)))
+			} else {
+				messages = append(messages, openai.UserMessage(msg.Content))
+			}
 		case "assistant":
-			messages = append(messages, openai.AssistantMessage(msg.Content))
+			messages = append(messages, openAIAssistantMessage(&msg))
+		case "tool":
+			if msg.ToolResult != nil {
+				messages = append(messages, openai.ToolMessage(msg.Content, msg.ToolResult.ToolUseID))
+			}
 		}
 	}
 	params.Messages = messages
 	
-	// Convert tools - skip for now to get basic functionality working
+	for i := range req.Tools {
+		tool := &req.Tools[i]
+		function := shared.FunctionDefinitionParam{
+			Name:       tool.Name,
+			Parameters: shared.FunctionParameters(tool.schema()),
+		}
+		if tool.Description != "" {
+			function.Description = openai.String(tool.Description)
+		}
+		params.Tools = append(params.Tools, openai.ChatCompletionToolParam{Function: function})
+	}
 	
 	return params
 }
 
 func (p *OpenAIProvider) convertFromChatCompletion(resp *openai.ChatCompletion) *ChatResponse {
 	chatResp := &ChatResponse{
-		ID:    resp.ID,
-		Model: resp.Model,
+		ID:           resp.ID,
+		Model:        resp.Model,
+		ProviderMeta: openAIProviderMeta(resp),
 	}
 	
 	if resp.Usage.PromptTokens > 0 {
@@ -181,19 +294,92 @@ func (p *OpenAIProvider) convertFromChatCompletion(resp *openai.ChatCompletion)
 			chatResp.Content = choice.Message.Content
 		}
 		
-		// Convert tool calls
-		for _, toolCall := range choice.Message.ToolCalls {
-			if toolCall.Function.Name != "" {
-				chatResp.ToolUse = append(chatResp.ToolUse, ToolUse{
-					ID:   toolCall.ID,
-					Name: toolCall.Function.Name,
-					Args: map[string]interface{}{
-						"arguments": toolCall.Function.Arguments,
-					},
-				})
+		chatResp.ToolUse = openAIToolUses(choice.Message.ToolCalls)
+	}
+	
+	return chatResp
+}
+
+func openAIToolUses(toolCalls []openai.ChatCompletionMessageToolCall) []ToolUse {
+	var toolUses []ToolUse
+	for _, toolCall := range toolCalls {
+		if toolCall.Function.Name != "" {
+			toolUses = append(toolUses, ToolUse{
+				ID:   toolCall.ID,
+				Name: toolCall.Function.Name,
+				Args: parseToolArgs([]byte(toolCall.Function.Arguments)),
+			})
+		}
+	}
+	return toolUses
+}
+
+// openAIAssistantMessage converts an assistant message with the tool calls
+// it made, which OpenAI expects with their arguments encoded as JSON.
+func openAIAssistantMessage(msg *Message) openai.ChatCompletionMessageParamUnion {
+	message := openai.AssistantMessage(msg.Content)
+	if msg.Content == "" && len(msg.ToolUse) > 0 {
+		message.OfAssistant.Content = openai.ChatCompletionAssistantMessageParamContentUnion{}
+	}
+	for _, toolUse := range msg.ToolUse {
+		args, err := json.Marshal(toolUse.Args)
+		if err != nil || toolUse.Args == nil {
+			args = []byte("{}")
+		}
+		message.OfAssistant.ToolCalls = append(message.OfAssistant.ToolCalls, openai.ChatCompletionMessageToolCallParam{
+			ID: toolUse.ID,
+			Function: openai.ChatCompletionMessageToolCallFunctionParam{
+				Name:      toolUse.Name,
+				Arguments: string(args),
+			},
+		})
+	}
+	return message
+}
+
+func openAIProviderMeta(completion *openai.ChatCompletion) *ProviderMeta {
+	meta := &ProviderMeta{
+		SystemFingerprint: completion.SystemFingerprint,
+		ServiceTier:       string(completion.ServiceTier),
+	}
+	if len(completion.Choices) > 0 {
+		choice := completion.Choices[0]
+		meta.FinishReason = choice.FinishReason
+		meta.RefusalMessage = choice.Message.Refusal
+		meta.Refusal = choice.Message.Refusal != "" || choice.FinishReason == "content_filter"
+	}
+	return meta
+}
+
+// openAIContentParts converts a message's attachments to image and file
+// content parts, sent inline as data URLs.
+func openAIContentParts(msg *Message) []openai.ChatCompletionContentPartUnionParam {
+	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(msg.Attachments)+1)
+	if msg.Content != "" {
+		parts = append(parts, openai.TextContentPart(msg.Content))
+	}
+	
+	for i := range msg.Attachments {
+		attachment := &msg.Attachments[i]
+		switch {
+		case attachment.isImage():
+			parts = append(parts, openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+				URL: attachment.dataURL(),
+			}))
+		case attachment.isPDF():
+			file := openai.ChatCompletionContentPartFileFileParam{
+				FileData: openai.String(attachment.dataURL()),
+			}
+			if attachment.Name != "" {
+				file.Filename = openai.String(attachment.Name)
 			}
+			parts = append(parts, openai.FileContentPart(file))
+		case strings.HasPrefix(attachment.mediaType(), "text/"):
+			parts = append(parts, openai.TextContentPart(string(attachment.Data)))
+		default:
+			parts = append(parts, openai.TextContentPart(attachment.unsupportedNote()))
 		}
 	}
 	
-	return chatResp
+	return parts
 }
\ No newline at end of file
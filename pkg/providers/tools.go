@@ -0,0 +1,46 @@
+package providers
+
+import (
+	"encoding/json"
+)
+
+// schema returns the tool's parameters, or an object schema with no
+// properties for tools that take no arguments.
+func (t *Tool) schema() map[string]interface{} {
+	if len(t.Parameters) == 0 {
+		return map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	return t.Parameters
+}
+
+// schemaStrings reads a list of strings, such as required or enum, from a
+// schema decoded from YAML or JSON.
+func schemaStrings(value interface{}) []string {
+	switch list := value.(type) {
+	case []string:
+		return list
+	case []interface{}:
+		strs := make([]string, 0, len(list))
+		for _, item := range list {
+			if s, ok := item.(string); ok {
+				strs = append(strs, s)
+			}
+		}
+		return strs
+	}
+	return nil
+}
+
+// parseToolArgs decodes the JSON arguments of a tool call. Arguments that
+// are not a JSON object are kept as the raw string under "arguments", so
+// schema validation reports them rather than the call being dropped.
+func parseToolArgs(raw []byte) map[string]interface{} {
+	if len(raw) == 0 {
+		return map[string]interface{}{}
+	}
+	var args map[string]interface{}
+	if err := json.Unmarshal(raw, &args); err != nil || args == nil {
+		return map[string]interface{}{"arguments": string(raw)}
+	}
+	return args
+}
\ No newline at end of file
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"sync"
 	"time"
 )
 
@@ -14,38 +15,101 @@ type Provider interface {
 }
 
 type ChatRequest struct {
-	Model       string             `json:"model"`
-	Messages    []Message          `json:"messages"`
-	Tools       []Tool             `json:"tools,omitempty"`
-	MaxTokens   int                `json:"max_tokens,omitempty"`
-	Temperature float64            `json:"temperature,omitempty"`
-	TopP        float64            `json:"top_p,omitempty"`
-	Stream      bool               `json:"stream,omitempty"`
-	Metadata    map[string]string  `json:"metadata,omitempty"`
+	Model          string            `json:"model"`
+	Messages       []Message         `json:"messages"`
+	Tools          []Tool            `json:"tools,omitempty"`
+	MaxTokens      int               `json:"max_tokens,omitempty"`
+	Temperature    float64           `json:"temperature,omitempty"`
+	TopP           float64           `json:"top_p,omitempty"`
+	ThinkingBudget int               `json:"thinking_budget,omitempty"`
+	Stream         bool              `json:"stream,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	// ResponseSchema asks for a reply that is a JSON object matching it
+	ResponseSchema *ResponseSchema `json:"response_schema,omitempty"`
+}
+
+// ResponseSchema describes the JSON object a reply must be. Providers with
+// a structured output mode are held to it; the others are only asked, so
+// callers validate the reply either way. Strict turns on OpenAI's strict
+// schema adherence, which supports a subset of JSON Schema.
+type ResponseSchema struct {
+	Name   string                 `json:"name"`
+	Schema map[string]interface{} `json:"schema"`
+	Strict bool                   `json:"strict,omitempty"`
 }
 
 type ChatResponse struct {
-	ID      string    `json:"id"`
-	Content string    `json:"content"`
-	Usage   *Usage    `json:"usage,omitempty"`
-	ToolUse []ToolUse `json:"tool_use,omitempty"`
-	Model   string    `json:"model"`
-	Error   string    `json:"error,omitempty"`
+	ID       string    `json:"id"`
+	Content  string    `json:"content"`
+	Thinking string    `json:"thinking,omitempty"`
+	Usage    *Usage    `json:"usage,omitempty"`
+	ToolUse  []ToolUse `json:"tool_use,omitempty"`
+	Model    string    `json:"model"`
+	Error    string    `json:"error,omitempty"`
+	// ProviderMeta explains how the provider ended the response
+	ProviderMeta *ProviderMeta `json:"provider_meta,omitempty"`
+	// ThinkingBlocks are the reasoning blocks as the provider returned
+	// them, signed, to be sent back with the tool results of the turn
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
+}
+
+// ThinkingBlock is a block of a model's reasoning that must be replayed
+// unchanged in later turns. Thinking blocks carry the text and its
+// Signature, and redacted_thinking blocks only the encrypted Data.
+type ThinkingBlock struct {
+	Type      string `json:"type"`
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
 }
 
 type StreamChunk struct {
-	ID      string    `json:"id"`
-	Content string    `json:"content"`
-	Delta   string    `json:"delta"`
-	Done    bool      `json:"done"`
-	Usage   *Usage    `json:"usage,omitempty"`
-	ToolUse []ToolUse `json:"tool_use,omitempty"`
-	Error   string    `json:"error,omitempty"`
+	ID            string    `json:"id"`
+	Content       string    `json:"content"`
+	Delta         string    `json:"delta"`
+	Thinking      string    `json:"thinking,omitempty"`
+	ThinkingDelta string    `json:"thinking_delta,omitempty"`
+	Done          bool      `json:"done"`
+	Usage         *Usage    `json:"usage,omitempty"`
+	ToolUse       []ToolUse `json:"tool_use,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	// ProviderMeta is set on the final chunk
+	ProviderMeta *ProviderMeta `json:"provider_meta,omitempty"`
+	
+	// err keeps the typed provider error so failures such as revoked
+	// credentials can be classified without parsing Error.
+	err error
 }
 
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role        string       `json:"role"`
+	Content     string       `json:"content"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+	// ToolUse are the tool calls an assistant message made
+	ToolUse []ToolUse `json:"tool_use,omitempty"`
+	// ThinkingBlocks are the reasoning that led an assistant message to
+	// its tool calls, sent back first
+	ThinkingBlocks []ThinkingBlock `json:"thinking_blocks,omitempty"`
+	// ToolResult is set on messages with the "tool" role, whose Content is
+	// the output of the call
+	ToolResult *ToolResult `json:"tool_result,omitempty"`
+}
+
+// ToolResult identifies the call a tool message answers. IsError tells the
+// model the call failed, so Content holds the error.
+type ToolResult struct {
+	ToolUseID string `json:"tool_use_id"`
+	Name      string `json:"name"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// Attachment is a file sent to the model alongside a message's text.
+// Providers send the types they support, such as images and PDFs, and
+// describe the others in text.
+type Attachment struct {
+	Name     string `json:"name,omitempty"`
+	MimeType string `json:"mime_type"`
+	Data     []byte `json:"data"`
 }
 
 type Tool struct {
@@ -66,6 +130,29 @@ type Usage struct {
 	TotalTokens      int `json:"total_tokens"`
 }
 
+// ProviderMeta is what a provider reported about a response beyond its
+// content, in the provider's own terms: a finish reason of "end_turn",
+// "stop" or "SAFETY" is passed through as is. Refusal is set when the model
+// declined to answer or the provider's safety filters withheld the answer,
+// so callers can check it without knowing each provider's vocabulary.
+type ProviderMeta struct {
+	FinishReason      string         `json:"finish_reason,omitempty"`
+	StopSequence      string         `json:"stop_sequence,omitempty"`
+	Refusal           bool           `json:"refusal,omitempty"`
+	RefusalMessage    string         `json:"refusal_message,omitempty"`
+	BlockReason       string         `json:"block_reason,omitempty"`
+	SafetyRatings     []SafetyRating `json:"safety_ratings,omitempty"`
+	SystemFingerprint string         `json:"system_fingerprint,omitempty"`
+	ServiceTier       string         `json:"service_tier,omitempty"`
+	RequestID         string         `json:"request_id,omitempty"`
+}
+
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked,omitempty"`
+}
+
 type Config struct {
 	Anthropic *AnthropicConfig `json:"anthropic,omitempty"`
 	OpenAI    *OpenAIConfig    `json:"openai,omitempty"`
@@ -73,45 +160,92 @@ type Config struct {
 }
 
 type AnthropicConfig struct {
-	APIKey  string        `json:"api_key"`
-	BaseURL string        `json:"base_url,omitempty"`
-	Version string        `json:"version,omitempty"`
-	Timeout time.Duration `json:"timeout,omitempty"`
+	APIKey  string            `json:"api_key"`
+	BaseURL string            `json:"base_url,omitempty"`
+	Version string            `json:"version,omitempty"`
+	Timeout time.Duration     `json:"timeout,omitempty"`
+	HTTP    *HTTPClientConfig `json:"http,omitempty"`
 }
 
 type OpenAIConfig struct {
-	APIKey  string        `json:"api_key"`
-	BaseURL string        `json:"base_url,omitempty"`
-	OrgID   string        `json:"org_id,omitempty"`
-	Timeout time.Duration `json:"timeout,omitempty"`
+	APIKey  string            `json:"api_key"`
+	BaseURL string            `json:"base_url,omitempty"`
+	OrgID   string            `json:"org_id,omitempty"`
+	Timeout time.Duration     `json:"timeout,omitempty"`
+	HTTP    *HTTPClientConfig `json:"http,omitempty"`
 }
 
 type GeminiConfig struct {
-	APIKey    string        `json:"api_key"`
-	ProjectID string        `json:"project_id,omitempty"`
-	Timeout   time.Duration `json:"timeout,omitempty"`
+	APIKey    string            `json:"api_key"`
+	ProjectID string            `json:"project_id,omitempty"`
+	Timeout   time.Duration     `json:"timeout,omitempty"`
+	HTTP      *HTTPClientConfig `json:"http,omitempty"`
+}
+
+type HTTPClientConfig struct {
+	ProxyURL           string        `json:"proxy_url,omitempty"`
+	CABundle           string        `json:"ca_bundle,omitempty"`
+	InsecureSkipVerify bool          `json:"insecure_skip_verify,omitempty"`
+	ConnectTimeout     time.Duration `json:"connect_timeout,omitempty"`
+	ReadTimeout        time.Duration `json:"read_timeout,omitempty"`
+	Timeout            time.Duration `json:"timeout,omitempty"`
+	MaxIdleConns       int           `json:"max_idle_conns,omitempty"`
 }
 
 type Manager struct {
-	providers map[string]Provider
+	providers  map[string]Provider
+	wrapped    map[string]Provider
+	middleware []ProviderMiddleware
+	mu         sync.RWMutex
 }
 
 func NewManager() *Manager {
 	return &Manager{
 		providers: make(map[string]Provider),
+		wrapped:   make(map[string]Provider),
 	}
 }
 
 func (m *Manager) RegisterProvider(name string, provider Provider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
 	m.providers[name] = provider
+	m.wrapped[name] = wrapProvider(name, provider, m.middleware)
+}
+
+// Use appends middleware to the chain and rewraps all registered providers.
+func (m *Manager) Use(middleware ...ProviderMiddleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.middleware = append(m.middleware, middleware...)
+	for name, provider := range m.providers {
+		m.wrapped[name] = wrapProvider(name, provider, m.middleware)
+	}
+}
+
+// Wrap applies the manager's middleware to a provider that is not
+// registered with it, as if it were registered under name.
+func (m *Manager) Wrap(name string, provider Provider) Provider {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	return wrapProvider(name, provider, m.middleware)
 }
 
 func (m *Manager) GetProvider(name string) (Provider, bool) {
-	provider, exists := m.providers[name]
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	provider, exists := m.wrapped[name]
 	return provider, exists
 }
 
 func (m *Manager) ListProviders() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
 	names := make([]string, 0, len(m.providers))
 	for name := range m.providers {
 		names = append(names, name)
@@ -120,6 +254,9 @@ func (m *Manager) ListProviders() []string {
 }
 
 func (m *Manager) Close() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
 	for _, provider := range m.providers {
 		if err := provider.Close(); err != nil {
 			return err
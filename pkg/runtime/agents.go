@@ -0,0 +1,274 @@
+package runtime
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+type AgentOverrides struct {
+	Provider     string            `json:"provider,omitempty"`
+	Model        string            `json:"model,omitempty"`
+	SystemPrompt *string           `json:"system_prompt,omitempty"`
+	Environment  map[string]string `json:"environment,omitempty"`
+}
+
+func (e *Engine) CloneAgent(clusterName, agentName, newName string, overrides *AgentOverrides, pre *Precondition) (*config.Agent, error) {
+	if newName == "" {
+		return nil, fmt.Errorf("new agent name is required")
+	}
+	
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.Lock()
+	if err := checkPrecondition(cluster, pre); err != nil {
+		cluster.mu.Unlock()
+		return nil, err
+	}
+	source := findAgentSpec(cluster.Config, agentName)
+	if source == nil {
+		cluster.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	if findAgentSpec(cluster.Config, newName) != nil {
+		cluster.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s in cluster %s", ErrAgentExists, newName, clusterName)
+	}
+	
+	clone := copyAgentSpec(source)
+	clone.Name = newName
+	quota, agents := cluster.Config.Spec.Quota, len(cluster.Config.Spec.Agents)+1
+	// Claim the version now so a concurrent write made against the same
+	// version fails while the clone starts
+	e.bumpResourceVersion(cluster)
+	cluster.mu.Unlock()
+	
+	if overrides != nil {
+		if overrides.Provider != "" {
+			clone.Provider = overrides.Provider
+		}
+		if overrides.Model != "" {
+			clone.Model = overrides.Model
+		}
+		if overrides.SystemPrompt != nil {
+			clone.SystemPrompt = *overrides.SystemPrompt
+		}
+		for key, value := range overrides.Environment {
+			if clone.Environment == nil {
+				clone.Environment = make(map[string]string)
+			}
+			clone.Environment[key] = value
+		}
+	}
+	
+	if err := e.config.Policy.CheckAgent(clone); err != nil {
+		return nil, err
+	}
+	e.agentQuota.Lock()
+	defer e.agentQuota.Unlock()
+	if err := e.checkAgentQuota(clusterName, quota, agents); err != nil {
+		return nil, err
+	}
+	
+	_, exists, err := e.providerFor(cluster.Config.Metadata.Namespace, clone.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("provider %s not available", clone.Provider)
+	}
+	
+	if err := e.createAgent(cluster, clone); err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.Lock()
+	cluster.Config.Spec.Agents = append(cluster.Config.Spec.Agents, *clone)
+	cluster.UpdatedAt = time.Now()
+	e.saveCluster(cluster)
+	cluster.mu.Unlock()
+	
+	e.logger.Info("Agent cloned",
+		zap.String("cluster", clusterName),
+		zap.String("source", agentName),
+		zap.String("agent", newName))
+	
+	return clone, nil
+}
+
+func (e *Engine) RenameAgent(clusterName, agentName, newName string, pre *Precondition) error {
+	if newName == "" {
+		return fmt.Errorf("new agent name is required")
+	}
+	
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	
+	if err := checkPrecondition(cluster, pre); err != nil {
+		return err
+	}
+	
+	targetAgent, exists := cluster.Agents[agentName]
+	if !exists {
+		return fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	if _, taken := cluster.Agents[newName]; taken || findAgentSpec(cluster.Config, newName) != nil {
+		return fmt.Errorf("%w: %s in cluster %s", ErrAgentExists, newName, clusterName)
+	}
+	
+	delete(cluster.Agents, agentName)
+	cluster.Agents[newName] = targetAgent
+	targetAgent.SetName(newName)
+	e.versions.rename(clusterName, agentName, newName)
+	e.instances.rename(clusterName, agentName, newName)
+	
+	// Keep the spec, dependency, route and workflow references pointing at
+	// the new name
+	var dependents []string
+	for i := range cluster.Config.Spec.Agents {
+		spec := &cluster.Config.Spec.Agents[i]
+		if spec.Name == agentName {
+			spec.Name = newName
+		}
+		for j, dep := range spec.DependsOn {
+			if dep == agentName {
+				spec.DependsOn[j] = newName
+				dependents = append(dependents, spec.Name)
+			}
+		}
+		if spec.Router != nil {
+			for j := range spec.Router.Routes {
+				if spec.Router.Routes[j].Agent == agentName {
+					spec.Router.Routes[j].Agent = newName
+				}
+			}
+			if spec.Router.Default == agentName {
+				spec.Router.Default = newName
+			}
+		}
+	}
+	for _, running := range cluster.Agents {
+		if router := running.Config.Router; router != nil {
+			for j := range router.Routes {
+				if router.Routes[j].Agent == agentName {
+					router.Routes[j].Agent = newName
+				}
+			}
+			if router.Default == agentName {
+				router.Default = newName
+			}
+		}
+	}
+	for i := range cluster.Config.Spec.Workflows {
+		steps := cluster.Config.Spec.Workflows[i].Steps
+		for j := range steps {
+			if steps[j].Agent == agentName {
+				steps[j].Agent = newName
+			}
+		}
+	}
+	// Agents depending on it delegate through a tool named after it
+	for _, name := range dependents {
+		running, exists := cluster.Agents[name]
+		if !exists || name == newName {
+			continue
+		}
+		if err := e.retargetDelegation(clusterName, running, agentName, newName); err != nil {
+			e.logger.Warn("Failed to update delegation tool",
+				zap.String("cluster", clusterName),
+				zap.String("agent", name),
+				zap.String("to", newName),
+				zap.Error(err))
+		}
+	}
+	cluster.UpdatedAt = time.Now()
+	e.bumpResourceVersion(cluster)
+	e.saveCluster(cluster)
+	
+	e.logger.Info("Agent renamed",
+		zap.String("cluster", clusterName),
+		zap.String("from", agentName),
+		zap.String("to", newName))
+	
+	return nil
+}
+
+func findAgentSpec(cluster *config.AgentCluster, name string) *config.Agent {
+	for i := range cluster.Spec.Agents {
+		if cluster.Spec.Agents[i].Name == name {
+			return &cluster.Spec.Agents[i]
+		}
+	}
+	return nil
+}
+
+func copyAgentSpec(source *config.Agent) *config.Agent {
+	clone := *source
+	clone.Tools = append([]config.Tool(nil), source.Tools...)
+	for i, tool := range clone.Tools {
+		clone.Tools[i].Command = append([]string(nil), tool.Command...)
+		clone.Tools[i].Args = append([]string(nil), tool.Args...)
+		clone.Tools[i].Env = copyStringMap(tool.Env)
+		clone.Tools[i].Config = copyStringMap(tool.Config)
+		if tool.Auth != nil {
+			auth := *tool.Auth
+			clone.Tools[i].Auth = &auth
+		}
+	}
+	clone.DependsOn = append([]string(nil), source.DependsOn...)
+	clone.Environment = copyStringMap(source.Environment)
+	if source.Cache != nil {
+		cache := *source.Cache
+		clone.Cache = &cache
+	}
+	if source.Router != nil {
+		router := *source.Router
+		router.Routes = append([]config.Route(nil), source.Router.Routes...)
+		clone.Router = &router
+	}
+	if source.SmokeTests != nil {
+		clone.SmokeTests = make([]config.SmokeTest, len(source.SmokeTests))
+		for i, test := range source.SmokeTests {
+			test.Expect.Contains = append([]string(nil), test.Expect.Contains...)
+			test.Expect.NotContains = append([]string(nil), test.Expect.NotContains...)
+			clone.SmokeTests[i] = test
+		}
+	}
+	if source.Fallback != nil {
+		fallback := *source.Fallback
+		clone.Fallback = &fallback
+	}
+	if source.Experiment != nil {
+		experiment := *source.Experiment
+		experiment.Variants = append([]config.ExperimentVariant(nil), source.Experiment.Variants...)
+		clone.Experiment = &experiment
+	}
+	if source.Scaling.Autoscale != nil {
+		autoscale := *source.Scaling.Autoscale
+		clone.Scaling.Autoscale = &autoscale
+	}
+	return &clone
+}
+
+
+func copyStringMap(source map[string]string) map[string]string {
+	if source == nil {
+		return nil
+	}
+	
+	clone := make(map[string]string, len(source))
+	for key, value := range source {
+		clone[key] = value
+	}
+	return clone
+}
\ No newline at end of file
@@ -0,0 +1,326 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+// clusterOnlyFields are the cluster fields agents are built without, so
+// changing them leaves the agents running. Any other cluster field, such
+// as the system prompt or the outbound policy, recreates every agent.
+var clusterOnlyFields = []string{
+	"apiVersion",
+	"kind",
+	"metadata.annotations",
+	"spec.run_smoke_tests",
+	"spec.startup_timeout",
+	"spec.quota",
+	"spec.workflows",
+	"spec.schedules",
+}
+
+// ClusterApply is what applying a spec to a cluster changed or, for a dry
+// run, would change.
+type ClusterApply struct {
+	*ClusterDiff
+	DryRun bool `json:"dry_run"`
+	// AgentsRecreated are the agents replaced by ones built from the new
+	// spec; agents not listed, added or removed keep running untouched
+	AgentsRecreated []string `json:"agents_recreated"`
+	// Restarted is set when the cluster was not running, so it is started
+	// from the new spec instead
+	Restarted       bool   `json:"restarted"`
+	ResourceVersion string `json:"resource_version"`
+}
+
+// ApplyCluster brings a cluster to a candidate spec, changing only what
+// differs: agents the candidate adds are created, agents it removes are
+// stopped, and agents whose spec changed are recreated, while the others
+// keep serving requests. A cluster that is not running is restarted from
+// the candidate, as UpdateCluster does. With dryRun set nothing changes and
+// the result is the plan. The write is refused with ErrConflict if pre
+// does not hold; when pre has no resource version, the candidate's
+// metadata.resourceVersion is used.
+func (e *Engine) ApplyCluster(clusterName string, candidate *config.AgentCluster, pre *Precondition, dryRun bool) (*ClusterApply, error) {
+	diff, err := e.DiffCluster(clusterName, candidate)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ValidateClusterNames(candidate); err != nil {
+		return nil, err
+	}
+	if err := e.config.Policy.CheckCluster(candidate); err != nil {
+		return nil, err
+	}
+	if err := e.checkAgentQuota(clusterName, candidate.Spec.Quota, len(candidate.Spec.Agents)); err != nil {
+		return nil, err
+	}
+	order, err := startupOrder(candidate.Spec.Agents)
+	if err != nil {
+		return nil, err
+	}
+	
+	if pre == nil {
+		pre = &Precondition{}
+	}
+	if pre.ResourceVersion == "" {
+		pre.ResourceVersion = candidate.Metadata.ResourceVersion
+	}
+	
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	cluster.mu.RLock()
+	err = checkPrecondition(cluster, pre)
+	version := cluster.Config.Metadata.ResourceVersion
+	running := cluster.Status == ClusterStatusRunning && cluster.started == cluster.Config
+	cluster.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	
+	result := &ClusterApply{
+		ClusterDiff:     diff,
+		DryRun:          dryRun,
+		AgentsRecreated: []string{},
+		Restarted:       !running,
+		ResourceVersion: version,
+	}
+	rebuild := make(map[string]bool)
+	for _, name := range diff.AgentsAdded {
+		rebuild[name] = true
+	}
+	for _, changed := range diff.AgentsChanged {
+		rebuild[changed.Name] = true
+		result.AgentsRecreated = append(result.AgentsRecreated, changed.Name)
+	}
+	if !running || agentFieldsChanged(diff.Fields) {
+		result.AgentsRecreated = result.AgentsRecreated[:0]
+		for _, spec := range candidate.Spec.Agents {
+			rebuild[spec.Name] = true
+			if !isAdded(diff, spec.Name) {
+				result.AgentsRecreated = append(result.AgentsRecreated, spec.Name)
+			}
+		}
+	}
+	
+	if dryRun || (running && !diff.Changed) {
+		return result, nil
+	}
+	if !running {
+		pre.ResourceVersion = version
+		if err := e.UpdateCluster(clusterName, candidate, pre); err != nil {
+			return nil, err
+		}
+		result.ResourceVersion = candidate.Metadata.ResourceVersion
+		return result, nil
+	}
+	
+	// Build the new agents first, against the candidate, so the cluster
+	// serves requests with its current agents until they are all ready
+	staging := &Cluster{Name: clusterName, Config: candidate}
+	built := make(map[string]*agent.Agent)
+	discard := func() {
+		for _, a := range built {
+			e.retireAgent(a)
+		}
+	}
+	for _, spec := range order {
+		if !rebuild[spec.Name] {
+			continue
+		}
+		a, err := e.buildAgent(staging, findAgentSpec(candidate, spec.Name))
+		if err != nil {
+			discard()
+			return nil, fmt.Errorf("failed to create agent %s: %w", spec.Name, err)
+		}
+		built[spec.Name] = a
+	}
+	
+	// Other writes to the namespace may have been made while the agents
+	// were built, so check the quota again before the spec takes effect
+	e.agentQuota.Lock()
+	defer e.agentQuota.Unlock()
+	if err := e.checkAgentQuota(clusterName, candidate.Spec.Quota, len(candidate.Spec.Agents)); err != nil {
+		discard()
+		return nil, err
+	}
+	
+	cluster.mu.Lock()
+	if cluster.Config.Metadata.ResourceVersion != version || cluster.started != cluster.Config {
+		cluster.mu.Unlock()
+		discard()
+		return nil, fmt.Errorf("%w: cluster %s changed while the spec was applied", ErrConflict, clusterName)
+	}
+	var retired []*agent.Agent
+	agents := make(map[string]*agent.Agent, len(candidate.Spec.Agents))
+	for name, current := range cluster.Agents {
+		if a, ok := built[name]; ok {
+			agents[name] = a
+			retired = append(retired, current)
+		} else if findAgentSpec(candidate, name) != nil {
+			agents[name] = current
+		} else {
+			retired = append(retired, current)
+		}
+	}
+	for name, a := range built {
+		agents[name] = a
+	}
+	candidate.Metadata.ResourceVersion = version
+	cluster.Config = candidate
+	cluster.started = candidate
+	cluster.Agents = agents
+	cluster.Message = ""
+	cluster.UpdatedAt = time.Now()
+	e.bumpResourceVersion(cluster)
+	e.saveCluster(cluster)
+	cluster.mu.Unlock()
+	result.ResourceVersion = candidate.Metadata.ResourceVersion
+	
+	for name, a := range built {
+		if rollout := e.versions.takeRollout(clusterName, name); rollout != nil {
+			e.retireAgent(rollout.candidate)
+		}
+		e.versions.deployed(clusterName, name, a.Version)
+		e.instances.configure(clusterName, a)
+		if spec := findAgentSpec(candidate, name); spec.Health != nil {
+			e.watchHealth(a, spec.Health)
+		}
+	}
+	for _, name := range diff.AgentsRemoved {
+		if rollout := e.versions.takeRollout(clusterName, name); rollout != nil {
+			e.retireAgent(rollout.candidate)
+		}
+		e.instances.remove(clusterName, name)
+	}
+	for _, a := range retired {
+		e.retireAgent(a)
+	}
+	e.instances.setLimit(clusterName, candidate.Spec.ResourcePolicy.MaxConcurrentAgents)
+	if fieldChanged(diff.Fields, "spec.schedules") {
+		e.startSchedules(cluster)
+	}
+	if candidate.Spec.RunSmokeTests && len(built) > 0 {
+		go e.runSmokeTests(cluster, candidate)
+	}
+	
+	e.logger.Info("Cluster applied",
+		zap.String("name", clusterName),
+		zap.Strings("added", diff.AgentsAdded),
+		zap.Strings("removed", diff.AgentsRemoved),
+		zap.Strings("recreated", result.AgentsRecreated),
+		zap.String("resource_version", candidate.Metadata.ResourceVersion))
+	
+	return result, nil
+}
+
+// PatchCluster applies a JSON merge patch (RFC 7386) to a cluster's spec
+// and applies the result as ApplyCluster does. Lists, such as the agents,
+// are replaced as a whole. Without a resource version in pre, the patch is
+// applied only if the cluster has not changed since it was read.
+func (e *Engine) PatchCluster(clusterName string, patch []byte, pre *Precondition, dryRun bool) (*ClusterApply, error) {
+	var changes interface{}
+	if err := json.Unmarshal(patch, &changes); err != nil {
+		return nil, fmt.Errorf("invalid merge patch: %w", err)
+	}
+	
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	cluster.mu.RLock()
+	data, err := json.Marshal(cluster.Config)
+	version := cluster.Config.Metadata.ResourceVersion
+	cluster.mu.RUnlock()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cluster spec: %w", err)
+	}
+	var current interface{}
+	if err := json.Unmarshal(data, &current); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster spec: %w", err)
+	}
+	
+	data, err = json.Marshal(mergePatch(current, changes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode patched spec: %w", err)
+	}
+	var candidate config.AgentCluster
+	if err := json.Unmarshal(data, &candidate); err != nil {
+		return nil, fmt.Errorf("invalid patched spec: %w", err)
+	}
+	
+	if pre == nil {
+		pre = &Precondition{}
+	}
+	if pre.ResourceVersion == "" {
+		pre.ResourceVersion = version
+	}
+	return e.ApplyCluster(clusterName, &candidate, pre, dryRun)
+}
+
+// mergePatch applies a JSON merge patch to a decoded JSON document: null
+// removes a field, objects merge and any other value replaces the target.
+func mergePatch(target, patch interface{}) interface{} {
+	fields, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	merged, ok := target.(map[string]interface{})
+	if !ok {
+		merged = make(map[string]interface{})
+	}
+	for key, value := range fields {
+		if value == nil {
+			delete(merged, key)
+		} else {
+			merged[key] = mergePatch(merged[key], value)
+		}
+	}
+	return merged
+}
+
+// agentFieldsChanged reports whether a changed cluster field is one the
+// agents are built from.
+func agentFieldsChanged(changes []FieldChange) bool {
+	for _, change := range changes {
+		if !hasFieldPrefix(change.Field, clusterOnlyFields) {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldChanged(changes []FieldChange, field string) bool {
+	for _, change := range changes {
+		if hasFieldPrefix(change.Field, []string{field}) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFieldPrefix(field string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if field == prefix || strings.HasPrefix(field, prefix+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func isAdded(diff *ClusterDiff, name string) bool {
+	for _, added := range diff.AgentsAdded {
+		if added == name {
+			return true
+		}
+	}
+	return false
+}
\ No newline at end of file
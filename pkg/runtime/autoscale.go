@@ -0,0 +1,288 @@
+package runtime
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+const (
+	autoscaleInterval        = 10 * time.Second
+	autoscaleWindow          = time.Minute
+	maxLoadSamples           = 10000
+	defaultQueueDepth        = 1
+	defaultScaleUpCooldown   = 30 * time.Second
+	defaultScaleDownCooldown = 5 * time.Minute
+	defaultIdleTimeout       = 5 * time.Minute
+)
+
+// Reasons an agent's instances were resized
+const (
+	ScaleReasonManual     = "manual"
+	ScaleReasonRequest    = "request"
+	ScaleReasonIdle       = "idle"
+	ScaleReasonQueueDepth = "queue_depth"
+	ScaleReasonLatency    = "p95_latency"
+	ScaleReasonTokens     = "tokens_per_second"
+	ScaleReasonLowLoad    = "low_load"
+	ScaleReasonEvicted    = "evicted"
+)
+
+// ScaleEvent records a change to the number of an agent's instances.
+type ScaleEvent struct {
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+// AgentLoad is the load on an agent's instances over the last minute.
+// P95QueueMs is the 95th percentile of the time requests waited for an
+// instance, which their latency includes.
+type AgentLoad struct {
+	Requests        int     `json:"requests"`
+	P95LatencyMs    int64   `json:"p95_latency_ms"`
+	P95QueueMs      int64   `json:"p95_queue_ms"`
+	TokensPerSecond float64 `json:"tokens_per_second"`
+}
+
+// loadSample is a request an agent served. Its latency includes the time
+// it was queued.
+type loadSample struct {
+	at      time.Time
+	latency time.Duration
+	queued  time.Duration
+	tokens  int
+}
+
+// record adds a request to the pool's load. The caller holds the lock.
+func (pool *instancePool) record(sample loadSample) {
+	pool.trim(sample.at)
+	if len(pool.samples) >= maxLoadSamples {
+		pool.samples = pool.samples[1:]
+	}
+	pool.samples = append(pool.samples, sample)
+}
+
+// trim drops the requests older than the autoscale window.
+func (pool *instancePool) trim(now time.Time) {
+	cutoff := now.Add(-autoscaleWindow)
+	i := sort.Search(len(pool.samples), func(i int) bool {
+		return pool.samples[i].at.After(cutoff)
+	})
+	pool.samples = pool.samples[i:]
+}
+
+func (pool *instancePool) load(now time.Time) AgentLoad {
+	pool.trim(now)
+	load := AgentLoad{Requests: len(pool.samples)}
+	if len(pool.samples) == 0 {
+		return load
+	}
+	
+	latencies := make([]time.Duration, len(pool.samples))
+	queued := make([]time.Duration, len(pool.samples))
+	tokens := 0
+	for i, sample := range pool.samples {
+		latencies[i] = sample.latency
+		queued[i] = sample.queued
+		tokens += sample.tokens
+	}
+	load.P95LatencyMs = percentile95(latencies).Milliseconds()
+	load.P95QueueMs = percentile95(queued).Milliseconds()
+	load.TokensPerSecond = float64(tokens) / autoscaleWindow.Seconds()
+	return load
+}
+
+// autoscale resizes the pool for its load, and scales it to zero once it
+// has been idle as long as the policy allows. The caller holds the lock.
+func (pool *instancePool) autoscale(now time.Time, policy config.ResourcePolicy) (ScaleEvent, bool) {
+	load := pool.load(now)
+	peakActive, peakQueued := pool.peakActive, pool.peakQueued
+	pool.peakActive, pool.peakQueued = pool.active(), len(pool.waiting)
+	
+	n := pool.desired
+	if n == 0 {
+		// The next request starts an instance
+		return ScaleEvent{}, false
+	}
+	var sinceScaled time.Duration = math.MaxInt64
+	if pool.lastScaled != nil {
+		sinceScaled = now.Sub(pool.lastScaled.Time)
+	}
+	
+	if policy.ScaleToZero && pool.scaling.MinInstances == 0 && peakActive == 0 && peakQueued == 0 {
+		if now.Sub(pool.lastRequest) >= idleTimeout(policy) {
+			return pool.scale(0, ScaleReasonIdle), true
+		}
+	}
+	
+	autoscale := pool.scaling.Autoscale
+	if autoscale == nil {
+		return ScaleEvent{}, false
+	}
+	
+	// How far the load is over the targets the instances there are can
+	// take, by the target it is furthest over
+	queueDepth := autoscale.QueueDepth
+	if queueDepth <= 0 {
+		queueDepth = defaultQueueDepth
+	}
+	ratio, reason := float64(peakQueued)/float64(n*queueDepth), ScaleReasonQueueDepth
+	latency := time.Duration(load.P95LatencyMs) * time.Millisecond
+	if autoscale.Latency > 0 {
+		if r := float64(latency) / float64(autoscale.Latency); r > ratio {
+			ratio, reason = r, ScaleReasonLatency
+		}
+	}
+	if autoscale.TokensPerSecond > 0 {
+		if r := load.TokensPerSecond / (float64(n) * autoscale.TokensPerSecond); r > ratio {
+			ratio, reason = r, ScaleReasonTokens
+		}
+	}
+	
+	if ratio > 1 {
+		cooldown := autoscale.ScaleUpCooldown
+		if cooldown <= 0 {
+			cooldown = defaultScaleUpCooldown
+		}
+		if n >= pool.scaling.MaxInstances || sinceScaled < cooldown {
+			return ScaleEvent{}, false
+		}
+		want := int(math.Ceil(float64(n) * ratio))
+		if want > pool.scaling.MaxInstances {
+			want = pool.scaling.MaxInstances
+		}
+		return pool.scale(want, reason), true
+	}
+	
+	// Instances are removed one at a time, once the load of the window
+	// would have fit on one fewer
+	cooldown := autoscale.ScaleDownCooldown
+	if cooldown <= 0 {
+		cooldown = defaultScaleDownCooldown
+	}
+	floor := pool.scaling.MinInstances
+	if floor < 1 {
+		floor = 1
+	}
+	if n <= floor || sinceScaled < cooldown || peakQueued > 0 {
+		return ScaleEvent{}, false
+	}
+	if pool.scaling.MaxConcurrency > 0 && peakActive > (n-1)*pool.scaling.MaxConcurrency {
+		return ScaleEvent{}, false
+	}
+	if autoscale.TokensPerSecond > 0 && load.TokensPerSecond > float64(n-1)*autoscale.TokensPerSecond {
+		return ScaleEvent{}, false
+	}
+	return pool.scale(n-1, ScaleReasonLowLoad), true
+}
+
+// idleTimeout is how long a cluster's agents go without requests before
+// they are idle.
+func idleTimeout(policy config.ResourcePolicy) time.Duration {
+	if policy.IdleTimeout <= 0 {
+		return defaultIdleTimeout
+	}
+	return policy.IdleTimeout
+}
+
+// active counts the requests the pool's instances are serving.
+// percentile95 returns the 95th percentile of durations, which it sorts.
+func percentile95(durations []time.Duration) time.Duration {
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	return durations[(len(durations)*95+99)/100-1]
+}
+
+func (pool *instancePool) active() int {
+	active := 0
+	for _, inst := range pool.instances {
+		active += inst.active
+	}
+	return active
+}
+
+func (p *instancePools) autoscale(clusterName string, a *agent.Agent, now time.Time, policy config.ResourcePolicy) (ScaleEvent, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	pool, ok := p.pools[budgetKey(clusterName, a.GetName())]
+	if !ok {
+		return ScaleEvent{}, false
+	}
+	event, ok := pool.autoscale(now, policy)
+	if ok && event.To == 0 {
+		p.broadcastFreed()
+	}
+	return event, ok
+}
+
+// runAutoscaler resizes the instances of autoscaled agents, and scales
+// idle agents to zero where their cluster's resource policy allows it,
+// until the engine shuts down.
+func (e *Engine) runAutoscaler() {
+	ticker := time.NewTicker(autoscaleInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-e.done:
+			return
+		case now := <-ticker.C:
+			e.autoscale(now)
+		}
+	}
+}
+
+func (e *Engine) autoscale(now time.Time) {
+	for _, cluster := range e.ListClusters() {
+		cluster.mu.RLock()
+		running := cluster.Status == ClusterStatusRunning
+		policy := cluster.Config.Spec.ResourcePolicy
+		agents := make([]*agent.Agent, 0, len(cluster.Agents))
+		for _, a := range cluster.Agents {
+			agents = append(agents, a)
+		}
+		cluster.mu.RUnlock()
+		if !running {
+			continue
+		}
+		
+		for _, a := range agents {
+			if event, ok := e.instances.autoscale(cluster.Name, a, now, policy); ok {
+				e.agentScaled(a, event)
+			}
+		}
+		if policy.ScaleToZero {
+			e.settleWarmPool(cluster, agents, policy.WarmPool)
+		}
+	}
+}
+
+// agentScaled logs and publishes a change to an agent's instances.
+func (e *Engine) agentScaled(a *agent.Agent, event ScaleEvent) {
+	e.logger.Info("Agent scaled",
+		zap.String("cluster", a.ClusterName),
+		zap.String("agent", a.GetName()),
+		zap.Int("from", event.From),
+		zap.Int("to", event.To),
+		zap.String("reason", event.Reason))
+	
+	e.publishEvent(agent.Event{
+		Type:    agent.EventAgentScaled,
+		AgentID: a.ID,
+		Data: map[string]interface{}{
+			"cluster": a.ClusterName,
+			"agent":   a.GetName(),
+			"from":    event.From,
+			"to":      event.To,
+			"reason":  event.Reason,
+		},
+	})
+}
\ No newline at end of file
@@ -0,0 +1,247 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+// Budget scopes and limits, as reported by BudgetError
+const (
+	BudgetScopeRequest = "request"
+	BudgetScopeSession = "session"
+	BudgetScopeDaily   = "daily"
+	
+	BudgetLimitTurns  = "turns"
+	BudgetLimitTokens = "tokens"
+	BudgetLimitCost   = "cost"
+)
+
+// BudgetError is returned for requests that ran out of one of their agent's
+// budgets. It wraps ErrBudgetExceeded.
+type BudgetError struct {
+	Cluster string `json:"cluster"`
+	Agent   string `json:"agent"`
+	Scope   string `json:"scope"`
+	Limit   string `json:"limit"`
+	// Used is what the scope had used when the request was stopped; costs
+	// are in US dollars
+	Used float64 `json:"used"`
+	Max  float64 `json:"max"`
+}
+
+func (e *BudgetError) Error() string {
+	if e.Limit == BudgetLimitCost {
+		return fmt.Sprintf("%s: %s %s budget of $%.4f used up ($%.4f)", ErrBudgetExceeded, e.Scope, e.Limit, e.Max, e.Used)
+	}
+	return fmt.Sprintf("%s: %s %s budget of %.0f used up (%.0f)", ErrBudgetExceeded, e.Scope, e.Limit, e.Max, e.Used)
+}
+
+func (e *BudgetError) Unwrap() error {
+	return ErrBudgetExceeded
+}
+
+// BudgetSpend is what was used of a budget.
+type BudgetSpend struct {
+	Tokens int `json:"tokens"`
+	// Cost is in US dollars, for models with a known price
+	Cost float64 `json:"cost"`
+}
+
+// BudgetStatus is an agent's budget and what it has used today.
+type BudgetStatus struct {
+	Cluster string              `json:"cluster"`
+	Agent   string              `json:"agent"`
+	Budget  *config.AgentBudget `json:"budget,omitempty"`
+	// Day is the UTC day Today counts
+	Day   string      `json:"day"`
+	Today BudgetSpend `json:"today"`
+}
+
+// budgetLedger counts what each agent has used in the current UTC day.
+type budgetLedger struct {
+	day   string
+	spent map[string]BudgetSpend
+	mu    sync.Mutex
+}
+
+func newBudgetLedger() *budgetLedger {
+	return &budgetLedger{spent: make(map[string]BudgetSpend)}
+}
+
+func budgetKey(clusterName, agentName string) string {
+	return clusterName + "/" + agentName
+}
+
+// today returns what the agent has used today and the day it is.
+func (l *budgetLedger) today(key string) (BudgetSpend, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.rollover()
+	return l.spent[key], l.day
+}
+
+func (l *budgetLedger) charge(key string, spend BudgetSpend) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.rollover()
+	total := l.spent[key]
+	total.Tokens += spend.Tokens
+	total.Cost += spend.Cost
+	l.spent[key] = total
+}
+
+// rollover starts a new day's counts once the UTC day changes. The caller
+// must hold l.mu.
+func (l *budgetLedger) rollover() {
+	if day := time.Now().UTC().Format("2006-01-02"); day != l.day {
+		l.day = day
+		l.spent = make(map[string]BudgetSpend)
+	}
+}
+
+// requestBudget tracks what a request uses against its agent's budgets.
+type requestBudget struct {
+	cluster string
+	agent   string
+	budget  *config.AgentBudget
+	turns   int
+	priced  bool
+	spent   BudgetSpend
+	// session and daily are what the session and the agent had used
+	// before the request
+	session BudgetSpend
+	daily   BudgetSpend
+}
+
+// startBudget starts tracking a request that calls model.
+func (e *Engine) startBudget(clusterName string, targetAgent *agent.Agent, model string, req *agent.Request) *requestBudget {
+	daily, _ := e.budgetLedger.today(budgetKey(clusterName, targetAgent.GetName()))
+	_, priced := targetAgent.Config.Budget.Price(model)
+	return &requestBudget{
+		cluster: clusterName,
+		agent:   targetAgent.GetName(),
+		budget:  targetAgent.Config.Budget,
+		priced:  priced,
+		session: BudgetSpend{Tokens: req.SessionTokens, Cost: req.SessionCost},
+		daily:   daily,
+	}
+}
+
+// add counts a model call and returns what it cost. Cached answers count
+// as a turn but cost nothing.
+func (b *requestBudget) add(model string, usage *providers.Usage, cached bool) float64 {
+	b.turns++
+	if usage == nil || cached {
+		return 0
+	}
+	
+	b.spent.Tokens += usage.TotalTokens
+	price, ok := b.budget.Price(model)
+	if !ok {
+		return 0
+	}
+	cost := price.Cost(usage.PromptTokens, usage.CompletionTokens)
+	b.spent.Cost += cost
+	return cost
+}
+
+// check returns a *BudgetError if any budget is used up, which stops the
+// request before its next model call.
+func (b *requestBudget) check() *BudgetError {
+	if b.budget == nil {
+		return nil
+	}
+	
+	if limits := b.budget.Request; limits != nil && limits.MaxTurns > 0 && b.turns >= limits.MaxTurns {
+		return b.exceeded(BudgetScopeRequest, BudgetLimitTurns, float64(b.turns), float64(limits.MaxTurns))
+	}
+	for _, scope := range []struct {
+		name   string
+		limits *config.BudgetLimits
+		before BudgetSpend
+	}{
+		{BudgetScopeRequest, b.budget.Request, BudgetSpend{}},
+		{BudgetScopeSession, b.budget.Session, b.session},
+		{BudgetScopeDaily, b.budget.Daily, b.daily},
+	} {
+		if scope.limits == nil {
+			continue
+		}
+		if tokens := scope.before.Tokens + b.spent.Tokens; scope.limits.MaxTokens > 0 && tokens >= scope.limits.MaxTokens {
+			return b.exceeded(scope.name, BudgetLimitTokens, float64(tokens), float64(scope.limits.MaxTokens))
+		}
+		if cost := scope.before.Cost + b.spent.Cost; scope.limits.MaxCost > 0 && cost >= scope.limits.MaxCost {
+			return b.exceeded(scope.name, BudgetLimitCost, cost, scope.limits.MaxCost)
+		}
+	}
+	return nil
+}
+
+func (b *requestBudget) exceeded(scope, limit string, used, max float64) *BudgetError {
+	return &BudgetError{
+		Cluster: b.cluster,
+		Agent:   b.agent,
+		Scope:   scope,
+		Limit:   limit,
+		Used:    used,
+		Max:     max,
+	}
+}
+
+// chargeBudget adds what a request used to its agent's daily spend, and to
+// its cluster's and its namespace's daily quota usage.
+func (e *Engine) chargeBudget(b *requestBudget) {
+	e.budgetLedger.charge(budgetKey(b.cluster, b.agent), b.spent)
+	e.quotas.charge(b.cluster, b.spent)
+}
+
+// budgetExceeded reports a request stopped by a budget.
+func (e *Engine) budgetExceeded(targetAgent *agent.Agent, requestID string, err *BudgetError) {
+	e.logger.Warn("Request stopped by budget",
+		zap.String("cluster", err.Cluster),
+		zap.String("agent", err.Agent),
+		zap.String("request", requestID),
+		zap.String("scope", err.Scope),
+		zap.String("limit", err.Limit),
+		zap.Float64("used", err.Used),
+		zap.Float64("max", err.Max))
+	
+	e.publishEvent(agent.Event{
+		Type:    agent.EventBudgetExceeded,
+		AgentID: targetAgent.ID,
+		Data: map[string]interface{}{
+			"cluster":    err.Cluster,
+			"agent":      err.Agent,
+			"request_id": requestID,
+			"scope":      err.Scope,
+			"limit":      err.Limit,
+			"used":       err.Used,
+			"max":        err.Max,
+		},
+	})
+}
+
+// AgentBudget returns an agent's budget and what it has used today.
+func (e *Engine) AgentBudget(agentID string) (*BudgetStatus, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	
+	today, day := e.budgetLedger.today(budgetKey(a.ClusterName, a.GetName()))
+	return &BudgetStatus{
+		Cluster: a.ClusterName,
+		Agent:   a.GetName(),
+		Budget:  a.Config.Budget,
+		Day:     day,
+		Today:   today,
+	}, nil
+}
\ No newline at end of file
@@ -0,0 +1,260 @@
+package runtime
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+)
+
+type ColdStartKind string
+
+const (
+	// ColdStartAgentStart covers creating an agent, including tool discovery
+	ColdStartAgentStart ColdStartKind = "agent_start"
+	// ColdStartMCPHandshake covers starting or reconnecting an MCP server
+	// and its initialize handshake
+	ColdStartMCPHandshake ColdStartKind = "mcp_handshake"
+	// ColdStartProviderWarmup is the first provider call made by an agent
+	ColdStartProviderWarmup ColdStartKind = "provider_warmup"
+	// ColdStartWake is the first request served by an agent that had gone
+	// idle
+	ColdStartWake ColdStartKind = "wake"
+)
+
+const (
+	coldStartRetention  = 6 * time.Hour
+	coldStartMaxSamples = 10000
+	
+	defaultColdStartThreshold = 5 * time.Second
+	defaultColdStartObjective = 0.99
+)
+
+// coldStartWindows are the windows burn rates are reported over. A short
+// and a long window together separate brief spikes from sustained burn.
+var coldStartWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+	{"6h", 6 * time.Hour},
+}
+
+type ColdStartSample struct {
+	Kind     ColdStartKind `json:"kind"`
+	Cluster  string        `json:"cluster"`
+	Agent    string        `json:"agent"`
+	Target   string        `json:"target,omitempty"`
+	Duration time.Duration `json:"duration"`
+	At       time.Time     `json:"at"`
+}
+
+type ColdStartFilter struct {
+	Cluster string
+	Agent   string
+}
+
+// ColdStartReport summarizes the samples of one kind. Durations are in
+// milliseconds.
+type ColdStartReport struct {
+	Kind        ColdStartKind            `json:"kind"`
+	Count       int                      `json:"count"`
+	P50Ms       float64                  `json:"p50_ms"`
+	P90Ms       float64                  `json:"p90_ms"`
+	P99Ms       float64                  `json:"p99_ms"`
+	MaxMs       float64                  `json:"max_ms"`
+	ThresholdMs float64                  `json:"threshold_ms"`
+	Objective   float64                  `json:"objective"`
+	BurnRates   map[string]ColdStartBurn `json:"burn_rates"`
+}
+
+// ColdStartBurn is the SLO error budget burn over one window. A burn rate
+// of 1 uses the budget exactly as fast as the objective allows.
+type ColdStartBurn struct {
+	Total    int     `json:"total"`
+	Slow     int     `json:"slow"`
+	BurnRate float64 `json:"burn_rate"`
+}
+
+type coldStartRecorder struct {
+	slo     config.ColdStartSLOConfig
+	samples []ColdStartSample
+	warmed  map[string]bool
+	mu      sync.Mutex
+}
+
+func newColdStartRecorder(slo config.ColdStartSLOConfig) *coldStartRecorder {
+	return &coldStartRecorder{
+		slo:    slo,
+		warmed: make(map[string]bool),
+	}
+}
+
+func (r *coldStartRecorder) record(sample ColdStartSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if sample.At.IsZero() {
+		sample.At = time.Now()
+	}
+	r.samples = append(r.samples, sample)
+	
+	cutoff := time.Now().Add(-coldStartRetention)
+	drop := 0
+	for drop < len(r.samples) && r.samples[drop].At.Before(cutoff) {
+		drop++
+	}
+	if excess := len(r.samples) - drop - coldStartMaxSamples; excess > 0 {
+		drop += excess
+	}
+	if drop > 0 {
+		r.samples = append([]ColdStartSample(nil), r.samples[drop:]...)
+	}
+}
+
+// firstCall reports whether this is the first provider call for agentID.
+func (r *coldStartRecorder) firstCall(agentID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if r.warmed[agentID] {
+		return false
+	}
+	r.warmed[agentID] = true
+	return true
+}
+
+func (r *coldStartRecorder) forget(agentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.warmed, agentID)
+}
+
+func (r *coldStartRecorder) threshold(kind ColdStartKind) time.Duration {
+	if threshold, ok := r.slo.Thresholds[string(kind)]; ok && threshold > 0 {
+		return threshold
+	}
+	if r.slo.Threshold > 0 {
+		return r.slo.Threshold
+	}
+	return defaultColdStartThreshold
+}
+
+func (r *coldStartRecorder) objective() float64 {
+	if r.slo.Objective > 0 && r.slo.Objective < 1 {
+		return r.slo.Objective
+	}
+	return defaultColdStartObjective
+}
+
+func (r *coldStartRecorder) report(filter ColdStartFilter) []ColdStartReport {
+	r.mu.Lock()
+	byKind := make(map[ColdStartKind][]ColdStartSample)
+	for _, sample := range r.samples {
+		if filter.Cluster != "" && sample.Cluster != filter.Cluster {
+			continue
+		}
+		if filter.Agent != "" && sample.Agent != filter.Agent {
+			continue
+		}
+		byKind[sample.Kind] = append(byKind[sample.Kind], sample)
+	}
+	r.mu.Unlock()
+	
+	now := time.Now()
+	objective := r.objective()
+	
+	reports := make([]ColdStartReport, 0, len(byKind))
+	for kind, samples := range byKind {
+		threshold := r.threshold(kind)
+		
+		durations := make([]time.Duration, len(samples))
+		for i, sample := range samples {
+			durations[i] = sample.Duration
+		}
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		
+		report := ColdStartReport{
+			Kind:        kind,
+			Count:       len(samples),
+			P50Ms:       milliseconds(percentile(durations, 0.50)),
+			P90Ms:       milliseconds(percentile(durations, 0.90)),
+			P99Ms:       milliseconds(percentile(durations, 0.99)),
+			MaxMs:       milliseconds(durations[len(durations)-1]),
+			ThresholdMs: milliseconds(threshold),
+			Objective:   objective,
+			BurnRates:   make(map[string]ColdStartBurn, len(coldStartWindows)),
+		}
+		
+		for _, window := range coldStartWindows {
+			var burn ColdStartBurn
+			for _, sample := range samples {
+				if now.Sub(sample.At) > window.duration {
+					continue
+				}
+				burn.Total++
+				if sample.Duration > threshold {
+					burn.Slow++
+				}
+			}
+			if burn.Total > 0 {
+				burn.BurnRate = (float64(burn.Slow) / float64(burn.Total)) / (1 - objective)
+			}
+			report.BurnRates[window.name] = burn
+		}
+		
+		reports = append(reports, report)
+	}
+	
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Kind < reports[j].Kind })
+	return reports
+}
+
+// percentile returns the nearest-rank percentile of sorted durations.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	index := int(float64(len(sorted))*p+0.5) - 1
+	if index < 0 {
+		index = 0
+	}
+	if index >= len(sorted) {
+		index = len(sorted) - 1
+	}
+	return sorted[index]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+// ColdStarts reports cold-start latencies and SLO burn rates, optionally
+// narrowed to a cluster or agent.
+func (e *Engine) ColdStarts(filter ColdStartFilter) []ColdStartReport {
+	return e.coldStarts.report(filter)
+}
+
+// recordFirstResponse records provider warmup for an agent's first call and
+// wake latency when the request woke an idle agent.
+func (e *Engine) recordFirstResponse(targetAgent *agent.Agent, clusterName, agentName string, waking bool, latency time.Duration) {
+	if e.coldStarts.firstCall(targetAgent.ID) {
+		e.coldStarts.record(ColdStartSample{
+			Kind:     ColdStartProviderWarmup,
+			Cluster:  clusterName,
+			Agent:    agentName,
+			Target:   targetAgent.Config.Provider,
+			Duration: latency,
+		})
+	}
+	if waking {
+		e.coldStarts.record(ColdStartSample{
+			Kind:     ColdStartWake,
+			Cluster:  clusterName,
+			Agent:    agentName,
+			Duration: latency,
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+// configClusters are the clusters the server config declares. The engine
+// deploys them on startup, keeps them deployed, and applies the changes
+// made to them when the config is reloaded.
+type configClusters struct {
+	mu       sync.Mutex
+	clusters []config.AgentCluster
+	// reload serializes reloads, which apply specs outside of mu
+	reload sync.Mutex
+}
+
+func (c *configClusters) list() []config.AgentCluster {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]config.AgentCluster(nil), c.clusters...)
+}
+
+// replace sets the declared clusters and returns those they replace.
+func (c *configClusters) replace(clusters []config.AgentCluster) []config.AgentCluster {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous := c.clusters
+	c.clusters = append([]config.AgentCluster(nil), clusters...)
+	return previous
+}
+
+// deployConfigClusters deploys the clusters of the server config that are
+// not deployed. A cluster deployed already keeps its spec, whether it came
+// from the config or was updated through the API since. In distributed
+// mode the leader deploys them, each on the node running the fewest
+// clusters.
+func (e *Engine) deployConfigClusters() {
+	if !e.leads() {
+		return
+	}
+	for _, spec := range e.declared.list() {
+		if e.clusterDeployed(clusterSpecID(&spec)) {
+			continue
+		}
+		e.deployConfigCluster(&spec)
+	}
+}
+
+func (e *Engine) deployConfigCluster(spec *config.AgentCluster) {
+	copied, err := copyClusterSpec(spec)
+	if err == nil {
+		node, remote := e.PlaceCluster()
+		if remote {
+			err = e.deployCluster(copied, node.ID)
+		} else {
+			err = e.DeployCluster(copied)
+		}
+	}
+	if errors.Is(err, ErrConflict) && e.distribution != nil {
+		// Another node deployed it since the last sync
+		return
+	}
+	if err != nil {
+		e.logger.Warn("Failed to deploy cluster from config",
+			zap.String("cluster", clusterSpecID(spec)),
+			zap.Error(err))
+	}
+}
+
+// ReloadClusters makes the clusters of a reloaded server config the ones
+// the engine keeps deployed. Clusters the config adds are deployed, those
+// whose spec changed in it are applied in place, as ApplyCluster does, and
+// those it no longer lists are deleted. Clusters the config did not change
+// keep their spec, including changes made through the API. Writes to
+// clusters owned by someone else are refused and logged, like any other
+// write that does not name the owner. Clusters other nodes run are left
+// to those nodes, which reload the same config, and clusters the config
+// adds are left to the leader.
+func (e *Engine) ReloadClusters(clusters []config.AgentCluster) {
+	e.declared.reload.Lock()
+	defer e.declared.reload.Unlock()
+	
+	previous := make(map[string]*config.AgentCluster)
+	for _, spec := range e.declared.replace(clusters) {
+		spec := spec
+		previous[clusterSpecID(&spec)] = &spec
+	}
+	
+	for i := range clusters {
+		spec := &clusters[i]
+		name := clusterSpecID(spec)
+		old, listed := previous[name]
+		delete(previous, name)
+		
+		if _, remote := e.ClusterNode(name); remote {
+			continue
+		}
+		if _, err := e.getCluster(name); err != nil {
+			if e.leads() {
+				e.deployConfigCluster(spec)
+			}
+			continue
+		}
+		if listed && sameClusterSpec(old, spec) {
+			continue
+		}
+		
+		copied, err := copyClusterSpec(spec)
+		if err != nil {
+			e.logger.Warn("Failed to apply cluster from config", zap.String("cluster", name), zap.Error(err))
+			continue
+		}
+		result, err := e.ApplyCluster(name, copied, nil, false)
+		if err != nil {
+			e.logger.Warn("Failed to apply cluster from config", zap.String("cluster", name), zap.Error(err))
+			continue
+		}
+		e.logger.Info("Applied cluster from config",
+			zap.String("cluster", name),
+			zap.Bool("changed", result.Changed),
+			zap.String("resource_version", result.ResourceVersion))
+	}
+	
+	for name := range previous {
+		if _, err := e.getCluster(name); err != nil {
+			continue
+		}
+		if err := e.DeleteCluster(name, nil); err != nil {
+			e.logger.Warn("Failed to delete cluster removed from config", zap.String("cluster", name), zap.Error(err))
+			continue
+		}
+		e.logger.Info("Deleted cluster removed from config", zap.String("cluster", name))
+	}
+}
+
+// clusterDeployed reports whether a cluster is deployed, on this node or
+// another.
+func (e *Engine) clusterDeployed(clusterName string) bool {
+	if _, err := e.getCluster(clusterName); err == nil {
+		return true
+	}
+	_, remote := e.ClusterNode(clusterName)
+	return remote
+}
+
+// copyClusterSpec copies a spec for the engine to keep, since it writes to
+// the specs it deploys. A resource version in the config is dropped; the
+// config is not a write made against one.
+func copyClusterSpec(spec *config.AgentCluster) (*config.AgentCluster, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cluster spec: %w", err)
+	}
+	var copied config.AgentCluster
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster spec: %w", err)
+	}
+	copied.Metadata.ResourceVersion = ""
+	return &copied, nil
+}
+
+func sameClusterSpec(a, b *config.AgentCluster) bool {
+	before, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	after, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(before, after)
+}
\ No newline at end of file
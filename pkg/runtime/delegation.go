@@ -0,0 +1,189 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/tools"
+	"go.uber.org/zap"
+)
+
+// maxDelegationDepth bounds how many agents a request may pass through, the
+// agent the client called included.
+const maxDelegationDepth = 4
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// delegationToolName is the name of the tool that asks an agent, kept to
+// the characters providers accept in tool names.
+func delegationToolName(agentName string) string {
+	return "ask_" + invalidToolNameChars.ReplaceAllString(agentName, "_")
+}
+
+type delegationKey struct{}
+
+// delegation is the request a delegation tool is called for.
+type delegation struct {
+	requestID string
+	agents    []string
+}
+
+func withDelegation(ctx context.Context, req *agent.Request, agentName string) context.Context {
+	agents := make([]string, 0, len(req.Delegation)+1)
+	agents = append(agents, req.Delegation...)
+	agents = append(agents, agentName)
+	return context.WithValue(ctx, delegationKey{}, &delegation{requestID: req.ID, agents: agents})
+}
+
+// delegateTool asks another agent of the cluster, one the agent depends on,
+// and returns its answer, so a supervisor agent can hand work to workers.
+type delegateTool struct {
+	engine  *Engine
+	cluster string
+	target  string
+}
+
+func (t *delegateTool) Name() string {
+	return delegationToolName(t.target)
+}
+
+func (t *delegateTool) Type() string {
+	return "agent"
+}
+
+func (t *delegateTool) Close() error {
+	return nil
+}
+
+func (t *delegateTool) Definition() tools.Definition {
+	return tools.Definition{
+		Name:        t.Name(),
+		Description: fmt.Sprintf("Ask the %s agent to handle a task and return its answer. The agent does not see this conversation, so include everything it needs in the message.", t.target),
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "The task or question for the agent",
+				},
+			},
+			"required": []interface{}{"message"},
+		},
+	}
+}
+
+func (t *delegateTool) Execute(ctx context.Context, args map[string]interface{}) (*tools.Result, error) {
+	message, _ := args["message"].(string)
+	if message == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+	
+	parent, _ := ctx.Value(delegationKey{}).(*delegation)
+	if parent == nil {
+		parent = &delegation{requestID: fmt.Sprintf("req-%d", time.Now().UnixNano())}
+	}
+	for _, name := range parent.agents {
+		if name == t.target {
+			return &tools.Result{Error: fmt.Sprintf("agent %s is already working on this request", t.target)}, nil
+		}
+	}
+	if len(parent.agents) >= maxDelegationDepth {
+		return &tools.Result{Error: fmt.Sprintf("delegation is limited to %d agents per request", maxDelegationDepth)}, nil
+	}
+	
+	req := &agent.Request{
+		ID:         fmt.Sprintf("%s-%s-%d", parent.requestID, t.target, time.Now().UnixNano()),
+		Messages:   []agent.Message{{Role: "user", Content: message}},
+		Delegation: parent.agents,
+	}
+	// The worker gets what is left of the caller's time
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Timeout = time.Until(deadline)
+		if req.Timeout <= 0 {
+			return nil, ctx.Err()
+		}
+	}
+	
+	resp, err := t.engine.ProcessRequestContext(ctx, t.cluster, t.target, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return &tools.Result{Error: resp.Error}, nil
+	}
+	
+	result := &tools.Result{
+		Data:     resp.Content,
+		Metadata: map[string]interface{}{"agent": t.target, "request_id": req.ID},
+	}
+	if resp.Output != nil {
+		result.Data = resp.Output
+	}
+	return result, nil
+}
+
+// delegationTools creates an ask_<agent> tool for each agent the agent
+// depends on. Agents are looked up when the tool is called, so they may
+// start after the agent that depends on them.
+func (e *Engine) delegationTools(clusterName string, agentConfig *config.Agent, taken map[string]bool) []*builtTool {
+	var built []*builtTool
+	for _, dep := range agentConfig.DependsOn {
+		if dep == agentConfig.Name {
+			continue
+		}
+		b := e.delegationTool(clusterName, dep)
+		if taken[b.name] {
+			e.logger.Warn("Skipping delegation tool, a tool of the same name is configured",
+				zap.String("cluster", clusterName),
+				zap.String("agent", agentConfig.Name),
+				zap.String("tool", b.name))
+			continue
+		}
+		taken[b.name] = true
+		built = append(built, b)
+	}
+	return built
+}
+
+// delegationTool creates the ask_<agent> tool for target.
+func (e *Engine) delegationTool(clusterName, target string) *builtTool {
+	tool := &delegateTool{engine: e, cluster: clusterName, target: target}
+	definition := tool.Definition()
+	return &builtTool{
+		name:    tool.Name(),
+		pending: []pendingTool{{tool: tool}},
+		definitions: []agent.ToolDefinition{{
+			Name:        definition.Name,
+			Description: definition.Description,
+			Parameters:  definition.Parameters,
+		}},
+	}
+}
+
+// retargetDelegation replaces the tool a running agent delegates to an
+// agent renamed from oldName with one for newName. Tools the agent has
+// configured under either name are left alone, as when it was deployed.
+func (e *Engine) retargetDelegation(clusterName string, a *agent.Agent, oldName, newName string) error {
+	oldTool := delegationToolName(oldName)
+	if tool, ok := e.toolManager.GetTool(tools.Key{Scope: a.ID, Name: oldTool}); ok {
+		if _, delegating := tool.(*delegateTool); delegating {
+			if err := e.swapAgentTool(a, oldTool, nil); err != nil {
+				return err
+			}
+		}
+	}
+	
+	b := e.delegationTool(clusterName, newName)
+	if _, taken := e.toolManager.GetTool(tools.Key{Scope: a.ID, Name: b.name}); taken {
+		e.logger.Warn("Skipping delegation tool, a tool of the same name is configured",
+			zap.String("cluster", clusterName),
+			zap.String("agent", a.GetName()),
+			zap.String("tool", b.name))
+		return nil
+	}
+	return e.swapAgentTool(a, b.name, b)
+}
\ No newline at end of file
@@ -0,0 +1,235 @@
+package runtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/config"
+)
+
+// redactedValue stands in for tool credentials in a diff.
+const redactedValue = "[redacted]"
+
+// ClusterDiff describes what deploying a candidate spec over a running
+// cluster would change.
+type ClusterDiff struct {
+	Cluster         string        `json:"cluster"`
+	Changed         bool          `json:"changed"`
+	Fields          []FieldChange `json:"fields"`
+	AgentsAdded     []string      `json:"agents_added"`
+	AgentsRemoved   []string      `json:"agents_removed"`
+	AgentsChanged   []AgentDiff   `json:"agents_changed"`
+	PolicyViolation string        `json:"policy_violation,omitempty"`
+}
+
+// AgentDiff lists the changed fields of an agent present in both specs.
+type AgentDiff struct {
+	Name   string        `json:"name"`
+	Fields []FieldChange `json:"fields"`
+}
+
+// FieldChange is one changed field, named by its dotted JSON path. Tools
+// and smoke tests are keyed by name, e.g. tools.search.timeout; Old is
+// omitted for added fields and New for removed ones.
+type FieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old,omitempty"`
+	New   interface{} `json:"new,omitempty"`
+}
+
+// DiffCluster compares a candidate spec with the one a cluster is running.
+// Nothing is deployed; a candidate the policy would refuse is reported in
+// PolicyViolation rather than as an error.
+func (e *Engine) DiffCluster(clusterName string, candidate *config.AgentCluster) (*ClusterDiff, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	if err := checkCandidate(clusterName, candidate); err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	current, err := specMap(cluster.Config)
+	cluster.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	proposed, err := specMap(candidate)
+	if err != nil {
+		return nil, err
+	}
+	
+	diff := &ClusterDiff{
+		Cluster:       clusterName,
+		Fields:        []FieldChange{},
+		AgentsAdded:   []string{},
+		AgentsRemoved: []string{},
+		AgentsChanged: []AgentDiff{},
+	}
+	
+	currentAgents := agentMaps(current)
+	proposedAgents := agentMaps(proposed)
+	diffValues("", current, proposed, &diff.Fields)
+	
+	for _, name := range sortedKeys(currentAgents, proposedAgents) {
+		before, inCurrent := currentAgents[name]
+		after, inProposed := proposedAgents[name]
+		switch {
+		case !inCurrent:
+			diff.AgentsAdded = append(diff.AgentsAdded, name)
+		case !inProposed:
+			diff.AgentsRemoved = append(diff.AgentsRemoved, name)
+		default:
+			var fields []FieldChange
+			diffValues("", before, after, &fields)
+			if len(fields) > 0 {
+				diff.AgentsChanged = append(diff.AgentsChanged, AgentDiff{Name: name, Fields: fields})
+			}
+		}
+	}
+	
+	diff.Changed = len(diff.Fields)+len(diff.AgentsAdded)+len(diff.AgentsRemoved)+len(diff.AgentsChanged) > 0
+	
+	if err := e.config.Policy.CheckCluster(candidate); err != nil {
+		diff.PolicyViolation = err.Error()
+	}
+	
+	return diff, nil
+}
+
+// specMap converts a spec to its JSON form so that fields are compared and
+// named the way clients write them.
+func specMap(spec *config.AgentCluster) (map[string]interface{}, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode cluster spec: %w", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("failed to decode cluster spec: %w", err)
+	}
+	// The resource version is bookkeeping, not part of the spec
+	if metadata, ok := fields["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "resourceVersion")
+	}
+	return fields, nil
+}
+
+// agentMaps removes the agents from a spec map and returns them by name.
+func agentMaps(spec map[string]interface{}) map[string]interface{} {
+	agents := make(map[string]interface{})
+	clusterSpec, _ := spec["spec"].(map[string]interface{})
+	if clusterSpec == nil {
+		return agents
+	}
+	list, _ := clusterSpec["agents"].([]interface{})
+	for _, item := range list {
+		if fields, ok := item.(map[string]interface{}); ok {
+			name, _ := fields["name"].(string)
+			agents[name] = fields
+		}
+	}
+	delete(clusterSpec, "agents")
+	return agents
+}
+
+func diffValues(field string, before, after interface{}, changes *[]FieldChange) {
+	beforeMap, beforeIsMap := asKeyedMap(before)
+	afterMap, afterIsMap := asKeyedMap(after)
+	if beforeIsMap && afterIsMap {
+		for _, key := range sortedKeys(beforeMap, afterMap) {
+			diffValues(joinField(field, key), beforeMap[key], afterMap[key], changes)
+		}
+		return
+	}
+	
+	if reflect.DeepEqual(before, after) {
+		return
+	}
+	*changes = append(*changes, FieldChange{
+		Field: field,
+		Old:   redactField(field, before),
+		New:   redactField(field, after),
+	})
+}
+
+// asKeyedMap returns objects as they are and lists of named objects, such
+// as tools, keyed by name so that reordering them is not a change.
+func asKeyedMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case []interface{}:
+		keyed := make(map[string]interface{}, len(v))
+		for _, item := range v {
+			fields, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+			name, ok := fields["name"].(string)
+			if !ok || name == "" || keyed[name] != nil {
+				return nil, false
+			}
+			keyed[name] = fields
+		}
+		return keyed, len(keyed) > 0
+	default:
+		return nil, false
+	}
+}
+
+// redactField hides tool credentials, including those inside an object or
+// list that was added or removed as a whole.
+func redactField(field string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = redactField(joinField(field, key), item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactField(field, item)
+		}
+		return redacted
+	}
+	
+	elements := strings.Split(field, ".")
+	for i, element := range elements[:len(elements)-1] {
+		if element == "auth" && elements[i+1] != "type" {
+			return redactedValue
+		}
+	}
+	return value
+}
+
+func joinField(field, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+func sortedKeys(maps ...map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
\ No newline at end of file
@@ -0,0 +1,759 @@
+package runtime
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultHeartbeatInterval = 5 * time.Second
+	defaultNodeTimeout       = 15 * time.Second
+)
+
+// Node is a server of a distributed deployment. Clusters counts the
+// clusters it runs.
+type Node struct {
+	ID        string    `json:"id"`
+	Address   string    `json:"address"`
+	StartedAt time.Time `json:"started_at"`
+	LastSeen  time.Time `json:"last_seen"`
+	Clusters  int       `json:"clusters"`
+	Self      bool      `json:"self,omitempty"`
+	Leader    bool      `json:"leader,omitempty"`
+}
+
+// RemoteCluster is a cluster another node runs, as that node last saved
+// it.
+type RemoteCluster struct {
+	Name            string
+	Node            string
+	Status          ClusterStatus
+	Message         string
+	ResourceVersion string
+	Agents          []RemoteAgent
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	// specAgents counts the agents of the cluster's spec
+	specAgents int
+}
+
+// RemoteAgent is an agent of a RemoteCluster.
+type RemoteAgent struct {
+	ID       string
+	Name     string
+	Status   agent.Status
+	Provider string
+	Model    string
+}
+
+// clusterOwner is the node that claimed a cluster, and when.
+type clusterOwner struct {
+	node      string
+	claimedAt time.Time
+}
+
+// distribution is what a node knows of the others as of its last sync:
+// the live nodes, the leader, and the clusters they run.
+type distribution struct {
+	self              Node
+	heartbeatInterval time.Duration
+	nodeTimeout       time.Duration
+	
+	mu     sync.RWMutex
+	nodes  []Node
+	leader string
+	remote map[string]*RemoteCluster
+	// agents maps the IDs of remote agents to their clusters
+	agents map[string]string
+}
+
+func (e *Engine) initializeDistribution() error {
+	cfg := e.config.Distributed
+	if !cfg.Enabled {
+		return nil
+	}
+	if e.state == nil {
+		return fmt.Errorf("distributed mode requires a shared state store")
+	}
+	
+	nodeID := cfg.NodeID
+	if nodeID == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("failed to name node: %w", err)
+		}
+		nodeID = hostname
+	}
+	d := &distribution{
+		self: Node{
+			ID:        nodeID,
+			Address:   strings.TrimSuffix(cfg.Address, "/"),
+			StartedAt: time.Now(),
+			Self:      true,
+		},
+		heartbeatInterval: cfg.HeartbeatInterval,
+		nodeTimeout:       cfg.NodeTimeout,
+		remote:            make(map[string]*RemoteCluster),
+		agents:            make(map[string]string),
+	}
+	if d.heartbeatInterval == 0 {
+		d.heartbeatInterval = defaultHeartbeatInterval
+	}
+	if d.nodeTimeout == 0 {
+		d.nodeTimeout = defaultNodeTimeout
+	}
+	e.distribution = d
+	
+	e.logger.Info("Joining distributed deployment",
+		zap.String("node", nodeID),
+		zap.String("address", d.self.Address))
+	return nil
+}
+
+// runDistribution syncs with the other nodes every heartbeat until the
+// engine shuts down.
+func (e *Engine) runDistribution() {
+	ticker := time.NewTicker(e.distribution.heartbeatInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-e.done:
+			return
+		case now := <-ticker.C:
+			e.syncNodes(now)
+		}
+	}
+}
+
+// syncNodes heartbeats and renews or takes the leader lease, then starts
+// the clusters this node owns and stops those another node has claimed
+// since. The leader also schedules the clusters of nodes that are down,
+// and those without a node, onto the live node running the fewest.
+func (e *Engine) syncNodes(now time.Time) {
+	d := e.distribution
+	self := d.self.ID
+	
+	// Clusters listed before the owners are read were claimed before them
+	local := make(map[string]*Cluster)
+	for _, cluster := range e.ListClusters() {
+		local[cluster.Name] = cluster
+	}
+	
+	if err := e.state.heartbeat(&d.self, now); err != nil {
+		e.logger.Warn("Failed to heartbeat", zap.Error(err))
+		return
+	}
+	leader, err := e.state.lead(self, now, d.nodeTimeout)
+	if err != nil {
+		e.logger.Warn("Failed to elect leader", zap.Error(err))
+		return
+	}
+	e.setLeader(leader)
+	leads := leader == self
+	
+	nodes, err := e.state.nodes()
+	if err != nil {
+		e.logger.Warn("Failed to list nodes", zap.Error(err))
+		return
+	}
+	states, deleted, err := e.state.load()
+	if err != nil {
+		e.logger.Warn("Failed to load clusters", zap.Error(err))
+		return
+	}
+	owners, err := e.state.owners()
+	if err != nil {
+		e.logger.Warn("Failed to load cluster owners", zap.Error(err))
+		return
+	}
+	
+	live := make(map[string]*Node)
+	var dead []Node
+	for i := range nodes {
+		node := &nodes[i]
+		if node.ID == self {
+			node.Self = true
+			node.LastSeen = now
+		} else if now.Sub(node.LastSeen) > d.nodeTimeout {
+			dead = append(dead, *node)
+			continue
+		}
+		live[node.ID] = node
+	}
+	if live[self] == nil {
+		node := d.self
+		node.LastSeen = now
+		live[self] = &node
+	}
+	for _, owner := range owners {
+		if node, ok := live[owner.node]; ok {
+			node.Clusters++
+		}
+	}
+	
+	saved := make(map[string]bool, len(states))
+	remote := make(map[string]*RemoteCluster)
+	agents := make(map[string]string)
+	var adopted []*clusterState
+	for _, state := range states {
+		name := clusterSpecID(state.Config)
+		saved[name] = true
+		e.observeResourceVersion(state.Config.Metadata.ResourceVersion)
+		
+		owner, claimed := owners[name]
+		if !claimed || live[owner.node] == nil {
+			if !leads {
+				continue
+			}
+			owner, claimed = e.rescheduleCluster(name, owner.node, live, now)
+			if !claimed {
+				continue
+			}
+		}
+		
+		if owner.node == self {
+			if local[name] == nil {
+				adopted = append(adopted, state)
+			}
+			continue
+		}
+		remote[name] = newRemoteCluster(state, owner.node)
+		for _, a := range remote[name].Agents {
+			agents[a.ID] = name
+		}
+	}
+	e.observeResourceVersion(strconv.FormatUint(deleted, 10))
+	
+	for name, cluster := range local {
+		if owner, claimed := owners[name]; claimed && owner.node == self {
+			continue
+		}
+		e.dropCluster(cluster)
+	}
+	
+	if leads {
+		e.cleanUpNodes(now, owners, saved, local, dead)
+	}
+	
+	e.adoptClusters(adopted)
+	
+	sorted := make([]Node, 0, len(live))
+	for _, node := range live {
+		sorted = append(sorted, *node)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+	
+	d.mu.Lock()
+	d.nodes = sorted
+	d.remote = remote
+	d.agents = agents
+	d.mu.Unlock()
+}
+
+// rescheduleCluster claims a cluster whose node is down, or that has none,
+// for the live node running the fewest clusters. previous is the node it
+// had, if any.
+func (e *Engine) rescheduleCluster(name, previous string, live map[string]*Node, now time.Time) (clusterOwner, bool) {
+	target := leastLoaded(live)
+	ok, err := e.state.claim(name, target.ID, previous, now)
+	if err != nil {
+		e.logger.Warn("Failed to schedule cluster", zap.String("cluster", name), zap.Error(err))
+		return clusterOwner{}, false
+	}
+	if !ok {
+		// Its node changed since the owners were read; the next sync sees
+		// the new one
+		return clusterOwner{}, false
+	}
+	target.Clusters++
+	e.logger.Info("Scheduled cluster",
+		zap.String("cluster", name),
+		zap.String("node", target.ID),
+		zap.String("previous_node", previous))
+	return clusterOwner{node: target.ID, claimedAt: now}, true
+}
+
+// cleanUpNodes removes, as the leader, the claims left behind by writes
+// that never saved their cluster, and the nodes that are down and no
+// longer own clusters.
+func (e *Engine) cleanUpNodes(now time.Time, owners map[string]clusterOwner, saved map[string]bool, local map[string]*Cluster, dead []Node) {
+	timeout := e.distribution.nodeTimeout
+	for name, owner := range owners {
+		if !saved[name] && now.Sub(owner.claimedAt) > timeout && local[name] == nil {
+			if err := e.state.release(name, owner.node); err != nil {
+				e.logger.Warn("Failed to release cluster", zap.String("cluster", name), zap.Error(err))
+			}
+		}
+	}
+	
+	owning := make(map[string]bool)
+	for _, owner := range owners {
+		owning[owner.node] = true
+	}
+	for _, node := range dead {
+		if owning[node.ID] {
+			continue
+		}
+		if err := e.state.forget(node.ID, now.Add(-timeout)); err != nil {
+			e.logger.Warn("Failed to remove node", zap.String("node", node.ID), zap.Error(err))
+		}
+	}
+}
+
+// leastLoaded returns the live node running the fewest clusters, the
+// first by ID among equals.
+func leastLoaded(live map[string]*Node) *Node {
+	var least *Node
+	for _, node := range live {
+		if least == nil || node.Clusters < least.Clusters || (node.Clusters == least.Clusters && node.ID < least.ID) {
+			least = node
+		}
+	}
+	return least
+}
+
+func newRemoteCluster(state *clusterState, node string) *RemoteCluster {
+	remote := &RemoteCluster{
+		Name:            clusterSpecID(state.Config),
+		Node:            node,
+		Status:          state.Status,
+		Message:         state.Message,
+		ResourceVersion: state.Config.Metadata.ResourceVersion,
+		Agents:          make([]RemoteAgent, 0, len(state.Agents)),
+		CreatedAt:       state.CreatedAt,
+		UpdatedAt:       state.UpdatedAt,
+		specAgents:      len(state.Config.Spec.Agents),
+	}
+	for _, saved := range state.Agents {
+		a := RemoteAgent{ID: saved.ID, Name: saved.Name, Status: saved.Status}
+		for _, spec := range state.Config.Spec.Agents {
+			if spec.Name == saved.Name {
+				a.Provider = spec.Provider
+				a.Model = spec.Model
+				break
+			}
+		}
+		remote.Agents = append(remote.Agents, a)
+	}
+	return remote
+}
+
+// observeResourceVersion makes the versions handed out from now on newer
+// than one saved by any node.
+func (e *Engine) observeResourceVersion(resourceVersion string) {
+	version, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return
+	}
+	e.raiseResourceVersion(version)
+}
+
+// adoptClusters restores the clusters this node has claimed, and starts
+// those that were not stopped.
+func (e *Engine) adoptClusters(states []*clusterState) {
+	if len(states) == 0 {
+		return
+	}
+	e.mu.Lock()
+	var started []*Cluster
+	for _, state := range states {
+		if cluster := e.restoreCluster(state); cluster != nil {
+			started = append(started, cluster)
+		}
+	}
+	e.mu.Unlock()
+	
+	for _, cluster := range started {
+		go e.startCluster(cluster)
+	}
+}
+
+// dropCluster stops a cluster another node has claimed, and forgets it.
+// Its saved state is left to that node.
+func (e *Engine) dropCluster(cluster *Cluster) {
+	e.mu.Lock()
+	if e.clusters[cluster.Name] != cluster {
+		e.mu.Unlock()
+		return
+	}
+	delete(e.clusters, cluster.Name)
+	e.mu.Unlock()
+	
+	cluster.mu.Lock()
+	cluster.dropped = true
+	for _, a := range cluster.Agents {
+		if err := e.agentManager.StopAgent(a.ID); err != nil {
+			e.logger.Warn("Failed to stop agent",
+				zap.String("agent", a.GetName()),
+				zap.Error(err))
+		}
+		e.removeAgent(a)
+	}
+	e.stopSchedules(cluster.Name)
+	e.abortRollouts(cluster.Name)
+	cluster.Status = ClusterStatusStopped
+	cluster.mu.Unlock()
+	e.versions.forget(cluster.Name)
+	e.instances.forget(cluster.Name)
+	
+	e.logger.Info("Cluster moved to another node", zap.String("name", cluster.Name))
+}
+
+// claimCluster claims a new cluster for node, or this node if empty. It
+// fails with ErrConflict if another node has a cluster of that name.
+func (e *Engine) claimCluster(name, node string) error {
+	if e.distribution == nil {
+		return nil
+	}
+	if node == "" {
+		node = e.distribution.self.ID
+	}
+	ok, err := e.state.claim(name, node, "", time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%w: cluster %s already exists", ErrConflict, name)
+	}
+	return nil
+}
+
+// releaseCluster gives up this node's claim on a deleted cluster.
+func (e *Engine) releaseCluster(name string) {
+	if e.distribution == nil {
+		return
+	}
+	if err := e.state.release(name, e.distribution.self.ID); err != nil {
+		e.logger.Warn("Failed to release cluster", zap.String("cluster", name), zap.Error(err))
+	}
+}
+
+// leaveDistribution removes this node, its claims and its leader lease, so
+// the other nodes take its clusters over, and its place as the leader, at
+// once rather than after the node timeout.
+func (e *Engine) leaveDistribution() {
+	if e.distribution == nil {
+		return
+	}
+	if err := e.state.leave(e.distribution.self.ID); err != nil {
+		e.logger.Warn("Failed to leave distributed deployment", zap.Error(err))
+	}
+}
+
+// Distributed reports whether the engine is a node of a distributed
+// deployment.
+func (e *Engine) Distributed() bool {
+	return e.distribution != nil
+}
+
+// Node returns this node.
+func (e *Engine) Node() Node {
+	if e.distribution == nil {
+		return Node{}
+	}
+	return e.distribution.self
+}
+
+// Nodes returns the live nodes as of the last sync, by ID.
+func (e *Engine) Nodes() []Node {
+	d := e.distribution
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	nodes := append([]Node(nil), d.nodes...)
+	for i := range nodes {
+		if nodes[i].Self {
+			nodes[i].Clusters = len(e.ListClusters())
+		}
+		nodes[i].Leader = nodes[i].ID == d.leader
+	}
+	return nodes
+}
+
+// Leader returns the ID of the node that holds the leader lease, as of the
+// last sync.
+func (e *Engine) Leader() string {
+	d := e.distribution
+	if d == nil {
+		return ""
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.leader
+}
+
+// leads reports whether this node does the work one node does for the
+// whole deployment: always, unless it is distributed and not the leader.
+func (e *Engine) leads() bool {
+	return e.distribution == nil || e.Leader() == e.distribution.self.ID
+}
+
+func (e *Engine) setLeader(leader string) {
+	d := e.distribution
+	d.mu.Lock()
+	previous := d.leader
+	d.leader = leader
+	d.mu.Unlock()
+	
+	if leader != previous && (leader == d.self.ID || previous == d.self.ID) {
+		e.logger.Info("Leader changed",
+			zap.String("leader", leader),
+			zap.String("previous", previous))
+	}
+}
+
+// RemoteClusters returns the clusters other nodes run.
+func (e *Engine) RemoteClusters() []*RemoteCluster {
+	d := e.distribution
+	if d == nil {
+		return nil
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	clusters := make([]*RemoteCluster, 0, len(d.remote))
+	for _, cluster := range d.remote {
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
+
+// ClusterNode returns the node that runs a cluster, if another node does.
+func (e *Engine) ClusterNode(clusterName string) (Node, bool) {
+	d := e.distribution
+	if d == nil {
+		return Node{}, false
+	}
+	if _, err := e.getCluster(clusterName); err == nil {
+		return Node{}, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	cluster, ok := d.remote[clusterName]
+	if !ok {
+		return Node{}, false
+	}
+	return d.node(cluster.Node)
+}
+
+// AgentNode returns the node that runs an agent, if another node does.
+func (e *Engine) AgentNode(agentID string) (Node, bool) {
+	d := e.distribution
+	if d == nil {
+		return Node{}, false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	clusterName, ok := d.agents[agentID]
+	if !ok {
+		return Node{}, false
+	}
+	return d.node(d.remote[clusterName].Node)
+}
+
+// PlaceCluster returns the node a new cluster should run on, if not this
+// one: the live node running the fewest clusters, or this node among
+// equals.
+func (e *Engine) PlaceCluster() (Node, bool) {
+	nodes := e.Nodes()
+	var least *Node
+	for i := range nodes {
+		node := &nodes[i]
+		if least == nil || node.Clusters < least.Clusters || (node.Clusters == least.Clusters && node.Self) {
+			least = node
+		}
+	}
+	if least == nil || least.Self {
+		return Node{}, false
+	}
+	return *least, true
+}
+
+// Peers returns the other live nodes.
+func (e *Engine) Peers() []Node {
+	var peers []Node
+	for _, node := range e.Nodes() {
+		if !node.Self {
+			peers = append(peers, node)
+		}
+	}
+	return peers
+}
+
+// node returns a live node other than this one. d.mu must be held.
+func (d *distribution) node(id string) (Node, bool) {
+	for _, node := range d.nodes {
+		if node.ID == id && !node.Self {
+			return node, true
+		}
+	}
+	return Node{}, false
+}
+
+// namespaceAgents counts the agents in the specs of the clusters other
+// nodes run in a namespace, but for except.
+func (d *distribution) namespaceAgents(namespace, except string) int {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	
+	agents := 0
+	for name, cluster := range d.remote {
+		if clusterNamespace, _ := SplitClusterID(name); clusterNamespace != namespace || name == except {
+			continue
+		}
+		agents += cluster.specAgents
+	}
+	return agents
+}
+
+// heartbeat records that a node is up.
+func (s *stateStore) heartbeat(node *Node, now time.Time) error {
+	query := s.bind(`INSERT INTO goagents_nodes (id, address, started_at, last_seen) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET address = excluded.address, started_at = excluded.started_at, last_seen = excluded.last_seen`)
+	if _, err := s.db.Exec(query, node.ID, node.Address, node.StartedAt.UnixNano(), now.UnixNano()); err != nil {
+		return fmt.Errorf("failed to save node: %w", err)
+	}
+	return nil
+}
+
+func (s *stateStore) nodes() ([]Node, error) {
+	rows, err := s.db.Query(`SELECT id, address, started_at, last_seen FROM goagents_nodes`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load nodes: %w", err)
+	}
+	defer rows.Close()
+	
+	var nodes []Node
+	for rows.Next() {
+		var node Node
+		var startedAt, lastSeen int64
+		if err := rows.Scan(&node.ID, &node.Address, &startedAt, &lastSeen); err != nil {
+			return nil, fmt.Errorf("failed to load nodes: %w", err)
+		}
+		node.StartedAt = time.Unix(0, startedAt)
+		node.LastSeen = time.Unix(0, lastSeen)
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+func (s *stateStore) owners() (map[string]clusterOwner, error) {
+	rows, err := s.db.Query(`SELECT name, node, claimed_at FROM goagents_cluster_owners`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cluster owners: %w", err)
+	}
+	defer rows.Close()
+	
+	owners := make(map[string]clusterOwner)
+	for rows.Next() {
+		var name, node string
+		var claimedAt int64
+		if err := rows.Scan(&name, &node, &claimedAt); err != nil {
+			return nil, fmt.Errorf("failed to load cluster owners: %w", err)
+		}
+		owners[name] = clusterOwner{node: node, claimedAt: time.Unix(0, claimedAt)}
+	}
+	return owners, rows.Err()
+}
+
+// claim makes node the owner of a cluster, if previous still is, or if
+// the cluster has none when previous is empty. It reports whether it did.
+func (s *stateStore) claim(name, node, previous string, now time.Time) (bool, error) {
+	query := `INSERT INTO goagents_cluster_owners (name, node, claimed_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO NOTHING`
+	args := []interface{}{name, node, now.UnixNano()}
+	if previous != "" {
+		query = `UPDATE goagents_cluster_owners SET node = ?, claimed_at = ? WHERE name = ? AND node = ?`
+		args = []interface{}{node, now.UnixNano(), name, previous}
+	}
+	result, err := s.db.Exec(s.bind(query), args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim cluster: %w", err)
+	}
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim cluster: %w", err)
+	}
+	return claimed == 1, nil
+}
+
+// release drops node's claim on a cluster.
+func (s *stateStore) release(name, node string) error {
+	query := s.bind(`DELETE FROM goagents_cluster_owners WHERE name = ? AND node = ?`)
+	if _, err := s.db.Exec(query, name, node); err != nil {
+		return fmt.Errorf("failed to release cluster: %w", err)
+	}
+	return nil
+}
+
+// lead takes the leader lease for node, or renews it, unless another node
+// holds it and has renewed it within ttl. It returns the leader.
+func (s *stateStore) lead(node string, now time.Time, ttl time.Duration) (string, error) {
+	query := s.bind(`INSERT INTO goagents_leader (id, node, expires_at) VALUES (1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET node = excluded.node, expires_at = excluded.expires_at
+		WHERE goagents_leader.node = excluded.node OR goagents_leader.expires_at < ?`)
+	if _, err := s.db.Exec(query, node, now.Add(ttl).UnixNano(), now.UnixNano()); err != nil {
+		return "", fmt.Errorf("failed to take leader lease: %w", err)
+	}
+	
+	var leader string
+	if err := s.db.QueryRow(`SELECT node FROM goagents_leader WHERE id = 1`).Scan(&leader); err != nil {
+		return "", fmt.Errorf("failed to load leader: %w", err)
+	}
+	return leader, nil
+}
+
+// forget removes a node that has not been seen since before.
+func (s *stateStore) forget(node string, before time.Time) error {
+	query := s.bind(`DELETE FROM goagents_nodes WHERE id = ? AND last_seen < ?`)
+	if _, err := s.db.Exec(query, node, before.UnixNano()); err != nil {
+		return fmt.Errorf("failed to remove node: %w", err)
+	}
+	return nil
+}
+
+// leave removes a node, its claims and its leader lease.
+func (s *stateStore) leave(node string) error {
+	if _, err := s.db.Exec(s.bind(`DELETE FROM goagents_cluster_owners WHERE node = ?`), node); err != nil {
+		return fmt.Errorf("failed to release clusters: %w", err)
+	}
+	if _, err := s.db.Exec(s.bind(`DELETE FROM goagents_leader WHERE node = ?`), node); err != nil {
+		return fmt.Errorf("failed to give up leader lease: %w", err)
+	}
+	if _, err := s.db.Exec(s.bind(`DELETE FROM goagents_nodes WHERE id = ?`), node); err != nil {
+		return fmt.Errorf("failed to remove node: %w", err)
+	}
+	return nil
+}
+
+// bind numbers a query's placeholders for Postgres.
+func (s *stateStore) bind(query string) string {
+	if !s.postgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String()
+}
\ No newline at end of file
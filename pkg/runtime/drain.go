@@ -0,0 +1,121 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// drainPollInterval is how often Drain checks whether the requests and
+// jobs it waits for have finished.
+const drainPollInterval = 50 * time.Millisecond
+
+// jobShutdownTimeout bounds how long Close waits for the jobs it
+// interrupts to be saved as failed.
+const jobShutdownTimeout = 5 * time.Second
+
+// DrainStatus reports whether the engine is draining, and the work it is
+// still finishing. Jobs counts those queued and running.
+type DrainStatus struct {
+	Draining       bool       `json:"draining"`
+	Since          *time.Time `json:"since,omitempty"`
+	ActiveRequests int        `json:"active_requests"`
+	Jobs           int        `json:"jobs"`
+	Drained        bool       `json:"drained"`
+}
+
+// drainState is set while the engine drains.
+type drainState struct {
+	mu    sync.Mutex
+	since time.Time
+}
+
+// StartDrain stops the engine taking new work: jobs are refused with
+// ErrDraining and schedules skip their runs. Requests and jobs already
+// taken carry on. It reports whether the engine was not draining already.
+func (e *Engine) StartDrain() bool {
+	e.drain.mu.Lock()
+	defer e.drain.mu.Unlock()
+	
+	if !e.drain.since.IsZero() {
+		return false
+	}
+	e.drain.since = time.Now()
+	e.logger.Info("Draining engine")
+	return true
+}
+
+// StopDrain takes new work again.
+func (e *Engine) StopDrain() {
+	e.drain.mu.Lock()
+	defer e.drain.mu.Unlock()
+	
+	if e.drain.since.IsZero() {
+		return
+	}
+	e.drain.since = time.Time{}
+	e.logger.Info("Stopped draining engine")
+}
+
+// Draining reports whether the engine is draining.
+func (e *Engine) Draining() bool {
+	e.drain.mu.Lock()
+	defer e.drain.mu.Unlock()
+	return !e.drain.since.IsZero()
+}
+
+// Drain starts draining, if the engine is not already, and waits until the
+// requests and jobs it runs have finished or ctx is done.
+func (e *Engine) Drain(ctx context.Context) error {
+	e.StartDrain()
+	
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+	
+	for {
+		status := e.DrainStatus()
+		if status.Drained {
+			e.logger.Info("Engine drained")
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %d requests and %d jobs unfinished", ctx.Err(), status.ActiveRequests, status.Jobs)
+		case <-ticker.C:
+		}
+	}
+}
+
+// DrainStatus returns the engine's drain status.
+func (e *Engine) DrainStatus() DrainStatus {
+	e.drain.mu.Lock()
+	since := e.drain.since
+	e.drain.mu.Unlock()
+	
+	status := DrainStatus{
+		Draining:       !since.IsZero(),
+		ActiveRequests: e.inflight.count(),
+		Jobs:           e.jobs.unfinished(),
+	}
+	if status.Draining {
+		status.Since = &since
+		status.Drained = status.ActiveRequests == 0 && status.Jobs == 0
+	}
+	return status
+}
+
+// waitForJobs waits, up to jobShutdownTimeout, for the job workers to save
+// the jobs the shutdown interrupted.
+func (e *Engine) waitForJobs() {
+	finished := make(chan struct{})
+	go func() {
+		e.jobWorkers.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(jobShutdownTimeout):
+		e.logger.Warn("Shut down before interrupted jobs were saved")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,473 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/tools"
+	"go.uber.org/zap"
+)
+
+// retiredToolGrace is how long a replaced tool is kept open, so calls
+// already running on it can finish.
+const retiredToolGrace = 5 * time.Minute
+
+// registeredTools records the names each configured tool of an agent was
+// registered under, keyed by agent ID and configured name, so the tool can
+// be replaced or removed while the agent runs.
+type registeredTools struct {
+	agents map[string]map[string][]string
+	mu     sync.Mutex
+}
+
+func newRegisteredTools() *registeredTools {
+	return &registeredTools{
+		agents: make(map[string]map[string][]string),
+	}
+}
+
+func (r *registeredTools) track(agentID, name string, names []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if r.agents[agentID] == nil {
+		r.agents[agentID] = make(map[string][]string)
+	}
+	r.agents[agentID][name] = names
+}
+
+func (r *registeredTools) names(agentID, name string) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	return r.agents[agentID][name]
+}
+
+func (r *registeredTools) untrack(agentID, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	delete(r.agents[agentID], name)
+}
+
+func (r *registeredTools) forget(agentID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	delete(r.agents, agentID)
+}
+
+// sharedTools are the tools added through the API for every agent.
+type sharedTools struct {
+	tools []config.Tool
+	mu    sync.RWMutex
+}
+
+func (s *sharedTools) list() []config.Tool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	return append([]config.Tool(nil), s.tools...)
+}
+
+// set adds tool or replaces the shared tool of its name, and reports
+// whether it was added.
+func (s *sharedTools) set(tool config.Tool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for i := range s.tools {
+		if s.tools[i].Name == tool.Name {
+			s.tools[i] = tool
+			return false
+		}
+	}
+	s.tools = append(s.tools, tool)
+	return true
+}
+
+func (s *sharedTools) get(name string) (config.Tool, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	for _, tool := range s.tools {
+		if tool.Name == name {
+			return tool, true
+		}
+	}
+	return config.Tool{}, false
+}
+
+func (s *sharedTools) remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for i := range s.tools {
+		if s.tools[i].Name == name {
+			s.tools = append(s.tools[:i], s.tools[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// toolsFor returns an agent's tools: its own, followed by the shared tools
+// it has no tool of the same name for.
+func (e *Engine) toolsFor(agentConfig *config.Agent) []config.Tool {
+	result := append([]config.Tool(nil), agentConfig.Tools...)
+	for _, shared := range e.sharedTools.list() {
+		if findToolSpec(agentConfig.Tools, shared.Name) < 0 {
+			result = append(result, shared)
+		}
+	}
+	return result
+}
+
+// swapAgentTool replaces the tools an agent registered for its configured
+// tool name with b, or removes them when b is nil, and updates the tools
+// the agent advertises to match.
+func (e *Engine) swapAgentTool(a *agent.Agent, name string, b *builtTool) error {
+	e.toolUpdates.Lock()
+	defer e.toolUpdates.Unlock()
+	
+	if _, err := e.agentManager.GetAgent(a.ID); err != nil {
+		if b != nil {
+			b.close()
+		}
+		return err
+	}
+	
+	oldNames := e.registeredTools.names(a.ID, name)
+	var retired []tools.Tool
+	for _, oldName := range oldNames {
+		if tool, ok := e.toolManager.GetTool(tools.Key{Scope: a.ID, Name: oldName}); ok {
+			retired = append(retired, tool)
+		}
+	}
+	e.registeredTools.untrack(a.ID, name)
+	e.toolSecrets.untrack(a.ID, name)
+	
+	// Register the new tools before removing the old ones, so calls made
+	// in between find a tool of the name
+	kept := make(map[string]bool)
+	var definitions []agent.ToolDefinition
+	if b != nil {
+		e.registerTool(a.ID, b)
+		for _, newName := range b.names() {
+			kept[newName] = true
+		}
+		definitions = b.definitions
+	}
+	for _, oldName := range oldNames {
+		if !kept[oldName] {
+			e.toolManager.RemoveTool(tools.Key{Scope: a.ID, Name: oldName})
+		}
+	}
+	
+	removed := make(map[string]bool, len(oldNames))
+	for _, oldName := range oldNames {
+		removed[oldName] = true
+	}
+	advertised := make([]agent.ToolDefinition, 0, len(a.ToolDefinitions())+len(definitions))
+	for _, definition := range a.ToolDefinitions() {
+		if !removed[definition.Name] && !kept[definition.Name] {
+			advertised = append(advertised, definition)
+		}
+	}
+	a.SetToolDefinitions(append(advertised, definitions...))
+	
+	retireTools(retired)
+	return nil
+}
+
+// retireTools closes replaced tools once calls running on them have had
+// the time to finish.
+func retireTools(retired []tools.Tool) {
+	if len(retired) == 0 {
+		return
+	}
+	time.AfterFunc(retiredToolGrace, func() {
+		for _, tool := range retired {
+			tool.Close()
+		}
+	})
+}
+
+// SetAgentTool adds a tool to an agent, or replaces the agent's tool of the
+// same name, without redeploying the cluster. The agent's spec is updated
+// and the running agent advertises the tool from its next request on. It
+// reports whether the tool was added rather than replaced.
+func (e *Engine) SetAgentTool(clusterName, agentName string, tool *config.Tool, pre *Precondition) (bool, error) {
+	if err := validateToolSpec(tool); err != nil {
+		return false, err
+	}
+	
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return false, err
+	}
+	
+	cluster.mu.RLock()
+	if err := checkPrecondition(cluster, pre); err != nil {
+		cluster.mu.RUnlock()
+		return false, err
+	}
+	spec := findAgentSpec(cluster.Config, agentName)
+	if spec == nil {
+		cluster.mu.RUnlock()
+		return false, fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	candidate := copyAgentSpec(spec)
+	running := cluster.Agents[agentName]
+	version := cluster.Config.Metadata.ResourceVersion
+	outbound := e.outboundPolicy(cluster)
+	cluster.mu.RUnlock()
+	
+	created := true
+	if i := findToolSpec(candidate.Tools, tool.Name); i >= 0 {
+		candidate.Tools[i] = *tool
+		created = false
+	} else {
+		candidate.Tools = append(candidate.Tools, *tool)
+	}
+	if err := e.config.Policy.CheckAgent(candidate); err != nil {
+		return false, err
+	}
+	
+	// Build the tool before taking the lock, as connecting to it may take
+	// a while
+	var b *builtTool
+	if running != nil {
+		b, err = e.buildTool(clusterName, candidate, tool, outbound)
+		if err != nil {
+			return false, fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+	}
+	
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	
+	if current := cluster.Config.Metadata.ResourceVersion; current != version {
+		if b != nil {
+			b.close()
+		}
+		return false, fmt.Errorf("%w: cluster %s changed while the tool was created", ErrConflict, clusterName)
+	}
+	if b != nil {
+		if err := e.swapAgentTool(running, tool.Name, b); err != nil {
+			return false, err
+		}
+	}
+	spec = findAgentSpec(cluster.Config, agentName)
+	spec.Tools = candidate.Tools
+	cluster.UpdatedAt = time.Now()
+	e.bumpResourceVersion(cluster)
+	e.saveCluster(cluster)
+	e.recordVersion(clusterName, spec, running)
+	
+	e.logger.Info("Agent tool set",
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName),
+		zap.String("tool", tool.Name),
+		zap.Bool("created", created))
+	
+	return created, nil
+}
+
+// RemoveAgentTool removes a tool from an agent's spec and from the running
+// agent. A shared tool of the same name takes its place.
+func (e *Engine) RemoveAgentTool(clusterName, agentName, toolName string, pre *Precondition) error {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	
+	if err := checkPrecondition(cluster, pre); err != nil {
+		return err
+	}
+	spec := findAgentSpec(cluster.Config, agentName)
+	if spec == nil {
+		return fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	i := findToolSpec(spec.Tools, toolName)
+	if i < 0 {
+		return fmt.Errorf("%w: %s of agent %s", ErrToolNotFound, toolName, agentName)
+	}
+	
+	spec.Tools = append(spec.Tools[:i:i], spec.Tools[i+1:]...)
+	cluster.UpdatedAt = time.Now()
+	e.bumpResourceVersion(cluster)
+	e.saveCluster(cluster)
+	e.recordVersion(clusterName, spec, cluster.Agents[agentName])
+	
+	if running := cluster.Agents[agentName]; running != nil {
+		var b *builtTool
+		if shared, ok := e.sharedTools.get(toolName); ok {
+			b, err = e.buildTool(clusterName, spec, &shared, e.outboundPolicy(cluster))
+			if err != nil {
+				e.logger.Warn("Skipping shared tool",
+					zap.String("cluster", clusterName),
+					zap.String("agent", agentName),
+					zap.String("tool", toolName),
+					zap.Error(err))
+			}
+		}
+		if err := e.swapAgentTool(running, toolName, b); err != nil {
+			return err
+		}
+	}
+	
+	e.logger.Info("Agent tool removed",
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName),
+		zap.String("tool", toolName))
+	
+	return nil
+}
+
+// SharedTools lists the tools added for every agent.
+func (e *Engine) SharedTools() []config.Tool {
+	return e.sharedTools.list()
+}
+
+// SetSharedTool adds a tool to every agent, including agents created later,
+// or replaces the shared tool of the same name. Agents with a tool of that
+// name of their own keep theirs. It reports whether the tool was added.
+func (e *Engine) SetSharedTool(tool *config.Tool) (bool, error) {
+	if err := validateToolSpec(tool); err != nil {
+		return false, err
+	}
+	if err := e.checkToolSpec(tool); err != nil {
+		return false, err
+	}
+	
+	created := e.sharedTools.set(*tool)
+	e.applySharedTool(tool.Name, tool)
+	
+	e.logger.Info("Shared tool set",
+		zap.String("tool", tool.Name),
+		zap.Bool("created", created))
+	return created, nil
+}
+
+// RemoveSharedTool removes a shared tool from every agent that uses it.
+func (e *Engine) RemoveSharedTool(name string) error {
+	if !e.sharedTools.remove(name) {
+		return fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+	e.applySharedTool(name, nil)
+	
+	e.logger.Info("Shared tool removed", zap.String("tool", name))
+	return nil
+}
+
+// applySharedTool builds tool for every running agent without a tool of
+// the name of its own and swaps it in, or removes the shared tool from them
+// when tool is nil. An agent the tool cannot be built for is skipped.
+func (e *Engine) applySharedTool(name string, tool *config.Tool) {
+	type target struct {
+		cluster  string
+		running  *agent.Agent
+		spec     *config.Agent
+		outbound *tools.OutboundPolicy
+	}
+	
+	var targets []target
+	for _, cluster := range e.ListClusters() {
+		cluster.mu.RLock()
+		for agentName, running := range cluster.Agents {
+			spec := findAgentSpec(cluster.Config, agentName)
+			if spec == nil || findToolSpec(spec.Tools, name) >= 0 {
+				continue
+			}
+			targets = append(targets, target{
+				cluster:  cluster.Name,
+				running:  running,
+				spec:     copyAgentSpec(spec),
+				outbound: e.outboundPolicy(cluster),
+			})
+		}
+		cluster.mu.RUnlock()
+	}
+	
+	for _, t := range targets {
+		var b *builtTool
+		if tool != nil {
+			var err error
+			b, err = e.buildTool(t.cluster, t.spec, tool, t.outbound)
+			if err != nil {
+				e.logger.Warn("Skipping shared tool",
+					zap.String("cluster", t.cluster),
+					zap.String("agent", t.spec.Name),
+					zap.String("tool", name),
+					zap.Error(err))
+				continue
+			}
+		}
+		if err := e.swapAgentTool(t.running, name, b); err != nil {
+			e.logger.Warn("Failed to update agent tools",
+				zap.String("cluster", t.cluster),
+				zap.String("agent", t.spec.Name),
+				zap.String("tool", name),
+				zap.Error(err))
+		}
+	}
+}
+
+// validateToolSpec checks the fields every tool needs.
+func validateToolSpec(tool *config.Tool) error {
+	if tool.Name == "" {
+		return fmt.Errorf("tool name is required")
+	}
+	if tool.Type == "" {
+		return fmt.Errorf("tool %s: type is required", tool.Name)
+	}
+	return nil
+}
+
+// checkToolSpec checks the settings of a tool that do not depend on the
+// agent it is created for, so a shared tool is refused up front rather
+// than skipped for every agent.
+func (e *Engine) checkToolSpec(tool *config.Tool) error {
+	if tool.Type == "exec" {
+		if err := e.config.Policy.Exec.CheckTool(tool); err != nil {
+			return fmt.Errorf("tool %s: %w", tool.Name, err)
+		}
+	}
+	if _, err := e.toolGuard(tool); err != nil {
+		return fmt.Errorf("tool %s: %w", tool.Name, err)
+	}
+	if _, err := toolCacheTTL(tool); err != nil {
+		return fmt.Errorf("tool %s: %w", tool.Name, err)
+	}
+	if _, err := tools.ParseRetryPolicy(tool.Config, tool.Timeout); err != nil {
+		return fmt.Errorf("tool %s: %w", tool.Name, err)
+	}
+	if _, err := toolMaxConcurrency(tool); err != nil {
+		return fmt.Errorf("tool %s: %w", tool.Name, err)
+	}
+	if _, err := toolDryRun(tool); err != nil {
+		return fmt.Errorf("tool %s: %w", tool.Name, err)
+	}
+	if tool.Parameters != nil {
+		if _, err := tools.CompileSchema(tool.Parameters); err != nil {
+			return fmt.Errorf("tool %s: invalid parameters: %w", tool.Name, err)
+		}
+	}
+	return nil
+}
+
+func findToolSpec(toolSpecs []config.Tool, name string) int {
+	for i := range toolSpecs {
+		if toolSpecs[i].Name == name {
+			return i
+		}
+	}
+	return -1
+}
\ No newline at end of file
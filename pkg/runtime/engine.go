@@ -2,14 +2,28 @@ package runtime
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/goagents/goagents/pkg/agent"
 	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/events"
+	"github.com/goagents/goagents/pkg/files"
+	"github.com/goagents/goagents/pkg/guardrails"
+	"github.com/goagents/goagents/pkg/knowledge"
 	"github.com/goagents/goagents/pkg/providers"
+	"github.com/goagents/goagents/pkg/sessions"
 	"github.com/goagents/goagents/pkg/tools"
+	"github.com/goagents/goagents/pkg/vault"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
@@ -18,20 +32,81 @@ type Engine struct {
 	agentManager    *agent.Manager
 	providerManager *providers.Manager
 	toolManager     *tools.Manager
+	injectionGuard  *tools.InjectionGuard
+	responseCache   providers.ResponseCache
+	files           files.Store
+	sessions        sessions.Store
+	sessionLocks    sessionLocks
+	vault           *vault.Vault
+	credentialPools map[string]*providers.CredentialPool
+	tenantProviders *tenantProviders
+	feedback        *feedbackStore
+	memories        *memoryStore
+	knowledge       *knowledge.Store
+	toolAudit       *toolAuditLog
+	// transcripts is nil unless transcripts are enabled
+	transcripts     *transcriptLog
+	secrets         *tools.SecretResolver
+	toolSecrets     *toolSecrets
+	registeredTools *registeredTools
+	sharedTools     *sharedTools
+	// toolUpdates serializes changes to the tools of running agents
+	toolUpdates sync.Mutex
+	coldStarts      *coldStartRecorder
+	inflight        *inflightTracker
+	workflowRuns    *workflowRuns
+	scheduler       *scheduler
+	jobs            *jobQueue
+	guardrailLog    *guardrailLog
+	budgetLedger    *budgetLedger
+	quotas          *quotaLedger
+	experiments     *experimentStats
+	versions        *versionStore
+	instances       *instancePools
+	state           *stateStore
+	distribution    *distribution
+	declared        *configClusters
+	health          *healthMonitor
+	features        *featureFlags
+	events          *events.Bus
+	webhooks        *webhookSet
+	deadLetters     *deadLetterLog
+	templates       *templateRegistry
+	drain           drainState
+	jobWorkers      sync.WaitGroup
 	clusters        map[string]*Cluster
+	resourceVersion uint64
 	logger          *zap.Logger
-	metrics         *Metrics
-	mu              sync.RWMutex
+	metrics         *requestMetrics
+	prometheus      *prometheusMetrics
+	tracer          trace.Tracer
+	// tracerProvider exports spans; it is nil when tracing is disabled
+	tracerProvider *sdktrace.TracerProvider
+	// agentQuota is held from an agent quota check until the agents it
+	// allowed are in the cluster's spec, so concurrent writes count each
+	// other's agents
+	agentQuota sync.Mutex
+	// done is closed when the engine shuts down
+	done chan struct{}
+	mu   sync.RWMutex
 }
 
 type Cluster struct {
-	Name      string
-	Config    *config.AgentCluster
-	Agents    map[string]*agent.Agent
-	Status    ClusterStatus
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	mu        sync.RWMutex
+	Name       string
+	Config     *config.AgentCluster
+	Agents     map[string]*agent.Agent
+	Status     ClusterStatus
+	Message    string
+	SmokeTests []SmokeTestResult
+	Conditions []ClusterCondition
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	// started is the spec the cluster last finished starting from; the
+	// reconcile loop leaves the cluster alone until it is the current one
+	started *config.AgentCluster
+	// dropped is set once another node runs the cluster
+	dropped bool
+	mu      sync.RWMutex
 }
 
 type ClusterStatus string
@@ -43,191 +118,1794 @@ const (
 	ClusterStatusFailed  ClusterStatus = "failed"
 )
 
-type Metrics struct {
-	ClustersTotal      int64
-	AgentsTotal        int64
-	RequestsTotal      int64
-	RequestsSucceeded  int64
-	RequestsFailed     int64
-	AverageResponseTime time.Duration
-	mu                 sync.RWMutex
-}
-
 func NewEngine(cfg *config.Config, logger *zap.Logger) (*Engine, error) {
 	engine := &Engine{
 		config:          cfg,
 		agentManager:    agent.NewManager(logger),
 		providerManager: providers.NewManager(),
 		toolManager:     tools.NewManager(),
+		credentialPools: make(map[string]*providers.CredentialPool),
+		tenantProviders: newTenantProviders(),
+		coldStarts:      newColdStartRecorder(cfg.Server.Metrics.ColdStartSLO),
+		inflight:        newInflightTracker(),
+		workflowRuns:    newWorkflowRuns(),
+		scheduler:       newScheduler(),
+		guardrailLog:    &guardrailLog{},
+		budgetLedger:    newBudgetLedger(),
+		quotas:          newQuotaLedger(),
+		experiments:     newExperimentStats(),
+		versions:        newVersionStore(),
+		instances:       newInstancePools(),
+		health:          newHealthMonitor(),
+		declared:        &configClusters{clusters: cfg.Clusters},
+		features:        newFeatureFlags(cfg.Features),
+		toolSecrets:     newToolSecrets(),
+		registeredTools: newRegisteredTools(),
+		sharedTools:     &sharedTools{},
 		clusters:        make(map[string]*Cluster),
 		logger:          logger,
-		metrics:         &Metrics{},
+		metrics:         newRequestMetrics(),
+		done:            make(chan struct{}),
+	}
+	engine.prometheus = newPrometheusMetrics(engine)
+	
+	if err := engine.initializeTracing(); err != nil {
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	
+	if err := engine.initializeEvents(); err != nil {
+		return nil, fmt.Errorf("failed to initialize events: %w", err)
+	}
+	
+	if err := engine.initializeWebhooks(); err != nil {
+		return nil, fmt.Errorf("failed to initialize webhooks: %w", err)
+	}
+	
+	if err := engine.initializeProviders(); err != nil {
+		return nil, fmt.Errorf("failed to initialize providers: %w", err)
+	}
+	
+	if err := engine.initializeCache(); err != nil {
+		return nil, fmt.Errorf("failed to initialize response cache: %w", err)
+	}
+	
+	if err := engine.initializeFiles(); err != nil {
+		return nil, fmt.Errorf("failed to initialize file store: %w", err)
+	}
+	
+	if err := engine.initializeSessions(); err != nil {
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+	
+	if err := engine.initializeState(); err != nil {
+		return nil, fmt.Errorf("failed to initialize state store: %w", err)
+	}
+	engine.templates = newTemplateRegistry(engine.state)
+	
+	if err := engine.initializeDistribution(); err != nil {
+		return nil, fmt.Errorf("failed to initialize distributed mode: %w", err)
+	}
+	
+	if err := engine.initializeKnowledge(); err != nil {
+		return nil, fmt.Errorf("failed to initialize knowledge store: %w", err)
+	}
+	
+	if err := engine.initializeVault(); err != nil {
+		return nil, fmt.Errorf("failed to initialize credential vault: %w", err)
+	}
+	engine.secrets = &tools.SecretResolver{Vault: engine.vaultSecret}
+	
+	mode, err := tools.ParseInjectionMode(cfg.Policy.PromptInjection.Mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize prompt injection guard: %w", err)
+	}
+	guard, err := tools.NewInjectionGuard(mode, cfg.Policy.PromptInjection.Patterns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize prompt injection guard: %w", err)
+	}
+	engine.injectionGuard = guard
+	
+	if cfg.Tools.MaxConcurrency != 0 {
+		engine.toolManager.SetMaxConcurrency(cfg.Tools.MaxConcurrency)
+	}
+	
+	feedback, err := newFeedbackStore(cfg.Feedback)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize feedback store: %w", err)
+	}
+	engine.feedback = feedback
+	
+	memories, err := newMemoryStore(cfg.Memory)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize memory store: %w", err)
+	}
+	engine.memories = memories
+	
+	toolAudit, err := newToolAuditLog(cfg.Tools.Audit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tool audit log: %w", err)
+	}
+	engine.toolAudit = toolAudit
+	
+	if cfg.Transcripts.Enabled {
+		transcripts, err := newTranscriptLog(cfg.Transcripts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize transcripts: %w", err)
+		}
+		engine.transcripts = transcripts
+	}
+	
+	jobs, err := newJobQueue(cfg.Jobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize job queue: %w", err)
+	}
+	engine.jobs = jobs
+	for i := 0; i < jobs.config.Workers; i++ {
+		engine.jobWorkers.Add(1)
+		go engine.runJobs()
+	}
+	engine.toolManager.Use(tools.ToolMiddlewareFunc(engine.auditToolCalls))
+	
+	refreshInterval := cfg.Tools.SecretRefreshInterval
+	if refreshInterval == 0 {
+		refreshInterval = defaultSecretRefreshInterval
+	}
+	if refreshInterval > 0 {
+		go engine.watchToolSecrets(refreshInterval)
+	}
+	go engine.runAutoscaler()
+	
+	if err := engine.restoreClusters(); err != nil {
+		return nil, fmt.Errorf("failed to restore clusters: %w", err)
+	}
+	if engine.distribution != nil {
+		engine.syncNodes(time.Now())
+		go engine.runDistribution()
+	}
+	engine.deployConfigClusters()
+	go engine.resumeJobs()
+	go engine.runReconciler()
+	
+	engine.publishEvent(agent.Event{Type: agent.EventEngineStarted})
+	return engine, nil
+}
+
+func (e *Engine) initializeCache() error {
+	switch e.config.Cache.Backend {
+	case "", "memory":
+		e.responseCache = providers.NewMemoryCache(e.config.Cache.MaxEntries)
+	case "redis":
+		if e.config.Cache.Redis == nil {
+			return fmt.Errorf("redis cache backend requires redis configuration")
+		}
+		cache, err := providers.NewRedisCache(&providers.RedisCacheConfig{
+			Addr:      e.config.Cache.Redis.Addr,
+			Password:  e.config.Cache.Redis.Password,
+			DB:        e.config.Cache.Redis.DB,
+			KeyPrefix: e.config.Cache.Redis.KeyPrefix,
+		})
+		if err != nil {
+			return err
+		}
+		e.responseCache = cache
+	default:
+		return fmt.Errorf("unsupported cache backend: %s", e.config.Cache.Backend)
+	}
+	
+	e.logger.Info("Initialized response cache", zap.String("backend", e.config.Cache.Backend))
+	return nil
+}
+
+func (e *Engine) initializeFiles() error {
+	maxSize, err := config.ParseByteSize(e.config.Files.MaxSize)
+	if err != nil {
+		return err
+	}
+	limits := files.Limits{MaxSize: maxSize, TTL: e.config.Files.TTL}
+	
+	switch e.config.Files.Backend {
+	case "", "memory":
+		e.files = files.NewMemoryStore(limits)
+	case "disk":
+		store, err := files.NewDiskStore(e.config.Files.Dir, limits)
+		if err != nil {
+			return err
+		}
+		e.files = store
+	default:
+		return fmt.Errorf("unsupported file store backend: %s", e.config.Files.Backend)
+	}
+	
+	return nil
+}
+
+// Files returns the store holding binary content returned by tools.
+func (e *Engine) Files() files.Store {
+	return e.files
+}
+
+func (e *Engine) initializeProviders() error {
+	// Initialize Anthropic provider
+	if e.config.Providers.Anthropic != nil {
+		providerConfig := e.config.Providers.Anthropic
+		apiKeys := append([]string{providerConfig.APIKey}, providerConfig.BackupAPIKeys...)
+		if err := e.initializeProvider("anthropic", apiKeys); err != nil {
+			return err
+		}
+		e.logger.Info("Registered Anthropic provider", zap.Int("api_keys", len(apiKeys)))
+	}
+	
+	// Initialize OpenAI provider
+	if e.config.Providers.OpenAI != nil {
+		providerConfig := e.config.Providers.OpenAI
+		apiKeys := append([]string{providerConfig.APIKey}, providerConfig.BackupAPIKeys...)
+		if err := e.initializeProvider("openai", apiKeys); err != nil {
+			return err
+		}
+		e.logger.Info("Registered OpenAI provider", zap.Int("api_keys", len(apiKeys)))
+	}
+	
+	// Initialize Gemini provider
+	if e.config.Providers.Gemini != nil {
+		providerConfig := e.config.Providers.Gemini
+		apiKeys := append([]string{providerConfig.APIKey}, providerConfig.BackupAPIKeys...)
+		if err := e.initializeProvider("gemini", apiKeys); err != nil {
+			return err
+		}
+		e.logger.Info("Registered Gemini provider", zap.Int("api_keys", len(apiKeys)))
+	}
+	
+	return nil
+}
+
+func (e *Engine) initializeProvider(name string, apiKeys []string) error {
+	factory, err := e.providerFactory(name)
+	if err != nil {
+		return err
+	}
+	
+	pool, err := providers.NewCredentialPool(name, apiKeys, factory, e.handleCredentialRevoked)
+	if err != nil {
+		return fmt.Errorf("failed to create %s provider: %w", name, err)
+	}
+	e.registerProvider(name, pool)
+	return nil
+}
+
+// providerFactory returns a constructor for clients of the named provider
+// that applies the server's settings for it, such as the base URL and HTTP
+// client options, to the given API key.
+func (e *Engine) providerFactory(name string) (func(apiKey string) (providers.Provider, error), error) {
+	switch name {
+	case "anthropic":
+		providerConfig := e.config.Providers.Anthropic
+		if providerConfig == nil {
+			providerConfig = &config.AnthropicConfig{}
+		}
+		return func(apiKey string) (providers.Provider, error) {
+			return providers.NewAnthropicProvider(&providers.AnthropicConfig{
+				APIKey:  apiKey,
+				BaseURL: providerConfig.BaseURL,
+				Version: providerConfig.Version,
+				HTTP:    convertHTTPClientConfig(providerConfig.HTTP),
+			})
+		}, nil
+	case "openai":
+		providerConfig := e.config.Providers.OpenAI
+		if providerConfig == nil {
+			providerConfig = &config.OpenAIConfig{}
+		}
+		return func(apiKey string) (providers.Provider, error) {
+			return providers.NewOpenAIProvider(&providers.OpenAIConfig{
+				APIKey:  apiKey,
+				BaseURL: providerConfig.BaseURL,
+				OrgID:   providerConfig.OrgID,
+				HTTP:    convertHTTPClientConfig(providerConfig.HTTP),
+			})
+		}, nil
+	case "gemini":
+		providerConfig := e.config.Providers.Gemini
+		if providerConfig == nil {
+			providerConfig = &config.GeminiConfig{}
+		}
+		return func(apiKey string) (providers.Provider, error) {
+			return providers.NewGeminiProvider(&providers.GeminiConfig{
+				APIKey:    apiKey,
+				ProjectID: providerConfig.ProjectID,
+				HTTP:      convertHTTPClientConfig(providerConfig.HTTP),
+			})
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %s", name)
+	}
+}
+
+func (e *Engine) registerProvider(name string, pool *providers.CredentialPool) {
+	e.providerManager.RegisterProvider(name, pool)
+	e.credentialPools[name] = pool
+}
+
+// handleCredentialRevoked raises an alert when a provider rejects an API key.
+// Losing the last key is reported separately since requests can then only
+// succeed through an agent fallback.
+func (e *Engine) handleCredentialRevoked(event providers.CredentialEvent) {
+	e.logger.Error("Provider rejected API key, credential marked invalid",
+		zap.String("provider", event.Provider),
+		zap.String("key", event.Fingerprint),
+		zap.Int("remaining_keys", event.Remaining),
+		zap.Error(event.Err))
+	
+	e.publishEvent(agent.Event{
+		Type: agent.EventCredentialRevoked,
+		Data: map[string]interface{}{
+			"severity":       "critical",
+			"provider":       event.Provider,
+			"key":            event.Fingerprint,
+			"remaining_keys": event.Remaining,
+			"error":          event.Err.Error(),
+		},
+	})
+	
+	if event.Remaining == 0 {
+		e.logger.Error("All API keys revoked, provider unavailable", zap.String("provider", event.Provider))
+		e.publishEvent(agent.Event{
+			Type: agent.EventProviderUnavailable,
+			Data: map[string]interface{}{
+				"severity": "critical",
+				"provider": event.Provider,
+			},
+		})
+	}
+}
+
+// ProviderCredentials reports the state of every configured API key per
+// provider. Keys are identified by fingerprint only.
+func (e *Engine) ProviderCredentials() map[string][]providers.CredentialStatus {
+	credentials := make(map[string][]providers.CredentialStatus, len(e.credentialPools))
+	for name, pool := range e.credentialPools {
+		credentials[name] = pool.Status()
+	}
+	return credentials
+}
+
+func convertHTTPClientConfig(httpConfig *config.HTTPClientConfig) *providers.HTTPClientConfig {
+	if httpConfig == nil {
+		return nil
+	}
+	
+	return &providers.HTTPClientConfig{
+		ProxyURL:           httpConfig.ProxyURL,
+		CABundle:           httpConfig.CABundle,
+		InsecureSkipVerify: httpConfig.InsecureSkipVerify,
+		ConnectTimeout:     httpConfig.ConnectTimeout,
+		ReadTimeout:        httpConfig.ReadTimeout,
+		Timeout:            httpConfig.Timeout,
+		MaxIdleConns:       httpConfig.MaxIdleConns,
+	}
+}
+
+func (e *Engine) DeployCluster(clusterConfig *config.AgentCluster) error {
+	return e.deployCluster(clusterConfig, "")
+}
+
+// deployCluster deploys a cluster on this node, or, in distributed mode,
+// on node: the cluster is saved for that node to start when it next syncs.
+func (e *Engine) deployCluster(clusterConfig *config.AgentCluster, node string) error {
+	e.agentQuota.Lock()
+	defer e.agentQuota.Unlock()
+	if err := e.checkAgentQuota(clusterSpecID(clusterConfig), clusterConfig.Spec.Quota, len(clusterConfig.Spec.Agents)); err != nil {
+		return err
+	}
+	
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	
+	if clusterConfig.Metadata.Namespace == "" {
+		clusterConfig.Metadata.Namespace = config.DefaultNamespace
+	}
+	if err := config.ValidateClusterNames(clusterConfig); err != nil {
+		return err
+	}
+	
+	clusterName := clusterSpecID(clusterConfig)
+	if _, exists := e.clusters[clusterName]; exists {
+		return fmt.Errorf("%w: cluster %s already exists", ErrConflict, clusterName)
+	}
+	
+	if err := e.config.Policy.CheckCluster(clusterConfig); err != nil {
+		return err
+	}
+	if err := e.claimCluster(clusterName, node); err != nil {
+		return err
+	}
+	
+	clusterConfig.Metadata.ResourceVersion = e.nextResourceVersion()
+	cluster := &Cluster{
+		Name:      clusterName,
+		Config:    clusterConfig,
+		Agents:    make(map[string]*agent.Agent),
+		Status:    ClusterStatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if node != "" {
+		e.saveCluster(cluster)
+		e.logger.Info("Cluster deployed", zap.String("name", clusterName), zap.String("node", node))
+		return nil
+	}
+	
+	e.clusters[clusterName] = cluster
+	e.saveCluster(cluster)
+	
+	e.logger.Info("Cluster deployed", zap.String("name", clusterName))
+	
+	// Start cluster in background
+	go e.startCluster(cluster)
+	
+	return nil
+}
+
+// UpdateCluster replaces a running cluster's spec and recreates its agents.
+// The write is refused with ErrConflict if pre does not hold; when pre has
+// no resource version, the candidate's metadata.resourceVersion is used.
+func (e *Engine) UpdateCluster(clusterName string, candidate *config.AgentCluster, pre *Precondition) error {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return err
+	}
+	
+	if err := checkCandidate(clusterName, candidate); err != nil {
+		return err
+	}
+	if err := config.ValidateClusterNames(candidate); err != nil {
+		return err
+	}
+	
+	if err := e.config.Policy.CheckCluster(candidate); err != nil {
+		return err
+	}
+	e.agentQuota.Lock()
+	defer e.agentQuota.Unlock()
+	if err := e.checkAgentQuota(clusterName, candidate.Spec.Quota, len(candidate.Spec.Agents)); err != nil {
+		return err
+	}
+	
+	if pre == nil {
+		pre = &Precondition{}
+	}
+	if pre.ResourceVersion == "" {
+		pre.ResourceVersion = candidate.Metadata.ResourceVersion
+	}
+	
+	cluster.mu.Lock()
+	if err := checkPrecondition(cluster, pre); err != nil {
+		cluster.mu.Unlock()
+		return err
+	}
+	
+	for _, agent := range cluster.Agents {
+		if err := e.agentManager.StopAgent(agent.ID); err != nil {
+			e.logger.Warn("Failed to stop agent",
+				zap.String("agent", agent.GetName()),
+				zap.Error(err))
+		}
+		e.removeAgent(agent)
+	}
+	e.stopSchedules(clusterName)
+	e.abortRollouts(clusterName)
+	e.instances.forget(clusterName)
+	
+	cluster.Config = candidate
+	cluster.Agents = make(map[string]*agent.Agent)
+	cluster.SmokeTests = nil
+	cluster.Message = ""
+	cluster.UpdatedAt = time.Now()
+	e.bumpResourceVersion(cluster)
+	e.saveCluster(cluster)
+	e.publishClusterEvent(agent.EventClusterUpdated, cluster, nil)
+	cluster.mu.Unlock()
+	
+	e.logger.Info("Cluster updated",
+		zap.String("name", clusterName),
+		zap.String("resource_version", candidate.Metadata.ResourceVersion))
+	
+	go e.startCluster(cluster)
+	
+	return nil
+}
+
+func (e *Engine) startCluster(cluster *Cluster) {
+	cluster.mu.Lock()
+	cluster.Status = ClusterStatusRunning
+	cluster.started = nil
+	cluster.UpdatedAt = time.Now()
+	cluster.mu.Unlock()
+	
+	e.logger.Info("Starting cluster", zap.String("name", cluster.Name))
+	
+	cluster.mu.RLock()
+	spec := cluster.Config
+	cluster.mu.RUnlock()
+	order, err := startupOrder(spec.Spec.Agents)
+	if err != nil {
+		e.logger.Error("Cluster failed",
+			zap.String("name", cluster.Name),
+			zap.Error(err))
+		cluster.mu.Lock()
+		cluster.Status = ClusterStatusFailed
+		cluster.Message = err.Error()
+		cluster.UpdatedAt = time.Now()
+		e.saveCluster(cluster)
+		e.publishClusterEvent(agent.EventClusterFailed, cluster, map[string]interface{}{
+			"error": err.Error(),
+		})
+		cluster.mu.Unlock()
+		return
+	}
+	
+	// Initialize agents for the cluster, each once the agents it depends
+	// on are ready
+	timeout := spec.Spec.StartupTimeout
+	if timeout <= 0 {
+		timeout = defaultStartupTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	e.instances.setLimit(cluster.Name, spec.Spec.ResourcePolicy.MaxConcurrentAgents)
+	ready := make(map[string]bool)
+	var notStarted []string
+	for _, agentConfig := range order {
+		err := e.dependenciesReady(cluster, &agentConfig, ready, deadline)
+		
+		// The cluster may have been updated or stopped while agents waited
+		cluster.mu.RLock()
+		replaced := cluster.Config != spec || cluster.Status == ClusterStatusStopped
+		cluster.mu.RUnlock()
+		if replaced {
+			return
+		}
+		
+		if err == nil {
+			err = e.createAgent(cluster, &agentConfig)
+		}
+		if err != nil {
+			e.logger.Error("Failed to create agent", 
+				zap.String("cluster", cluster.Name),
+				zap.String("agent", agentConfig.Name),
+				zap.Error(err))
+			notStarted = append(notStarted, fmt.Sprintf("%s: %v", agentConfig.Name, err))
+			continue
+		}
+	}
+	cluster.mu.Lock()
+	deployed := map[string]interface{}{
+		"agents": len(cluster.Agents),
+	}
+	if len(notStarted) > 0 {
+		cluster.Message = "agents not started: " + strings.Join(notStarted, "; ")
+		deployed["message"] = cluster.Message
+	}
+	current := cluster.Config == spec
+	if current {
+		cluster.started = spec
+	}
+	e.saveCluster(cluster)
+	e.publishClusterEvent(agent.EventClusterDeployed, cluster, deployed)
+	cluster.mu.Unlock()
+	
+	// A cluster updated while it started is started again from the new
+	// spec, which runs its own smoke tests and schedules
+	if !current {
+		return
+	}
+	if spec.Spec.RunSmokeTests {
+		e.runSmokeTests(cluster, spec)
+	}
+	
+	e.startSchedules(cluster)
+	
+	e.logger.Info("Cluster started", zap.String("name", cluster.Name))
+}
+
+// createAgent creates an agent from its spec and puts it in the cluster,
+// in place of any agent of the same name.
+func (e *Engine) createAgent(cluster *Cluster, agentConfig *config.Agent) error {
+	newAgent, err := e.buildAgent(cluster, agentConfig)
+	if err != nil {
+		return err
+	}
+	
+	cluster.mu.Lock()
+	cluster.Agents[agentConfig.Name] = newAgent
+	cluster.mu.Unlock()
+	e.versions.deployed(cluster.Name, agentConfig.Name, newAgent.Version)
+	e.instances.configure(cluster.Name, newAgent)
+	if agentConfig.Health != nil {
+		e.watchHealth(newAgent, agentConfig.Health)
+	}
+	return nil
+}
+
+// buildAgent creates an agent from its spec, with its tools, without
+// making it the one that serves the cluster's requests for its name.
+func (e *Engine) buildAgent(cluster *Cluster, agentConfig *config.Agent) (*agent.Agent, error) {
+	start := time.Now()
+	
+	systemPrompt, layers, err := e.config.Policy.SystemPrompt.Compose(cluster.Config.Spec.SystemPrompt, agentConfig.SystemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Convert config to agent config
+	agentCfg := &agent.AgentConfig{
+		Provider:       agentConfig.Provider,
+		Model:          agentConfig.Model,
+		SystemPrompt:   systemPrompt,
+		Environment:    agentConfig.Environment,
+		ThinkingBudget: agentConfig.ThinkingBudget,
+		MaxTurns:       agentConfig.MaxTurns,
+		Budget:         agentConfig.Budget,
+		Experiment:     agentConfig.Experiment,
+	}
+	agentCfg.Scaling = agent.ScalingConfig{
+		MinInstances:   agentConfig.Scaling.MinInstances,
+		MaxInstances:   agentConfig.Scaling.MaxInstances,
+		MaxConcurrency: agentConfig.Scaling.MaxConcurrency,
+		QueueTimeout:   agentConfig.Scaling.QueueTimeout,
+		MaxQueueDepth:  agentConfig.Scaling.MaxQueueDepth,
+		Autoscale:      agentConfig.Scaling.Autoscale,
+	}
+	agentCfg.Resources = agent.ResourceConfig{
+		MemoryLimit: agentConfig.Resources.MemoryLimit,
+		CPULimit:    agentConfig.Resources.CPULimit,
+		Timeout:     agentConfig.Resources.Timeout,
+		IdleTimeout: idleTimeout(cluster.Config.Spec.ResourcePolicy),
+	}
+	
+	tmpl, _, err := config.ParsePromptTemplate(systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl != nil {
+		agentCfg.PromptTemplate = tmpl
+		agentCfg.PromptVariables = make(map[string]agent.PromptVariable)
+		for name, variable := range config.PromptVariables(cluster.Config, agentConfig) {
+			agentCfg.PromptVariables[name] = agent.PromptVariable{
+				Default:  variable.Default,
+				Required: variable.Required,
+			}
+		}
+	}
+	for _, layer := range layers {
+		agentCfg.PromptLayers = append(agentCfg.PromptLayers, agent.PromptLayer{
+			Name:   layer.Name,
+			Length: layer.Length,
+		})
+	}
+	
+	if agentConfig.Cache != nil {
+		agentCfg.Cache = agent.CacheConfig{
+			Enabled: agentConfig.Cache.Enabled,
+			TTL:     agentConfig.Cache.TTL,
+		}
+	}
+	
+	if agentConfig.Fallback != nil {
+		agentCfg.Fallback = agent.FallbackConfig{
+			Provider: agentConfig.Fallback.Provider,
+			Model:    agentConfig.Fallback.Model,
+		}
+	}
+	
+	if agentConfig.Memory != nil {
+		agentCfg.Memory = agent.MemoryConfig{
+			Enabled:        agentConfig.Memory.Enabled,
+			Provider:       agentConfig.Memory.Provider,
+			EmbeddingModel: agentConfig.Memory.EmbeddingModel,
+			TopK:           agentConfig.Memory.TopK,
+			ScoreThreshold: agentConfig.Memory.ScoreThreshold,
+		}
+	}
+	
+	if agentConfig.Knowledge != nil {
+		agentCfg.Knowledge = agent.KnowledgeConfig{
+			Enabled:        agentConfig.Knowledge.Enabled,
+			Provider:       agentConfig.Knowledge.Provider,
+			EmbeddingModel: agentConfig.Knowledge.EmbeddingModel,
+			ChunkStrategy:  agentConfig.Knowledge.Chunking.Strategy,
+			ChunkSize:      agentConfig.Knowledge.Chunking.Size,
+			ChunkOverlap:   agentConfig.Knowledge.Chunking.Overlap,
+			TopK:           agentConfig.Knowledge.TopK,
+			ScoreThreshold: agentConfig.Knowledge.ScoreThreshold,
+		}
+	}
+	
+	if agentConfig.Router != nil {
+		agentCfg.Router = routerConfig(cluster.Config, agentConfig.Router)
+	}
+	
+	if agentConfig.Guardrails != nil {
+		pipeline, err := guardrails.New(agentConfig.Guardrails)
+		if err != nil {
+			return nil, fmt.Errorf("invalid guardrails: %w", err)
+		}
+		agentCfg.Guardrails = pipeline
+	}
+	
+	if agentConfig.Output != nil {
+		validator, err := tools.CompileSchema(agentConfig.Output.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("invalid output schema: %w", err)
+		}
+		agentCfg.Output = &agent.OutputConfig{
+			Name:       agentConfig.Output.Name,
+			Schema:     agentConfig.Output.Schema,
+			Strict:     agentConfig.Output.Strict,
+			MaxRetries: agentConfig.Output.MaxRetries,
+			Validator:  validator,
+		}
+	}
+	
+	// Convert tools. They are registered once the agent exists, scoped to
+	// its ID so agents never see each other's tools.
+	var built []*builtTool
+	outbound := e.outboundPolicy(cluster)
+	for _, toolConfig := range e.toolsFor(agentConfig) {
+		b, err := e.buildTool(cluster.Name, agentConfig, &toolConfig, outbound)
+		if err != nil {
+			e.logger.Warn("Skipping tool",
+				zap.String("cluster", cluster.Name),
+				zap.String("agent", agentConfig.Name),
+				zap.String("tool", toolConfig.Name),
+				zap.Error(err))
+			continue
+		}
+		built = append(built, b)
+		agentCfg.ToolDefinitions = append(agentCfg.ToolDefinitions, b.definitions...)
+	}
+	
+	// Agents it depends on are tools of their own, for it to delegate to
+	taken := make(map[string]bool)
+	for _, b := range built {
+		for _, name := range b.names() {
+			taken[name] = true
+		}
+	}
+	for _, b := range e.delegationTools(cluster.Name, agentConfig, taken) {
+		built = append(built, b)
+		agentCfg.ToolDefinitions = append(agentCfg.ToolDefinitions, b.definitions...)
+	}
+	
+	// Create agent
+	newAgent, err := e.agentManager.CreateAgent(agentCfg)
+	if err != nil {
+		for _, b := range built {
+			b.close()
+		}
+		return nil, fmt.Errorf("failed to create agent: %w", err)
+	}
+	
+	for _, b := range built {
+		e.registerTool(newAgent.ID, b)
+	}
+	
+	newAgent.Name = agentConfig.Name
+	newAgent.ClusterName = cluster.Name
+	newAgent.Version = e.versions.record(cluster.Name, agentConfig)
+	if err := e.agentManager.StartAgent(newAgent.ID); err != nil {
+		e.logger.Warn("Failed to start agent",
+			zap.String("agent", agentConfig.Name),
+			zap.Error(err))
+	}
+	
+	e.coldStarts.record(ColdStartSample{
+		Kind:     ColdStartAgentStart,
+		Cluster:  cluster.Name,
+		Agent:    agentConfig.Name,
+		Duration: time.Since(start),
+	})
+	
+	e.logger.Info("Agent created", 
+		zap.String("cluster", cluster.Name),
+		zap.String("agent", agentConfig.Name),
+		zap.String("provider", agentConfig.Provider))
+	
+	return newAgent, nil
+}
+
+// builtTool is a configured tool created for an agent: the tools it
+// registers, which are more than one for MCP servers and OpenAPI specs,
+// with their settings, and the definitions advertised for them.
+type builtTool struct {
+	name        string
+	pending     []pendingTool
+	definitions []agent.ToolDefinition
+	// secret is set when the tool's auth references secrets
+	secret *secretTool
+}
+
+// names lists the names the tool registers, sorted.
+func (b *builtTool) names() []string {
+	names := make([]string, 0, len(b.pending))
+	for _, p := range b.pending {
+		names = append(names, p.tool.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (b *builtTool) close() {
+	for _, p := range b.pending {
+		p.tool.Close()
+	}
+}
+
+// buildTool creates a configured tool for an agent, with the settings it
+// is registered with. It is not registered yet.
+func (e *Engine) buildTool(clusterName string, agentConfig *config.Agent, toolConfig *config.Tool, outbound *tools.OutboundPolicy) (*builtTool, error) {
+	toolCfg := &tools.Config{
+		Type:      toolConfig.Type,
+		Name:      toolConfig.Name,
+		URL:       toolConfig.URL,
+		Endpoint:  toolConfig.Endpoint,
+		Server:    toolConfig.Server,
+		Transport: toolConfig.Transport,
+		Command:   toolConfig.Command,
+		Args:      toolConfig.Args,
+		Env:       toolConfig.Env,
+		Config:    toolConfig.Config,
+		Timeout:   toolConfig.Timeout,
+		Files:     e.files,
+		Outbound:  outbound,
+	}
+	
+	if toolConfig.Auth != nil {
+		toolCfg.Auth = &tools.AuthConfig{
+			Type:   toolConfig.Auth.Type,
+			Token:  toolConfig.Auth.Token,
+			APIKey: toolConfig.Auth.APIKey,
+			Secret: toolConfig.Auth.Secret,
+		}
+	}
+	
+	var refs *tools.AuthConfig
+	if toolCfg.Auth.HasSecretRefs() {
+		// Tools added at runtime were not checked when the cluster was
+		// deployed
+		namespace, _ := SplitClusterID(clusterName)
+		if err := e.config.Policy.Secrets.CheckAuth(namespace, toolConfig.Auth); err != nil {
+			return nil, err
+		}
+		refs = toolCfg.Auth
+		auth, err := e.secrets.ResolveAuth(refs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+		}
+		toolCfg.Auth = auth
+	}
+	
+	if toolConfig.Type == "exec" {
+		execCfg, err := e.execConfig(toolConfig)
+		if err != nil {
+			return nil, fmt.Errorf("rejected by exec policy: %w", err)
+		}
+		toolCfg.Exec = execCfg
+		if toolCfg.Timeout == 0 {
+			toolCfg.Timeout = e.config.Policy.Exec.MaxTimeout
+		}
+	}
+	
+	if toolConfig.Type == "vector_search" {
+		embed, err := e.embedFunc(toolConfig, agentConfig.Provider)
+		if err != nil {
+			return nil, fmt.Errorf("no embedding provider: %w", err)
+		}
+		toolCfg.Embed = embed
+	}
+	
+	guard, err := e.toolGuard(toolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("rejected by prompt injection policy: %w", err)
+	}
+	
+	cacheTTL, err := toolCacheTTL(toolConfig)
+	if err != nil {
+		return nil, err
+	}
+	
+	retry, err := tools.ParseRetryPolicy(toolConfig.Config, toolCfg.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("invalid retry policy: %w", err)
+	}
+	
+	maxConcurrency, err := toolMaxConcurrency(toolConfig)
+	if err != nil {
+		return nil, err
+	}
+	
+	dryRun, err := toolDryRun(toolConfig)
+	if err != nil {
+		return nil, err
+	}
+	
+	var schema *tools.Schema
+	if toolConfig.Parameters != nil {
+		schema, err = tools.CompileSchema(toolConfig.Parameters)
+		if err != nil {
+			return nil, fmt.Errorf("invalid parameters: %w", err)
+		}
+	}
+	
+	tool, err := tools.CreateTool(toolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tool: %w", err)
+	}
+	
+	b := &builtTool{name: toolConfig.Name}
+	for _, registered := range e.discoverTools(clusterName, agentConfig.Name, tool) {
+		p := pendingTool{
+			tool:     registered,
+			guard:    guard,
+			cacheTTL: cacheTTL,
+			retry:    retry,
+			limit:    maxConcurrency,
+			dryRun:   dryRun,
+		}
+		
+		definition := tools.Definition{Name: registered.Name()}
+		describer, advertised := registered.(tools.Describer)
+		if advertised {
+			definition = describer.Definition()
+		}
+		// Configured parameters apply to the configured tool, not to
+		// tools discovered through it
+		if schema != nil && registered.Name() == toolConfig.Name {
+			p.schema = schema
+			definition.Parameters = toolConfig.Parameters
+			if definition.Description == "" {
+				definition.Description = toolConfig.Config["description"]
+			}
+			advertised = true
+		}
+		
+		b.pending = append(b.pending, p)
+		if advertised {
+			b.definitions = append(b.definitions, agent.ToolDefinition{
+				Name:        definition.Name,
+				Description: definition.Description,
+				Parameters:  definition.Parameters,
+			})
+		}
+	}
+	
+	if refs != nil {
+		b.secret = &secretTool{
+			cluster: clusterName,
+			agent:   agentConfig.Name,
+			config:  toolCfg,
+			refs:    refs,
+			names:   b.names(),
+		}
+	}
+	return b, nil
+}
+
+// registerTool registers a built tool in an agent's scope.
+func (e *Engine) registerTool(agentID string, b *builtTool) {
+	for _, p := range b.pending {
+		key := e.toolManager.RegisterTool(agentID, p.tool)
+		e.toolManager.Guard(key, p.guard)
+		e.toolManager.Cache(key, p.cacheTTL)
+		e.toolManager.Retry(key, p.retry)
+		e.toolManager.Validate(key, p.schema)
+		e.toolManager.Limit(key, p.limit)
+		e.toolManager.DryRun(key, p.dryRun)
+	}
+	e.registeredTools.track(agentID, b.name, b.names())
+	if b.secret != nil {
+		e.toolSecrets.track(agentID, b.secret)
+	}
+}
+
+// execConfig checks an exec tool against the exec policy and returns its
+// settings, with the policy's maximums standing in for unset limits.
+func (e *Engine) execConfig(toolConfig *config.Tool) (*tools.ExecConfig, error) {
+	policy := &e.config.Policy.Exec
+	if err := policy.CheckTool(toolConfig); err != nil {
+		return nil, err
+	}
+	
+	execCfg := &tools.ExecConfig{
+		WorkingDir:      toolConfig.Config["working_dir"],
+		AllowedCommands: policy.AllowedCommands,
+		AllowedDirs:     policy.AllowedDirs,
+		CPUTime:         policy.MaxCPUTime,
+	}
+	
+	if value := toolConfig.Config["cpu_time"]; value != "" {
+		execCfg.CPUTime, _ = time.ParseDuration(value)
+	}
+	
+	memoryLimit := toolConfig.Config["memory_limit"]
+	if memoryLimit == "" {
+		memoryLimit = policy.MaxMemory
+	}
+	maxOutput := toolConfig.Config["max_output"]
+	if maxOutput == "" {
+		maxOutput = policy.MaxOutput
+	}
+	execCfg.MemoryLimit, _ = config.ParseByteSize(memoryLimit)
+	execCfg.MaxOutput, _ = config.ParseByteSize(maxOutput)
+	
+	return execCfg, nil
+}
+
+// embedFunc embeds text for a vector_search tool with the provider named in
+// its config, or the agent's provider, and the configured embedding_model.
+func (e *Engine) embedFunc(toolConfig *config.Tool, agentProvider string) (tools.EmbedFunc, error) {
+	model := toolConfig.Config["embedding_model"]
+	if model == "" {
+		return nil, fmt.Errorf("embedding_model is required")
+	}
+	
+	providerName := toolConfig.Config["provider"]
+	if providerName == "" {
+		providerName = agentProvider
+	}
+	provider, exists := e.providerManager.GetProvider(providerName)
+	if !exists {
+		return nil, fmt.Errorf("provider %s not configured", providerName)
+	}
+	if !providers.SupportsEmbeddings(provider) {
+		return nil, fmt.Errorf("%w: %s", providers.ErrEmbeddingsUnsupported, providerName)
+	}
+	
+	return func(ctx context.Context, texts []string) ([][]float32, error) {
+		resp, err := providers.Embed(ctx, provider, &providers.EmbeddingRequest{
+			Model: model,
+			Input: texts,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Embeddings, nil
+	}, nil
+}
+
+// toolGuard returns the prompt injection guard for a tool's results. A tool
+// may ask for a stricter mode than the policy's, but not a weaker one.
+func (e *Engine) toolGuard(toolConfig *config.Tool) (*tools.InjectionGuard, error) {
+	mode, err := tools.ParseInjectionMode(toolConfig.Config["prompt_injection"])
+	if err != nil {
+		return nil, err
+	}
+	return e.injectionGuard.WithMode(tools.StricterInjectionMode(e.injectionGuard.Mode(), mode)), nil
+}
+
+// outboundPolicy returns the hosts a cluster's tools may reach: those the
+// server's policy allows, narrowed by the cluster's own policy if it has one.
+func (e *Engine) outboundPolicy(cluster *Cluster) *tools.OutboundPolicy {
+	server := &e.config.Policy.Outbound
+	policy := &tools.OutboundPolicy{
+		AllowPrivate: server.AllowPrivate,
+		AllowedHosts: server.AllowedHosts,
+		DeniedHosts:  server.DeniedHosts,
+	}
+	if clusterPolicy := cluster.Config.Spec.Outbound; clusterPolicy != nil {
+		policy = &tools.OutboundPolicy{
+			AllowPrivate: clusterPolicy.AllowPrivate,
+			AllowedHosts: clusterPolicy.AllowedHosts,
+			DeniedHosts:  clusterPolicy.DeniedHosts,
+			Parent:       policy,
+		}
+	}
+	return policy
+}
+
+// pendingTool is a tool created for an agent, with its settings, waiting
+// to be registered under the agent's ID.
+type pendingTool struct {
+	tool     tools.Tool
+	guard    *tools.InjectionGuard
+	cacheTTL time.Duration
+	retry    *tools.RetryPolicy
+	schema   *tools.Schema
+	limit    int
+	dryRun   bool
+}
+
+// removeAgent deletes an agent and closes the tools registered for it.
+func (e *Engine) removeAgent(a *agent.Agent) {
+	e.toolUpdates.Lock()
+	defer e.toolUpdates.Unlock()
+	
+	e.coldStarts.forget(a.ID)
+	e.forgetHealth(a.ID)
+	e.toolSecrets.forget(a.ID)
+	e.registeredTools.forget(a.ID)
+	if err := e.agentManager.DeleteAgent(a.ID); err != nil {
+		e.logger.Warn("Failed to delete agent",
+			zap.String("agent", a.GetName()),
+			zap.Error(err))
+	}
+	if err := e.toolManager.RemoveScope(a.ID); err != nil {
+		e.logger.Warn("Failed to close agent tools",
+			zap.String("agent", a.GetName()),
+			zap.Error(err))
+	}
+}
+
+// toolCacheTTL reads how long a tool's results are reused from its
+// cache_ttl config key. Tools are not cached by default.
+func toolCacheTTL(toolConfig *config.Tool) (time.Duration, error) {
+	value := toolConfig.Config["cache_ttl"]
+	if value == "" {
+		return 0, nil
+	}
+	ttl, err := time.ParseDuration(value)
+	if err != nil || ttl < 0 {
+		return 0, fmt.Errorf("invalid cache_ttl: %q", value)
+	}
+	return ttl, nil
+}
+
+// toolMaxConcurrency reads how many calls of a tool may run at once from
+// its max_concurrency config key. Tools are only bounded by the shared
+// worker pool by default.
+func toolMaxConcurrency(toolConfig *config.Tool) (int, error) {
+	value := toolConfig.Config["max_concurrency"]
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid max_concurrency: %q", value)
+	}
+	return n, nil
+}
+
+// toolDryRun reads from a tool's dry_run config key whether its calls are
+// only described rather than made.
+func toolDryRun(toolConfig *config.Tool) (bool, error) {
+	value := toolConfig.Config["dry_run"]
+	if value == "" {
+		return false, nil
+	}
+	dryRun, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid dry_run: %q", value)
+	}
+	return dryRun, nil
+}
+
+// discoverTools expands an MCP tool into the tools its server offers and an
+// OpenAPI tool into its operations. Other tools, and MCP servers that cannot
+// be listed, are returned unchanged.
+func (e *Engine) discoverTools(clusterName, agentName string, tool tools.Tool) []tools.Tool {
+	if openAPITool, ok := tool.(*tools.OpenAPITool); ok {
+		operations := openAPITool.Operations()
+		result := make([]tools.Tool, 0, len(operations))
+		names := make([]string, 0, len(operations))
+		for _, operation := range operations {
+			result = append(result, operation)
+			names = append(names, operation.Name())
+		}
+		e.logger.Info("Generated OpenAPI tools",
+			zap.String("tool", tool.Name()),
+			zap.Strings("tools", names))
+		return result
+	}
+	
+	mcpTool, ok := tool.(*tools.MCPTool)
+	if !ok {
+		return []tools.Tool{tool}
+	}
+	
+	mcpTool.OnConnect(func(d time.Duration) {
+		e.coldStarts.record(ColdStartSample{
+			Kind:     ColdStartMCPHandshake,
+			Cluster:  clusterName,
+			Agent:    agentName,
+			Target:   tool.Name(),
+			Duration: d,
+		})
+	})
+	
+	discovered, err := mcpTool.Discover(context.Background())
+	if err != nil {
+		e.logger.Warn("Failed to discover MCP tools, registering server as a single tool",
+			zap.String("tool", tool.Name()),
+			zap.Error(err))
+		return []tools.Tool{tool}
+	}
+	if len(discovered) == 0 {
+		return []tools.Tool{tool}
+	}
+	
+	result := make([]tools.Tool, 0, len(discovered))
+	names := make([]string, 0, len(discovered))
+	for _, serverTool := range discovered {
+		result = append(result, serverTool)
+		names = append(names, serverTool.Name())
+	}
+	
+	e.logger.Info("Discovered MCP tools",
+		zap.String("tool", tool.Name()),
+		zap.Strings("tools", names))
+	
+	return result
+}
+
+func (e *Engine) ProcessRequest(clusterName, agentName string, req *agent.Request) (*agent.Response, error) {
+	return e.processRequest(context.Background(), clusterName, agentName, req)
+}
+
+// ProcessRequestContext is ProcessRequest as part of the trace of ctx, such
+// as that of the API request it serves. The request is not cancelled with
+// ctx.
+func (e *Engine) ProcessRequestContext(ctx context.Context, clusterName, agentName string, req *agent.Request) (*agent.Response, error) {
+	return e.processRequest(traceContext(ctx), clusterName, agentName, req)
+}
+
+// processRequest is ProcessRequest under a parent context, which cancels
+// the request when it is done.
+func (e *Engine) processRequest(parent context.Context, clusterName, agentName string, req *agent.Request) (resp *agent.Response, err error) {
+	parent, span := e.startRequestSpan(parent, clusterName, agentName, req, false)
+	defer func() {
+		endSpan(span, requestFailure(resp, err))
+	}()
+	
+	targetAgent, provider, err := e.resolveAgent(clusterName, agentName)
+	if err != nil {
+		return nil, err
+	}
+	targetAgent, provider, router := e.routeRequest(clusterName, targetAgent, provider, req)
+	agentName = targetAgent.GetName()
+	span.SetAttributes(attribute.String("gen_ai.agent.name", agentName))
+	
+	// Requests to agents running an experiment go to their variant's model
+	primary := provider
+	providerName, model := targetAgent.Config.Provider, targetAgent.Config.Model
+	assignment := e.assignVariant(clusterName, targetAgent, provider, req)
+	if assignment != nil {
+		providerName, model, provider = assignment.providerName, assignment.model, assignment.provider
+	}
+	
+	waking := targetAgent.Wake()
+	
+	start := time.Now()
+	tally := e.startTally(clusterName, agentName, providerName, model)
+	if err := e.admitRequest(clusterName, targetAgent, req.ID); err != nil {
+		tally.fail()
+		return nil, err
+	}
+	
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if req.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, req.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	defer cancel()
+	ctx = withDelegation(ctx, req, agentName)
+	
+	inflightID := e.inflight.start(clusterName, agentName, req.ID, false, cancel)
+	defer e.inflight.finish(inflightID)
+	e.publishRequestStarted(clusterName, targetAgent, req.ID, false)
+	defer func() {
+		e.publishRequestEnded(clusterName, targetAgent, req.ID, false, start, requestFailure(resp, err))
+	}()
+	
+	// Requests wait, queued, for an instance of the agent to take them
+	lease, err := e.instances.acquire(ctx, clusterName, targetAgent, req.Priority)
+	if err != nil {
+		tally.fail()
+		return nil, err
+	}
+	defer lease.release()
+	// An agent scaled to zero wakes up on the request, like an idle one
+	waking = waking || lease.coldStart
+	
+	spend := e.startBudget(clusterName, targetAgent, model, req)
+	violations, err := e.checkInput(ctx, clusterName, targetAgent, req)
+	var providerReq *providers.ChatRequest
+	if err == nil {
+		providerReq, err = e.buildProviderRequest(targetAgent, req)
+	}
+	if err == nil {
+		providerReq.Model = model
+		err = e.attachFiles(ctx, providerReq, req)
+	}
+	if err != nil {
+		tally.fail()
+		return nil, err
+	}
+	recalled := e.recallMemories(ctx, clusterName, targetAgent, req, providerReq)
+	citations := e.retrieveKnowledge(ctx, clusterName, targetAgent, req, providerReq)
+	capture := e.startTranscript(clusterName, targetAgent, req, providerReq, false)
+	defer func() {
+		e.finishTranscript(capture, requestFailure(resp, err))
+	}()
+	
+	// Call the provider, running the tools the model uses and calling it
+	// again with their results until it answers or the turns run out
+	limit := maxTurns(targetAgent)
+	var providerResp *providers.ChatResponse
+	var cached bool
+	var usage providers.Usage
+	var toolUses []agent.ToolUse
+	var output json.RawMessage
+	var outputErr error
+	var blocked *guardrails.Violation
+	var budgetErr *BudgetError
+	turns, retries, guardRetries := 0, 0, 0
+	for {
+		if budgetErr = spend.check(); budgetErr != nil {
+			break
+		}
+		turns++
+		e.inflight.setPhase(inflightID, RequestPhaseProvider)
+		reportProgress(ctx, JobEvent{Type: JobEventTurn, Turn: turns})
+		providerResp, cached, err = e.chatCaptured(ctx, capture, targetAgent, providerName, provider, providerReq)
+		if e.variantFallback(ctx, targetAgent, assignment, err) {
+			// Later turns stay with the agent's own model
+			providerName = targetAgent.Config.Provider
+			provider = primary
+			providerReq.Model = targetAgent.Config.Model
+			providerResp, cached, err = e.chatCaptured(ctx, capture, targetAgent, providerName, provider, providerReq)
+		}
+		if fallback, ok := e.fallbackProvider(targetAgent, err); ok {
+			// Later turns stay with the fallback
+			providerName = targetAgent.Config.Fallback.Provider
+			provider = fallback
+			providerReq.Model = targetAgent.Config.Fallback.Model
+			providerResp, cached, err = e.chatCaptured(ctx, capture, targetAgent, providerName, provider, providerReq)
+		}
+		if err != nil {
+			break
+		}
+		cost := spend.add(providerReq.Model, providerResp.Usage, cached)
+		if !cached {
+			e.prometheus.countUsage(clusterName, agentName, providerName, providerReq.Model, providerResp.Usage, cost)
+		}
+		if len(providerResp.ToolUse) == 0 {
+			guarded := e.checkOutput(ctx, clusterName, targetAgent, req.ID, providerResp.Content)
+			violations = append(violations, guarded.Violations...)
+			if guarded.Blocked != nil {
+				if guarded.Blocked.Action == config.GuardrailRetry && guardRetries < targetAgent.Config.Guardrails.MaxRetries() {
+					guardRetries++
+					addUsage(&usage, providerResp.Usage)
+					providerReq.Messages = append(providerReq.Messages, guardrailCorrection(providerResp.Content, guarded.Blocked)...)
+					continue
+				}
+				blocked = guarded.Blocked
+				break
+			}
+			if guarded.Text != providerResp.Content {
+				// Cached responses are shared, so the redacted answer is a copy
+				redacted := *providerResp
+				redacted.Content = guarded.Text
+				providerResp = &redacted
+			}
+			
+			// An answer that breaks the output contract is sent back to be
+			// corrected
+			if targetAgent.Config.Output == nil {
+				break
+			}
+			if output, outputErr = parseOutput(targetAgent, providerResp.Content); outputErr == nil || retries >= outputMaxRetries(targetAgent) {
+				break
+			}
+			retries++
+			addUsage(&usage, providerResp.Usage)
+			providerReq.Messages = append(providerReq.Messages, outputCorrection(providerResp.Content, outputErr)...)
+			continue
+		}
+		if turns >= limit {
+			break
+		}
+		addUsage(&usage, providerResp.Usage)
+		
+		e.inflight.setPhase(inflightID, RequestPhaseTool)
+		reportProgress(ctx, JobEvent{Type: JobEventToolCalls, Turn: turns, Tools: toolNames(providerResp.ToolUse), Content: providerResp.Content})
+		ran, results := e.runToolUses(ctx, targetAgent, req, providerResp.ToolUse, capture)
+		toolUses = append(toolUses, ran...)
+		providerReq.Messages = append(providerReq.Messages, providers.Message{
+			Role:           "assistant",
+			Content:        providerResp.Content,
+			ToolUse:        providerResp.ToolUse,
+			ThinkingBlocks: providerResp.ThinkingBlocks,
+		})
+		providerReq.Messages = append(providerReq.Messages, results...)
+	}
+	e.chargeBudget(spend)
+	tally.serve(providerName, providerReq.Model)
+	if budgetErr != nil {
+		tally.fail()
+		e.budgetExceeded(targetAgent, req.ID, budgetErr)
+		return nil, budgetErr
+	}
+	if err != nil {
+		tally.fail()
+		
+		if e.inflight.cancelled(inflightID) {
+			return &agent.Response{
+				ID:    req.ID,
+				Error: "request cancelled",
+			}, nil
+		}
+		
+		e.recordHealth(targetAgent, err)
+		e.recordVariant(clusterName, targetAgent, assignment, nil, 0, 0, true)
+		return &agent.Response{
+			ID:    req.ID,
+			Error: fmt.Sprintf("provider error: %v", err),
+		}, nil
+	}
+	
+	addUsage(&usage, providerResp.Usage)
+	lease.countTokens(usage.TotalTokens)
+	
+	duration := time.Since(start)
+	e.recordFirstResponse(targetAgent, clusterName, agentName, waking, duration)
+	e.recordResponse(clusterName, targetAgent, req.ID, providerName, providerResp.Model, &usage, duration, assignment)
+	e.recordVariant(clusterName, targetAgent, assignment, &usage, spend.spent.Cost, duration, false)
+	e.recordHealth(targetAgent, nil)
+	
+	// Update agent activity
+	targetAgent.UpdateLastActivity()
+	
+	// Convert provider response to agent response
+	resp = &agent.Response{
+		ID:      req.ID,
+		Content: providerResp.Content,
+		Metadata: map[string]interface{}{
+			"model":    providerResp.Model,
+			"provider": providerName,
+			"usage":    &usage,
+			"cached":   cached,
+			"turns":    turns,
+			"memories": recalled,
+			"instance": lease.id(),
+		},
+		ToolUses:     toolUses,
+		ProviderMeta: providerResp.ProviderMeta,
+	}
+	
+	// Calls the model still wanted when the turns ran out are returned
+	// without results, for the caller to run
+	for _, toolUse := range providerResp.ToolUse {
+		resp.ToolUses = append(resp.ToolUses, agent.ToolUse{
+			ID:   toolUse.ID,
+			Name: toolUse.Name,
+			Args: toolUse.Args,
+		})
+	}
+	if len(providerResp.ToolUse) > 0 {
+		resp.Metadata["max_turns_reached"] = true
+	}
+	if targetAgent.Version > 0 {
+		resp.Metadata["agent_version"] = targetAgent.Version
+	}
+	if len(citations) > 0 {
+		resp.Metadata["citations"] = citations
 	}
-	
-	if err := engine.initializeProviders(); err != nil {
-		return nil, fmt.Errorf("failed to initialize providers: %w", err)
+	if router != "" {
+		resp.Metadata["router"] = router
+		resp.Metadata["agent"] = agentName
 	}
-	
-	return engine, nil
-}
-
-func (e *Engine) initializeProviders() error {
-	// Initialize Anthropic provider
-	if e.config.Providers.Anthropic != nil {
-		providerConfig := &providers.AnthropicConfig{
-			APIKey:  e.config.Providers.Anthropic.APIKey,
-			BaseURL: e.config.Providers.Anthropic.BaseURL,
-			Version: e.config.Providers.Anthropic.Version,
+	if len(violations) > 0 {
+		resp.Metadata["guardrails"] = violations
+	}
+	if spend.priced {
+		resp.Metadata["cost"] = spend.spent.Cost
+	}
+	if assignment != nil {
+		resp.Metadata["experiment"] = assignment.experiment
+		resp.Metadata["variant"] = assignment.variant
+		if assignment.fellBack {
+			resp.Metadata["variant_fallback"] = true
 		}
-		provider := providers.NewAnthropicProvider(providerConfig)
-		e.providerManager.RegisterProvider("anthropic", provider)
-		e.logger.Info("Registered Anthropic provider")
 	}
-	
-	// Initialize OpenAI provider
-	if e.config.Providers.OpenAI != nil {
-		providerConfig := &providers.OpenAIConfig{
-			APIKey:  e.config.Providers.OpenAI.APIKey,
-			BaseURL: e.config.Providers.OpenAI.BaseURL,
-			OrgID:   e.config.Providers.OpenAI.OrgID,
+	if targetAgent.Config.Output != nil {
+		resp.Output = output
+		resp.Metadata["output_retries"] = retries
+		if outputErr != nil && len(providerResp.ToolUse) == 0 {
+			resp.Error = fmt.Sprintf("output does not match schema: %v", outputErr)
 		}
-		provider := providers.NewOpenAIProvider(providerConfig)
-		e.providerManager.RegisterProvider("openai", provider)
-		e.logger.Info("Registered OpenAI provider")
+	}
+	if blocked != nil {
+		resp.Content = blocked.Message
+		resp.Output = nil
+		resp.Error = fmt.Sprintf("response blocked by guardrail %s: %s", blocked.Guardrail, blocked.Reason)
+	}
+	// A blocked response or one that misses its schema fails the request,
+	// as it does for events and traces
+	if requestFailure(resp, nil) != "" {
+		tally.fail()
+	} else {
+		tally.succeed(duration)
 	}
 	
-	// Initialize Gemini provider
-	if e.config.Providers.Gemini != nil {
-		providerConfig := &providers.GeminiConfig{
-			APIKey:    e.config.Providers.Gemini.APIKey,
-			ProjectID: e.config.Providers.Gemini.ProjectID,
-		}
-		provider := providers.NewGeminiProvider(providerConfig)
-		e.providerManager.RegisterProvider("gemini", provider)
-		e.logger.Info("Registered Gemini provider")
+	if req.IncludeThinking {
+		resp.Thinking = providerResp.Thinking
 	}
 	
-	return nil
+	if memoryEnabled(targetAgent, req) && resp.Content != "" && blocked == nil {
+		go e.rememberExchange(clusterName, targetAgent, provider, providerReq.Model, req, resp.Content)
+	}
+	
+	return resp, nil
 }
 
-func (e *Engine) DeployCluster(clusterConfig *config.AgentCluster) error {
-	e.mu.Lock()
-	defer e.mu.Unlock()
+// requestFailure is why a request failed, or empty if it succeeded.
+func requestFailure(resp *agent.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return resp.Error
+}
+
+func (e *Engine) chatWithCache(ctx context.Context, targetAgent *agent.Agent, providerName string, provider providers.Provider, providerReq *providers.ChatRequest) (*providers.ChatResponse, bool, error) {
+	if !targetAgent.Config.Cache.Enabled || e.responseCache == nil {
+		resp, err := e.callProvider(ctx, targetAgent, providerName, provider, providerReq)
+		return resp, false, err
+	}
 	
-	clusterName := clusterConfig.Metadata.Name
-	if _, exists := e.clusters[clusterName]; exists {
-		return fmt.Errorf("cluster %s already exists", clusterName)
+	key, err := providers.CacheKey(providerReq)
+	if err != nil {
+		e.logger.Warn("Failed to compute cache key", zap.Error(err))
+		resp, err := e.callProvider(ctx, targetAgent, providerName, provider, providerReq)
+		return resp, false, err
 	}
+	key = providerName + ":" + key
 	
-	cluster := &Cluster{
-		Name:      clusterName,
-		Config:    clusterConfig,
-		Agents:    make(map[string]*agent.Agent),
-		Status:    ClusterStatusPending,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	cachedResp, hit, err := e.responseCache.Get(ctx, key)
+	if err != nil {
+		e.logger.Warn("Response cache lookup failed", zap.Error(err))
+	}
+	if hit {
+		return cachedResp, true, nil
 	}
 	
-	e.clusters[clusterName] = cluster
-	e.metrics.ClustersTotal++
+	resp, err := e.callProvider(ctx, targetAgent, providerName, provider, providerReq)
+	if err != nil {
+		return nil, false, err
+	}
 	
-	e.logger.Info("Cluster deployed", zap.String("name", clusterName))
+	ttl := e.config.Cache.TTL
+	if targetAgent.Config.Cache.TTL > 0 {
+		ttl = targetAgent.Config.Cache.TTL
+	}
 	
-	// Start cluster in background
-	go e.startCluster(cluster)
+	if err := e.responseCache.Set(ctx, key, resp, ttl); err != nil {
+		e.logger.Warn("Failed to store response in cache", zap.Error(err))
+	}
 	
-	return nil
+	return resp, false, nil
 }
 
-func (e *Engine) startCluster(cluster *Cluster) {
-	cluster.mu.Lock()
-	cluster.Status = ClusterStatusRunning
-	cluster.UpdatedAt = time.Now()
-	cluster.mu.Unlock()
-	
-	e.logger.Info("Starting cluster", zap.String("name", cluster.Name))
-	
-	// Initialize agents for the cluster
-	for _, agentConfig := range cluster.Config.Spec.Agents {
-		if err := e.createAgent(cluster, &agentConfig); err != nil {
-			e.logger.Error("Failed to create agent", 
-				zap.String("cluster", cluster.Name),
-				zap.String("agent", agentConfig.Name),
-				zap.Error(err))
-			continue
-		}
+// callProvider sends a chat request to the provider, counting it in the
+// metrics if it fails.
+func (e *Engine) callProvider(ctx context.Context, targetAgent *agent.Agent, providerName string, provider providers.Provider, providerReq *providers.ChatRequest) (*providers.ChatResponse, error) {
+	ctx, span := e.startProviderSpan(ctx, targetAgent, providerName, providerReq.Model)
+	resp, err := provider.Chat(ctx, providerReq)
+	e.prometheus.countProviderError(targetAgent, providerName, providerReq.Model, err)
+	var usage *providers.Usage
+	if resp != nil {
+		usage = resp.Usage
 	}
-	
-	e.logger.Info("Cluster started", zap.String("name", cluster.Name))
+	endProviderSpan(span, usage, err)
+	return resp, err
 }
 
-func (e *Engine) createAgent(cluster *Cluster, agentConfig *config.Agent) error {
-	// Convert config to agent config
-	agentCfg := &agent.AgentConfig{
-		Provider:     agentConfig.Provider,
-		Model:        agentConfig.Model,
-		SystemPrompt: agentConfig.SystemPrompt,
-		Environment:  agentConfig.Environment,
-	}
-	
-	// Convert tools
-	for _, toolConfig := range agentConfig.Tools {
-		toolCfg := &tools.Config{
-			Type:     toolConfig.Type,
-			Name:     toolConfig.Name,
-			URL:      toolConfig.URL,
-			Endpoint: toolConfig.Endpoint,
-			Server:   toolConfig.Server,
-			Config:   toolConfig.Config,
-		}
-		
-		if toolConfig.Auth != nil {
-			toolCfg.Auth = &tools.AuthConfig{
-				Type:   toolConfig.Auth.Type,
-				Token:  toolConfig.Auth.Token,
-				APIKey: toolConfig.Auth.APIKey,
-				Secret: toolConfig.Auth.Secret,
-			}
-		}
-		
-		tool, err := tools.CreateTool(toolCfg)
+// streamProvider starts streaming a model call from the provider, counting
+// it in the metrics if it fails. The call's span is returned to be ended
+// with the stream, unless the call failed.
+func (e *Engine) streamProvider(ctx context.Context, targetAgent *agent.Agent, providerName string, provider providers.Provider, providerReq *providers.ChatRequest) (<-chan *providers.StreamChunk, trace.Span, error) {
+	ctx, span := e.startProviderSpan(ctx, targetAgent, providerName, providerReq.Model)
+	chunks, err := provider.Stream(ctx, providerReq)
+	e.prometheus.countProviderError(targetAgent, providerName, providerReq.Model, err)
+	if err != nil {
+		endProviderSpan(span, nil, err)
+	}
+	return chunks, span, err
+}
+
+func (e *Engine) StreamRequest(ctx context.Context, clusterName, agentName string, req *agent.Request) (_ <-chan *providers.StreamChunk, err error) {
+	targetAgent, provider, err := e.resolveAgent(clusterName, agentName)
+	if err != nil {
+		return nil, err
+	}
+	targetAgent, provider, _ = e.routeRequest(clusterName, targetAgent, provider, req)
+	agentName = targetAgent.GetName()
+	if targetAgent.Config.Guardrails.HasOutput() {
+		return e.streamGuarded(ctx, clusterName, agentName, req)
+	}
+	
+	ctx, span := e.startRequestSpan(ctx, clusterName, agentName, req, true)
+	defer func() {
+		// The span of a stream that started ends with the stream
 		if err != nil {
-			e.logger.Warn("Failed to create tool", 
-				zap.String("tool", toolConfig.Name),
-				zap.Error(err))
-			continue
+			endSpan(span, err.Error())
 		}
-		
-		e.toolManager.RegisterTool(tool)
+	}()
+	
+	primary := provider
+	providerName, model := targetAgent.Config.Provider, targetAgent.Config.Model
+	assignment := e.assignVariant(clusterName, targetAgent, provider, req)
+	if assignment != nil {
+		providerName, model, provider = assignment.providerName, assignment.model, assignment.provider
 	}
 	
-	// Create agent
-	newAgent, err := e.agentManager.CreateAgent(agentCfg)
+	waking := targetAgent.Wake()
+	
+	start := time.Now()
+	tally := e.startTally(clusterName, agentName, providerName, model)
+	if err := e.admitRequest(clusterName, targetAgent, req.ID); err != nil {
+		tally.fail()
+		return nil, err
+	}
+	
+	spend := e.startBudget(clusterName, targetAgent, model, req)
+	if budgetErr := spend.check(); budgetErr != nil {
+		tally.fail()
+		e.budgetExceeded(targetAgent, req.ID, budgetErr)
+		return nil, budgetErr
+	}
+	
+	_, err = e.checkInput(ctx, clusterName, targetAgent, req)
+	var providerReq *providers.ChatRequest
+	if err == nil {
+		providerReq, err = e.buildProviderRequest(targetAgent, req)
+	}
+	if err == nil {
+		providerReq.Model = model
+		providerReq.Stream = true
+		err = e.attachFiles(ctx, providerReq, req)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to create agent: %w", err)
+		tally.fail()
+		return nil, err
 	}
 	
-	newAgent.Name = agentConfig.Name
-	newAgent.ClusterName = cluster.Name
+	var cancel context.CancelFunc
+	if req.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
 	
-	cluster.mu.Lock()
-	cluster.Agents[agentConfig.Name] = newAgent
-	cluster.mu.Unlock()
+	inflightID := e.inflight.start(clusterName, agentName, req.ID, true, cancel)
+	e.publishRequestStarted(clusterName, targetAgent, req.ID, true)
+	capture := e.startTranscript(clusterName, targetAgent, req, providerReq, true)
+	lease, err := e.instances.acquire(ctx, clusterName, targetAgent, req.Priority)
+	if err != nil {
+		cancel()
+		e.inflight.finish(inflightID)
+		tally.fail()
+		e.publishRequestEnded(clusterName, targetAgent, req.ID, true, start, err.Error())
+		e.finishTranscript(capture, err.Error())
+		return nil, err
+	}
+	waking = waking || lease.coldStart
+	e.inflight.setPhase(inflightID, RequestPhaseProvider)
 	
-	e.metrics.AgentsTotal++
+	providerChunks, providerSpan, err := e.streamCaptured(ctx, capture, targetAgent, providerName, provider, providerReq)
+	if e.variantFallback(ctx, targetAgent, assignment, err) {
+		providerName = targetAgent.Config.Provider
+		providerReq.Model = targetAgent.Config.Model
+		providerChunks, providerSpan, err = e.streamCaptured(ctx, capture, targetAgent, providerName, primary, providerReq)
+	}
+	if fallback, ok := e.fallbackProvider(targetAgent, err); ok {
+		providerName = targetAgent.Config.Fallback.Provider
+		providerReq.Model = targetAgent.Config.Fallback.Model
+		providerChunks, providerSpan, err = e.streamCaptured(ctx, capture, targetAgent, providerName, fallback, providerReq)
+	}
+	tally.serve(providerName, providerReq.Model)
+	if err != nil {
+		lease.release()
+		cancel()
+		e.inflight.finish(inflightID)
+		tally.fail()
+		e.recordHealth(targetAgent, err)
+		e.recordVariant(clusterName, targetAgent, assignment, nil, 0, 0, true)
+		e.publishRequestEnded(clusterName, targetAgent, req.ID, true, start, "provider error: "+err.Error())
+		e.finishTranscript(capture, "provider error: "+err.Error())
+		return nil, fmt.Errorf("provider error: %w", err)
+	}
 	
-	e.logger.Info("Agent created", 
-		zap.String("cluster", cluster.Name),
-		zap.String("agent", agentConfig.Name),
-		zap.String("provider", agentConfig.Provider))
+	chunks := make(chan *providers.StreamChunk, 10)
+	go func() {
+		defer close(chunks)
+		defer cancel()
+		defer e.inflight.finish(inflightID)
+		defer lease.release()
+		
+		failed := false
+		first := true
+		var usage *providers.Usage
+		var providerErr error
+		var completion string
+	forward:
+		for chunk := range providerChunks {
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if chunk.Content != "" {
+				completion = chunk.Content
+			}
+			if chunk.Error != "" {
+				failed = true
+				providerErr = errors.New(chunk.Error)
+				e.prometheus.countProviderError(targetAgent, providerName, providerReq.Model, providerErr)
+			} else if first {
+				first = false
+				e.recordFirstResponse(targetAgent, clusterName, agentName, waking, time.Since(start))
+			}
+			
+			if !req.IncludeThinking && (chunk.Thinking != "" || chunk.ThinkingDelta != "") {
+				// Drop reasoning-only chunks entirely when the caller opted out
+				if chunk.Delta == "" && !chunk.Done && chunk.Error == "" {
+					continue
+				}
+				stripped := *chunk
+				stripped.Thinking = ""
+				stripped.ThinkingDelta = ""
+				chunk = &stripped
+			}
+			
+			select {
+			case <-ctx.Done():
+				failed = true
+				break forward
+			case chunks <- chunk:
+			}
+		}
+		endProviderSpan(providerSpan, usage, providerErr)
+		
+		if failed {
+			tally.fail()
+		} else {
+			tally.succeed(time.Since(start))
+		}
+		
+		cost := spend.add(providerReq.Model, usage, false)
+		e.prometheus.countUsage(clusterName, agentName, providerName, providerReq.Model, usage, cost)
+		e.chargeBudget(spend)
+		if usage != nil {
+			lease.countTokens(usage.TotalTokens)
+		}
+		// A stream the client left says nothing of the agent's health, nor
+		// of its experiment variant
+		if providerErr != nil || !failed {
+			e.recordHealth(targetAgent, providerErr)
+			e.recordVariant(clusterName, targetAgent, assignment, usage, spend.spent.Cost, time.Since(start), failed)
+		}
+		if !failed {
+			e.recordResponse(clusterName, targetAgent, req.ID, providerName, providerReq.Model, usage, time.Since(start), assignment)
+		}
+		failure := ""
+		if providerErr != nil {
+			failure = providerErr.Error()
+		} else if failed {
+			failure = "stream ended before it finished"
+		}
+		e.publishRequestEnded(clusterName, targetAgent, req.ID, true, start, failure)
+		endSpan(span, failure)
+		capture.streamed(completion, usage, providerErr)
+		e.finishTranscript(capture, failure)
+		
+		targetAgent.UpdateLastActivity()
+	}()
 	
-	return nil
+	return chunks, nil
 }
 
-func (e *Engine) ProcessRequest(clusterName, agentName string, req *agent.Request) (*agent.Response, error) {
+func (e *Engine) resolveAgent(clusterName, agentName string) (*agent.Agent, providers.Provider, error) {
 	cluster, err := e.getCluster(clusterName)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	
 	cluster.mu.RLock()
@@ -235,24 +1913,71 @@ func (e *Engine) ProcessRequest(clusterName, agentName string, req *agent.Reques
 	cluster.mu.RUnlock()
 	
 	if !exists {
-		return nil, fmt.Errorf("agent %s not found in cluster %s", agentName, clusterName)
+		return nil, nil, fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	targetAgent = e.versions.route(clusterName, targetAgent)
+	if targetAgent.GetStatus() == agent.StatusFailed {
+		return nil, nil, fmt.Errorf("%w: %s failed: %s", ErrAgentUnavailable, agentName, targetAgent.GetErrorMessage())
+	}
+	
+	// The policy may have been tightened since the agent was deployed
+	if err := e.config.Policy.CheckModel(targetAgent.Config.Provider, targetAgent.Config.Model); err != nil {
+		return nil, nil, fmt.Errorf("agent %s: %w", agentName, err)
+	}
+	
+	// Check if provider is available, preferring the namespace's own keys
+	provider, exists, err := e.providerFor(cluster.Config.Metadata.Namespace, targetAgent.Config.Provider)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !exists {
+		return nil, nil, fmt.Errorf("provider %s not available", targetAgent.Config.Provider)
+	}
+	
+	return targetAgent, provider, nil
+}
+
+// fallbackProvider returns the agent's fallback provider when err shows that
+// the primary provider has no valid credentials left.
+func (e *Engine) fallbackProvider(targetAgent *agent.Agent, err error) (providers.Provider, bool) {
+	fallback := targetAgent.Config.Fallback
+	if fallback.Provider == "" || !errors.Is(err, providers.ErrCredentialsRevoked) {
+		return nil, false
 	}
 	
-	// Check if provider is available
-	provider, exists := e.providerManager.GetProvider(targetAgent.Config.Provider)
+	if err := e.config.Policy.CheckModel(fallback.Provider, fallback.Model); err != nil {
+		e.logger.Warn("Fallback provider blocked by policy",
+			zap.String("agent", targetAgent.GetName()),
+			zap.Error(err))
+		return nil, false
+	}
+	
+	provider, exists, err := e.providerFor(e.clusterNamespace(targetAgent.ClusterName), fallback.Provider)
+	if err != nil {
+		e.logger.Warn("Fallback provider unavailable",
+			zap.String("agent", targetAgent.GetName()),
+			zap.Error(err))
+		return nil, false
+	}
 	if !exists {
-		return nil, fmt.Errorf("provider %s not available", targetAgent.Config.Provider)
+		return nil, false
 	}
 	
-	start := time.Now()
-	e.metrics.mu.Lock()
-	e.metrics.RequestsTotal++
-	e.metrics.mu.Unlock()
+	e.logger.Warn("Failing over to fallback provider",
+		zap.String("agent", targetAgent.GetName()),
+		zap.String("provider", targetAgent.Config.Provider),
+		zap.String("fallback", fallback.Provider),
+		zap.String("model", fallback.Model))
 	
+	return provider, true
+}
+
+func (e *Engine) buildProviderRequest(targetAgent *agent.Agent, req *agent.Request) (*providers.ChatRequest, error) {
 	// Convert agent request to provider request
 	providerReq := &providers.ChatRequest{
-		Model:    targetAgent.Config.Model,
-		Messages: make([]providers.Message, len(req.Messages)),
+		Model:          targetAgent.Config.Model,
+		Messages:       make([]providers.Message, len(req.Messages)),
+		ThinkingBudget: targetAgent.Config.ThinkingBudget,
 	}
 	
 	for i, msg := range req.Messages {
@@ -263,55 +1988,101 @@ func (e *Engine) ProcessRequest(clusterName, agentName string, req *agent.Reques
 	}
 	
 	// Add system prompt if available
-	if targetAgent.Config.SystemPrompt != "" {
+	systemPrompt, err := renderSystemPrompt(targetAgent, req)
+	if err != nil {
+		return nil, err
+	}
+	if systemPrompt != "" {
 		systemMsg := providers.Message{
 			Role:    "system",
-			Content: targetAgent.Config.SystemPrompt,
+			Content: systemPrompt,
 		}
 		providerReq.Messages = append([]providers.Message{systemMsg}, providerReq.Messages...)
 	}
-	
-	ctx := context.Background()
-	if req.Timeout > 0 {
-		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, req.Timeout)
-		defer cancel()
+	if err := setResponseSchema(targetAgent, providerReq); err != nil {
+		return nil, err
 	}
 	
-	// Call provider
-	providerResp, err := provider.Chat(ctx, providerReq)
-	if err != nil {
-		e.metrics.mu.Lock()
-		e.metrics.RequestsFailed++
-		e.metrics.mu.Unlock()
-		
-		return &agent.Response{
-			ID:    req.ID,
-			Error: fmt.Sprintf("provider error: %v", err),
-		}, nil
+	// Advertise the agent's tools, limited to those named in the request
+	allowed := make(map[string]bool, len(req.Tools))
+	for _, name := range req.Tools {
+		allowed[name] = true
+	}
+	for _, definition := range targetAgent.ToolDefinitions() {
+		if len(allowed) > 0 && !allowed[definition.Name] {
+			continue
+		}
+		providerReq.Tools = append(providerReq.Tools, providers.Tool{
+			Name:        definition.Name,
+			Description: definition.Description,
+			Parameters:  definition.Parameters,
+		})
 	}
 	
-	duration := time.Since(start)
-	e.metrics.mu.Lock()
-	e.metrics.RequestsSucceeded++
-	e.metrics.AverageResponseTime = (e.metrics.AverageResponseTime + duration) / 2
-	e.metrics.mu.Unlock()
+	return providerReq, nil
+}
+
+// attachFiles loads the files that the request's messages reference from the
+// file store into the provider request.
+func (e *Engine) attachFiles(ctx context.Context, providerReq *providers.ChatRequest, req *agent.Request) error {
+	// The system prompt, if any, comes before the request's messages
+	offset := len(providerReq.Messages) - len(req.Messages)
 	
-	// Update agent activity
-	targetAgent.UpdateLastActivity()
+	for i, msg := range req.Messages {
+		for _, attachment := range msg.Attachments {
+			file, data, err := e.files.Get(ctx, attachment.FileID)
+			if err != nil {
+				return fmt.Errorf("failed to load attachment: %w", err)
+			}
+			
+			providerMsg := &providerReq.Messages[offset+i]
+			providerMsg.Attachments = append(providerMsg.Attachments, providers.Attachment{
+				Name:     file.Name,
+				MimeType: file.MimeType,
+				Data:     data,
+			})
+		}
+	}
 	
-	// Convert provider response to agent response
-	resp := &agent.Response{
-		ID:      req.ID,
-		Content: providerResp.Content,
-		Metadata: map[string]interface{}{
-			"model":    providerResp.Model,
-			"provider": targetAgent.Config.Provider,
-			"usage":    providerResp.Usage,
-		},
+	return nil
+}
+
+func (e *Engine) UseProviderMiddleware(middleware ...providers.ProviderMiddleware) {
+	e.providerManager.Use(middleware...)
+}
+
+// UseToolMiddleware wraps every tool call made for any agent.
+func (e *Engine) UseToolMiddleware(middleware ...tools.ToolMiddleware) {
+	e.toolManager.Use(middleware...)
+}
+
+// ToolCall is a call of an agent's tool made through the API. A dry run
+// returns what would be called instead of calling it.
+type ToolCall struct {
+	Tool           string                 `json:"tool" binding:"required"`
+	Args           map[string]interface{} `json:"args,omitempty"`
+	ConversationID string                 `json:"conversation_id,omitempty"`
+	DryRun         bool                   `json:"dry_run,omitempty"`
+}
+
+// ExecuteTool calls one of an agent's tools as the model would, through
+// the same validation, limits and middleware.
+func (e *Engine) ExecuteTool(ctx context.Context, agentID string, call *ToolCall) (*tools.Result, error) {
+	if _, err := e.agentManager.GetAgent(agentID); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	key := tools.Key{Scope: agentID, Name: call.Tool}
+	if _, exists := e.toolManager.GetTool(key); !exists {
+		return nil, fmt.Errorf("%w: %s of agent %s", ErrToolNotFound, call.Tool, agentID)
 	}
 	
-	return resp, nil
+	if call.ConversationID != "" {
+		ctx = tools.WithConversation(ctx, call.ConversationID)
+	}
+	if call.DryRun {
+		ctx = tools.WithDryRun(ctx)
+	}
+	return e.toolManager.Execute(ctx, key, call.Args)
 }
 
 func (e *Engine) getCluster(name string) (*Cluster, error) {
@@ -320,7 +2091,7 @@ func (e *Engine) getCluster(name string) (*Cluster, error) {
 	
 	cluster, exists := e.clusters[name]
 	if !exists {
-		return nil, fmt.Errorf("cluster not found: %s", name)
+		return nil, fmt.Errorf("%w: %s", ErrClusterNotFound, name)
 	}
 	
 	return cluster, nil
@@ -338,6 +2109,35 @@ func (e *Engine) ListClusters() []*Cluster {
 	return clusters
 }
 
+// RunningAgent is an agent of a running cluster.
+type RunningAgent struct {
+	Cluster  string
+	Name     string
+	Provider string
+	Model    string
+}
+
+// RunningAgents lists the agents of every running cluster, read under each
+// cluster's lock.
+func (e *Engine) RunningAgents() []RunningAgent {
+	var agents []RunningAgent
+	for _, cluster := range e.ListClusters() {
+		cluster.mu.RLock()
+		if cluster.Status == ClusterStatusRunning {
+			for name, a := range cluster.Agents {
+				agents = append(agents, RunningAgent{
+					Cluster:  cluster.Name,
+					Name:     name,
+					Provider: a.Config.Provider,
+					Model:    a.Config.Model,
+				})
+			}
+		}
+		cluster.mu.RUnlock()
+	}
+	return agents
+}
+
 func (e *Engine) GetClusterStatus(name string) (*Cluster, error) {
 	return e.getCluster(name)
 }
@@ -351,27 +2151,54 @@ func (e *Engine) StopCluster(name string) error {
 	cluster.mu.Lock()
 	defer cluster.mu.Unlock()
 	
+	if e.stopCluster(cluster) {
+		e.saveCluster(cluster)
+	}
+	return nil
+}
+
+// stopCluster stops a cluster's agents, and reports whether it was not
+// stopped already. The cluster's lock must be held.
+func (e *Engine) stopCluster(cluster *Cluster) bool {
 	if cluster.Status == ClusterStatusStopped {
-		return nil
+		return false
 	}
+	name := cluster.Name
 	
 	// Stop all agents in the cluster
 	for _, agent := range cluster.Agents {
 		if err := e.agentManager.StopAgent(agent.ID); err != nil {
 			e.logger.Warn("Failed to stop agent", 
-				zap.String("agent", agent.Name),
+				zap.String("agent", agent.GetName()),
 				zap.Error(err))
 		}
 	}
+	e.stopSchedules(name)
+	e.abortRollouts(name)
 	
 	cluster.Status = ClusterStatusStopped
 	cluster.UpdatedAt = time.Now()
 	
 	e.logger.Info("Cluster stopped", zap.String("name", name))
-	return nil
+	return true
 }
 
-func (e *Engine) DeleteCluster(name string) error {
+func (e *Engine) DeleteCluster(name string, pre *Precondition) error {
+	cluster, err := e.getCluster(name)
+	if err != nil {
+		return err
+	}
+	
+	// Bumping the version fails any concurrent write that read the cluster
+	// before it was deleted
+	cluster.mu.Lock()
+	if err := checkPrecondition(cluster, pre); err != nil {
+		cluster.mu.Unlock()
+		return err
+	}
+	e.bumpResourceVersion(cluster)
+	cluster.mu.Unlock()
+	
 	if err := e.StopCluster(name); err != nil {
 		return err
 	}
@@ -386,49 +2213,98 @@ func (e *Engine) DeleteCluster(name string) error {
 	
 	// Delete all agents
 	for _, agent := range cluster.Agents {
-		if err := e.agentManager.DeleteAgent(agent.ID); err != nil {
-			e.logger.Warn("Failed to delete agent", 
-				zap.String("agent", agent.Name),
-				zap.Error(err))
-		}
+		e.removeAgent(agent)
 	}
 	
 	delete(e.clusters, name)
-	e.metrics.ClustersTotal--
+	e.versions.forget(name)
+	e.instances.forget(name)
+	cluster.mu.RLock()
+	e.removeClusterState(cluster)
+	e.publishClusterEvent(agent.EventClusterDeleted, cluster, nil)
+	cluster.mu.RUnlock()
+	e.releaseCluster(name)
+	e.metrics.forgetCluster(name)
+	e.prometheus.forgetCluster(name)
 	
 	e.logger.Info("Cluster deleted", zap.String("name", name))
 	return nil
 }
 
-func (e *Engine) GetMetrics() *Metrics {
-	e.metrics.mu.RLock()
-	defer e.metrics.mu.RUnlock()
-	
-	metrics := *e.metrics
-	return &metrics
-}
-
+// Close shuts the engine down. Drain it first for the requests and jobs
+// it runs to finish; those still running are cancelled. Clusters are saved
+// as they were, to be started again when the server restarts, and the
+// jobs the shutdown interrupted are saved as failed.
 func (e *Engine) Close() error {
 	e.logger.Info("Shutting down engine")
+	e.publishEvent(agent.Event{Type: agent.EventEngineStopping})
+	for _, cluster := range e.ListClusters() {
+		cluster.mu.Lock()
+		e.saveCluster(cluster)
+		cluster.mu.Unlock()
+	}
+	close(e.done)
 	
-	// Stop all clusters
-	for name := range e.clusters {
-		if err := e.StopCluster(name); err != nil {
-			e.logger.Warn("Failed to stop cluster during shutdown", 
-				zap.String("cluster", name),
-				zap.Error(err))
-		}
+	for _, cluster := range e.ListClusters() {
+		cluster.mu.Lock()
+		e.stopCluster(cluster)
+		cluster.mu.Unlock()
+	}
+	e.waitForJobs()
+	e.flushEvents()
+	e.flushTraces()
+	if err := e.deadLetters.close(); err != nil {
+		e.logger.Warn("Failed to close dead letter file", zap.Error(err))
 	}
 	
 	// Close providers
 	if err := e.providerManager.Close(); err != nil {
 		e.logger.Warn("Failed to close providers", zap.Error(err))
 	}
+	e.tenantProviders.close()
+	
+	if err := e.feedback.close(); err != nil {
+		e.logger.Warn("Failed to close feedback store", zap.Error(err))
+	}
+	
+	if err := e.toolAudit.close(); err != nil {
+		e.logger.Warn("Failed to close tool audit log", zap.Error(err))
+	}
+	if e.transcripts != nil {
+		if err := e.transcripts.close(); err != nil {
+			e.logger.Warn("Failed to close transcripts", zap.Error(err))
+		}
+	}
 	
 	// Close tools
 	if err := e.toolManager.Close(); err != nil {
 		e.logger.Warn("Failed to close tools", zap.Error(err))
 	}
 	
+	if e.responseCache != nil {
+		if err := e.responseCache.Close(); err != nil {
+			e.logger.Warn("Failed to close response cache", zap.Error(err))
+		}
+	}
+	
+	if e.files != nil {
+		if err := e.files.Close(); err != nil {
+			e.logger.Warn("Failed to close file store", zap.Error(err))
+		}
+	}
+	
+	if e.sessions != nil {
+		if err := e.sessions.Close(); err != nil {
+			e.logger.Warn("Failed to close session store", zap.Error(err))
+		}
+	}
+	
+	if e.state != nil {
+		e.leaveDistribution()
+		if err := e.state.close(); err != nil {
+			e.logger.Warn("Failed to close state store", zap.Error(err))
+		}
+	}
+	
 	return nil
 }
\ No newline at end of file
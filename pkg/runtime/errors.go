@@ -0,0 +1,64 @@
+package runtime
+
+import "errors"
+
+var (
+	ErrClusterNotFound  = errors.New("cluster not found")
+	ErrAgentNotFound    = errors.New("agent not found")
+	ErrAgentExists      = errors.New("agent already exists")
+	ErrRequestNotFound  = errors.New("request not found")
+	ErrResponseNotFound = errors.New("response not found")
+	ErrConflict         = errors.New("conflict")
+	ErrFeatureNotFound  = errors.New("feature flag not found")
+	ErrToolNotFound     = errors.New("tool not found")
+	ErrMemoryNotFound   = errors.New("memory not found")
+	// ErrKnowledgeDisabled is returned for knowledge base calls on agents
+	// without one
+	ErrKnowledgeDisabled = errors.New("knowledge base not enabled")
+	// ErrMissingPromptVariable is returned for requests that do not supply
+	// a required system prompt variable
+	ErrMissingPromptVariable = errors.New("missing prompt variable")
+	ErrWorkflowNotFound      = errors.New("workflow not found")
+	ErrWorkflowRunNotFound   = errors.New("workflow run not found")
+	ErrScheduleNotFound      = errors.New("schedule not found")
+	ErrScheduleRunNotFound   = errors.New("schedule run not found")
+	ErrJobNotFound           = errors.New("job not found")
+	ErrAgentVersionNotFound  = errors.New("agent version not found")
+	ErrRolloutNotFound       = errors.New("rollout not found")
+	ErrTemplateNotFound      = errors.New("template not found")
+	ErrTranscriptNotFound    = errors.New("transcript not found")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	// ErrInvalidTemplate is returned for cluster templates that are not
+	// valid, and for instances of them given wrong or missing values
+	ErrInvalidTemplate = errors.New("invalid template")
+	// ErrJobQueueFull is returned for jobs submitted while the queue is
+	// full
+	ErrJobQueueFull = errors.New("job queue full")
+	// ErrGuardrailBlocked is returned for requests an input guardrail
+	// blocks
+	ErrGuardrailBlocked = errors.New("blocked by guardrail")
+	// ErrBudgetExceeded is wrapped by the *BudgetError returned for
+	// requests that ran out of budget
+	ErrBudgetExceeded = errors.New("budget exceeded")
+	// ErrQuotaExceeded is wrapped by the *QuotaError returned for requests
+	// to a cluster or namespace that used up its request, token or cost
+	// quota
+	ErrQuotaExceeded = errors.New("quota exceeded")
+	// ErrAgentQuotaExceeded is wrapped by the *QuotaError returned for
+	// cluster writes that would go past an agent quota
+	ErrAgentQuotaExceeded = errors.New("agent quota exceeded")
+	// ErrAgentUnavailable is returned for requests to agents that failed
+	// their health checks and have not been restarted
+	ErrAgentUnavailable = errors.New("agent unavailable")
+	// ErrAgentBusy is returned for requests that waited for an instance of
+	// their agent until the queue timeout
+	ErrAgentBusy = errors.New("agent busy")
+	// ErrAgentQueueFull is wrapped by the *QueueFullError returned for
+	// requests that found their agent's queue full
+	ErrAgentQueueFull = errors.New("agent queue full")
+	// ErrDraining is returned for work submitted while the engine drains
+	ErrDraining = errors.New("draining")
+	// ErrRedactedCredential is returned for imported specs that still hold
+	// credentials redacted by an export
+	ErrRedactedCredential = errors.New("redacted credential")
+)
@@ -0,0 +1,138 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/events"
+	"go.uber.org/zap"
+)
+
+// eventFlushTimeout bounds how long Close waits for the event sinks to
+// send the events still queued.
+const eventFlushTimeout = 5 * time.Second
+
+// initializeEvents starts the event bus with the configured sinks, and has
+// the agent manager publish to it.
+func (e *Engine) initializeEvents() error {
+	e.events = events.NewBus(e.logger)
+	for i := range e.config.Events.Sinks {
+		sinkConfig := &e.config.Events.Sinks[i]
+		sink, err := newEventSink(sinkConfig, e.logger)
+		if err != nil {
+			return fmt.Errorf("sink %s: %w", sinkConfig.SinkName(), err)
+		}
+		e.events.AddSink(sink, events.SinkOptions{
+			Name:       sinkConfig.SinkName(),
+			Type:       sinkConfig.Type,
+			Events:     sinkConfig.Events,
+			BufferSize: sinkConfig.BufferSize,
+			BatchSize:  sinkConfig.BatchSize,
+			Timeout:    sinkConfig.Timeout,
+		})
+		e.logger.Info("Added event sink",
+			zap.String("name", sinkConfig.SinkName()),
+			zap.String("type", sinkConfig.Type))
+	}
+	e.agentManager.SetEventPublisher(e.publishEvent)
+	return nil
+}
+
+func newEventSink(sinkConfig *config.EventSinkConfig, logger *zap.Logger) (events.Sink, error) {
+	switch sinkConfig.Type {
+	case "log":
+		return events.NewLogSink(logger), nil
+	case "webhook":
+		return events.NewWebhookSink(&events.WebhookConfig{
+			URL:     sinkConfig.URL,
+			Headers: sinkConfig.Headers,
+		})
+	case "nats":
+		return events.NewNATSSink(&events.NATSConfig{
+			URL:     sinkConfig.URL,
+			Subject: sinkConfig.Subject,
+			Token:   sinkConfig.Token,
+		})
+	case "kafka":
+		return events.NewKafkaSink(&events.KafkaConfig{
+			URL:     sinkConfig.URL,
+			Topic:   sinkConfig.Topic,
+			Headers: sinkConfig.Headers,
+		})
+	default:
+		return nil, fmt.Errorf("unsupported event sink type: %s", sinkConfig.Type)
+	}
+}
+
+// publishEvent sends an event to the event sinks.
+func (e *Engine) publishEvent(event agent.Event) {
+	e.events.Publish(event)
+}
+
+// EventSinks reports on the sinks events are sent to.
+func (e *Engine) EventSinks() []events.SinkStatus {
+	return e.events.Status()
+}
+
+// flushEvents sends the events still queued, up to eventFlushTimeout, and
+// closes the sinks.
+func (e *Engine) flushEvents() {
+	ctx, cancel := context.WithTimeout(context.Background(), eventFlushTimeout)
+	defer cancel()
+	if err := e.events.Close(ctx); err != nil {
+		e.logger.Warn("Shut down before events were sent", zap.Error(err))
+	}
+}
+
+// publishClusterEvent publishes an event about a cluster, with data added
+// to the cluster's name and namespace; the caller holds the cluster's lock.
+func (e *Engine) publishClusterEvent(eventType agent.EventType, cluster *Cluster, data map[string]interface{}) {
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+	data["cluster"] = cluster.Name
+	data["namespace"] = cluster.Config.Metadata.Namespace
+	data["resource_version"] = cluster.Config.Metadata.ResourceVersion
+	e.publishEvent(agent.Event{
+		Type: eventType,
+		Data: data,
+	})
+}
+
+// publishRequestStarted publishes the start of a request to an agent.
+func (e *Engine) publishRequestStarted(clusterName string, a *agent.Agent, requestID string, stream bool) {
+	e.publishEvent(agent.Event{
+		Type:    agent.EventRequestStarted,
+		AgentID: a.ID,
+		Data: map[string]interface{}{
+			"cluster":    clusterName,
+			"agent":      a.GetName(),
+			"request_id": requestID,
+			"stream":     stream,
+		},
+	})
+}
+
+// publishRequestEnded publishes the end of a request to an agent that
+// started at start. failure says why it failed, and is empty if it did not.
+func (e *Engine) publishRequestEnded(clusterName string, a *agent.Agent, requestID string, stream bool, start time.Time, failure string) {
+	data := map[string]interface{}{
+		"cluster":     clusterName,
+		"agent":       a.GetName(),
+		"request_id":  requestID,
+		"stream":      stream,
+		"success":     failure == "",
+		"duration_ms": time.Since(start).Milliseconds(),
+	}
+	if failure != "" {
+		data["error"] = failure
+	}
+	e.publishEvent(agent.Event{
+		Type:    agent.EventRequestEnded,
+		AgentID: a.ID,
+		Data:    data,
+	})
+}
\ No newline at end of file
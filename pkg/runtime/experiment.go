@@ -0,0 +1,254 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+// VariantStats is what the requests sent to one variant of an experiment
+// used, for comparing the variants' cost and reliability.
+type VariantStats struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Weight   int    `json:"weight"`
+	Requests int64  `json:"requests"`
+	Failures int64  `json:"failures"`
+	// Fallbacks counts the requests that failed on the variant and were
+	// answered by the agent's own model
+	Fallbacks int64 `json:"fallbacks"`
+	Tokens    int64 `json:"tokens"`
+	// Cost is in US dollars, for models with a known price
+	Cost         float64 `json:"cost"`
+	AvgLatencyMs int64   `json:"avg_latency_ms"`
+}
+
+// ExperimentStatus is an agent's experiment with what each variant has
+// served since the server started.
+type ExperimentStatus struct {
+	Cluster    string                  `json:"cluster"`
+	Agent      string                  `json:"agent"`
+	Experiment *config.AgentExperiment `json:"experiment,omitempty"`
+	Variants   []VariantStats          `json:"variants"`
+}
+
+// variantAssignment is the variant of its agent's experiment a request was
+// sent to.
+type variantAssignment struct {
+	experiment   string
+	variant      string
+	providerName string
+	model        string
+	provider     providers.Provider
+	// own is set when the variant is the agent's own model
+	own bool
+	// fellBack is set once the request has moved to the agent's own model
+	fellBack bool
+}
+
+// variantTotals is what a variant has served; latency is the sum over the
+// requests that succeeded.
+type variantTotals struct {
+	VariantStats
+	latency time.Duration
+}
+
+// experimentStats counts what each variant of each agent's experiment has
+// served, by cluster, agent, experiment and variant.
+type experimentStats struct {
+	variants map[string]*variantTotals
+	mu       sync.Mutex
+}
+
+func newExperimentStats() *experimentStats {
+	return &experimentStats{variants: make(map[string]*variantTotals)}
+}
+
+func variantKey(clusterName, agentName, experiment, variant string) string {
+	return clusterName + "/" + agentName + "/" + experiment + "/" + variant
+}
+
+func (s *experimentStats) record(clusterName, agentName string, a *variantAssignment, usage *providers.Usage, cost float64, latency time.Duration, failed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	key := variantKey(clusterName, agentName, a.experiment, a.variant)
+	totals, ok := s.variants[key]
+	if !ok {
+		totals = &variantTotals{}
+		s.variants[key] = totals
+	}
+	
+	totals.Requests++
+	if a.fellBack {
+		totals.Fallbacks++
+	}
+	if failed {
+		totals.Failures++
+		return
+	}
+	if usage != nil {
+		totals.Tokens += int64(usage.TotalTokens)
+	}
+	totals.Cost += cost
+	totals.latency += latency
+}
+
+func (s *experimentStats) get(clusterName, agentName, experiment, variant string) VariantStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	totals, ok := s.variants[variantKey(clusterName, agentName, experiment, variant)]
+	if !ok {
+		return VariantStats{}
+	}
+	stats := totals.VariantStats
+	if succeeded := stats.Requests - stats.Failures; succeeded > 0 {
+		stats.AvgLatencyMs = totals.latency.Milliseconds() / succeeded
+	}
+	return stats
+}
+
+// assignVariant picks the variant of the agent's experiment a request goes
+// to. Requests in a session, or with the same value for the experiment's
+// sticky key, always get the same variant; others are spread at random by
+// weight. It returns nil for agents without an experiment, and when the
+// variant's model cannot be used, leaving the request on the agent's own.
+func (e *Engine) assignVariant(clusterName string, targetAgent *agent.Agent, provider providers.Provider, req *agent.Request) *variantAssignment {
+	experiment := targetAgent.Config.Experiment
+	if experiment == nil {
+		return nil
+	}
+	
+	variant := pickVariant(experiment, stickyKey(experiment, req))
+	providerName, model := variant.Target(targetAgent.Config.Provider, targetAgent.Config.Model)
+	assignment := &variantAssignment{
+		experiment:   experiment.Name,
+		variant:      variant.Name,
+		providerName: providerName,
+		model:        model,
+		provider:     provider,
+		own:          providerName == targetAgent.Config.Provider && model == targetAgent.Config.Model,
+	}
+	if assignment.own {
+		return assignment
+	}
+	
+	// The policy may have been tightened since the agent was deployed
+	err := e.config.Policy.CheckModel(providerName, model)
+	if err == nil {
+		var exists bool
+		assignment.provider, exists, err = e.providerFor(e.clusterNamespace(clusterName), providerName)
+		if err == nil && !exists {
+			err = fmt.Errorf("provider %s not available", providerName)
+		}
+	}
+	if err != nil {
+		e.logger.Warn("Experiment variant unavailable",
+			zap.String("cluster", clusterName),
+			zap.String("agent", targetAgent.GetName()),
+			zap.String("experiment", experiment.Name),
+			zap.String("variant", variant.Name),
+			zap.Error(err))
+		return nil
+	}
+	return assignment
+}
+
+// stickyKey returns what keeps a request on the variant of the requests
+// before it, or "" if nothing does.
+func stickyKey(experiment *config.AgentExperiment, req *agent.Request) string {
+	if req.SessionID != "" {
+		return "session:" + req.SessionID
+	}
+	if experiment.StickyKey != "" {
+		if value, ok := req.Context[experiment.StickyKey]; ok && value != nil {
+			return fmt.Sprintf("%s:%v", experiment.StickyKey, value)
+		}
+	}
+	return ""
+}
+
+// pickVariant picks a variant by weight, the same one every time for the
+// same key.
+func pickVariant(experiment *config.AgentExperiment, key string) *config.ExperimentVariant {
+	var n int
+	if key == "" {
+		n = rand.Intn(experiment.TotalWeight())
+	} else {
+		h := fnv.New64a()
+		h.Write([]byte(experiment.Name + "\x00" + key))
+		n = int(h.Sum64() % uint64(experiment.TotalWeight()))
+	}
+	
+	for i := range experiment.Variants {
+		n -= experiment.Variants[i].Weight
+		if n < 0 {
+			return &experiment.Variants[i]
+		}
+	}
+	return &experiment.Variants[len(experiment.Variants)-1]
+}
+
+// variantFallback reports whether a request that failed on its variant
+// should be sent to the agent's own model instead. Each request falls back
+// at most once, and requests that were cancelled not at all.
+func (e *Engine) variantFallback(ctx context.Context, targetAgent *agent.Agent, a *variantAssignment, err error) bool {
+	if a == nil || a.own || a.fellBack || err == nil || ctx.Err() != nil {
+		return false
+	}
+	
+	e.logger.Warn("Experiment variant failed, falling back to the agent's model",
+		zap.String("agent", targetAgent.GetName()),
+		zap.String("experiment", a.experiment),
+		zap.String("variant", a.variant),
+		zap.String("provider", a.providerName),
+		zap.String("model", a.model),
+		zap.Error(err))
+	a.fellBack = true
+	return true
+}
+
+// recordVariant counts a request towards the variant it was sent to.
+func (e *Engine) recordVariant(clusterName string, targetAgent *agent.Agent, a *variantAssignment, usage *providers.Usage, cost float64, latency time.Duration, failed bool) {
+	if a == nil {
+		return
+	}
+	e.experiments.record(clusterName, targetAgent.GetName(), a, usage, cost, latency, failed)
+}
+
+// AgentExperiment returns an agent's experiment and what each of its
+// variants has served.
+func (e *Engine) AgentExperiment(agentID string) (*ExperimentStatus, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	
+	status := &ExperimentStatus{
+		Cluster:    a.ClusterName,
+		Agent:      a.GetName(),
+		Experiment: a.Config.Experiment,
+		Variants:   make([]VariantStats, 0),
+	}
+	if a.Config.Experiment == nil {
+		return status, nil
+	}
+	for _, variant := range a.Config.Experiment.Variants {
+		stats := e.experiments.get(a.ClusterName, a.GetName(), a.Config.Experiment.Name, variant.Name)
+		stats.Name = variant.Name
+		stats.Provider, stats.Model = variant.Target(a.Config.Provider, a.Config.Model)
+		stats.Weight = variant.Weight
+		status.Variants = append(status.Variants, stats)
+	}
+	return status, nil
+}
\ No newline at end of file
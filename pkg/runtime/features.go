@@ -0,0 +1,225 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+// FeatureFlag is the current state of a flag: the config file's settings
+// with any changes made through the admin API applied on top.
+type FeatureFlag struct {
+	Name      string                       `json:"name"`
+	Enabled   bool                         `json:"enabled"`
+	Overrides []config.FeatureFlagOverride `json:"overrides"`
+	UpdatedAt time.Time                    `json:"updated_at"`
+}
+
+// FeatureFlagUpdate changes a flag. Without a cluster it sets the flag's
+// default; with a cluster, and optionally an agent, it sets an override.
+type FeatureFlagUpdate struct {
+	Enabled bool   `json:"enabled"`
+	Cluster string `json:"cluster,omitempty"`
+	Agent   string `json:"agent,omitempty"`
+}
+
+// featureFlags holds flag state in memory. Changes made at runtime last
+// until the server restarts.
+type featureFlags struct {
+	flags map[string]*FeatureFlag
+	mu    sync.RWMutex
+}
+
+func newFeatureFlags(cfg map[string]config.FeatureFlagConfig) *featureFlags {
+	f := &featureFlags{flags: make(map[string]*FeatureFlag)}
+	now := time.Now()
+	for name, flag := range cfg {
+		f.flags[name] = &FeatureFlag{
+			Name:      name,
+			Enabled:   flag.Enabled,
+			Overrides: append([]config.FeatureFlagOverride{}, flag.Overrides...),
+			UpdatedAt: now,
+		}
+	}
+	return f
+}
+
+// enabled resolves a flag for an agent: an override for the agent wins
+// over one for its cluster, which wins over the flag's default. Unknown
+// flags are off.
+func (f *featureFlags) enabled(name, clusterName, agentName string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	
+	flag, exists := f.flags[name]
+	if !exists {
+		return false
+	}
+	
+	enabled := flag.Enabled
+	for _, override := range flag.Overrides {
+		if override.Cluster != clusterName {
+			continue
+		}
+		if override.Agent == agentName {
+			return override.Enabled
+		}
+		if override.Agent == "" {
+			enabled = override.Enabled
+		}
+	}
+	return enabled
+}
+
+func (f *featureFlags) list() []FeatureFlag {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	
+	flags := make([]FeatureFlag, 0, len(f.flags))
+	for _, flag := range f.flags {
+		flags = append(flags, flag.copy())
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags
+}
+
+func (f *featureFlags) get(name string) (*FeatureFlag, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	
+	flag, exists := f.flags[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrFeatureNotFound, name)
+	}
+	copied := flag.copy()
+	return &copied, nil
+}
+
+func (f *featureFlags) set(name string, update *FeatureFlagUpdate) (*FeatureFlag, error) {
+	if update.Agent != "" && update.Cluster == "" {
+		return nil, fmt.Errorf("cluster is required with agent")
+	}
+	if err := config.ValidateFeatureFlag(name, &config.FeatureFlagConfig{}); err != nil {
+		return nil, err
+	}
+	
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	
+	flag, exists := f.flags[name]
+	if !exists {
+		flag = &FeatureFlag{Name: name, Overrides: []config.FeatureFlagOverride{}}
+		f.flags[name] = flag
+	}
+	
+	if update.Cluster == "" {
+		flag.Enabled = update.Enabled
+	} else {
+		flag.Overrides = removeOverride(flag.Overrides, update.Cluster, update.Agent)
+		flag.Overrides = append(flag.Overrides, config.FeatureFlagOverride{
+			Cluster: update.Cluster,
+			Agent:   update.Agent,
+			Enabled: update.Enabled,
+		})
+	}
+	flag.UpdatedAt = time.Now()
+	
+	copied := flag.copy()
+	return &copied, nil
+}
+
+// remove deletes an override, or the whole flag when clusterName is empty.
+func (f *featureFlags) remove(name, clusterName, agentName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	
+	flag, exists := f.flags[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrFeatureNotFound, name)
+	}
+	
+	if clusterName == "" {
+		delete(f.flags, name)
+		return nil
+	}
+	
+	remaining := removeOverride(flag.Overrides, clusterName, agentName)
+	if len(remaining) == len(flag.Overrides) {
+		return fmt.Errorf("%w: %s has no override for %s", ErrFeatureNotFound, name, overrideTarget(clusterName, agentName))
+	}
+	flag.Overrides = remaining
+	flag.UpdatedAt = time.Now()
+	return nil
+}
+
+func (flag *FeatureFlag) copy() FeatureFlag {
+	copied := *flag
+	copied.Overrides = append([]config.FeatureFlagOverride{}, flag.Overrides...)
+	return copied
+}
+
+func removeOverride(overrides []config.FeatureFlagOverride, clusterName, agentName string) []config.FeatureFlagOverride {
+	remaining := make([]config.FeatureFlagOverride, 0, len(overrides))
+	for _, override := range overrides {
+		if override.Cluster != clusterName || override.Agent != agentName {
+			remaining = append(remaining, override)
+		}
+	}
+	return remaining
+}
+
+func overrideTarget(clusterName, agentName string) string {
+	if agentName == "" {
+		return "cluster " + clusterName
+	}
+	return "agent " + agentName + " in cluster " + clusterName
+}
+
+// FeatureEnabled reports whether an experimental behavior is on for an
+// agent. Features check it on every use, so a flag turned off through the
+// admin API takes effect on the next request.
+func (e *Engine) FeatureEnabled(name, clusterName, agentName string) bool {
+	return e.features.enabled(name, clusterName, agentName)
+}
+
+func (e *Engine) FeatureFlags() []FeatureFlag {
+	return e.features.list()
+}
+
+func (e *Engine) FeatureFlag(name string) (*FeatureFlag, error) {
+	return e.features.get(name)
+}
+
+func (e *Engine) SetFeatureFlag(name string, update *FeatureFlagUpdate) (*FeatureFlag, error) {
+	flag, err := e.features.set(name, update)
+	if err != nil {
+		return nil, err
+	}
+	
+	e.logger.Info("Feature flag updated",
+		zap.String("feature", name),
+		zap.Bool("enabled", update.Enabled),
+		zap.String("cluster", update.Cluster),
+		zap.String("agent", update.Agent))
+		
+	return flag, nil
+}
+
+// DeleteFeatureFlag removes an override, or the whole flag when clusterName
+// is empty.
+func (e *Engine) DeleteFeatureFlag(name, clusterName, agentName string) error {
+	if err := e.features.remove(name, clusterName, agentName); err != nil {
+		return err
+	}
+	
+	e.logger.Info("Feature flag removed",
+		zap.String("feature", name),
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName))
+		
+	return nil
+}
\ No newline at end of file
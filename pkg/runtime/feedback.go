@@ -0,0 +1,429 @@
+package runtime
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultFeedbackMaxResponses = 10000
+	defaultFeedbackMaxScore     = 5
+)
+
+type FeedbackRating string
+
+const (
+	FeedbackUp   FeedbackRating = "up"
+	FeedbackDown FeedbackRating = "down"
+)
+
+// ResponseRecord is what is remembered about a served response so that
+// feedback on it can be attributed to an agent and prompt version.
+type ResponseRecord struct {
+	ID       string `json:"response_id"`
+	Cluster  string `json:"cluster"`
+	Agent    string `json:"agent"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// PromptVersion identifies the system prompt the response was made with
+	PromptVersion string `json:"prompt_version"`
+	// Experiment and Variant are set for responses of agents running an
+	// experiment
+	Experiment string           `json:"experiment,omitempty"`
+	Variant    string           `json:"variant,omitempty"`
+	Usage      *providers.Usage `json:"usage,omitempty"`
+	LatencyMs  int64            `json:"latency_ms"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// FeedbackInput is the feedback a user gives on a response.
+type FeedbackInput struct {
+	Rating  FeedbackRating     `json:"rating,omitempty"`
+	Comment string             `json:"comment,omitempty"`
+	Scores  map[string]float64 `json:"scores,omitempty"`
+}
+
+// Feedback is a piece of feedback joined with the response it is about.
+type Feedback struct {
+	ID string `json:"id"`
+	FeedbackInput
+	ResponseRecord
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+type FeedbackFilter struct {
+	Cluster string
+	Agent   string
+}
+
+// FeedbackSummary aggregates the feedback on one agent and prompt version
+// and, for agents running an experiment, one variant.
+type FeedbackSummary struct {
+	Cluster       string `json:"cluster"`
+	Agent         string `json:"agent"`
+	PromptVersion string `json:"prompt_version"`
+	Experiment    string `json:"experiment,omitempty"`
+	Variant       string `json:"variant,omitempty"`
+	Model         string `json:"model"`
+	Count         int    `json:"count"`
+	Responses     int    `json:"responses"`
+	Up            int    `json:"up"`
+	Down          int    `json:"down"`
+	// Satisfaction is the share of ratings that are up
+	Satisfaction float64            `json:"satisfaction"`
+	Scores       map[string]float64 `json:"scores,omitempty"`
+	// TotalTokens and AvgLatencyMs cover the responses given feedback
+	TotalTokens  int   `json:"total_tokens"`
+	AvgLatencyMs int64 `json:"avg_latency_ms"`
+	// SmokeTestsPassed is the agent's latest smoke test outcome, if it has
+	// any
+	SmokeTestsPassed *bool     `json:"smoke_tests_passed,omitempty"`
+	LastFeedbackAt   time.Time `json:"last_feedback_at"`
+}
+
+// feedbackStore remembers recent responses and keeps the feedback given on
+// them, appending each piece to a JSON lines file when a path is set.
+type feedbackStore struct {
+	config    config.FeedbackConfig
+	responses map[string]*ResponseRecord
+	order     []string
+	feedback  []Feedback
+	nextID    uint64
+	file      *os.File
+	mu        sync.RWMutex
+}
+
+func newFeedbackStore(cfg config.FeedbackConfig) (*feedbackStore, error) {
+	if cfg.MaxResponses <= 0 {
+		cfg.MaxResponses = defaultFeedbackMaxResponses
+	}
+	if cfg.MaxScore <= 0 {
+		cfg.MaxScore = defaultFeedbackMaxScore
+	}
+	
+	store := &feedbackStore{
+		config:    cfg,
+		responses: make(map[string]*ResponseRecord),
+	}
+	
+	if cfg.Path != "" {
+		if err := store.load(); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open feedback file: %w", err)
+		}
+		store.file = file
+	}
+	
+	return store, nil
+}
+
+func (s *feedbackStore) load() error {
+	file, err := os.Open(s.config.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open feedback file: %w", err)
+	}
+	defer file.Close()
+	
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var feedback Feedback
+		if err := json.Unmarshal(scanner.Bytes(), &feedback); err != nil {
+			return fmt.Errorf("failed to parse feedback file: %w", err)
+		}
+		s.feedback = append(s.feedback, feedback)
+	}
+	s.nextID = uint64(len(s.feedback))
+	
+	return scanner.Err()
+}
+
+// recordResponse remembers a response, forgetting the oldest once
+// MaxResponses are held.
+func (s *feedbackStore) recordResponse(record *ResponseRecord) {
+	if record.ID == "" {
+		return
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if _, exists := s.responses[record.ID]; !exists {
+		s.order = append(s.order, record.ID)
+	}
+	s.responses[record.ID] = record
+	
+	for len(s.order) > s.config.MaxResponses {
+		delete(s.responses, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (s *feedbackStore) validate(input *FeedbackInput) error {
+	if input.Rating == "" && input.Comment == "" && len(input.Scores) == 0 {
+		return fmt.Errorf("feedback needs a rating, comment or scores")
+	}
+	if input.Rating != "" && input.Rating != FeedbackUp && input.Rating != FeedbackDown {
+		return fmt.Errorf("invalid rating %q, expected up or down", input.Rating)
+	}
+	
+	for criterion, score := range input.Scores {
+		if len(s.config.Rubric) > 0 && !inRubric(s.config.Rubric, criterion) {
+			return fmt.Errorf("unknown rubric criterion %q", criterion)
+		}
+		if score < 0 || score > s.config.MaxScore {
+			return fmt.Errorf("score for %s must be between 0 and %v", criterion, s.config.MaxScore)
+		}
+	}
+	
+	return nil
+}
+
+func inRubric(rubric []string, criterion string) bool {
+	for _, name := range rubric {
+		if name == criterion {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *feedbackStore) submit(responseID string, input *FeedbackInput) (*Feedback, error) {
+	if err := s.validate(input); err != nil {
+		return nil, err
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	record, ok := s.responses[responseID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrResponseNotFound, responseID)
+	}
+	
+	s.nextID++
+	feedback := Feedback{
+		ID:             fmt.Sprintf("fb-%d", s.nextID),
+		FeedbackInput:  *input,
+		ResponseRecord: *record,
+		SubmittedAt:    time.Now().UTC(),
+	}
+	
+	if s.file != nil {
+		line, err := json.Marshal(&feedback)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.file.Write(append(line, '\n')); err != nil {
+			return nil, fmt.Errorf("failed to persist feedback: %w", err)
+		}
+	}
+	
+	s.feedback = append(s.feedback, feedback)
+	return &feedback, nil
+}
+
+func (s *feedbackStore) forResponse(responseID string) []Feedback {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	matches := make([]Feedback, 0)
+	for _, feedback := range s.feedback {
+		if feedback.ResponseRecord.ID == responseID {
+			matches = append(matches, feedback)
+		}
+	}
+	return matches
+}
+
+func (s *feedbackStore) summaries(filter FeedbackFilter) []*FeedbackSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	type scoreTotal struct {
+		sum   float64
+		count int
+	}
+	
+	byKey := make(map[string]*FeedbackSummary)
+	scores := make(map[string]map[string]*scoreTotal)
+	latency := make(map[string]int64)
+	seen := make(map[string]bool)
+	for _, feedback := range s.feedback {
+		if (filter.Cluster != "" && feedback.Cluster != filter.Cluster) || (filter.Agent != "" && feedback.Agent != filter.Agent) {
+			continue
+		}
+		
+		key := feedback.Cluster + "/" + feedback.Agent + "/" + feedback.PromptVersion + "/" + feedback.Experiment + "/" + feedback.Variant
+		summary, ok := byKey[key]
+		if !ok {
+			summary = &FeedbackSummary{
+				Cluster:       feedback.Cluster,
+				Agent:         feedback.Agent,
+				PromptVersion: feedback.PromptVersion,
+				Experiment:    feedback.Experiment,
+				Variant:       feedback.Variant,
+			}
+			byKey[key] = summary
+			scores[key] = make(map[string]*scoreTotal)
+		}
+		
+		summary.Count++
+		summary.Model = feedback.Model
+		switch feedback.Rating {
+		case FeedbackUp:
+			summary.Up++
+		case FeedbackDown:
+			summary.Down++
+		}
+		for criterion, score := range feedback.Scores {
+			total, ok := scores[key][criterion]
+			if !ok {
+				total = &scoreTotal{}
+				scores[key][criterion] = total
+			}
+			total.sum += score
+			total.count++
+		}
+		// A response given feedback several times counts once
+		if responseKey := key + "/" + feedback.ResponseRecord.ID; !seen[responseKey] {
+			seen[responseKey] = true
+			summary.Responses++
+			if feedback.Usage != nil {
+				summary.TotalTokens += feedback.Usage.TotalTokens
+			}
+			latency[key] += feedback.LatencyMs
+		}
+		if feedback.SubmittedAt.After(summary.LastFeedbackAt) {
+			summary.LastFeedbackAt = feedback.SubmittedAt
+		}
+	}
+	
+	result := make([]*FeedbackSummary, 0, len(byKey))
+	for key, summary := range byKey {
+		if rated := summary.Up + summary.Down; rated > 0 {
+			summary.Satisfaction = float64(summary.Up) / float64(rated)
+		}
+		if len(scores[key]) > 0 {
+			summary.Scores = make(map[string]float64, len(scores[key]))
+			for criterion, total := range scores[key] {
+				summary.Scores[criterion] = total.sum / float64(total.count)
+			}
+		}
+		summary.AvgLatencyMs = latency[key] / int64(summary.Responses)
+		result = append(result, summary)
+	}
+	
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cluster != result[j].Cluster {
+			return result[i].Cluster < result[j].Cluster
+		}
+		if result[i].Agent != result[j].Agent {
+			return result[i].Agent < result[j].Agent
+		}
+		return result[i].LastFeedbackAt.After(result[j].LastFeedbackAt)
+	})
+	return result
+}
+
+func (s *feedbackStore) close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// promptVersion returns a short, stable identifier for a system prompt, so
+// feedback can be compared across prompt revisions.
+func promptVersion(systemPrompt string) string {
+	sum := sha256.Sum256([]byte(systemPrompt))
+	return hex.EncodeToString(sum[:6])
+}
+
+func (e *Engine) recordResponse(clusterName string, targetAgent *agent.Agent, responseID, providerName, model string, usage *providers.Usage, latency time.Duration, assignment *variantAssignment) {
+	record := &ResponseRecord{
+		ID:            responseID,
+		Cluster:       clusterName,
+		Agent:         targetAgent.GetName(),
+		Provider:      providerName,
+		Model:         model,
+		PromptVersion: promptVersion(targetAgent.Config.SystemPrompt),
+		Usage:         usage,
+		LatencyMs:     latency.Milliseconds(),
+		CreatedAt:     time.Now().UTC(),
+	}
+	if assignment != nil {
+		record.Experiment = assignment.experiment
+		record.Variant = assignment.variant
+	}
+	e.feedback.recordResponse(record)
+}
+
+// SubmitFeedback records feedback on a response served by the engine.
+func (e *Engine) SubmitFeedback(responseID string, input *FeedbackInput) (*Feedback, error) {
+	feedback, err := e.feedback.submit(responseID, input)
+	if err != nil {
+		return nil, err
+	}
+	
+	e.logger.Info("Feedback received",
+		zap.String("response_id", responseID),
+		zap.String("cluster", feedback.Cluster),
+		zap.String("agent", feedback.Agent),
+		zap.String("rating", string(feedback.Rating)))
+	return feedback, nil
+}
+
+// ResponseFeedback lists the feedback given on a response.
+func (e *Engine) ResponseFeedback(responseID string) []Feedback {
+	return e.feedback.forResponse(responseID)
+}
+
+// FeedbackSummaries aggregates feedback per agent, prompt version and
+// experiment variant. The summaries for an agent's current prompt version
+// are joined with its latest smoke test results.
+func (e *Engine) FeedbackSummaries(filter FeedbackFilter) []*FeedbackSummary {
+	summaries := e.feedback.summaries(filter)
+	
+	for _, summary := range summaries {
+		cluster, err := e.getCluster(summary.Cluster)
+		if err != nil {
+			continue
+		}
+		
+		cluster.mu.RLock()
+		targetAgent, exists := cluster.Agents[summary.Agent]
+		if !exists || promptVersion(targetAgent.Config.SystemPrompt) != summary.PromptVersion {
+			cluster.mu.RUnlock()
+			continue
+		}
+		for _, result := range cluster.SmokeTests {
+			if result.Agent != summary.Agent {
+				continue
+			}
+			passed := result.Passed && (summary.SmokeTestsPassed == nil || *summary.SmokeTestsPassed)
+			summary.SmokeTestsPassed = &passed
+		}
+		cluster.mu.RUnlock()
+	}
+	
+	return summaries
+}
\ No newline at end of file
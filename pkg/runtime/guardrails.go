@@ -0,0 +1,175 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/guardrails"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+const (
+	guardrailEventsMaxEntries = 1000
+	defaultGuardrailEvents    = 100
+)
+
+// GuardrailEvent is a guardrail violation on a request or answer.
+type GuardrailEvent struct {
+	Cluster   string    `json:"cluster"`
+	Agent     string    `json:"agent"`
+	AgentID   string    `json:"agent_id"`
+	RequestID string    `json:"request_id,omitempty"`
+	At        time.Time `json:"at"`
+	guardrails.Violation
+}
+
+type GuardrailEventFilter struct {
+	Cluster string
+	Agent   string
+	// Limit caps the events returned, newest first
+	Limit int
+}
+
+// guardrailLog keeps the most recent guardrail violations.
+type guardrailLog struct {
+	events []GuardrailEvent
+	mu     sync.Mutex
+}
+
+func (g *guardrailLog) record(event GuardrailEvent) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	
+	g.events = append(g.events, event)
+	if len(g.events) > guardrailEventsMaxEntries {
+		g.events = g.events[len(g.events)-guardrailEventsMaxEntries:]
+	}
+}
+
+// GuardrailEvents returns recent guardrail violations, newest first.
+func (e *Engine) GuardrailEvents(filter GuardrailEventFilter) []GuardrailEvent {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultGuardrailEvents
+	}
+	
+	e.guardrailLog.mu.Lock()
+	defer e.guardrailLog.mu.Unlock()
+	
+	events := make([]GuardrailEvent, 0)
+	for i := len(e.guardrailLog.events) - 1; i >= 0 && len(events) < limit; i-- {
+		event := e.guardrailLog.events[i]
+		if filter.Cluster != "" && event.Cluster != filter.Cluster {
+			continue
+		}
+		if filter.Agent != "" && event.Agent != filter.Agent && event.AgentID != filter.Agent {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func (e *Engine) recordViolations(clusterName string, targetAgent *agent.Agent, requestID string, violations []guardrails.Violation) {
+	for _, violation := range violations {
+		e.logger.Info("Guardrail triggered",
+			zap.String("cluster", clusterName),
+			zap.String("agent", targetAgent.GetName()),
+			zap.String("request", requestID),
+			zap.String("guardrail", violation.Guardrail),
+			zap.String("stage", string(violation.Stage)),
+			zap.String("action", violation.Action),
+			zap.String("reason", violation.Reason))
+		e.guardrailLog.record(GuardrailEvent{
+			Cluster:   clusterName,
+			Agent:     targetAgent.GetName(),
+			AgentID:   targetAgent.ID,
+			RequestID: requestID,
+			At:        time.Now(),
+			Violation: violation,
+		})
+	}
+}
+
+// checkInput applies the agent's input guardrails to the request's last
+// user message, redacting it in place, and returns ErrGuardrailBlocked if
+// a guardrail blocks it.
+func (e *Engine) checkInput(ctx context.Context, clusterName string, targetAgent *agent.Agent, req *agent.Request) ([]guardrails.Violation, error) {
+	pipeline := targetAgent.Config.Guardrails
+	if !pipeline.HasInput() {
+		return nil, nil
+	}
+	
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		if req.Messages[i].Role != "user" {
+			continue
+		}
+		
+		result := pipeline.Check(ctx, guardrails.StageInput, req.Messages[i].Content)
+		e.recordViolations(clusterName, targetAgent, req.ID, result.Violations)
+		if v := result.Blocked; v != nil {
+			if v.Message != "" {
+				return result.Violations, fmt.Errorf("%w: %s", ErrGuardrailBlocked, v.Message)
+			}
+			return result.Violations, fmt.Errorf("%w: %s: %s", ErrGuardrailBlocked, v.Guardrail, v.Reason)
+		}
+		req.Messages[i].Content = result.Text
+		return result.Violations, nil
+	}
+	return nil, nil
+}
+
+// checkOutput applies the agent's output guardrails to an answer.
+func (e *Engine) checkOutput(ctx context.Context, clusterName string, targetAgent *agent.Agent, requestID, content string) *guardrails.Result {
+	pipeline := targetAgent.Config.Guardrails
+	if !pipeline.HasOutput() {
+		return &guardrails.Result{Text: content}
+	}
+	
+	result := pipeline.Check(ctx, guardrails.StageOutput, content)
+	e.recordViolations(clusterName, targetAgent, requestID, result.Violations)
+	return result
+}
+
+// guardrailCorrection asks the model for another answer in place of one a
+// guardrail rejected.
+func guardrailCorrection(reply string, violation *guardrails.Violation) []providers.Message {
+	return []providers.Message{
+		{Role: "assistant", Content: reply},
+		{Role: "user", Content: fmt.Sprintf("Your reply was rejected: it %s. Reply again without this problem.", violation.Reason)},
+	}
+}
+
+// streamGuarded serves a streaming request to an agent whose answers are
+// checked by guardrails. An answer cannot be taken back once streamed, so
+// it is checked whole and sent as a single chunk.
+func (e *Engine) streamGuarded(ctx context.Context, clusterName, agentName string, req *agent.Request) (<-chan *providers.StreamChunk, error) {
+	resp, err := e.ProcessRequestContext(ctx, clusterName, agentName, req)
+	if err != nil {
+		return nil, err
+	}
+	
+	chunk := &providers.StreamChunk{
+		ID:           "final_chunk_0",
+		Content:      resp.Content,
+		Delta:        resp.Content,
+		Done:         true,
+		Error:        resp.Error,
+		ProviderMeta: resp.ProviderMeta,
+	}
+	if req.IncludeThinking {
+		chunk.Thinking = resp.Thinking
+	}
+	if usage, ok := resp.Metadata["usage"].(*providers.Usage); ok {
+		chunk.Usage = usage
+	}
+	
+	chunks := make(chan *providers.StreamChunk, 1)
+	chunks <- chunk
+	close(chunks)
+	return chunks, nil
+}
\ No newline at end of file
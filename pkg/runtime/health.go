@@ -0,0 +1,309 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultFailureThreshold  = 3
+	defaultProbeTimeout      = 10 * time.Second
+	defaultRestartBackoff    = time.Second
+	defaultRestartMaxBackoff = 5 * time.Minute
+)
+
+// HealthStatus is what the engine knows of the health of an agent with
+// health checks.
+type HealthStatus struct {
+	ConsecutiveFailures int `json:"consecutive_failures"`
+	// Restarts counts the restarts the agent has not yet recovered from
+	Restarts    int       `json:"restarts"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastProbe   time.Time `json:"last_probe,omitempty"`
+	NextRestart time.Time `json:"next_restart,omitempty"`
+}
+
+type agentHealth struct {
+	config *config.AgentHealth
+	status HealthStatus
+	stop   chan struct{}
+}
+
+// healthMonitor tracks the health of agents with health checks, by agent
+// ID.
+type healthMonitor struct {
+	agents map[string]*agentHealth
+	mu     sync.Mutex
+}
+
+func newHealthMonitor() *healthMonitor {
+	return &healthMonitor{agents: make(map[string]*agentHealth)}
+}
+
+// watchHealth starts tracking an agent's health and, if it has a probe
+// interval, probing it.
+func (e *Engine) watchHealth(a *agent.Agent, cfg *config.AgentHealth) {
+	h := &agentHealth{config: cfg, stop: make(chan struct{})}
+	e.health.mu.Lock()
+	e.health.agents[a.ID] = h
+	e.health.mu.Unlock()
+	
+	if cfg.Interval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(cfg.Interval)
+		defer ticker.Stop()
+		
+		for {
+			select {
+			case <-h.stop:
+				return
+			case <-e.done:
+				return
+			case <-ticker.C:
+				e.probeAgent(a, cfg)
+			}
+		}
+	}()
+}
+
+// forgetHealth stops tracking an agent and returns its last status.
+func (e *Engine) forgetHealth(agentID string) (HealthStatus, bool) {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	
+	h, ok := e.health.agents[agentID]
+	if !ok {
+		return HealthStatus{}, false
+	}
+	close(h.stop)
+	delete(e.health.agents, agentID)
+	return h.status, true
+}
+
+// AgentHealth returns the health of an agent with health checks.
+func (e *Engine) AgentHealth(agentID string) (*HealthStatus, bool) {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	
+	h, ok := e.health.agents[agentID]
+	if !ok {
+		return nil, false
+	}
+	status := h.status
+	return &status, true
+}
+
+// probeAgent checks that the agent's provider answers, and returns why
+// not. Failed agents are not probed; they wait for their restart.
+func (e *Engine) probeAgent(a *agent.Agent, cfg *config.AgentHealth) error {
+	if a.GetStatus() == agent.StatusFailed {
+		return fmt.Errorf("%w: %s failed: %s", ErrAgentUnavailable, a.GetName(), a.GetErrorMessage())
+	}
+	
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	_, provider, err := e.resolveAgent(a.ClusterName, a.GetName())
+	if err == nil {
+		_, err = provider.Chat(ctx, &providers.ChatRequest{
+			Model:     a.Config.Model,
+			Messages:  []providers.Message{{Role: "user", Content: "ping"}},
+			MaxTokens: 1,
+		})
+	}
+	
+	e.health.mu.Lock()
+	if h, ok := e.health.agents[a.ID]; ok {
+		h.status.LastProbe = time.Now()
+	}
+	e.health.mu.Unlock()
+	if err != nil {
+		err = fmt.Errorf("probe failed: %w", err)
+	}
+	e.recordHealth(a, err)
+	return err
+}
+
+// recordHealth counts the outcome of a request or probe. Any success
+// clears the failures, and the restarts the agent needed to get there; the
+// failure that reaches the threshold marks the agent failed.
+func (e *Engine) recordHealth(a *agent.Agent, err error) {
+	e.health.mu.Lock()
+	h, ok := e.health.agents[a.ID]
+	if !ok {
+		e.health.mu.Unlock()
+		return
+	}
+	if err == nil {
+		h.status.ConsecutiveFailures = 0
+		h.status.Restarts = 0
+		e.health.mu.Unlock()
+		return
+	}
+	
+	h.status.ConsecutiveFailures++
+	h.status.LastError = err.Error()
+	threshold := h.config.FailureThreshold
+	if threshold <= 0 {
+		threshold = defaultFailureThreshold
+	}
+	failed := h.status.ConsecutiveFailures == threshold
+	e.health.mu.Unlock()
+	
+	if failed {
+		e.failAgent(a, fmt.Sprintf("%d consecutive failures, last: %v", threshold, err))
+	}
+}
+
+// failAgent marks an agent failed and schedules its restart, if its policy
+// allows one.
+func (e *Engine) failAgent(a *agent.Agent, reason string) {
+	e.logger.Error("Agent failed",
+		zap.String("cluster", a.ClusterName),
+		zap.String("agent", a.GetName()),
+		zap.String("reason", reason))
+	if err := e.agentManager.MarkFailed(a.ID, reason); err != nil {
+		return
+	}
+	e.scheduleRestart(a)
+}
+
+func (e *Engine) scheduleRestart(a *agent.Agent) {
+	e.health.mu.Lock()
+	defer e.health.mu.Unlock()
+	
+	h, ok := e.health.agents[a.ID]
+	if !ok {
+		return
+	}
+	policy := h.config.Restart
+	if policy == nil {
+		policy = &config.RestartPolicy{Policy: config.RestartOnFailure}
+	}
+	switch {
+	case policy.Policy == config.RestartNever:
+		return
+	case policy.Policy == config.RestartOnFailure && policy.MaxRestarts > 0 && h.status.Restarts >= policy.MaxRestarts:
+		e.logger.Error("Agent restarts exhausted, leaving it failed",
+			zap.String("cluster", a.ClusterName),
+			zap.String("agent", a.GetName()),
+			zap.Int("restarts", h.status.Restarts))
+		return
+	}
+	
+	delay := restartBackoff(policy, h.status.Restarts)
+	h.status.NextRestart = time.Now().Add(delay)
+	e.logger.Info("Restarting agent",
+		zap.String("cluster", a.ClusterName),
+		zap.String("agent", a.GetName()),
+		zap.Duration("in", delay))
+	
+	stop := h.stop
+	time.AfterFunc(delay, func() {
+		select {
+		case <-stop:
+		case <-e.done:
+		default:
+			e.restartAgent(a)
+		}
+	})
+}
+
+// restartBackoff doubles the policy's backoff for each restart the agent
+// has not recovered from.
+func restartBackoff(policy *config.RestartPolicy, restarts int) time.Duration {
+	delay, max := policy.Backoff, policy.MaxBackoff
+	if delay <= 0 {
+		delay = defaultRestartBackoff
+	}
+	if max <= 0 {
+		max = defaultRestartMaxBackoff
+	}
+	for i := 0; i < restarts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
+
+// restartAgent replaces a failed agent with a new one created from its
+// spec. If that fails, the old agent stays failed and another restart is
+// scheduled.
+func (e *Engine) restartAgent(old *agent.Agent) {
+	cluster, err := e.getCluster(old.ClusterName)
+	if err != nil {
+		return
+	}
+	
+	cluster.mu.RLock()
+	current := cluster.Agents[old.GetName()]
+	spec := findAgentSpec(cluster.Config, old.GetName())
+	if spec != nil {
+		spec = copyAgentSpec(spec)
+	}
+	cluster.mu.RUnlock()
+	if current != old || spec == nil {
+		// Replaced or removed since it failed
+		return
+	}
+	
+	e.health.mu.Lock()
+	h, ok := e.health.agents[old.ID]
+	if ok {
+		h.status.Restarts++
+		h.status.NextRestart = time.Time{}
+	}
+	e.health.mu.Unlock()
+	if !ok {
+		return
+	}
+	
+	if err := e.createAgent(cluster, spec); err != nil {
+		e.logger.Error("Failed to restart agent",
+			zap.String("cluster", cluster.Name),
+			zap.String("agent", old.GetName()),
+			zap.Error(err))
+		e.health.mu.Lock()
+		h.status.LastError = err.Error()
+		e.health.mu.Unlock()
+		e.scheduleRestart(old)
+		return
+	}
+	
+	status, _ := e.forgetHealth(old.ID)
+	e.removeAgent(old)
+	
+	cluster.mu.RLock()
+	restarted, exists := cluster.Agents[old.GetName()]
+	cluster.mu.RUnlock()
+	if !exists {
+		return
+	}
+	e.health.mu.Lock()
+	if h, ok := e.health.agents[restarted.ID]; ok {
+		h.status.Restarts = status.Restarts
+		h.status.LastError = status.LastError
+	}
+	e.health.mu.Unlock()
+	
+	e.logger.Info("Agent restarted",
+		zap.String("cluster", cluster.Name),
+		zap.String("agent", old.GetName()),
+		zap.String("id", restarted.ID),
+		zap.Int("restarts", status.Restarts))
+}
\ No newline at end of file
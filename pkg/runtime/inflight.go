@@ -0,0 +1,163 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+type RequestPhase string
+
+const (
+	// RequestPhaseQueued covers the time before the provider is called
+	RequestPhaseQueued RequestPhase = "queued"
+	// RequestPhaseProvider is a provider call or stream in progress
+	RequestPhaseProvider RequestPhase = "provider"
+	// RequestPhaseTool is a tool call made on behalf of the request
+	RequestPhaseTool RequestPhase = "tool"
+)
+
+// ActiveRequest describes a request that is currently executing.
+type ActiveRequest struct {
+	ID        string        `json:"id"`
+	RequestID string        `json:"request_id,omitempty"`
+	Cluster   string        `json:"cluster"`
+	Agent     string        `json:"agent"`
+	Phase     RequestPhase  `json:"phase"`
+	Stream    bool          `json:"stream"`
+	StartedAt time.Time     `json:"started_at"`
+	ElapsedMs int64         `json:"elapsed_ms"`
+	// PhaseElapsedMs is how long the request has been in its current phase
+	PhaseElapsedMs int64 `json:"phase_elapsed_ms"`
+	Cancelled      bool  `json:"cancelled"`
+}
+
+type inflightRequest struct {
+	info         ActiveRequest
+	phaseStarted time.Time
+	cancel       context.CancelFunc
+}
+
+type inflightTracker struct {
+	requests map[string]*inflightRequest
+	nextID   uint64
+	mu       sync.Mutex
+}
+
+func newInflightTracker() *inflightTracker {
+	return &inflightTracker{
+		requests: make(map[string]*inflightRequest),
+	}
+}
+
+// start registers a request in the queued phase and returns its tracking ID.
+func (t *inflightTracker) start(cluster, agentName, requestID string, stream bool, cancel context.CancelFunc) string {
+	id := fmt.Sprintf("active-%d", atomic.AddUint64(&t.nextID, 1))
+	now := time.Now()
+	
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	t.requests[id] = &inflightRequest{
+		info: ActiveRequest{
+			ID:        id,
+			RequestID: requestID,
+			Cluster:   cluster,
+			Agent:     agentName,
+			Phase:     RequestPhaseQueued,
+			Stream:    stream,
+			StartedAt: now,
+		},
+		phaseStarted: now,
+		cancel:       cancel,
+	}
+	return id
+}
+
+func (t *inflightTracker) setPhase(id string, phase RequestPhase) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if req, ok := t.requests[id]; ok && req.info.Phase != phase {
+		req.info.Phase = phase
+		req.phaseStarted = time.Now()
+	}
+}
+
+// count returns how many requests are executing.
+func (t *inflightTracker) count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return len(t.requests)
+}
+
+func (t *inflightTracker) finish(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.requests, id)
+}
+
+// cancelled reports whether the request was cancelled through cancel.
+func (t *inflightTracker) cancelled(id string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	req, ok := t.requests[id]
+	return ok && req.info.Cancelled
+}
+
+func (t *inflightTracker) cancelRequest(id string) error {
+	t.mu.Lock()
+	req, ok := t.requests[id]
+	if ok {
+		req.info.Cancelled = true
+	}
+	t.mu.Unlock()
+	
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrRequestNotFound, id)
+	}
+	req.cancel()
+	return nil
+}
+
+func (t *inflightTracker) list() []ActiveRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	now := time.Now()
+	active := make([]ActiveRequest, 0, len(t.requests))
+	for _, req := range t.requests {
+		info := req.info
+		info.ElapsedMs = now.Sub(info.StartedAt).Milliseconds()
+		info.PhaseElapsedMs = now.Sub(req.phaseStarted).Milliseconds()
+		active = append(active, info)
+	}
+	
+	// Longest running first, since those are the ones worth looking at
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].StartedAt.Before(active[j].StartedAt)
+	})
+	return active
+}
+
+// ActiveRequests lists the requests currently executing, longest running
+// first.
+func (e *Engine) ActiveRequests() []ActiveRequest {
+	return e.inflight.list()
+}
+
+// CancelRequest cancels an executing request by its tracking ID.
+func (e *Engine) CancelRequest(id string) error {
+	if err := e.inflight.cancelRequest(id); err != nil {
+		return err
+	}
+	
+	e.logger.Info("Request cancelled", zap.String("id", id))
+	return nil
+}
@@ -0,0 +1,598 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+)
+
+const defaultQueueTimeout = 30 * time.Second
+
+// AgentInstance is one of the instances serving an agent's requests.
+type AgentInstance struct {
+	ID     string `json:"id"`
+	Active int    `json:"active"`
+	Served int64  `json:"served"`
+	// Draining instances finish the requests they have and take no more
+	Draining  bool      `json:"draining,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// AgentInstances is the pool of instances serving an agent's requests.
+type AgentInstances struct {
+	Cluster        string          `json:"cluster"`
+	Agent          string          `json:"agent"`
+	Desired        int             `json:"desired"`
+	MinInstances   int             `json:"min_instances,omitempty"`
+	MaxInstances   int             `json:"max_instances,omitempty"`
+	MaxConcurrency int             `json:"max_concurrency,omitempty"`
+	Queued         int             `json:"queued"`
+	MaxQueueDepth  int             `json:"max_queue_depth"`
+	Warm           bool            `json:"warm,omitempty"`
+	Load           AgentLoad       `json:"load"`
+	LastScaled     *ScaleEvent     `json:"last_scaled,omitempty"`
+	Instances      []AgentInstance `json:"instances"`
+	// QueuedByPriority counts the queued requests of each priority, and
+	// Rejected the requests the full queue turned away or shed since the
+	// agent was deployed
+	QueuedByPriority map[string]int `json:"queued_by_priority,omitempty"`
+	Rejected         int64          `json:"rejected"`
+}
+
+type agentInstance struct {
+	id        string
+	active    int
+	served    int64
+	draining  bool
+	startedAt time.Time
+}
+
+type instancePool struct {
+	name      string
+	scaling   agent.ScalingConfig
+	desired   int
+	instances []*agentInstance
+	next      int
+	// waiting are the requests queued for an instance, in the order they
+	// are served
+	waiting  []*queuedRequest
+	seq      int64
+	rejected int64
+	// changed is closed, and replaced, whenever an instance frees up or
+	// the pool is resized
+	changed chan struct{}
+	closed  bool
+	// samples are the requests served in the last autoscale window, and
+	// peakActive and peakQueued the most requests in progress and queued
+	// since the autoscaler last looked
+	samples     []loadSample
+	peakActive  int
+	peakQueued  int
+	lastRequest time.Time
+	lastScaled  *ScaleEvent
+	// warm is set while the agent is scaled to zero with its tools kept
+	// connected, and cold once their connections were released
+	warm bool
+	cold bool
+}
+
+// instancePools holds the instance pools of agents by cluster and agent
+// name, so an agent recreated under the same name, by a restart or a
+// rollback, keeps its instances.
+type instancePools struct {
+	pools map[string]*instancePool
+	// limits caps the agents of a cluster running instances at once
+	limits map[string]int
+	// freed is closed, and replaced, whenever an instance frees up or an
+	// agent stops running, so agents waiting for room can start
+	freed chan struct{}
+	mu    sync.Mutex
+}
+
+func newInstancePools() *instancePools {
+	return &instancePools{
+		pools:  make(map[string]*instancePool),
+		limits: make(map[string]int),
+		freed:  make(chan struct{}),
+	}
+}
+
+// instanceLease is a request's hold on an instance, until it is released.
+type instanceLease struct {
+	pools    *instancePools
+	pool     *instancePool
+	instance *agentInstance
+	started  time.Time
+	tokens   int
+	// queued is how long the request waited for the instance
+	queued time.Duration
+	// coldStart is set when the agent had no instances for the request
+	coldStart bool
+}
+
+// setLimit caps the agents of a cluster running instances at once. Zero
+// leaves them unlimited.
+func (p *instancePools) setLimit(clusterName string, limit int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if limit > 0 {
+		p.limits[clusterName] = limit
+	} else {
+		delete(p.limits, clusterName)
+	}
+}
+
+// configure creates the pool of an agent that was deployed, with its
+// minimum number of instances and at least one. An agent that already has
+// a pool keeps it, resized to fit its scaling limits.
+func (p *instancePools) configure(clusterName string, a *agent.Agent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	pool, ok := p.pools[budgetKey(clusterName, a.GetName())]
+	if !ok {
+		p.pool(clusterName, a)
+		return
+	}
+	if !sameScaling(pool.scaling, a.Config.Scaling) {
+		pool.scaling = a.Config.Scaling
+		pool.resize(clampInstances(pool.scaling, pool.desired))
+	}
+}
+
+// pool returns the pool of an agent, creating it if it has none. The
+// caller holds the lock.
+func (p *instancePools) pool(clusterName string, a *agent.Agent) *instancePool {
+	pool, ok := p.pools[budgetKey(clusterName, a.GetName())]
+	if !ok {
+		pool = &instancePool{
+			name:        a.GetName(),
+			scaling:     a.Config.Scaling,
+			changed:     make(chan struct{}),
+			lastRequest: time.Now(),
+		}
+		// Agents the cluster has no room for start with no instances, and
+		// get one on their first request
+		initial := 1
+		if limit, ok := p.limits[clusterName]; ok && p.running(clusterName) >= limit {
+			initial = 0
+		}
+		p.pools[budgetKey(clusterName, a.GetName())] = pool
+		pool.resize(clampInstances(pool.scaling, initial))
+	}
+	return pool
+}
+
+// running counts the agents of a cluster that have instances. The caller
+// holds the lock.
+func (p *instancePools) running(clusterName string) int {
+	n := 0
+	for key, pool := range p.pools {
+		if strings.HasPrefix(key, clusterName+"/") && pool.desired > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+// makeRoom reports whether another agent of a cluster may start
+// instances. A cluster at its limit makes room by scaling to zero the agent
+// that has gone longest without a request, among those that may run no
+// instances and have none busy. The caller holds the lock.
+func (p *instancePools) makeRoom(clusterName string) bool {
+	limit, ok := p.limits[clusterName]
+	if !ok || p.running(clusterName) < limit {
+		return true
+	}
+	
+	var victim *instancePool
+	for key, pool := range p.pools {
+		if !strings.HasPrefix(key, clusterName+"/") || pool.desired == 0 {
+			continue
+		}
+		if pool.scaling.MinInstances > 0 || pool.active() > 0 || len(pool.waiting) > 0 {
+			continue
+		}
+		if victim == nil || pool.lastRequest.Before(victim.lastRequest) {
+			victim = pool
+		}
+	}
+	if victim == nil {
+		return false
+	}
+	victim.scale(0, ScaleReasonEvicted)
+	return true
+}
+
+// broadcastFreed wakes requests waiting for room to start their agent.
+// The caller holds the lock.
+func (p *instancePools) broadcastFreed() {
+	close(p.freed)
+	p.freed = make(chan struct{})
+}
+
+func sameScaling(a, b agent.ScalingConfig) bool {
+	if a.Autoscale == nil || b.Autoscale == nil {
+		return a == b
+	}
+	autoscale := *a.Autoscale == *b.Autoscale
+	a.Autoscale, b.Autoscale = nil, nil
+	return autoscale && a == b
+}
+
+// clampInstances fits n within an agent's scaling limits.
+func clampInstances(scaling agent.ScalingConfig, n int) int {
+	if n < scaling.MinInstances {
+		n = scaling.MinInstances
+	}
+	if scaling.MaxInstances > 0 && n > scaling.MaxInstances {
+		n = scaling.MaxInstances
+	}
+	return n
+}
+
+// scale resizes the pool and records why.
+func (pool *instancePool) scale(n int, reason string) ScaleEvent {
+	event := ScaleEvent{From: pool.desired, To: n, Reason: reason, Time: time.Now()}
+	pool.lastScaled = &event
+	pool.resize(n)
+	return event
+}
+
+// resize sets the number of instances taking requests. Instances are
+// added, or drained, the least busy first. The caller holds the lock.
+func (pool *instancePool) resize(n int) {
+	pool.desired = n
+	
+	var running []*agentInstance
+	for _, inst := range pool.instances {
+		if !inst.draining {
+			running = append(running, inst)
+		}
+	}
+	// Instances still draining are put back to work before new ones start
+	for _, inst := range pool.instances {
+		if len(running) >= n {
+			break
+		}
+		if inst.draining {
+			inst.draining = false
+			running = append(running, inst)
+		}
+	}
+	for len(running) < n {
+		pool.next++
+		inst := &agentInstance{
+			id:        fmt.Sprintf("%s-%d", pool.name, pool.next),
+			startedAt: time.Now(),
+		}
+		pool.instances = append(pool.instances, inst)
+		running = append(running, inst)
+	}
+	
+	if len(running) > n {
+		sort.SliceStable(running, func(i, j int) bool {
+			return running[i].active < running[j].active
+		})
+		for _, inst := range running[:len(running)-n] {
+			inst.draining = true
+		}
+	}
+	pool.prune()
+	pool.broadcast()
+}
+
+// prune removes drained instances that have no requests left.
+func (pool *instancePool) prune() {
+	kept := pool.instances[:0]
+	for _, inst := range pool.instances {
+		if !inst.draining || inst.active > 0 {
+			kept = append(kept, inst)
+		}
+	}
+	pool.instances = kept
+}
+
+func (pool *instancePool) broadcast() {
+	close(pool.changed)
+	pool.changed = make(chan struct{})
+}
+
+// pick returns the least busy instance that can take another request.
+func (pool *instancePool) pick() *agentInstance {
+	var best *agentInstance
+	for _, inst := range pool.instances {
+		if inst.draining {
+			continue
+		}
+		if pool.scaling.MaxConcurrency > 0 && inst.active >= pool.scaling.MaxConcurrency {
+			continue
+		}
+		if best == nil || inst.active < best.active || inst.active == best.active && inst.served < best.served {
+			best = inst
+		}
+	}
+	return best
+}
+
+// acquire holds an instance of the agent for a request. When every
+// instance is busy, or other requests are queued ahead of it, the request
+// is queued by priority until an instance frees up for it, its context is
+// done or the agent's queue timeout passes. A request that finds the queue
+// full is turned away with a *QueueFullError. An agent scaled to zero
+// starts an instance for it, once its cluster has room for another
+// running agent.
+func (p *instancePools) acquire(ctx context.Context, clusterName string, a *agent.Agent, priority string) (*instanceLease, error) {
+	started := time.Now()
+	p.mu.Lock()
+	pool := p.pool(clusterName, a)
+	pool.lastRequest = started
+	
+	var timeout <-chan time.Time
+	var waiter *queuedRequest
+	coldStart := false
+	defer func() {
+		if waiter != nil {
+			pool.dequeue(waiter)
+		}
+		p.mu.Unlock()
+	}()
+	for {
+		if pool.closed {
+			return nil, fmt.Errorf("%w: %s was stopped", ErrAgentUnavailable, a.GetName())
+		}
+		if waiter != nil && waiter.shed {
+			waiter = nil
+			return nil, pool.queueFull(clusterName, time.Now())
+		}
+		if pool.desired == 0 && p.makeRoom(clusterName) {
+			pool.scale(1, ScaleReasonRequest)
+			coldStart = true
+		}
+		if pool.first(waiter) {
+			if inst := pool.pick(); inst != nil {
+				inst.active++
+				if active := pool.active(); active > pool.peakActive {
+					pool.peakActive = active
+				}
+				return &instanceLease{pools: p, pool: pool, instance: inst, started: started, queued: time.Since(started), coldStart: coldStart}, nil
+			}
+		}
+		
+		if waiter == nil {
+			var ok bool
+			if waiter, ok = pool.enqueue(priority, started); !ok {
+				return nil, pool.queueFull(clusterName, started)
+			}
+			wait := pool.scaling.QueueTimeout
+			if wait <= 0 {
+				wait = defaultQueueTimeout
+			}
+			timer := time.NewTimer(wait)
+			defer timer.Stop()
+			timeout = timer.C
+			// A request of higher priority may go ahead of those queued
+			continue
+		}
+		changed, freed := pool.changed, p.freed
+		p.mu.Unlock()
+		
+		var err error
+		timedOut := false
+		select {
+		case <-changed:
+		case <-freed:
+		case <-ctx.Done():
+			err = ctx.Err()
+		case <-timeout:
+			timedOut = true
+		}
+		p.mu.Lock()
+		if timedOut {
+			// The pool is scaled under the lock, so its size is read here
+			err = fmt.Errorf("%w: all instances of %s stayed busy", ErrAgentBusy, a.GetName())
+			if pool.desired == 0 {
+				err = fmt.Errorf("%w: cluster %s has no room to start %s", ErrAgentBusy, clusterName, a.GetName())
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// id returns the ID of the instance held.
+func (l *instanceLease) id() string {
+	return l.instance.id
+}
+
+// countTokens adds tokens the request used to the load of its agent.
+func (l *instanceLease) countTokens(tokens int) {
+	l.tokens += tokens
+}
+
+// release lets the instance take another request.
+func (l *instanceLease) release() {
+	l.pools.mu.Lock()
+	defer l.pools.mu.Unlock()
+	
+	now := time.Now()
+	l.pool.record(loadSample{at: now, latency: now.Sub(l.started), queued: l.queued, tokens: l.tokens})
+	l.instance.active--
+	l.instance.served++
+	if l.instance.draining && l.instance.active == 0 {
+		l.pool.prune()
+	}
+	if !l.pool.closed {
+		l.pool.broadcast()
+	}
+	l.pools.broadcastFreed()
+}
+
+// scale resizes an agent's pool.
+func (p *instancePools) scale(clusterName string, a *agent.Agent, n int, reason string) (*AgentInstances, ScaleEvent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	pool := p.pool(clusterName, a)
+	event := pool.scale(n, reason)
+	if n == 0 {
+		p.broadcastFreed()
+	}
+	return pool.status(clusterName), event
+}
+
+// canStart reports whether an agent may start instances in its cluster,
+// because it has some already or the cluster has room for it.
+func (p *instancePools) canStart(clusterName string, a *agent.Agent) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if p.pool(clusterName, a).desired > 0 {
+		return true
+	}
+	limit, ok := p.limits[clusterName]
+	return !ok || p.running(clusterName) < limit
+}
+
+// status returns an agent's pool, which it has once it was deployed.
+func (p *instancePools) status(clusterName string, a *agent.Agent) *AgentInstances {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	return p.pool(clusterName, a).status(clusterName)
+}
+
+func (pool *instancePool) status(clusterName string) *AgentInstances {
+	status := &AgentInstances{
+		Cluster:          clusterName,
+		Agent:            pool.name,
+		Desired:          pool.desired,
+		MinInstances:     pool.scaling.MinInstances,
+		MaxInstances:     pool.scaling.MaxInstances,
+		MaxConcurrency:   pool.scaling.MaxConcurrency,
+		Queued:           len(pool.waiting),
+		MaxQueueDepth:    pool.maxQueueDepth(),
+		Warm:             pool.warm,
+		Load:             pool.load(time.Now()),
+		LastScaled:       pool.lastScaled,
+		Instances:        make([]AgentInstance, 0, len(pool.instances)),
+		QueuedByPriority: pool.queuedByPriority(),
+		Rejected:         pool.rejected,
+	}
+	for _, inst := range pool.instances {
+		status.Instances = append(status.Instances, AgentInstance{
+			ID:        inst.id,
+			Active:    inst.active,
+			Served:    inst.served,
+			Draining:  inst.draining,
+			StartedAt: inst.startedAt,
+		})
+	}
+	return status
+}
+
+func (p *instancePools) rename(clusterName, agentName, newName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	pool, ok := p.pools[budgetKey(clusterName, agentName)]
+	if !ok {
+		return
+	}
+	delete(p.pools, budgetKey(clusterName, agentName))
+	p.pools[budgetKey(clusterName, newName)] = pool
+	pool.name = newName
+}
+
+// remove drops the pool of an agent removed from its cluster. Requests
+// still queued for it fail.
+func (p *instancePools) remove(clusterName, agentName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	key := budgetKey(clusterName, agentName)
+	if pool, ok := p.pools[key]; ok {
+		pool.closed = true
+		pool.broadcast()
+		delete(p.pools, key)
+		p.broadcastFreed()
+	}
+}
+
+// forget drops the pools of a cluster's agents. Requests still queued for
+// them fail.
+func (p *instancePools) forget(clusterName string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	for key, pool := range p.pools {
+		if strings.HasPrefix(key, clusterName+"/") {
+			pool.closed = true
+			pool.broadcast()
+			delete(p.pools, key)
+		}
+	}
+	delete(p.limits, clusterName)
+	p.broadcastFreed()
+}
+
+// ScaleAgent sets the number of instances serving an agent's requests,
+// within its scaling limits. Scaling down drains instances: they finish
+// the requests they have and take no more.
+func (e *Engine) ScaleAgent(clusterName, agentName string, instances int) (*AgentInstances, error) {
+	targetAgent, err := e.deployedAgent(clusterName, agentName)
+	if err != nil {
+		return nil, err
+	}
+	
+	scaling := targetAgent.Config.Scaling
+	if instances < 0 {
+		return nil, fmt.Errorf("instances must not be negative")
+	}
+	if instances < scaling.MinInstances {
+		return nil, fmt.Errorf("agent %s runs at least %d instances", agentName, scaling.MinInstances)
+	}
+	if scaling.MaxInstances > 0 && instances > scaling.MaxInstances {
+		return nil, fmt.Errorf("agent %s runs at most %d instances", agentName, scaling.MaxInstances)
+	}
+	if instances > 0 && !e.instances.canStart(clusterName, targetAgent) {
+		return nil, fmt.Errorf("cluster %s is running its maximum number of agents", clusterName)
+	}
+	
+	status, event := e.instances.scale(clusterName, targetAgent, instances, ScaleReasonManual)
+	e.agentScaled(targetAgent, event)
+	return status, nil
+}
+
+// AgentInstances returns the instances serving an agent's requests.
+func (e *Engine) AgentInstances(agentID string) (*AgentInstances, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	return e.instances.status(a.ClusterName, a), nil
+}
+
+// deployedAgent returns the agent serving a cluster's requests for a name.
+func (e *Engine) deployedAgent(clusterName, agentName string) (*agent.Agent, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+	
+	a, ok := cluster.Agents[agentName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	return a, nil
+}
\ No newline at end of file
@@ -0,0 +1,583 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultJobWorkers   = 4
+	defaultJobMaxQueued = 100
+	defaultJobTTL       = 24 * time.Hour
+	// maxJobEvents bounds the progress events kept per job; the oldest are
+	// dropped first
+	maxJobEvents = 200
+	// Jobs queued before a restart wait up to jobResumeTimeout for their
+	// cluster to start again, checking every jobResumeInterval
+	jobResumeTimeout  = 5 * time.Minute
+	jobResumeInterval = 250 * time.Millisecond
+)
+
+type JobStatus string
+
+const (
+	JobStatusQueued    JobStatus = "queued"
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Job progress events
+const (
+	JobEventQueued  = "queued"
+	JobEventStarted = "started"
+	// JobEventTurn is a call to the model, one per turn of the request
+	JobEventTurn = "turn"
+	// JobEventToolCalls lists the tools the model is calling, with what it
+	// said alongside
+	JobEventToolCalls = "tool_calls"
+	JobEventFinished  = "finished"
+)
+
+// JobEvent reports the progress of a job.
+type JobEvent struct {
+	Type    string   `json:"type"`
+	Turn    int      `json:"turn,omitempty"`
+	Tools   []string `json:"tools,omitempty"`
+	Content string   `json:"content,omitempty"`
+	// Status is the job's final status, in finished events
+	Status JobStatus `json:"status,omitempty"`
+	Time   time.Time `json:"time"`
+}
+
+// Job is an agent request run in the background, for work that takes
+// longer than a client can wait on one HTTP request.
+type Job struct {
+	ID         string          `json:"id"`
+	Cluster    string          `json:"cluster"`
+	Agent      string          `json:"agent"`
+	Status     JobStatus       `json:"status"`
+	Request    *agent.Request  `json:"request"`
+	Response   *agent.Response `json:"response,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Events     []JobEvent      `json:"events"`
+	CreatedAt  time.Time       `json:"created_at"`
+	StartedAt  *time.Time      `json:"started_at,omitempty"`
+	FinishedAt *time.Time      `json:"finished_at,omitempty"`
+	// dropped counts the events dropped from the front of Events
+	dropped   int
+	cancel    context.CancelFunc
+	cancelled bool
+	// changed is closed and replaced whenever an event is added
+	changed chan struct{}
+}
+
+func (j *Job) finished() bool {
+	return j.FinishedAt != nil
+}
+
+// addEvent records a progress event and wakes those watching the job; the
+// caller holds the queue's lock.
+func (j *Job) addEvent(event JobEvent) {
+	event.Time = time.Now().UTC()
+	j.Events = append(j.Events, event)
+	if len(j.Events) > maxJobEvents {
+		j.Events = j.Events[1:]
+		j.dropped++
+	}
+	close(j.changed)
+	j.changed = make(chan struct{})
+}
+
+func (j *Job) finish(status JobStatus) {
+	now := time.Now().UTC()
+	j.Status = status
+	j.FinishedAt = &now
+	j.cancel = nil
+	j.addEvent(JobEvent{Type: JobEventFinished, Status: status})
+}
+
+func (j *Job) copy() *Job {
+	c := *j
+	c.Events = append([]JobEvent(nil), j.Events...)
+	return &c
+}
+
+// jobQueue holds jobs until one of the workers runs them, and keeps them
+// for TTL once they finish, writing them all to a JSON file when a path is
+// set. Jobs are updated by the workers and read by status queries, so both
+// go through the queue's lock, and readers get copies.
+type jobQueue struct {
+	config config.JobsConfig
+	jobs   map[string]*Job
+	queue  chan *Job
+	// resumed are the jobs the last server queued but did not start, in
+	// the order they were submitted, for the engine to queue again
+	resumed []*Job
+	mu      sync.Mutex
+}
+
+func newJobQueue(cfg config.JobsConfig) (*jobQueue, error) {
+	if cfg.Workers <= 0 {
+		cfg.Workers = defaultJobWorkers
+	}
+	if cfg.MaxQueued <= 0 {
+		cfg.MaxQueued = defaultJobMaxQueued
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultJobTTL
+	}
+	q := &jobQueue{
+		config: cfg,
+		jobs:   make(map[string]*Job),
+		queue:  make(chan *Job, cfg.MaxQueued),
+	}
+	
+	if cfg.Path == "" {
+		return q, nil
+	}
+	data, err := os.ReadFile(cfg.Path)
+	if os.IsNotExist(err) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs file: %w", err)
+	}
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs file: %w", err)
+	}
+	// Requests cannot be resumed part way, so jobs the last server was
+	// running fail; those it had not started yet are run again
+	for _, job := range jobs {
+		job.changed = make(chan struct{})
+		switch {
+		case job.finished():
+		case job.Status == JobStatusQueued:
+			q.resumed = append(q.resumed, job)
+		default:
+			job.Error = "interrupted by server restart"
+			job.finish(JobStatusFailed)
+		}
+		q.jobs[job.ID] = job
+	}
+	sort.Slice(q.resumed, func(i, j int) bool {
+		return q.resumed[i].CreatedAt.Before(q.resumed[j].CreatedAt)
+	})
+	return q, q.save()
+}
+
+// prune forgets jobs that finished more than TTL ago; the caller holds the
+// lock.
+func (q *jobQueue) prune() {
+	cutoff := time.Now().Add(-q.config.TTL)
+	for id, job := range q.jobs {
+		if job.finished() && job.FinishedAt.Before(cutoff) {
+			delete(q.jobs, id)
+		}
+	}
+}
+
+// unfinished counts the jobs queued and running.
+func (q *jobQueue) unfinished() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	
+	count := 0
+	for _, job := range q.jobs {
+		if !job.finished() {
+			count++
+		}
+	}
+	return count
+}
+
+// save writes the jobs to the queue's file, if it has one; the caller holds
+// the lock.
+func (q *jobQueue) save() error {
+	if q.config.Path == "" {
+		return nil
+	}
+	
+	jobs := make([]*Job, 0, len(q.jobs))
+	for _, job := range q.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	data, err := json.Marshal(jobs)
+	if err != nil {
+		return fmt.Errorf("failed to encode jobs: %w", err)
+	}
+	
+	tmp, err := os.CreateTemp(filepath.Dir(q.config.Path), ".jobs-*")
+	if err != nil {
+		return fmt.Errorf("failed to write jobs file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write jobs file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write jobs file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), q.config.Path); err != nil {
+		return fmt.Errorf("failed to write jobs file: %w", err)
+	}
+	return nil
+}
+
+// saveJobs saves the jobs, logging rather than failing the change that
+// prompted it; the caller holds the lock.
+func (e *Engine) saveJobs() {
+	if err := e.jobs.save(); err != nil {
+		e.logger.Warn("Failed to save jobs", zap.Error(err))
+	}
+}
+
+type progressKey struct{}
+
+// withProgress makes the request run under ctx report its progress to fn.
+func withProgress(ctx context.Context, fn func(JobEvent)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+func reportProgress(ctx context.Context, event JobEvent) {
+	if fn, ok := ctx.Value(progressKey{}).(func(JobEvent)); ok {
+		fn(event)
+	}
+}
+
+func toolNames(toolUses []providers.ToolUse) []string {
+	names := make([]string, len(toolUses))
+	for i, toolUse := range toolUses {
+		names[i] = toolUse.Name
+	}
+	return names
+}
+
+func newJobID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate job ID: %w", err)
+	}
+	return "job-" + hex.EncodeToString(buf), nil
+}
+
+// resumeJobs queues again the jobs the last server queued but did not
+// start. Each waits for its cluster, which is restored as the server
+// starts or, in distributed mode, adopted at the first sync, to finish
+// starting first.
+func (e *Engine) resumeJobs() {
+	e.jobs.mu.Lock()
+	resumed := e.jobs.resumed
+	e.jobs.resumed = nil
+	e.jobs.mu.Unlock()
+	
+	deadline := time.Now().Add(jobResumeTimeout)
+	for _, job := range resumed {
+		for !e.clusterStarted(job.Cluster) && time.Now().Before(deadline) {
+			select {
+			case <-e.done:
+				return
+			case <-time.After(jobResumeInterval):
+			}
+		}
+		select {
+		case e.jobs.queue <- job:
+		case <-e.done:
+			return
+		}
+		e.logger.Info("Job resumed",
+			zap.String("job", job.ID),
+			zap.String("cluster", job.Cluster),
+			zap.String("agent", job.Agent))
+	}
+}
+
+// clusterStarted reports whether a cluster has finished starting from its
+// current spec, or will not start as it is stopped or failed. A cluster
+// that does not exist may yet be restored or adopted.
+func (e *Engine) clusterStarted(name string) bool {
+	cluster, err := e.getCluster(name)
+	if err != nil {
+		return false
+	}
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+	switch cluster.Status {
+	case ClusterStatusStopped, ClusterStatusFailed:
+		return true
+	}
+	return cluster.started == cluster.Config
+}
+
+// runJobs is a worker that runs queued jobs until the engine shuts down.
+func (e *Engine) runJobs() {
+	defer e.jobWorkers.Done()
+	for {
+		select {
+		case <-e.done:
+			return
+		case job := <-e.jobs.queue:
+			e.runJob(job)
+		}
+	}
+}
+
+func (e *Engine) runJob(job *Job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	e.jobs.mu.Lock()
+	// Jobs cancelled while queued have already finished
+	if job.finished() {
+		e.jobs.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	job.Status = JobStatusRunning
+	job.StartedAt = &now
+	job.cancel = cancel
+	job.addEvent(JobEvent{Type: JobEventStarted})
+	e.saveJobs()
+	e.jobs.mu.Unlock()
+	
+	e.logger.Info("Job started",
+		zap.String("job", job.ID),
+		zap.String("cluster", job.Cluster),
+		zap.String("agent", job.Agent))
+	
+	ctx = withProgress(ctx, func(event JobEvent) {
+		e.jobs.mu.Lock()
+		job.addEvent(event)
+		e.jobs.mu.Unlock()
+	})
+	go func() {
+		select {
+		case <-e.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	resp, err := e.processRequest(ctx, job.Cluster, job.Agent, job.Request)
+	
+	e.jobs.mu.Lock()
+	status := JobStatusSucceeded
+	switch {
+	case job.cancelled:
+		status = JobStatusCancelled
+	case err != nil:
+		status = JobStatusFailed
+		job.Error = err.Error()
+	case resp.Error != "":
+		status = JobStatusFailed
+		job.Error = resp.Error
+		job.Response = resp
+	default:
+		job.Response = resp
+	}
+	select {
+	case <-e.done:
+		if status == JobStatusFailed {
+			job.Error = "server shut down"
+		}
+	default:
+	}
+	job.finish(status)
+	e.saveJobs()
+	e.publishJobCompleted(job)
+	e.jobs.mu.Unlock()
+	
+	e.logger.Info("Job finished",
+		zap.String("job", job.ID),
+		zap.String("status", string(status)))
+}
+
+// publishJobCompleted publishes that a job finished, whatever its status;
+// the caller holds the queue's lock.
+func (e *Engine) publishJobCompleted(job *Job) {
+	data := map[string]interface{}{
+		"job_id":  job.ID,
+		"cluster": job.Cluster,
+		"agent":   job.Agent,
+		"status":  job.Status,
+	}
+	if job.Error != "" {
+		data["error"] = job.Error
+	}
+	if job.StartedAt != nil {
+		data["duration_ms"] = job.FinishedAt.Sub(*job.StartedAt).Milliseconds()
+	}
+	e.publishEvent(agent.Event{
+		Type: agent.EventJobCompleted,
+		Data: data,
+	})
+}
+
+// SubmitJob queues a request to an agent and returns the job that will run
+// it. It fails with ErrJobQueueFull when too many jobs are waiting, and
+// with ErrDraining while the engine drains.
+func (e *Engine) SubmitJob(clusterName, agentName string, req *agent.Request) (*Job, error) {
+	if e.Draining() {
+		return nil, fmt.Errorf("%w: no new jobs are taken", ErrDraining)
+	}
+	if _, _, err := e.resolveAgent(clusterName, agentName); err != nil {
+		return nil, err
+	}
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		ID:        id,
+		Cluster:   clusterName,
+		Agent:     agentName,
+		Status:    JobStatusQueued,
+		Request:   req,
+		CreatedAt: time.Now().UTC(),
+		changed:   make(chan struct{}),
+	}
+	
+	e.jobs.mu.Lock()
+	defer e.jobs.mu.Unlock()
+	
+	select {
+	case e.jobs.queue <- job:
+	default:
+		return nil, fmt.Errorf("%w: %d jobs are waiting", ErrJobQueueFull, cap(e.jobs.queue))
+	}
+	job.addEvent(JobEvent{Type: JobEventQueued})
+	e.jobs.prune()
+	e.jobs.jobs[id] = job
+	e.saveJobs()
+	
+	e.logger.Info("Job queued",
+		zap.String("job", id),
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName))
+	return job.copy(), nil
+}
+
+// Job returns a job by ID.
+func (e *Engine) Job(id string) (*Job, error) {
+	e.jobs.mu.Lock()
+	defer e.jobs.mu.Unlock()
+	
+	job, ok := e.jobs.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return job.copy(), nil
+}
+
+// Jobs returns the kept jobs of an agent, newest first.
+func (e *Engine) Jobs(clusterName, agentName string) []*Job {
+	e.jobs.mu.Lock()
+	defer e.jobs.mu.Unlock()
+	
+	jobs := make([]*Job, 0)
+	for _, job := range e.jobs.jobs {
+		if job.Cluster == clusterName && job.Agent == agentName {
+			jobs = append(jobs, job.copy())
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+	return jobs
+}
+
+// CancelJob cancels a queued or running job. A running job finishes once
+// its request stops.
+func (e *Engine) CancelJob(id string) (*Job, error) {
+	e.jobs.mu.Lock()
+	defer e.jobs.mu.Unlock()
+	
+	job, ok := e.jobs.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if job.finished() {
+		return nil, fmt.Errorf("%w: job %s has already finished", ErrConflict, id)
+	}
+	
+	job.cancelled = true
+	if job.cancel != nil {
+		job.cancel()
+	} else {
+		job.finish(JobStatusCancelled)
+		e.saveJobs()
+		e.publishJobCompleted(job)
+	}
+	
+	e.logger.Info("Job cancelled", zap.String("job", id))
+	return job.copy(), nil
+}
+
+// JobEvents streams a job's progress events, from the first one kept, until
+// the job finishes or ctx is done.
+func (e *Engine) JobEvents(ctx context.Context, id string) (<-chan JobEvent, error) {
+	e.jobs.mu.Lock()
+	job, ok := e.jobs.jobs[id]
+	e.jobs.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	
+	events := make(chan JobEvent)
+	go func() {
+		defer close(events)
+		
+		// next counts events from the job's first, including dropped ones
+		next := 0
+		for {
+			e.jobs.mu.Lock()
+			start := next - job.dropped
+			if start < 0 {
+				start = 0
+			}
+			pending := append([]JobEvent(nil), job.Events[start:]...)
+			next = job.dropped + len(job.Events)
+			finished := job.finished()
+			changed := job.changed
+			e.jobs.mu.Unlock()
+			
+			for _, event := range pending {
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if finished {
+				return
+			}
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			case <-e.done:
+				return
+			}
+		}
+	}()
+	return events, nil
+}
\ No newline at end of file
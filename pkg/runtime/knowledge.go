@@ -0,0 +1,347 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/knowledge"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultKnowledgeTopK            = 4
+	defaultKnowledgeScoreThreshold  = 0.3
+	defaultKnowledgeMaxDocumentSize = 10 << 20
+	// knowledgeEmbedBatch bounds the chunks embedded in one provider call
+	knowledgeEmbedBatch   = 64
+	knowledgeFetchTimeout = 30 * time.Second
+)
+
+// DocumentInput is a document to add to an agent's knowledge base. It is
+// read from Data, uploaded with the request, from the stored file FileID,
+// from URL, or from Content, in that order.
+type DocumentInput struct {
+	Title    string            `json:"title,omitempty"`
+	URL      string            `json:"url,omitempty"`
+	FileID   string            `json:"file_id,omitempty"`
+	Content  string            `json:"content,omitempty"`
+	MimeType string            `json:"mime_type,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	// Name is the uploaded file's name
+	Name string `json:"-"`
+	Data []byte `json:"-"`
+}
+
+// Citation is a source added to a prompt, numbered as the model was asked
+// to cite it.
+type Citation struct {
+	Index      int     `json:"index"`
+	DocumentID string  `json:"document_id"`
+	Title      string  `json:"title,omitempty"`
+	Source     string  `json:"source,omitempty"`
+	Chunk      int     `json:"chunk"`
+	Score      float64 `json:"score"`
+}
+
+func (e *Engine) initializeKnowledge() error {
+	store, err := knowledge.NewStore(e.config.Knowledge.Path)
+	if err != nil {
+		return err
+	}
+	e.knowledge = store
+	return nil
+}
+
+// knowledgeAgent returns the agent with the ID if it has a knowledge base.
+func (e *Engine) knowledgeAgent(agentID string) (*agent.Agent, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	if !a.Config.Knowledge.Enabled {
+		return nil, fmt.Errorf("%w: agent %s", ErrKnowledgeDisabled, agentID)
+	}
+	return a, nil
+}
+
+// IngestDocument reads a document, splits it into chunks and stores them
+// with their embeddings in the agent's knowledge base.
+func (e *Engine) IngestDocument(ctx context.Context, agentID string, input *DocumentInput) (*knowledge.Document, error) {
+	a, err := e.knowledgeAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+	
+	data, mimeType, source, err := e.readDocument(ctx, a, input)
+	if err != nil {
+		return nil, err
+	}
+	maxSize := e.config.Knowledge.MaxDocumentSize
+	if maxSize == 0 {
+		maxSize = defaultKnowledgeMaxDocumentSize
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the %d byte limit", knowledge.ErrTooLarge, len(data), maxSize)
+	}
+	
+	title, text, err := knowledge.Extract(data, mimeType, source)
+	if err != nil {
+		return nil, err
+	}
+	cfg := a.Config.Knowledge
+	texts, err := knowledge.Split(text, knowledge.ChunkOptions{
+		Strategy: cfg.ChunkStrategy,
+		Size:     cfg.ChunkSize,
+		Overlap:  cfg.ChunkOverlap,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("%w: no text", knowledge.ErrUnsupported)
+	}
+	
+	embed, err := e.embedder(a.ClusterName, a, cfg.Provider, cfg.EmbeddingModel)
+	if err != nil {
+		return nil, err
+	}
+	chunks := make([]knowledge.Chunk, 0, len(texts))
+	for start := 0; start < len(texts); start += knowledgeEmbedBatch {
+		end := start + knowledgeEmbedBatch
+		if end > len(texts) {
+			end = len(texts)
+		}
+		vectors, err := embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to embed document: %w", err)
+		}
+		for i, vector := range vectors {
+			chunks = append(chunks, knowledge.Chunk{
+				Index:     start + i,
+				Content:   texts[start+i],
+				Embedding: vector,
+			})
+		}
+	}
+	
+	doc, err := knowledge.NewDocument(a.ClusterName, a.GetName())
+	if err != nil {
+		return nil, err
+	}
+	doc.Title = input.Title
+	if doc.Title == "" {
+		doc.Title = title
+	}
+	doc.Source = source
+	doc.MimeType = knowledge.DetectType(data, mimeType, source)
+	doc.Size = len(data)
+	doc.Metadata = input.Metadata
+	if err := e.knowledge.Add(doc, chunks); err != nil {
+		return nil, err
+	}
+	
+	e.logger.Info("Ingested document",
+		zap.String("cluster", a.ClusterName),
+		zap.String("agent", a.GetName()),
+		zap.String("document", doc.ID),
+		zap.Int("chunks", len(chunks)))
+	return doc, nil
+}
+
+// readDocument returns a document's content, MIME type and source.
+func (e *Engine) readDocument(ctx context.Context, a *agent.Agent, input *DocumentInput) ([]byte, string, string, error) {
+	switch {
+	case input.Data != nil:
+		return input.Data, input.MimeType, input.Name, nil
+	case input.FileID != "":
+		file, data, err := e.files.Get(ctx, input.FileID)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return data, file.MimeType, file.Name, nil
+	case input.URL != "":
+		return e.fetchDocument(ctx, a, input.URL)
+	case input.Content != "":
+		mimeType := input.MimeType
+		if mimeType == "" {
+			mimeType = "text/plain"
+		}
+		return []byte(input.Content), mimeType, "", nil
+	default:
+		return nil, "", "", fmt.Errorf("a file, file_id, url or content is required")
+	}
+}
+
+// fetchDocument downloads a document under the cluster's outbound policy,
+// like the tools that fetch URLs.
+func (e *Engine) fetchDocument(ctx context.Context, a *agent.Agent, rawURL string) ([]byte, string, string, error) {
+	cluster, err := e.getCluster(a.ClusterName)
+	if err != nil {
+		return nil, "", "", err
+	}
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid url: %q", rawURL)
+	}
+	policy := e.outboundPolicy(cluster)
+	if err := policy.CheckURL(target); err != nil {
+		return nil, "", "", err
+	}
+	
+	client := &http.Client{
+		Timeout:       knowledgeFetchTimeout,
+		Transport:     policy.Transport(),
+		CheckRedirect: policy.CheckRedirect,
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to fetch document: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, "", "", fmt.Errorf("failed to fetch document: HTTP %d", resp.StatusCode)
+	}
+	
+	maxSize := e.config.Knowledge.MaxDocumentSize
+	if maxSize == 0 {
+		maxSize = defaultKnowledgeMaxDocumentSize
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to read document: %w", err)
+	}
+	
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	// The file name lets markdown served as text/plain be recognized
+	if path.Ext(target.Path) != "" && strings.HasPrefix(mimeType, "text/plain") {
+		mimeType = ""
+	}
+	return data, mimeType, target.String(), nil
+}
+
+// Documents returns the documents in an agent's knowledge base, most
+// recently ingested first.
+func (e *Engine) Documents(agentID string) ([]knowledge.Document, error) {
+	a, err := e.knowledgeAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+	return e.knowledge.Documents(a.ClusterName, a.GetName()), nil
+}
+
+func (e *Engine) DeleteDocument(agentID, id string) error {
+	a, err := e.knowledgeAgent(agentID)
+	if err != nil {
+		return err
+	}
+	return e.knowledge.Delete(a.ClusterName, a.GetName(), id)
+}
+
+// SearchKnowledge returns the chunks of an agent's knowledge base closest
+// to a query, as they would be retrieved for a request. A topK of zero uses
+// the agent's.
+func (e *Engine) SearchKnowledge(ctx context.Context, agentID, query string, topK int) ([]knowledge.Match, error) {
+	a, err := e.knowledgeAgent(agentID)
+	if err != nil {
+		return nil, err
+	}
+	return e.searchKnowledge(ctx, a.ClusterName, a, query, topK)
+}
+
+func (e *Engine) searchKnowledge(ctx context.Context, clusterName string, targetAgent *agent.Agent, query string, topK int) ([]knowledge.Match, error) {
+	cfg := targetAgent.Config.Knowledge
+	if topK == 0 {
+		topK = cfg.TopK
+	}
+	if topK == 0 {
+		topK = defaultKnowledgeTopK
+	}
+	threshold := cfg.ScoreThreshold
+	if threshold == 0 {
+		threshold = defaultKnowledgeScoreThreshold
+	}
+	
+	embed, err := e.embedder(clusterName, targetAgent, cfg.Provider, cfg.EmbeddingModel)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return e.knowledge.Search(clusterName, targetAgent.GetName(), vectors[0], topK, threshold), nil
+}
+
+// retrieveKnowledge adds the chunks of the agent's knowledge base closest
+// to the request's last user message to the prompt as numbered sources,
+// and returns them as citations. Retrieval failures are logged rather than
+// failing the request. Requests opt out with a knowledge context value of
+// false.
+func (e *Engine) retrieveKnowledge(ctx context.Context, clusterName string, targetAgent *agent.Agent, req *agent.Request, providerReq *providers.ChatRequest) []Citation {
+	if !targetAgent.Config.Knowledge.Enabled {
+		return nil
+	}
+	if enabled, ok := req.Context["knowledge"].(bool); ok && !enabled {
+		return nil
+	}
+	query := lastUserMessage(req.Messages)
+	if query == "" {
+		return nil
+	}
+	
+	matches, err := e.searchKnowledge(ctx, clusterName, targetAgent, query, 0)
+	if err != nil {
+		e.logger.Warn("Failed to retrieve knowledge",
+			zap.String("cluster", clusterName),
+			zap.String("agent", targetAgent.GetName()),
+			zap.Error(err))
+		return nil
+	}
+	if len(matches) == 0 {
+		return nil
+	}
+	
+	var content strings.Builder
+	content.WriteString("Sources from your knowledge base. When you use one, cite it by its number, like [1].")
+	citations := make([]Citation, 0, len(matches))
+	for i, match := range matches {
+		citation := Citation{
+			Index:      i + 1,
+			DocumentID: match.Document.ID,
+			Title:      match.Document.Title,
+			Source:     match.Document.Source,
+			Chunk:      match.Index,
+			Score:      match.Score,
+		}
+		citations = append(citations, citation)
+		
+		fmt.Fprintf(&content, "\n\n[%d]", citation.Index)
+		switch {
+		case citation.Title != "" && citation.Source != "":
+			fmt.Fprintf(&content, " %s (%s)", citation.Title, citation.Source)
+		case citation.Title != "":
+			fmt.Fprintf(&content, " %s", citation.Title)
+		case citation.Source != "":
+			fmt.Fprintf(&content, " %s", citation.Source)
+		}
+		fmt.Fprintf(&content, "\n%s", match.Content)
+	}
+	insertSystemMessage(providerReq, content.String())
+	
+	return citations
+}
\ No newline at end of file
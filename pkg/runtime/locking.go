@@ -0,0 +1,65 @@
+package runtime
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// OwnerAnnotation names the owner of a cluster, such as a GitOps controller.
+// Writes to an owned cluster must be made as that owner or be forced.
+const OwnerAnnotation = "goagents.dev/owner"
+
+// Precondition guards a write to a cluster against clobbering someone
+// else's change. An empty ResourceVersion skips the version check. Force
+// overrides the owner annotation but not the version check.
+type Precondition struct {
+	ResourceVersion string
+	Owner           string
+	Force           bool
+}
+
+// checkPrecondition reports an ErrConflict if the cluster has changed since
+// the writer read it or is owned by someone else. A nil precondition is a
+// write with no version and no owner. The cluster's lock must be held.
+func checkPrecondition(cluster *Cluster, pre *Precondition) error {
+	if pre == nil {
+		pre = &Precondition{}
+	}
+	
+	current := cluster.Config.Metadata.ResourceVersion
+	if pre.ResourceVersion != "" && pre.ResourceVersion != current {
+		return fmt.Errorf("%w: cluster %s is at resource version %s, not %s", ErrConflict, cluster.Name, current, pre.ResourceVersion)
+	}
+	
+	owner := cluster.Config.Metadata.Annotations[OwnerAnnotation]
+	if owner != "" && owner != pre.Owner && !pre.Force {
+		return fmt.Errorf("%w: cluster %s is owned by %s", ErrConflict, cluster.Name, owner)
+	}
+	
+	return nil
+}
+
+// nextResourceVersion returns a version that no cluster has had before, so
+// a version read before a cluster was deleted and recreated never matches.
+func (e *Engine) nextResourceVersion() string {
+	return strconv.FormatUint(atomic.AddUint64(&e.resourceVersion, 1), 10)
+}
+
+// raiseResourceVersion makes the versions handed out from now on newer
+// than version. Writers hand versions out concurrently, so it only ever
+// raises the counter.
+func (e *Engine) raiseResourceVersion(version uint64) {
+	for {
+		current := atomic.LoadUint64(&e.resourceVersion)
+		if version <= current || atomic.CompareAndSwapUint64(&e.resourceVersion, current, version) {
+			return
+		}
+	}
+}
+
+// bumpResourceVersion records a write to the cluster. The cluster's lock
+// must be held.
+func (e *Engine) bumpResourceVersion(cluster *Cluster) {
+	cluster.Config.Metadata.ResourceVersion = e.nextResourceVersion()
+}
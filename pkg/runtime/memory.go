@@ -0,0 +1,489 @@
+package runtime
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/providers"
+	"github.com/goagents/goagents/pkg/tools"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMemoryTopK           = 5
+	defaultMemoryScoreThreshold = 0.5
+	defaultMemoryMaxEntries     = 1000
+	// memoryDuplicateScore is how close a new fact must be to a stored one
+	// to count as the same fact
+	memoryDuplicateScore   = 0.95
+	memoryExtractMaxTokens = 512
+	memoryExtractTimeout   = time.Minute
+)
+
+const memoryExtractPrompt = "You pick out what is worth remembering from a conversation. " +
+	"List the lasting facts the exchange below reveals about the user, their preferences, goals and circumstances, " +
+	"or about decisions that were made, one short self-contained sentence per line. " +
+	"Leave out small talk, questions and anything only relevant to this exchange. " +
+	"If there is nothing worth remembering, reply with NONE."
+
+// Memory is a fact an agent keeps from one conversation to the next.
+// Memories belong to an agent's cluster and name, and to the scope of the
+// requests they came from, so one user's facts are never recalled for
+// another when requests carry a memory_scope.
+type Memory struct {
+	ID         string    `json:"id"`
+	Cluster    string    `json:"cluster"`
+	Agent      string    `json:"agent"`
+	Scope      string    `json:"scope,omitempty"`
+	Content    string    `json:"content"`
+	Embedding  []float32 `json:"embedding,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+type memoryMatch struct {
+	memory *Memory
+	score  float64
+}
+
+// memoryStore keeps agents' memories in memory, writing them all to a JSON
+// file when a path is set. Memories are ranked by cosine similarity, which
+// is fast enough for the few thousand facts an agent keeps.
+type memoryStore struct {
+	path       string
+	maxEntries int
+	memories   map[string][]*Memory
+	mu         sync.RWMutex
+}
+
+func newMemoryStore(cfg config.MemoryConfig) (*memoryStore, error) {
+	store := &memoryStore{
+		path:       cfg.Path,
+		maxEntries: cfg.MaxEntries,
+		memories:   make(map[string][]*Memory),
+	}
+	if store.maxEntries == 0 {
+		store.maxEntries = defaultMemoryMaxEntries
+	}
+	
+	if cfg.Path == "" {
+		return store, nil
+	}
+	data, err := os.ReadFile(cfg.Path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read memory file: %w", err)
+	}
+	var memories []*Memory
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return nil, fmt.Errorf("failed to parse memory file: %w", err)
+	}
+	for _, memory := range memories {
+		key := memoryKey(memory.Cluster, memory.Agent)
+		store.memories[key] = append(store.memories[key], memory)
+	}
+	
+	return store, nil
+}
+
+func memoryKey(cluster, agentName string) string {
+	return cluster + "/" + agentName
+}
+
+// add stores a memory, forgetting the agent's least recently used ones
+// beyond maxEntries.
+func (s *memoryStore) add(memory *Memory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	key := memoryKey(memory.Cluster, memory.Agent)
+	memories := append(s.memories[key], memory)
+	if len(memories) > s.maxEntries {
+		sort.SliceStable(memories, func(i, j int) bool {
+			return memories[i].LastUsedAt.After(memories[j].LastUsedAt)
+		})
+		memories = memories[:s.maxEntries]
+	}
+	s.memories[key] = memories
+	
+	return s.save()
+}
+
+// search returns the memories in scope closest to vector, best first, that
+// score at least threshold.
+func (s *memoryStore) search(cluster, agentName, scope string, vector []float32, topK int, threshold float64) []memoryMatch {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	var matches []memoryMatch
+	for _, memory := range s.memories[memoryKey(cluster, agentName)] {
+		if memory.Scope != scope || len(memory.Embedding) != len(vector) {
+			continue
+		}
+		score := tools.CosineSimilarity(vector, memory.Embedding)
+		if score >= threshold {
+			matches = append(matches, memoryMatch{memory: memory, score: score})
+		}
+	}
+	
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches
+}
+
+// touch marks memories as used. It is saved with the next change, since
+// losing it only affects which memories are forgotten first.
+func (s *memoryStore) touch(matches []memoryMatch) {
+	now := time.Now().UTC()
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for _, match := range matches {
+		match.memory.LastUsedAt = now
+	}
+}
+
+// list returns an agent's memories without their embeddings, most recently
+// used first.
+func (s *memoryStore) list(cluster, agentName string) []Memory {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	memories := s.memories[memoryKey(cluster, agentName)]
+	list := make([]Memory, 0, len(memories))
+	for _, memory := range memories {
+		m := *memory
+		m.Embedding = nil
+		list = append(list, m)
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].LastUsedAt.After(list[j].LastUsedAt)
+	})
+	return list
+}
+
+func (s *memoryStore) delete(cluster, agentName, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	key := memoryKey(cluster, agentName)
+	memories := s.memories[key]
+	for i, memory := range memories {
+		if memory.ID == id {
+			s.memories[key] = append(memories[:i:i], memories[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrMemoryNotFound, id)
+}
+
+// clear forgets all of an agent's memories.
+func (s *memoryStore) clear(cluster, agentName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	delete(s.memories, memoryKey(cluster, agentName))
+	return s.save()
+}
+
+// save writes every memory to the file atomically. The caller must hold
+// the write lock.
+func (s *memoryStore) save() error {
+	if s.path == "" {
+		return nil
+	}
+	
+	var memories []*Memory
+	for _, list := range s.memories {
+		memories = append(memories, list...)
+	}
+	data, err := json.Marshal(memories)
+	if err != nil {
+		return fmt.Errorf("failed to encode memories: %w", err)
+	}
+	
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".memory-*")
+	if err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write memory file: %w", err)
+	}
+	
+	return nil
+}
+
+// memoryEnabled reports whether a request uses the agent's memory. Requests
+// opt out with a memory context value of false.
+func memoryEnabled(targetAgent *agent.Agent, req *agent.Request) bool {
+	if !targetAgent.Config.Memory.Enabled {
+		return false
+	}
+	enabled, ok := req.Context["memory"].(bool)
+	return !ok || enabled
+}
+
+// memoryScope returns the memory_scope of a request, such as a user ID.
+func memoryScope(req *agent.Request) string {
+	scope, _ := req.Context["memory_scope"].(string)
+	return scope
+}
+
+// embedder embeds texts with a model of the named provider, or of the
+// agent's provider when providerName is empty.
+func (e *Engine) embedder(clusterName string, targetAgent *agent.Agent, providerName, model string) (tools.EmbedFunc, error) {
+	if providerName == "" {
+		providerName = targetAgent.Config.Provider
+	}
+	provider, exists, err := e.providerFor(e.clusterNamespace(clusterName), providerName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("provider %s not available", providerName)
+	}
+	
+	return func(ctx context.Context, texts []string) ([][]float32, error) {
+		resp, err := providers.Embed(ctx, provider, &providers.EmbeddingRequest{
+			Model: model,
+			Input: texts,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return resp.Embeddings, nil
+	}, nil
+}
+
+func (e *Engine) memoryEmbedder(clusterName string, targetAgent *agent.Agent) (tools.EmbedFunc, error) {
+	cfg := targetAgent.Config.Memory
+	return e.embedder(clusterName, targetAgent, cfg.Provider, cfg.EmbeddingModel)
+}
+
+// insertSystemMessage adds a system message after the system prompt and
+// before the conversation.
+func insertSystemMessage(providerReq *providers.ChatRequest, content string) {
+	at := 0
+	for at < len(providerReq.Messages) && providerReq.Messages[at].Role == "system" {
+		at++
+	}
+	messages := make([]providers.Message, 0, len(providerReq.Messages)+1)
+	messages = append(messages, providerReq.Messages[:at]...)
+	messages = append(messages, providers.Message{Role: "system", Content: content})
+	providerReq.Messages = append(messages, providerReq.Messages[at:]...)
+}
+
+// recallMemories adds the agent's memories closest to the request's last
+// user message to the prompt, after the system prompt, and returns how
+// many were added. Memory failures are logged rather than failing the
+// request.
+func (e *Engine) recallMemories(ctx context.Context, clusterName string, targetAgent *agent.Agent, req *agent.Request, providerReq *providers.ChatRequest) int {
+	if !memoryEnabled(targetAgent, req) {
+		return 0
+	}
+	query := lastUserMessage(req.Messages)
+	if query == "" {
+		return 0
+	}
+	
+	embed, err := e.memoryEmbedder(clusterName, targetAgent)
+	if err == nil {
+		var vectors [][]float32
+		vectors, err = embed(ctx, []string{query})
+		if err == nil {
+			return e.injectMemories(clusterName, targetAgent, req, providerReq, vectors[0])
+		}
+	}
+	e.logger.Warn("Failed to recall memories",
+		zap.String("cluster", clusterName),
+		zap.String("agent", targetAgent.GetName()),
+		zap.Error(err))
+	return 0
+}
+
+func (e *Engine) injectMemories(clusterName string, targetAgent *agent.Agent, req *agent.Request, providerReq *providers.ChatRequest, vector []float32) int {
+	cfg := targetAgent.Config.Memory
+	topK := cfg.TopK
+	if topK == 0 {
+		topK = defaultMemoryTopK
+	}
+	threshold := cfg.ScoreThreshold
+	if threshold == 0 {
+		threshold = defaultMemoryScoreThreshold
+	}
+	
+	matches := e.memories.search(clusterName, targetAgent.GetName(), memoryScope(req), vector, topK, threshold)
+	if len(matches) == 0 {
+		return 0
+	}
+	e.memories.touch(matches)
+	
+	var content strings.Builder
+	content.WriteString("What you remember from earlier conversations:")
+	for _, match := range matches {
+		content.WriteString("\n- ")
+		content.WriteString(match.memory.Content)
+	}
+	insertSystemMessage(providerReq, content.String())
+	
+	return len(matches)
+}
+
+// rememberExchange asks the model that answered for the facts worth keeping from
+// a request and its answer and stores those it does not already know. It
+// runs after the response is returned, so it has its own deadline.
+func (e *Engine) rememberExchange(clusterName string, targetAgent *agent.Agent, provider providers.Provider, model string, req *agent.Request, answer string) {
+	ctx, cancel := context.WithTimeout(context.Background(), memoryExtractTimeout)
+	defer cancel()
+	
+	if err := e.storeMemories(ctx, clusterName, targetAgent, provider, model, req, answer); err != nil {
+		e.logger.Warn("Failed to store memories",
+			zap.String("cluster", clusterName),
+			zap.String("agent", targetAgent.GetName()),
+			zap.Error(err))
+	}
+}
+
+func (e *Engine) storeMemories(ctx context.Context, clusterName string, targetAgent *agent.Agent, provider providers.Provider, model string, req *agent.Request, answer string) error {
+	facts, err := extractFacts(ctx, provider, model, req, answer)
+	if err != nil || len(facts) == 0 {
+		return err
+	}
+	
+	embed, err := e.memoryEmbedder(clusterName, targetAgent)
+	if err != nil {
+		return err
+	}
+	vectors, err := embed(ctx, facts)
+	if err != nil {
+		return err
+	}
+	
+	scope := memoryScope(req)
+	for i, fact := range facts {
+		if known := e.memories.search(clusterName, targetAgent.GetName(), scope, vectors[i], 1, memoryDuplicateScore); len(known) > 0 {
+			e.memories.touch(known)
+			continue
+		}
+		
+		id, err := newMemoryID()
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if err := e.memories.add(&Memory{
+			ID:         id,
+			Cluster:    clusterName,
+			Agent:      targetAgent.GetName(),
+			Scope:      scope,
+			Content:    fact,
+			Embedding:  vectors[i],
+			CreatedAt:  now,
+			LastUsedAt: now,
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFacts returns the facts the model finds worth keeping
+// from the request's messages and the answer, one per line of its reply.
+func extractFacts(ctx context.Context, provider providers.Provider, model string, req *agent.Request, answer string) ([]string, error) {
+	var transcript strings.Builder
+	for _, msg := range req.Messages {
+		if msg.Role == "system" {
+			continue
+		}
+		fmt.Fprintf(&transcript, "%s: %s\n\n", msg.Role, msg.Content)
+	}
+	fmt.Fprintf(&transcript, "assistant: %s\n", answer)
+	
+	resp, err := provider.Chat(ctx, &providers.ChatRequest{
+		Model:     model,
+		MaxTokens: memoryExtractMaxTokens,
+		Messages: []providers.Message{
+			{Role: "system", Content: memoryExtractPrompt},
+			{Role: "user", Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	var facts []string
+	for _, line := range strings.Split(resp.Content, "\n") {
+		fact := strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-*•"))
+		if fact == "" || strings.EqualFold(strings.TrimRight(fact, "."), "none") {
+			continue
+		}
+		facts = append(facts, fact)
+	}
+	return facts, nil
+}
+
+func lastUserMessage(messages []agent.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == "user" {
+			return messages[i].Content
+		}
+	}
+	return ""
+}
+
+func newMemoryID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate memory ID: %w", err)
+	}
+	return "mem-" + hex.EncodeToString(buf), nil
+}
+
+// Memories returns what an agent remembers, most recently used first.
+func (e *Engine) Memories(agentID string) ([]Memory, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	return e.memories.list(a.ClusterName, a.GetName()), nil
+}
+
+// DeleteMemory makes an agent forget one memory, or all of them when id is
+// empty.
+func (e *Engine) DeleteMemory(agentID, id string) error {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	if id == "" {
+		return e.memories.clear(a.ClusterName, a.GetName())
+	}
+	return e.memories.delete(a.ClusterName, a.GetName(), id)
+}
\ No newline at end of file
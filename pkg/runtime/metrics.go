@@ -0,0 +1,327 @@
+package runtime
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets are the upper bounds of the buckets of latency
+// histograms. Latencies past the last bound fall in a bucket of their own.
+var latencyBuckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+	25 * time.Second,
+	time.Minute,
+	2 * time.Minute,
+	5 * time.Minute,
+}
+
+// Metrics is a snapshot of the engine's metrics. Requests are counted when
+// they end, and AverageResponseTime and Latency cover those that
+// succeeded. Agents are keyed by cluster and name, as cluster/agent.
+type Metrics struct {
+	ClustersTotal       int64
+	AgentsTotal         int64
+	RequestsTotal       int64
+	RequestsSucceeded   int64
+	RequestsFailed      int64
+	AverageResponseTime time.Duration
+	Latency             LatencySummary
+	Clusters            map[string]RequestMetrics
+	Agents              map[string]RequestMetrics
+	Providers           map[string]RequestMetrics
+	Models              map[string]RequestMetrics
+}
+
+// RequestMetrics counts the requests of an agent, cluster, namespace,
+// provider or model. Latency covers the requests that succeeded.
+type RequestMetrics struct {
+	RequestsTotal     int64          `json:"requests_total"`
+	RequestsSucceeded int64          `json:"requests_succeeded"`
+	RequestsFailed    int64          `json:"requests_failed"`
+	Latency           LatencySummary `json:"latency"`
+}
+
+// LatencySummary sums up a latency histogram. Percentiles are estimated by
+// interpolating within the bucket they fall in, so they are as precise as
+// the buckets are narrow; those past the last bucket are reported as its
+// bound.
+type LatencySummary struct {
+	Count  int64   `json:"count"`
+	MeanMs float64 `json:"mean_ms"`
+	P50Ms  float64 `json:"p50_ms"`
+	P90Ms  float64 `json:"p90_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+// latencyHistogram counts latencies into latencyBuckets. Its counters are
+// atomic, so requests record into it without a lock.
+type latencyHistogram struct {
+	counts []atomic.Int64
+	sum    atomic.Int64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{
+		counts: make([]atomic.Int64, len(latencyBuckets)+1),
+	}
+}
+
+func (h *latencyHistogram) observe(latency time.Duration) {
+	bucket := sort.Search(len(latencyBuckets), func(i int) bool {
+		return latency <= latencyBuckets[i]
+	})
+	h.counts[bucket].Add(1)
+	h.sum.Add(int64(latency))
+}
+
+func (h *latencyHistogram) summary() LatencySummary {
+	counts := make([]int64, len(h.counts))
+	var total int64
+	for i := range h.counts {
+		counts[i] = h.counts[i].Load()
+		total += counts[i]
+	}
+	if total == 0 {
+		return LatencySummary{}
+	}
+	return LatencySummary{
+		Count:  total,
+		MeanMs: milliseconds(time.Duration(h.sum.Load() / total)),
+		P50Ms:  milliseconds(bucketQuantile(counts, total, 0.50)),
+		P90Ms:  milliseconds(bucketQuantile(counts, total, 0.90)),
+		P99Ms:  milliseconds(bucketQuantile(counts, total, 0.99)),
+	}
+}
+
+// bucketQuantile estimates the q quantile of the latencies counted into
+// buckets, assuming those in a bucket are spread evenly across it.
+func bucketQuantile(counts []int64, total int64, q float64) time.Duration {
+	rank := q * float64(total)
+	var below int64
+	for i, count := range counts {
+		if count == 0 || float64(below+count) < rank {
+			below += count
+			continue
+		}
+		if i == len(latencyBuckets) {
+			break
+		}
+		var lower time.Duration
+		if i > 0 {
+			lower = latencyBuckets[i-1]
+		}
+		upper := latencyBuckets[i]
+		return lower + time.Duration((rank-float64(below))/float64(count)*float64(upper-lower))
+	}
+	return latencyBuckets[len(latencyBuckets)-1]
+}
+
+// requestStats counts requests; its counters are atomic.
+type requestStats struct {
+	total     atomic.Int64
+	succeeded atomic.Int64
+	failed    atomic.Int64
+	latency   *latencyHistogram
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{latency: newLatencyHistogram()}
+}
+
+func (s *requestStats) record(succeeded bool, latency time.Duration) {
+	s.total.Add(1)
+	if succeeded {
+		s.succeeded.Add(1)
+		s.latency.observe(latency)
+	} else {
+		s.failed.Add(1)
+	}
+}
+
+func (s *requestStats) snapshot() RequestMetrics {
+	return RequestMetrics{
+		RequestsTotal:     s.total.Load(),
+		RequestsSucceeded: s.succeeded.Load(),
+		RequestsFailed:    s.failed.Load(),
+		Latency:           s.latency.summary(),
+	}
+}
+
+// statsSet holds requestStats by key, creating them on first use.
+type statsSet struct {
+	stats map[string]*requestStats
+	mu    sync.RWMutex
+}
+
+func (s *statsSet) get(key string) *requestStats {
+	s.mu.RLock()
+	stats, ok := s.stats[key]
+	s.mu.RUnlock()
+	if ok {
+		return stats
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stats, ok := s.stats[key]; ok {
+		return stats
+	}
+	if s.stats == nil {
+		s.stats = make(map[string]*requestStats)
+	}
+	stats = newRequestStats()
+	s.stats[key] = stats
+	return stats
+}
+
+// lookup returns the metrics of a key, which are zero for a key with no
+// requests.
+func (s *statsSet) lookup(key string) RequestMetrics {
+	s.mu.RLock()
+	stats, ok := s.stats[key]
+	s.mu.RUnlock()
+	if !ok {
+		return RequestMetrics{}
+	}
+	return stats.snapshot()
+}
+
+func (s *statsSet) snapshot() map[string]RequestMetrics {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	snapshot := make(map[string]RequestMetrics, len(s.stats))
+	for key, stats := range s.stats {
+		snapshot[key] = stats.snapshot()
+	}
+	return snapshot
+}
+
+func (s *statsSet) forget(match func(key string) bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for key := range s.stats {
+		if match(key) {
+			delete(s.stats, key)
+		}
+	}
+}
+
+// requestMetrics count the engine's requests, in all and by namespace,
+// cluster, agent, provider and model. Agents are keyed as cluster/agent,
+// so an agent recreated by an update or rollout keeps its history.
+type requestMetrics struct {
+	all        *requestStats
+	namespaces statsSet
+	clusters   statsSet
+	agents     statsSet
+	providers  statsSet
+	models     statsSet
+}
+
+func newRequestMetrics() *requestMetrics {
+	return &requestMetrics{all: newRequestStats()}
+}
+
+// forgetCluster drops the metrics of a deleted cluster and its agents.
+// Those of its namespace, provider and models stay.
+func (m *requestMetrics) forgetCluster(clusterName string) {
+	m.clusters.forget(func(key string) bool {
+		return key == clusterName
+	})
+	m.agents.forget(func(key string) bool {
+		return strings.HasPrefix(key, clusterName+"/")
+	})
+}
+
+// requestTally counts a request in the metrics when it ends. The provider
+// and model are those that served it, which a fallback may change after
+// the request starts.
+type requestTally struct {
+	metrics    *requestMetrics
+	prometheus *prometheusMetrics
+	cluster    string
+	agent      string
+	provider   string
+	model      string
+}
+
+func (e *Engine) startTally(clusterName, agentName, providerName, model string) *requestTally {
+	return &requestTally{
+		metrics:    e.metrics,
+		prometheus: e.prometheus,
+		cluster:    clusterName,
+		agent:      agentName,
+		provider:   providerName,
+		model:      model,
+	}
+}
+
+// serve records the provider and model a request went to.
+func (t *requestTally) serve(providerName, model string) {
+	t.provider, t.model = providerName, model
+}
+
+func (t *requestTally) succeed(latency time.Duration) {
+	t.record(true, latency)
+}
+
+func (t *requestTally) fail() {
+	t.record(false, 0)
+}
+
+func (t *requestTally) record(succeeded bool, latency time.Duration) {
+	namespace, _ := SplitClusterID(t.cluster)
+	t.metrics.all.record(succeeded, latency)
+	t.metrics.namespaces.get(namespace).record(succeeded, latency)
+	t.metrics.clusters.get(t.cluster).record(succeeded, latency)
+	t.metrics.agents.get(t.cluster+"/"+t.agent).record(succeeded, latency)
+	t.metrics.providers.get(t.provider).record(succeeded, latency)
+	t.metrics.models.get(t.model).record(succeeded, latency)
+	t.prometheus.countRequest(t.cluster, t.agent, t.provider, t.model, succeeded, latency)
+}
+
+// GetMetrics returns a snapshot of the engine's metrics.
+func (e *Engine) GetMetrics() *Metrics {
+	metrics := &Metrics{
+		Clusters:  e.metrics.clusters.snapshot(),
+		Agents:    e.metrics.agents.snapshot(),
+		Providers: e.metrics.providers.snapshot(),
+		Models:    e.metrics.models.snapshot(),
+	}
+	for _, cluster := range e.ListClusters() {
+		metrics.ClustersTotal++
+		cluster.mu.RLock()
+		metrics.AgentsTotal += int64(len(cluster.Agents))
+		cluster.mu.RUnlock()
+	}
+	
+	all := e.metrics.all.snapshot()
+	metrics.RequestsTotal = all.RequestsTotal
+	metrics.RequestsSucceeded = all.RequestsSucceeded
+	metrics.RequestsFailed = all.RequestsFailed
+	metrics.Latency = all.Latency
+	if all.Latency.Count > 0 {
+		metrics.AverageResponseTime = time.Duration(e.metrics.all.latency.sum.Load() / all.Latency.Count)
+	}
+	return metrics
+}
+
+// AgentMetrics returns the request metrics of an agent of a cluster.
+func (e *Engine) AgentMetrics(clusterName, agentName string) RequestMetrics {
+	return e.metrics.agents.lookup(clusterName + "/" + agentName)
+}
\ No newline at end of file
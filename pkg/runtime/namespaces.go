@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/config"
+)
+
+// namespaceSeparator joins a namespace and a cluster name into the ID of a
+// cluster outside the default namespace.
+const namespaceSeparator = ":"
+
+// ClusterID returns the ID a cluster is known by in the API and to its
+// agents: its name in the default namespace, for compatibility with
+// clusters deployed before namespaces, and "namespace:name" in the others.
+func ClusterID(namespace, name string) string {
+	if namespace == "" || namespace == config.DefaultNamespace {
+		return name
+	}
+	return namespace + namespaceSeparator + name
+}
+
+// SplitClusterID returns the namespace and the name of a cluster ID.
+func SplitClusterID(id string) (namespace, name string) {
+	if i := strings.Index(id, namespaceSeparator); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return config.DefaultNamespace, id
+}
+
+// clusterSpecID returns the ID of the cluster a spec deploys.
+func clusterSpecID(spec *config.AgentCluster) string {
+	return ClusterID(spec.Metadata.Namespace, spec.Metadata.Name)
+}
+
+// checkCandidate fills in the name and namespace a candidate spec for a
+// cluster leaves out, and refuses one that names another cluster.
+func checkCandidate(clusterName string, candidate *config.AgentCluster) error {
+	namespace, name := SplitClusterID(clusterName)
+	if candidate.Metadata.Name == "" {
+		candidate.Metadata.Name = name
+	}
+	if candidate.Metadata.Namespace == "" {
+		candidate.Metadata.Namespace = namespace
+	}
+	if clusterSpecID(candidate) != clusterName {
+		return fmt.Errorf("candidate is named %s, not %s", clusterSpecID(candidate), clusterName)
+	}
+	return nil
+}
+
+// NamespaceMetrics sums up a namespace's clusters, agents and requests.
+type NamespaceMetrics struct {
+	Namespace         string `json:"namespace"`
+	Clusters          int    `json:"clusters"`
+	Agents            int    `json:"agents"`
+	RequestsTotal     int64  `json:"requests_total"`
+	RequestsSucceeded int64  `json:"requests_succeeded"`
+	RequestsFailed    int64  `json:"requests_failed"`
+	// Latency covers the requests that succeeded
+	Latency LatencySummary `json:"latency"`
+}
+
+// Namespaces returns the namespaces that have clusters or have served
+// requests, sorted by name.
+func (e *Engine) Namespaces() []NamespaceMetrics {
+	byName := make(map[string]*NamespaceMetrics)
+	get := func(namespace string) *NamespaceMetrics {
+		m, ok := byName[namespace]
+		if !ok {
+			m = &NamespaceMetrics{Namespace: namespace}
+			byName[namespace] = m
+		}
+		return m
+	}
+	
+	for _, cluster := range e.ListClusters() {
+		namespace, _ := SplitClusterID(cluster.Name)
+		m := get(namespace)
+		m.Clusters++
+		cluster.mu.RLock()
+		m.Agents += len(cluster.Agents)
+		cluster.mu.RUnlock()
+	}
+	
+	for namespace, requests := range e.metrics.namespaces.snapshot() {
+		m := get(namespace)
+		m.RequestsTotal = requests.RequestsTotal
+		m.RequestsSucceeded = requests.RequestsSucceeded
+		m.RequestsFailed = requests.RequestsFailed
+		m.Latency = requests.Latency
+	}
+	
+	namespaces := make([]NamespaceMetrics, 0, len(byName))
+	for _, m := range byName {
+		namespaces = append(namespaces, *m)
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		return namespaces[i].Namespace < namespaces[j].Namespace
+	})
+	return namespaces
+}
+
+// GetNamespaceMetrics returns the metrics of one namespace, which are zero
+// for a namespace with no clusters.
+func (e *Engine) GetNamespaceMetrics(namespace string) NamespaceMetrics {
+	for _, m := range e.Namespaces() {
+		if m.Namespace == namespace {
+			return m
+		}
+	}
+	return NamespaceMetrics{Namespace: namespace}
+}
\ No newline at end of file
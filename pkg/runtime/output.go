@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/providers"
+)
+
+const (
+	defaultOutputName       = "response"
+	defaultOutputMaxRetries = 2
+)
+
+// setResponseSchema asks the model for the agent's output object, through
+// the provider's structured output mode where it has one and through the
+// prompt for all providers.
+func setResponseSchema(targetAgent *agent.Agent, providerReq *providers.ChatRequest) error {
+	output := targetAgent.Config.Output
+	if output == nil {
+		return nil
+	}
+	
+	name := output.Name
+	if name == "" {
+		name = defaultOutputName
+	}
+	providerReq.ResponseSchema = &providers.ResponseSchema{
+		Name:   name,
+		Schema: output.Schema,
+		Strict: output.Strict,
+	}
+	
+	schema, err := json.Marshal(output.Schema)
+	if err != nil {
+		return fmt.Errorf("failed to encode output schema: %w", err)
+	}
+	insertSystemMessage(providerReq, "Reply with only a JSON object, without code fences or other text, that matches this JSON schema:\n"+string(schema))
+	return nil
+}
+
+// parseOutput decodes a reply as the agent's output object and validates
+// it against the schema. Code fences around the object are tolerated.
+func parseOutput(targetAgent *agent.Agent, content string) (json.RawMessage, error) {
+	output := targetAgent.Config.Output
+	
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "```") {
+		content = strings.TrimPrefix(content, "```json")
+		content = strings.TrimPrefix(content, "```")
+		content = strings.TrimSuffix(strings.TrimSpace(content), "```")
+	}
+	
+	var object map[string]interface{}
+	decoder := json.NewDecoder(strings.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&object); err != nil {
+		return nil, fmt.Errorf("reply is not a JSON object: %v", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("reply has text after the JSON object")
+	}
+	if err := output.Validator.Validate(object); err != nil {
+		return nil, err
+	}
+	
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(strings.TrimSpace(content))); err != nil {
+		return nil, fmt.Errorf("reply is not a JSON object: %v", err)
+	}
+	return compact.Bytes(), nil
+}
+
+func outputMaxRetries(targetAgent *agent.Agent) int {
+	if retries := targetAgent.Config.Output.MaxRetries; retries > 0 {
+		return retries
+	}
+	return defaultOutputMaxRetries
+}
+
+// outputCorrection asks the model to fix a reply that is not a valid output
+// object.
+func outputCorrection(reply string, err error) []providers.Message {
+	return []providers.Message{
+		{Role: "assistant", Content: reply},
+		{Role: "user", Content: fmt.Sprintf("Your reply does not match the required JSON schema: %v. Reply again with only the corrected JSON object.", err)},
+	}
+}
\ No newline at end of file
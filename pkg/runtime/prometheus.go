@@ -0,0 +1,182 @@
+package runtime
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/providers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// prometheusMetrics are the engine's Prometheus metrics, in a registry of
+// its own rather than the global one so engines do not clash. Counters and
+// the latency histogram are updated as requests, model calls and tool calls
+// end; clusters, agent states and queue depths are read when scraped.
+type prometheusMetrics struct {
+	registry       *prometheus.Registry
+	requests       *prometheus.CounterVec
+	duration       *prometheus.HistogramVec
+	tokens         *prometheus.CounterVec
+	cost           *prometheus.CounterVec
+	toolCalls      *prometheus.CounterVec
+	providerErrors *prometheus.CounterVec
+}
+
+func newPrometheusMetrics(e *Engine) *prometheusMetrics {
+	buckets := make([]float64, len(latencyBuckets))
+	for i, bound := range latencyBuckets {
+		buckets[i] = bound.Seconds()
+	}
+	
+	m := &prometheusMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goagents_requests_total",
+			Help: "Requests to agents that ended, by status: success or failure.",
+		}, []string{"cluster", "agent", "provider", "model", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "goagents_request_duration_seconds",
+			Help:    "How long requests to agents that succeeded took.",
+			Buckets: buckets,
+		}, []string{"cluster", "agent", "provider", "model"}),
+		tokens: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goagents_tokens_total",
+			Help: "Tokens used by model calls, by type: prompt or completion. Cached answers use none.",
+		}, []string{"cluster", "agent", "provider", "model", "type"}),
+		cost: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goagents_cost_dollars_total",
+			Help: "What model calls cost in US dollars, for models with a known price.",
+		}, []string{"cluster", "agent", "provider", "model"}),
+		toolCalls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goagents_tool_calls_total",
+			Help: "Tool calls made by agents, by status: success or failure.",
+		}, []string{"cluster", "agent", "tool", "status"}),
+		providerErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "goagents_provider_errors_total",
+			Help: "Model calls that failed, including those a fallback then served.",
+		}, []string{"cluster", "agent", "provider", "model"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.duration,
+		m.tokens,
+		m.cost,
+		m.toolCalls,
+		m.providerErrors,
+		&stateCollector{engine: e},
+	)
+	return m
+}
+
+func statusLabel(succeeded bool) string {
+	if succeeded {
+		return "success"
+	}
+	return "failure"
+}
+
+func (m *prometheusMetrics) countRequest(clusterName, agentName, providerName, model string, succeeded bool, latency time.Duration) {
+	m.requests.WithLabelValues(clusterName, agentName, providerName, model, statusLabel(succeeded)).Inc()
+	if succeeded {
+		m.duration.WithLabelValues(clusterName, agentName, providerName, model).Observe(latency.Seconds())
+	}
+}
+
+// countUsage counts the tokens and cost of a model call that was not
+// answered from the cache.
+func (m *prometheusMetrics) countUsage(clusterName, agentName, providerName, model string, usage *providers.Usage, cost float64) {
+	if usage == nil {
+		return
+	}
+	m.tokens.WithLabelValues(clusterName, agentName, providerName, model, "prompt").Add(float64(usage.PromptTokens))
+	m.tokens.WithLabelValues(clusterName, agentName, providerName, model, "completion").Add(float64(usage.CompletionTokens))
+	if cost > 0 {
+		m.cost.WithLabelValues(clusterName, agentName, providerName, model).Add(cost)
+	}
+}
+
+func (m *prometheusMetrics) countToolCall(a *agent.Agent, tool string, succeeded bool) {
+	m.toolCalls.WithLabelValues(a.ClusterName, a.GetName(), tool, statusLabel(succeeded)).Inc()
+}
+
+// countProviderError counts a failed model call; err may be nil.
+func (m *prometheusMetrics) countProviderError(a *agent.Agent, providerName, model string, err error) {
+	if err == nil {
+		return
+	}
+	m.providerErrors.WithLabelValues(a.ClusterName, a.GetName(), providerName, model).Inc()
+}
+
+// forgetCluster drops the series of a deleted cluster's agents.
+func (m *prometheusMetrics) forgetCluster(clusterName string) {
+	labels := prometheus.Labels{"cluster": clusterName}
+	m.requests.DeletePartialMatch(labels)
+	m.duration.DeletePartialMatch(labels)
+	m.tokens.DeletePartialMatch(labels)
+	m.cost.DeletePartialMatch(labels)
+	m.toolCalls.DeletePartialMatch(labels)
+	m.providerErrors.DeletePartialMatch(labels)
+}
+
+var (
+	clustersDesc = prometheus.NewDesc(
+		"goagents_clusters",
+		"Clusters on this node, by status.",
+		[]string{"status"}, nil)
+	agentsDesc = prometheus.NewDesc(
+		"goagents_agents",
+		"Agents on this node, by cluster and state.",
+		[]string{"cluster", "state"}, nil)
+	queueDepthDesc = prometheus.NewDesc(
+		"goagents_queue_depth",
+		"Requests queued for an instance of an agent.",
+		[]string{"cluster", "agent"}, nil)
+)
+
+// stateCollector reports the clusters and agents the engine runs, and
+// their queues, as they are when scraped.
+type stateCollector struct {
+	engine *Engine
+}
+
+func (c *stateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- clustersDesc
+	ch <- agentsDesc
+	ch <- queueDepthDesc
+}
+
+func (c *stateCollector) Collect(ch chan<- prometheus.Metric) {
+	clusters := make(map[ClusterStatus]int)
+	for _, cluster := range c.engine.ListClusters() {
+		cluster.mu.RLock()
+		clusters[cluster.Status]++
+		agents := make([]*agent.Agent, 0, len(cluster.Agents))
+		for _, a := range cluster.Agents {
+			agents = append(agents, a)
+		}
+		cluster.mu.RUnlock()
+		
+		states := make(map[agent.Status]int)
+		for _, a := range agents {
+			states[a.GetStatus()]++
+			queued := c.engine.instances.status(cluster.Name, a).Queued
+			ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(queued), cluster.Name, a.GetName())
+		}
+		for state, n := range states {
+			ch <- prometheus.MustNewConstMetric(agentsDesc, prometheus.GaugeValue, float64(n), cluster.Name, string(state))
+		}
+	}
+	for status, n := range clusters {
+		ch <- prometheus.MustNewConstMetric(clustersDesc, prometheus.GaugeValue, float64(n), string(status))
+	}
+}
+
+// PrometheusHandler serves the engine's metrics to Prometheus.
+func (e *Engine) PrometheusHandler() http.Handler {
+	return promhttp.HandlerFor(e.prometheus.registry, promhttp.HandlerOpts{})
+}
\ No newline at end of file
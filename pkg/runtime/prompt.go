@@ -0,0 +1,57 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/agent"
+)
+
+// renderSystemPrompt returns the agent's system prompt, rendered with the
+// request's variables when it is a template. A variable is taken from the
+// request's context, then the session's metadata, then the agent's
+// environment, and otherwise its default.
+func renderSystemPrompt(targetAgent *agent.Agent, req *agent.Request) (string, error) {
+	tmpl := targetAgent.Config.PromptTemplate
+	if tmpl == nil {
+		return targetAgent.Config.SystemPrompt, nil
+	}
+	
+	// Context values keep their JSON types, so {{if .vip}} is false for a
+	// vip of false
+	values := make(map[string]interface{})
+	for name, variable := range targetAgent.Config.PromptVariables {
+		if !variable.Required {
+			values[name] = variable.Default
+		}
+	}
+	for name, value := range targetAgent.Config.Environment {
+		values[name] = value
+	}
+	for name, value := range req.SessionMetadata {
+		values[name] = value
+	}
+	for name, value := range req.Context {
+		if value != nil {
+			values[name] = value
+		}
+	}
+	
+	var missing []string
+	for name, variable := range targetAgent.Config.PromptVariables {
+		if _, ok := values[name]; variable.Required && !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return "", fmt.Errorf("%w: %s", ErrMissingPromptVariable, strings.Join(missing, ", "))
+	}
+	
+	var prompt strings.Builder
+	if err := tmpl.Execute(&prompt, values); err != nil {
+		return "", fmt.Errorf("failed to render system prompt: %w", err)
+	}
+	return prompt.String(), nil
+}
\ No newline at end of file
@@ -0,0 +1,158 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+const defaultMaxQueueDepth = 100
+
+// Request priorities. Requests queued for an agent are served the highest
+// priority first, and in the order they came within a priority.
+const (
+	PriorityHigh   = "high"
+	PriorityNormal = "normal"
+	PriorityLow    = "low"
+)
+
+var priorityRanks = map[string]int{
+	PriorityLow:    0,
+	PriorityNormal: 1,
+	PriorityHigh:   2,
+}
+
+func priorityRank(priority string) int {
+	if rank, ok := priorityRanks[priority]; ok {
+		return rank
+	}
+	return priorityRanks[PriorityNormal]
+}
+
+// QueueFullError is returned for requests turned away because their
+// agent's queue was full, or that gave up their place in it to a request
+// of higher priority. It wraps ErrAgentQueueFull.
+type QueueFullError struct {
+	Cluster  string `json:"cluster"`
+	Agent    string `json:"agent"`
+	MaxDepth int    `json:"max_depth"`
+	// RetryAfter is how long the agent's requests have been taking, about
+	// when a place in the queue frees up
+	RetryAfter time.Duration `json:"retry_after"`
+}
+
+func (e *QueueFullError) Error() string {
+	return fmt.Sprintf("%s: %d requests are queued for %s", ErrAgentQueueFull, e.MaxDepth, e.Agent)
+}
+
+func (e *QueueFullError) Unwrap() error {
+	return ErrAgentQueueFull
+}
+
+// queuedRequest is a request waiting for an instance of its agent.
+type queuedRequest struct {
+	priority string
+	rank     int
+	seq      int64
+	enqueued time.Time
+	// shed is set when a request of higher priority took the request's
+	// place in a full queue
+	shed bool
+}
+
+// maxQueueDepth returns how many requests may wait for an instance.
+func (pool *instancePool) maxQueueDepth() int {
+	if pool.scaling.MaxQueueDepth > 0 {
+		return pool.scaling.MaxQueueDepth
+	}
+	return defaultMaxQueueDepth
+}
+
+// first reports whether a request is next to take an instance: the request
+// at the head of the queue, or a request not queued when none is.
+func (pool *instancePool) first(waiter *queuedRequest) bool {
+	if waiter == nil {
+		return len(pool.waiting) == 0
+	}
+	return pool.waiting[0] == waiter
+}
+
+// enqueue queues a request in order of priority. A full queue sheds the
+// last request of lower priority to make room, and otherwise turns the
+// request away. The caller holds the lock.
+func (pool *instancePool) enqueue(priority string, now time.Time) (*queuedRequest, bool) {
+	waiter := &queuedRequest{
+		priority: priority,
+		rank:     priorityRank(priority),
+		enqueued: now,
+	}
+	if len(pool.waiting) >= pool.maxQueueDepth() {
+		last := pool.waiting[len(pool.waiting)-1]
+		if last.rank >= waiter.rank {
+			pool.rejected++
+			return nil, false
+		}
+		last.shed = true
+		pool.rejected++
+		pool.waiting = pool.waiting[:len(pool.waiting)-1]
+		pool.broadcast()
+	}
+	
+	pool.seq++
+	waiter.seq = pool.seq
+	i := sort.Search(len(pool.waiting), func(i int) bool {
+		return pool.waiting[i].rank < waiter.rank
+	})
+	pool.waiting = append(pool.waiting, nil)
+	copy(pool.waiting[i+1:], pool.waiting[i:])
+	pool.waiting[i] = waiter
+	
+	if len(pool.waiting) > pool.peakQueued {
+		pool.peakQueued = len(pool.waiting)
+	}
+	return waiter, true
+}
+
+// dequeue takes a request off the queue, and lets the next one try for an
+// instance. The caller holds the lock.
+func (pool *instancePool) dequeue(waiter *queuedRequest) {
+	for i, queued := range pool.waiting {
+		if queued == waiter {
+			pool.waiting = append(pool.waiting[:i], pool.waiting[i+1:]...)
+			if i == 0 {
+				pool.broadcast()
+			}
+			return
+		}
+	}
+}
+
+// queuedByPriority counts the queued requests of each priority.
+func (pool *instancePool) queuedByPriority() map[string]int {
+	if len(pool.waiting) == 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, waiter := range pool.waiting {
+		priority := waiter.priority
+		if priority == "" {
+			priority = PriorityNormal
+		}
+		counts[priority]++
+	}
+	return counts
+}
+
+// queueFull is the error for a request turned away by the pool's queue.
+func (pool *instancePool) queueFull(clusterName string, now time.Time) *QueueFullError {
+	retryAfter := time.Duration(pool.load(now).P95LatencyMs) * time.Millisecond
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return &QueueFullError{
+		Cluster:    clusterName,
+		Agent:      pool.name,
+		MaxDepth:   pool.maxQueueDepth(),
+		RetryAfter: retryAfter,
+	}
+}
\ No newline at end of file
@@ -0,0 +1,303 @@
+package runtime
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+// Quota scopes and limits, as reported by QuotaError
+const (
+	QuotaScopeCluster   = "cluster"
+	QuotaScopeNamespace = "namespace"
+	
+	QuotaLimitAgents   = "agents"
+	QuotaLimitRequests = "requests_per_minute"
+	QuotaLimitTokens   = "tokens_per_day"
+	QuotaLimitCost     = "cost_per_day"
+)
+
+// QuotaError is returned for cluster writes that would take a cluster or a
+// namespace past its agent quota, wrapping ErrAgentQuotaExceeded, and for
+// requests to one that has used up its request, token or cost quota,
+// wrapping ErrQuotaExceeded.
+type QuotaError struct {
+	Scope string `json:"scope"`
+	// Name is the cluster or the namespace
+	Name  string `json:"name"`
+	Limit string `json:"limit"`
+	// Used is what the scope had used, or would have had for agents; costs
+	// are in US dollars
+	Used float64 `json:"used"`
+	Max  float64 `json:"max"`
+}
+
+func (e *QuotaError) Error() string {
+	switch e.Limit {
+	case QuotaLimitAgents:
+		return fmt.Sprintf("%s: %s %s would have %.0f agents, more than its quota of %.0f", ErrAgentQuotaExceeded, e.Scope, e.Name, e.Used, e.Max)
+	case QuotaLimitCost:
+		return fmt.Sprintf("%s: %s %s %s quota of $%.4f used up ($%.4f)", ErrQuotaExceeded, e.Scope, e.Name, e.Limit, e.Max, e.Used)
+	}
+	return fmt.Sprintf("%s: %s %s %s quota of %.0f used up (%.0f)", ErrQuotaExceeded, e.Scope, e.Name, e.Limit, e.Max, e.Used)
+}
+
+func (e *QuotaError) Unwrap() error {
+	if e.Limit == QuotaLimitAgents {
+		return ErrAgentQuotaExceeded
+	}
+	return ErrQuotaExceeded
+}
+
+// QuotaUsage is what a cluster or a namespace uses of its quota.
+type QuotaUsage struct {
+	Agents             int `json:"agents"`
+	RequestsThisMinute int `json:"requests_this_minute"`
+	TokensToday        int `json:"tokens_today"`
+	// CostToday is in US dollars, for models with a known price
+	CostToday float64 `json:"cost_today"`
+}
+
+// QuotaStatus is a cluster's or a namespace's quota and what it uses.
+type QuotaStatus struct {
+	Scope string        `json:"scope"`
+	Name  string        `json:"name"`
+	Quota *config.Quota `json:"quota,omitempty"`
+	// Day is the UTC day TokensToday and CostToday count
+	Day   string     `json:"day"`
+	Usage QuotaUsage `json:"usage"`
+}
+
+// quotaLedger counts the requests each cluster and namespace started in
+// the current minute and what they used in the current UTC day.
+type quotaLedger struct {
+	minute   time.Time
+	requests map[string]int
+	daily    *budgetLedger
+	mu       sync.Mutex
+}
+
+func newQuotaLedger() *quotaLedger {
+	return &quotaLedger{
+		requests: make(map[string]int),
+		daily:    newBudgetLedger(),
+	}
+}
+
+func quotaKey(scope, name string) string {
+	return scope + "/" + name
+}
+
+// quotaScope is a quota a request counts against.
+type quotaScope struct {
+	scope string
+	name  string
+	quota *config.Quota
+}
+
+// admit counts a request against every scope, unless one of them has used
+// up its quota.
+func (l *quotaLedger) admit(scopes []quotaScope) *QuotaError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.rollover()
+	for _, s := range scopes {
+		if s.quota == nil {
+			continue
+		}
+		key := quotaKey(s.scope, s.name)
+		if requests := l.requests[key]; s.quota.MaxRequestsPerMinute > 0 && requests >= s.quota.MaxRequestsPerMinute {
+			return s.exceeded(QuotaLimitRequests, float64(requests), float64(s.quota.MaxRequestsPerMinute))
+		}
+		today, _ := l.daily.today(key)
+		if s.quota.MaxTokensPerDay > 0 && today.Tokens >= s.quota.MaxTokensPerDay {
+			return s.exceeded(QuotaLimitTokens, float64(today.Tokens), float64(s.quota.MaxTokensPerDay))
+		}
+		if s.quota.MaxCostPerDay > 0 && today.Cost >= s.quota.MaxCostPerDay {
+			return s.exceeded(QuotaLimitCost, today.Cost, s.quota.MaxCostPerDay)
+		}
+	}
+	
+	for _, s := range scopes {
+		l.requests[quotaKey(s.scope, s.name)]++
+	}
+	return nil
+}
+
+func (s quotaScope) exceeded(limit string, used, max float64) *QuotaError {
+	return &QuotaError{
+		Scope: s.scope,
+		Name:  s.name,
+		Limit: limit,
+		Used:  used,
+		Max:   max,
+	}
+}
+
+func (l *quotaLedger) requestsThisMinute(key string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.rollover()
+	return l.requests[key]
+}
+
+// rollover starts a new minute's request counts once the minute changes.
+// The caller must hold l.mu.
+func (l *quotaLedger) rollover() {
+	if minute := time.Now().Truncate(time.Minute); !minute.Equal(l.minute) {
+		l.minute = minute
+		l.requests = make(map[string]int)
+	}
+}
+
+// charge adds what a request used to its cluster's and its namespace's
+// daily usage.
+func (l *quotaLedger) charge(clusterName string, spend BudgetSpend) {
+	namespace, _ := SplitClusterID(clusterName)
+	l.daily.charge(quotaKey(QuotaScopeCluster, clusterName), spend)
+	l.daily.charge(quotaKey(QuotaScopeNamespace, namespace), spend)
+}
+
+// quotaScopes returns the quotas of a cluster and of its namespace.
+func (e *Engine) quotaScopes(clusterName string) []quotaScope {
+	var quota *config.Quota
+	if cluster, err := e.getCluster(clusterName); err == nil {
+		cluster.mu.RLock()
+		quota = cluster.Config.Spec.Quota
+		cluster.mu.RUnlock()
+	}
+	namespace, _ := SplitClusterID(clusterName)
+	return []quotaScope{
+		{scope: QuotaScopeCluster, name: clusterName, quota: quota},
+		{scope: QuotaScopeNamespace, name: namespace, quota: e.config.Namespaces[namespace].Quota},
+	}
+}
+
+// admitRequest counts a request against its cluster's and its namespace's
+// quotas, and refuses it with a *QuotaError when either is used up. A
+// request that is admitted runs to the end, even if it takes the daily
+// usage past a quota.
+func (e *Engine) admitRequest(clusterName string, targetAgent *agent.Agent, requestID string) error {
+	quotaErr := e.quotas.admit(e.quotaScopes(clusterName))
+	if quotaErr == nil {
+		return nil
+	}
+	
+	e.logger.Warn("Request refused by quota",
+		zap.String("cluster", clusterName),
+		zap.String("agent", targetAgent.GetName()),
+		zap.String("request", requestID),
+		zap.String("scope", quotaErr.Scope),
+		zap.String("name", quotaErr.Name),
+		zap.String("limit", quotaErr.Limit),
+		zap.Float64("used", quotaErr.Used),
+		zap.Float64("max", quotaErr.Max))
+	
+	e.publishEvent(agent.Event{
+		Type:    agent.EventQuotaExceeded,
+		AgentID: targetAgent.ID,
+		Data: map[string]interface{}{
+			"cluster":    clusterName,
+			"agent":      targetAgent.GetName(),
+			"request_id": requestID,
+			"scope":      quotaErr.Scope,
+			"name":       quotaErr.Name,
+			"limit":      quotaErr.Limit,
+			"used":       quotaErr.Used,
+			"max":        quotaErr.Max,
+		},
+	})
+	return quotaErr
+}
+
+// checkAgentQuota refuses to give a cluster more agents than its quota,
+// with quota, or than its namespace's quota allows its clusters together.
+// e.agentQuota must be held until the agents are in the cluster's spec.
+func (e *Engine) checkAgentQuota(clusterName string, quota *config.Quota, agents int) error {
+	if quota != nil && quota.MaxAgents > 0 && agents > quota.MaxAgents {
+		return &QuotaError{
+			Scope: QuotaScopeCluster,
+			Name:  clusterName,
+			Limit: QuotaLimitAgents,
+			Used:  float64(agents),
+			Max:   float64(quota.MaxAgents),
+		}
+	}
+	
+	namespace, _ := SplitClusterID(clusterName)
+	quota = e.config.Namespaces[namespace].Quota
+	if quota == nil || quota.MaxAgents == 0 {
+		return nil
+	}
+	if total := agents + e.namespaceAgents(namespace, clusterName); total > quota.MaxAgents {
+		return &QuotaError{
+			Scope: QuotaScopeNamespace,
+			Name:  namespace,
+			Limit: QuotaLimitAgents,
+			Used:  float64(total),
+			Max:   float64(quota.MaxAgents),
+		}
+	}
+	return nil
+}
+
+// namespaceAgents counts the agents in the specs of a namespace's
+// clusters, leaving out the cluster named except.
+func (e *Engine) namespaceAgents(namespace, except string) int {
+	agents := 0
+	for _, cluster := range e.ListClusters() {
+		if clusterNamespace, _ := SplitClusterID(cluster.Name); clusterNamespace != namespace || cluster.Name == except {
+			continue
+		}
+		cluster.mu.RLock()
+		agents += len(cluster.Config.Spec.Agents)
+		cluster.mu.RUnlock()
+	}
+	if e.distribution != nil {
+		agents += e.distribution.namespaceAgents(namespace, except)
+	}
+	return agents
+}
+
+// ClusterQuota returns a cluster's quota and what it uses of it.
+func (e *Engine) ClusterQuota(clusterName string) (*QuotaStatus, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	quota := cluster.Config.Spec.Quota
+	agents := len(cluster.Config.Spec.Agents)
+	cluster.mu.RUnlock()
+	return e.quotaStatus(QuotaScopeCluster, clusterName, quota, agents), nil
+}
+
+// NamespaceQuota returns a namespace's quota and what its clusters use of
+// it together, which is nothing for a namespace with no clusters.
+func (e *Engine) NamespaceQuota(namespace string) *QuotaStatus {
+	return e.quotaStatus(QuotaScopeNamespace, namespace, e.config.Namespaces[namespace].Quota, e.namespaceAgents(namespace, ""))
+}
+
+func (e *Engine) quotaStatus(scope, name string, quota *config.Quota, agents int) *QuotaStatus {
+	key := quotaKey(scope, name)
+	today, day := e.quotas.daily.today(key)
+	return &QuotaStatus{
+		Scope: scope,
+		Name:  name,
+		Quota: quota,
+		Day:   day,
+		Usage: QuotaUsage{
+			Agents:             agents,
+			RequestsThisMinute: e.quotas.requestsThisMinute(key),
+			TokensToday:        today.Tokens,
+			CostToday:          today.Cost,
+		},
+	}
+}
\ No newline at end of file
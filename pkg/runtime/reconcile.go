@@ -0,0 +1,261 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+const reconcileInterval = 30 * time.Second
+
+// Cluster conditions, which the reconcile loop reports
+const (
+	// ConditionReady is true when every agent of the spec runs
+	ConditionReady = "Ready"
+	// ConditionReconciled is true when the agents running are the ones
+	// the spec asks for
+	ConditionReconciled = "Reconciled"
+)
+
+type ConditionStatus string
+
+const (
+	ConditionTrue  ConditionStatus = "True"
+	ConditionFalse ConditionStatus = "False"
+)
+
+// ClusterCondition is one aspect of a cluster's state, as the reconcile
+// loop last found it. LastTransitionTime is when its status last changed.
+type ClusterCondition struct {
+	Type               string          `json:"type"`
+	Status             ConditionStatus `json:"status"`
+	Reason             string          `json:"reason"`
+	Message            string          `json:"message,omitempty"`
+	LastTransitionTime time.Time       `json:"last_transition_time"`
+}
+
+// setCondition records a condition of the cluster. The cluster's lock must
+// be held.
+func (cluster *Cluster) setCondition(condition ClusterCondition, now time.Time) {
+	condition.LastTransitionTime = now
+	for i, existing := range cluster.Conditions {
+		if existing.Type != condition.Type {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		}
+		cluster.Conditions[i] = condition
+		return
+	}
+	cluster.Conditions = append(cluster.Conditions, condition)
+}
+
+// runReconciler converges clusters on their specs until the engine shuts
+// down.
+func (e *Engine) runReconciler() {
+	ticker := time.NewTicker(reconcileInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-e.done:
+			return
+		case now := <-ticker.C:
+			e.reconcile(now)
+		}
+	}
+}
+
+// reconcile deploys the clusters of the server config that are missing,
+// then converges each running cluster on its spec.
+func (e *Engine) reconcile(now time.Time) {
+	e.deployConfigClusters()
+	for _, cluster := range e.ListClusters() {
+		e.reconcileCluster(cluster, now)
+	}
+}
+
+// agentAction is a change that brings a cluster's agents closer to its
+// spec.
+type agentAction struct {
+	name string
+	// spec is nil for an agent to remove
+	spec *config.Agent
+	// current is the agent to replace or remove, if any
+	current *agent.Agent
+	reason  string
+}
+
+// reconcileCluster creates the agents of a running cluster's spec that are
+// missing, restarts those that failed or stopped, and removes agents the
+// spec no longer has. Agents with health checks are restarted by their
+// restart policy instead, and agents wait for the agents they depend on.
+// Clusters still starting are left alone.
+func (e *Engine) reconcileCluster(cluster *Cluster, now time.Time) {
+	cluster.mu.RLock()
+	if cluster.Status != ClusterStatusRunning || cluster.started != cluster.Config {
+		cluster.mu.RUnlock()
+		return
+	}
+	version := cluster.Config.Metadata.ResourceVersion
+	
+	var actions []agentAction
+	var waiting []string
+	for i := range cluster.Config.Spec.Agents {
+		spec := &cluster.Config.Spec.Agents[i]
+		current := cluster.Agents[spec.Name]
+		var reason string
+		switch {
+		case current == nil:
+			reason = "missing"
+		case spec.Health != nil:
+			continue
+		default:
+			switch current.GetStatus() {
+			case agent.StatusFailed:
+				reason = "failed"
+			case agent.StatusStopped, agent.StatusStopping:
+				reason = "stopped"
+			default:
+				continue
+			}
+		}
+		if dep := unreadyDependency(cluster, spec); dep != "" {
+			waiting = append(waiting, fmt.Sprintf("%s waits for %s", spec.Name, dep))
+			continue
+		}
+		actions = append(actions, agentAction{name: spec.Name, spec: copyAgentSpec(spec), current: current, reason: reason})
+	}
+	for name, current := range cluster.Agents {
+		if findAgentSpec(cluster.Config, name) == nil {
+			actions = append(actions, agentAction{name: name, current: current, reason: "not in spec"})
+		}
+	}
+	cluster.mu.RUnlock()
+	
+	var failures []string
+	for _, action := range actions {
+		if err := e.applyAgentAction(cluster, action, version); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", action.name, err))
+			continue
+		}
+		e.logger.Info("Reconciled agent",
+			zap.String("cluster", cluster.Name),
+			zap.String("agent", action.name),
+			zap.String("reason", action.reason))
+	}
+	
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if cluster.Config.Metadata.ResourceVersion != version {
+		// Written to meanwhile; the next pass looks again
+		return
+	}
+	
+	reconciled := ClusterCondition{Type: ConditionReconciled, Status: ConditionTrue, Reason: "Converged"}
+	switch {
+	case len(failures) > 0:
+		reconciled.Status, reconciled.Reason = ConditionFalse, "AgentsFailed"
+		reconciled.Message = strings.Join(failures, "; ")
+	case len(waiting) > 0:
+		reconciled.Status, reconciled.Reason = ConditionFalse, "WaitingForDependencies"
+		reconciled.Message = strings.Join(waiting, "; ")
+	}
+	cluster.setCondition(reconciled, now)
+	cluster.setCondition(readyCondition(cluster), now)
+}
+
+// applyAgentAction creates, replaces or removes an agent, unless the
+// cluster was written to since resourceVersion.
+func (e *Engine) applyAgentAction(cluster *Cluster, action agentAction, resourceVersion string) error {
+	var built *agent.Agent
+	if action.spec != nil {
+		var err error
+		built, err = e.buildAgent(cluster, action.spec)
+		if err != nil {
+			return err
+		}
+	}
+	
+	cluster.mu.Lock()
+	if cluster.Config.Metadata.ResourceVersion != resourceVersion || cluster.Agents[action.name] != action.current {
+		cluster.mu.Unlock()
+		if built != nil {
+			e.retireAgent(built)
+		}
+		return fmt.Errorf("%w: cluster %s changed while the agent was reconciled", ErrConflict, cluster.Name)
+	}
+	if built != nil {
+		cluster.Agents[action.name] = built
+	} else {
+		delete(cluster.Agents, action.name)
+	}
+	cluster.UpdatedAt = time.Now()
+	e.saveCluster(cluster)
+	cluster.mu.Unlock()
+	
+	if built != nil {
+		e.versions.deployed(cluster.Name, action.name, built.Version)
+		e.instances.configure(cluster.Name, built)
+		if action.spec.Health != nil {
+			e.watchHealth(built, action.spec.Health)
+		}
+	}
+	if action.current != nil {
+		e.retireAgent(action.current)
+	}
+	if action.spec == nil {
+		e.instances.remove(cluster.Name, action.name)
+	}
+	return nil
+}
+
+// unreadyDependency returns an agent the spec depends on that is not
+// running, if any. The cluster's lock must be held.
+func unreadyDependency(cluster *Cluster, spec *config.Agent) string {
+	for _, dep := range spec.DependsOn {
+		if dep == spec.Name {
+			continue
+		}
+		a, ok := cluster.Agents[dep]
+		if !ok {
+			return dep
+		}
+		if status := a.GetStatus(); status != agent.StatusRunning && status != agent.StatusIdle {
+			return dep
+		}
+	}
+	return ""
+}
+
+// readyCondition reports whether every agent of the cluster's spec runs.
+// The cluster's lock must be held.
+func readyCondition(cluster *Cluster) ClusterCondition {
+	var notReady []string
+	for _, spec := range cluster.Config.Spec.Agents {
+		a, ok := cluster.Agents[spec.Name]
+		if !ok {
+			notReady = append(notReady, spec.Name+" is missing")
+			continue
+		}
+		if status := a.GetStatus(); status != agent.StatusRunning && status != agent.StatusIdle {
+			notReady = append(notReady, fmt.Sprintf("%s is %s", spec.Name, status))
+		}
+	}
+	if len(notReady) > 0 {
+		sort.Strings(notReady)
+		return ClusterCondition{
+			Type:    ConditionReady,
+			Status:  ConditionFalse,
+			Reason:  "AgentsNotReady",
+			Message: strings.Join(notReady, "; "),
+		}
+	}
+	return ClusterCondition{Type: ConditionReady, Status: ConditionTrue, Reason: "AgentsReady"}
+}
\ No newline at end of file
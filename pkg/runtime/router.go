@@ -0,0 +1,154 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.uber.org/zap"
+)
+
+const (
+	// routeDescriptionLength bounds route descriptions taken from an
+	// agent's system prompt
+	routeDescriptionLength = 200
+	// routingTimeout bounds the classification of requests sent without a
+	// timeout
+	routingTimeout = 30 * time.Second
+)
+
+// routerConfig returns an agent's routes, describing the routes that have
+// no description with the start of the agent's system prompt.
+func routerConfig(cluster *config.AgentCluster, router *config.AgentRouter) *agent.RouterConfig {
+	cfg := &agent.RouterConfig{Default: router.Default}
+	for _, route := range router.Routes {
+		description := route.Description
+		if spec := findAgentSpec(cluster, route.Agent); description == "" && spec != nil {
+			description = strings.Join(strings.Fields(spec.SystemPrompt), " ")
+			if runes := []rune(description); len(runes) > routeDescriptionLength {
+				description = string(runes[:routeDescriptionLength]) + "..."
+			}
+		}
+		cfg.Routes = append(cfg.Routes, agent.Route{
+			Agent:       route.Agent,
+			Description: description,
+		})
+	}
+	return cfg
+}
+
+// routeRequest hands a request sent to a router to the agent it picks,
+// following routes to other routers, and returns the agent that answers
+// with the router that picked it. Requests for agents that are not
+// routers, or that suit no route, stay where they are.
+func (e *Engine) routeRequest(clusterName string, targetAgent *agent.Agent, provider providers.Provider, req *agent.Request) (*agent.Agent, providers.Provider, string) {
+	router := ""
+	visited := map[string]bool{targetAgent.GetName(): true}
+	for targetAgent.Config.Router != nil {
+		targetAgent.Wake()
+		name := e.chooseRoute(clusterName, targetAgent, provider, req)
+		if name == "" || visited[name] {
+			break
+		}
+		visited[name] = true
+		
+		routed, routedProvider, err := e.resolveAgent(clusterName, name)
+		if err != nil {
+			e.logger.Warn("Failed to route request",
+				zap.String("cluster", clusterName),
+				zap.String("router", targetAgent.GetName()),
+				zap.String("agent", name),
+				zap.Error(err))
+			break
+		}
+		router = targetAgent.GetName()
+		targetAgent, provider = routed, routedProvider
+	}
+	return targetAgent, provider, router
+}
+
+// chooseRoute asks the router's model which route suits the request, and
+// returns the route's agent, the router's default when no route suits it,
+// or "" for the router to answer.
+func (e *Engine) chooseRoute(clusterName string, router *agent.Agent, provider providers.Provider, req *agent.Request) string {
+	routes := router.Config.Router.Routes
+	
+	var list strings.Builder
+	for _, route := range routes {
+		fmt.Fprintf(&list, "- %s", route.Agent)
+		if route.Description != "" {
+			fmt.Fprintf(&list, ": %s", route.Description)
+		}
+		list.WriteString("\n")
+	}
+	instructions := "Choose the agent best suited to answer the last message of the conversation. The agents are:\n" +
+		list.String() + "\nReply with only the agent's name, or none if no agent suits the conversation."
+	
+	// The router's own system prompt can steer the choice
+	systemPrompt, err := renderSystemPrompt(router, req)
+	if err != nil {
+		systemPrompt = ""
+	}
+	if systemPrompt != "" {
+		instructions = systemPrompt + "\n\n" + instructions
+	}
+	
+	providerReq := &providers.ChatRequest{
+		Model:     router.Config.Model,
+		MaxTokens: 50,
+		Messages:  []providers.Message{{Role: "system", Content: instructions}},
+	}
+	for _, msg := range req.Messages {
+		providerReq.Messages = append(providerReq.Messages, providers.Message{
+			Role:    msg.Role,
+			Content: msg.Content,
+		})
+	}
+	
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = routingTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	resp, err := provider.Chat(ctx, providerReq)
+	if err != nil {
+		e.logger.Warn("Failed to classify request for routing",
+			zap.String("cluster", clusterName),
+			zap.String("router", router.GetName()),
+			zap.Error(err))
+		return router.Config.Router.Default
+	}
+	
+	if name := matchRoute(routes, resp.Content); name != "" {
+		return name
+	}
+	return router.Config.Router.Default
+}
+
+// matchRoute finds the route a classification reply names: the reply
+// itself, or the only route named in a longer reply.
+func matchRoute(routes []agent.Route, reply string) string {
+	reply = strings.ToLower(strings.Trim(strings.TrimSpace(reply), "`'\"*."))
+	for _, route := range routes {
+		if strings.ToLower(route.Agent) == reply {
+			return route.Agent
+		}
+	}
+	
+	match := ""
+	for _, route := range routes {
+		if strings.Contains(reply, strings.ToLower(route.Agent)) {
+			if match != "" {
+				return ""
+			}
+			match = route.Agent
+		}
+	}
+	return match
+}
\ No newline at end of file
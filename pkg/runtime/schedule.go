@@ -0,0 +1,496 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/cron"
+	"go.uber.org/zap"
+)
+
+// maxScheduleRuns bounds the runs kept for run history; the oldest
+// finished runs are dropped first
+const maxScheduleRuns = 1000
+
+type ScheduleRunStatus string
+
+const (
+	ScheduleRunRunning   ScheduleRunStatus = "running"
+	ScheduleRunSucceeded ScheduleRunStatus = "succeeded"
+	ScheduleRunFailed    ScheduleRunStatus = "failed"
+	// ScheduleRunSkipped is a run that was due while an earlier one was
+	// still running, under the skip overlap policy
+	ScheduleRunSkipped ScheduleRunStatus = "skipped"
+	// ScheduleRunCancelled is a run replaced by a later one, or stopped
+	// with its cluster
+	ScheduleRunCancelled ScheduleRunStatus = "cancelled"
+)
+
+// What started a schedule run
+const (
+	ScheduleTriggerCron   = "cron"
+	ScheduleTriggerManual = "manual"
+)
+
+// ScheduleRun is a run of one of a cluster's schedules.
+type ScheduleRun struct {
+	ID       string            `json:"id"`
+	Cluster  string            `json:"cluster"`
+	Schedule string            `json:"schedule"`
+	Trigger  string            `json:"trigger"`
+	Status   ScheduleRunStatus `json:"status"`
+	// ScheduledAt is when a cron run was due
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	// Output is the agent's answer, or the output of the workflow run
+	Output interface{} `json:"output,omitempty"`
+	Error  string      `json:"error,omitempty"`
+	// WorkflowRun is the ID of the run a workflow schedule started
+	WorkflowRun string `json:"workflow_run,omitempty"`
+	cancel      context.CancelFunc
+	// done is closed when the run finishes
+	done chan struct{}
+}
+
+// ScheduleInfo is a schedule of a cluster and when it next runs.
+type ScheduleInfo struct {
+	config.Schedule
+	// NextRun is unset while the cluster is not running
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Running int        `json:"running"`
+}
+
+func (r *ScheduleRun) finished() bool {
+	return r.Status != ScheduleRunRunning
+}
+
+// clusterSchedules are the timers of a running cluster's schedules.
+// Cancelling ctx stops them and the runs they started.
+type clusterSchedules struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	next   map[string]time.Time
+}
+
+// scheduler keeps the schedules of running clusters and recent runs of
+// schedules. Runs are updated by the goroutines executing them and read by
+// history queries, so both go through the scheduler's lock, and readers
+// get copies.
+type scheduler struct {
+	clusters map[string]*clusterSchedules
+	runs     map[string]*ScheduleRun
+	order    []string
+	nextID   uint64
+	mu       sync.Mutex
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		clusters: make(map[string]*clusterSchedules),
+		runs:     make(map[string]*ScheduleRun),
+	}
+}
+
+// add keeps a run; the caller holds the lock.
+func (s *scheduler) add(run *ScheduleRun) {
+	s.nextID++
+	run.ID = fmt.Sprintf("run-%d-%d", time.Now().Unix(), s.nextID)
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	
+	for i := 0; len(s.runs) > maxScheduleRuns && i < len(s.order); {
+		if oldest := s.runs[s.order[i]]; oldest.finished() {
+			delete(s.runs, s.order[i])
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			continue
+		}
+		i++
+	}
+}
+
+// running returns the unfinished runs of a schedule; the caller holds the
+// lock.
+func (s *scheduler) running(cluster, schedule string) []*ScheduleRun {
+	var runs []*ScheduleRun
+	for _, id := range s.order {
+		run := s.runs[id]
+		if run.Cluster == cluster && run.Schedule == schedule && !run.finished() {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+func (s *scheduler) get(id string) (*ScheduleRun, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	run, exists := s.runs[id]
+	if !exists {
+		return nil, false
+	}
+	return run.copy(), true
+}
+
+// list returns a schedule's runs, newest first.
+func (s *scheduler) list(cluster, schedule string) []*ScheduleRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	runs := make([]*ScheduleRun, 0)
+	for i := len(s.order) - 1; i >= 0; i-- {
+		run := s.runs[s.order[i]]
+		if run.Cluster == cluster && run.Schedule == schedule {
+			runs = append(runs, run.copy())
+		}
+	}
+	return runs
+}
+
+func (r *ScheduleRun) copy() *ScheduleRun {
+	c := *r
+	return &c
+}
+
+// startSchedules starts the timers of a cluster's schedules, replacing any
+// it already had.
+func (e *Engine) startSchedules(cluster *Cluster) {
+	cluster.mu.RLock()
+	schedules := append([]config.Schedule(nil), cluster.Config.Spec.Schedules...)
+	cluster.mu.RUnlock()
+	
+	e.stopSchedules(cluster.Name)
+	if len(schedules) == 0 {
+		return
+	}
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	cs := &clusterSchedules{ctx: ctx, cancel: cancel, next: make(map[string]time.Time)}
+	e.scheduler.mu.Lock()
+	e.scheduler.clusters[cluster.Name] = cs
+	e.scheduler.mu.Unlock()
+	
+	for i := range schedules {
+		go e.watchSchedule(cluster.Name, &schedules[i], cs)
+	}
+}
+
+// stopSchedules stops the timers of a cluster's schedules and cancels
+// their runs.
+func (e *Engine) stopSchedules(clusterName string) {
+	e.scheduler.mu.Lock()
+	cs, ok := e.scheduler.clusters[clusterName]
+	delete(e.scheduler.clusters, clusterName)
+	e.scheduler.mu.Unlock()
+	
+	if ok {
+		cs.cancel()
+	}
+}
+
+// watchSchedule triggers a schedule each time its cron expression fires,
+// until the cluster's schedules are stopped or the engine shuts down.
+func (e *Engine) watchSchedule(clusterName string, schedule *config.Schedule, cs *clusterSchedules) {
+	// The expression and time zone were checked when the cluster was
+	// deployed
+	expr, err := cron.Parse(schedule.Cron)
+	if err == nil {
+		var loc *time.Location
+		if loc, err = schedule.Location(); err == nil {
+			e.runScheduleTimer(clusterName, schedule, expr, loc, cs)
+			return
+		}
+	}
+	e.logger.Error("Failed to start schedule",
+		zap.String("cluster", clusterName),
+		zap.String("schedule", schedule.Name),
+		zap.Error(err))
+}
+
+func (e *Engine) runScheduleTimer(clusterName string, schedule *config.Schedule, expr *cron.Schedule, loc *time.Location, cs *clusterSchedules) {
+	for {
+		next := expr.Next(time.Now().In(loc))
+		if next.IsZero() {
+			return
+		}
+		e.scheduler.mu.Lock()
+		cs.next[schedule.Name] = next
+		e.scheduler.mu.Unlock()
+		
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-cs.ctx.Done():
+			timer.Stop()
+			return
+		case <-e.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+			e.triggerSchedule(cs.ctx, clusterName, schedule, ScheduleTriggerCron, &next)
+		}
+	}
+}
+
+// triggerSchedule starts a run of a schedule, applying its overlap policy
+// to the runs still going, and returns a copy of the run.
+func (e *Engine) triggerSchedule(parent context.Context, clusterName string, schedule *config.Schedule, trigger string, scheduledAt *time.Time) *ScheduleRun {
+	now := time.Now()
+	run := &ScheduleRun{
+		Cluster:     clusterName,
+		Schedule:    schedule.Name,
+		Trigger:     trigger,
+		Status:      ScheduleRunRunning,
+		ScheduledAt: scheduledAt,
+		StartedAt:   now,
+		done:        make(chan struct{}),
+	}
+	
+	if e.Draining() {
+		run.Status = ScheduleRunSkipped
+		run.Error = "server is draining"
+		run.FinishedAt = &now
+		close(run.done)
+		e.scheduler.mu.Lock()
+		e.scheduler.add(run)
+		copied := run.copy()
+		e.scheduler.mu.Unlock()
+		
+		e.logger.Info("Schedule run skipped",
+			zap.String("cluster", clusterName),
+			zap.String("schedule", schedule.Name),
+			zap.String("reason", "draining"))
+		return copied
+	}
+	
+	e.scheduler.mu.Lock()
+	running := e.scheduler.running(clusterName, schedule.Name)
+	if len(running) > 0 {
+		switch schedule.Overlap {
+		case config.OverlapAllow:
+		case config.OverlapReplace:
+			for _, earlier := range running {
+				earlier.Status = ScheduleRunCancelled
+				earlier.Error = fmt.Sprintf("replaced by run started at %s", now.Format(time.RFC3339))
+				earlier.cancel()
+			}
+		default:
+			run.Status = ScheduleRunSkipped
+			run.Error = fmt.Sprintf("run %s is still running", running[0].ID)
+			run.FinishedAt = &now
+			close(run.done)
+			e.scheduler.add(run)
+			copied := run.copy()
+			e.scheduler.mu.Unlock()
+			
+			e.logger.Info("Schedule run skipped",
+				zap.String("cluster", clusterName),
+				zap.String("schedule", schedule.Name),
+				zap.String("running", running[0].ID))
+			return copied
+		}
+	}
+	
+	var ctx context.Context
+	if schedule.Timeout > 0 {
+		ctx, run.cancel = context.WithTimeout(parent, schedule.Timeout)
+	} else {
+		ctx, run.cancel = context.WithCancel(parent)
+	}
+	e.scheduler.add(run)
+	copied := run.copy()
+	e.scheduler.mu.Unlock()
+	
+	e.logger.Info("Schedule run started",
+		zap.String("cluster", clusterName),
+		zap.String("schedule", schedule.Name),
+		zap.String("run", run.ID),
+		zap.String("trigger", trigger))
+	
+	go e.executeSchedule(ctx, schedule, run)
+	return copied
+}
+
+// executeSchedule sends the schedule's prompt to its agent, or runs its
+// workflow and waits for the run, then records the outcome.
+func (e *Engine) executeSchedule(ctx context.Context, schedule *config.Schedule, run *ScheduleRun) {
+	defer close(run.done)
+	defer run.cancel()
+	
+	var output interface{}
+	var err error
+	if schedule.Workflow != "" {
+		var workflowRun *WorkflowRun
+		workflowRun, err = e.startWorkflow(ctx, run.Cluster, schedule.Workflow, schedule.Input)
+		if err == nil {
+			e.scheduler.mu.Lock()
+			run.WorkflowRun = workflowRun.ID
+			e.scheduler.mu.Unlock()
+			
+			// Cancelling ctx cancels the workflow run, which then finishes
+			workflowRun, err = e.WaitWorkflowRun(context.Background(), workflowRun)
+		}
+		if err == nil {
+			output = workflowRun.Output
+			if workflowRun.Status == WorkflowStatusFailed {
+				err = errors.New(workflowRun.Error)
+			}
+		}
+	} else {
+		output, err = e.askScheduledAgent(ctx, schedule, run)
+	}
+	
+	var status ScheduleRunStatus
+	e.scheduler.mu.Lock()
+	now := time.Now()
+	run.FinishedAt = &now
+	// A replaced run is already marked cancelled
+	if run.Status == ScheduleRunRunning {
+		switch {
+		case err == nil:
+			run.Status = ScheduleRunSucceeded
+			run.Output = output
+		case errors.Is(ctx.Err(), context.Canceled):
+			run.Status = ScheduleRunCancelled
+			run.Error = err.Error()
+		default:
+			run.Status = ScheduleRunFailed
+			run.Error = err.Error()
+		}
+	}
+	status = run.Status
+	e.scheduler.mu.Unlock()
+	
+	e.logger.Info("Schedule run finished",
+		zap.String("cluster", run.Cluster),
+		zap.String("schedule", run.Schedule),
+		zap.String("run", run.ID),
+		zap.String("status", string(status)))
+}
+
+func (e *Engine) askScheduledAgent(ctx context.Context, schedule *config.Schedule, run *ScheduleRun) (interface{}, error) {
+	reqContext := make(map[string]interface{}, len(schedule.Context)+2)
+	for k, v := range schedule.Context {
+		reqContext[k] = v
+	}
+	reqContext["schedule"] = schedule.Name
+	reqContext["schedule_run"] = run.ID
+	
+	req := &agent.Request{
+		ID:       fmt.Sprintf("%s-%s", schedule.Name, run.ID),
+		Messages: []agent.Message{{Role: "user", Content: schedule.Prompt}},
+		Context:  reqContext,
+	}
+	resp, err := e.processRequest(ctx, run.Cluster, schedule.Agent, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	if resp.Output != nil {
+		var output interface{}
+		if err := json.Unmarshal(resp.Output, &output); err == nil {
+			return output, nil
+		}
+	}
+	return resp.Content, nil
+}
+
+func (e *Engine) findSchedule(clusterName, scheduleName string) (*config.Schedule, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+	for _, schedule := range cluster.Config.Spec.Schedules {
+		if schedule.Name == scheduleName {
+			return &schedule, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s in cluster %s", ErrScheduleNotFound, scheduleName, clusterName)
+}
+
+// Schedules returns the schedules declared by a cluster, with when each
+// next runs.
+func (e *Engine) Schedules(clusterName string) ([]ScheduleInfo, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	cluster.mu.RLock()
+	schedules := append([]config.Schedule(nil), cluster.Config.Spec.Schedules...)
+	cluster.mu.RUnlock()
+	
+	e.scheduler.mu.Lock()
+	defer e.scheduler.mu.Unlock()
+	
+	cs := e.scheduler.clusters[clusterName]
+	infos := make([]ScheduleInfo, len(schedules))
+	for i, schedule := range schedules {
+		infos[i] = ScheduleInfo{
+			Schedule: schedule,
+			Running:  len(e.scheduler.running(clusterName, schedule.Name)),
+		}
+		if cs != nil {
+			if next, ok := cs.next[schedule.Name]; ok {
+				infos[i].NextRun = &next
+			}
+		}
+	}
+	return infos, nil
+}
+
+// TriggerSchedule starts a run of a cluster's schedule now, subject to its
+// overlap policy, and returns it while it runs in the background.
+func (e *Engine) TriggerSchedule(clusterName, scheduleName string) (*ScheduleRun, error) {
+	schedule, err := e.findSchedule(clusterName, scheduleName)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Runs of a running cluster stop with it
+	parent := context.Background()
+	e.scheduler.mu.Lock()
+	if cs, ok := e.scheduler.clusters[clusterName]; ok {
+		parent = cs.ctx
+	}
+	e.scheduler.mu.Unlock()
+	
+	return e.triggerSchedule(parent, clusterName, schedule, ScheduleTriggerManual, nil), nil
+}
+
+// ScheduleRun returns a run of a cluster's schedule by ID.
+func (e *Engine) ScheduleRun(clusterName, scheduleName, id string) (*ScheduleRun, error) {
+	run, exists := e.scheduler.get(id)
+	if !exists || run.Cluster != clusterName || run.Schedule != scheduleName {
+		return nil, fmt.Errorf("%w: %s", ErrScheduleRunNotFound, id)
+	}
+	return run, nil
+}
+
+// WaitScheduleRun waits for a run to finish, or for ctx to be done, and
+// returns the run as it is then.
+func (e *Engine) WaitScheduleRun(ctx context.Context, run *ScheduleRun) (*ScheduleRun, error) {
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+	}
+	return e.ScheduleRun(run.Cluster, run.Schedule, run.ID)
+}
+
+// ScheduleRuns returns the kept runs of a schedule, newest first.
+func (e *Engine) ScheduleRuns(clusterName, scheduleName string) ([]*ScheduleRun, error) {
+	if _, err := e.findSchedule(clusterName, scheduleName); err != nil {
+		return nil, err
+	}
+	return e.scheduler.list(clusterName, scheduleName), nil
+}
\ No newline at end of file
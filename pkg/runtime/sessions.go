@@ -0,0 +1,264 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/providers"
+	"github.com/goagents/goagents/pkg/sessions"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSessionTTL              = 24 * time.Hour
+	defaultSessionMaxMessages      = 200
+	defaultSessionMaxHistoryTokens = 16000
+)
+
+// sessionLocks serializes the messages sent in a session, so each one is
+// answered with the history the one before it saved. Sessions share a
+// fixed set of locks by hash of their ID.
+type sessionLocks [64]sync.Mutex
+
+func (l *sessionLocks) lock(id string) func() {
+	h := fnv.New32a()
+	h.Write([]byte(id))
+	mu := &l[h.Sum32()%uint32(len(l))]
+	mu.Lock()
+	return mu.Unlock
+}
+
+func (e *Engine) initializeSessions() error {
+	switch e.config.Sessions.Backend {
+	case "", "memory":
+		e.sessions = sessions.NewMemoryStore()
+	case "sqlite":
+		store, err := sessions.NewSQLStore("sqlite", e.config.Sessions.Path)
+		if err != nil {
+			return err
+		}
+		e.sessions = store
+	case "postgres":
+		store, err := sessions.NewSQLStore("postgres", e.config.Sessions.DSN)
+		if err != nil {
+			return err
+		}
+		e.sessions = store
+	case "redis":
+		if e.config.Sessions.Redis == nil {
+			return fmt.Errorf("redis session backend requires redis configuration")
+		}
+		store, err := sessions.NewRedisStore(&sessions.RedisStoreConfig{
+			Addr:      e.config.Sessions.Redis.Addr,
+			Password:  e.config.Sessions.Redis.Password,
+			DB:        e.config.Sessions.Redis.DB,
+			KeyPrefix: e.config.Sessions.Redis.KeyPrefix,
+		})
+		if err != nil {
+			return err
+		}
+		e.sessions = store
+	default:
+		return fmt.Errorf("unsupported session backend: %s", e.config.Sessions.Backend)
+	}
+	
+	e.logger.Info("Initialized session store", zap.String("backend", e.config.Sessions.Backend))
+	return nil
+}
+
+// CreateSession starts a conversation with an agent whose history the
+// server keeps.
+func (e *Engine) CreateSession(ctx context.Context, agentID string, metadata map[string]string) (*sessions.Session, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	
+	session, err := sessions.New(a.ClusterName, a.GetName(), metadata)
+	if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt = session.UpdatedAt.Add(e.sessionTTL())
+	if err := e.sessions.Put(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to save session: %w", err)
+	}
+	return session, nil
+}
+
+func (e *Engine) GetSession(ctx context.Context, id string) (*sessions.Session, error) {
+	return e.sessions.Get(ctx, id)
+}
+
+// ListSessions returns an agent's sessions, most recently active first.
+func (e *Engine) ListSessions(ctx context.Context, agentID string) ([]*sessions.Session, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	return e.sessions.List(ctx, a.ClusterName, a.GetName())
+}
+
+func (e *Engine) DeleteSession(ctx context.Context, id string) error {
+	unlock := e.sessionLocks.lock(id)
+	defer unlock()
+	
+	return e.sessions.Delete(ctx, id)
+}
+
+// AgentState returns what an agent keeps across sessions. Like sessions,
+// state belongs to the agent's cluster and name.
+func (e *Engine) AgentState(ctx context.Context, agentID string) (map[string]interface{}, error) {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	return e.sessions.GetState(ctx, a.ClusterName, a.GetName())
+}
+
+// SetAgentState replaces what an agent keeps across sessions.
+func (e *Engine) SetAgentState(ctx context.Context, agentID string, state map[string]interface{}) error {
+	a, err := e.agentManager.GetAgent(agentID)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrAgentNotFound, agentID)
+	}
+	return e.sessions.PutState(ctx, a.ClusterName, a.GetName(), state)
+}
+
+// SessionChat sends the request's messages in a session. The model sees
+// them after as much of the session's history as fits, and the messages
+// and the answer are added to the history. A request that fails leaves the
+// history as it was.
+func (e *Engine) SessionChat(ctx context.Context, id string, req *agent.Request) (*agent.Response, error) {
+	unlock := e.sessionLocks.lock(id)
+	defer unlock()
+	
+	session, err := e.sessions.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	
+	now := time.Now().UTC()
+	incoming := make([]agent.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		if msg.Timestamp.IsZero() {
+			msg.Timestamp = now
+		}
+		incoming[i] = msg
+	}
+	
+	history := append(append([]agent.Message(nil), session.Messages...), incoming...)
+	history = e.compactSession(ctx, session, history, len(incoming))
+	
+	budget := e.sessionMaxHistoryTokens()
+	var summary []agent.Message
+	if session.Summary != "" {
+		summary = append(summary, summaryMessage(session.Summary))
+		budget -= estimateTokens(&summary[0])
+	}
+	sent := fitHistory(history, len(incoming), budget)
+	req.Messages = append(summary, sent...)
+	req.SessionID = session.ID
+	req.SessionMetadata = session.Metadata
+	req.SessionTokens = session.TotalTokens
+	req.SessionCost = session.TotalCost
+	
+	resp, err := e.ProcessRequestContext(ctx, session.Cluster, session.Agent, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Metadata == nil {
+		resp.Metadata = make(map[string]interface{})
+	}
+	resp.Metadata["session_id"] = session.ID
+	resp.Metadata["history_messages"] = len(sent) - len(incoming)
+	if session.Summary != "" {
+		resp.Metadata["summarized_messages"] = session.SummarizedMessages
+	}
+	if resp.Error != "" {
+		return resp, nil
+	}
+	
+	session.ContextTokens = 0
+	for i := range req.Messages {
+		session.ContextTokens += estimateTokens(&req.Messages[i])
+	}
+	if usage, ok := resp.Metadata["usage"].(*providers.Usage); ok && usage != nil {
+		session.TotalTokens += usage.TotalTokens
+	}
+	if cost, ok := resp.Metadata["cost"].(float64); ok {
+		session.TotalCost += cost
+	}
+	
+	history = append(history, agent.Message{
+		ID:        resp.ID,
+		Role:      "assistant",
+		Content:   resp.Content,
+		Timestamp: time.Now().UTC(),
+	})
+	if max := e.sessionMaxMessages(); len(history) > max {
+		history = history[len(history)-max:]
+	}
+	
+	session.Messages = history
+	session.UpdatedAt = time.Now().UTC()
+	session.ExpiresAt = session.UpdatedAt.Add(e.sessionTTL())
+	if err := e.sessions.Put(ctx, session); err != nil {
+		// The answer is still good; only the history is behind
+		e.logger.Warn("Failed to save session",
+			zap.String("session", session.ID),
+			zap.Error(err))
+	}
+	
+	return resp, nil
+}
+
+// fitHistory keeps the last keep messages, which are being sent, and as
+// many of the messages before them as fit in maxTokens. The history starts
+// at a user message, as some providers require.
+func fitHistory(messages []agent.Message, keep, maxTokens int) []agent.Message {
+	start := len(messages) - keep
+	budget := maxTokens
+	for _, msg := range messages[start:] {
+		budget -= estimateTokens(&msg)
+	}
+	for start > 0 && estimateTokens(&messages[start-1]) <= budget {
+		start--
+		budget -= estimateTokens(&messages[start])
+	}
+	
+	for start < len(messages)-keep && messages[start].Role != "user" {
+		start++
+	}
+	return messages[start:]
+}
+
+// estimateTokens approximates a message's size in tokens, at about four
+// characters a token, without a model-specific tokenizer.
+func estimateTokens(msg *agent.Message) int {
+	return len(msg.Content)/4 + 4
+}
+
+func (e *Engine) sessionTTL() time.Duration {
+	if e.config.Sessions.TTL > 0 {
+		return e.config.Sessions.TTL
+	}
+	return defaultSessionTTL
+}
+
+func (e *Engine) sessionMaxMessages() int {
+	if e.config.Sessions.MaxMessages > 0 {
+		return e.config.Sessions.MaxMessages
+	}
+	return defaultSessionMaxMessages
+}
+
+func (e *Engine) sessionMaxHistoryTokens() int {
+	if e.config.Sessions.MaxHistoryTokens > 0 {
+		return e.config.Sessions.MaxHistoryTokens
+	}
+	return defaultSessionMaxHistoryTokens
+}
\ No newline at end of file
@@ -0,0 +1,152 @@
+package runtime
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+const defaultSmokeTestTimeout = 30 * time.Second
+
+type SmokeTestResult struct {
+	Agent    string        `json:"agent"`
+	Test     string        `json:"test"`
+	Passed   bool          `json:"passed"`
+	Failures []string      `json:"failures,omitempty"`
+	Latency  time.Duration `json:"latency"`
+	RanAt    time.Time     `json:"ran_at"`
+}
+
+// runSmokeTests sends the smoke-test prompts of each agent of spec, the
+// spec the cluster was started from, and marks agents whose responses do
+// not meet expectations as degraded.
+func (e *Engine) runSmokeTests(cluster *Cluster, spec *config.AgentCluster) {
+	var results []SmokeTestResult
+	
+	for _, agentConfig := range spec.Spec.Agents {
+		if len(agentConfig.SmokeTests) == 0 {
+			continue
+		}
+		
+		cluster.mu.RLock()
+		targetAgent, exists := cluster.Agents[agentConfig.Name]
+		cluster.mu.RUnlock()
+		if !exists {
+			continue
+		}
+		
+		failed := 0
+		for i, test := range agentConfig.SmokeTests {
+			result := e.runSmokeTest(cluster.Name, agentConfig.Name, i, test)
+			if !result.Passed {
+				failed++
+				e.logger.Warn("Smoke test failed",
+					zap.String("cluster", cluster.Name),
+					zap.String("agent", agentConfig.Name),
+					zap.String("test", result.Test),
+					zap.Strings("failures", result.Failures))
+			}
+			results = append(results, result)
+		}
+		
+		if failed > 0 {
+			reason := fmt.Sprintf("%d of %d smoke tests failed", failed, len(agentConfig.SmokeTests))
+			if err := e.agentManager.MarkDegraded(targetAgent.ID, reason); err != nil {
+				e.logger.Warn("Failed to mark agent degraded",
+					zap.String("agent", agentConfig.Name),
+					zap.Error(err))
+			}
+		}
+	}
+	
+	cluster.mu.Lock()
+	cluster.SmokeTests = results
+	cluster.mu.Unlock()
+	
+	e.logger.Info("Smoke tests completed",
+		zap.String("cluster", cluster.Name),
+		zap.Int("tests", len(results)))
+}
+
+func (e *Engine) runSmokeTest(clusterName, agentName string, index int, test config.SmokeTest) SmokeTestResult {
+	result := SmokeTestResult{
+		Agent: agentName,
+		Test:  test.Name,
+		RanAt: time.Now(),
+	}
+	if result.Test == "" {
+		result.Test = fmt.Sprintf("smoke-%d", index+1)
+	}
+	
+	timeout := test.Timeout
+	if timeout <= 0 {
+		timeout = defaultSmokeTestTimeout
+	}
+	
+	start := time.Now()
+	resp, err := e.ProcessRequest(clusterName, agentName, &agent.Request{
+		ID: fmt.Sprintf("smoke-%s-%d", agentName, index+1),
+		Messages: []agent.Message{
+			{Role: "user", Content: test.Prompt},
+		},
+		// Test prompts are not worth remembering
+		Context: map[string]interface{}{"memory": false},
+		Timeout: timeout,
+	})
+	result.Latency = time.Since(start)
+	
+	switch {
+	case err != nil:
+		result.Failures = append(result.Failures, err.Error())
+	case resp.Error != "":
+		result.Failures = append(result.Failures, resp.Error)
+	default:
+		result.Failures = checkSmokeTestExpectations(test.Expect, resp.Content, result.Latency)
+	}
+	
+	result.Passed = len(result.Failures) == 0
+	return result
+}
+
+// checkSmokeTestExpectations returns a description of every expectation the
+// response violates. Substring checks are case-insensitive.
+func checkSmokeTestExpectations(expect config.SmokeTestExpect, content string, latency time.Duration) []string {
+	var failures []string
+	lowered := strings.ToLower(content)
+	
+	for _, want := range expect.Contains {
+		if !strings.Contains(lowered, strings.ToLower(want)) {
+			failures = append(failures, fmt.Sprintf("response does not contain %q", want))
+		}
+	}
+	
+	for _, unwanted := range expect.NotContains {
+		if strings.Contains(lowered, strings.ToLower(unwanted)) {
+			failures = append(failures, fmt.Sprintf("response contains %q", unwanted))
+		}
+	}
+	
+	if expect.Matches != "" {
+		pattern, err := regexp.Compile(expect.Matches)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("invalid matches pattern: %v", err))
+		} else if !pattern.MatchString(content) {
+			failures = append(failures, fmt.Sprintf("response does not match %q", expect.Matches))
+		}
+	}
+	
+	if expect.MaxLength > 0 && len(content) > expect.MaxLength {
+		failures = append(failures, fmt.Sprintf("response length %d exceeds %d", len(content), expect.MaxLength))
+	}
+	
+	if expect.MaxLatency > 0 && latency > expect.MaxLatency {
+		failures = append(failures, fmt.Sprintf("latency %s exceeds %s", latency, expect.MaxLatency))
+	}
+	
+	return failures
+}
\ No newline at end of file
@@ -0,0 +1,142 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+)
+
+const (
+	defaultStartupTimeout = 2 * time.Minute
+	readinessRetry        = 100 * time.Millisecond
+	maxReadinessRetry     = 10 * time.Second
+)
+
+// startupOrder sorts a cluster's agents so that each comes after the agents
+// it depends on: first those that depend on none, then those that only
+// depend on them, and so on, in the order of the spec within each level.
+// An agent depending on itself is not a cycle; it only delegates to
+// itself.
+func startupOrder(agents []config.Agent) ([]config.Agent, error) {
+	byName := make(map[string]*config.Agent, len(agents))
+	for i := range agents {
+		byName[agents[i].Name] = &agents[i]
+	}
+	
+	// Depth-first search, in spec order so the cycle reported is stable
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := make(map[string]int)
+	levels := make(map[string]int)
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			for i, step := range path {
+				if step == name {
+					return fmt.Errorf("dependency cycle: %s", strings.Join(append(path[i:], name), " -> "))
+				}
+			}
+		case visited:
+			return nil
+		}
+		spec, ok := byName[name]
+		if !ok {
+			return nil
+		}
+		
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range spec.DependsOn {
+			if dep == name {
+				continue
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+			if levels[dep]+1 > levels[name] {
+				levels[name] = levels[dep] + 1
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for i := range agents {
+		if err := visit(agents[i].Name); err != nil {
+			return nil, err
+		}
+	}
+	
+	order := append([]config.Agent(nil), agents...)
+	sort.SliceStable(order, func(i, j int) bool {
+		return levels[order[i].Name] < levels[order[j].Name]
+	})
+	return order, nil
+}
+
+// dependenciesReady waits for the agents an agent depends on to be ready,
+// until the deadline. ready remembers the agents found ready already.
+func (e *Engine) dependenciesReady(cluster *Cluster, spec *config.Agent, ready map[string]bool, deadline time.Time) error {
+	for _, dep := range spec.DependsOn {
+		if dep == spec.Name || ready[dep] {
+			continue
+		}
+		
+		cluster.mu.RLock()
+		depAgent := cluster.Agents[dep]
+		depSpec := findAgentSpec(cluster.Config, dep)
+		cluster.mu.RUnlock()
+		if depAgent == nil || depSpec == nil {
+			return fmt.Errorf("dependency %s did not start", dep)
+		}
+		if err := e.waitReady(depAgent, depSpec.Health, deadline); err != nil {
+			return fmt.Errorf("dependency %s is not ready: %w", dep, err)
+		}
+		ready[dep] = true
+	}
+	return nil
+}
+
+// waitReady waits for an agent to run and, if it is probed for its health,
+// to pass a probe. It tries again, backing off, until the deadline or the
+// agent fails.
+func (e *Engine) waitReady(a *agent.Agent, health *config.AgentHealth, deadline time.Time) error {
+	delay := readinessRetry
+	for {
+		var err error
+		switch status := a.GetStatus(); status {
+		case agent.StatusRunning, agent.StatusIdle:
+			if health == nil || health.Interval <= 0 {
+				return nil
+			}
+			if err = e.probeAgent(a, health); err == nil {
+				return nil
+			}
+		case agent.StatusFailed:
+			return fmt.Errorf("%w: %s", ErrAgentUnavailable, a.GetErrorMessage())
+		default:
+			err = fmt.Errorf("agent is %s", status)
+		}
+		
+		if time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		select {
+		case <-e.done:
+			return fmt.Errorf("engine shut down")
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxReadinessRetry {
+			delay = maxReadinessRetry
+		}
+	}
+}
\ No newline at end of file
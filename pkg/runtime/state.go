@@ -0,0 +1,323 @@
+package runtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	_ "github.com/lib/pq"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// clusterState is what is saved of a deployed cluster, so a restarted
+// server can restore it.
+type clusterState struct {
+	Config    *config.AgentCluster `json:"config"`
+	Status    ClusterStatus        `json:"status"`
+	Message   string               `json:"message,omitempty"`
+	Agents    []agentState         `json:"agents,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// agentState is what is saved of an agent of a deployed cluster. Agents
+// are created again from the cluster's spec when it is restored.
+type agentState struct {
+	ID      string       `json:"id,omitempty"`
+	Name    string       `json:"name"`
+	Version int          `json:"version"`
+	Status  agent.Status `json:"status"`
+}
+
+// stateStore keeps the deployed clusters in SQLite or Postgres. Each
+// cluster is a row holding its state as JSON. The resource version of the
+// last cluster deleted is kept too, since saved clusters only carry their
+// own, as are the cluster templates. In distributed mode it also holds the
+// nodes, which of them runs each cluster, and which leads.
+type stateStore struct {
+	db       *sql.DB
+	postgres bool
+}
+
+var stateSchema = []string{
+	`CREATE TABLE IF NOT EXISTS goagents_clusters (
+		name TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		updated_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS goagents_resource_version (
+		id INTEGER PRIMARY KEY,
+		version BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS goagents_nodes (
+		id TEXT PRIMARY KEY,
+		address TEXT NOT NULL,
+		started_at BIGINT NOT NULL,
+		last_seen BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS goagents_cluster_owners (
+		name TEXT PRIMARY KEY,
+		node TEXT NOT NULL,
+		claimed_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS goagents_leader (
+		id INTEGER PRIMARY KEY,
+		node TEXT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS goagents_templates (
+		name TEXT PRIMARY KEY,
+		data TEXT NOT NULL,
+		updated_at BIGINT NOT NULL
+	)`,
+}
+
+// newStateStore opens the database and creates the table the store needs.
+// driver is sqlite or postgres.
+func newStateStore(driver, dsn string) (*stateStore, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database: %w", err)
+	}
+	if driver == "sqlite" {
+		// SQLite allows one writer at a time
+		db.SetMaxOpenConns(1)
+	}
+	for _, statement := range stateSchema {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create state tables: %w", err)
+		}
+	}
+	return &stateStore{db: db, postgres: driver == "postgres"}, nil
+}
+
+func (s *stateStore) save(name string, state *clusterState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode cluster: %w", err)
+	}
+	
+	query := `INSERT INTO goagents_clusters (name, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`
+	if s.postgres {
+		query = `INSERT INTO goagents_clusters (name, data, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`
+	}
+	if _, err := s.db.Exec(query, name, string(data), time.Now().UnixNano()); err != nil {
+		return fmt.Errorf("failed to save cluster: %w", err)
+	}
+	return nil
+}
+
+// remove deletes a cluster that was at resourceVersion.
+func (s *stateStore) remove(name string, resourceVersion uint64) error {
+	remove := `DELETE FROM goagents_clusters WHERE name = ?`
+	record := `INSERT INTO goagents_resource_version (id, version) VALUES (1, ?)
+		ON CONFLICT (id) DO UPDATE SET version = excluded.version
+		WHERE goagents_resource_version.version < excluded.version`
+	if s.postgres {
+		remove = strings.Replace(remove, "?", "$1", 1)
+		record = strings.Replace(record, "?", "$1", 1)
+	}
+	if _, err := s.db.Exec(remove, name); err != nil {
+		return fmt.Errorf("failed to remove cluster: %w", err)
+	}
+	if _, err := s.db.Exec(record, int64(resourceVersion)); err != nil {
+		return fmt.Errorf("failed to record resource version: %w", err)
+	}
+	return nil
+}
+
+// load returns the saved clusters, in the order they were last saved, and
+// the resource version of the last cluster deleted.
+func (s *stateStore) load() ([]*clusterState, uint64, error) {
+	var deleted int64
+	err := s.db.QueryRow(`SELECT version FROM goagents_resource_version WHERE id = 1`).Scan(&deleted)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, fmt.Errorf("failed to load resource version: %w", err)
+	}
+	
+	rows, err := s.db.Query(`SELECT name, data FROM goagents_clusters ORDER BY updated_at`)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to load clusters: %w", err)
+	}
+	defer rows.Close()
+	
+	var states []*clusterState
+	for rows.Next() {
+		var name, data string
+		if err := rows.Scan(&name, &data); err != nil {
+			return nil, 0, fmt.Errorf("failed to load clusters: %w", err)
+		}
+		var state clusterState
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode cluster %s: %w", name, err)
+		}
+		if state.Config == nil {
+			return nil, 0, fmt.Errorf("cluster %s was saved without its spec", name)
+		}
+		states = append(states, &state)
+	}
+	return states, uint64(deleted), rows.Err()
+}
+
+func (s *stateStore) close() error {
+	return s.db.Close()
+}
+
+func (e *Engine) initializeState() error {
+	switch e.config.State.Backend {
+	case "", "memory":
+		return nil
+	case "sqlite":
+		if err := os.MkdirAll(filepath.Dir(e.config.State.Path), 0o700); err != nil {
+			return fmt.Errorf("failed to create state directory: %w", err)
+		}
+		store, err := newStateStore("sqlite", e.config.State.Path)
+		if err != nil {
+			return err
+		}
+		e.state = store
+	case "postgres":
+		store, err := newStateStore("postgres", e.config.State.DSN)
+		if err != nil {
+			return err
+		}
+		e.state = store
+	default:
+		return fmt.Errorf("unsupported state backend: %s", e.config.State.Backend)
+	}
+	
+	e.logger.Info("Initialized state store", zap.String("backend", e.config.State.Backend))
+	return nil
+}
+
+// saveCluster saves a cluster's spec, status and agents. The cluster's
+// lock must be held. Failures are logged: the cluster keeps running, but
+// a restart would restore it as it was last saved. Nothing is saved once
+// the engine shuts down, so clusters stopped by the shutdown start again,
+// nor once another node runs the cluster.
+func (e *Engine) saveCluster(cluster *Cluster) {
+	if e.state == nil || cluster.dropped {
+		return
+	}
+	select {
+	case <-e.done:
+		return
+	default:
+	}
+	
+	state := &clusterState{
+		Config:    cluster.Config,
+		Status:    cluster.Status,
+		Message:   cluster.Message,
+		Agents:    make([]agentState, 0, len(cluster.Agents)),
+		CreatedAt: cluster.CreatedAt,
+		UpdatedAt: cluster.UpdatedAt,
+	}
+	for _, a := range cluster.Agents {
+		state.Agents = append(state.Agents, agentState{
+			ID:      a.ID,
+			Name:    a.GetName(),
+			Version: a.Version,
+			Status:  a.GetStatus(),
+		})
+	}
+	if err := e.state.save(cluster.Name, state); err != nil {
+		e.logger.Warn("Failed to save cluster",
+			zap.String("cluster", cluster.Name),
+			zap.Error(err))
+	}
+}
+
+// removeClusterState deletes a cluster's saved state. The cluster's lock
+// must be held.
+func (e *Engine) removeClusterState(cluster *Cluster) {
+	if e.state == nil {
+		return
+	}
+	version, _ := strconv.ParseUint(cluster.Config.Metadata.ResourceVersion, 10, 64)
+	if err := e.state.remove(cluster.Name, version); err != nil {
+		e.logger.Warn("Failed to remove saved cluster",
+			zap.String("cluster", cluster.Name),
+			zap.Error(err))
+	}
+}
+
+// restoreClusters deploys the clusters saved by the last server. Clusters
+// that were stopped stay stopped; the others start again. A cluster the
+// server's policy no longer allows is restored as failed. In distributed
+// mode a node restores the clusters it claims instead, as it syncs.
+func (e *Engine) restoreClusters() error {
+	if e.state == nil || e.distribution != nil {
+		return nil
+	}
+	states, deleted, err := e.state.load()
+	if err != nil {
+		return err
+	}
+	
+	e.raiseResourceVersion(deleted)
+	e.mu.Lock()
+	var started []*Cluster
+	for _, state := range states {
+		if cluster := e.restoreCluster(state); cluster != nil {
+			started = append(started, cluster)
+		}
+	}
+	e.mu.Unlock()
+	
+	for _, cluster := range started {
+		go e.startCluster(cluster)
+	}
+	return nil
+}
+
+// restoreCluster adds a saved cluster to the engine, and returns it if it
+// is to be started. e.mu must be held.
+func (e *Engine) restoreCluster(state *clusterState) *Cluster {
+	name := clusterSpecID(state.Config)
+	if _, exists := e.clusters[name]; exists {
+		return nil
+	}
+	// Versions handed out from now on must be new to every cluster
+	if version, err := strconv.ParseUint(state.Config.Metadata.ResourceVersion, 10, 64); err == nil {
+		e.raiseResourceVersion(version)
+	}
+	
+	cluster := &Cluster{
+		Name:      name,
+		Config:    state.Config,
+		Agents:    make(map[string]*agent.Agent),
+		Status:    ClusterStatusPending,
+		CreatedAt: state.CreatedAt,
+		UpdatedAt: state.UpdatedAt,
+	}
+	var started *Cluster
+	if state.Status == ClusterStatusStopped {
+		cluster.Status = ClusterStatusStopped
+		cluster.Message = state.Message
+	} else if err := e.config.Policy.CheckCluster(cluster.Config); err != nil {
+		cluster.Status = ClusterStatusFailed
+		cluster.Message = "not restored: " + err.Error()
+	} else {
+		started = cluster
+	}
+	e.clusters[name] = cluster
+	
+	e.logger.Info("Cluster restored",
+		zap.String("name", name),
+		zap.String("status", string(state.Status)),
+		zap.Int("agents", len(state.Agents)))
+	return started
+}
\ No newline at end of file
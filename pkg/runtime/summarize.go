@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/providers"
+	"github.com/goagents/goagents/pkg/sessions"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultSummaryThreshold    = 0.8
+	defaultSummaryKeepMessages = 6
+	defaultSummaryMaxTokens    = 1024
+)
+
+const summaryPrompt = "You compress conversations. Summarize the conversation below so it can stand in for it: " +
+	"keep the user's goals, facts, names, numbers, decisions and open questions, and leave out pleasantries. " +
+	"Write the summary in the third person and reply with the summary only."
+
+// compactSession summarizes the earlier turns of a session once its history
+// nears the budget, keeping the most recent messages verbatim. incoming
+// counts the messages at the end of history that are being sent, which are
+// always kept. It returns the history to send, and leaves the session as it
+// was if the summary cannot be written.
+func (e *Engine) compactSession(ctx context.Context, session *sessions.Session, history []agent.Message, incoming int) []agent.Message {
+	cfg := e.config.Sessions.Summarize
+	if !cfg.Enabled {
+		return history
+	}
+	
+	threshold := cfg.Threshold
+	if threshold == 0 {
+		threshold = defaultSummaryThreshold
+	}
+	tokens := len(session.Summary) / 4
+	for i := range history {
+		tokens += estimateTokens(&history[i])
+	}
+	if float64(tokens) < threshold*float64(e.sessionMaxHistoryTokens()) {
+		return history
+	}
+	
+	keep := cfg.KeepMessages
+	if keep == 0 {
+		keep = defaultSummaryKeepMessages
+	}
+	if keep < incoming {
+		keep = incoming
+	}
+	// The verbatim part starts at a user message, so no answer is kept
+	// without its question
+	split := len(history) - keep
+	for split > 0 && history[split].Role != "user" {
+		split--
+	}
+	if split <= 0 {
+		return history
+	}
+	
+	summary, usage, err := e.summarize(ctx, session, history[:split])
+	if err != nil {
+		e.logger.Warn("Failed to summarize session",
+			zap.String("session", session.ID),
+			zap.Error(err))
+		return history
+	}
+	
+	session.Summary = summary
+	session.SummarizedMessages += split
+	if usage != nil {
+		session.TotalTokens += usage.TotalTokens
+	}
+	return history[split:]
+}
+
+// summarize writes a summary of a session's earlier summary and messages,
+// with the configured model or the session's agent.
+func (e *Engine) summarize(ctx context.Context, session *sessions.Session, messages []agent.Message) (string, *providers.Usage, error) {
+	cfg := e.config.Sessions.Summarize
+	
+	var provider providers.Provider
+	model := cfg.Model
+	if cfg.Provider == "" {
+		targetAgent, agentProvider, err := e.resolveAgent(session.Cluster, session.Agent)
+		if err != nil {
+			return "", nil, err
+		}
+		provider = agentProvider
+		model = targetAgent.Config.Model
+	} else {
+		if err := e.config.Policy.CheckModel(cfg.Provider, cfg.Model); err != nil {
+			return "", nil, err
+		}
+		summarizer, exists, err := e.providerFor(e.clusterNamespace(session.Cluster), cfg.Provider)
+		if err != nil {
+			return "", nil, err
+		}
+		if !exists {
+			return "", nil, fmt.Errorf("provider %s not available", cfg.Provider)
+		}
+		provider = summarizer
+	}
+	
+	var transcript strings.Builder
+	if session.Summary != "" {
+		fmt.Fprintf(&transcript, "Summary of the conversation so far:\n%s\n\n", session.Summary)
+	}
+	for _, msg := range messages {
+		fmt.Fprintf(&transcript, "%s: %s\n\n", msg.Role, msg.Content)
+	}
+	
+	maxTokens := cfg.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = defaultSummaryMaxTokens
+	}
+	resp, err := provider.Chat(ctx, &providers.ChatRequest{
+		Model:     model,
+		MaxTokens: maxTokens,
+		Messages: []providers.Message{
+			{Role: "system", Content: summaryPrompt},
+			{Role: "user", Content: transcript.String()},
+		},
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", nil, fmt.Errorf("empty summary")
+	}
+	return summary, resp.Usage, nil
+}
+
+// summaryMessage passes a session's summary to the model, after the
+// agent's own system prompt.
+func summaryMessage(summary string) agent.Message {
+	return agent.Message{
+		Role:    "system",
+		Content: "Summary of the earlier conversation:\n" + summary,
+	}
+}
\ No newline at end of file
@@ -0,0 +1,295 @@
+package runtime
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+)
+
+// Template is a cluster template kept in the registry.
+type Template struct {
+	config.ClusterTemplate
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateInstance is a cluster to instantiate from a template: the values
+// of the template's parameters, and the cluster's name and namespace when
+// they are not the ones the template gives.
+type TemplateInstance struct {
+	Name      string                 `json:"name,omitempty"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Values    map[string]interface{} `json:"values,omitempty"`
+}
+
+// templateRegistry keeps cluster templates in the state store, so every
+// node of a distributed deployment shares them, or in memory without one.
+type templateRegistry struct {
+	store     *stateStore
+	templates map[string]*Template
+	mu        sync.RWMutex
+}
+
+func newTemplateRegistry(store *stateStore) *templateRegistry {
+	return &templateRegistry{
+		store:     store,
+		templates: make(map[string]*Template),
+	}
+}
+
+func (r *templateRegistry) create(tmpl *Template) error {
+	if r.store != nil {
+		return r.store.createTemplate(tmpl)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if _, exists := r.templates[tmpl.Name]; exists {
+		return fmt.Errorf("%w: template %s already exists", ErrConflict, tmpl.Name)
+	}
+	r.templates[tmpl.Name] = tmpl
+	return nil
+}
+
+// update replaces a template, keeping the time it was created.
+func (r *templateRegistry) update(tmpl *Template) error {
+	if r.store != nil {
+		return r.store.updateTemplate(tmpl)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	current, exists := r.templates[tmpl.Name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, tmpl.Name)
+	}
+	tmpl.CreatedAt = current.CreatedAt
+	r.templates[tmpl.Name] = tmpl
+	return nil
+}
+
+func (r *templateRegistry) get(name string) (*Template, error) {
+	if r.store != nil {
+		return r.store.template(name)
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	
+	tmpl, exists := r.templates[name]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return tmpl, nil
+}
+
+func (r *templateRegistry) list() ([]*Template, error) {
+	var templates []*Template
+	if r.store != nil {
+		var err error
+		if templates, err = r.store.listTemplates(); err != nil {
+			return nil, err
+		}
+	} else {
+		r.mu.RLock()
+		for _, tmpl := range r.templates {
+			templates = append(templates, tmpl)
+		}
+		r.mu.RUnlock()
+	}
+	
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+func (r *templateRegistry) remove(name string) error {
+	if r.store != nil {
+		return r.store.removeTemplate(name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	
+	if _, exists := r.templates[name]; !exists {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	delete(r.templates, name)
+	return nil
+}
+
+// CreateTemplate adds a cluster template to the registry. A template of
+// the same name is a conflict.
+func (e *Engine) CreateTemplate(tmpl *config.ClusterTemplate) (*Template, error) {
+	if err := config.ValidateClusterTemplate(tmpl); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	now := time.Now()
+	saved := &Template{ClusterTemplate: *tmpl, CreatedAt: now, UpdatedAt: now}
+	if err := e.templates.create(saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+// UpdateTemplate replaces a cluster template. Clusters instantiated from
+// it are left as they are.
+func (e *Engine) UpdateTemplate(tmpl *config.ClusterTemplate) (*Template, error) {
+	if err := config.ValidateClusterTemplate(tmpl); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	saved := &Template{ClusterTemplate: *tmpl, UpdatedAt: time.Now()}
+	if err := e.templates.update(saved); err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
+
+func (e *Engine) GetTemplate(name string) (*Template, error) {
+	return e.templates.get(name)
+}
+
+// ListTemplates returns the cluster templates, by name.
+func (e *Engine) ListTemplates() ([]*Template, error) {
+	return e.templates.list()
+}
+
+func (e *Engine) DeleteTemplate(name string) error {
+	return e.templates.remove(name)
+}
+
+// RenderTemplate returns the cluster a template describes for an instance,
+// annotated with the template's name, without deploying it.
+func (e *Engine) RenderTemplate(name string, instance *TemplateInstance) (*config.AgentCluster, error) {
+	tmpl, err := e.templates.get(name)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster, err := config.InstantiateClusterTemplate(&tmpl.ClusterTemplate, instance.Values)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTemplate, err)
+	}
+	if instance.Name != "" {
+		cluster.Metadata.Name = instance.Name
+	}
+	if instance.Namespace != "" {
+		cluster.Metadata.Namespace = instance.Namespace
+	}
+	if cluster.Metadata.Name == "" {
+		return nil, fmt.Errorf("%w: cluster name is required, as the instance's name or the template's metadata.name", ErrInvalidTemplate)
+	}
+	if cluster.Metadata.Namespace == "" {
+		cluster.Metadata.Namespace = config.DefaultNamespace
+	}
+	if cluster.APIVersion == "" {
+		cluster.APIVersion = "goagents.dev/v1"
+	}
+	if cluster.Kind == "" {
+		cluster.Kind = "AgentCluster"
+	}
+	if cluster.Metadata.Annotations == nil {
+		cluster.Metadata.Annotations = make(map[string]string)
+	}
+	cluster.Metadata.Annotations[config.TemplateAnnotation] = tmpl.Name
+	cluster.Metadata.ResourceVersion = ""
+	return cluster, nil
+}
+
+func (s *stateStore) createTemplate(tmpl *Template) error {
+	data, err := json.Marshal(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to encode template: %w", err)
+	}
+	query := s.bind(`INSERT INTO goagents_templates (name, data, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO NOTHING`)
+	result, err := s.db.Exec(query, tmpl.Name, string(data), tmpl.UpdatedAt.UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to save template: %w", err)
+	}
+	if created, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to save template: %w", err)
+	} else if created == 0 {
+		return fmt.Errorf("%w: template %s already exists", ErrConflict, tmpl.Name)
+	}
+	return nil
+}
+
+func (s *stateStore) updateTemplate(tmpl *Template) error {
+	current, err := s.template(tmpl.Name)
+	if err != nil {
+		return err
+	}
+	tmpl.CreatedAt = current.CreatedAt
+	data, err := json.Marshal(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to encode template: %w", err)
+	}
+	query := s.bind(`UPDATE goagents_templates SET data = ?, updated_at = ? WHERE name = ?`)
+	result, err := s.db.Exec(query, string(data), tmpl.UpdatedAt.UnixNano(), tmpl.Name)
+	if err != nil {
+		return fmt.Errorf("failed to save template: %w", err)
+	}
+	if updated, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to save template: %w", err)
+	} else if updated == 0 {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, tmpl.Name)
+	}
+	return nil
+}
+
+func (s *stateStore) template(name string) (*Template, error) {
+	var data string
+	err := s.db.QueryRow(s.bind(`SELECT data FROM goagents_templates WHERE name = ?`), name).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load template: %w", err)
+	}
+	var tmpl Template
+	if err := json.Unmarshal([]byte(data), &tmpl); err != nil {
+		return nil, fmt.Errorf("failed to decode template %s: %w", name, err)
+	}
+	return &tmpl, nil
+}
+
+func (s *stateStore) listTemplates() ([]*Template, error) {
+	rows, err := s.db.Query(`SELECT name, data FROM goagents_templates`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load templates: %w", err)
+	}
+	defer rows.Close()
+	
+	var templates []*Template
+	for rows.Next() {
+		var name, data string
+		if err := rows.Scan(&name, &data); err != nil {
+			return nil, fmt.Errorf("failed to load templates: %w", err)
+		}
+		var tmpl Template
+		if err := json.Unmarshal([]byte(data), &tmpl); err != nil {
+			return nil, fmt.Errorf("failed to decode template %s: %w", name, err)
+		}
+		templates = append(templates, &tmpl)
+	}
+	return templates, rows.Err()
+}
+
+func (s *stateStore) removeTemplate(name string) error {
+	result, err := s.db.Exec(s.bind(`DELETE FROM goagents_templates WHERE name = ?`), name)
+	if err != nil {
+		return fmt.Errorf("failed to remove template: %w", err)
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to remove template: %w", err)
+	} else if removed == 0 {
+		return fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+	}
+	return nil
+}
\ No newline at end of file
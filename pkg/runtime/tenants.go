@@ -0,0 +1,254 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/providers"
+	"github.com/goagents/goagents/pkg/vault"
+	"go.uber.org/zap"
+)
+
+// ErrVaultDisabled is returned by the tenant credential methods when no
+// vault is configured.
+var ErrVaultDisabled = errors.New("credential vault is not enabled")
+
+// tenantProviders caches the provider clients built from namespaces'
+// credentials, keyed by namespace and provider.
+type tenantProviders struct {
+	pools map[string]*providers.CredentialPool
+	mu    sync.Mutex
+}
+
+func newTenantProviders() *tenantProviders {
+	return &tenantProviders{
+		pools: make(map[string]*providers.CredentialPool),
+	}
+}
+
+func tenantKey(namespace, provider string) string {
+	return namespace + "/" + provider
+}
+
+func (t *tenantProviders) get(namespace, provider string) (*providers.CredentialPool, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	pool, ok := t.pools[tenantKey(namespace, provider)]
+	return pool, ok
+}
+
+// add caches pool unless another request got there first, and returns the
+// cached pool.
+func (t *tenantProviders) add(namespace, provider string, pool *providers.CredentialPool) *providers.CredentialPool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	key := tenantKey(namespace, provider)
+	if existing, ok := t.pools[key]; ok {
+		pool.Close()
+		return existing
+	}
+	t.pools[key] = pool
+	return pool
+}
+
+func (t *tenantProviders) remove(namespace, provider string) {
+	t.mu.Lock()
+	pool, ok := t.pools[tenantKey(namespace, provider)]
+	delete(t.pools, tenantKey(namespace, provider))
+	t.mu.Unlock()
+	
+	if ok {
+		pool.Close()
+	}
+}
+
+func (t *tenantProviders) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	for key, pool := range t.pools {
+		pool.Close()
+		delete(t.pools, key)
+	}
+}
+
+func (e *Engine) initializeVault() error {
+	if !e.config.Vault.Enabled {
+		return nil
+	}
+	
+	v, err := vault.New(e.config.Vault.MasterKey, e.config.Vault.Path)
+	if err != nil {
+		return err
+	}
+	e.vault = v
+	
+	e.logger.Info("Initialized credential vault", zap.Bool("persistent", e.config.Vault.Path != ""))
+	return nil
+}
+
+// providerFor returns the provider that serves a namespace: a client built
+// from the namespace's own credentials when it has stored any for the
+// provider, and the server's otherwise.
+func (e *Engine) providerFor(namespace, name string) (providers.Provider, bool, error) {
+	if e.vault != nil {
+		if pool, ok := e.tenantProviders.get(namespace, name); ok {
+			return e.providerManager.Wrap(name, pool), true, nil
+		}
+		
+		credential, err := e.vault.Get(namespace, name)
+		switch {
+		case err == nil:
+			pool, err := e.newTenantPool(namespace, name, credential)
+			if err != nil {
+				return nil, false, err
+			}
+			pool = e.tenantProviders.add(namespace, name, pool)
+			return e.providerManager.Wrap(name, pool), true, nil
+		case !errors.Is(err, vault.ErrNotFound):
+			// Never fall back to the server's keys when the tenant's
+			// are merely unreadable, or the server would be billed
+			return nil, false, fmt.Errorf("failed to load %s credentials for namespace %s: %w", name, namespace, err)
+		}
+	}
+	
+	provider, exists := e.providerManager.GetProvider(name)
+	return provider, exists, nil
+}
+
+func (e *Engine) newTenantPool(namespace, name string, credential *vault.Credential) (*providers.CredentialPool, error) {
+	factory, err := e.providerFactory(name)
+	if err != nil {
+		return nil, err
+	}
+	
+	pool, err := providers.NewCredentialPool(name, credential.APIKeys(), factory, func(event providers.CredentialEvent) {
+		e.handleTenantCredentialRevoked(namespace, event)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s provider for namespace %s: %w", name, namespace, err)
+	}
+	return pool, nil
+}
+
+// handleTenantCredentialRevoked reports a tenant key rejected by its
+// provider. It is the tenant's key, so this is a warning for them rather
+// than a critical alert for the server.
+func (e *Engine) handleTenantCredentialRevoked(namespace string, event providers.CredentialEvent) {
+	e.logger.Warn("Provider rejected tenant API key, credential marked invalid",
+		zap.String("namespace", namespace),
+		zap.String("provider", event.Provider),
+		zap.String("key", event.Fingerprint),
+		zap.Int("remaining_keys", event.Remaining),
+		zap.Error(event.Err))
+		
+	e.publishEvent(agent.Event{
+		Type: agent.EventCredentialRevoked,
+		Data: map[string]interface{}{
+			"severity":       "warning",
+			"namespace":      namespace,
+			"provider":       event.Provider,
+			"key":            event.Fingerprint,
+			"remaining_keys": event.Remaining,
+			"error":          event.Err.Error(),
+		},
+	})
+}
+
+// SetTenantCredential stores a namespace's API keys for a provider. The
+// namespace's clusters use them from their next request on.
+func (e *Engine) SetTenantCredential(namespace, provider string, apiKeys []string) error {
+	if e.vault == nil {
+		return ErrVaultDisabled
+	}
+	if _, err := e.providerFactory(provider); err != nil {
+		return err
+	}
+	if len(apiKeys) == 0 || apiKeys[0] == "" {
+		return fmt.Errorf("api_key is required")
+	}
+	if err := e.config.Policy.CheckProvider(provider); err != nil {
+		return err
+	}
+	
+	if err := e.vault.Put(namespace, &vault.Credential{
+		Provider:      provider,
+		APIKey:        apiKeys[0],
+		BackupAPIKeys: apiKeys[1:],
+	}); err != nil {
+		return fmt.Errorf("failed to store credential: %w", err)
+	}
+	e.tenantProviders.remove(namespace, provider)
+	e.refreshToolSecretsAsync()
+	
+	e.logger.Info("Tenant credential stored",
+		zap.String("namespace", namespace),
+		zap.String("provider", provider),
+		zap.Int("api_keys", len(apiKeys)))
+	return nil
+}
+
+// DeleteTenantCredential removes a namespace's API keys for a provider, so
+// its clusters go back to the server's keys.
+func (e *Engine) DeleteTenantCredential(namespace, provider string) error {
+	if e.vault == nil {
+		return ErrVaultDisabled
+	}
+	
+	if err := e.vault.Delete(namespace, provider); err != nil {
+		return err
+	}
+	e.tenantProviders.remove(namespace, provider)
+	e.refreshToolSecretsAsync()
+	
+	e.logger.Info("Tenant credential deleted",
+		zap.String("namespace", namespace),
+		zap.String("provider", provider))
+	return nil
+}
+
+// TenantCredentials reports the state of a namespace's API keys per
+// provider. Keys are identified by fingerprint only, and show as valid
+// until a request has been made with them.
+func (e *Engine) TenantCredentials(namespace string) (map[string][]providers.CredentialStatus, error) {
+	if e.vault == nil {
+		return nil, ErrVaultDisabled
+	}
+	
+	credentials := make(map[string][]providers.CredentialStatus)
+	for _, name := range e.vault.Providers(namespace) {
+		if pool, ok := e.tenantProviders.get(namespace, name); ok {
+			credentials[name] = pool.Status()
+			continue
+		}
+		
+		credential, err := e.vault.Get(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		statuses := make([]providers.CredentialStatus, 0, len(credential.APIKeys()))
+		for i, apiKey := range credential.APIKeys() {
+			statuses = append(statuses, providers.CredentialStatus{
+				Fingerprint: providers.KeyFingerprint(apiKey),
+				Active:      i == 0,
+				Valid:       true,
+			})
+		}
+		credentials[name] = statuses
+	}
+	
+	return credentials, nil
+}
+
+// clusterNamespace returns the namespace of a deployed cluster.
+func (e *Engine) clusterNamespace(name string) string {
+	cluster, err := e.getCluster(name)
+	if err != nil {
+		return ""
+	}
+	return cluster.Config.Metadata.Namespace
+}
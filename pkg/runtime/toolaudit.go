@@ -0,0 +1,269 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/tools"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultToolAuditMaxEntries = 10000
+	defaultToolCallsLimit      = 100
+	// maxAuditedArgLength cuts long string arguments, such as documents
+	// passed to a tool, to keep the log small
+	maxAuditedArgLength = 512
+)
+
+// sensitiveArgPattern matches argument names whose values are never kept.
+var sensitiveArgPattern = regexp.MustCompile(`(?i)passw(or)?d|passphrase|secret|token|api[-_]?key|authorization|credential|private[-_]?key|cookie`)
+
+// ToolCallRecord describes one tool execution for audit.
+type ToolCallRecord struct {
+	ID           string `json:"id"`
+	AgentID      string `json:"agent_id"`
+	Cluster      string `json:"cluster,omitempty"`
+	Agent        string `json:"agent,omitempty"`
+	Tool         string `json:"tool"`
+	ToolType     string `json:"tool_type"`
+	Conversation string `json:"conversation_id,omitempty"`
+	// ArgsHash identifies the arguments without revealing them
+	ArgsHash string                 `json:"args_hash"`
+	Args     map[string]interface{} `json:"args,omitempty"`
+	// Outcome is success, error when the tool reported an error, or failed
+	// when it could not be run at all
+	Outcome    string    `json:"outcome"`
+	Error      string    `json:"error,omitempty"`
+	DryRun     bool      `json:"dry_run,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type ToolCallFilter struct {
+	Tool    string
+	Outcome string
+	Since   time.Time
+	// Limit caps the records returned, newest first
+	Limit int
+}
+
+// toolAuditLog keeps the most recent tool calls and appends every call to
+// a JSON lines file when a path is set.
+type toolAuditLog struct {
+	config  config.ToolAuditConfig
+	records []ToolCallRecord
+	nextID  uint64
+	file    *os.File
+	mu      sync.RWMutex
+}
+
+func newToolAuditLog(cfg config.ToolAuditConfig) (*toolAuditLog, error) {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultToolAuditMaxEntries
+	}
+	
+	log := &toolAuditLog{config: cfg}
+	if cfg.Path != "" {
+		if err := log.load(); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open tool audit file: %w", err)
+		}
+		log.file = file
+	}
+	
+	return log, nil
+}
+
+// load reads back the most recent records of an existing audit file.
+func (l *toolAuditLog) load() error {
+	file, err := os.Open(l.config.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open tool audit file: %w", err)
+	}
+	defer file.Close()
+	
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var record ToolCallRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return fmt.Errorf("failed to parse tool audit file: %w", err)
+		}
+		l.nextID++
+		l.append(record)
+	}
+	
+	return scanner.Err()
+}
+
+func (l *toolAuditLog) append(record ToolCallRecord) {
+	l.records = append(l.records, record)
+	if excess := len(l.records) - l.config.MaxEntries; excess > 0 {
+		l.records = append(l.records[:0:0], l.records[excess:]...)
+	}
+}
+
+func (l *toolAuditLog) record(record ToolCallRecord) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.nextID++
+	record.ID = fmt.Sprintf("tc-%d", l.nextID)
+	l.append(record)
+	
+	if l.file != nil {
+		line, err := json.Marshal(&record)
+		if err != nil {
+			return err
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to persist tool call: %w", err)
+		}
+	}
+	return nil
+}
+
+// forAgent lists an agent's tool calls, newest first.
+func (l *toolAuditLog) forAgent(agentID string, filter ToolCallFilter) []ToolCallRecord {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultToolCallsLimit
+	}
+	
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	
+	matches := make([]ToolCallRecord, 0)
+	for i := len(l.records) - 1; i >= 0 && len(matches) < limit; i-- {
+		record := l.records[i]
+		if record.AgentID != agentID ||
+			(filter.Tool != "" && record.Tool != filter.Tool) ||
+			(filter.Outcome != "" && record.Outcome != filter.Outcome) ||
+			record.StartedAt.Before(filter.Since) {
+			continue
+		}
+		matches = append(matches, record)
+	}
+	return matches
+}
+
+func (l *toolAuditLog) close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// auditToolCalls is tool middleware that records every call in the audit
+// log. It runs outermost, so calls refused by validation, concurrency
+// limits or other middleware are recorded too.
+func (e *Engine) auditToolCalls(key tools.Key, tool tools.Tool, next tools.ExecuteFunc) tools.ExecuteFunc {
+	return func(ctx context.Context, args map[string]interface{}) (*tools.Result, error) {
+		start := time.Now()
+		result, err := next(ctx, args)
+		
+		record := ToolCallRecord{
+			AgentID:      key.Scope,
+			Tool:         key.Name,
+			ToolType:     tool.Type(),
+			Conversation: tools.ConversationFrom(ctx),
+			ArgsHash:     argsHash(args),
+			Outcome:      "success",
+			StartedAt:    start.UTC(),
+			DurationMs:   time.Since(start).Milliseconds(),
+		}
+		if e.config.Tools.Audit.Args != "hash" {
+			record.Args = redactArgs(args)
+		}
+		switch {
+		case err != nil:
+			record.Outcome = "failed"
+			record.Error = err.Error()
+		case result != nil && result.Error != "":
+			record.Outcome = "error"
+			record.Error = result.Error
+		}
+		if result != nil {
+			record.DryRun, _ = result.Metadata["dry_run"].(bool)
+		}
+		if a, lookupErr := e.agentManager.GetAgent(key.Scope); lookupErr == nil {
+			record.Cluster = a.ClusterName
+			record.Agent = a.GetName()
+		}
+		
+		if auditErr := e.toolAudit.record(record); auditErr != nil {
+			e.logger.Warn("Failed to record tool call",
+				zap.String("agent_id", key.Scope),
+				zap.String("tool", key.Name),
+				zap.Error(auditErr))
+		}
+		return result, err
+	}
+}
+
+// ToolCalls lists the recorded tool calls of an agent, newest first. Calls
+// of deleted agents stay listed until they age out of the log.
+func (e *Engine) ToolCalls(agentID string, filter ToolCallFilter) []ToolCallRecord {
+	return e.toolAudit.forAgent(agentID, filter)
+}
+
+// argsHash is a SHA-256 of the arguments' JSON encoding, which orders
+// object keys, so equal arguments always hash the same.
+func argsHash(args map[string]interface{}) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		data = []byte(fmt.Sprint(args))
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// redactArgs copies arguments with the values of sensitive names hidden and
+// long strings cut short.
+func redactArgs(args map[string]interface{}) map[string]interface{} {
+	if args == nil {
+		return nil
+	}
+	return redactArg("", args).(map[string]interface{})
+}
+
+func redactArg(name string, value interface{}) interface{} {
+	if name != "" && sensitiveArgPattern.MatchString(name) {
+		return redactedValue
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		redacted := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			redacted[key] = redactArg(key, item)
+		}
+		return redacted
+	case []interface{}:
+		redacted := make([]interface{}, len(v))
+		for i, item := range v {
+			redacted[i] = redactArg("", item)
+		}
+		return redacted
+	case string:
+		if len(v) > maxAuditedArgLength {
+			return v[:maxAuditedArgLength] + "..."
+		}
+	}
+	return value
+}
\ No newline at end of file
@@ -0,0 +1,107 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/providers"
+	"github.com/goagents/goagents/pkg/tools"
+	"go.uber.org/zap"
+)
+
+// defaultMaxTurns bounds the model calls of a request for agents that do
+// not set max_turns.
+const defaultMaxTurns = 10
+
+func maxTurns(targetAgent *agent.Agent) int {
+	if targetAgent.Config.MaxTurns > 0 {
+		return targetAgent.Config.MaxTurns
+	}
+	return defaultMaxTurns
+}
+
+// runToolUses runs the tool calls of one model turn, one after another in
+// the order the model made them, and returns the calls with their results
+// and the messages that report the results to the model. Each call is
+// recorded in the request's transcript, if it has one.
+func (e *Engine) runToolUses(ctx context.Context, targetAgent *agent.Agent, req *agent.Request, toolUses []providers.ToolUse, capture *transcriptCapture) ([]agent.ToolUse, []providers.Message) {
+	// Results are cached per request, as a conversation of its own
+	ctx = tools.WithConversation(ctx, req.ID)
+	
+	ran := make([]agent.ToolUse, 0, len(toolUses))
+	messages := make([]providers.Message, 0, len(toolUses))
+	for _, toolUse := range toolUses {
+		call := agent.ToolUse{
+			ID:   toolUse.ID,
+			Name: toolUse.Name,
+			Args: toolUse.Args,
+		}
+		
+		start := time.Now()
+		toolCtx, span := e.startToolSpan(ctx, toolUse)
+		result, err := e.toolManager.Execute(toolCtx, tools.Key{Scope: targetAgent.ID, Name: toolUse.Name}, toolUse.Args)
+		switch {
+		case err != nil:
+			call.Error = err.Error()
+		case result.Error != "":
+			call.Error = result.Error
+		default:
+			call.Result = result.Data
+		}
+		endSpan(span, call.Error)
+		capture.toolCall(&call, start)
+		e.prometheus.countToolCall(targetAgent, toolUse.Name, call.Error == "")
+		if call.Error != "" {
+			e.logger.Debug("Tool call failed",
+				zap.String("agent", targetAgent.GetName()),
+				zap.String("tool", toolUse.Name),
+				zap.String("error", call.Error))
+		}
+		
+		ran = append(ran, call)
+		messages = append(messages, toolResultMessage(&call))
+	}
+	return ran, messages
+}
+
+// toolResultMessage reports a call's result to the model as JSON, or its
+// error as text.
+func toolResultMessage(call *agent.ToolUse) providers.Message {
+	message := providers.Message{
+		Role: "tool",
+		ToolResult: &providers.ToolResult{
+			ToolUseID: call.ID,
+			Name:      call.Name,
+			IsError:   call.Error != "",
+		},
+	}
+	
+	if call.Error != "" {
+		message.Content = call.Error
+		return message
+	}
+	if text, ok := call.Result.(string); ok {
+		message.Content = text
+		return message
+	}
+	data, err := json.Marshal(call.Result)
+	if err != nil {
+		message.Content = err.Error()
+		message.ToolResult.IsError = true
+		return message
+	}
+	message.Content = string(data)
+	return message
+}
+
+// addUsage adds the tokens of one model call to those of the request.
+func addUsage(total *providers.Usage, usage *providers.Usage) {
+	if usage == nil {
+		return
+	}
+	total.PromptTokens += usage.PromptTokens
+	total.CompletionTokens += usage.CompletionTokens
+	total.TotalTokens += usage.TotalTokens
+}
\ No newline at end of file
@@ -0,0 +1,225 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/tools"
+	"github.com/goagents/goagents/pkg/vault"
+	"go.uber.org/zap"
+)
+
+const defaultSecretRefreshInterval = time.Minute
+
+// secretTool is a tool whose auth settings reference secrets, kept so the
+// tool can be rebuilt when a secret rotates.
+type secretTool struct {
+	cluster string
+	agent   string
+	// config is what the registered tools were created from, with the
+	// secrets as they were then
+	config *tools.Config
+	// refs is the configured auth, with the references unresolved
+	refs  *tools.AuthConfig
+	names []string
+}
+
+// toolSecrets tracks the secret tools of every agent, keyed by agent ID.
+type toolSecrets struct {
+	agents map[string][]*secretTool
+	mu     sync.Mutex
+	// refreshing serializes refreshes, which may take as long as it takes
+	// to connect the rebuilt tools
+	refreshing sync.Mutex
+}
+
+func newToolSecrets() *toolSecrets {
+	return &toolSecrets{
+		agents: make(map[string][]*secretTool),
+	}
+}
+
+func (s *toolSecrets) track(agentID string, tool *secretTool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.agents[agentID] = append(s.agents[agentID], tool)
+}
+
+// untrack stops tracking an agent's tool of the configured name, such as
+// when it is replaced at runtime.
+func (s *toolSecrets) untrack(agentID, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	secretTools := s.agents[agentID][:0:0]
+	for _, st := range s.agents[agentID] {
+		if st.config.Name != name {
+			secretTools = append(secretTools, st)
+		}
+	}
+	s.agents[agentID] = secretTools
+}
+
+func (s *toolSecrets) forget(agentID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	delete(s.agents, agentID)
+}
+
+func (s *toolSecrets) snapshot() map[string][]*secretTool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	snapshot := make(map[string][]*secretTool, len(s.agents))
+	for agentID, secretTools := range s.agents {
+		snapshot[agentID] = append([]*secretTool(nil), secretTools...)
+	}
+	return snapshot
+}
+
+// vaultSecret resolves vault:// references against the credential vault.
+// The path is a namespace and provider, such as "acme/openai", and the key
+// api_key, the default, or backup_api_key.N for the Nth backup key.
+func (e *Engine) vaultSecret(path, key string) (string, error) {
+	if e.vault == nil {
+		return "", ErrVaultDisabled
+	}
+	
+	namespace, provider, ok := strings.Cut(path, "/")
+	if !ok || namespace == "" || provider == "" {
+		return "", fmt.Errorf("invalid vault path %q, expected namespace/provider", path)
+	}
+	credential, err := e.vault.Get(namespace, provider)
+	if errors.Is(err, vault.ErrNotFound) {
+		return "", fmt.Errorf("%w: %v", tools.ErrSecretNotFound, err)
+	}
+	if err != nil {
+		return "", err
+	}
+	
+	if key == "" || key == "api_key" {
+		return credential.APIKey, nil
+	}
+	var index int
+	if _, err := fmt.Sscanf(key, "backup_api_key.%d", &index); err == nil {
+		if index < 0 || index >= len(credential.BackupAPIKeys) {
+			return "", fmt.Errorf("%w: %s has no backup key %d", tools.ErrSecretNotFound, path, index)
+		}
+		return credential.BackupAPIKeys[index], nil
+	}
+	return "", fmt.Errorf("%w: unknown vault key %q", tools.ErrSecretNotFound, key)
+}
+
+// watchToolSecrets refreshes tool secrets every interval until the engine
+// is closed, so secrets rotated in the environment or in mounted files are
+// picked up.
+func (e *Engine) watchToolSecrets(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+			e.refreshToolSecrets()
+		}
+	}
+}
+
+// refreshToolSecrets resolves the secrets of every secret tool again and
+// rebuilds the tools whose secrets changed. A tool whose secrets cannot be
+// resolved keeps running with the ones it has.
+func (e *Engine) refreshToolSecrets() {
+	e.toolSecrets.refreshing.Lock()
+	defer e.toolSecrets.refreshing.Unlock()
+	
+	for agentID, secretTools := range e.toolSecrets.snapshot() {
+		for _, st := range secretTools {
+			auth, err := e.secrets.ResolveAuth(st.refs)
+			if err != nil {
+				e.logger.Warn("Failed to refresh tool secrets",
+					zap.String("cluster", st.cluster),
+					zap.String("agent", st.agent),
+					zap.String("tool", st.config.Name),
+					zap.Error(err))
+				continue
+			}
+			if *auth == *st.config.Auth {
+				continue
+			}
+			
+			if err := e.rebuildSecretTool(agentID, st, auth); err != nil {
+				e.logger.Warn("Failed to rebuild tool with rotated secrets",
+					zap.String("cluster", st.cluster),
+					zap.String("agent", st.agent),
+					zap.String("tool", st.config.Name),
+					zap.Error(err))
+				continue
+			}
+			e.logger.Info("Tool secrets rotated",
+				zap.String("cluster", st.cluster),
+				zap.String("agent", st.agent),
+				zap.String("tool", st.config.Name))
+		}
+	}
+}
+
+// rebuildSecretTool creates the tool again with auth and swaps it in for
+// the registered one, keeping its settings. The rebuilt tool must offer the
+// same tools as before; otherwise the agent has to be redeployed.
+func (e *Engine) rebuildSecretTool(agentID string, st *secretTool, auth *tools.AuthConfig) error {
+	toolCfg := *st.config
+	toolCfg.Auth = auth
+	
+	tool, err := tools.CreateTool(&toolCfg)
+	if err != nil {
+		return err
+	}
+	rebuilt := e.discoverTools(st.cluster, st.agent, tool)
+	
+	names := make([]string, 0, len(rebuilt))
+	for _, registered := range rebuilt {
+		names = append(names, registered.Name())
+	}
+	sort.Strings(names)
+	if strings.Join(names, "\x00") != strings.Join(st.names, "\x00") {
+		tool.Close()
+		return fmt.Errorf("rebuilt tool offers %v instead of %v", names, st.names)
+	}
+	
+	var replaced []tools.Tool
+	for _, registered := range rebuilt {
+		old, ok := e.toolManager.Replace(tools.Key{Scope: agentID, Name: registered.Name()}, registered)
+		if !ok {
+			// The agent was removed while the tool was being rebuilt
+			for _, t := range append(rebuilt, replaced...) {
+				t.Close()
+			}
+			return nil
+		}
+		replaced = append(replaced, old)
+	}
+	st.config = &toolCfg
+	
+	retireTools(replaced)
+	return nil
+}
+
+// refreshToolSecretsAsync refreshes tool secrets in the background, such
+// as after a credential in the vault changed.
+func (e *Engine) refreshToolSecretsAsync() {
+	go func() {
+		select {
+		case <-e.done:
+		default:
+			e.refreshToolSecrets()
+		}
+	}()
+}
\ No newline at end of file
@@ -0,0 +1,159 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"go.uber.org/zap"
+)
+
+// tracerName is the instrumentation scope of the engine's and the API's
+// spans.
+const tracerName = "github.com/goagents/goagents"
+
+// traceFlushTimeout bounds how long shutting down waits for the spans that
+// are left to be exported.
+const traceFlushTimeout = 5 * time.Second
+
+// initializeTracing sets up the export of traces when tracing is enabled.
+// Otherwise spans go to a no-op tracer, which drops them.
+func (e *Engine) initializeTracing() error {
+	tracing := e.config.Server.Tracing
+	if !tracing.Enabled {
+		e.tracer = noop.NewTracerProvider().Tracer(tracerName)
+		return nil
+	}
+	
+	var options []otlptracehttp.Option
+	switch {
+	case strings.Contains(tracing.Endpoint, "://"):
+		options = append(options, otlptracehttp.WithEndpointURL(tracing.Endpoint))
+	case tracing.Endpoint != "":
+		options = append(options, otlptracehttp.WithEndpoint(tracing.Endpoint))
+	}
+	if tracing.Insecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	if len(tracing.Headers) > 0 {
+		options = append(options, otlptracehttp.WithHeaders(tracing.Headers))
+	}
+	exporter, err := otlptracehttp.New(context.Background(), options...)
+	if err != nil {
+		return fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	
+	serviceName := tracing.ServiceName
+	if serviceName == "" {
+		serviceName = "goagents"
+	}
+	service, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return fmt.Errorf("failed to describe service: %w", err)
+	}
+	ratio := tracing.SampleRatio
+	if ratio == 0 {
+		ratio = 1
+	}
+	
+	e.tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(service),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	e.tracer = e.tracerProvider.Tracer(tracerName)
+	e.logger.Info("Tracing enabled",
+		zap.String("endpoint", tracing.Endpoint),
+		zap.Float64("sample_ratio", ratio))
+	return nil
+}
+
+// flushTraces exports the spans that are left.
+func (e *Engine) flushTraces() {
+	if e.tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+	if err := e.tracerProvider.Shutdown(ctx); err != nil {
+		e.logger.Warn("Shut down before traces were exported", zap.Error(err))
+	}
+}
+
+// Tracer returns the tracer of the engine's spans, for the API to start
+// the spans of requests under.
+func (e *Engine) Tracer() trace.Tracer {
+	return e.tracer
+}
+
+// traceContext carries the span of ctx, but not its deadline, cancellation
+// or values, to work that stands apart from it.
+func traceContext(ctx context.Context) context.Context {
+	return trace.ContextWithSpanContext(context.Background(), trace.SpanContextFromContext(ctx))
+}
+
+// startRequestSpan starts the span of a request to an agent, which the
+// request's model and tool calls are children of.
+func (e *Engine) startRequestSpan(ctx context.Context, clusterName, agentName string, req *agent.Request, stream bool) (context.Context, trace.Span) {
+	return e.tracer.Start(ctx, "invoke_agent "+agentName, trace.WithAttributes(
+		attribute.String("gen_ai.operation.name", "invoke_agent"),
+		attribute.String("gen_ai.agent.name", agentName),
+		attribute.String("goagents.cluster", clusterName),
+		attribute.String("goagents.request_id", req.ID),
+		attribute.Bool("goagents.stream", stream),
+	))
+}
+
+// startProviderSpan starts the span of a model call.
+func (e *Engine) startProviderSpan(ctx context.Context, targetAgent *agent.Agent, providerName, model string) (context.Context, trace.Span) {
+	return e.tracer.Start(ctx, "chat "+model, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("gen_ai.operation.name", "chat"),
+		attribute.String("gen_ai.system", providerName),
+		attribute.String("gen_ai.request.model", model),
+		attribute.String("gen_ai.agent.name", targetAgent.GetName()),
+		attribute.String("goagents.cluster", targetAgent.ClusterName),
+	))
+}
+
+// startToolSpan starts the span of a tool call.
+func (e *Engine) startToolSpan(ctx context.Context, toolUse providers.ToolUse) (context.Context, trace.Span) {
+	return e.tracer.Start(ctx, "execute_tool "+toolUse.Name, trace.WithAttributes(
+		attribute.String("gen_ai.operation.name", "execute_tool"),
+		attribute.String("gen_ai.tool.name", toolUse.Name),
+		attribute.String("gen_ai.tool.call.id", toolUse.ID),
+	))
+}
+
+// endProviderSpan ends the span of a model call with the tokens it used,
+// or its error.
+func endProviderSpan(span trace.Span, usage *providers.Usage, err error) {
+	if usage != nil {
+		span.SetAttributes(
+			attribute.Int("gen_ai.usage.input_tokens", usage.PromptTokens),
+			attribute.Int("gen_ai.usage.output_tokens", usage.CompletionTokens),
+		)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// endSpan ends a span, marking it failed when there is a failure.
+func endSpan(span trace.Span, failure string) {
+	if failure != "" {
+		span.SetStatus(codes.Error, failure)
+	}
+	span.End()
+}
\ No newline at end of file
@@ -0,0 +1,496 @@
+package runtime
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/guardrails"
+	"github.com/goagents/goagents/pkg/providers"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTranscriptsMaxEntries = 1000
+	defaultTranscriptsLimit      = 20
+	redactedSecret               = "[redacted secret]"
+)
+
+// secretPatterns find credentials in text: API keys in the formats of
+// common services, bearer tokens, JWTs and private keys.
+var secretPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}`),
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`),
+	regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`),
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	regexp.MustCompile(`(?i)\bbearer\s+[A-Za-z0-9._~+/-]+=*`),
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+}
+
+// secretAssignmentPattern finds values given to sensitive names in text,
+// such as password=hunter2, keeping the name.
+var secretAssignmentPattern = regexp.MustCompile(`(?i)\b((?:passw(?:or)?d|passphrase|secret|token|api[-_]?key|access[-_]?key)["']?\s*[:=]\s*["']?)[^\s"',;]+`)
+
+// Transcript records a request as the model saw it, for debugging and
+// offline evaluation.
+type Transcript struct {
+	ID        string `json:"id"`
+	RequestID string `json:"request_id"`
+	AgentID   string `json:"agent_id"`
+	Cluster   string `json:"cluster"`
+	Agent     string `json:"agent"`
+	Stream    bool   `json:"stream,omitempty"`
+	// Messages are those of the first model call: the system prompt, the
+	// conversation and any recalled memories and knowledge
+	Messages []TranscriptMessage `json:"messages"`
+	Calls    []TranscriptCall    `json:"calls"`
+	// Error is why the request failed, if it did
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+type TranscriptMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TranscriptCall is one model call and the tool calls it asked for. A call
+// retried on another provider has a call of its own.
+type TranscriptCall struct {
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	// Messages are those added since the previous call other than its
+	// completion and tool results, such as corrections of an answer
+	Messages   []TranscriptMessage  `json:"messages,omitempty"`
+	Completion string               `json:"completion,omitempty"`
+	ToolCalls  []TranscriptToolCall `json:"tool_calls,omitempty"`
+	Usage      *providers.Usage     `json:"usage,omitempty"`
+	Cached     bool                 `json:"cached,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	LatencyMs  int64                `json:"latency_ms"`
+}
+
+type TranscriptToolCall struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Args       map[string]interface{} `json:"args,omitempty"`
+	Result     interface{}            `json:"result,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	DurationMs int64                  `json:"duration_ms"`
+}
+
+type TranscriptFilter struct {
+	Since time.Time
+	// Limit caps the transcripts returned, newest first
+	Limit int
+}
+
+// transcriptLog keeps the most recent transcripts and appends every one to
+// a JSON lines file when a path is set.
+type transcriptLog struct {
+	config      config.TranscriptsConfig
+	transcripts []Transcript
+	nextID      uint64
+	file        *os.File
+	mu          sync.RWMutex
+}
+
+func newTranscriptLog(cfg config.TranscriptsConfig) (*transcriptLog, error) {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = defaultTranscriptsMaxEntries
+	}
+	
+	log := &transcriptLog{config: cfg}
+	if cfg.Path != "" {
+		if err := log.load(); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open transcripts file: %w", err)
+		}
+		log.file = file
+	}
+	
+	return log, nil
+}
+
+// load reads back the most recent transcripts of an existing file.
+func (l *transcriptLog) load() error {
+	file, err := os.Open(l.config.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open transcripts file: %w", err)
+	}
+	defer file.Close()
+	
+	// Transcripts hold whole conversations, so lines can be long
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var transcript Transcript
+		if err := json.Unmarshal(scanner.Bytes(), &transcript); err != nil {
+			return fmt.Errorf("failed to parse transcripts file: %w", err)
+		}
+		l.nextID++
+		l.append(transcript)
+	}
+	
+	return scanner.Err()
+}
+
+func (l *transcriptLog) append(transcript Transcript) {
+	l.transcripts = append(l.transcripts, transcript)
+	if excess := len(l.transcripts) - l.config.MaxEntries; excess > 0 {
+		l.transcripts = append(l.transcripts[:0:0], l.transcripts[excess:]...)
+	}
+}
+
+func (l *transcriptLog) record(transcript *Transcript) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.nextID++
+	transcript.ID = fmt.Sprintf("tr-%d", l.nextID)
+	l.append(*transcript)
+	
+	if l.file != nil {
+		line, err := json.Marshal(transcript)
+		if err != nil {
+			return err
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to persist transcript: %w", err)
+		}
+	}
+	return nil
+}
+
+// forAgent lists an agent's transcripts, newest first.
+func (l *transcriptLog) forAgent(agentID string, filter TranscriptFilter) []Transcript {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultTranscriptsLimit
+	}
+	
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	
+	matches := make([]Transcript, 0)
+	for i := len(l.transcripts) - 1; i >= 0 && len(matches) < limit; i-- {
+		transcript := l.transcripts[i]
+		if transcript.AgentID != agentID || transcript.StartedAt.Before(filter.Since) {
+			continue
+		}
+		matches = append(matches, transcript)
+	}
+	return matches
+}
+
+// find returns the latest transcript of an agent's request.
+func (l *transcriptLog) find(agentID, requestID string) (Transcript, bool) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	
+	for i := len(l.transcripts) - 1; i >= 0; i-- {
+		if l.transcripts[i].AgentID == agentID && l.transcripts[i].RequestID == requestID {
+			return l.transcripts[i], true
+		}
+	}
+	return Transcript{}, false
+}
+
+func (l *transcriptLog) close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// redactor hides what the config's redact list names in the text of a
+// transcript, or returns nil when nothing is hidden.
+func (l *transcriptLog) redactor() func(string) string {
+	secrets, pii := true, true
+	var kinds []string
+	if len(l.config.Redact) > 0 {
+		secrets, pii = false, false
+	}
+	for _, redact := range l.config.Redact {
+		switch redact {
+		case "none":
+			return nil
+		case "secrets":
+			secrets = true
+		case "pii":
+			pii, kinds = true, nil
+		default:
+			if !pii {
+				kinds = append(kinds, redact)
+			}
+		}
+	}
+	pii = pii || len(kinds) > 0
+	
+	return func(text string) string {
+		if secrets {
+			text = redactSecrets(text)
+		}
+		if pii {
+			text = guardrails.RedactPII(text, kinds)
+		}
+		return text
+	}
+}
+
+// redactSecrets replaces the credentials secretPatterns find in text.
+func redactSecrets(text string) string {
+	for _, pattern := range secretPatterns {
+		text = pattern.ReplaceAllString(text, redactedSecret)
+	}
+	return secretAssignmentPattern.ReplaceAllString(text, "${1}"+redactedSecret)
+}
+
+// transcriptCapture builds the transcript of a sampled request. Its
+// methods do nothing on a nil capture, which requests that are not
+// sampled get.
+type transcriptCapture struct {
+	transcript Transcript
+	redact     func(string) string
+	// sent counts the messages of the request already in the transcript
+	sent      int
+	callStart time.Time
+}
+
+// startTranscript starts capturing a request about to call the model with
+// providerReq, if transcripts are enabled and the request is sampled.
+func (e *Engine) startTranscript(clusterName string, targetAgent *agent.Agent, req *agent.Request, providerReq *providers.ChatRequest, stream bool) *transcriptCapture {
+	if e.transcripts == nil {
+		return nil
+	}
+	if rate := e.config.Transcripts.SampleRate; rate > 0 && rand.Float64() >= rate {
+		return nil
+	}
+	
+	capture := &transcriptCapture{
+		transcript: Transcript{
+			RequestID: req.ID,
+			AgentID:   targetAgent.ID,
+			Cluster:   clusterName,
+			Agent:     targetAgent.GetName(),
+			Stream:    stream,
+			Calls:     []TranscriptCall{},
+			StartedAt: time.Now().UTC(),
+		},
+		redact: e.transcripts.redactor(),
+	}
+	capture.transcript.Messages = capture.messages(providerReq.Messages, false)
+	capture.sent = len(providerReq.Messages)
+	return capture
+}
+
+// messages copies messages for the transcript with their text redacted.
+// Later calls leave out the completions and tool results already in it.
+func (c *transcriptCapture) messages(messages []providers.Message, later bool) []TranscriptMessage {
+	var copied []TranscriptMessage
+	for _, message := range messages {
+		if later && (message.Role == "assistant" || message.Role == "tool") {
+			continue
+		}
+		copied = append(copied, TranscriptMessage{
+			Role:    message.Role,
+			Content: c.text(message.Content),
+		})
+	}
+	return copied
+}
+
+func (c *transcriptCapture) text(text string) string {
+	if c.redact == nil {
+		return text
+	}
+	return c.redact(text)
+}
+
+// value copies a tool argument or result with the values of sensitive
+// names hidden and its text redacted.
+func (c *transcriptCapture) value(name string, value interface{}) interface{} {
+	if name != "" && sensitiveArgPattern.MatchString(name) {
+		return redactedValue
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = c.value(key, item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = c.value("", item)
+		}
+		return copied
+	case string:
+		return c.text(v)
+	case nil, bool, float64, int, int64, json.Number:
+		return v
+	}
+	
+	// Results of other types are redacted through their JSON encoding
+	data, err := json.Marshal(value)
+	if err != nil {
+		return c.text(fmt.Sprint(value))
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return c.text(string(data))
+	}
+	return c.value("", decoded)
+}
+
+// call marks the start of a model call with providerReq.
+func (c *transcriptCapture) call(providerName string, providerReq *providers.ChatRequest) {
+	if c == nil {
+		return
+	}
+	c.callStart = time.Now()
+	c.transcript.Calls = append(c.transcript.Calls, TranscriptCall{
+		Provider: providerName,
+		Model:    providerReq.Model,
+		Messages: c.messages(providerReq.Messages[c.sent:], true),
+	})
+	c.sent = len(providerReq.Messages)
+}
+
+// called records how the model call last started ended.
+func (c *transcriptCapture) called(resp *providers.ChatResponse, cached bool, err error) {
+	if c == nil || len(c.transcript.Calls) == 0 {
+		return
+	}
+	call := &c.transcript.Calls[len(c.transcript.Calls)-1]
+	call.Cached = cached
+	call.LatencyMs = time.Since(c.callStart).Milliseconds()
+	if err != nil {
+		call.Error = c.text(err.Error())
+		return
+	}
+	call.Completion = c.text(resp.Content)
+	call.Usage = resp.Usage
+}
+
+// streamed records the completion of a streamed model call.
+func (c *transcriptCapture) streamed(completion string, usage *providers.Usage, err error) {
+	if c == nil || len(c.transcript.Calls) == 0 {
+		return
+	}
+	call := &c.transcript.Calls[len(c.transcript.Calls)-1]
+	call.LatencyMs = time.Since(c.callStart).Milliseconds()
+	call.Completion = c.text(completion)
+	call.Usage = usage
+	if err != nil {
+		call.Error = c.text(err.Error())
+	}
+}
+
+// chatCaptured is chatWithCache recording the model call in the
+// transcript of the request.
+func (e *Engine) chatCaptured(ctx context.Context, capture *transcriptCapture, targetAgent *agent.Agent, providerName string, provider providers.Provider, providerReq *providers.ChatRequest) (*providers.ChatResponse, bool, error) {
+	capture.call(providerName, providerReq)
+	resp, cached, err := e.chatWithCache(ctx, targetAgent, providerName, provider, providerReq)
+	capture.called(resp, cached, err)
+	return resp, cached, err
+}
+
+// streamCaptured is streamProvider recording the start of the model call
+// in the transcript of the request, and its end if it failed to start.
+func (e *Engine) streamCaptured(ctx context.Context, capture *transcriptCapture, targetAgent *agent.Agent, providerName string, provider providers.Provider, providerReq *providers.ChatRequest) (<-chan *providers.StreamChunk, trace.Span, error) {
+	capture.call(providerName, providerReq)
+	chunks, span, err := e.streamProvider(ctx, targetAgent, providerName, provider, providerReq)
+	if err != nil {
+		capture.called(nil, false, err)
+	}
+	return chunks, span, err
+}
+
+// toolCall records a tool call the last model call asked for, which took
+// from start until now.
+func (c *transcriptCapture) toolCall(toolCall *agent.ToolUse, start time.Time) {
+	if c == nil || len(c.transcript.Calls) == 0 {
+		return
+	}
+	recorded := TranscriptToolCall{
+		ID:         toolCall.ID,
+		Name:       toolCall.Name,
+		Result:     c.value("", toolCall.Result),
+		Error:      c.text(toolCall.Error),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if toolCall.Args != nil {
+		recorded.Args = c.value("", toolCall.Args).(map[string]interface{})
+	}
+	call := &c.transcript.Calls[len(c.transcript.Calls)-1]
+	call.ToolCalls = append(call.ToolCalls, recorded)
+}
+
+// finishTranscript keeps the transcript of a request that ended, with why
+// it failed if it did, and exports it when asked to.
+func (e *Engine) finishTranscript(c *transcriptCapture, failure string) {
+	if c == nil {
+		return
+	}
+	transcript := &c.transcript
+	transcript.Error = c.text(failure)
+	transcript.DurationMs = time.Since(transcript.StartedAt).Milliseconds()
+	
+	if err := e.transcripts.record(transcript); err != nil {
+		e.logger.Warn("Failed to record transcript",
+			zap.String("agent_id", transcript.AgentID),
+			zap.String("request_id", transcript.RequestID),
+			zap.Error(err))
+	}
+	if e.config.Transcripts.Export {
+		e.publishEvent(agent.Event{
+			Type:    agent.EventRequestTranscript,
+			AgentID: transcript.AgentID,
+			Data: map[string]interface{}{
+				"cluster":    transcript.Cluster,
+				"agent":      transcript.Agent,
+				"request_id": transcript.RequestID,
+				"transcript": transcript,
+			},
+		})
+	}
+}
+
+// Transcripts lists the captured transcripts of an agent's requests,
+// newest first. It is empty when transcripts are not enabled.
+func (e *Engine) Transcripts(agentID string, filter TranscriptFilter) []Transcript {
+	if e.transcripts == nil {
+		return []Transcript{}
+	}
+	return e.transcripts.forAgent(agentID, filter)
+}
+
+// Transcript returns the captured transcript of an agent's request.
+func (e *Engine) Transcript(agentID, requestID string) (*Transcript, error) {
+	if e.transcripts != nil {
+		if transcript, ok := e.transcripts.find(agentID, requestID); ok {
+			return &transcript, nil
+		}
+	}
+	return nil, ErrTranscriptNotFound
+}
\ No newline at end of file
@@ -0,0 +1,635 @@
+package runtime
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+// maxAgentVersions bounds the versions kept of each agent; the oldest are
+// forgotten first.
+const maxAgentVersions = 50
+
+// AgentVersion is a spec an agent has been created from. Versions are
+// numbered from 1 in the order their specs were first deployed, and
+// deploying a spec again reuses its version.
+type AgentVersion struct {
+	Version   int           `json:"version"`
+	Spec      *config.Agent `json:"spec"`
+	CreatedAt time.Time     `json:"created_at"`
+	digest    string
+}
+
+// AgentRollout is a new version of an agent running alongside the current
+// one. It serves Weight percent of the agent's requests until it is
+// promoted or rolled back.
+type AgentRollout struct {
+	Version   int       `json:"version"`
+	Weight    int       `json:"weight"`
+	StartedAt time.Time `json:"started_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	candidate *agent.Agent
+}
+
+// AgentVersions is an agent's version history.
+type AgentVersions struct {
+	Cluster string `json:"cluster"`
+	Agent   string `json:"agent"`
+	// Current is the version serving the agent's requests, besides a
+	// rollout's
+	Current  int            `json:"current"`
+	Versions []AgentVersion `json:"versions"`
+	Rollout  *AgentRollout  `json:"rollout,omitempty"`
+}
+
+type agentHistory struct {
+	versions []AgentVersion
+	next     int
+	// deployed lists the versions the agent has run, the current one last;
+	// rolling back returns to the one before it
+	deployed []int
+	rollout  *AgentRollout
+}
+
+func (h *agentHistory) find(version int) *AgentVersion {
+	for i := range h.versions {
+		if h.versions[i].Version == version {
+			return &h.versions[i]
+		}
+	}
+	return nil
+}
+
+func (h *agentHistory) current() int {
+	if len(h.deployed) == 0 {
+		return 0
+	}
+	return h.deployed[len(h.deployed)-1]
+}
+
+// versionStore keeps the version history and rollout of each agent, by
+// cluster and agent name.
+type versionStore struct {
+	agents map[string]*agentHistory
+	mu     sync.Mutex
+}
+
+func newVersionStore() *versionStore {
+	return &versionStore{agents: make(map[string]*agentHistory)}
+}
+
+func specDigest(spec *config.Agent) string {
+	data, _ := json.Marshal(spec)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// record returns the version of an agent's spec, adding it to the agent's
+// history if it is new.
+func (s *versionStore) record(clusterName string, spec *config.Agent) int {
+	digest := specDigest(spec)
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	key := budgetKey(clusterName, spec.Name)
+	h, ok := s.agents[key]
+	if !ok {
+		h = &agentHistory{}
+		s.agents[key] = h
+	}
+	for _, version := range h.versions {
+		if version.digest == digest {
+			return version.Version
+		}
+	}
+	
+	h.next++
+	h.versions = append(h.versions, AgentVersion{
+		Version:   h.next,
+		Spec:      copyAgentSpec(spec),
+		CreatedAt: time.Now().UTC(),
+		digest:    digest,
+	})
+	if len(h.versions) > maxAgentVersions {
+		h.versions = h.versions[len(h.versions)-maxAgentVersions:]
+	}
+	return h.next
+}
+
+// deployed notes that an agent now runs a version. Going back to the
+// version it ran before the current one is a rollback, which forgets the
+// current one.
+func (s *versionStore) deployed(clusterName, agentName string, version int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, agentName)]
+	if !ok || h.current() == version {
+		return
+	}
+	if n := len(h.deployed); n >= 2 && h.deployed[n-2] == version {
+		h.deployed = h.deployed[:n-1]
+		return
+	}
+	h.deployed = append(h.deployed, version)
+	if len(h.deployed) > maxAgentVersions {
+		h.deployed = h.deployed[len(h.deployed)-maxAgentVersions:]
+	}
+}
+
+func (s *versionStore) history(clusterName, agentName string) *AgentVersions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	history := &AgentVersions{
+		Cluster:  clusterName,
+		Agent:    agentName,
+		Versions: make([]AgentVersion, 0),
+	}
+	h, ok := s.agents[budgetKey(clusterName, agentName)]
+	if !ok {
+		return history
+	}
+	history.Current = h.current()
+	history.Versions = append(history.Versions, h.versions...)
+	if h.rollout != nil {
+		rollout := *h.rollout
+		history.Rollout = &rollout
+	}
+	return history
+}
+
+// spec returns a copy of the spec of one of an agent's versions.
+func (s *versionStore) spec(clusterName, agentName string, version int) (*config.Agent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, agentName)]
+	if !ok {
+		return nil, false
+	}
+	found := h.find(version)
+	if found == nil {
+		return nil, false
+	}
+	return copyAgentSpec(found.Spec), true
+}
+
+// target returns the spec of the version an agent rolls back to: version,
+// or the one it ran before the current one when version is 0.
+func (s *versionStore) target(clusterName, agentName string, version int) (*config.Agent, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, agentName)]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: agent %s has no earlier version", ErrAgentVersionNotFound, agentName)
+	}
+	if version == 0 {
+		if len(h.deployed) < 2 {
+			return nil, 0, fmt.Errorf("%w: agent %s has no earlier version", ErrAgentVersionNotFound, agentName)
+		}
+		version = h.deployed[len(h.deployed)-2]
+	}
+	
+	found := h.find(version)
+	if found == nil {
+		return nil, 0, fmt.Errorf("%w: version %d of agent %s", ErrAgentVersionNotFound, version, agentName)
+	}
+	if version == h.current() {
+		return nil, 0, fmt.Errorf("%w: agent %s already runs version %d", ErrConflict, agentName, version)
+	}
+	return copyAgentSpec(found.Spec), version, nil
+}
+
+// checkRollout reports whether spec can be rolled out: the agent must not
+// be rolling out another version already, nor run spec.
+func (s *versionStore) checkRollout(clusterName string, spec *config.Agent) error {
+	digest := specDigest(spec)
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, spec.Name)]
+	if !ok {
+		return nil
+	}
+	if h.rollout != nil {
+		return fmt.Errorf("%w: agent %s is already rolling out version %d", ErrConflict, spec.Name, h.rollout.Version)
+	}
+	if current := h.find(h.current()); current != nil && current.digest == digest {
+		return fmt.Errorf("%w: agent %s already runs version %d", ErrConflict, spec.Name, current.Version)
+	}
+	return nil
+}
+
+// startRollout runs candidate alongside the agent, unless a rollout has
+// been started since checkRollout.
+func (s *versionStore) startRollout(clusterName string, candidate *agent.Agent, weight int) (*AgentRollout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h := s.agents[budgetKey(clusterName, candidate.GetName())]
+	if h.rollout != nil {
+		return nil, fmt.Errorf("%w: agent %s is already rolling out version %d", ErrConflict, candidate.GetName(), h.rollout.Version)
+	}
+	
+	now := time.Now().UTC()
+	h.rollout = &AgentRollout{
+		Version:   candidate.Version,
+		Weight:    weight,
+		StartedAt: now,
+		UpdatedAt: now,
+		candidate: candidate,
+	}
+	rollout := *h.rollout
+	return &rollout, nil
+}
+
+func (s *versionStore) shiftRollout(clusterName, agentName string, weight int) (*AgentRollout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, agentName)]
+	if !ok || h.rollout == nil {
+		return nil, fmt.Errorf("%w: agent %s in cluster %s", ErrRolloutNotFound, agentName, clusterName)
+	}
+	h.rollout.Weight = weight
+	h.rollout.UpdatedAt = time.Now().UTC()
+	rollout := *h.rollout
+	return &rollout, nil
+}
+
+// takeRollout ends an agent's rollout and returns it, or nil if there is
+// none.
+func (s *versionStore) takeRollout(clusterName, agentName string) *AgentRollout {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, agentName)]
+	if !ok || h.rollout == nil {
+		return nil
+	}
+	rollout := h.rollout
+	h.rollout = nil
+	return rollout
+}
+
+// takeRollouts ends the rollouts of a cluster's agents and returns them.
+func (s *versionStore) takeRollouts(clusterName string) []*AgentRollout {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	var rollouts []*AgentRollout
+	for key, h := range s.agents {
+		if h.rollout != nil && strings.HasPrefix(key, clusterName+"/") {
+			rollouts = append(rollouts, h.rollout)
+			h.rollout = nil
+		}
+	}
+	return rollouts
+}
+
+// route returns the agent that serves a request for running: the rollout's
+// agent for its share of requests, unless it has failed, and running for
+// the rest.
+func (s *versionStore) route(clusterName string, running *agent.Agent) *agent.Agent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, running.GetName())]
+	if !ok || h.rollout == nil || h.rollout.Weight == 0 {
+		return running
+	}
+	if rand.Intn(100) >= h.rollout.Weight || h.rollout.candidate.GetStatus() == agent.StatusFailed {
+		return running
+	}
+	return h.rollout.candidate
+}
+
+// rename moves an agent's history to its new name, renaming the specs and
+// the rollout's agent with it.
+func (s *versionStore) rename(clusterName, agentName, newName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	h, ok := s.agents[budgetKey(clusterName, agentName)]
+	if !ok {
+		return
+	}
+	delete(s.agents, budgetKey(clusterName, agentName))
+	s.agents[budgetKey(clusterName, newName)] = h
+	for i := range h.versions {
+		spec := copyAgentSpec(h.versions[i].Spec)
+		spec.Name = newName
+		h.versions[i].Spec = spec
+		h.versions[i].digest = specDigest(spec)
+	}
+	if h.rollout != nil {
+		h.rollout.candidate.SetName(newName)
+	}
+}
+
+// forget drops the histories of a cluster's agents.
+func (s *versionStore) forget(clusterName string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for key := range s.agents {
+		if strings.HasPrefix(key, clusterName+"/") {
+			delete(s.agents, key)
+		}
+	}
+}
+
+// AgentVersions returns an agent's version history and its rollout, if
+// one is in progress.
+func (e *Engine) AgentVersions(clusterName, agentName string) (*AgentVersions, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	spec := findAgentSpec(cluster.Config, agentName)
+	cluster.mu.RUnlock()
+	if spec == nil {
+		return nil, fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	return e.versions.history(clusterName, agentName), nil
+}
+
+// StartRollout deploys a new spec for an agent alongside its current one,
+// sending weight percent of the agent's requests to the new version. The
+// cluster's spec is unchanged until the rollout is promoted.
+func (e *Engine) StartRollout(clusterName, agentName string, spec *config.Agent, weight int, pre *Precondition) (*AgentRollout, error) {
+	if err := checkRolloutWeight(weight); err != nil {
+		return nil, err
+	}
+	if spec.Name == "" {
+		spec.Name = agentName
+	}
+	if spec.Name != agentName {
+		return nil, fmt.Errorf("spec is named %s, not %s", spec.Name, agentName)
+	}
+	
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	candidate, err := e.checkAgentSpec(cluster, spec, pre)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.versions.checkRollout(clusterName, candidate); err != nil {
+		return nil, err
+	}
+	
+	built, err := e.buildAgent(cluster, candidate)
+	if err != nil {
+		return nil, err
+	}
+	rollout, err := e.versions.startRollout(clusterName, built, weight)
+	if err != nil {
+		e.removeAgent(built)
+		return nil, err
+	}
+	
+	e.logger.Info("Agent rollout started",
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName),
+		zap.Int("version", rollout.Version),
+		zap.Int("weight", weight))
+	return rollout, nil
+}
+
+// ShiftRollout changes the share of an agent's requests its rollout gets.
+func (e *Engine) ShiftRollout(clusterName, agentName string, weight int) (*AgentRollout, error) {
+	if err := checkRolloutWeight(weight); err != nil {
+		return nil, err
+	}
+	if _, err := e.getCluster(clusterName); err != nil {
+		return nil, err
+	}
+	
+	rollout, err := e.versions.shiftRollout(clusterName, agentName, weight)
+	if err != nil {
+		return nil, err
+	}
+	e.logger.Info("Agent rollout shifted",
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName),
+		zap.Int("version", rollout.Version),
+		zap.Int("weight", weight))
+	return rollout, nil
+}
+
+// PromoteRollout makes an agent's rollout its current version: the new
+// spec replaces the agent's in the cluster and the new version serves all
+// of its requests.
+func (e *Engine) PromoteRollout(clusterName, agentName string, pre *Precondition) (*AgentVersions, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	err = checkPrecondition(cluster, pre)
+	version := cluster.Config.Metadata.ResourceVersion
+	cluster.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	
+	rollout := e.versions.takeRollout(clusterName, agentName)
+	if rollout == nil {
+		return nil, fmt.Errorf("%w: agent %s in cluster %s", ErrRolloutNotFound, agentName, clusterName)
+	}
+	spec, ok := e.versions.spec(clusterName, agentName, rollout.Version)
+	if ok {
+		err = e.swapAgent(cluster, spec, rollout.candidate, version)
+	} else {
+		err = fmt.Errorf("%w: version %d of agent %s", ErrAgentVersionNotFound, rollout.Version, agentName)
+	}
+	if err != nil {
+		e.retireAgent(rollout.candidate)
+		return nil, err
+	}
+	
+	e.logger.Info("Agent rollout promoted",
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName),
+		zap.Int("version", rollout.Version))
+	return e.versions.history(clusterName, agentName), nil
+}
+
+// RollbackAgent rolls an agent back. A rollout in progress is dropped, and
+// the agent keeps its current version; otherwise the agent is redeployed
+// with version, or with the version it ran before the current one when
+// version is 0.
+func (e *Engine) RollbackAgent(clusterName, agentName string, version int, pre *Precondition) (*AgentVersions, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	err = checkPrecondition(cluster, pre)
+	exists := findAgentSpec(cluster.Config, agentName) != nil
+	cluster.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, agentName, clusterName)
+	}
+	
+	if rollout := e.versions.takeRollout(clusterName, agentName); rollout != nil {
+		e.retireAgent(rollout.candidate)
+		e.logger.Info("Agent rollout rolled back",
+			zap.String("cluster", clusterName),
+			zap.String("agent", agentName),
+			zap.Int("version", rollout.Version))
+		if version == 0 {
+			return e.versions.history(clusterName, agentName), nil
+		}
+	}
+	
+	spec, version, err := e.versions.target(clusterName, agentName, version)
+	if err != nil {
+		return nil, err
+	}
+	// The policy may have been tightened since the version was deployed
+	spec, err = e.checkAgentSpec(cluster, spec, pre)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	resourceVersion := cluster.Config.Metadata.ResourceVersion
+	cluster.mu.RUnlock()
+	built, err := e.buildAgent(cluster, spec)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.swapAgent(cluster, spec, built, resourceVersion); err != nil {
+		e.retireAgent(built)
+		return nil, err
+	}
+	
+	e.logger.Info("Agent rolled back",
+		zap.String("cluster", clusterName),
+		zap.String("agent", agentName),
+		zap.Int("version", version))
+	return e.versions.history(clusterName, agentName), nil
+}
+
+func checkRolloutWeight(weight int) error {
+	if weight < 0 || weight > 100 {
+		return fmt.Errorf("weight must be between 0 and 100")
+	}
+	return nil
+}
+
+// checkAgentSpec checks a spec for one of a cluster's agents as a cluster
+// update would, and returns a copy of it to deploy.
+func (e *Engine) checkAgentSpec(cluster *Cluster, spec *config.Agent, pre *Precondition) (*config.Agent, error) {
+	cluster.mu.RLock()
+	if err := checkPrecondition(cluster, pre); err != nil {
+		cluster.mu.RUnlock()
+		return nil, err
+	}
+	candidate := *cluster.Config
+	candidate.Spec.Agents = make([]config.Agent, len(cluster.Config.Spec.Agents))
+	found := false
+	for i, existing := range cluster.Config.Spec.Agents {
+		if existing.Name == spec.Name {
+			existing = *copyAgentSpec(spec)
+			found = true
+		}
+		candidate.Spec.Agents[i] = existing
+	}
+	cluster.mu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("%w: %s in cluster %s", ErrAgentNotFound, spec.Name, cluster.Name)
+	}
+	
+	if err := e.config.Policy.CheckCluster(&candidate); err != nil {
+		return nil, err
+	}
+	_, exists, err := e.providerFor(candidate.Metadata.Namespace, spec.Provider)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("provider %s not available", spec.Provider)
+	}
+	return copyAgentSpec(spec), nil
+}
+
+// swapAgent makes built, created from spec, the agent that serves spec's
+// name in the cluster, replacing the agent's spec with it, and removes the
+// agent it replaces. It fails with ErrConflict if the cluster has changed
+// since resourceVersion.
+func (e *Engine) swapAgent(cluster *Cluster, spec *config.Agent, built *agent.Agent, resourceVersion string) error {
+	cluster.mu.Lock()
+	current := findAgentSpec(cluster.Config, spec.Name)
+	if current == nil || cluster.Config.Metadata.ResourceVersion != resourceVersion {
+		cluster.mu.Unlock()
+		return fmt.Errorf("%w: cluster %s changed while the agent was created", ErrConflict, cluster.Name)
+	}
+	*current = *spec
+	old := cluster.Agents[spec.Name]
+	cluster.Agents[spec.Name] = built
+	cluster.UpdatedAt = time.Now()
+	e.bumpResourceVersion(cluster)
+	e.saveCluster(cluster)
+	cluster.mu.Unlock()
+	
+	e.versions.deployed(cluster.Name, spec.Name, built.Version)
+	e.instances.configure(cluster.Name, built)
+	if spec.Health != nil {
+		e.watchHealth(built, spec.Health)
+	}
+	if old != nil {
+		e.retireAgent(old)
+	}
+	return nil
+}
+
+// recordVersion notes a change to a running agent's spec made in place,
+// without recreating the agent.
+func (e *Engine) recordVersion(clusterName string, spec *config.Agent, running *agent.Agent) {
+	version := e.versions.record(clusterName, spec)
+	e.versions.deployed(clusterName, spec.Name, version)
+	if running != nil {
+		running.Version = version
+	}
+}
+
+// retireAgent stops an agent and removes it.
+func (e *Engine) retireAgent(a *agent.Agent) {
+	if err := e.agentManager.StopAgent(a.ID); err != nil {
+		e.logger.Warn("Failed to stop agent",
+			zap.String("agent", a.GetName()),
+			zap.Error(err))
+	}
+	e.removeAgent(a)
+}
+
+// abortRollouts drops the rollouts of a cluster's agents.
+func (e *Engine) abortRollouts(clusterName string) {
+	for _, rollout := range e.versions.takeRollouts(clusterName) {
+		e.retireAgent(rollout.candidate)
+	}
+}
\ No newline at end of file
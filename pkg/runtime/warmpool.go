@@ -0,0 +1,106 @@
+package runtime
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/tools"
+	"go.uber.org/zap"
+)
+
+// settleWarm picks the agents of a cluster scaled to zero that are kept
+// warm: the n that had a request most recently. It returns the names of
+// the agents that became warm, whose tools are connected ahead of their
+// next request, and of those that went cold, whose tool connections are
+// released. Agents with instances are neither.
+func (p *instancePools) settleWarm(clusterName string, n int) (warm, cold []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	var idle []*instancePool
+	for key, pool := range p.pools {
+		if !strings.HasPrefix(key, clusterName+"/") {
+			continue
+		}
+		// Agents drained to zero may still finish requests with their tools
+		if pool.desired > 0 || pool.active() > 0 {
+			pool.warm, pool.cold = false, false
+			continue
+		}
+		idle = append(idle, pool)
+	}
+	sort.Slice(idle, func(i, j int) bool {
+		return idle[i].lastRequest.After(idle[j].lastRequest)
+	})
+	
+	for i, pool := range idle {
+		if i < n {
+			if !pool.warm {
+				pool.warm, pool.cold = true, false
+				warm = append(warm, pool.name)
+			}
+			continue
+		}
+		pool.warm = false
+		if !pool.cold {
+			pool.cold = true
+			cold = append(cold, pool.name)
+		}
+	}
+	return warm, cold
+}
+
+// settleWarmPool keeps the warm pool of a cluster's resource policy
+// connected, so the first request to one of its agents after it was
+// scaled to zero does not wait for MCP handshakes, and releases the tool
+// connections of the other agents scaled to zero.
+func (e *Engine) settleWarmPool(cluster *Cluster, agents []*agent.Agent, size int) {
+	warm, cold := e.instances.settleWarm(cluster.Name, size)
+	if len(warm) == 0 && len(cold) == 0 {
+		return
+	}
+	
+	byName := make(map[string]*agent.Agent, len(agents))
+	for _, a := range agents {
+		byName[a.GetName()] = a
+	}
+	for _, name := range cold {
+		if a, ok := byName[name]; ok {
+			e.disconnectTools(a)
+		}
+	}
+	for _, name := range warm {
+		if a, ok := byName[name]; ok {
+			go e.connectTools(a)
+		}
+	}
+}
+
+// connectTools opens the connections of an agent's tools that hold one.
+func (e *Engine) connectTools(a *agent.Agent) {
+	for _, tool := range e.toolManager.ListTools(a.ID) {
+		connector, ok := tool.(tools.Connector)
+		if !ok {
+			continue
+		}
+		if err := connector.Connect(context.Background()); err != nil {
+			e.logger.Warn("Failed to warm up tool",
+				zap.String("cluster", a.ClusterName),
+				zap.String("agent", a.GetName()),
+				zap.String("tool", tool.Name()),
+				zap.Error(err))
+		}
+	}
+}
+
+// disconnectTools releases the connections of an agent's tools until they
+// are called again.
+func (e *Engine) disconnectTools(a *agent.Agent) {
+	for _, tool := range e.toolManager.ListTools(a.ID) {
+		if connector, ok := tool.(tools.Connector); ok {
+			connector.Disconnect()
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,367 @@
+package runtime
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/events"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultMaxDeadLetters   = 1000
+	defaultDeadLettersLimit = 100
+)
+
+// DeadLetter is an event a webhook could not be given, after every attempt.
+type DeadLetter struct {
+	ID       string      `json:"id"`
+	Webhook  string      `json:"webhook"`
+	Event    agent.Event `json:"event"`
+	Attempts int         `json:"attempts"`
+	Error    string      `json:"error"`
+	FailedAt time.Time   `json:"failed_at"`
+}
+
+type DeadLetterFilter struct {
+	Webhook string
+	// Limit caps the dead letters returned, newest first
+	Limit int
+}
+
+// Webhook describes a webhook and how its deliveries went. Its secret and
+// headers are not reported.
+type Webhook struct {
+	Name       string            `json:"name"`
+	URL        string            `json:"url"`
+	Events     []string          `json:"events"`
+	Deliveries events.SinkStatus `json:"deliveries"`
+}
+
+// webhookSet holds the webhooks events are posted to: those configured at
+// first, then changed through the API.
+type webhookSet struct {
+	endpoints []config.WebhookConfig
+	mu        sync.RWMutex
+}
+
+func (s *webhookSet) list() []config.WebhookConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	return append([]config.WebhookConfig(nil), s.endpoints...)
+}
+
+func (s *webhookSet) get(name string) (config.WebhookConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	for _, webhook := range s.endpoints {
+		if webhook.Name == name {
+			return webhook, true
+		}
+	}
+	return config.WebhookConfig{}, false
+}
+
+// deadLetterLog keeps the most recent dead letters and appends every one to
+// a JSON lines file when a path is set.
+type deadLetterLog struct {
+	config  config.WebhooksConfig
+	letters []DeadLetter
+	nextID  uint64
+	file    *os.File
+	mu      sync.RWMutex
+}
+
+func newDeadLetterLog(cfg config.WebhooksConfig) (*deadLetterLog, error) {
+	if cfg.MaxDeadLetters <= 0 {
+		cfg.MaxDeadLetters = defaultMaxDeadLetters
+	}
+	
+	log := &deadLetterLog{config: cfg}
+	if cfg.DeadLetterPath != "" {
+		if err := log.load(); err != nil {
+			return nil, err
+		}
+		file, err := os.OpenFile(cfg.DeadLetterPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+		}
+		log.file = file
+	}
+	
+	return log, nil
+}
+
+// load reads back the most recent dead letters of an existing file.
+func (l *deadLetterLog) load() error {
+	file, err := os.Open(l.config.DeadLetterPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+	defer file.Close()
+	
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var letter DeadLetter
+		if err := json.Unmarshal(scanner.Bytes(), &letter); err != nil {
+			return fmt.Errorf("failed to parse dead letter file: %w", err)
+		}
+		l.nextID++
+		l.append(letter)
+	}
+	
+	return scanner.Err()
+}
+
+func (l *deadLetterLog) append(letter DeadLetter) {
+	l.letters = append(l.letters, letter)
+	if excess := len(l.letters) - l.config.MaxDeadLetters; excess > 0 {
+		l.letters = append(l.letters[:0:0], l.letters[excess:]...)
+	}
+}
+
+func (l *deadLetterLog) record(letter DeadLetter) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	l.nextID++
+	letter.ID = fmt.Sprintf("dl-%d", l.nextID)
+	l.append(letter)
+	
+	if l.file != nil {
+		line, err := json.Marshal(&letter)
+		if err != nil {
+			return err
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to persist dead letter: %w", err)
+		}
+	}
+	return nil
+}
+
+// list returns dead letters, newest first.
+func (l *deadLetterLog) list(filter DeadLetterFilter) []DeadLetter {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultDeadLettersLimit
+	}
+	
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	
+	matches := make([]DeadLetter, 0)
+	for i := len(l.letters) - 1; i >= 0 && len(matches) < limit; i-- {
+		letter := l.letters[i]
+		if filter.Webhook != "" && letter.Webhook != filter.Webhook {
+			continue
+		}
+		matches = append(matches, letter)
+	}
+	return matches
+}
+
+func (l *deadLetterLog) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// initializeWebhooks adds the configured webhooks to the event bus.
+func (e *Engine) initializeWebhooks() error {
+	deadLetters, err := newDeadLetterLog(e.config.Webhooks)
+	if err != nil {
+		return err
+	}
+	e.deadLetters = deadLetters
+	e.webhooks = &webhookSet{}
+	
+	for i := range e.config.Webhooks.Endpoints {
+		webhookConfig := &e.config.Webhooks.Endpoints[i]
+		if err := e.addWebhook(webhookConfig); err != nil {
+			return fmt.Errorf("webhook %s: %w", webhookConfig.Name, err)
+		}
+		e.webhooks.endpoints = append(e.webhooks.endpoints, *webhookConfig)
+		e.logger.Info("Added webhook", zap.String("name", webhookConfig.Name), zap.String("url", webhookConfig.URL))
+	}
+	return nil
+}
+
+// addWebhook starts posting events to a webhook. Each webhook is fed one
+// event at a time, so its retries hold up only its own events.
+func (e *Engine) addWebhook(webhookConfig *config.WebhookConfig) error {
+	name := webhookConfig.Name
+	sink, err := events.NewSignedWebhookSink(&events.SignedWebhookConfig{
+		URL:          webhookConfig.URL,
+		Secret:       webhookConfig.Secret,
+		Headers:      webhookConfig.Headers,
+		MaxRetries:   webhookConfig.MaxRetries,
+		RetryBackoff: webhookConfig.RetryBackoff,
+		Timeout:      webhookConfig.Timeout,
+		DeadLetter: func(event agent.Event, attempts int, err error) {
+			e.deadLetter(name, event, attempts, err)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	e.events.AddSink(sink, events.SinkOptions{
+		Name:      name,
+		Type:      "webhook",
+		Events:    webhookEvents(webhookConfig),
+		BatchSize: 1,
+		Timeout:   sink.MaxDeliveryTime(),
+	})
+	return nil
+}
+
+// SetWebhook adds a webhook or replaces the webhook of its name, and
+// reports whether it was added. Events already queued for a replaced
+// webhook are still posted to it as it was.
+func (e *Engine) SetWebhook(webhookConfig *config.WebhookConfig) (bool, error) {
+	if err := config.ValidateWebhook(webhookConfig); err != nil {
+		return false, err
+	}
+	for i := range e.config.Events.Sinks {
+		if e.config.Events.Sinks[i].SinkName() == webhookConfig.Name {
+			return false, fmt.Errorf("name %s is already used by an event sink", webhookConfig.Name)
+		}
+	}
+	
+	e.webhooks.mu.Lock()
+	defer e.webhooks.mu.Unlock()
+	
+	index := -1
+	for i := range e.webhooks.endpoints {
+		if e.webhooks.endpoints[i].Name == webhookConfig.Name {
+			index = i
+			break
+		}
+	}
+	if index >= 0 {
+		e.events.RemoveSink(webhookConfig.Name)
+	}
+	if err := e.addWebhook(webhookConfig); err != nil {
+		return false, err
+	}
+	if index >= 0 {
+		e.webhooks.endpoints[index] = *webhookConfig
+	} else {
+		e.webhooks.endpoints = append(e.webhooks.endpoints, *webhookConfig)
+	}
+	
+	e.logger.Info("Webhook set",
+		zap.String("name", webhookConfig.Name),
+		zap.String("url", webhookConfig.URL),
+		zap.Bool("created", index < 0))
+	return index < 0, nil
+}
+
+// RemoveWebhook stops posting events to a webhook. Its dead letters are
+// kept.
+func (e *Engine) RemoveWebhook(name string) error {
+	e.webhooks.mu.Lock()
+	defer e.webhooks.mu.Unlock()
+	
+	for i := range e.webhooks.endpoints {
+		if e.webhooks.endpoints[i].Name == name {
+			e.events.RemoveSink(name)
+			e.webhooks.endpoints = append(e.webhooks.endpoints[:i], e.webhooks.endpoints[i+1:]...)
+			e.logger.Info("Webhook removed", zap.String("name", name))
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrWebhookNotFound, name)
+}
+
+func webhookEvents(webhookConfig *config.WebhookConfig) []string {
+	if len(webhookConfig.Events) > 0 {
+		return webhookConfig.Events
+	}
+	return config.DefaultWebhookEvents
+}
+
+// deadLetter records an event a webhook could not be given.
+func (e *Engine) deadLetter(webhook string, event agent.Event, attempts int, err error) {
+	e.logger.Warn("Webhook delivery failed, event dead-lettered",
+		zap.String("webhook", webhook),
+		zap.String("event", event.ID),
+		zap.String("type", string(event.Type)),
+		zap.Int("attempts", attempts),
+		zap.Error(err))
+	
+	letter := DeadLetter{
+		Webhook:  webhook,
+		Event:    event,
+		Attempts: attempts,
+		Error:    err.Error(),
+		FailedAt: time.Now().UTC(),
+	}
+	if recordErr := e.deadLetters.record(letter); recordErr != nil {
+		e.logger.Warn("Failed to record dead letter",
+			zap.String("webhook", webhook),
+			zap.Error(recordErr))
+	}
+}
+
+// Webhooks lists the webhooks with how their deliveries went.
+func (e *Engine) Webhooks() []Webhook {
+	statuses := make(map[string]events.SinkStatus)
+	for _, status := range e.events.Status() {
+		statuses[status.Name] = status
+	}
+	
+	endpoints := e.webhooks.list()
+	webhooks := make([]Webhook, 0, len(endpoints))
+	for i := range endpoints {
+		webhooks = append(webhooks, Webhook{
+			Name:       endpoints[i].Name,
+			URL:        endpoints[i].URL,
+			Events:     webhookEvents(&endpoints[i]),
+			Deliveries: statuses[endpoints[i].Name],
+		})
+	}
+	return webhooks
+}
+
+// Webhook describes a webhook with how its deliveries went.
+func (e *Engine) Webhook(name string) (*Webhook, error) {
+	webhookConfig, ok := e.webhooks.get(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrWebhookNotFound, name)
+	}
+	webhook := &Webhook{
+		Name:   webhookConfig.Name,
+		URL:    webhookConfig.URL,
+		Events: webhookEvents(&webhookConfig),
+	}
+	for _, status := range e.events.Status() {
+		if status.Name == name {
+			webhook.Deliveries = status
+		}
+	}
+	return webhook, nil
+}
+
+// DeadLetters lists the events webhooks could not be given, newest first.
+func (e *Engine) DeadLetters(filter DeadLetterFilter) []DeadLetter {
+	return e.deadLetters.list(filter)
+}
\ No newline at end of file
@@ -0,0 +1,636 @@
+package runtime
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxWorkflowRuns bounds the runs kept for status queries; the oldest
+	// finished runs are dropped first
+	maxWorkflowRuns = 1000
+	// maxWorkflowFanOut bounds the items of a for_each step run at once
+	maxWorkflowFanOut           = 8
+	defaultWorkflowRetryBackoff = time.Second
+	defaultWorkflowInput        = "{{json .input}}"
+)
+
+type WorkflowStatus string
+
+const (
+	WorkflowStatusPending   WorkflowStatus = "pending"
+	WorkflowStatusRunning   WorkflowStatus = "running"
+	WorkflowStatusSucceeded WorkflowStatus = "succeeded"
+	WorkflowStatusFailed    WorkflowStatus = "failed"
+	// WorkflowStatusSkipped is a step whose when condition was false
+	WorkflowStatusSkipped WorkflowStatus = "skipped"
+	// WorkflowStatusCancelled is a step that did not run because a step it
+	// depends on failed or the run ran out of time
+	WorkflowStatusCancelled WorkflowStatus = "cancelled"
+)
+
+// WorkflowRun is a run of one of a cluster's workflows.
+type WorkflowRun struct {
+	ID         string                 `json:"id"`
+	Cluster    string                 `json:"cluster"`
+	Workflow   string                 `json:"workflow"`
+	Status     WorkflowStatus         `json:"status"`
+	Input      map[string]interface{} `json:"input,omitempty"`
+	Output     interface{}            `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Steps      []WorkflowStepRun      `json:"steps"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+	// done is closed when the run finishes
+	done chan struct{}
+}
+
+type WorkflowStepRun struct {
+	Name     string         `json:"name"`
+	Status   WorkflowStatus `json:"status"`
+	Output   interface{}    `json:"output,omitempty"`
+	Error    string         `json:"error,omitempty"`
+	Attempts int            `json:"attempts,omitempty"`
+	// Items is the number of items a for_each step ran for
+	Items      int        `json:"items,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (r *WorkflowRun) finished() bool {
+	return r.Status == WorkflowStatusSucceeded || r.Status == WorkflowStatusFailed
+}
+
+// workflowRuns keeps recent workflow runs. Runs are updated by the steps
+// running them and read by status queries, so both go through the store's
+// lock, and readers get copies.
+type workflowRuns struct {
+	runs   map[string]*WorkflowRun
+	order  []string
+	nextID uint64
+	mu     sync.Mutex
+}
+
+func newWorkflowRuns() *workflowRuns {
+	return &workflowRuns{
+		runs: make(map[string]*WorkflowRun),
+	}
+}
+
+func (s *workflowRuns) add(run *WorkflowRun) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.nextID++
+	run.ID = fmt.Sprintf("run-%d-%d", time.Now().Unix(), s.nextID)
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	
+	for i := 0; len(s.runs) > maxWorkflowRuns && i < len(s.order); {
+		if oldest := s.runs[s.order[i]]; oldest.finished() {
+			delete(s.runs, s.order[i])
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			continue
+		}
+		i++
+	}
+}
+
+func (s *workflowRuns) update(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn()
+}
+
+func (s *workflowRuns) get(id string) (*WorkflowRun, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	run, exists := s.runs[id]
+	if !exists {
+		return nil, false
+	}
+	return run.copy(), true
+}
+
+// list returns a workflow's runs, newest first.
+func (s *workflowRuns) list(cluster, workflow string) []*WorkflowRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	runs := make([]*WorkflowRun, 0)
+	for i := len(s.order) - 1; i >= 0; i-- {
+		run := s.runs[s.order[i]]
+		if run.Cluster == cluster && run.Workflow == workflow {
+			runs = append(runs, run.copy())
+		}
+	}
+	return runs
+}
+
+func (r *WorkflowRun) copy() *WorkflowRun {
+	c := *r
+	c.Steps = append([]WorkflowStepRun(nil), r.Steps...)
+	return &c
+}
+
+// templateData returns what workflow templates see: the run's input and
+// the status, output and error of each step by name.
+func (s *workflowRuns) templateData(run *WorkflowRun) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	steps := make(map[string]interface{}, len(run.Steps))
+	for _, step := range run.Steps {
+		steps[step.Name] = map[string]interface{}{
+			"status": string(step.Status),
+			"output": step.Output,
+			"error":  step.Error,
+		}
+	}
+	return map[string]interface{}{
+		"input": run.Input,
+		"steps": steps,
+	}
+}
+
+// Workflows returns the workflows declared by a cluster.
+func (e *Engine) Workflows(clusterName string) ([]config.Workflow, error) {
+	cluster, err := e.getCluster(clusterName)
+	if err != nil {
+		return nil, err
+	}
+	
+	cluster.mu.RLock()
+	defer cluster.mu.RUnlock()
+	return append([]config.Workflow{}, cluster.Config.Spec.Workflows...), nil
+}
+
+func (e *Engine) findWorkflow(clusterName, workflowName string) (config.Workflow, error) {
+	workflows, err := e.Workflows(clusterName)
+	if err != nil {
+		return config.Workflow{}, err
+	}
+	for _, workflow := range workflows {
+		if workflow.Name == workflowName {
+			// Steps are copied so that renames in the spec do not race
+			// with the run
+			workflow.Steps = append([]config.WorkflowStep(nil), workflow.Steps...)
+			return workflow, nil
+		}
+	}
+	return config.Workflow{}, fmt.Errorf("%w: %s in cluster %s", ErrWorkflowNotFound, workflowName, clusterName)
+}
+
+// RunWorkflow starts a run of a cluster's workflow and returns it while its
+// steps run in the background.
+func (e *Engine) RunWorkflow(clusterName, workflowName string, input map[string]interface{}) (*WorkflowRun, error) {
+	return e.startWorkflow(context.Background(), clusterName, workflowName, input)
+}
+
+// startWorkflow is RunWorkflow under a parent context; the run is
+// cancelled when it is done.
+func (e *Engine) startWorkflow(parent context.Context, clusterName, workflowName string, input map[string]interface{}) (*WorkflowRun, error) {
+	workflow, err := e.findWorkflow(clusterName, workflowName)
+	if err != nil {
+		return nil, err
+	}
+	
+	run := &WorkflowRun{
+		Cluster:   clusterName,
+		Workflow:  workflowName,
+		Status:    WorkflowStatusRunning,
+		Input:     input,
+		Steps:     make([]WorkflowStepRun, len(workflow.Steps)),
+		StartedAt: time.Now(),
+		done:      make(chan struct{}),
+	}
+	for i, step := range workflow.Steps {
+		run.Steps[i] = WorkflowStepRun{Name: step.Name, Status: WorkflowStatusPending}
+	}
+	e.workflowRuns.add(run)
+	
+	e.logger.Info("Workflow run started",
+		zap.String("cluster", clusterName),
+		zap.String("workflow", workflowName),
+		zap.String("run", run.ID))
+	
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if workflow.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, workflow.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	go func() {
+		defer cancel()
+		e.executeWorkflow(ctx, &workflow, run)
+	}()
+	go func() {
+		select {
+		case <-e.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	
+	copied, _ := e.workflowRuns.get(run.ID)
+	return copied, nil
+}
+
+// WorkflowRun returns a run of a cluster's workflow by ID.
+func (e *Engine) WorkflowRun(clusterName, workflowName, id string) (*WorkflowRun, error) {
+	run, exists := e.workflowRuns.get(id)
+	if !exists || run.Cluster != clusterName || run.Workflow != workflowName {
+		return nil, fmt.Errorf("%w: %s", ErrWorkflowRunNotFound, id)
+	}
+	return run, nil
+}
+
+// WaitWorkflowRun waits for a run to finish, or for ctx to be done, and
+// returns the run as it is then.
+func (e *Engine) WaitWorkflowRun(ctx context.Context, run *WorkflowRun) (*WorkflowRun, error) {
+	select {
+	case <-run.done:
+	case <-ctx.Done():
+	}
+	return e.WorkflowRun(run.Cluster, run.Workflow, run.ID)
+}
+
+// WorkflowRuns returns the kept runs of a workflow, newest first.
+func (e *Engine) WorkflowRuns(clusterName, workflowName string) ([]*WorkflowRun, error) {
+	if _, err := e.findWorkflow(clusterName, workflowName); err != nil {
+		return nil, err
+	}
+	return e.workflowRuns.list(clusterName, workflowName), nil
+}
+
+// executeWorkflow runs each step once the steps it depends on have
+// finished, then sets the run's status and output.
+func (e *Engine) executeWorkflow(ctx context.Context, workflow *config.Workflow, run *WorkflowRun) {
+	defer close(run.done)
+	
+	done := make(map[string]chan struct{}, len(workflow.Steps))
+	for _, step := range workflow.Steps {
+		done[step.Name] = make(chan struct{})
+	}
+	
+	var wg sync.WaitGroup
+	for i := range workflow.Steps {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			step := &workflow.Steps[i]
+			defer close(done[step.Name])
+			for _, dep := range step.DependsOn {
+				<-done[dep]
+			}
+			e.runWorkflowStep(ctx, run, i, step)
+		}(i)
+	}
+	wg.Wait()
+	
+	data := e.workflowRuns.templateData(run)
+	output, outputErr := workflowOutput(workflow, run, data)
+	
+	var status WorkflowStatus
+	e.workflowRuns.update(func() {
+		now := time.Now()
+		run.FinishedAt = &now
+		run.Status = WorkflowStatusSucceeded
+		for _, step := range run.Steps {
+			if step.Status == WorkflowStatusFailed || step.Status == WorkflowStatusCancelled {
+				run.Status = WorkflowStatusFailed
+				if run.Error == "" {
+					run.Error = fmt.Sprintf("step %s: %s", step.Name, step.Error)
+				}
+			}
+		}
+		if run.Status == WorkflowStatusSucceeded {
+			run.Output = output
+			if outputErr != nil {
+				run.Status = WorkflowStatusFailed
+				run.Error = outputErr.Error()
+			}
+		}
+		status = run.Status
+	})
+	
+	e.logger.Info("Workflow run finished",
+		zap.String("cluster", run.Cluster),
+		zap.String("workflow", run.Workflow),
+		zap.String("run", run.ID),
+		zap.String("status", string(status)))
+}
+
+// workflowOutput renders the workflow's output template or, without one,
+// collects the outputs of the steps no other step depends on.
+func workflowOutput(workflow *config.Workflow, run *WorkflowRun, data map[string]interface{}) (interface{}, error) {
+	if workflow.Output != "" {
+		output, err := renderWorkflowTemplate("output", workflow.Output, data)
+		if err != nil {
+			return nil, fmt.Errorf("output: %w", err)
+		}
+		return output, nil
+	}
+	
+	dependedOn := make(map[string]bool)
+	for _, step := range workflow.Steps {
+		for _, dep := range step.DependsOn {
+			dependedOn[dep] = true
+		}
+	}
+	steps := data["steps"].(map[string]interface{})
+	outputs := make(map[string]interface{})
+	for _, step := range workflow.Steps {
+		if dependedOn[step.Name] {
+			continue
+		}
+		outputs[step.Name] = steps[step.Name].(map[string]interface{})["output"]
+	}
+	if len(outputs) == 1 {
+		for _, output := range outputs {
+			return output, nil
+		}
+	}
+	return outputs, nil
+}
+
+func (e *Engine) runWorkflowStep(ctx context.Context, run *WorkflowRun, index int, step *config.WorkflowStep) {
+	finish := func(status WorkflowStatus, output interface{}, attempts int, err error) {
+		e.workflowRuns.update(func() {
+			now := time.Now()
+			stepRun := &run.Steps[index]
+			stepRun.Status = status
+			stepRun.Output = output
+			stepRun.Attempts += attempts
+			stepRun.FinishedAt = &now
+			if err != nil {
+				stepRun.Error = err.Error()
+			}
+		})
+	}
+	
+	data := e.workflowRuns.templateData(run)
+	steps := data["steps"].(map[string]interface{})
+	for _, dep := range step.DependsOn {
+		status := steps[dep].(map[string]interface{})["status"]
+		if status == string(WorkflowStatusFailed) || status == string(WorkflowStatusCancelled) {
+			finish(WorkflowStatusCancelled, nil, 0, fmt.Errorf("step %s did not succeed", dep))
+			return
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		finish(WorkflowStatusCancelled, nil, 0, err)
+		return
+	}
+	
+	e.workflowRuns.update(func() {
+		now := time.Now()
+		run.Steps[index].Status = WorkflowStatusRunning
+		run.Steps[index].StartedAt = &now
+	})
+	
+	if step.When != "" {
+		condition, err := renderWorkflowTemplate("when", step.When, data)
+		if err != nil {
+			finish(WorkflowStatusFailed, nil, 0, fmt.Errorf("when: %w", err))
+			return
+		}
+		if !workflowConditionTrue(condition) {
+			finish(WorkflowStatusSkipped, nil, 0, nil)
+			return
+		}
+	}
+	
+	if step.ForEach == "" {
+		output, attempts, err := e.attemptWorkflowStep(ctx, run, step, data, fmt.Sprintf("%s-%s", run.ID, step.Name))
+		if err != nil {
+			finish(WorkflowStatusFailed, nil, attempts, err)
+			return
+		}
+		finish(WorkflowStatusSucceeded, output, attempts, nil)
+		return
+	}
+	
+	items, err := workflowItems(step.ForEach, data)
+	if err != nil {
+		finish(WorkflowStatusFailed, nil, 0, err)
+		return
+	}
+	e.workflowRuns.update(func() {
+		run.Steps[index].Items = len(items)
+	})
+	
+	// Items run in parallel, a few at a time, and every item runs even when
+	// one fails so the step's attempts are complete
+	outputs := make([]interface{}, len(items))
+	errs := make([]error, len(items))
+	attempts := make([]int, len(items))
+	slots := make(chan struct{}, maxWorkflowFanOut)
+	var wg sync.WaitGroup
+	for i, item := range items {
+		wg.Add(1)
+		go func(i int, item interface{}) {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			
+			itemData := make(map[string]interface{}, len(data)+2)
+			for key, value := range data {
+				itemData[key] = value
+			}
+			itemData["item"] = item
+			itemData["index"] = i
+			outputs[i], attempts[i], errs[i] = e.attemptWorkflowStep(ctx, run, step, itemData, fmt.Sprintf("%s-%s-%d", run.ID, step.Name, i))
+		}(i, item)
+	}
+	wg.Wait()
+	
+	total := 0
+	for _, n := range attempts {
+		total += n
+	}
+	for i, err := range errs {
+		if err != nil {
+			finish(WorkflowStatusFailed, nil, total, fmt.Errorf("item %d: %w", i, err))
+			return
+		}
+	}
+	finish(WorkflowStatusSucceeded, outputs, total, nil)
+}
+
+// attemptWorkflowStep calls the step until it succeeds or its attempts run
+// out, and returns the attempts made.
+func (e *Engine) attemptWorkflowStep(ctx context.Context, run *WorkflowRun, step *config.WorkflowStep, data map[string]interface{}, requestID string) (interface{}, int, error) {
+	maxAttempts, backoff := 1, defaultWorkflowRetryBackoff
+	if step.Retry != nil {
+		maxAttempts = step.Retry.MaxAttempts
+		if step.Retry.Backoff > 0 {
+			backoff = step.Retry.Backoff
+		}
+	}
+	
+	for attempt := 1; ; attempt++ {
+		output, err := e.callWorkflowStep(ctx, run, step, data, requestID)
+		if err == nil || attempt >= maxAttempts {
+			return output, attempt, err
+		}
+		
+		e.logger.Debug("Retrying workflow step",
+			zap.String("run", run.ID),
+			zap.String("step", step.Name),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, attempt, ctx.Err()
+		}
+		backoff *= 2
+	}
+}
+
+// callWorkflowStep asks the step's agent, or calls its tool, once.
+func (e *Engine) callWorkflowStep(ctx context.Context, run *WorkflowRun, step *config.WorkflowStep, data map[string]interface{}, requestID string) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	targetAgent, _, err := e.resolveAgent(run.Cluster, step.Agent)
+	if err != nil {
+		return nil, err
+	}
+	
+	if step.Tool != "" {
+		args, err := renderWorkflowArgs(step.Args, data)
+		if err != nil {
+			return nil, err
+		}
+		result, err := e.ExecuteTool(ctx, targetAgent.ID, &ToolCall{
+			Tool:           step.Tool,
+			Args:           args.(map[string]interface{}),
+			ConversationID: run.ID,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if result.Error != "" {
+			return nil, errors.New(result.Error)
+		}
+		return result.Data, nil
+	}
+	
+	input := step.Input
+	if input == "" {
+		input = defaultWorkflowInput
+	}
+	message, err := renderWorkflowTemplate("input", input, data)
+	if err != nil {
+		return nil, fmt.Errorf("input: %w", err)
+	}
+	req := &agent.Request{
+		ID:       requestID,
+		Messages: []agent.Message{{Role: "user", Content: message}},
+		Context: map[string]interface{}{
+			"workflow":     run.Workflow,
+			"workflow_run": run.ID,
+		},
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.Timeout = time.Until(deadline)
+	}
+	
+	resp, err := e.ProcessRequest(run.Cluster, step.Agent, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, errors.New(resp.Error)
+	}
+	// Structured output is decoded so later steps can use its fields
+	if resp.Output != nil {
+		var output interface{}
+		if err := json.Unmarshal(resp.Output, &output); err == nil {
+			return output, nil
+		}
+	}
+	return resp.Content, nil
+}
+
+func renderWorkflowTemplate(name, text string, data map[string]interface{}) (string, error) {
+	tmpl, err := config.ParseWorkflowTemplate(name, text)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, data); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// renderWorkflowArgs renders the strings in a step's args.
+func renderWorkflowArgs(value interface{}, data map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		rendered, err := renderWorkflowTemplate("args", v, data)
+		if err != nil {
+			return nil, fmt.Errorf("args: %w", err)
+		}
+		return rendered, nil
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			var err error
+			if rendered[key], err = renderWorkflowArgs(item, data); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if rendered[i], err = renderWorkflowArgs(item, data); err != nil {
+				return nil, err
+			}
+		}
+		return rendered, nil
+	case nil:
+		return map[string]interface{}{}, nil
+	}
+	return value, nil
+}
+
+// workflowItems renders a for_each template and decodes the JSON array it
+// renders.
+func workflowItems(forEach string, data map[string]interface{}) ([]interface{}, error) {
+	rendered, err := renderWorkflowTemplate("for_each", forEach, data)
+	if err != nil {
+		return nil, fmt.Errorf("for_each: %w", err)
+	}
+	var items []interface{}
+	if err := json.Unmarshal([]byte(rendered), &items); err != nil {
+		return nil, fmt.Errorf("for_each must render a JSON array: %w", err)
+	}
+	return items, nil
+}
+
+// workflowConditionTrue reports whether a rendered when condition holds.
+// Missing values render as "<no value>", which is false.
+func workflowConditionTrue(condition string) bool {
+	switch strings.ToLower(strings.TrimSpace(condition)) {
+	case "", "false", "0", "no", "<no value>":
+		return false
+	}
+	return true
+}
\ No newline at end of file
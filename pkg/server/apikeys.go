@@ -0,0 +1,235 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goagents/goagents/pkg/config"
+	"go.uber.org/zap"
+)
+
+// apiKeyPrefix marks the keys the server generates.
+const apiKeyPrefix = "gak_"
+
+// apiKeyStore holds the API keys: those configured at first, then changed
+// through the API. Keys set through the API are kept in memory only.
+type apiKeyStore struct {
+	keys []config.APIKeyConfig
+	mu   sync.RWMutex
+}
+
+func newAPIKeyStore(keys []config.APIKeyConfig) *apiKeyStore {
+	return &apiKeyStore{keys: append([]config.APIKeyConfig(nil), keys...)}
+}
+
+// find returns the API key whose key is key.
+func (s *apiKeyStore) find(key string) *config.APIKeyConfig {
+	if key == "" {
+		return nil
+	}
+	
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	for i := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(s.keys[i].Key), []byte(key)) == 1 {
+			apiKey := s.keys[i]
+			return &apiKey
+		}
+	}
+	return nil
+}
+
+func (s *apiKeyStore) list() []config.APIKeyConfig {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	return append([]config.APIKeyConfig(nil), s.keys...)
+}
+
+func (s *apiKeyStore) get(name string) (config.APIKeyConfig, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	
+	for _, apiKey := range s.keys {
+		if apiKey.Name == name {
+			return apiKey, true
+		}
+	}
+	return config.APIKeyConfig{}, false
+}
+
+// add adds apiKey unless there is one of its name, and reports whether it
+// was added.
+func (s *apiKeyStore) add(apiKey config.APIKeyConfig) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for i := range s.keys {
+		if s.keys[i].Name == apiKey.Name {
+			return false
+		}
+	}
+	s.keys = append(s.keys, apiKey)
+	return true
+}
+
+// setNamespaces changes the namespaces an API key may reach.
+func (s *apiKeyStore) setNamespaces(name string, namespaces []string) (config.APIKeyConfig, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for i := range s.keys {
+		if s.keys[i].Name == name {
+			s.keys[i].Namespaces = append([]string(nil), namespaces...)
+			return s.keys[i], true
+		}
+	}
+	return config.APIKeyConfig{}, false
+}
+
+func (s *apiKeyStore) remove(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	for i := range s.keys {
+		if s.keys[i].Name == name {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func newAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	return apiKeyPrefix + hex.EncodeToString(buf), nil
+}
+
+// apiKeyBody is an API key as it is created or changed through the API.
+type apiKeyBody struct {
+	Name       string   `json:"name"`
+	Namespaces []string `json:"namespaces" binding:"required"`
+}
+
+func (s *Server) listAPIKeysHandler(c *gin.Context) {
+	apiKeys := s.apiKeys.list()
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": apiKeys,
+		"total":    len(apiKeys),
+	})
+}
+
+func (s *Server) getAPIKeyHandler(c *gin.Context) {
+	apiKey, ok := s.apiKeys.get(c.Param("name"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "API key not found",
+			"details": "no API key named " + c.Param("name"),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, apiKey)
+}
+
+// createAPIKeyHandler generates an API key. The key itself is returned
+// only here; later reads give its name and namespaces.
+func (s *Server) createAPIKeyHandler(c *gin.Context) {
+	var body apiKeyBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid API key",
+			"details": err.Error(),
+		})
+		return
+	}
+	if body.Name == "" || len(body.Namespaces) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid API key",
+			"details": "name and at least one namespace are required",
+		})
+		return
+	}
+	
+	key, err := newAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create API key",
+			"details": err.Error(),
+		})
+		return
+	}
+	apiKey := config.APIKeyConfig{
+		Name:       body.Name,
+		Key:        key,
+		Namespaces: body.Namespaces,
+	}
+	if !s.apiKeys.add(apiKey) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Failed to create API key",
+			"details": "an API key named " + body.Name + " already exists",
+		})
+		return
+	}
+	
+	s.logger.Info("API key created", zap.String("name", apiKey.Name), zap.Strings("namespaces", apiKey.Namespaces))
+	c.JSON(http.StatusCreated, gin.H{
+		"name":       apiKey.Name,
+		"key":        apiKey.Key,
+		"namespaces": apiKey.Namespaces,
+	})
+}
+
+// updateAPIKeyHandler changes the namespaces an API key may reach; its key
+// stays the same.
+func (s *Server) updateAPIKeyHandler(c *gin.Context) {
+	var body apiKeyBody
+	if err := c.ShouldBindJSON(&body); err != nil || len(body.Namespaces) == 0 {
+		details := "at least one namespace is required"
+		if err != nil {
+			details = err.Error()
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid API key",
+			"details": details,
+		})
+		return
+	}
+	
+	apiKey, ok := s.apiKeys.setNamespaces(c.Param("name"), body.Namespaces)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "API key not found",
+			"details": "no API key named " + c.Param("name"),
+		})
+		return
+	}
+	
+	s.logger.Info("API key updated", zap.String("name", apiKey.Name), zap.Strings("namespaces", apiKey.Namespaces))
+	c.JSON(http.StatusOK, apiKey)
+}
+
+func (s *Server) deleteAPIKeyHandler(c *gin.Context) {
+	name := c.Param("name")
+	if !s.apiKeys.remove(name) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "API key not found",
+			"details": "no API key named " + name,
+		})
+		return
+	}
+	
+	s.logger.Info("API key deleted", zap.String("name", name))
+	c.JSON(http.StatusOK, gin.H{
+		"message": "API key deleted successfully",
+		"name":    name,
+	})
+}
\ No newline at end of file
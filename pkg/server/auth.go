@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/runtime"
+)
+
+// apiKeyContextKey holds the API key a request was made with.
+const apiKeyContextKey = "goagents.api_key"
+
+// allNamespaces in an API key's namespaces grants every namespace.
+const allNamespaces = "*"
+
+// unauthenticatedRoutes are reachable without an API key: probes, and the
+// chat gateway webhooks, which verify their callers themselves.
+var unauthenticatedRoutes = map[string]bool{
+	"/health":                         true,
+	"/ready":                          true,
+	"/api/v1/gateways/teams/messages": true,
+}
+
+// authMiddleware requires an API key, once auth is enabled, and confines
+// keys to their namespaces. Routes under a cluster, an agent, a session,
+// a job or a namespace belong to its namespace; the cluster, agent and
+// namespace lists are filtered by the handlers, and the handlers of cluster
+// imports and template instances check the namespace they deploy to; every
+// other route, including the templates themselves, needs a key for all
+// namespaces.
+func (s *Server) authMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := &s.config.Security.Auth
+		path := c.FullPath()
+		if !auth.Enabled || unauthenticatedRoutes[path] || path == s.config.Server.Metrics.Path {
+			c.Next()
+			return
+		}
+		
+		apiKey := s.apiKeys.find(requestAPIKey(c))
+		if apiKey == nil {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   "Unauthorized",
+				"details": "a valid API key is required",
+			})
+			return
+		}
+		c.Set(apiKeyContextKey, apiKey)
+		
+		namespace, scoped := s.routeNamespace(c)
+		if (!scoped && !grantsNamespace(apiKey, allNamespaces)) || (namespace != "" && !grantsNamespace(apiKey, namespace)) {
+			if namespace == "" {
+				namespace = "all namespaces"
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"details": "API key " + apiKey.Name + " may not access " + namespace,
+			})
+			return
+		}
+		
+		c.Next()
+	}
+}
+
+// routeNamespace returns the namespace a request's target belongs to, and
+// whether the route is confined to namespaces at all. Lists return no
+// namespace, since their handlers filter them; so do targets that do not
+// exist, which their handlers report.
+func (s *Server) routeNamespace(c *gin.Context) (string, bool) {
+	path := c.FullPath()
+	switch {
+	case path == "/api/v1/clusters", path == "/api/v1/agents", path == "/api/v1/namespaces",
+		path == "/api/v1/clusters/import", path == "/api/v1/templates/:name/instantiate":
+		return "", true
+	case strings.HasPrefix(path, "/api/v1/clusters/:name"):
+		namespace, _ := runtime.SplitClusterID(c.Param("name"))
+		return namespace, true
+	case strings.HasPrefix(path, "/api/v1/namespaces/:namespace"):
+		return c.Param("namespace"), true
+	case strings.HasPrefix(path, "/api/v1/agents/:id"):
+		clusterName, _, found := s.findAgent(c.Param("id"))
+		if !found {
+			return "", true
+		}
+		namespace, _ := runtime.SplitClusterID(clusterName)
+		return namespace, true
+	case strings.HasPrefix(path, "/api/v1/sessions/:id"):
+		session, err := s.engine.GetSession(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			return "", true
+		}
+		namespace, _ := runtime.SplitClusterID(session.Cluster)
+		return namespace, true
+	case strings.HasPrefix(path, "/api/v1/jobs/:id"):
+		job, err := s.engine.Job(c.Param("id"))
+		if err != nil {
+			return "", true
+		}
+		namespace, _ := runtime.SplitClusterID(job.Cluster)
+		return namespace, true
+	}
+	return "", false
+}
+
+// requestAPIKey reads the API key from the Authorization bearer token or
+// the X-API-Key header.
+func requestAPIKey(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return c.GetHeader("X-API-Key")
+}
+
+func grantsNamespace(apiKey *config.APIKeyConfig, namespace string) bool {
+	for _, granted := range apiKey.Namespaces {
+		if granted == allNamespaces || granted == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// canAccessNamespace reports whether the request may see a namespace's
+// clusters and agents. Without auth every request may.
+func canAccessNamespace(c *gin.Context, namespace string) bool {
+	value, ok := c.Get(apiKeyContextKey)
+	if !ok {
+		return true
+	}
+	return grantsNamespace(value.(*config.APIKeyConfig), namespace)
+}
\ No newline at end of file
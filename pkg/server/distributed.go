@@ -0,0 +1,152 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goagents/goagents/pkg/runtime"
+	"go.uber.org/zap"
+)
+
+// forwardedHeader marks a request forwarded by another node, with that
+// node's ID. A forwarded request is served where it lands, so requests
+// never go round between nodes that disagree on where a cluster runs.
+const forwardedHeader = "X-GoAgents-Forwarded"
+
+// peerClient looks for sessions and jobs on the other nodes.
+var peerClient = &http.Client{Timeout: 10 * time.Second}
+
+// forwardMiddleware sends requests for what another node runs to that
+// node, in distributed mode: routes under its clusters and agents, and
+// under sessions and jobs this node does not have. New clusters go to the
+// node that runs the fewest. Every other route is served by the node the
+// request reaches.
+func (s *Server) forwardMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.engine.Distributed() || c.GetHeader(forwardedHeader) != "" {
+			c.Next()
+			return
+		}
+		
+		node, remote := s.routeNode(c)
+		if !remote {
+			c.Next()
+			return
+		}
+		s.forward(c, node)
+	}
+}
+
+// routeNode returns the node that serves a request, if another node does.
+func (s *Server) routeNode(c *gin.Context) (runtime.Node, bool) {
+	path := c.FullPath()
+	switch {
+	case path == "/api/v1/clusters" && c.Request.Method == http.MethodPost, path == "/api/v1/clusters/import",
+		path == "/api/v1/templates/:name/instantiate":
+		return s.engine.PlaceCluster()
+	case strings.HasPrefix(path, "/api/v1/clusters/:name"):
+		return s.engine.ClusterNode(c.Param("name"))
+	case strings.HasPrefix(path, "/api/v1/agents/:id"):
+		return s.engine.AgentNode(c.Param("id"))
+	case strings.HasPrefix(path, "/api/v1/sessions/:id"):
+		// Sessions in a shared store are found on every node, but chats
+		// run where the session's agent does
+		session, err := s.engine.GetSession(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			return s.findPeer(c, "/api/v1/sessions/"+url.PathEscape(c.Param("id")))
+		}
+		if path == "/api/v1/sessions/:id/chat" {
+			return s.engine.ClusterNode(session.Cluster)
+		}
+	case strings.HasPrefix(path, "/api/v1/jobs/:id"):
+		if _, err := s.engine.Job(c.Param("id")); err != nil {
+			return s.findPeer(c, "/api/v1/jobs/"+url.PathEscape(c.Param("id")))
+		}
+	}
+	return runtime.Node{}, false
+}
+
+// findPeer returns the first other node that answers a GET of path, made
+// with the request's credentials.
+func (s *Server) findPeer(c *gin.Context, path string) (runtime.Node, bool) {
+	for _, node := range s.engine.Peers() {
+		req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, node.Address+path, nil)
+		if err != nil {
+			continue
+		}
+		req.Header.Set(forwardedHeader, s.engine.Node().ID)
+		for _, header := range []string{"Authorization", "X-API-Key"} {
+			if value := c.GetHeader(header); value != "" {
+				req.Header.Set(header, value)
+			}
+		}
+		
+		resp, err := peerClient.Do(req)
+		if err != nil {
+			s.logger.Warn("Failed to reach node", zap.String("node", node.ID), zap.Error(err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			return node, true
+		}
+	}
+	return runtime.Node{}, false
+}
+
+// forward proxies the request to node, streaming its response back.
+func (s *Server) forward(c *gin.Context, node runtime.Node) {
+	target, err := url.Parse(node.Address)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to forward request",
+			"details": "node " + node.ID + " has an invalid address: " + err.Error(),
+		})
+		return
+	}
+	
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = target.Scheme
+			req.URL.Host = target.Host
+			req.Host = target.Host
+			req.Header.Set(forwardedHeader, s.engine.Node().ID)
+		},
+		// Streamed responses are passed on as they come
+		FlushInterval: -1,
+		ErrorHandler: func(w http.ResponseWriter, req *http.Request, err error) {
+			s.logger.Warn("Failed to forward request",
+				zap.String("node", node.ID),
+				zap.String("path", req.URL.Path),
+				zap.Error(err))
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error":   "Failed to forward request",
+				"details": "node " + node.ID + " did not answer: " + err.Error(),
+			})
+		},
+	}
+	c.Header("X-GoAgents-Node", node.ID)
+	proxy.ServeHTTP(c.Writer, c.Request)
+	c.Abort()
+}
+
+// nodesHandler lists the live nodes of a distributed deployment, and
+// which of them leads.
+func (s *Server) nodesHandler(c *gin.Context) {
+	if !s.engine.Distributed() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Distributed mode is not enabled"})
+		return
+	}
+	
+	nodes := s.engine.Nodes()
+	c.JSON(http.StatusOK, gin.H{
+		"node":   s.engine.Node().ID,
+		"leader": s.engine.Leader(),
+		"nodes":  nodes,
+		"total":  len(nodes),
+	})
+}
\ No newline at end of file
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// drainedRoutes start new agent work, so they are refused while the server
+// drains. Reads, and requests about work already running, are served.
+var drainedRoutes = map[string]map[string]bool{
+	http.MethodPost: {
+		"/api/v1/agents/:id/chat":                        true,
+		"/api/v1/agents/:id/stream":                      true,
+		"/api/v1/agents/:id/jobs":                        true,
+		"/api/v1/agents/:id/sessions":                    true,
+		"/api/v1/agents/:id/tool-calls":                  true,
+		"/api/v1/sessions/:id/chat":                      true,
+		"/api/v1/clusters/:name/workflows/:workflow/run": true,
+		"/api/v1/clusters/:name/schedules/:schedule/run": true,
+		"/api/v1/gateways/teams/messages":                true,
+		"/mcp":                                           true,
+	},
+}
+
+// drainMiddleware refuses new agent work while the server drains, telling
+// clients to reconnect so they reach another instance.
+func (s *Server) drainMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !drainedRoutes[c.Request.Method][c.FullPath()] || !s.isDraining() {
+			c.Next()
+			return
+		}
+		
+		c.Header("Connection", "close")
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Server is draining",
+			"details": "new requests are refused while in-flight requests finish",
+		})
+	}
+}
+
+// isDraining reports whether the server is shutting down or was asked to
+// drain.
+func (s *Server) isDraining() bool {
+	return s.draining.Load() || s.engine.Draining()
+}
+
+func (s *Server) getDrainHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.engine.DrainStatus())
+}
+
+// startDrainHandler drains the server. Given wait, it waits that long for
+// in-flight requests and jobs to finish, answering 200 once they have and
+// 202 while they are still running.
+func (s *Server) startDrainHandler(c *gin.Context) {
+	var wait time.Duration
+	if value := c.Query("wait"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid wait",
+				"details": "wait must be a duration such as 30s",
+			})
+			return
+		}
+		wait = parsed
+	}
+	
+	s.engine.StartDrain()
+	if wait > 0 {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), wait)
+		defer cancel()
+		s.engine.Drain(ctx)
+	}
+	
+	status := s.engine.DrainStatus()
+	code := http.StatusAccepted
+	if status.Drained {
+		code = http.StatusOK
+	}
+	c.JSON(code, status)
+}
+
+// stopDrainHandler takes new requests again, unless the server is shutting
+// down.
+func (s *Server) stopDrainHandler(c *gin.Context) {
+	if s.draining.Load() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Server is shutting down",
+			"details": "a server draining to shut down cannot take requests again",
+		})
+		return
+	}
+	
+	s.engine.StopDrain()
+	c.JSON(http.StatusOK, s.engine.DrainStatus())
+}
\ No newline at end of file
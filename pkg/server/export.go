@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/runtime"
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// exportClusterHandler returns the spec a cluster runs as YAML, or as JSON
+// with format=json, with its credentials redacted.
+func (s *Server) exportClusterHandler(c *gin.Context) {
+	spec, err := s.engine.ExportCluster(c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to export cluster",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if c.Query("format") == "json" {
+		c.JSON(http.StatusOK, spec)
+		return
+	}
+	var data bytes.Buffer
+	encoder := yaml.NewEncoder(&data)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(spec); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to export cluster",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml", data.Bytes())
+}
+
+// importClusterHandler deploys a cluster from an exported spec.
+func (s *Server) importClusterHandler(c *gin.Context) {
+	var spec config.AgentCluster
+	if err := bindSpec(c, &spec); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid cluster configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	namespace := spec.Metadata.Namespace
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+	if !canAccessNamespace(c, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Failed to import cluster",
+			"details": "API key may not access namespace " + namespace,
+		})
+		return
+	}
+	
+	if err := s.engine.ImportCluster(&spec); err != nil {
+		s.logger.Error("Failed to import cluster", zap.Error(err))
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to import cluster",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, gin.H{
+		"message":          "Cluster imported successfully",
+		"name":             runtime.ClusterID(spec.Metadata.Namespace, spec.Metadata.Name),
+		"namespace":        spec.Metadata.Namespace,
+		"resource_version": spec.Metadata.ResourceVersion,
+	})
+}
+
+// bindSpec decodes a body written as a cluster file is: JSON when the
+// request says so, YAML otherwise. The two differ in durations, which JSON
+// gives in nanoseconds and YAML as strings such as 30s.
+func bindSpec(c *gin.Context, out interface{}) error {
+	if c.ContentType() == "application/json" {
+		return c.ShouldBindJSON(out)
+	}
+	return c.ShouldBindYAML(out)
+}
\ No newline at end of file
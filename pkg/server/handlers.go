@@ -1,14 +1,25 @@
 package server
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/goagents/goagents/pkg/agent"
 	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/files"
+	"github.com/goagents/goagents/pkg/knowledge"
+	"github.com/goagents/goagents/pkg/runtime"
+	"github.com/goagents/goagents/pkg/sessions"
+	"github.com/goagents/goagents/pkg/tools"
+	"github.com/goagents/goagents/pkg/vault"
 	"go.uber.org/zap"
 )
 
@@ -18,10 +29,19 @@ func (s *Server) healthHandler(c *gin.Context) {
 		"status":    "healthy",
 		"timestamp": time.Now().UTC(),
 		"version":   "1.0.0",
+		"read_only": s.IsReadOnly(),
 	})
 }
 
 func (s *Server) readyHandler(c *gin.Context) {
+	if s.isDraining() {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status":    "draining",
+			"timestamp": time.Now().UTC(),
+		})
+		return
+	}
+	
 	clusters := s.engine.ListClusters()
 	runningClusters := 0
 	
@@ -41,22 +61,60 @@ func (s *Server) readyHandler(c *gin.Context) {
 
 // Cluster handlers
 func (s *Server) listClustersHandler(c *gin.Context) {
-	clusters := s.engine.ListClusters()
+	namespaceFilter := c.Query("namespace")
+	
+	clusterList := make([]gin.H, 0)
+	for _, cluster := range s.engine.ListClusters() {
+		namespace, _ := runtime.SplitClusterID(cluster.Name)
+		if (namespaceFilter != "" && namespace != namespaceFilter) || !canAccessNamespace(c, namespace) {
+			continue
+		}
+		
+		summary := gin.H{
+			"name":             cluster.Name,
+			"namespace":        namespace,
+			"status":           cluster.Status,
+			"agents":           len(cluster.Agents),
+			"resource_version": cluster.Config.Metadata.ResourceVersion,
+			"created_at":       cluster.CreatedAt,
+			"updated_at":       cluster.UpdatedAt,
+		}
+		if cluster.Message != "" {
+			summary["message"] = cluster.Message
+		}
+		if s.engine.Distributed() {
+			summary["node"] = s.engine.Node().ID
+		}
+		clusterList = append(clusterList, summary)
+	}
 	
-	clusterList := make([]gin.H, len(clusters))
-	for i, cluster := range clusters {
-		clusterList[i] = gin.H{
-			"name":       cluster.Name,
-			"status":     cluster.Status,
-			"agents":     len(cluster.Agents),
-			"created_at": cluster.CreatedAt,
-			"updated_at": cluster.UpdatedAt,
+	// In distributed mode, the clusters of the other nodes as they last
+	// saved them
+	for _, cluster := range s.engine.RemoteClusters() {
+		namespace, _ := runtime.SplitClusterID(cluster.Name)
+		if (namespaceFilter != "" && namespace != namespaceFilter) || !canAccessNamespace(c, namespace) {
+			continue
+		}
+		
+		summary := gin.H{
+			"name":             cluster.Name,
+			"namespace":        namespace,
+			"status":           cluster.Status,
+			"agents":           len(cluster.Agents),
+			"resource_version": cluster.ResourceVersion,
+			"created_at":       cluster.CreatedAt,
+			"updated_at":       cluster.UpdatedAt,
+			"node":             cluster.Node,
 		}
+		if cluster.Message != "" {
+			summary["message"] = cluster.Message
+		}
+		clusterList = append(clusterList, summary)
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
 		"clusters": clusterList,
-		"total":    len(clusters),
+		"total":    len(clusterList),
 	})
 }
 
@@ -70,9 +128,21 @@ func (s *Server) createClusterHandler(c *gin.Context) {
 		return
 	}
 	
+	namespace := clusterConfig.Metadata.Namespace
+	if namespace == "" {
+		namespace = config.DefaultNamespace
+	}
+	if !canAccessNamespace(c, namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Failed to deploy cluster",
+			"details": "API key may not access namespace " + namespace,
+		})
+		return
+	}
+	
 	if err := s.engine.DeployCluster(&clusterConfig); err != nil {
 		s.logger.Error("Failed to deploy cluster", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
 			"error": "Failed to deploy cluster",
 			"details": err.Error(),
 		})
@@ -80,11 +150,83 @@ func (s *Server) createClusterHandler(c *gin.Context) {
 	}
 	
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Cluster created successfully",
-		"name":    clusterConfig.Metadata.Name,
+		"message":          "Cluster created successfully",
+		"name":             runtime.ClusterID(clusterConfig.Metadata.Namespace, clusterConfig.Metadata.Name),
+		"namespace":        clusterConfig.Metadata.Namespace,
+		"resource_version": clusterConfig.Metadata.ResourceVersion,
 	})
 }
 
+// updateClusterHandler replaces a cluster's spec. Conflicting writes are
+// rejected with 409; see writePrecondition.
+func (s *Server) updateClusterHandler(c *gin.Context) {
+	clusterName := c.Param("name")
+	
+	var candidate config.AgentCluster
+	if err := c.ShouldBindJSON(&candidate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid cluster configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	result, err := s.engine.ApplyCluster(clusterName, &candidate, writePrecondition(c), dryRun)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to update cluster",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", strconv.Quote(result.ResourceVersion))
+	c.JSON(http.StatusOK, result)
+}
+
+func (s *Server) patchClusterHandler(c *gin.Context) {
+	clusterName := c.Param("name")
+	
+	patch, err := c.GetRawData()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to read merge patch",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	result, err := s.engine.PatchCluster(clusterName, patch, writePrecondition(c), dryRun)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to patch cluster",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.Header("ETag", strconv.Quote(result.ResourceVersion))
+	c.JSON(http.StatusOK, result)
+}
+
+// writePrecondition reads the guards for a cluster write: the resource
+// version from If-Match, the writer from X-GoAgents-Owner, and force=true to
+// override another owner's annotation.
+func writePrecondition(c *gin.Context) *runtime.Precondition {
+	version := strings.TrimPrefix(c.GetHeader("If-Match"), "W/")
+	if unquoted, err := strconv.Unquote(version); err == nil {
+		version = unquoted
+	}
+	force, _ := strconv.ParseBool(c.Query("force"))
+	return &runtime.Precondition{
+		ResourceVersion: version,
+		Owner:           c.GetHeader("X-GoAgents-Owner"),
+		Force:           force,
+	}
+}
+
 func (s *Server) getClusterHandler(c *gin.Context) {
 	clusterName := c.Param("name")
 	
@@ -99,41 +241,46 @@ func (s *Server) getClusterHandler(c *gin.Context) {
 	
 	agents := make([]gin.H, 0, len(cluster.Agents))
 	for _, agent := range cluster.Agents {
-		metrics := agent.GetMetrics()
+		metrics := s.engine.AgentMetrics(cluster.Name, agent.GetName())
 		agents = append(agents, gin.H{
 			"id":            agent.ID,
-			"name":          agent.Name,
+			"name":          agent.GetName(),
 			"status":        agent.GetStatus(),
 			"provider":      agent.Config.Provider,
 			"model":         agent.Config.Model,
+			"error":         agent.ErrorMessage,
 			"created_at":    agent.CreatedAt,
 			"updated_at":    agent.UpdatedAt,
 			"last_activity": agent.LastActivity,
-			"metrics": gin.H{
-				"requests_total":     metrics.RequestsTotal,
-				"requests_succeeded": metrics.RequestsSucceeded,
-				"requests_failed":    metrics.RequestsFailed,
-				"response_time":      metrics.ResponseTime,
-				"last_request_time":  metrics.LastRequestTime,
-			},
+			"metrics":       metrics,
 		})
 	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"name":       cluster.Name,
-		"status":     cluster.Status,
-		"created_at": cluster.CreatedAt,
-		"updated_at": cluster.UpdatedAt,
-		"agents":     agents,
-		"config":     cluster.Config,
-	})
+	details := gin.H{
+		"name":             cluster.Name,
+		"namespace":        cluster.Config.Metadata.Namespace,
+		"status":           cluster.Status,
+		"resource_version": cluster.Config.Metadata.ResourceVersion,
+		"created_at":       cluster.CreatedAt,
+		"updated_at":       cluster.UpdatedAt,
+		"agents":           agents,
+		"smoke_tests":      cluster.SmokeTests,
+		"conditions":       cluster.Conditions,
+		"config":           cluster.Config,
+	}
+	if cluster.Message != "" {
+		details["message"] = cluster.Message
+	}
+	
+	c.Header("ETag", strconv.Quote(cluster.Config.Metadata.ResourceVersion))
+	c.JSON(http.StatusOK, details)
 }
 
 func (s *Server) deleteClusterHandler(c *gin.Context) {
 	clusterName := c.Param("name")
 	
-	if err := s.engine.DeleteCluster(clusterName); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	if err := s.engine.DeleteCluster(clusterName, writePrecondition(c)); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
 			"error": "Failed to delete cluster",
 			"details": err.Error(),
 		})
@@ -146,6 +293,32 @@ func (s *Server) deleteClusterHandler(c *gin.Context) {
 	})
 }
 
+// diffClusterHandler reports what deploying a candidate spec would change
+// without deploying it.
+func (s *Server) diffClusterHandler(c *gin.Context) {
+	clusterName := c.Param("name")
+	
+	var candidate config.AgentCluster
+	if err := c.ShouldBindJSON(&candidate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid cluster configuration",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	diff, err := s.engine.DiffCluster(clusterName, &candidate)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to diff cluster",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, diff)
+}
+
 func (s *Server) scaleClusterHandler(c *gin.Context) {
 	clusterName := c.Param("name")
 	
@@ -162,217 +335,1742 @@ func (s *Server) scaleClusterHandler(c *gin.Context) {
 		return
 	}
 	
-	// For demo purposes, simulate scaling operation
-	s.logger.Info("Scaling agent",
-		zap.String("cluster", clusterName),
-		zap.String("agent", scaleRequest.Agent),
-		zap.Int("instances", scaleRequest.Instances))
+	status, err := s.engine.ScaleAgent(clusterName, scaleRequest.Agent, scaleRequest.Instances)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to scale agent",
+			"details": err.Error(),
+		})
+		return
+	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"message":   "Agent scaled successfully",
-		"cluster":   clusterName,
-		"agent":     scaleRequest.Agent,
-		"instances": scaleRequest.Instances,
-	})
+	c.JSON(http.StatusOK, status)
 }
 
-// Agent handlers
-func (s *Server) listAgentsHandler(c *gin.Context) {
-	clusterFilter := c.Query("cluster")
-	
-	clusters := s.engine.ListClusters()
-	var allAgents []gin.H
+func (s *Server) cloneAgentHandler(c *gin.Context) {
+	clusterName := c.Param("name")
+	agentName := c.Param("agent")
 	
-	for _, cluster := range clusters {
-		if clusterFilter != "" && cluster.Name != clusterFilter {
-			continue
-		}
-		
-		for _, agent := range cluster.Agents {
-			metrics := agent.GetMetrics()
-			allAgents = append(allAgents, gin.H{
-				"id":            agent.ID,
-				"name":          agent.Name,
-				"cluster":       agent.ClusterName,
-				"status":        agent.GetStatus(),
-				"provider":      agent.Config.Provider,
-				"model":         agent.Config.Model,
-				"created_at":    agent.CreatedAt,
-				"updated_at":    agent.UpdatedAt,
-				"last_activity": agent.LastActivity,
-				"metrics": gin.H{
-					"requests_total":     metrics.RequestsTotal,
-					"requests_succeeded": metrics.RequestsSucceeded,
-					"requests_failed":    metrics.RequestsFailed,
-				},
-			})
-		}
+	var cloneRequest struct {
+		Name      string                  `json:"name" binding:"required"`
+		Overrides *runtime.AgentOverrides `json:"overrides,omitempty"`
 	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"agents": allAgents,
-		"total":  len(allAgents),
-	})
-}
-
-func (s *Server) getAgentHandler(c *gin.Context) {
-	agentID := c.Param("id")
+	if err := c.ShouldBindJSON(&cloneRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid clone request",
+			"details": err.Error(),
+		})
+		return
+	}
 	
-	// Find agent across all clusters
-	clusters := s.engine.ListClusters()
-	for _, cluster := range clusters {
-		for _, agent := range cluster.Agents {
-			if agent.ID == agentID {
-				metrics := agent.GetMetrics()
-				c.JSON(http.StatusOK, gin.H{
-					"id":            agent.ID,
-					"name":          agent.Name,
-					"cluster":       agent.ClusterName,
-					"status":        agent.GetStatus(),
-					"provider":      agent.Config.Provider,
-					"model":         agent.Config.Model,
-					"system_prompt": agent.Config.SystemPrompt,
-					"created_at":    agent.CreatedAt,
-					"updated_at":    agent.UpdatedAt,
-					"last_activity": agent.LastActivity,
-					"metrics":       metrics,
-					"config":        agent.Config,
-				})
-				return
-			}
-		}
+	clone, err := s.engine.CloneAgent(clusterName, agentName, cloneRequest.Name, cloneRequest.Overrides, writePrecondition(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to clone agent",
+			"details": err.Error(),
+		})
+		return
 	}
 	
-	c.JSON(http.StatusNotFound, gin.H{
-		"error": "Agent not found",
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Agent cloned successfully",
+		"cluster": clusterName,
+		"source":  agentName,
+		"agent":   clone,
 	})
 }
 
-func (s *Server) chatHandler(c *gin.Context) {
-	agentID := c.Param("id")
+func (s *Server) renameAgentHandler(c *gin.Context) {
+	clusterName := c.Param("name")
+	agentName := c.Param("agent")
 	
-	var chatRequest struct {
-		Messages []agent.Message        `json:"messages" binding:"required"`
-		Context  map[string]interface{} `json:"context,omitempty"`
-		Timeout  int                    `json:"timeout,omitempty"`
+	var renameRequest struct {
+		Name string `json:"name" binding:"required"`
 	}
 	
-	if err := c.ShouldBindJSON(&chatRequest); err != nil {
+	if err := c.ShouldBindJSON(&renameRequest); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "Invalid chat request",
+			"error": "Invalid rename request",
 			"details": err.Error(),
 		})
 		return
 	}
 	
-	// Find agent's cluster and name
-	clusters := s.engine.ListClusters()
-	var clusterName, agentName string
+	if err := s.engine.RenameAgent(clusterName, agentName, renameRequest.Name, writePrecondition(c)); err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to rename agent",
+			"details": err.Error(),
+		})
+		return
+	}
 	
-	for _, cluster := range clusters {
-		for _, agent := range cluster.Agents {
-			if agent.ID == agentID {
-				clusterName = cluster.Name
-				agentName = agent.Name
-				break
-			}
-		}
-		if clusterName != "" {
-			break
-		}
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Agent renamed successfully",
+		"cluster": clusterName,
+		"from":    agentName,
+		"to":      renameRequest.Name,
+	})
+}
+
+func (s *Server) setAgentToolHandler(c *gin.Context) {
+	clusterName := c.Param("name")
+	agentName := c.Param("agent")
+	
+	var tool config.Tool
+	if err := c.ShouldBindJSON(&tool); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid tool",
+			"details": err.Error(),
+		})
+		return
 	}
 	
-	if clusterName == "" {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error": "Agent not found",
+	created, err := s.engine.SetAgentTool(clusterName, agentName, &tool, writePrecondition(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to set tool",
+			"details": err.Error(),
 		})
 		return
 	}
 	
-	// Create request
-	req := &agent.Request{
-		ID:       fmt.Sprintf("req-%d", time.Now().UnixNano()),
-		Messages: chatRequest.Messages,
-		Context:  chatRequest.Context,
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
 	}
+	c.JSON(status, gin.H{
+		"message": "Tool set successfully",
+		"cluster": clusterName,
+		"agent":   agentName,
+		"tool":    tool.Name,
+	})
+}
+
+func (s *Server) deleteAgentToolHandler(c *gin.Context) {
+	clusterName := c.Param("name")
+	agentName := c.Param("agent")
+	toolName := c.Param("tool")
 	
-	if chatRequest.Timeout > 0 {
-		req.Timeout = time.Duration(chatRequest.Timeout) * time.Second
+	if err := s.engine.RemoveAgentTool(clusterName, agentName, toolName, writePrecondition(c)); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to remove tool",
+			"details": err.Error(),
+		})
+		return
 	}
 	
-	// Process request
-	resp, err := s.engine.ProcessRequest(clusterName, agentName, req)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tool removed successfully",
+		"cluster": clusterName,
+		"agent":   agentName,
+		"tool":    toolName,
+	})
+}
+
+func (s *Server) agentVersionsHandler(c *gin.Context) {
+	versions, err := s.engine.AgentVersions(c.Param("name"), c.Param("agent"))
 	if err != nil {
-		s.logger.Error("Failed to process request", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Failed to process request",
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get agent versions",
 			"details": err.Error(),
 		})
 		return
 	}
 	
-	if resp.Error != "" {
+	c.JSON(http.StatusOK, versions)
+}
+
+// startRolloutHandler deploys a new spec for an agent alongside its
+// current one, sending weight percent of its requests to the new version.
+func (s *Server) startRolloutHandler(c *gin.Context) {
+	var body struct {
+		Spec   *config.Agent `json:"spec" binding:"required"`
+		Weight int           `json:"weight"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
-			"error": resp.Error,
+			"error":   "Invalid rollout",
+			"details": err.Error(),
 		})
 		return
 	}
 	
-	c.JSON(http.StatusOK, resp)
+	rollout, err := s.engine.StartRollout(c.Param("name"), c.Param("agent"), body.Spec, body.Weight, writePrecondition(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to start rollout",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, rollout)
 }
 
-func (s *Server) streamHandler(c *gin.Context) {
-	agentID := c.Param("id")
-	
-	// For demo purposes, simulate streaming
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	
-	// Mock streaming response
-	chunks := []string{
-		"Hello, this is a streaming response",
-		" from agent " + agentID + ".",
-		" Each chunk is sent separately",
-		" to demonstrate real-time streaming capabilities.",
-		" This concludes the demo stream.",
+func (s *Server) shiftRolloutHandler(c *gin.Context) {
+	var body struct {
+		Weight *int `json:"weight" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid rollout",
+			"details": err.Error(),
+		})
+		return
 	}
 	
-	for i, chunk := range chunks {
-		data := map[string]interface{}{
-			"id":      i,
-			"delta":   chunk,
-			"content": chunk,
-			"done":    i == len(chunks)-1,
-		}
-		
-		jsonData, _ := json.Marshal(data)
-		c.SSEvent("message", string(jsonData))
-		c.Writer.Flush()
-		
-		time.Sleep(500 * time.Millisecond)
+	rollout, err := s.engine.ShiftRollout(c.Param("name"), c.Param("agent"), *body.Weight)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to shift rollout",
+			"details": err.Error(),
+		})
+		return
 	}
+	
+	c.JSON(http.StatusOK, rollout)
 }
 
-// Metrics handler
-func (s *Server) metricsHandler(c *gin.Context) {
-	metrics := s.engine.GetMetrics()
+func (s *Server) promoteRolloutHandler(c *gin.Context) {
+	versions, err := s.engine.PromoteRollout(c.Param("name"), c.Param("agent"), writePrecondition(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to promote rollout",
+			"details": err.Error(),
+		})
+		return
+	}
 	
-	c.JSON(http.StatusOK, gin.H{
-		"clusters_total":        metrics.ClustersTotal,
-		"agents_total":          metrics.AgentsTotal,
-		"requests_total":        metrics.RequestsTotal,
-		"requests_succeeded":    metrics.RequestsSucceeded,
-		"requests_failed":       metrics.RequestsFailed,
-		"average_response_time": metrics.AverageResponseTime,
-		"timestamp":             time.Now().UTC(),
-	})
+	c.JSON(http.StatusOK, versions)
 }
 
-// System info handler
-func (s *Server) infoHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"name":        "GoAgents",
+// rollbackAgentHandler drops an agent's rollout, if it has one, or
+// redeploys the version it ran before, or the version the body names.
+func (s *Server) rollbackAgentHandler(c *gin.Context) {
+	var body struct {
+		Version int `json:"version"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid rollback",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	versions, err := s.engine.RollbackAgent(c.Param("name"), c.Param("agent"), body.Version, writePrecondition(c))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to roll back agent",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, versions)
+}
+
+func (s *Server) listWorkflowsHandler(c *gin.Context) {
+	workflows, err := s.engine.Workflows(c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to list workflows",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"workflows": workflows,
+		"count":     len(workflows),
+	})
+}
+
+// runWorkflowHandler starts a workflow run and returns 202 with the run,
+// whose status can be polled. With wait=true it returns the finished run,
+// or the run as it is when the client gives up.
+func (s *Server) runWorkflowHandler(c *gin.Context) {
+	var body struct {
+		Input map[string]interface{} `json:"input"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid workflow input",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	run, err := s.engine.RunWorkflow(c.Param("name"), c.Param("workflow"), body.Input)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to run workflow",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if c.Query("wait") == "true" {
+		run, err = s.engine.WaitWorkflowRun(c.Request.Context(), run)
+		if err != nil {
+			c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+				"error":   "Failed to get workflow run",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	status := http.StatusAccepted
+	if run.Status == runtime.WorkflowStatusSucceeded || run.Status == runtime.WorkflowStatusFailed {
+		status = http.StatusOK
+	}
+	c.JSON(status, run)
+}
+
+func (s *Server) listWorkflowRunsHandler(c *gin.Context) {
+	runs, err := s.engine.WorkflowRuns(c.Param("name"), c.Param("workflow"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to list workflow runs",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"runs":  runs,
+		"count": len(runs),
+	})
+}
+
+func (s *Server) getWorkflowRunHandler(c *gin.Context) {
+	run, err := s.engine.WorkflowRun(c.Param("name"), c.Param("workflow"), c.Param("run"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get workflow run",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, run)
+}
+
+func (s *Server) listSchedulesHandler(c *gin.Context) {
+	schedules, err := s.engine.Schedules(c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to list schedules",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"schedules": schedules,
+		"count":     len(schedules),
+	})
+}
+
+// runScheduleHandler triggers a schedule now and returns 202 with the run,
+// or 200 if the overlap policy skipped it. With wait=true it returns the
+// finished run, or the run as it is when the client gives up.
+func (s *Server) runScheduleHandler(c *gin.Context) {
+	run, err := s.engine.TriggerSchedule(c.Param("name"), c.Param("schedule"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to run schedule",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if c.Query("wait") == "true" {
+		run, err = s.engine.WaitScheduleRun(c.Request.Context(), run)
+		if err != nil {
+			c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+				"error":   "Failed to get schedule run",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	status := http.StatusAccepted
+	if run.Status != runtime.ScheduleRunRunning {
+		status = http.StatusOK
+	}
+	c.JSON(status, run)
+}
+
+func (s *Server) listScheduleRunsHandler(c *gin.Context) {
+	runs, err := s.engine.ScheduleRuns(c.Param("name"), c.Param("schedule"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to list schedule runs",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"runs":  runs,
+		"count": len(runs),
+	})
+}
+
+func (s *Server) getScheduleRunHandler(c *gin.Context) {
+	run, err := s.engine.ScheduleRun(c.Param("name"), c.Param("schedule"), c.Param("run"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get schedule run",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, run)
+}
+
+// writeProcessError responds to a failed chat or stream request. Requests
+// turned away by a full queue are told when to try again.
+func writeProcessError(c *gin.Context, err error) {
+	var queueErr *runtime.QueueFullError
+	if errors.As(err, &queueErr) {
+		seconds := (queueErr.RetryAfter + time.Second - 1) / time.Second
+		c.Header("Retry-After", strconv.Itoa(int(seconds)))
+	}
+	c.JSON(errorStatus(err, http.StatusInternalServerError), processError(err))
+}
+
+// processError is the body of a failed chat or stream request. Requests
+// stopped by a budget or refused by a quota say which one.
+func processError(err error) gin.H {
+	body := gin.H{
+		"error":   "Failed to process request",
+		"details": err.Error(),
+	}
+	var budgetErr *runtime.BudgetError
+	if errors.As(err, &budgetErr) {
+		body["budget"] = budgetErr
+	}
+	var quotaErr *runtime.QuotaError
+	if errors.As(err, &quotaErr) {
+		body["quota"] = quotaErr
+	}
+	return body
+}
+
+// errorStatus maps engine sentinel errors to HTTP status codes.
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, runtime.ErrClusterNotFound), errors.Is(err, runtime.ErrAgentNotFound),
+		errors.Is(err, runtime.ErrFeatureNotFound), errors.Is(err, runtime.ErrToolNotFound),
+		errors.Is(err, runtime.ErrRequestNotFound), errors.Is(err, files.ErrNotFound),
+		errors.Is(err, vault.ErrNotFound), errors.Is(err, runtime.ErrResponseNotFound),
+		errors.Is(err, sessions.ErrNotFound), errors.Is(err, runtime.ErrMemoryNotFound),
+		errors.Is(err, runtime.ErrKnowledgeDisabled), errors.Is(err, knowledge.ErrNotFound),
+		errors.Is(err, runtime.ErrWorkflowNotFound), errors.Is(err, runtime.ErrWorkflowRunNotFound),
+		errors.Is(err, runtime.ErrScheduleNotFound), errors.Is(err, runtime.ErrScheduleRunNotFound),
+		errors.Is(err, runtime.ErrJobNotFound), errors.Is(err, runtime.ErrAgentVersionNotFound),
+		errors.Is(err, runtime.ErrRolloutNotFound), errors.Is(err, runtime.ErrTemplateNotFound),
+		errors.Is(err, runtime.ErrTranscriptNotFound), errors.Is(err, runtime.ErrWebhookNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, runtime.ErrAgentExists), errors.Is(err, runtime.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, config.ErrPolicyViolation), errors.Is(err, tools.ErrOutboundBlocked),
+		errors.Is(err, runtime.ErrAgentQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, runtime.ErrMissingPromptVariable), errors.Is(err, runtime.ErrRedactedCredential),
+		errors.Is(err, runtime.ErrInvalidTemplate):
+		return http.StatusBadRequest
+	case errors.Is(err, knowledge.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, knowledge.ErrUnsupported), errors.Is(err, runtime.ErrGuardrailBlocked):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, runtime.ErrBudgetExceeded), errors.Is(err, runtime.ErrQuotaExceeded):
+		return http.StatusTooManyRequests
+	case errors.Is(err, runtime.ErrAgentUnavailable), errors.Is(err, runtime.ErrJobQueueFull), errors.Is(err, runtime.ErrAgentBusy),
+		errors.Is(err, runtime.ErrAgentQueueFull), errors.Is(err, runtime.ErrDraining):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, runtime.ErrVaultDisabled):
+		return http.StatusNotImplemented
+	default:
+		return fallback
+	}
+}
+
+// Agent handlers
+func (s *Server) listAgentsHandler(c *gin.Context) {
+	clusterFilter := c.Query("cluster")
+	namespaceFilter := c.Query("namespace")
+	
+	clusters := s.engine.ListClusters()
+	var allAgents []gin.H
+	
+	for _, cluster := range clusters {
+		if clusterFilter != "" && cluster.Name != clusterFilter {
+			continue
+		}
+		namespace, _ := runtime.SplitClusterID(cluster.Name)
+		if (namespaceFilter != "" && namespace != namespaceFilter) || !canAccessNamespace(c, namespace) {
+			continue
+		}
+		
+		for _, agent := range cluster.Agents {
+			metrics := s.engine.AgentMetrics(cluster.Name, agent.GetName())
+			summary := gin.H{
+				"id":            agent.ID,
+				"name":          agent.GetName(),
+				"cluster":       agent.ClusterName,
+				"namespace":     namespace,
+				"status":        agent.GetStatus(),
+				"provider":      agent.Config.Provider,
+				"model":         agent.Config.Model,
+				"created_at":    agent.CreatedAt,
+				"updated_at":    agent.UpdatedAt,
+				"last_activity": agent.LastActivity,
+				"metrics": gin.H{
+					"requests_total":     metrics.RequestsTotal,
+					"requests_succeeded": metrics.RequestsSucceeded,
+					"requests_failed":    metrics.RequestsFailed,
+				},
+			}
+			if s.engine.Distributed() {
+				summary["node"] = s.engine.Node().ID
+			}
+			allAgents = append(allAgents, summary)
+		}
+	}
+	
+	// Agents other nodes run carry no metrics; their nodes report them
+	for _, cluster := range s.engine.RemoteClusters() {
+		if clusterFilter != "" && cluster.Name != clusterFilter {
+			continue
+		}
+		namespace, _ := runtime.SplitClusterID(cluster.Name)
+		if (namespaceFilter != "" && namespace != namespaceFilter) || !canAccessNamespace(c, namespace) {
+			continue
+		}
+		
+		for _, agent := range cluster.Agents {
+			allAgents = append(allAgents, gin.H{
+				"id":        agent.ID,
+				"name":      agent.Name,
+				"cluster":   cluster.Name,
+				"namespace": namespace,
+				"status":    agent.Status,
+				"provider":  agent.Provider,
+				"model":     agent.Model,
+				"node":      cluster.Node,
+			})
+		}
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"agents": allAgents,
+		"total":  len(allAgents),
+	})
+}
+
+func (s *Server) getAgentHandler(c *gin.Context) {
+	agentID := c.Param("id")
+	
+	// Find agent across all clusters
+	clusters := s.engine.ListClusters()
+	for _, cluster := range clusters {
+		for _, agent := range cluster.Agents {
+			if agent.ID == agentID {
+				metrics := s.engine.AgentMetrics(cluster.Name, agent.GetName())
+				details := gin.H{
+					"id":            agent.ID,
+					"name":          agent.GetName(),
+					"cluster":       agent.ClusterName,
+					"status":        agent.GetStatus(),
+					"provider":      agent.Config.Provider,
+					"model":         agent.Config.Model,
+					"system_prompt": agent.Config.SystemPrompt,
+					"prompt_layers": agent.Config.PromptLayers,
+					"tools":         agent.ToolDefinitions(),
+					"created_at":    agent.CreatedAt,
+					"updated_at":    agent.UpdatedAt,
+					"last_activity": agent.LastActivity,
+					"metrics":       metrics,
+					"config":        agent.Config,
+				}
+				if message := agent.GetErrorMessage(); message != "" {
+					details["error"] = message
+				}
+				if health, ok := s.engine.AgentHealth(agent.ID); ok {
+					details["health"] = health
+				}
+				if instances, err := s.engine.AgentInstances(agent.ID); err == nil {
+					details["instances"] = instances
+				}
+				c.JSON(http.StatusOK, details)
+				return
+			}
+		}
+	}
+	
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": "Agent not found",
+	})
+}
+
+type chatRequestBody struct {
+	Messages        []agent.Message        `json:"messages" binding:"required"`
+	Context         map[string]interface{} `json:"context,omitempty"`
+	Timeout         int                    `json:"timeout,omitempty"`
+	IncludeThinking bool                   `json:"include_thinking,omitempty"`
+	Priority        string                 `json:"priority,omitempty" binding:"omitempty,oneof=high normal low"`
+}
+
+func (s *Server) chatHandler(c *gin.Context) {
+	agentID := c.Param("id")
+	
+	var chatRequest chatRequestBody
+	if err := c.ShouldBindJSON(&chatRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid chat request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	clusterName, agentName, found := s.findAgent(agentID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Agent not found",
+		})
+		return
+	}
+	
+	req := newAgentRequest(&chatRequest)
+	
+	// Clients can opt into streaming through the Accept header
+	if format := negotiateFormat(c, formatJSON); format != formatJSON {
+		s.streamResponse(c, clusterName, agentName, req, format)
+		return
+	}
+	
+	// Process request
+	resp, err := s.engine.ProcessRequestContext(c.Request.Context(), clusterName, agentName, req)
+	if err != nil {
+		s.logger.Error("Failed to process request", zap.Error(err))
+		writeProcessError(c, err)
+		return
+	}
+	
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": resp.Error,
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) streamHandler(c *gin.Context) {
+	agentID := c.Param("id")
+	
+	var chatRequest chatRequestBody
+	if err := c.ShouldBindJSON(&chatRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid chat request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	clusterName, agentName, found := s.findAgent(agentID)
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Agent not found",
+		})
+		return
+	}
+	
+	format := negotiateFormat(c, formatSSE)
+	if format == formatJSON {
+		format = formatSSE
+	}
+	
+	s.streamResponse(c, clusterName, agentName, newAgentRequest(&chatRequest), format)
+}
+
+func newAgentRequest(chatRequest *chatRequestBody) *agent.Request {
+	req := &agent.Request{
+		ID:       fmt.Sprintf("req-%d", time.Now().UnixNano()),
+		Messages:        chatRequest.Messages,
+		Context:         chatRequest.Context,
+		IncludeThinking: chatRequest.IncludeThinking,
+		Priority:        chatRequest.Priority,
+	}
+	
+	if chatRequest.Timeout > 0 {
+		req.Timeout = time.Duration(chatRequest.Timeout) * time.Second
+	}
+	
+	return req
+}
+
+func (s *Server) findAgent(agentID string) (string, string, bool) {
+	for _, cluster := range s.engine.ListClusters() {
+		for _, agent := range cluster.Agents {
+			if agent.ID == agentID {
+				return cluster.Name, agent.GetName(), true
+			}
+		}
+	}
+	
+	return "", "", false
+}
+
+// Metrics handler
+func (s *Server) metricsHandler(c *gin.Context) {
+	metrics := s.engine.GetMetrics()
+	
+	c.JSON(http.StatusOK, gin.H{
+		"clusters_total":        metrics.ClustersTotal,
+		"agents_total":          metrics.AgentsTotal,
+		"requests_total":        metrics.RequestsTotal,
+		"requests_succeeded":    metrics.RequestsSucceeded,
+		"requests_failed":       metrics.RequestsFailed,
+		"average_response_time": metrics.AverageResponseTime,
+		"latency":               metrics.Latency,
+		"clusters":              metrics.Clusters,
+		"agents":                metrics.Agents,
+		"providers":             metrics.Providers,
+		"models":                metrics.Models,
+		"timestamp":             time.Now().UTC(),
+	})
+}
+
+func (s *Server) listNamespacesHandler(c *gin.Context) {
+	namespaces := make([]runtime.NamespaceMetrics, 0)
+	for _, namespace := range s.engine.Namespaces() {
+		if canAccessNamespace(c, namespace.Namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"namespaces": namespaces,
+		"total":      len(namespaces),
+	})
+}
+
+func (s *Server) namespaceMetricsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.engine.GetNamespaceMetrics(c.Param("namespace")))
+}
+
+func (s *Server) namespaceQuotaHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.engine.NamespaceQuota(c.Param("namespace")))
+}
+
+func (s *Server) clusterQuotaHandler(c *gin.Context) {
+	status, err := s.engine.ClusterQuota(c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get cluster quota",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, status)
+}
+
+func (s *Server) coldStartsHandler(c *gin.Context) {
+	reports := s.engine.ColdStarts(runtime.ColdStartFilter{
+		Cluster: c.Query("cluster"),
+		Agent:   c.Query("agent"),
+	})
+	
+	c.JSON(http.StatusOK, gin.H{
+		"cold_starts": reports,
+		"timestamp":   time.Now().UTC(),
+	})
+}
+
+// File handlers
+func (s *Server) getFileHandler(c *gin.Context) {
+	file, data, err := s.engine.Files().Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get file",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	// Content came from arbitrary URLs; never let a browser render it inline
+	disposition := "attachment"
+	if file.Name != "" {
+		disposition = mime.FormatMediaType("attachment", map[string]string{"filename": file.Name})
+	}
+	c.Header("Content-Disposition", disposition)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Data(http.StatusOK, file.MimeType, data)
+}
+
+// In-flight request handlers
+func (s *Server) listActiveRequestsHandler(c *gin.Context) {
+	requests := s.engine.ActiveRequests()
+	
+	c.JSON(http.StatusOK, gin.H{
+		"requests": requests,
+		"count":    len(requests),
+	})
+}
+
+func (s *Server) cancelRequestHandler(c *gin.Context) {
+	id := c.Param("id")
+	
+	if err := s.engine.CancelRequest(id); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to cancel request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Request cancelled",
+		"id":      id,
+	})
+}
+
+// submitJobHandler queues a chat request to run in the background and
+// returns 202 with the job, whose status and result can be polled.
+func (s *Server) submitJobHandler(c *gin.Context) {
+	var chatRequest chatRequestBody
+	if err := c.ShouldBindJSON(&chatRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	clusterName, agentName, found := s.findAgent(c.Param("id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Agent not found",
+		})
+		return
+	}
+	
+	job, err := s.engine.SubmitJob(clusterName, agentName, newAgentRequest(&chatRequest))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to submit job",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusAccepted, job)
+}
+
+func (s *Server) listJobsHandler(c *gin.Context) {
+	clusterName, agentName, found := s.findAgent(c.Param("id"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "Agent not found",
+		})
+		return
+	}
+	
+	jobs := s.engine.Jobs(clusterName, agentName)
+	c.JSON(http.StatusOK, gin.H{
+		"jobs":  jobs,
+		"count": len(jobs),
+	})
+}
+
+func (s *Server) getJobHandler(c *gin.Context) {
+	job, err := s.engine.Job(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get job",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, job)
+}
+
+func (s *Server) cancelJobHandler(c *gin.Context) {
+	job, err := s.engine.CancelJob(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to cancel job",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, job)
+}
+
+func (s *Server) executeToolHandler(c *gin.Context) {
+	var call runtime.ToolCall
+	if err := c.ShouldBindJSON(&call); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid tool call",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	result, err := s.engine.ExecuteTool(c.Request.Context(), c.Param("id"), &call)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to call tool",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	// A tool set up for dry runs only is never called, whatever was asked
+	dryRun, _ := result.Metadata["dry_run"].(bool)
+	c.JSON(http.StatusOK, gin.H{
+		"tool":    call.Tool,
+		"dry_run": dryRun,
+		"result":  result,
+	})
+}
+
+func (s *Server) toolCallsHandler(c *gin.Context) {
+	filter := runtime.ToolCallFilter{
+		Tool:    c.Query("tool"),
+		Outcome: c.Query("outcome"),
+	}
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid since",
+				"details": err.Error(),
+			})
+			return
+		}
+		filter.Since = t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"details": "limit must be a positive integer",
+			})
+			return
+		}
+		filter.Limit = n
+	}
+	
+	calls := s.engine.ToolCalls(c.Param("id"), filter)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"tool_calls": calls,
+		"count":      len(calls),
+		"timestamp":  time.Now().UTC(),
+	})
+}
+
+func (s *Server) transcriptsHandler(c *gin.Context) {
+	var filter runtime.TranscriptFilter
+	if since := c.Query("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid since",
+				"details": err.Error(),
+			})
+			return
+		}
+		filter.Since = t
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"details": "limit must be a positive integer",
+			})
+			return
+		}
+		filter.Limit = n
+	}
+	
+	transcripts := s.engine.Transcripts(c.Param("id"), filter)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"transcripts": transcripts,
+		"count":       len(transcripts),
+		"timestamp":   time.Now().UTC(),
+	})
+}
+
+func (s *Server) getTranscriptHandler(c *gin.Context) {
+	transcript, err := s.engine.Transcript(c.Param("id"), c.Param("request_id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get transcript",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, transcript)
+}
+
+func (s *Server) guardrailEventsHandler(c *gin.Context) {
+	filter := runtime.GuardrailEventFilter{
+		Cluster: c.Query("cluster"),
+		Agent:   c.Query("agent"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"details": "limit must be a positive integer",
+			})
+			return
+		}
+		filter.Limit = n
+	}
+	
+	events := s.engine.GuardrailEvents(filter)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"events":    events,
+		"count":     len(events),
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// webhooksHandler lists the webhooks and how their deliveries went.
+func (s *Server) webhooksHandler(c *gin.Context) {
+	webhooks := s.engine.Webhooks()
+	c.JSON(http.StatusOK, gin.H{
+		"webhooks": webhooks,
+		"total":    len(webhooks),
+	})
+}
+
+func (s *Server) getWebhookHandler(c *gin.Context) {
+	webhook, err := s.engine.Webhook(c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// webhookBody is a webhook as it is set through the API, with durations
+// written as strings such as 30s.
+type webhookBody struct {
+	URL          string            `json:"url" binding:"required"`
+	Secret       string            `json:"secret" binding:"required"`
+	Events       []string          `json:"events,omitempty"`
+	Headers      map[string]string `json:"headers,omitempty"`
+	MaxRetries   int               `json:"max_retries,omitempty"`
+	RetryBackoff string            `json:"retry_backoff,omitempty"`
+	Timeout      string            `json:"timeout,omitempty"`
+}
+
+// setWebhookHandler adds a webhook or replaces the webhook of its name.
+func (s *Server) setWebhookHandler(c *gin.Context) {
+	var body webhookBody
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	retryBackoff, err := optionalDuration(body.RetryBackoff)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook",
+			"details": "invalid retry_backoff: " + err.Error(),
+		})
+		return
+	}
+	timeout, err := optionalDuration(body.Timeout)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid webhook",
+			"details": "invalid timeout: " + err.Error(),
+		})
+		return
+	}
+	
+	webhookConfig := config.WebhookConfig{
+		Name:         c.Param("name"),
+		URL:          body.URL,
+		Secret:       body.Secret,
+		Events:       body.Events,
+		Headers:      body.Headers,
+		MaxRetries:   body.MaxRetries,
+		RetryBackoff: retryBackoff,
+		Timeout:      timeout,
+	}
+	created, err := s.engine.SetWebhook(&webhookConfig)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to set webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"message": "Webhook set successfully",
+		"webhook": webhookConfig.Name,
+	})
+}
+
+// optionalDuration parses a duration such as 30s, which is zero when
+// value is empty.
+func optionalDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func (s *Server) deleteWebhookHandler(c *gin.Context) {
+	name := c.Param("name")
+	
+	if err := s.engine.RemoveWebhook(name); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to remove webhook",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Webhook removed successfully",
+		"webhook": name,
+	})
+}
+
+// deadLettersHandler lists the events webhooks could not be given.
+func (s *Server) deadLettersHandler(c *gin.Context) {
+	filter := runtime.DeadLetterFilter{
+		Webhook: c.Query("webhook"),
+	}
+	if limit := c.Query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"details": "limit must be a positive integer",
+			})
+			return
+		}
+		filter.Limit = n
+	}
+	
+	letters := s.engine.DeadLetters(filter)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"dead_letters": letters,
+		"count":        len(letters),
+		"timestamp":    time.Now().UTC(),
+	})
+}
+
+// eventSinksHandler reports on the sinks events are sent to.
+func (s *Server) eventSinksHandler(c *gin.Context) {
+	sinks := s.engine.EventSinks()
+	c.JSON(http.StatusOK, gin.H{
+		"sinks": sinks,
+		"total": len(sinks),
+	})
+}
+
+// Shared tool handlers
+func (s *Server) listSharedToolsHandler(c *gin.Context) {
+	tools := s.engine.SharedTools()
+	
+	c.JSON(http.StatusOK, gin.H{
+		"tools": tools,
+		"count": len(tools),
+	})
+}
+
+func (s *Server) setSharedToolHandler(c *gin.Context) {
+	var tool config.Tool
+	if err := c.ShouldBindJSON(&tool); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid tool",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	created, err := s.engine.SetSharedTool(&tool)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error":   "Failed to set tool",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"message": "Tool set successfully",
+		"tool":    tool.Name,
+	})
+}
+
+func (s *Server) deleteSharedToolHandler(c *gin.Context) {
+	name := c.Param("name")
+	
+	if err := s.engine.RemoveSharedTool(name); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to remove tool",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tool removed successfully",
+		"tool":    name,
+	})
+}
+
+// Session handlers
+type createSessionBody struct {
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+func (s *Server) createSessionHandler(c *gin.Context) {
+	var body createSessionBody
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid session",
+				"details": err.Error(),
+			})
+			return
+		}
+	}
+	
+	session, err := s.engine.CreateSession(c.Request.Context(), c.Param("id"), body.Metadata)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to create session",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, session)
+}
+
+func (s *Server) listSessionsHandler(c *gin.Context) {
+	list, err := s.engine.ListSessions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to list sessions",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	// Histories are left out; they are read one session at a time
+	summaries := make([]gin.H, 0, len(list))
+	for _, session := range list {
+		summaries = append(summaries, gin.H{
+			"id":            session.ID,
+			"cluster":       session.Cluster,
+			"agent":         session.Agent,
+			"metadata":      session.Metadata,
+			"message_count": len(session.Messages),
+			"created_at":    session.CreatedAt,
+			"updated_at":    session.UpdatedAt,
+			"expires_at":    session.ExpiresAt,
+		})
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": summaries,
+		"count":    len(summaries),
+	})
+}
+
+func (s *Server) getSessionHandler(c *gin.Context) {
+	session, err := s.engine.GetSession(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get session",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) deleteSessionHandler(c *gin.Context) {
+	id := c.Param("id")
+	
+	if err := s.engine.DeleteSession(c.Request.Context(), id); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to delete session",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Session deleted successfully",
+		"session": id,
+	})
+}
+
+func (s *Server) sessionChatHandler(c *gin.Context) {
+	var chatRequest chatRequestBody
+	if err := c.ShouldBindJSON(&chatRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid chat request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	resp, err := s.engine.SessionChat(c.Request.Context(), c.Param("id"), newAgentRequest(&chatRequest))
+	if err != nil {
+		s.logger.Error("Failed to process session request", zap.Error(err))
+		writeProcessError(c, err)
+		return
+	}
+	
+	if resp.Error != "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": resp.Error,
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, resp)
+}
+
+func (s *Server) getAgentStateHandler(c *gin.Context) {
+	state, err := s.engine.AgentState(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get agent state",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"state": state,
+	})
+}
+
+func (s *Server) getAgentBudgetHandler(c *gin.Context) {
+	status, err := s.engine.AgentBudget(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get agent budget",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, status)
+}
+
+func (s *Server) getAgentExperimentHandler(c *gin.Context) {
+	status, err := s.engine.AgentExperiment(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get agent experiment",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, status)
+}
+
+func (s *Server) getAgentInstancesHandler(c *gin.Context) {
+	status, err := s.engine.AgentInstances(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get agent instances",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, status)
+}
+
+func (s *Server) setAgentStateHandler(c *gin.Context) {
+	var body struct {
+		State map[string]interface{} `json:"state" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid agent state",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	if err := s.engine.SetAgentState(c.Request.Context(), c.Param("id"), body.State); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to set agent state",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"state": body.State,
+	})
+}
+
+func (s *Server) listMemoriesHandler(c *gin.Context) {
+	memories, err := s.engine.Memories(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to list memories",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"memories": memories,
+		"count":    len(memories),
+	})
+}
+
+// ingestDocumentHandler adds a document to an agent's knowledge base, from
+// a multipart upload in the file field or from a JSON body naming a
+// file_id, url or content.
+func (s *Server) ingestDocumentHandler(c *gin.Context) {
+	var input runtime.DocumentInput
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid document",
+				"details": err.Error(),
+			})
+			return
+		}
+		file, err := header.Open()
+		if err == nil {
+			input.Data, err = io.ReadAll(file)
+			file.Close()
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid document",
+				"details": err.Error(),
+			})
+			return
+		}
+		input.Name = header.Filename
+		input.MimeType = header.Header.Get("Content-Type")
+		input.Title = c.PostForm("title")
+	} else if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid document",
+			"details": err.Error(),
+		})
+		return
+	}
+	if input.Data == nil && input.FileID == "" && input.URL == "" && input.Content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid document",
+			"details": "a file, file_id, url or content is required",
+		})
+		return
+	}
+	
+	doc, err := s.engine.IngestDocument(c.Request.Context(), c.Param("id"), &input)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to ingest document",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, doc)
+}
+
+func (s *Server) listDocumentsHandler(c *gin.Context) {
+	docs, err := s.engine.Documents(c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to list documents",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"documents": docs,
+		"count":     len(docs),
+	})
+}
+
+func (s *Server) deleteDocumentHandler(c *gin.Context) {
+	id := c.Param("document")
+	
+	if err := s.engine.DeleteDocument(c.Param("id"), id); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to delete document",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Document deleted successfully",
+		"document": id,
+	})
+}
+
+// searchKnowledgeHandler returns what would be retrieved from an agent's
+// knowledge base for a query.
+func (s *Server) searchKnowledgeHandler(c *gin.Context) {
+	var body struct {
+		Query string `json:"query" binding:"required"`
+		TopK  int    `json:"top_k,omitempty"`
+	}
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid search request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	matches, err := s.engine.SearchKnowledge(c.Request.Context(), c.Param("id"), body.Query, body.TopK)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to search knowledge base",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"matches": matches,
+		"count":   len(matches),
+	})
+}
+
+// deleteMemoryHandler forgets one memory, or all of the agent's memories
+// when no memory is named.
+func (s *Server) deleteMemoryHandler(c *gin.Context) {
+	if err := s.engine.DeleteMemory(c.Param("id"), c.Param("memory")); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to delete memory",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Memory deleted successfully",
+	})
+}
+
+// Feedback handlers
+func (s *Server) submitFeedbackHandler(c *gin.Context) {
+	responseID := c.Param("id")
+	
+	var input runtime.FeedbackInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid feedback",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	feedback, err := s.engine.SubmitFeedback(responseID, &input)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to submit feedback",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, feedback)
+}
+
+func (s *Server) getResponseFeedbackHandler(c *gin.Context) {
+	feedback := s.engine.ResponseFeedback(c.Param("id"))
+	
+	c.JSON(http.StatusOK, gin.H{
+		"feedback": feedback,
+		"count":    len(feedback),
+	})
+}
+
+func (s *Server) feedbackSummaryHandler(c *gin.Context) {
+	summaries := s.engine.FeedbackSummaries(runtime.FeedbackFilter{
+		Cluster: c.Query("cluster"),
+		Agent:   c.Query("agent"),
+	})
+	
+	c.JSON(http.StatusOK, gin.H{
+		"summaries": summaries,
+		"count":     len(summaries),
+		"timestamp": time.Now().UTC(),
+	})
+}
+
+// Provider handlers
+func (s *Server) listProvidersHandler(c *gin.Context) {
+	credentials := s.engine.ProviderCredentials()
+	
+	names := make([]string, 0, len(credentials))
+	for name := range credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	providers := make([]gin.H, 0, len(names))
+	for _, name := range names {
+		keys := credentials[name]
+		available := false
+		for _, key := range keys {
+			if key.Valid {
+				available = true
+				break
+			}
+		}
+		
+		providers = append(providers, gin.H{
+			"name":        name,
+			"available":   available,
+			"credentials": keys,
+		})
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"providers": providers,
+		"count":     len(providers),
+	})
+}
+
+// Tenant credential handlers
+func (s *Server) listTenantCredentialsHandler(c *gin.Context) {
+	namespace := c.Param("namespace")
+	
+	credentials, err := s.engine.TenantCredentials(namespace)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error": "Failed to list credentials",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"namespace":   namespace,
+		"credentials": credentials,
+	})
+}
+
+func (s *Server) setTenantCredentialHandler(c *gin.Context) {
+	namespace := c.Param("namespace")
+	provider := c.Param("provider")
+	
+	var credentialRequest struct {
+		APIKey        string   `json:"api_key" binding:"required"`
+		BackupAPIKeys []string `json:"backup_api_keys,omitempty"`
+	}
+	
+	if err := c.ShouldBindJSON(&credentialRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid credential request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	apiKeys := append([]string{credentialRequest.APIKey}, credentialRequest.BackupAPIKeys...)
+	if err := s.engine.SetTenantCredential(namespace, provider, apiKeys); err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to store credential",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Credential stored successfully",
+		"namespace": namespace,
+		"provider":  provider,
+	})
+}
+
+func (s *Server) deleteTenantCredentialHandler(c *gin.Context) {
+	namespace := c.Param("namespace")
+	provider := c.Param("provider")
+	
+	if err := s.engine.DeleteTenantCredential(namespace, provider); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error": "Failed to delete credential",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Credential deleted successfully",
+		"namespace": namespace,
+		"provider":  provider,
+	})
+}
+
+// System info handler
+func (s *Server) infoHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"name":        "GoAgents",
 		"version":     "1.0.0",
 		"description": "AI Agent Orchestration Platform",
 		"api_version": "v1",
@@ -381,6 +2079,7 @@ func (s *Server) infoHandler(c *gin.Context) {
 			"ready":     "/ready",
 			"clusters":  "/api/v1/clusters",
 			"agents":    "/api/v1/agents",
+			"providers": "/api/v1/providers",
 			"metrics":   "/api/v1/metrics",
 			"prometheus": s.config.Server.Metrics.Path,
 		},
@@ -393,5 +2092,128 @@ func (s *Server) infoHandler(c *gin.Context) {
 		},
 		"providers": []string{"anthropic", "openai", "gemini"},
 		"tools":     []string{"http", "websocket", "mcp"},
+		"gateways":  []string{"discord", "teams"},
+	})
+}
+
+// Gateway handlers
+func (s *Server) teamsMessagesHandler(c *gin.Context) {
+	if s.gateways == nil || s.gateways.TeamsHandler() == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Teams gateway is not configured"})
+		return
+	}
+	
+	s.gateways.TeamsHandler().ServeHTTP(c.Writer, c.Request)
+}
+
+// Admin handlers
+func (s *Server) getReadOnlyHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"read_only": s.IsReadOnly(),
+	})
+}
+
+func (s *Server) setReadOnlyHandler(c *gin.Context) {
+	var readOnlyRequest struct {
+		Enabled *bool `json:"enabled" binding:"required"`
+	}
+	
+	if err := c.ShouldBindJSON(&readOnlyRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid read-only request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	s.SetReadOnly(*readOnlyRequest.Enabled)
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Read-only mode updated",
+		"read_only": s.IsReadOnly(),
+	})
+}
+
+// listFeaturesHandler lists flags. Given a cluster, and optionally an agent,
+// it also reports whether each flag is in effect for them.
+func (s *Server) listFeaturesHandler(c *gin.Context) {
+	features := s.engine.FeatureFlags()
+	response := gin.H{
+		"features": features,
+		"total":    len(features),
+	}
+	
+	if clusterName := c.Query("cluster"); clusterName != "" {
+		effective := make(map[string]bool, len(features))
+		for _, flag := range features {
+			effective[flag.Name] = s.engine.FeatureEnabled(flag.Name, clusterName, c.Query("agent"))
+		}
+		response["effective"] = effective
+	}
+	
+	c.JSON(http.StatusOK, response)
+}
+
+func (s *Server) getFeatureHandler(c *gin.Context) {
+	flag, err := s.engine.FeatureFlag(c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error": "Feature flag not found",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, flag)
+}
+
+// setFeatureHandler sets a flag's default, or an override when the body
+// names a cluster and optionally an agent.
+func (s *Server) setFeatureHandler(c *gin.Context) {
+	var featureRequest struct {
+		Enabled *bool  `json:"enabled" binding:"required"`
+		Cluster string `json:"cluster,omitempty"`
+		Agent   string `json:"agent,omitempty"`
+	}
+	
+	if err := c.ShouldBindJSON(&featureRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "Invalid feature flag request",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	flag, err := s.engine.SetFeatureFlag(c.Param("name"), &runtime.FeatureFlagUpdate{
+		Enabled: *featureRequest.Enabled,
+		Cluster: featureRequest.Cluster,
+		Agent:   featureRequest.Agent,
+	})
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to update feature flag",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, flag)
+}
+
+// deleteFeatureHandler removes the override named by the cluster and agent
+// query parameters, or the whole flag when no cluster is given.
+func (s *Server) deleteFeatureHandler(c *gin.Context) {
+	name := c.Param("name")
+	if err := s.engine.DeleteFeatureFlag(name, c.Query("cluster"), c.Query("agent")); err != nil {
+		c.JSON(errorStatus(err, http.StatusBadRequest), gin.H{
+			"error": "Failed to delete feature flag",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Feature flag deleted",
+		"name":    name,
 	})
 }
\ No newline at end of file
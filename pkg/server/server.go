@@ -3,22 +3,31 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/gateway"
+	"github.com/goagents/goagents/pkg/mcpserver"
 	"github.com/goagents/goagents/pkg/runtime"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/goagents/goagents/pkg/service"
 	"go.uber.org/zap"
 )
 
 type Server struct {
-	config *config.Config
-	engine *runtime.Engine
-	logger *zap.Logger
-	router *gin.Engine
-	server *http.Server
+	config   *config.Config
+	engine   *runtime.Engine
+	logger   *zap.Logger
+	router   *gin.Engine
+	server   *http.Server
+	gateways *gateway.Manager
+	apiKeys  *apiKeyStore
+	readOnly atomic.Bool
+	draining atomic.Bool
 }
 
 func NewServer(cfg *config.Config, engine *runtime.Engine, logger *zap.Logger) *Server {
@@ -32,14 +41,18 @@ func NewServer(cfg *config.Config, engine *runtime.Engine, logger *zap.Logger) *
 	router := gin.New()
 	
 	s := &Server{
-		config: cfg,
-		engine: engine,
-		logger: logger,
-		router: router,
+		config:  cfg,
+		engine:  engine,
+		logger:  logger,
+		router:  router,
+		apiKeys: newAPIKeyStore(cfg.Security.Auth.APIKeys),
 	}
 	
-	s.setupRoutes()
+	s.readOnly.Store(cfg.Server.ReadOnly)
+	
+	// Middleware must be registered before routes for gin to apply it
 	s.setupMiddleware()
+	s.setupRoutes()
 	
 	return s
 }
@@ -66,11 +79,16 @@ func (s *Server) setupMiddleware() {
 	// Recovery middleware
 	s.router.Use(gin.Recovery())
 	
+	// Tracing middleware
+	if s.config.Server.Tracing.Enabled {
+		s.router.Use(s.tracingMiddleware())
+	}
+	
 	// CORS middleware
 	s.router.Use(func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, X-API-Key")
 		
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -79,6 +97,84 @@ func (s *Server) setupMiddleware() {
 		
 		c.Next()
 	})
+	
+	// API key middleware
+	s.router.Use(s.authMiddleware())
+	
+	// Read-only middleware
+	s.router.Use(s.readOnlyMiddleware())
+	
+	// Forwarding to the node that runs the target, in distributed mode
+	s.router.Use(s.forwardMiddleware())
+	
+	// Refusing new agent work while draining
+	s.router.Use(s.drainMiddleware())
+}
+
+// Routes that stay writable in read-only mode: agent traffic is data plane,
+// the toggle itself must remain reachable to leave read-only mode, and
+// feature flags are kill switches that may be needed during an incident.
+var readOnlyExemptRoutes = map[string]bool{
+	"/api/v1/agents/:id/chat":                        true,
+	"/api/v1/agents/:id/stream":                      true,
+	"/api/v1/agents/:id/jobs":                        true,
+	"/api/v1/agents/:id/sessions":                    true,
+	"/api/v1/agents/:id/state":                       true,
+	"/api/v1/agents/:id/memories":                    true,
+	"/api/v1/agents/:id/memories/:memory":            true,
+	"/api/v1/agents/:id/knowledge/search":            true,
+	"/api/v1/sessions/:id":                           true,
+	"/api/v1/sessions/:id/chat":                      true,
+	"/api/v1/clusters/:name/diff":                    true,
+	"/api/v1/clusters/:name/workflows/:workflow/run": true,
+	"/api/v1/clusters/:name/schedules/:schedule/run": true,
+	"/api/v1/admin/read-only":                        true,
+	"/api/v1/admin/drain":                            true,
+	"/api/v1/gateways/teams/messages":                true,
+	"/api/v1/requests/active/:id":                    true,
+	"/api/v1/jobs/:id":                               true,
+	"/api/v1/responses/:id/feedback":                 true,
+	"/mcp":                                           true,
+}
+
+func (s *Server) readOnlyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !s.readOnly.Load() {
+			c.Next()
+			return
+		}
+		
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		
+		if readOnlyExemptRoutes[c.FullPath()] {
+			c.Next()
+			return
+		}
+		// Dry runs of cluster updates change nothing
+		dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+		if dryRun && c.FullPath() == "/api/v1/clusters/:name" && c.Request.Method != http.MethodDelete {
+			c.Next()
+			return
+		}
+		
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Server is in read-only mode",
+			"details": "mutating requests are rejected until read-only mode is disabled",
+		})
+	}
+}
+
+func (s *Server) SetReadOnly(enabled bool) {
+	s.readOnly.Store(enabled)
+	s.logger.Info("Read-only mode changed", zap.Bool("enabled", enabled))
+}
+
+func (s *Server) IsReadOnly() bool {
+	return s.readOnly.Load()
 }
 
 func (s *Server) setupRoutes() {
@@ -94,9 +190,43 @@ func (s *Server) setupRoutes() {
 		{
 			clusters.GET("", s.listClustersHandler)
 			clusters.POST("", s.createClusterHandler)
+			clusters.POST("/import", s.importClusterHandler)
 			clusters.GET("/:name", s.getClusterHandler)
+			clusters.PUT("/:name", s.updateClusterHandler)
+			clusters.PATCH("/:name", s.patchClusterHandler)
 			clusters.DELETE("/:name", s.deleteClusterHandler)
+			clusters.POST("/:name/diff", s.diffClusterHandler)
+			clusters.GET("/:name/export", s.exportClusterHandler)
 			clusters.POST("/:name/scale", s.scaleClusterHandler)
+			clusters.GET("/:name/quota", s.clusterQuotaHandler)
+			clusters.POST("/:name/agents/:agent/clone", s.cloneAgentHandler)
+			clusters.POST("/:name/agents/:agent/rename", s.renameAgentHandler)
+			clusters.POST("/:name/agents/:agent/tools", s.setAgentToolHandler)
+			clusters.DELETE("/:name/agents/:agent/tools/:tool", s.deleteAgentToolHandler)
+			clusters.GET("/:name/agents/:agent/versions", s.agentVersionsHandler)
+			clusters.POST("/:name/agents/:agent/rollout", s.startRolloutHandler)
+			clusters.PUT("/:name/agents/:agent/rollout", s.shiftRolloutHandler)
+			clusters.POST("/:name/agents/:agent/rollout/promote", s.promoteRolloutHandler)
+			clusters.POST("/:name/agents/:agent/rollback", s.rollbackAgentHandler)
+			clusters.GET("/:name/workflows", s.listWorkflowsHandler)
+			clusters.POST("/:name/workflows/:workflow/run", s.runWorkflowHandler)
+			clusters.GET("/:name/workflows/:workflow/runs", s.listWorkflowRunsHandler)
+			clusters.GET("/:name/workflows/:workflow/runs/:run", s.getWorkflowRunHandler)
+			clusters.GET("/:name/schedules", s.listSchedulesHandler)
+			clusters.POST("/:name/schedules/:schedule/run", s.runScheduleHandler)
+			clusters.GET("/:name/schedules/:schedule/runs", s.listScheduleRunsHandler)
+			clusters.GET("/:name/schedules/:schedule/runs/:run", s.getScheduleRunHandler)
+		}
+		
+		// Cluster templates
+		templates := v1.Group("/templates")
+		{
+			templates.GET("", s.listTemplatesHandler)
+			templates.POST("", s.createTemplateHandler)
+			templates.GET("/:name", s.getTemplateHandler)
+			templates.PUT("/:name", s.updateTemplateHandler)
+			templates.DELETE("/:name", s.deleteTemplateHandler)
+			templates.POST("/:name/instantiate", s.instantiateTemplateHandler)
 		}
 		
 		// Agent management
@@ -106,18 +236,140 @@ func (s *Server) setupRoutes() {
 			agents.GET("/:id", s.getAgentHandler)
 			agents.POST("/:id/chat", s.chatHandler)
 			agents.POST("/:id/stream", s.streamHandler)
+			agents.POST("/:id/jobs", s.submitJobHandler)
+			agents.GET("/:id/jobs", s.listJobsHandler)
+			agents.GET("/:id/tool-calls", s.toolCallsHandler)
+			agents.POST("/:id/tool-calls", s.executeToolHandler)
+			agents.GET("/:id/transcripts", s.transcriptsHandler)
+			agents.GET("/:id/transcripts/:request_id", s.getTranscriptHandler)
+			agents.POST("/:id/sessions", s.createSessionHandler)
+			agents.GET("/:id/sessions", s.listSessionsHandler)
+			agents.GET("/:id/state", s.getAgentStateHandler)
+			agents.PUT("/:id/state", s.setAgentStateHandler)
+			agents.GET("/:id/budget", s.getAgentBudgetHandler)
+			agents.GET("/:id/experiment", s.getAgentExperimentHandler)
+			agents.GET("/:id/instances", s.getAgentInstancesHandler)
+			agents.GET("/:id/memories", s.listMemoriesHandler)
+			agents.DELETE("/:id/memories", s.deleteMemoryHandler)
+			agents.DELETE("/:id/memories/:memory", s.deleteMemoryHandler)
+			agents.POST("/:id/knowledge/documents", s.ingestDocumentHandler)
+			agents.GET("/:id/knowledge/documents", s.listDocumentsHandler)
+			agents.DELETE("/:id/knowledge/documents/:document", s.deleteDocumentHandler)
+			agents.POST("/:id/knowledge/search", s.searchKnowledgeHandler)
+		}
+		
+		// Conversations whose history the server keeps
+		sessions := v1.Group("/sessions")
+		{
+			sessions.GET("/:id", s.getSessionHandler)
+			sessions.DELETE("/:id", s.deleteSessionHandler)
+			sessions.POST("/:id/chat", s.sessionChatHandler)
+		}
+		
+		// Requests run in the background
+		jobs := v1.Group("/jobs")
+		{
+			jobs.GET("/:id", s.getJobHandler)
+			jobs.GET("/:id/events", s.jobEventsHandler)
+			jobs.DELETE("/:id", s.cancelJobHandler)
+		}
+		
+		// Tools shared by every agent
+		sharedTools := v1.Group("/tools")
+		{
+			sharedTools.GET("", s.listSharedToolsHandler)
+			sharedTools.POST("", s.setSharedToolHandler)
+			sharedTools.DELETE("/:name", s.deleteSharedToolHandler)
+		}
+		
+		// Guardrail violations
+		v1.GET("/guardrails/events", s.guardrailEventsHandler)
+		
+		// Sinks of engine, cluster, agent and request events
+		v1.GET("/events/sinks", s.eventSinksHandler)
+		
+		// Webhooks given signed lifecycle events
+		v1.GET("/webhooks", s.webhooksHandler)
+		v1.GET("/webhooks/dead-letters", s.deadLettersHandler)
+		v1.GET("/webhooks/:name", s.getWebhookHandler)
+		v1.PUT("/webhooks/:name", s.setWebhookHandler)
+		v1.DELETE("/webhooks/:name", s.deleteWebhookHandler)
+		
+		// Files returned by tools
+		v1.GET("/files/:id", s.getFileHandler)
+		
+		// In-flight requests
+		requests := v1.Group("/requests")
+		{
+			requests.GET("/active", s.listActiveRequestsHandler)
+			requests.DELETE("/active/:id", s.cancelRequestHandler)
+		}
+		
+		// Feedback on responses
+		v1.POST("/responses/:id/feedback", s.submitFeedbackHandler)
+		v1.GET("/responses/:id/feedback", s.getResponseFeedbackHandler)
+		v1.GET("/feedback", s.feedbackSummaryHandler)
+		
+		// Provider credentials
+		v1.GET("/providers", s.listProvidersHandler)
+		
+		// Namespaces
+		v1.GET("/namespaces", s.listNamespacesHandler)
+		v1.GET("/namespaces/:namespace/metrics", s.namespaceMetricsHandler)
+		v1.GET("/namespaces/:namespace/quota", s.namespaceQuotaHandler)
+		
+		// Tenant provider credentials
+		credentials := v1.Group("/namespaces/:namespace/credentials")
+		{
+			credentials.GET("", s.listTenantCredentialsHandler)
+			credentials.PUT("/:provider", s.setTenantCredentialHandler)
+			credentials.DELETE("/:provider", s.deleteTenantCredentialHandler)
 		}
 		
 		// Metrics
 		v1.GET("/metrics", s.metricsHandler)
+		v1.GET("/metrics/cold-starts", s.coldStartsHandler)
 		
 		// System info
 		v1.GET("/info", s.infoHandler)
+		v1.GET("/nodes", s.nodesHandler)
+		
+		// Chat gateways
+		v1.POST("/gateways/teams/messages", s.teamsMessagesHandler)
+		
+		// Administration
+		admin := v1.Group("/admin")
+		{
+			admin.GET("/read-only", s.getReadOnlyHandler)
+			admin.PUT("/read-only", s.setReadOnlyHandler)
+			admin.GET("/drain", s.getDrainHandler)
+			admin.POST("/drain", s.startDrainHandler)
+			admin.DELETE("/drain", s.stopDrainHandler)
+			admin.GET("/features", s.listFeaturesHandler)
+			admin.GET("/features/:name", s.getFeatureHandler)
+			admin.PUT("/features/:name", s.setFeatureHandler)
+			admin.DELETE("/features/:name", s.deleteFeatureHandler)
+			admin.GET("/api-keys", s.listAPIKeysHandler)
+			admin.POST("/api-keys", s.createAPIKeyHandler)
+			admin.GET("/api-keys/:name", s.getAPIKeyHandler)
+			admin.PUT("/api-keys/:name", s.updateAPIKeyHandler)
+			admin.DELETE("/api-keys/:name", s.deleteAPIKeyHandler)
+		}
+	}
+	
+	// Agents published as MCP tools
+	if s.config.Server.MCP.Enabled {
+		mcp := gin.WrapH(mcpserver.NewServer(s.engine, &mcpserver.Config{
+			Timeout: s.config.Server.MCP.Timeout,
+		}, s.logger))
+		s.router.POST("/mcp", mcp)
+		s.router.GET("/mcp", mcp)
+		s.router.DELETE("/mcp", mcp)
 	}
 	
 	// Metrics endpoint for Prometheus
 	if s.config.Server.Metrics.Enabled {
-		s.router.GET(s.config.Server.Metrics.Path, gin.WrapH(promhttp.Handler()))
+		s.router.GET(s.config.Server.Metrics.Path, gin.WrapH(s.engine.PrometheusHandler()))
 	}
 }
 
@@ -132,25 +384,64 @@ func (s *Server) Start(ctx context.Context) error {
 		IdleTimeout:  120 * time.Second,
 	}
 	
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	
+	gateways, err := gateway.NewManager(&s.config.Gateways, s.engine, s.logger)
+	if err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to create gateways: %w", err)
+	}
+	s.gateways = gateways
+	
 	s.logger.Info("Starting HTTP server", zap.String("addr", addr))
 	
 	// Start server in a goroutine
 	errCh := make(chan error, 1)
 	go func() {
-		if err := s.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
 			errCh <- err
 		}
 	}()
 	
+	// The listener is bound, so the service manager can route traffic to us
+	service.Ready()
+	go service.Watchdog(ctx, func() bool {
+		return !s.draining.Load()
+	})
+	
+	if err := s.gateways.Start(ctx); err != nil {
+		s.logger.Error("Failed to start chat gateways", zap.Error(err))
+	}
+	defer s.gateways.Close()
+	
 	// Wait for context cancellation or server error
 	select {
 	case <-ctx.Done():
 		s.logger.Info("Shutting down HTTP server")
+		service.Stopping()
+		
+		// Fail readiness checks first so load balancers stop sending traffic
+		// while in-flight requests finish
+		s.draining.Store(true)
+		s.engine.StartDrain()
 		
 		// Graceful shutdown with timeout
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		shutdownTimeout := s.config.Server.ShutdownTimeout
+		if shutdownTimeout <= 0 {
+			shutdownTimeout = 30 * time.Second
+		}
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 		defer cancel()
 		
+		// New agent work is refused; wait for the requests and jobs already
+		// taken before closing connections
+		if err := s.engine.Drain(shutdownCtx); err != nil {
+			s.logger.Warn("Shutting down before the engine drained", zap.Error(err))
+		}
+		
 		if err := s.server.Shutdown(shutdownCtx); err != nil {
 			s.logger.Error("Failed to shutdown server gracefully", zap.Error(err))
 			return err
@@ -158,7 +449,7 @@ func (s *Server) Start(ctx context.Context) error {
 		
 		s.logger.Info("HTTP server stopped")
 		return nil
-		
+	
 	case err := <-errCh:
 		return fmt.Errorf("server error: %w", err)
 	}
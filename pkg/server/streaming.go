@@ -0,0 +1,147 @@
+package server
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goagents/goagents/pkg/agent"
+	"go.uber.org/zap"
+)
+
+type responseFormat string
+
+const (
+	formatJSON   responseFormat = "application/json"
+	formatSSE    responseFormat = "text/event-stream"
+	formatNDJSON responseFormat = "application/x-ndjson"
+)
+
+// negotiateFormat picks the response format from the Accept header, honoring
+// q-values. Wildcards and unknown types resolve to the fallback.
+func negotiateFormat(c *gin.Context, fallback responseFormat) responseFormat {
+	accept := c.GetHeader("Accept")
+	if accept == "" {
+		return fallback
+	}
+	
+	best := fallback
+	bestQ := -1.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		
+		var format responseFormat
+		switch mediaType {
+		case string(formatJSON):
+			format = formatJSON
+		case string(formatSSE):
+			format = formatSSE
+		case string(formatNDJSON), "application/ndjson", "application/jsonl":
+			format = formatNDJSON
+		default:
+			continue
+		}
+		
+		q := 1.0
+		if qValue, ok := params["q"]; ok {
+			parsed, err := strconv.ParseFloat(qValue, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		
+		if q > bestQ {
+			best = format
+			bestQ = q
+		}
+	}
+	
+	return best
+}
+
+func (s *Server) streamResponse(c *gin.Context, clusterName, agentName string, req *agent.Request, format responseFormat) {
+	chunks, err := s.engine.StreamRequest(c.Request.Context(), clusterName, agentName, req)
+	if err != nil {
+		s.logger.Error("Failed to start stream", zap.Error(err))
+		writeProcessError(c, err)
+		return
+	}
+	
+	c.Header("Content-Type", string(format))
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Request-ID", req.ID)
+	if format == formatSSE {
+		c.Header("Connection", "keep-alive")
+	}
+	c.Status(http.StatusOK)
+	
+	for chunk := range chunks {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			s.logger.Warn("Failed to encode stream chunk", zap.Error(err))
+			continue
+		}
+		
+		switch format {
+		case formatSSE:
+			event := "message"
+			if chunk.Error != "" {
+				event = "error"
+			}
+			c.SSEvent(event, string(data))
+		case formatNDJSON:
+			c.Writer.Write(append(data, '\n'))
+		}
+		c.Writer.Flush()
+	}
+}
+
+// jobEventsHandler streams a job's progress events as server-sent events,
+// or as NDJSON when the client asks for it, until the job finishes.
+func (s *Server) jobEventsHandler(c *gin.Context) {
+	events, err := s.engine.JobEvents(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to stream job events",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	format := negotiateFormat(c, formatSSE)
+	if format == formatJSON {
+		format = formatSSE
+	}
+	c.Header("Content-Type", string(format))
+	c.Header("Cache-Control", "no-cache")
+	if format == formatSSE {
+		c.Header("Connection", "keep-alive")
+	}
+	c.Status(http.StatusOK)
+	
+	for event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			s.logger.Warn("Failed to encode job event", zap.Error(err))
+			continue
+		}
+		
+		switch format {
+		case formatSSE:
+			c.SSEvent(event.Type, string(data))
+		case formatNDJSON:
+			c.Writer.Write(append(data, '\n'))
+		}
+		c.Writer.Flush()
+	}
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/goagents/goagents/pkg/runtime"
+	"go.uber.org/zap"
+)
+
+func (s *Server) listTemplatesHandler(c *gin.Context) {
+	templates, err := s.engine.ListTemplates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to list templates",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"templates": templates,
+		"total":     len(templates),
+	})
+}
+
+// createTemplateHandler adds a cluster template, written in YAML or JSON
+// as bindSpec reads it.
+func (s *Server) createTemplateHandler(c *gin.Context) {
+	var tmpl config.ClusterTemplate
+	if err := bindSpec(c, &tmpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid template",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	saved, err := s.engine.CreateTemplate(&tmpl)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to create template",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, saved)
+}
+
+func (s *Server) getTemplateHandler(c *gin.Context) {
+	tmpl, err := s.engine.GetTemplate(c.Param("name"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to get template",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// updateTemplateHandler replaces a cluster template. The body may leave
+// out the name, which is taken from the path.
+func (s *Server) updateTemplateHandler(c *gin.Context) {
+	name := c.Param("name")
+	
+	var tmpl config.ClusterTemplate
+	if err := bindSpec(c, &tmpl); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid template",
+			"details": err.Error(),
+		})
+		return
+	}
+	if tmpl.Name == "" {
+		tmpl.Name = name
+	}
+	if tmpl.Name != name {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid template",
+			"details": "template is named " + tmpl.Name + ", not " + name,
+		})
+		return
+	}
+	
+	saved, err := s.engine.UpdateTemplate(&tmpl)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to update template",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, saved)
+}
+
+func (s *Server) deleteTemplateHandler(c *gin.Context) {
+	name := c.Param("name")
+	
+	if err := s.engine.DeleteTemplate(name); err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to delete template",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Template deleted successfully",
+		"name":    name,
+	})
+}
+
+// instantiateTemplateHandler deploys a cluster from a template, or with
+// dry_run=true returns the cluster it would deploy.
+func (s *Server) instantiateTemplateHandler(c *gin.Context) {
+	var instance runtime.TemplateInstance
+	if err := c.ShouldBindJSON(&instance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid template instance",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	cluster, err := s.engine.RenderTemplate(c.Param("name"), &instance)
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to instantiate template",
+			"details": err.Error(),
+		})
+		return
+	}
+	if !canAccessNamespace(c, cluster.Metadata.Namespace) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Failed to instantiate template",
+			"details": "API key may not access namespace " + cluster.Metadata.Namespace,
+		})
+		return
+	}
+	
+	if dryRun, _ := strconv.ParseBool(c.Query("dry_run")); dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run": true,
+			"cluster": cluster,
+		})
+		return
+	}
+	
+	if err := s.engine.DeployCluster(cluster); err != nil {
+		s.logger.Error("Failed to instantiate template", zap.Error(err))
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{
+			"error":   "Failed to instantiate template",
+			"details": err.Error(),
+		})
+		return
+	}
+	
+	c.JSON(http.StatusCreated, gin.H{
+		"message":          "Cluster created successfully",
+		"name":             runtime.ClusterID(cluster.Metadata.Namespace, cluster.Metadata.Name),
+		"namespace":        cluster.Metadata.Namespace,
+		"resource_version": cluster.Metadata.ResourceVersion,
+		"cluster":          cluster,
+	})
+}
\ No newline at end of file
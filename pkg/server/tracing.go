@@ -0,0 +1,53 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracingMiddleware starts the span of each API request, which the spans
+// of the engine's work for it are children of. A caller's W3C traceparent
+// header is continued. Health checks and metrics scrapes are not traced.
+func (s *Server) tracingMiddleware() gin.HandlerFunc {
+	tracer := s.engine.Tracer()
+	propagator := propagation.TraceContext{}
+	untraced := map[string]bool{
+		"/health":                    true,
+		"/ready":                     true,
+		s.config.Server.Metrics.Path: true,
+	}
+	
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if untraced[route] {
+			c.Next()
+			return
+		}
+		
+		name := c.Request.Method
+		if route != "" {
+			name += " " + route
+		}
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.String("url.path", c.Request.URL.Path),
+		))
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+		
+		c.Next()
+		
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.response.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
\ No newline at end of file
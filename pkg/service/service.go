@@ -0,0 +1,73 @@
+// Package service integrates the server process with OS service managers:
+// readiness and watchdog notifications for systemd and the service control
+// manager on Windows.
+package service
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+type RunFunc func(ctx context.Context) error
+
+var (
+	ready     = make(chan struct{})
+	readyOnce sync.Once
+)
+
+// Run calls run with a context that is cancelled on SIGINT or SIGTERM, or
+// when the Windows service control manager asks the service to stop. The
+// name is the Windows service name and is ignored elsewhere.
+func Run(name string, run RunFunc) error {
+	if isWindowsService() {
+		return runWindowsService(name, run)
+	}
+	
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	
+	return run(ctx)
+}
+
+// Ready reports that startup has finished and the service is accepting
+// requests.
+func Ready() {
+	readyOnce.Do(func() {
+		close(ready)
+	})
+	sdNotify("READY=1")
+}
+
+// Stopping reports that the service has begun shutting down.
+func Stopping() {
+	sdNotify("STOPPING=1")
+}
+
+// Watchdog pings the systemd watchdog at half the interval configured with
+// WatchdogSec until ctx is done. Pings are skipped while healthy returns
+// false so that systemd restarts a wedged process. It returns immediately
+// when no watchdog is configured.
+func Watchdog(ctx context.Context, healthy func() bool) {
+	interval := watchdogInterval()
+	if interval <= 0 {
+		return
+	}
+	
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if healthy == nil || healthy() {
+				sdNotify("WATCHDOG=1")
+			}
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"time"
+
+	"github.com/coreos/go-systemd/v22/daemon"
+)
+
+func sdNotify(state string) {
+	// Not running under systemd, or NOTIFY_SOCKET is unset
+	daemon.SdNotify(false, state)
+}
+
+func watchdogInterval() time.Duration {
+	interval, err := daemon.SdWatchdogEnabled(false)
+	if err != nil {
+		return 0
+	}
+	return interval
+}
\ No newline at end of file
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package service
+
+import "time"
+
+func sdNotify(state string) {}
+
+func watchdogInterval() time.Duration {
+	return 0
+}
\ No newline at end of file
@@ -0,0 +1,58 @@
+//go:build windows
+
+package service
+
+import (
+	"context"
+
+	"golang.org/x/sys/windows/svc"
+)
+
+func isWindowsService() bool {
+	isService, err := svc.IsWindowsService()
+	return err == nil && isService
+}
+
+func runWindowsService(name string, run RunFunc) error {
+	return svc.Run(name, &windowsService{run: run})
+}
+
+type windowsService struct {
+	run RunFunc
+}
+
+// Execute reports StartPending until Ready is called and cancels the run
+// context on Stop or Shutdown, reporting StopPending while the server drains.
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, changes chan<- svc.Status) (bool, uint32) {
+	changes <- svc.Status{State: svc.StartPending}
+	
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- s.run(ctx)
+	}()
+	
+	readyCh := ready
+	for {
+		select {
+		case <-readyCh:
+			readyCh = nil
+			changes <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+		case err := <-errCh:
+			if err != nil {
+				return true, 1
+			}
+			return false, 0
+		case req := <-requests:
+			switch req.Cmd {
+			case svc.Interrogate:
+				changes <- req.CurrentStatus
+			case svc.Stop, svc.Shutdown:
+				changes <- svc.Status{State: svc.StopPending}
+				cancel()
+			}
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package service
+
+import "fmt"
+
+func isWindowsService() bool {
+	return false
+}
+
+func runWindowsService(name string, run RunFunc) error {
+	return fmt.Errorf("windows services are not supported on this platform")
+}
\ No newline at end of file
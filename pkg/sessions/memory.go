@@ -0,0 +1,109 @@
+package sessions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+type MemoryStore struct {
+	sessions map[string]*Session
+	states   map[string][]byte
+	mu       sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[string]*Session),
+		states:   make(map[string][]byte),
+	}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.sweep(time.Now())
+	s.sessions[session.ID] = session.clone()
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	session, ok := s.sessions[id]
+	if !ok || session.expired(time.Now()) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return session.clone(), nil
+}
+
+func (s *MemoryStore) List(ctx context.Context, cluster, agent string) ([]*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	now := time.Now()
+	var list []*Session
+	for _, session := range s.sessions {
+		if session.Cluster == cluster && session.Agent == agent && !session.expired(now) {
+			list = append(list, session.clone())
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].UpdatedAt.After(list[j].UpdatedAt)
+	})
+	return list, nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if _, ok := s.sessions[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+// GetState and PutState keep states encoded, so callers never share the
+// maps and slices inside them.
+func (s *MemoryStore) GetState(ctx context.Context, cluster, agent string) (map[string]interface{}, error) {
+	s.mu.Lock()
+	data, ok := s.states[cluster+"/"+agent]
+	s.mu.Unlock()
+	if !ok {
+		return map[string]interface{}{}, nil
+	}
+	return decodeState(string(data))
+}
+
+func (s *MemoryStore) PutState(ctx context.Context, cluster, agent string, state map[string]interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode agent state: %w", err)
+	}
+	
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	s.states[cluster+"/"+agent] = data
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+// sweep drops expired sessions. The caller must hold s.mu.
+func (s *MemoryStore) sweep(now time.Time) {
+	for id, session := range s.sessions {
+		if session.expired(now) {
+			delete(s.sessions, id)
+		}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,182 @@
+package sessions
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStoreConfig struct {
+	Addr      string `json:"addr"`
+	Password  string `json:"password,omitempty"`
+	DB        int    `json:"db,omitempty"`
+	KeyPrefix string `json:"key_prefix,omitempty"`
+}
+
+// RedisStore keeps each session under its own key, expiring with the
+// session, and indexes an agent's sessions in a sorted set scored by when
+// they were last updated. Index entries of expired sessions are dropped
+// when the agent's sessions are listed.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(config *RedisStoreConfig) (*RedisStore, error) {
+	if config.Addr == "" {
+		return nil, fmt.Errorf("redis address is required")
+	}
+	
+	prefix := "goagents:sessions:"
+	if config.KeyPrefix != "" {
+		prefix = config.KeyPrefix
+	}
+	
+	client := redis.NewClient(&redis.Options{
+		Addr:     config.Addr,
+		Password: config.Password,
+		DB:       config.DB,
+	})
+	
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+	}, nil
+}
+
+func (s *RedisStore) Put(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	
+	var ttl time.Duration
+	if !session.ExpiresAt.IsZero() {
+		ttl = time.Until(session.ExpiresAt)
+		if ttl <= 0 {
+			return nil
+		}
+	}
+	
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, s.sessionKey(session.ID), data, ttl)
+		pipe.ZAdd(ctx, s.agentKey(session.Cluster, session.Agent), redis.Z{
+			Score:  float64(session.UpdatedAt.UnixNano()),
+			Member: session.ID,
+		})
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (*Session, error) {
+	data, err := s.client.Get(ctx, s.sessionKey(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get failed: %w", err)
+	}
+	return decodeSession(data)
+}
+
+func (s *RedisStore) List(ctx context.Context, cluster, agent string) ([]*Session, error) {
+	index := s.agentKey(cluster, agent)
+	ids, err := s.client.ZRevRange(ctx, index, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis list failed: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = s.sessionKey(id)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("redis get failed: %w", err)
+	}
+	
+	var list []*Session
+	var expired []interface{}
+	for i, value := range values {
+		data, ok := value.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		session, err := decodeSession(data)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, session)
+	}
+	if len(expired) > 0 {
+		s.client.ZRem(ctx, index, expired...)
+	}
+	return list, nil
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	session, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, s.sessionKey(id))
+		pipe.ZRem(ctx, s.agentKey(session.Cluster, session.Agent), id)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("redis delete failed: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) GetState(ctx context.Context, cluster, agent string) (map[string]interface{}, error) {
+	data, err := s.client.Get(ctx, s.stateKey(cluster, agent)).Result()
+	if errors.Is(err, redis.Nil) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("redis get failed: %w", err)
+	}
+	return decodeState(data)
+}
+
+func (s *RedisStore) PutState(ctx context.Context, cluster, agent string, state map[string]interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode agent state: %w", err)
+	}
+	if err := s.client.Set(ctx, s.stateKey(cluster, agent), data, 0).Err(); err != nil {
+		return fmt.Errorf("redis set failed: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
+
+func (s *RedisStore) sessionKey(id string) string {
+	return s.prefix + "session:" + id
+}
+
+func (s *RedisStore) agentKey(cluster, agent string) string {
+	return s.prefix + "agent:" + cluster + "/" + agent
+}
+
+func (s *RedisStore) stateKey(cluster, agent string) string {
+	return s.prefix + "state:" + cluster + "/" + agent
+}
\ No newline at end of file
@@ -0,0 +1,216 @@
+package sessions
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+// SQLStore keeps sessions in SQLite or Postgres, so they survive restarts
+// and, with Postgres, are shared by every server using the database. Each
+// session is a row holding it as JSON, indexed by agent and expiry.
+type SQLStore struct {
+	db       *sql.DB
+	postgres bool
+}
+
+var sqlSchema = []string{
+	`CREATE TABLE IF NOT EXISTS goagents_sessions (
+		id TEXT PRIMARY KEY,
+		cluster TEXT NOT NULL,
+		agent TEXT NOT NULL,
+		data TEXT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`,
+	`CREATE INDEX IF NOT EXISTS goagents_sessions_agent ON goagents_sessions (cluster, agent, updated_at)`,
+	`CREATE INDEX IF NOT EXISTS goagents_sessions_expires ON goagents_sessions (expires_at)`,
+	`CREATE TABLE IF NOT EXISTS goagents_agent_state (
+		cluster TEXT NOT NULL,
+		agent TEXT NOT NULL,
+		data TEXT NOT NULL,
+		updated_at BIGINT NOT NULL,
+		PRIMARY KEY (cluster, agent)
+	)`,
+}
+
+// NewSQLStore opens the database and creates the tables the store needs.
+// driver is sqlite or postgres.
+func NewSQLStore(driver, dsn string) (*SQLStore, error) {
+	if driver != "sqlite" && driver != "postgres" {
+		return nil, fmt.Errorf("unsupported session database driver: %s", driver)
+	}
+	
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database: %w", err)
+	}
+	if driver == "sqlite" {
+		// SQLite allows one writer at a time
+		db.SetMaxOpenConns(1)
+	}
+	
+	for _, statement := range sqlSchema {
+		if _, err := db.Exec(statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to create session tables: %w", err)
+		}
+	}
+	
+	return &SQLStore{db: db, postgres: driver == "postgres"}, nil
+}
+
+func (s *SQLStore) Put(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	
+	now := time.Now()
+	if _, err := s.db.ExecContext(ctx, s.query(`DELETE FROM goagents_sessions WHERE expires_at > 0 AND expires_at < ?`), now.UnixNano()); err != nil {
+		return fmt.Errorf("failed to remove expired sessions: %w", err)
+	}
+	
+	_, err = s.db.ExecContext(ctx, s.query(`INSERT INTO goagents_sessions (id, cluster, agent, data, updated_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at, expires_at = excluded.expires_at`),
+		session.ID, session.Cluster, session.Agent, string(data), session.UpdatedAt.UnixNano(), unixNano(session.ExpiresAt))
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Get(ctx context.Context, id string) (*Session, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT data FROM goagents_sessions
+		WHERE id = ? AND (expires_at = 0 OR expires_at >= ?)`), id, time.Now().UnixNano()).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	return decodeSession(data)
+}
+
+func (s *SQLStore) List(ctx context.Context, cluster, agent string) ([]*Session, error) {
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT data FROM goagents_sessions
+		WHERE cluster = ? AND agent = ? AND (expires_at = 0 OR expires_at >= ?)
+		ORDER BY updated_at DESC`), cluster, agent, time.Now().UnixNano())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+	
+	var list []*Session
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		session, err := decodeSession(data)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, session)
+	}
+	return list, rows.Err()
+}
+
+func (s *SQLStore) Delete(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, s.query(`DELETE FROM goagents_sessions WHERE id = ?`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return nil
+}
+
+func (s *SQLStore) GetState(ctx context.Context, cluster, agent string) (map[string]interface{}, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, s.query(`SELECT data FROM goagents_agent_state WHERE cluster = ? AND agent = ?`), cluster, agent).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load agent state: %w", err)
+	}
+	return decodeState(data)
+}
+
+func (s *SQLStore) PutState(ctx context.Context, cluster, agent string, state map[string]interface{}) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode agent state: %w", err)
+	}
+	
+	_, err = s.db.ExecContext(ctx, s.query(`INSERT INTO goagents_agent_state (cluster, agent, data, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (cluster, agent) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at`),
+		cluster, agent, string(data), time.Now().UnixNano())
+	if err != nil {
+		return fmt.Errorf("failed to save agent state: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+// query numbers the placeholders of a query for Postgres, which does not
+// accept ?.
+func (s *SQLStore) query(query string) string {
+	if !s.postgres {
+		return query
+	}
+	
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func decodeSession(data string) (*Session, error) {
+	var session Session
+	if err := json.Unmarshal([]byte(data), &session); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return &session, nil
+}
+
+func decodeState(data string) (map[string]interface{}, error) {
+	var state map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, fmt.Errorf("failed to decode agent state: %w", err)
+	}
+	if state == nil {
+		state = map[string]interface{}{}
+	}
+	return state, nil
+}
\ No newline at end of file
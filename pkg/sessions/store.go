@@ -0,0 +1,92 @@
+// Package sessions stores the history of conversations with agents, so
+// clients can continue a conversation without sending its transcript with
+// every message.
+package sessions
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/goagents/goagents/pkg/agent"
+)
+
+var ErrNotFound = errors.New("session not found")
+
+// Session is a conversation with one agent. The agent is named by cluster
+// and name, which outlive the agent's ID across redeploys.
+type Session struct {
+	ID       string          `json:"id"`
+	Cluster  string          `json:"cluster"`
+	Agent    string          `json:"agent"`
+	Messages []agent.Message `json:"messages"`
+	// Summary stands in for the SummarizedMessages earlier messages that
+	// were compressed to fit the history budget
+	Summary            string `json:"summary,omitempty"`
+	SummarizedMessages int    `json:"summarized_messages,omitempty"`
+	// ContextTokens estimates the size of the history sent with the last
+	// message, and TotalTokens counts what the session has used in all,
+	// summaries included. TotalCost is what those tokens cost in US
+	// dollars, for models with a known price.
+	ContextTokens int               `json:"context_tokens,omitempty"`
+	TotalTokens   int               `json:"total_tokens,omitempty"`
+	TotalCost     float64           `json:"total_cost,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	ExpiresAt     time.Time         `json:"expires_at,omitempty"`
+}
+
+// Store keeps sessions until they expire, and each agent's state, which
+// does not expire. Put replaces the stored session with the same ID; stores
+// keep their own copy of what they are given.
+type Store interface {
+	Put(ctx context.Context, session *Session) error
+	Get(ctx context.Context, id string) (*Session, error)
+	// List returns the sessions with an agent, most recently updated first
+	List(ctx context.Context, cluster, agent string) ([]*Session, error)
+	Delete(ctx context.Context, id string) error
+	// GetState returns an empty state for an agent that has none stored
+	GetState(ctx context.Context, cluster, agent string) (map[string]interface{}, error)
+	PutState(ctx context.Context, cluster, agent string, state map[string]interface{}) error
+	Close() error
+}
+
+// New returns a session with an unguessable ID, since a session's history
+// can be read by ID.
+func New(cluster, agentName string, metadata map[string]string) (*Session, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	
+	now := time.Now().UTC()
+	return &Session{
+		ID:        "sess-" + hex.EncodeToString(buf),
+		Cluster:   cluster,
+		Agent:     agentName,
+		Messages:  []agent.Message{},
+		Metadata:  metadata,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}, nil
+}
+
+func (s *Session) expired(now time.Time) bool {
+	return !s.ExpiresAt.IsZero() && now.After(s.ExpiresAt)
+}
+
+func (s *Session) clone() *Session {
+	copied := *s
+	copied.Messages = append(make([]agent.Message, 0, len(s.Messages)), s.Messages...)
+	if s.Metadata != nil {
+		copied.Metadata = make(map[string]string, len(s.Metadata))
+		for key, value := range s.Metadata {
+			copied.Metadata[key] = value
+		}
+	}
+	return &copied
+}
\ No newline at end of file
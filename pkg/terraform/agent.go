@@ -0,0 +1,259 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/goagents/goagents/pkg/client"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// agentResource is a goagents_agent: an agent added to a cluster. Each
+// change updates the cluster's spec, conditional on its resource version.
+type agentResource struct {
+	client *client.Client
+}
+
+type agentModel struct {
+	ID       types.String `tfsdk:"id"`
+	Cluster  types.String `tfsdk:"cluster"`
+	Manifest types.String `tfsdk:"manifest"`
+	Name     types.String `tfsdk:"name"`
+}
+
+func newAgentResource() resource.Resource {
+	return &agentResource{}
+}
+
+func (r *agentResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_agent"
+}
+
+func (r *agentResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "An agent of a goagents cluster. The cluster's manifest, if it is a goagents_cluster, must not also list the agent.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "The cluster's ID and the agent's name, separated by a slash.",
+				Computed:    true,
+			},
+			"cluster": schema.StringAttribute{
+				Description: "The ID of the cluster the agent belongs to.",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"manifest": schema.StringAttribute{
+				Description: "The agent, as an agent of a cluster file in YAML or JSON. Changing its name replaces the agent.",
+				Required:    true,
+			},
+			"name": schema.StringAttribute{
+				Description: "The agent's name, from the manifest.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *agentResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+// ModifyPlan plans the name and ID the manifest gives the agent, and
+// replaces the agent when they change.
+func (r *agentResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var plan agentModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.Manifest.IsUnknown() {
+		return
+	}
+	
+	agentConfig, err := parseAgent(plan.Manifest.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("manifest"), "Invalid manifest", err.Error())
+		return
+	}
+	plan.Name = types.StringValue(agentConfig.Name)
+	if !plan.Cluster.IsUnknown() {
+		plan.ID = types.StringValue(plan.Cluster.ValueString() + "/" + agentConfig.Name)
+	}
+	
+	if !req.State.Raw.IsNull() {
+		var state agentModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if state.Name.ValueString() != agentConfig.Name {
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root("manifest"))
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *agentResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan agentModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	agentConfig, err := parseAgent(plan.Manifest.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("manifest"), "Invalid manifest", err.Error())
+		return
+	}
+	clusterID := plan.Cluster.ValueString()
+	cluster, err := updateCluster(ctx, r.client, clusterID, func(live *config.AgentCluster) error {
+		if _, existing := findAgent(live, agentConfig.Name); existing != nil {
+			return fmt.Errorf("cluster %s already has an agent named %s", clusterID, agentConfig.Name)
+		}
+		live.Spec.Agents = append(live.Spec.Agents, *agentConfig)
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create agent", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(setAgentState(ctx, &plan, cluster, agentConfig.Name, resp.Private.SetKey)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *agentResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state agentModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	cluster, err := r.client.GetCluster(ctx, state.Cluster.ValueString())
+	if client.IsNotFound(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read agent", err.Error())
+		return
+	}
+	if cluster.Config == nil {
+		resp.Diagnostics.AddError("Failed to read agent", "the server returned no spec for cluster "+cluster.Name)
+		return
+	}
+	_, agentConfig := findAgent(cluster.Config, state.Name.ValueString())
+	if agentConfig == nil {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	
+	hash, err := specHash(agentConfig)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read agent", err.Error())
+		return
+	}
+	saved, diags := req.Private.GetKey(ctx, specHashKey)
+	resp.Diagnostics.Append(diags...)
+	if state.Manifest.IsNull() || string(saved) != fmt.Sprintf("%q", hash) {
+		manifest, err := renderManifest(agentConfig)
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to read agent", err.Error())
+			return
+		}
+		state.Manifest = types.StringValue(manifest)
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *agentResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan agentModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	agentConfig, err := parseAgent(plan.Manifest.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("manifest"), "Invalid manifest", err.Error())
+		return
+	}
+	clusterID := plan.Cluster.ValueString()
+	cluster, err := updateCluster(ctx, r.client, clusterID, func(live *config.AgentCluster) error {
+		i, _ := findAgent(live, agentConfig.Name)
+		if i < 0 {
+			return fmt.Errorf("cluster %s has no agent named %s", clusterID, agentConfig.Name)
+		}
+		live.Spec.Agents[i] = *agentConfig
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to update agent", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(setAgentState(ctx, &plan, cluster, agentConfig.Name, resp.Private.SetKey)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *agentResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state agentModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	name := state.Name.ValueString()
+	_, err := updateCluster(ctx, r.client, state.Cluster.ValueString(), func(live *config.AgentCluster) error {
+		if i, _ := findAgent(live, name); i >= 0 {
+			live.Spec.Agents = append(live.Spec.Agents[:i], live.Spec.Agents[i+1:]...)
+		}
+		return nil
+	})
+	if err != nil && !client.IsNotFound(err) {
+		resp.Diagnostics.AddError("Failed to delete agent", err.Error())
+	}
+}
+
+// ImportState takes the ID of an agent as the cluster's ID and the agent's
+// name, separated by a slash.
+func (r *agentResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	i := strings.LastIndex(req.ID, "/")
+	if i <= 0 || i == len(req.ID)-1 {
+		resp.Diagnostics.AddError("Invalid import ID", "expected <cluster>/<agent>, got "+req.ID)
+		return
+	}
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("cluster"), req.ID[:i])...)
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("name"), req.ID[i+1:])...)
+}
+
+// setAgentState records an agent of a cluster the server returned after a
+// write, with the hash of its spec.
+func setAgentState(ctx context.Context, model *agentModel, cluster *client.Cluster, name string, setKey func(context.Context, string, []byte) diag.Diagnostics) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if cluster.Config == nil {
+		diags.AddError("Failed to read agent", "the server returned no spec for cluster "+cluster.Name)
+		return diags
+	}
+	_, agentConfig := findAgent(cluster.Config, name)
+	if agentConfig == nil {
+		diags.AddError("Failed to read agent", fmt.Sprintf("cluster %s has no agent named %s", cluster.Name, name))
+		return diags
+	}
+	hash, err := specHash(agentConfig)
+	if err != nil {
+		diags.AddError("Failed to read agent", err.Error())
+		return diags
+	}
+	diags.Append(setKey(ctx, specHashKey, []byte(fmt.Sprintf("%q", hash)))...)
+	
+	model.ID = types.StringValue(cluster.Name + "/" + name)
+	model.Name = types.StringValue(name)
+	return diags
+}
\ No newline at end of file
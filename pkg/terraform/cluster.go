@@ -0,0 +1,270 @@
+package terraform
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/goagents/goagents/pkg/client"
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// clusterResource is a goagents_cluster: a cluster deployed from a
+// manifest. Agents in the cluster that are not in the manifest are left
+// alone, so goagents_agent resources may add agents to it.
+type clusterResource struct {
+	client *client.Client
+}
+
+type clusterModel struct {
+	ID              types.String `tfsdk:"id"`
+	Manifest        types.String `tfsdk:"manifest"`
+	Name            types.String `tfsdk:"name"`
+	Namespace       types.String `tfsdk:"namespace"`
+	ResourceVersion types.String `tfsdk:"resource_version"`
+	Status          types.String `tfsdk:"status"`
+}
+
+func newClusterResource() resource.Resource {
+	return &clusterResource{}
+}
+
+func (r *clusterResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_cluster"
+}
+
+func (r *clusterResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A goagents cluster, deployed from a cluster manifest.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "The cluster's ID: its name, preceded by its namespace and a colon outside the default namespace.",
+				Computed:    true,
+			},
+			"manifest": schema.StringAttribute{
+				Description: "The cluster, as a cluster file in YAML or JSON. Changing its name or namespace replaces the cluster.",
+				Required:    true,
+			},
+			"name": schema.StringAttribute{
+				Description: "The cluster's name, from the manifest.",
+				Computed:    true,
+			},
+			"namespace": schema.StringAttribute{
+				Description: "The cluster's namespace, from the manifest.",
+				Computed:    true,
+			},
+			"resource_version": schema.StringAttribute{
+				Description: "The version of the cluster's spec on the server.",
+				Computed:    true,
+			},
+			"status": schema.StringAttribute{
+				Description: "The cluster's status.",
+				Computed:    true,
+			},
+		},
+	}
+}
+
+func (r *clusterResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+// ModifyPlan plans the name, namespace and ID the manifest gives the
+// cluster, and replaces the cluster when they change.
+func (r *clusterResource) ModifyPlan(ctx context.Context, req resource.ModifyPlanRequest, resp *resource.ModifyPlanResponse) {
+	if req.Plan.Raw.IsNull() {
+		return
+	}
+	var plan clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() || plan.Manifest.IsUnknown() {
+		return
+	}
+	
+	cluster, err := parseCluster(plan.Manifest.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("manifest"), "Invalid manifest", err.Error())
+		return
+	}
+	id := client.ClusterID(cluster.Metadata.Namespace, cluster.Metadata.Name)
+	plan.ID = types.StringValue(id)
+	plan.Name = types.StringValue(cluster.Metadata.Name)
+	plan.Namespace = types.StringValue(cluster.Metadata.Namespace)
+	
+	if !req.State.Raw.IsNull() {
+		var state clusterModel
+		resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+		if state.ID.ValueString() != id {
+			resp.RequiresReplace = append(resp.RequiresReplace, path.Root("manifest"))
+		}
+	}
+	resp.Diagnostics.Append(resp.Plan.Set(ctx, &plan)...)
+}
+
+func (r *clusterResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	cluster, err := parseCluster(plan.Manifest.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("manifest"), "Invalid manifest", err.Error())
+		return
+	}
+	if err := r.client.CreateCluster(ctx, cluster); err != nil {
+		resp.Diagnostics.AddError("Failed to create cluster", err.Error())
+		return
+	}
+	
+	id := client.ClusterID(cluster.Metadata.Namespace, cluster.Metadata.Name)
+	deployed, err := r.client.GetCluster(ctx, id)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read cluster", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(r.setState(ctx, &plan, deployed, agentNames(cluster), resp.Private.SetKey)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *clusterResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state clusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	cluster, err := r.client.GetCluster(ctx, state.ID.ValueString())
+	if client.IsNotFound(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read cluster", err.Error())
+		return
+	}
+	if cluster.Config == nil {
+		resp.Diagnostics.AddError("Failed to read cluster", "the server returned no spec for cluster "+state.ID.ValueString())
+		return
+	}
+	
+	// An imported cluster has no manifest yet and takes all its agents
+	var managed map[string]bool
+	if !state.Manifest.IsNull() {
+		if manifest, err := parseCluster(state.Manifest.ValueString()); err == nil {
+			managed = agentNames(manifest)
+		}
+	} else {
+		resp.Diagnostics.Append(resp.Private.SetKey(ctx, importedKey, []byte("true"))...)
+	}
+	hash, err := specHash(managedCluster(cluster.Config, managed))
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read cluster", err.Error())
+		return
+	}
+	saved, diags := req.Private.GetKey(ctx, specHashKey)
+	resp.Diagnostics.Append(diags...)
+	if state.Manifest.IsNull() || string(saved) != fmt.Sprintf("%q", hash) {
+		manifest, err := renderManifest(managedCluster(cluster.Config, managed))
+		if err != nil {
+			resp.Diagnostics.AddError("Failed to read cluster", err.Error())
+			return
+		}
+		state.Manifest = types.StringValue(manifest)
+	}
+	
+	state.ID = types.StringValue(cluster.Name)
+	state.Name = types.StringValue(cluster.Config.Metadata.Name)
+	state.Namespace = types.StringValue(cluster.Namespace)
+	state.ResourceVersion = types.StringValue(cluster.ResourceVersion)
+	state.Status = types.StringValue(cluster.Status)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *clusterResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan, state clusterModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	candidate, err := parseCluster(plan.Manifest.ValueString())
+	if err != nil {
+		resp.Diagnostics.AddAttributeError(path.Root("manifest"), "Invalid manifest", err.Error())
+		return
+	}
+	// Agents the manifest did not have before belong to other resources.
+	// The manifest of an imported cluster has all of them, so the first
+	// update after an import leaves alone the agents it does not name.
+	previous := make(map[string]bool)
+	imported, diags := req.Private.GetKey(ctx, importedKey)
+	resp.Diagnostics.Append(diags...)
+	if manifest, err := parseCluster(state.Manifest.ValueString()); err == nil && imported == nil {
+		previous = agentNames(manifest)
+	}
+	managed := agentNames(candidate)
+	
+	cluster, err := updateCluster(ctx, r.client, state.ID.ValueString(), func(live *config.AgentCluster) error {
+		agents := append([]config.Agent(nil), candidate.Spec.Agents...)
+		for _, agentConfig := range live.Spec.Agents {
+			if !managed[agentConfig.Name] && !previous[agentConfig.Name] {
+				agents = append(agents, agentConfig)
+			}
+		}
+		*live = *candidate
+		live.Spec.Agents = agents
+		return nil
+	})
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to update cluster", err.Error())
+		return
+	}
+	resp.Diagnostics.Append(r.setState(ctx, &plan, cluster, managed, resp.Private.SetKey)...)
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *clusterResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state clusterModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	if err := r.client.DeleteCluster(ctx, state.ID.ValueString()); err != nil && !client.IsNotFound(err) {
+		resp.Diagnostics.AddError("Failed to delete cluster", err.Error())
+	}
+}
+
+func (r *clusterResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resp.Diagnostics.Append(resp.State.SetAttribute(ctx, path.Root("id"), req.ID)...)
+}
+
+// setState records a cluster the server returned after a write, with the
+// hash of the part the resource manages.
+func (r *clusterResource) setState(ctx context.Context, model *clusterModel, cluster *client.Cluster, managed map[string]bool, setKey func(context.Context, string, []byte) diag.Diagnostics) diag.Diagnostics {
+	var diags diag.Diagnostics
+	if cluster.Config == nil {
+		diags.AddError("Failed to read cluster", "the server returned no spec for cluster "+cluster.Name)
+		return diags
+	}
+	hash, err := specHash(managedCluster(cluster.Config, managed))
+	if err != nil {
+		diags.AddError("Failed to read cluster", err.Error())
+		return diags
+	}
+	diags.Append(setKey(ctx, specHashKey, []byte(fmt.Sprintf("%q", hash)))...)
+	diags.Append(setKey(ctx, importedKey, nil)...)
+	
+	model.ID = types.StringValue(cluster.Name)
+	model.Name = types.StringValue(cluster.Config.Metadata.Name)
+	model.Namespace = types.StringValue(cluster.Namespace)
+	model.ResourceVersion = types.StringValue(cluster.ResourceVersion)
+	model.Status = types.StringValue(cluster.Status)
+	return diags
+}
\ No newline at end of file
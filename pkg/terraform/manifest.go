@@ -0,0 +1,138 @@
+package terraform
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/goagents/goagents/pkg/client"
+	"github.com/goagents/goagents/pkg/config"
+	"gopkg.in/yaml.v3"
+)
+
+// maxUpdateAttempts bounds the retries of a cluster update that conflicts
+// with another write.
+const maxUpdateAttempts = 5
+
+// specHashKey is the private state key holding the hash of the spec a
+// resource last left on the server, which tells changes made elsewhere.
+const specHashKey = "spec_hash"
+
+// importedKey is the private state key marking a cluster imported since
+// its last write, whose manifest took every agent the cluster had.
+const importedKey = "imported"
+
+// parseCluster reads a cluster manifest, in YAML or JSON, as a cluster
+// file is read.
+func parseCluster(manifest string) (*config.AgentCluster, error) {
+	var cluster config.AgentCluster
+	if err := yaml.Unmarshal([]byte(manifest), &cluster); err != nil {
+		return nil, fmt.Errorf("invalid cluster manifest: %w", err)
+	}
+	if cluster.Metadata.Name == "" {
+		return nil, fmt.Errorf("invalid cluster manifest: metadata.name is required")
+	}
+	if cluster.Metadata.Namespace == "" {
+		cluster.Metadata.Namespace = config.DefaultNamespace
+	}
+	return &cluster, nil
+}
+
+// parseAgent reads an agent manifest, in YAML or JSON, as an agent of a
+// cluster file is read.
+func parseAgent(manifest string) (*config.Agent, error) {
+	var agentConfig config.Agent
+	if err := yaml.Unmarshal([]byte(manifest), &agentConfig); err != nil {
+		return nil, fmt.Errorf("invalid agent manifest: %w", err)
+	}
+	if agentConfig.Name == "" {
+		return nil, fmt.Errorf("invalid agent manifest: name is required")
+	}
+	return &agentConfig, nil
+}
+
+// agentNames returns the names of a cluster's agents.
+func agentNames(cluster *config.AgentCluster) map[string]bool {
+	names := make(map[string]bool, len(cluster.Spec.Agents))
+	for _, agentConfig := range cluster.Spec.Agents {
+		names[agentConfig.Name] = true
+	}
+	return names
+}
+
+// managedCluster returns a copy of cluster with only the agents named in
+// managed, or all of them when managed is nil, and without its resource
+// version: the part of a cluster a goagents_cluster resource manages.
+func managedCluster(cluster *config.AgentCluster, managed map[string]bool) *config.AgentCluster {
+	copied := *cluster
+	copied.Metadata.ResourceVersion = ""
+	if managed != nil {
+		copied.Spec.Agents = nil
+		for _, agentConfig := range cluster.Spec.Agents {
+			if managed[agentConfig.Name] {
+				copied.Spec.Agents = append(copied.Spec.Agents, agentConfig)
+			}
+		}
+	}
+	return &copied
+}
+
+// specHash returns a hash of a spec as the server holds it.
+func specHash(spec interface{}) (string, error) {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// renderManifest writes a spec the server holds as YAML, to show in plans
+// when it was changed outside of Terraform.
+func renderManifest(spec interface{}) (string, error) {
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// findAgent returns the agent of a cluster named name.
+func findAgent(cluster *config.AgentCluster, name string) (int, *config.Agent) {
+	for i := range cluster.Spec.Agents {
+		if cluster.Spec.Agents[i].Name == name {
+			return i, &cluster.Spec.Agents[i]
+		}
+	}
+	return -1, nil
+}
+
+// updateCluster changes a cluster's spec with change, which is given the
+// spec the cluster runs. The update is conditional on the cluster not
+// having changed since it was read, and is tried again from the start
+// when it has. It returns the cluster after the update.
+func updateCluster(ctx context.Context, c *client.Client, id string, change func(*config.AgentCluster) error) (*client.Cluster, error) {
+	for attempt := 1; ; attempt++ {
+		cluster, err := c.GetCluster(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if cluster.Config == nil {
+			return nil, fmt.Errorf("cluster %s has no spec", id)
+		}
+		if err := change(cluster.Config); err != nil {
+			return nil, err
+		}
+		cluster.Config.Metadata.ResourceVersion = cluster.ResourceVersion
+		
+		err = c.UpdateCluster(ctx, id, cluster.Config)
+		if err == nil {
+			return c.GetCluster(ctx, id)
+		}
+		if !client.IsConflict(err) || attempt == maxUpdateAttempts {
+			return nil, err
+		}
+	}
+}
\ No newline at end of file
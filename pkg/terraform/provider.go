@@ -0,0 +1,134 @@
+// Package terraform is a Terraform provider for goagents. It manages
+// clusters, the agents in them, API keys and webhooks through the goagents
+// API, using the client SDK.
+package terraform
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/goagents/goagents/pkg/client"
+	"github.com/hashicorp/terraform-plugin-framework/datasource"
+	"github.com/hashicorp/terraform-plugin-framework/provider"
+	"github.com/hashicorp/terraform-plugin-framework/provider/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// Environment variables the provider falls back on for its settings.
+const (
+	endpointEnv = "GOAGENTS_ENDPOINT"
+	apiKeyEnv   = "GOAGENTS_API_KEY"
+)
+
+type goagentsProvider struct {
+	version string
+}
+
+type providerModel struct {
+	Endpoint types.String `tfsdk:"endpoint"`
+	APIKey   types.String `tfsdk:"api_key"`
+	Timeout  types.String `tfsdk:"timeout"`
+}
+
+// New returns a function creating the provider, as providerserver.Serve
+// expects.
+func New(version string) func() provider.Provider {
+	return func() provider.Provider {
+		return &goagentsProvider{version: version}
+	}
+}
+
+func (p *goagentsProvider) Metadata(ctx context.Context, req provider.MetadataRequest, resp *provider.MetadataResponse) {
+	resp.TypeName = "goagents"
+	resp.Version = p.version
+}
+
+func (p *goagentsProvider) Schema(ctx context.Context, req provider.SchemaRequest, resp *provider.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "Manages goagents clusters, agents, API keys and webhooks.",
+		Attributes: map[string]schema.Attribute{
+			"endpoint": schema.StringAttribute{
+				Description: "Base URL of the goagents API, such as https://goagents.example.com. Defaults to " + endpointEnv + ".",
+				Optional:    true,
+			},
+			"api_key": schema.StringAttribute{
+				Description: "API key granted all namespaces. Defaults to " + apiKeyEnv + ".",
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"timeout": schema.StringAttribute{
+				Description: "Timeout of each API request, such as 30s (default).",
+				Optional:    true,
+			},
+		},
+	}
+}
+
+func (p *goagentsProvider) Configure(ctx context.Context, req provider.ConfigureRequest, resp *provider.ConfigureResponse) {
+	var model providerModel
+	resp.Diagnostics.Append(req.Config.Get(ctx, &model)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	cfg := &client.Config{
+		BaseURL: os.Getenv(endpointEnv),
+		APIKey:  os.Getenv(apiKeyEnv),
+	}
+	if !model.Endpoint.IsNull() {
+		cfg.BaseURL = model.Endpoint.ValueString()
+	}
+	if !model.APIKey.IsNull() {
+		cfg.APIKey = model.APIKey.ValueString()
+	}
+	if !model.Timeout.IsNull() {
+		timeout, err := time.ParseDuration(model.Timeout.ValueString())
+		if err != nil {
+			resp.Diagnostics.AddError("Invalid timeout", err.Error())
+			return
+		}
+		cfg.Timeout = timeout
+	}
+	if cfg.BaseURL == "" {
+		resp.Diagnostics.AddError("Missing endpoint", "Set endpoint in the provider configuration or "+endpointEnv+".")
+		return
+	}
+	
+	c, err := client.NewClient(cfg)
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to create goagents client", err.Error())
+		return
+	}
+	resp.ResourceData = c
+	resp.DataSourceData = c
+}
+
+func (p *goagentsProvider) Resources(ctx context.Context) []func() resource.Resource {
+	return []func() resource.Resource{
+		newClusterResource,
+		newAgentResource,
+		newAPIKeyResource,
+		newWebhookResource,
+	}
+}
+
+func (p *goagentsProvider) DataSources(ctx context.Context) []func() datasource.DataSource {
+	return nil
+}
+
+// configureClient takes the client the provider configured for a resource.
+// It is nil while the provider has not been configured yet, as when
+// Terraform validates a configuration.
+func configureClient(req resource.ConfigureRequest, resp *resource.ConfigureResponse) *client.Client {
+	if req.ProviderData == nil {
+		return nil
+	}
+	c, ok := req.ProviderData.(*client.Client)
+	if !ok {
+		resp.Diagnostics.AddError("Unexpected provider data", "The provider did not configure a goagents client.")
+		return nil
+	}
+	return c
+}
\ No newline at end of file
@@ -0,0 +1,207 @@
+package terraform
+
+import (
+	"context"
+
+	"github.com/goagents/goagents/pkg/client"
+	"github.com/hashicorp/terraform-plugin-framework/diag"
+	"github.com/hashicorp/terraform-plugin-framework/path"
+	"github.com/hashicorp/terraform-plugin-framework/resource"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/planmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/resource/schema/stringplanmodifier"
+	"github.com/hashicorp/terraform-plugin-framework/types"
+)
+
+// webhookResource is a goagents_webhook: a webhook lifecycle events are
+// posted to. The server does not return secrets or headers, so changes to
+// them made elsewhere go unnoticed.
+type webhookResource struct {
+	client *client.Client
+}
+
+type webhookModel struct {
+	ID           types.String      `tfsdk:"id"`
+	Name         types.String      `tfsdk:"name"`
+	URL          types.String      `tfsdk:"url"`
+	Secret       types.String      `tfsdk:"secret"`
+	Events       types.List        `tfsdk:"events"`
+	Headers      map[string]string `tfsdk:"headers"`
+	MaxRetries   types.Int64       `tfsdk:"max_retries"`
+	RetryBackoff types.String      `tfsdk:"retry_backoff"`
+	Timeout      types.String      `tfsdk:"timeout"`
+}
+
+func newWebhookResource() resource.Resource {
+	return &webhookResource{}
+}
+
+func (r *webhookResource) Metadata(ctx context.Context, req resource.MetadataRequest, resp *resource.MetadataResponse) {
+	resp.TypeName = req.ProviderTypeName + "_webhook"
+}
+
+func (r *webhookResource) Schema(ctx context.Context, req resource.SchemaRequest, resp *resource.SchemaResponse) {
+	resp.Schema = schema.Schema{
+		Description: "A webhook goagents posts signed lifecycle events to. Webhooks set through the API last until the server restarts.",
+		Attributes: map[string]schema.Attribute{
+			"id": schema.StringAttribute{
+				Description: "The webhook's name.",
+				Computed:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.UseStateForUnknown(),
+				},
+			},
+			"name": schema.StringAttribute{
+				Description: "The webhook's name, unique among webhooks and event sinks.",
+				Required:    true,
+				PlanModifiers: []planmodifier.String{
+					stringplanmodifier.RequiresReplace(),
+				},
+			},
+			"url": schema.StringAttribute{
+				Description: "The http or https URL events are posted to.",
+				Required:    true,
+			},
+			"secret": schema.StringAttribute{
+				Description: "The secret payloads are signed with.",
+				Required:    true,
+				Sensitive:   true,
+			},
+			"events": schema.ListAttribute{
+				Description: "The event types posted, each a type or a prefix ending in \"*\". The server's default set when not given.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Computed:    true,
+			},
+			"headers": schema.MapAttribute{
+				Description: "Headers added to each delivery.",
+				ElementType: types.StringType,
+				Optional:    true,
+				Sensitive:   true,
+			},
+			"max_retries": schema.Int64Attribute{
+				Description: "Retries of a failed delivery; 0 uses the default of 3 and -1 turns retries off.",
+				Optional:    true,
+			},
+			"retry_backoff": schema.StringAttribute{
+				Description: "Wait before the first retry, doubling for each next one, such as 1s (default).",
+				Optional:    true,
+			},
+			"timeout": schema.StringAttribute{
+				Description: "Timeout of each attempt, such as 10s (default).",
+				Optional:    true,
+			},
+		},
+	}
+}
+
+func (r *webhookResource) Configure(ctx context.Context, req resource.ConfigureRequest, resp *resource.ConfigureResponse) {
+	r.client = configureClient(req, resp)
+}
+
+func (r *webhookResource) Create(ctx context.Context, req resource.CreateRequest, resp *resource.CreateResponse) {
+	var plan webhookModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	resp.Diagnostics.Append(r.set(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *webhookResource) Read(ctx context.Context, req resource.ReadRequest, resp *resource.ReadResponse) {
+	var state webhookModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	webhook, err := r.client.GetWebhook(ctx, state.ID.ValueString())
+	if client.IsNotFound(err) {
+		resp.State.RemoveResource(ctx)
+		return
+	}
+	if err != nil {
+		resp.Diagnostics.AddError("Failed to read webhook", err.Error())
+		return
+	}
+	state.Name = types.StringValue(webhook.Name)
+	state.URL = types.StringValue(webhook.URL)
+	events, diags := types.ListValueFrom(ctx, types.StringType, webhook.Events)
+	resp.Diagnostics.Append(diags...)
+	state.Events = events
+	resp.Diagnostics.Append(resp.State.Set(ctx, &state)...)
+}
+
+func (r *webhookResource) Update(ctx context.Context, req resource.UpdateRequest, resp *resource.UpdateResponse) {
+	var plan webhookModel
+	resp.Diagnostics.Append(req.Plan.Get(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	resp.Diagnostics.Append(r.set(ctx, &plan)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	resp.Diagnostics.Append(resp.State.Set(ctx, &plan)...)
+}
+
+func (r *webhookResource) Delete(ctx context.Context, req resource.DeleteRequest, resp *resource.DeleteResponse) {
+	var state webhookModel
+	resp.Diagnostics.Append(req.State.Get(ctx, &state)...)
+	if resp.Diagnostics.HasError() {
+		return
+	}
+	
+	if err := r.client.DeleteWebhook(ctx, state.ID.ValueString()); err != nil && !client.IsNotFound(err) {
+		resp.Diagnostics.AddError("Failed to delete webhook", err.Error())
+	}
+}
+
+// ImportState takes a webhook's name. Its secret and headers are not
+// returned by the server, so they are planned as changes afterwards.
+func (r *webhookResource) ImportState(ctx context.Context, req resource.ImportStateRequest, resp *resource.ImportStateResponse) {
+	resource.ImportStatePassthroughID(ctx, path.Root("id"), req, resp)
+}
+
+// set adds or replaces the webhook a plan describes, and fills in what the
+// server chose for it.
+func (r *webhookResource) set(ctx context.Context, plan *webhookModel) diag.Diagnostics {
+	var diags diag.Diagnostics
+	spec := &client.WebhookSpec{
+		URL:          plan.URL.ValueString(),
+		Secret:       plan.Secret.ValueString(),
+		Headers:      plan.Headers,
+		MaxRetries:   int(plan.MaxRetries.ValueInt64()),
+		RetryBackoff: plan.RetryBackoff.ValueString(),
+		Timeout:      plan.Timeout.ValueString(),
+	}
+	if !plan.Events.IsUnknown() && !plan.Events.IsNull() {
+		diags.Append(plan.Events.ElementsAs(ctx, &spec.Events, false)...)
+		if diags.HasError() {
+			return diags
+		}
+	}
+	
+	name := plan.Name.ValueString()
+	if err := r.client.SetWebhook(ctx, name, spec); err != nil {
+		diags.AddError("Failed to set webhook", err.Error())
+		return diags
+	}
+	webhook, err := r.client.GetWebhook(ctx, name)
+	if err != nil {
+		diags.AddError("Failed to read webhook", err.Error())
+		return diags
+	}
+	
+	events, eventDiags := types.ListValueFrom(ctx, types.StringType, webhook.Events)
+	diags.Append(eventDiags...)
+	plan.ID = types.StringValue(name)
+	plan.Events = events
+	return diags
+}
\ No newline at end of file
@@ -0,0 +1,200 @@
+// Package toolplugin is the protocol between goagents and plugin tools:
+// programs, written in any language, that goagents starts and calls over
+// gRPC using HashiCorp's go-plugin. Go plugins call Serve; tool.proto
+// describes the service for other languages.
+package toolplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	plugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// ProtocolVersion is the version of the Tool service. It is negotiated
+	// during the handshake, so a plugin built for another version fails to
+	// start instead of failing calls.
+	ProtocolVersion = 1
+	// PluginName is the name the tool is served under.
+	PluginName = "tool"
+	
+	serviceName = "goagents.toolplugin.v1.Tool"
+)
+
+// Handshake must match between goagents and the plugin. The cookie stops a
+// plugin binary from being run by hand by mistake; it is not a secret.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  ProtocolVersion,
+	MagicCookieKey:   "GOAGENTS_TOOL_PLUGIN",
+	MagicCookieValue: "a4e1c2b0-9f63-4d8e-b7d5-3c61f0e2a9b8",
+}
+
+// Tool is what a plugin implements.
+type Tool interface {
+	// Describe tells the model what the tool does and the JSON schema of
+	// its arguments.
+	Describe(ctx context.Context) (*Description, error)
+	Execute(ctx context.Context, args map[string]interface{}) (*Result, error)
+}
+
+type Description struct {
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Result mirrors tools.Result. Data must marshal to JSON.
+type Result struct {
+	Data     interface{}            `json:"data"`
+	Error    string                 `json:"error,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// Plugins returns the plugin sets by protocol version. goagents passes a
+// nil tool; plugins pass their implementation.
+func Plugins(tool Tool) map[int]plugin.PluginSet {
+	return map[int]plugin.PluginSet{
+		ProtocolVersion: {PluginName: &toolPlugin{tool: tool}},
+	}
+}
+
+// toolPlugin serves and dispenses a Tool over gRPC only.
+type toolPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	tool Tool
+}
+
+func (p *toolPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&serviceDesc, &grpcServer{tool: p.tool})
+	return nil
+}
+
+func (p *toolPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &grpcClient{conn: conn}, nil
+}
+
+// toolServer is the server side of the Tool service. The messages are
+// well-known protobuf types, so no generated code is needed.
+type toolServer interface {
+	Describe(ctx context.Context, in *emptypb.Empty) (*structpb.Struct, error)
+	Execute(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*toolServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Describe",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(emptypb.Empty)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(toolServer).Describe(ctx, req.(*emptypb.Empty))
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Describe"}, handler)
+			},
+		},
+		{
+			MethodName: "Execute",
+			Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+					return srv.(toolServer).Execute(ctx, req.(*structpb.Struct))
+				}
+				if interceptor == nil {
+					return handler(ctx, in)
+				}
+				return interceptor(ctx, in, &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/Execute"}, handler)
+			},
+		},
+	},
+	Metadata: "tool.proto",
+}
+
+type grpcServer struct {
+	tool Tool
+}
+
+func (s *grpcServer) Describe(ctx context.Context, in *emptypb.Empty) (*structpb.Struct, error) {
+	description, err := s.tool.Describe(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(description)
+}
+
+func (s *grpcServer) Execute(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	result, err := s.tool.Execute(ctx, in.AsMap())
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(result)
+}
+
+// grpcClient is the Tool goagents calls.
+type grpcClient struct {
+	conn *grpc.ClientConn
+}
+
+func (c *grpcClient) Describe(ctx context.Context) (*Description, error) {
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, "/"+serviceName+"/Describe", &emptypb.Empty{}, out); err != nil {
+		return nil, err
+	}
+	var description Description
+	if err := fromStruct(out, &description); err != nil {
+		return nil, fmt.Errorf("invalid description: %w", err)
+	}
+	return &description, nil
+}
+
+func (c *grpcClient) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	in, err := structpb.NewStruct(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+	out := new(structpb.Struct)
+	if err := c.conn.Invoke(ctx, "/"+serviceName+"/Execute", in, out); err != nil {
+		return nil, err
+	}
+	var result Result
+	if err := fromStruct(out, &result); err != nil {
+		return nil, fmt.Errorf("invalid result: %w", err)
+	}
+	return &result, nil
+}
+
+// toStruct converts a value to a Struct through JSON, so any value that
+// marshals to a JSON object can be sent.
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return structpb.NewStruct(fields)
+}
+
+func fromStruct(s *structpb.Struct, v interface{}) error {
+	data, err := s.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
\ No newline at end of file
@@ -0,0 +1,19 @@
+package toolplugin
+
+import (
+	plugin "github.com/hashicorp/go-plugin"
+)
+
+// Serve runs tool as a plugin until goagents stops it. It is called from a
+// plugin program's main function and does not return:
+//
+//	func main() {
+//		toolplugin.Serve(&myTool{})
+//	}
+func Serve(tool Tool) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig:  Handshake,
+		VersionedPlugins: Plugins(tool),
+		GRPCServer:       plugin.DefaultGRPCServer,
+	})
+}
\ No newline at end of file
@@ -0,0 +1,412 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultBrowserMaxBytes = 2 << 20
+	defaultBrowserMaxText  = 100000
+	defaultBrowserMaxLinks = 100
+)
+
+// skippedElements never contain readable page text.
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Form:     true,
+	atom.Button:   true,
+	atom.Select:   true,
+}
+
+// boilerplateElements are dropped when the page has no <main> or <article>
+// to read from.
+var boilerplateElements = map[atom.Atom]bool{
+	atom.Nav:    true,
+	atom.Header: true,
+	atom.Footer: true,
+	atom.Aside:  true,
+}
+
+// blockElements start a new line in the extracted text.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Section: true, atom.Article: true, atom.Main: true, atom.Blockquote: true,
+	atom.Pre: true, atom.Table: true, atom.Ul: true, atom.Ol: true, atom.Dd: true, atom.Dt: true,
+}
+
+type pageLink struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// BrowserTool fetches web pages and returns their readable text, title and
+// links. Only hosts matching allowed_domains that the outbound policy allows
+// may be fetched, including across redirects.
+type BrowserTool struct {
+	config         *Config
+	client         *http.Client
+	allowedDomains []string
+	maxBytes       int64
+	maxText        int
+	maxLinks       int
+	userAgent      string
+}
+
+func NewBrowserTool(config *Config) (*BrowserTool, error) {
+	var allowed []string
+	for _, domain := range strings.Split(config.Config["allowed_domains"], ",") {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			if _, err := path.Match(domain, ""); err != nil {
+				return nil, fmt.Errorf("invalid allowed domain %q: %w", domain, err)
+			}
+			allowed = append(allowed, domain)
+		}
+	}
+	
+	maxBytes, err := intConfig(config.Config, "max_bytes", defaultBrowserMaxBytes)
+	if err != nil {
+		return nil, err
+	}
+	maxText, err := intConfig(config.Config, "max_text", defaultBrowserMaxText)
+	if err != nil {
+		return nil, err
+	}
+	maxLinks, err := intConfig(config.Config, "max_links", defaultBrowserMaxLinks)
+	if err != nil {
+		return nil, err
+	}
+	
+	userAgent := config.Config["user_agent"]
+	if userAgent == "" {
+		userAgent = "goagents/1.0 (+browser tool)"
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	tool := &BrowserTool{
+		config:         config,
+		allowedDomains: allowed,
+		maxBytes:       int64(maxBytes),
+		maxText:        maxText,
+		maxLinks:       maxLinks,
+		userAgent:      userAgent,
+	}
+	tool.client = &http.Client{
+		Timeout:   timeout,
+		Transport: config.Outbound.Transport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= 10 {
+				return fmt.Errorf("stopped after 10 redirects")
+			}
+			return tool.checkURL(req.URL)
+		},
+	}
+	
+	return tool, nil
+}
+
+func (t *BrowserTool) Name() string {
+	return t.config.Name
+}
+
+func (t *BrowserTool) Type() string {
+	return "browser"
+}
+
+func (t *BrowserTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = "Fetch a web page and return its title, readable text and links."
+		if len(t.allowedDomains) > 0 {
+			description += " Allowed domains: " + strings.Join(t.allowedDomains, ", ") + "."
+		}
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"url": map[string]interface{}{
+					"type":        "string",
+					"description": "The http or https URL to fetch",
+				},
+			},
+			"required": []string{"url"},
+		},
+	}
+}
+
+func (t *BrowserTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	rawURL, _ := args["url"].(string)
+	target, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || rawURL == "" {
+		return &Result{Error: fmt.Sprintf("invalid url: %q", rawURL)}, nil
+	}
+	if err := t.checkURL(target); err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to create request: %v", err)}, nil
+	}
+	req.Header.Set("User-Agent", t.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("request failed: %v", err)}, nil
+	}
+	defer resp.Body.Close()
+	
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.maxBytes+1))
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to read response: %v", err)}, nil
+	}
+	truncated := int64(len(body)) > t.maxBytes
+	if truncated {
+		body = body[:t.maxBytes]
+	}
+	
+	metadata := map[string]interface{}{
+		"status_code":  resp.StatusCode,
+		"content_type": resp.Header.Get("Content-Type"),
+		"url":          resp.Request.URL.String(),
+	}
+	
+	if resp.StatusCode >= 400 {
+		return &Result{Error: fmt.Sprintf("HTTP %d", resp.StatusCode), Metadata: metadata}, nil
+	}
+	
+	contentType := resp.Header.Get("Content-Type")
+	if isBinaryContent(contentType, body) {
+		if truncated {
+			return &Result{Error: fmt.Sprintf("file is larger than %d bytes", t.maxBytes), Metadata: metadata}, nil
+		}
+		data, err := storeResponseFile(ctx, t.config.Files, resp, body)
+		if err != nil {
+			return &Result{Error: err.Error(), Metadata: metadata}, nil
+		}
+		return &Result{Data: data, Metadata: metadata}, nil
+	}
+	
+	reader, err := charset.NewReader(bytes.NewReader(body), contentType)
+	if err != nil {
+		reader = bytes.NewReader(body)
+	}
+	
+	page := map[string]interface{}{
+		"url": resp.Request.URL.String(),
+	}
+	
+	if strings.HasPrefix(contentType, "text/plain") {
+		text, _ := io.ReadAll(reader)
+		content, cut := truncateText(string(text), t.maxText)
+		page["text"] = content
+		page["truncated"] = truncated || cut
+		return &Result{Data: page, Metadata: metadata}, nil
+	}
+	
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to parse page: %v", err), Metadata: metadata}, nil
+	}
+	
+	extracted := extractPage(doc, resp.Request.URL, t.maxLinks)
+	content, cut := truncateText(extracted.text, t.maxText)
+	page["title"] = extracted.title
+	page["text"] = content
+	page["links"] = extracted.links
+	page["truncated"] = truncated || cut
+	
+	return &Result{Data: page, Metadata: metadata}, nil
+}
+
+func (t *BrowserTool) Close() error {
+	return nil
+}
+
+// checkURL rejects non-web schemes and hosts outside the allowlist.
+func (t *BrowserTool) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported url scheme %q", u.Scheme)
+	}
+	if err := t.config.Outbound.CheckURL(u); err != nil {
+		return err
+	}
+	if len(t.allowedDomains) == 0 {
+		return nil
+	}
+	
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range t.allowedDomains {
+		if ok, _ := path.Match(pattern, host); ok {
+			return nil
+		}
+	}
+	return fmt.Errorf("domain %s is not allowed", host)
+}
+
+type extractedPage struct {
+	title string
+	text  string
+	links []pageLink
+}
+
+// extractPage reads the title, text and links of a page. Like reader modes,
+// it prefers the <main> or <article> element and otherwise skips
+// navigation, headers, footers and sidebars.
+func extractPage(doc *html.Node, base *url.URL, maxLinks int) *extractedPage {
+	page := &extractedPage{links: make([]pageLink, 0)}
+	if title := findElement(doc, atom.Title); title != nil {
+		page.title = strings.TrimSpace(nodeText(title))
+	}
+	
+	root := findElement(doc, atom.Main)
+	if root == nil {
+		root = findElement(doc, atom.Article)
+	}
+	skipBoilerplate := root == nil
+	if root == nil {
+		if root = findElement(doc, atom.Body); root == nil {
+			root = doc
+		}
+	}
+	
+	var text strings.Builder
+	seen := make(map[string]bool)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if skippedElements[n.DataAtom] || (skipBoilerplate && boilerplateElements[n.DataAtom]) {
+				return
+			}
+			if n.DataAtom == atom.A && len(page.links) < maxLinks {
+				if link, ok := resolveLink(n, base); ok && !seen[link.URL] {
+					seen[link.URL] = true
+					page.links = append(page.links, link)
+				}
+			}
+			if blockElements[n.DataAtom] {
+				text.WriteString("\n")
+			}
+		}
+		if n.Type == html.TextNode {
+			text.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			walk(child)
+		}
+		if n.Type == html.ElementNode && blockElements[n.DataAtom] {
+			text.WriteString("\n")
+		}
+	}
+	walk(root)
+	
+	page.text = collapseWhitespace(text.String())
+	return page
+}
+
+// PageText returns the title and readable text of an HTML page, read the
+// same way the browser tool reads pages.
+func PageText(r io.Reader, contentType string) (string, string, error) {
+	reader, err := charset.NewReader(r, contentType)
+	if err != nil {
+		return "", "", err
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return "", "", err
+	}
+	page := extractPage(doc, nil, 0)
+	return page.title, page.text, nil
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func nodeText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var text strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		text.WriteString(nodeText(child))
+	}
+	return text.String()
+}
+
+func resolveLink(n *html.Node, base *url.URL) (pageLink, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key != "href" {
+			continue
+		}
+		ref, err := url.Parse(strings.TrimSpace(attr.Val))
+		if err != nil {
+			return pageLink{}, false
+		}
+		resolved := base.ResolveReference(ref)
+		if resolved.Scheme != "http" && resolved.Scheme != "https" {
+			return pageLink{}, false
+		}
+		resolved.Fragment = ""
+		return pageLink{
+			Text: collapseWhitespace(nodeText(n)),
+			URL:  resolved.String(),
+		}, true
+	}
+	return pageLink{}, false
+}
+
+// collapseWhitespace joins runs of spaces within lines and drops blank
+// lines.
+func collapseWhitespace(s string) string {
+	lines := strings.Split(s, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func truncateText(s string, limit int) (string, bool) {
+	runes := []rune(s)
+	if len(runes) <= limit {
+		return s, false
+	}
+	return string(runes[:limit]), true
+}
\ No newline at end of file
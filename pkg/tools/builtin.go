@@ -0,0 +1,708 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	maxBuiltinCount   = 100
+	maxRegexMatches   = 1000
+	maxBuiltinTextLen = 1 << 20
+)
+
+// builtinFunction is a small utility that runs in process.
+type builtinFunction struct {
+	description string
+	properties  map[string]interface{}
+	required    []string
+	run         func(args map[string]interface{}) (interface{}, error)
+}
+
+var builtinFunctions = map[string]builtinFunction{
+	"calculator": {
+		description: "Evaluate an arithmetic expression. Supports + - * / %, ^ for powers, parentheses, " +
+			"the constants pi and e, and sqrt, abs, round, floor, ceil, exp, ln, log, sin, cos, tan, pow, min and max.",
+		properties: map[string]interface{}{
+			"expression": map[string]interface{}{"type": "string", "description": "For example (2 + 3) * sqrt(16)"},
+		},
+		required: []string{"expression"},
+		run:      runCalculator,
+	},
+	"datetime": {
+		description: "Work with dates and times: now, convert a time to another timezone or format, " +
+			"add a duration to a time, or find the difference between two times.",
+		properties: map[string]interface{}{
+			"operation": map[string]interface{}{"type": "string", "enum": []string{"now", "convert", "add", "diff"}},
+			"time":      map[string]interface{}{"type": "string", "description": "RFC 3339, a date, or a Unix timestamp (default: now)"},
+			"end":       map[string]interface{}{"type": "string", "description": "Second time, for diff"},
+			"timezone":  map[string]interface{}{"type": "string", "description": "IANA timezone such as Europe/Paris (default: UTC)"},
+			"format":    map[string]interface{}{"type": "string", "description": "rfc3339, date, time, datetime, kitchen, unix, or a Go layout"},
+			"duration":  map[string]interface{}{"type": "string", "description": "Duration to add, such as 90m or -2h30m, for add"},
+			"days":      map[string]interface{}{"type": "integer", "description": "Days to add, for add"},
+			"months":    map[string]interface{}{"type": "integer", "description": "Months to add, for add"},
+			"years":     map[string]interface{}{"type": "integer", "description": "Years to add, for add"},
+		},
+		required: []string{"operation"},
+		run:      runDatetime,
+	},
+	"uuid": {
+		description: "Generate random (version 4) UUIDs.",
+		properties: map[string]interface{}{
+			"count": map[string]interface{}{"type": "integer", "description": "How many to generate (default: 1)"},
+		},
+		run: runUUID,
+	},
+	"random": {
+		description: "Generate random values: an integer or float in a range, a choice from a list, or a shuffled list.",
+		properties: map[string]interface{}{
+			"kind":  map[string]interface{}{"type": "string", "enum": []string{"int", "float", "choice", "shuffle"}},
+			"min":   map[string]interface{}{"type": "number", "description": "Lower bound, inclusive (default: 0)"},
+			"max":   map[string]interface{}{"type": "number", "description": "Upper bound, inclusive for int (default: 100 for int, 1 for float)"},
+			"items": map[string]interface{}{"type": "array", "description": "Items to choose from or shuffle"},
+			"count": map[string]interface{}{"type": "integer", "description": "How many values or choices (default: 1)"},
+		},
+		required: []string{"kind"},
+		run:      runRandom,
+	},
+	"jq": {
+		description: "Transform JSON with a jq expression. Supports paths (.a.b, .[0], .[], .[1:3]), pipes, commas, " +
+			"array and object construction, arithmetic, comparisons, and/or, //, and the functions select, map, " +
+			"sort, sort_by, group_by, unique, keys, length, has, add, min, max, first, last, reverse, flatten, " +
+			"to_entries, from_entries, join, split, tostring, tonumber, type, not, ascii_downcase and ascii_upcase.",
+		properties: map[string]interface{}{
+			"input":      map[string]interface{}{"description": "JSON value, or a string containing JSON"},
+			"expression": map[string]interface{}{"type": "string", "description": "For example .items[] | select(.price > 10) | .name"},
+		},
+		required: []string{"input", "expression"},
+		run:      runJQTool,
+	},
+	"regex_extract": {
+		description: "Extract matches of a regular expression (RE2 syntax) from text, with their capture groups.",
+		properties: map[string]interface{}{
+			"pattern": map[string]interface{}{"type": "string"},
+			"text":    map[string]interface{}{"type": "string"},
+			"all":     map[string]interface{}{"type": "boolean", "description": "Return every match rather than the first (default: true)"},
+		},
+		required: []string{"pattern", "text"},
+		run:      runRegexExtract,
+	},
+	"unit_convert": {
+		description: "Convert a value between units of length, area, mass, volume, time, speed, data or temperature, " +
+			"such as km to mi, lb to kg, gal to l, GiB to MB or F to C.",
+		properties: map[string]interface{}{
+			"value": map[string]interface{}{"type": "number"},
+			"from":  map[string]interface{}{"type": "string"},
+			"to":    map[string]interface{}{"type": "string"},
+		},
+		required: []string{"value", "from", "to"},
+		run:      runUnitConvert,
+	},
+}
+
+// BuiltinTool runs one of the built-in utility functions in process, so
+// simple agents need no external service for arithmetic, dates, IDs and
+// the like. The function is chosen by the tool config's "tool" entry, or
+// by the tool's name when that is one of the functions.
+type BuiltinTool struct {
+	config   *Config
+	function string
+	builtin  builtinFunction
+}
+
+func NewBuiltinTool(config *Config) (*BuiltinTool, error) {
+	function := config.Config["tool"]
+	if function == "" {
+		function = config.Name
+	}
+	builtin, ok := builtinFunctions[function]
+	if !ok {
+		return nil, fmt.Errorf("unknown builtin tool %q, expected one of %s", function, strings.Join(BuiltinToolNames(), ", "))
+	}
+	
+	return &BuiltinTool{
+		config:   config,
+		function: function,
+		builtin:  builtin,
+	}, nil
+}
+
+// BuiltinToolNames lists the built-in functions.
+func BuiltinToolNames() []string {
+	names := make([]string, 0, len(builtinFunctions))
+	for name := range builtinFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *BuiltinTool) Name() string {
+	return t.config.Name
+}
+
+func (t *BuiltinTool) Type() string {
+	return "builtin"
+}
+
+func (t *BuiltinTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = t.builtin.description
+	}
+	
+	parameters := map[string]interface{}{
+		"type":       "object",
+		"properties": t.builtin.properties,
+	}
+	if len(t.builtin.required) > 0 {
+		parameters["required"] = t.builtin.required
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters:  parameters,
+	}
+}
+
+func (t *BuiltinTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	for _, name := range t.builtin.required {
+		if _, ok := args[name]; !ok {
+			return &Result{Error: fmt.Sprintf("%s is required", name)}, nil
+		}
+	}
+	
+	data, err := t.builtin.run(args)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	return &Result{Data: data, Metadata: map[string]interface{}{"builtin": t.function}}, nil
+}
+
+func (t *BuiltinTool) Close() error {
+	return nil
+}
+
+// numberArg reads a numeric argument, accepting numbers sent as strings.
+func numberArg(args map[string]interface{}, name string, fallback float64) (float64, error) {
+	switch v := args[name].(type) {
+	case nil:
+		return fallback, nil
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be a number", name)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%s must be a number", name)
+	}
+}
+
+// countArg reads a count between 1 and maxBuiltinCount.
+func countArg(args map[string]interface{}) (int, error) {
+	count, err := numberArg(args, "count", 1)
+	if err != nil {
+		return 0, err
+	}
+	if count < 1 || count > maxBuiltinCount || count != math.Trunc(count) {
+		return 0, fmt.Errorf("count must be a whole number from 1 to %d", maxBuiltinCount)
+	}
+	return int(count), nil
+}
+
+func runCalculator(args map[string]interface{}) (interface{}, error) {
+	expression, _ := args["expression"].(string)
+	result, err := evaluateExpression(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	return map[string]interface{}{
+		"expression": expression,
+		"result":     result,
+	}, nil
+}
+
+var datetimeInputLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC850,
+	time.ANSIC,
+}
+
+var datetimeFormats = map[string]string{
+	"rfc3339":  time.RFC3339,
+	"date":     "2006-01-02",
+	"time":     "15:04:05",
+	"datetime": "2006-01-02 15:04:05",
+	"kitchen":  time.Kitchen,
+	"rfc1123":  time.RFC1123,
+}
+
+func runDatetime(args map[string]interface{}) (interface{}, error) {
+	location := time.UTC
+	if name, _ := args["timezone"].(string); name != "" {
+		var err error
+		if location, err = time.LoadLocation(name); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", name)
+		}
+	}
+	
+	start, err := parseDatetime(args["time"], location)
+	if err != nil {
+		return nil, err
+	}
+	format, _ := args["format"].(string)
+	
+	operation, _ := args["operation"].(string)
+	switch operation {
+	case "now", "convert":
+		return describeTime(start.In(location), format), nil
+	case "add":
+		years, err := numberArg(args, "years", 0)
+		if err != nil {
+			return nil, err
+		}
+		months, err := numberArg(args, "months", 0)
+		if err != nil {
+			return nil, err
+		}
+		days, err := numberArg(args, "days", 0)
+		if err != nil {
+			return nil, err
+		}
+		// Adding months to the 31st ends on the last day of the month
+		// rather than spilling into the next one
+		start = start.In(location)
+		result := start.AddDate(int(years), int(months), 0)
+		if result.Day() != start.Day() {
+			result = result.AddDate(0, 0, -result.Day())
+		}
+		result = result.AddDate(0, 0, int(days))
+		if value, _ := args["duration"].(string); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration %q", value)
+			}
+			result = result.Add(duration)
+		}
+		return describeTime(result, format), nil
+	case "diff":
+		if args["end"] == nil {
+			return nil, fmt.Errorf("end is required for diff")
+		}
+		end, err := parseDatetime(args["end"], location)
+		if err != nil {
+			return nil, err
+		}
+		difference := end.Sub(start)
+		return map[string]interface{}{
+			"seconds":  difference.Seconds(),
+			"minutes":  difference.Minutes(),
+			"hours":    difference.Hours(),
+			"days":     difference.Hours() / 24,
+			"duration": difference.String(),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %q, expected now, convert, add or diff", operation)
+	}
+}
+
+// parseDatetime reads a time in one of the common layouts or as a Unix
+// timestamp. Times without an offset are in location; no time means now.
+func parseDatetime(value interface{}, location *time.Location) (time.Time, error) {
+	switch v := value.(type) {
+	case nil:
+		return time.Now(), nil
+	case float64:
+		return unixTime(v), nil
+	case string:
+		v = strings.TrimSpace(v)
+		if v == "" || v == "now" {
+			return time.Now(), nil
+		}
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return unixTime(n), nil
+		}
+		for _, layout := range datetimeInputLayouts {
+			if t, err := time.ParseInLocation(layout, v, location); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("cannot parse time %q; use RFC 3339, such as 2024-05-01T09:30:00Z", v)
+	default:
+		return time.Time{}, fmt.Errorf("time must be a string or a Unix timestamp")
+	}
+}
+
+// unixTime accepts seconds or, for values too large to be seconds,
+// milliseconds.
+func unixTime(n float64) time.Time {
+	if math.Abs(n) > 1e11 {
+		return time.UnixMilli(int64(n))
+	}
+	seconds, fraction := math.Modf(n)
+	return time.Unix(int64(seconds), int64(fraction*1e9))
+}
+
+func describeTime(t time.Time, format string) map[string]interface{} {
+	zone, offset := t.Zone()
+	data := map[string]interface{}{
+		"time":           t.Format(time.RFC3339),
+		"unix":           t.Unix(),
+		"timezone":       t.Location().String(),
+		"zone":           zone,
+		"offset_seconds": offset,
+		"weekday":        t.Weekday().String(),
+		"day_of_year":    t.YearDay(),
+	}
+	_, week := t.ISOWeek()
+	data["iso_week"] = week
+	
+	switch format {
+	case "":
+	case "unix":
+		data["formatted"] = strconv.FormatInt(t.Unix(), 10)
+	default:
+		layout, ok := datetimeFormats[strings.ToLower(format)]
+		if !ok {
+			layout = format
+		}
+		data["formatted"] = t.Format(layout)
+	}
+	return data
+}
+
+func runUUID(args map[string]interface{}) (interface{}, error) {
+	count, err := countArg(args)
+	if err != nil {
+		return nil, err
+	}
+	
+	uuids := make([]string, count)
+	for i := range uuids {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return nil, fmt.Errorf("failed to generate UUID: %w", err)
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		uuids[i] = fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+	}
+	return map[string]interface{}{"uuids": uuids}, nil
+}
+
+func runRandom(args map[string]interface{}) (interface{}, error) {
+	count, err := countArg(args)
+	if err != nil {
+		return nil, err
+	}
+	
+	kind, _ := args["kind"].(string)
+	switch kind {
+	case "int":
+		low, err := numberArg(args, "min", 0)
+		if err != nil {
+			return nil, err
+		}
+		high, err := numberArg(args, "max", 100)
+		if err != nil {
+			return nil, err
+		}
+		low, high = math.Ceil(low), math.Floor(high)
+		if high < low || high-low > math.MaxInt64/2 {
+			return nil, fmt.Errorf("min must not be greater than max")
+		}
+		values := make([]int64, count)
+		for i := range values {
+			n, err := randomInt(int64(high-low) + 1)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = int64(low) + n
+		}
+		return map[string]interface{}{"values": values}, nil
+	case "float":
+		low, err := numberArg(args, "min", 0)
+		if err != nil {
+			return nil, err
+		}
+		high, err := numberArg(args, "max", 1)
+		if err != nil {
+			return nil, err
+		}
+		if high < low {
+			return nil, fmt.Errorf("min must not be greater than max")
+		}
+		values := make([]float64, count)
+		for i := range values {
+			n, err := randomInt(1 << 53)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = low + (high-low)*float64(n)/(1<<53)
+		}
+		return map[string]interface{}{"values": values}, nil
+	case "choice", "shuffle":
+		items, ok := args["items"].([]interface{})
+		if !ok || len(items) == 0 {
+			return nil, fmt.Errorf("items must be a non-empty array")
+		}
+		shuffled := append([]interface{}{}, items...)
+		for i := len(shuffled) - 1; i > 0; i-- {
+			j, err := randomInt(int64(i) + 1)
+			if err != nil {
+				return nil, err
+			}
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		}
+		if kind == "shuffle" {
+			return map[string]interface{}{"values": shuffled}, nil
+		}
+		// Choices are drawn without replacement
+		if count > len(shuffled) {
+			return nil, fmt.Errorf("count is larger than the number of items")
+		}
+		return map[string]interface{}{"values": shuffled[:count]}, nil
+	default:
+		return nil, fmt.Errorf("unknown kind %q, expected int, float, choice or shuffle", kind)
+	}
+}
+
+// randomInt returns a uniform value in [0, n) from crypto/rand.
+func randomInt(n int64) (int64, error) {
+	value, err := rand.Int(rand.Reader, big.NewInt(n))
+	if err != nil {
+		return 0, fmt.Errorf("failed to generate random number: %w", err)
+	}
+	return value.Int64(), nil
+}
+
+func runJQTool(args map[string]interface{}) (interface{}, error) {
+	expression, _ := args["expression"].(string)
+	filter, err := compileJQ(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression: %w", err)
+	}
+	
+	input := args["input"]
+	if text, ok := input.(string); ok {
+		if len(text) > maxBuiltinTextLen {
+			return nil, fmt.Errorf("input is larger than %d bytes", maxBuiltinTextLen)
+		}
+		if err := json.Unmarshal([]byte(text), &input); err != nil {
+			return nil, fmt.Errorf("input is not valid JSON: %w", err)
+		}
+	}
+	
+	results, err := runJQ(filter, input)
+	if err != nil {
+		return nil, err
+	}
+	if results == nil {
+		results = []interface{}{}
+	}
+	return map[string]interface{}{"results": results}, nil
+}
+
+func runRegexExtract(args map[string]interface{}) (interface{}, error) {
+	pattern, _ := args["pattern"].(string)
+	text, _ := args["text"].(string)
+	if len(text) > maxBuiltinTextLen {
+		return nil, fmt.Errorf("text is larger than %d bytes", maxBuiltinTextLen)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern: %w", err)
+	}
+	
+	limit := maxRegexMatches
+	if all, ok := args["all"].(bool); ok && !all {
+		limit = 1
+	}
+	
+	names := re.SubexpNames()
+	indexes := re.FindAllStringSubmatchIndex(text, limit)
+	matches := make([]map[string]interface{}, 0, len(indexes))
+	for _, index := range indexes {
+		match := map[string]interface{}{
+			"match": text[index[0]:index[1]],
+			"start": index[0],
+			"end":   index[1],
+		}
+		groups := make([]interface{}, 0, len(names)-1)
+		named := make(map[string]interface{})
+		for group := 1; group < len(names); group++ {
+			var value interface{}
+			if index[2*group] >= 0 {
+				value = text[index[2*group]:index[2*group+1]]
+			}
+			groups = append(groups, value)
+			if names[group] != "" {
+				named[names[group]] = value
+			}
+		}
+		if len(groups) > 0 {
+			match["groups"] = groups
+		}
+		if len(named) > 0 {
+			match["named"] = named
+		}
+		matches = append(matches, match)
+	}
+	
+	return map[string]interface{}{
+		"matches": matches,
+		"count":   len(matches),
+	}, nil
+}
+
+// unit is a unit's size in its dimension's base unit.
+type unit struct {
+	dimension string
+	factor    float64
+}
+
+var units = map[string]unit{
+	"mm": {"length", 0.001}, "cm": {"length", 0.01}, "m": {"length", 1}, "km": {"length", 1000},
+	"in": {"length", 0.0254}, "ft": {"length", 0.3048}, "yd": {"length", 0.9144},
+	"mi": {"length", 1609.344}, "nmi": {"length", 1852},
+	
+	"mm2": {"area", 1e-6}, "cm2": {"area", 1e-4}, "m2": {"area", 1}, "km2": {"area", 1e6},
+	"ha": {"area", 1e4}, "in2": {"area", 0.00064516}, "ft2": {"area", 0.09290304},
+	"yd2": {"area", 0.83612736}, "acre": {"area", 4046.8564224}, "mi2": {"area", 2589988.110336},
+	
+	"mg": {"mass", 1e-6}, "g": {"mass", 0.001}, "kg": {"mass", 1}, "t": {"mass", 1000},
+	"oz": {"mass", 0.028349523125}, "lb": {"mass", 0.45359237}, "st": {"mass", 6.35029318},
+	
+	"ml": {"volume", 0.001}, "cl": {"volume", 0.01}, "l": {"volume", 1}, "m3": {"volume", 1000},
+	"tsp": {"volume", 0.00492892159375}, "tbsp": {"volume", 0.01478676478125},
+	"floz": {"volume", 0.0295735295625}, "cup": {"volume", 0.2365882365},
+	"pt": {"volume", 0.473176473}, "qt": {"volume", 0.946352946}, "gal": {"volume", 3.785411784},
+	
+	"ns": {"time", 1e-9}, "us": {"time", 1e-6}, "ms": {"time", 0.001}, "s": {"time", 1},
+	"min": {"time", 60}, "h": {"time", 3600}, "d": {"time", 86400}, "wk": {"time", 604800},
+	"yr": {"time", 31557600},
+	
+	"m/s": {"speed", 1}, "km/h": {"speed", 1 / 3.6}, "mph": {"speed", 0.44704},
+	"ft/s": {"speed", 0.3048}, "kn": {"speed", 1852.0 / 3600},
+	
+	"bit": {"data", 0.125}, "b": {"data", 1}, "kb": {"data", 1e3}, "mb": {"data", 1e6},
+	"gb": {"data", 1e9}, "tb": {"data", 1e12}, "pb": {"data", 1e15}, "kib": {"data", 1 << 10},
+	"mib": {"data", 1 << 20}, "gib": {"data", 1 << 30}, "tib": {"data", 1 << 40}, "pib": {"data", 1 << 50},
+}
+
+var unitAliases = map[string]string{
+	"millimeter": "mm", "centimeter": "cm", "meter": "m", "metre": "m", "kilometer": "km", "kilometre": "km",
+	"inch": "in", "inches": "in", "foot": "ft", "feet": "ft", "yard": "yd", "mile": "mi", "nautical_mile": "nmi",
+	"sqm": "m2", "hectare": "ha", "sqft": "ft2", "acres": "acre",
+	"milligram": "mg", "gram": "g", "kilogram": "kg", "tonne": "t", "ounce": "oz", "pound": "lb", "lbs": "lb", "stone": "st",
+	"milliliter": "ml", "millilitre": "ml", "liter": "l", "litre": "l", "gallon": "gal", "quart": "qt", "pint": "pt",
+	"fl_oz": "floz", "teaspoon": "tsp", "tablespoon": "tbsp", "cups": "cup",
+	"second": "s", "sec": "s", "minute": "min", "hour": "h", "hr": "h", "day": "d", "week": "wk", "year": "yr",
+	"kph": "km/h", "kmh": "km/h", "mps": "m/s", "knot": "kn", "knots": "kn",
+	"byte": "b", "bits": "bit",
+	"celsius": "c", "fahrenheit": "f", "kelvin": "k",
+}
+
+func runUnitConvert(args map[string]interface{}) (interface{}, error) {
+	value, err := numberArg(args, "value", 0)
+	if err != nil {
+		return nil, err
+	}
+	fromName, _ := args["from"].(string)
+	toName, _ := args["to"].(string)
+	from, to := normalizeUnit(fromName), normalizeUnit(toName)
+	
+	var result float64
+	if isTemperatureUnit(from) || isTemperatureUnit(to) {
+		if !isTemperatureUnit(from) || !isTemperatureUnit(to) {
+			return nil, fmt.Errorf("cannot convert %s to %s", fromName, toName)
+		}
+		result = fromKelvin(toKelvin(value, from), to)
+	} else {
+		fromUnit, ok := units[from]
+		if !ok {
+			return nil, fmt.Errorf("unknown unit %q", fromName)
+		}
+		toUnit, ok := units[to]
+		if !ok {
+			return nil, fmt.Errorf("unknown unit %q", toName)
+		}
+		if fromUnit.dimension != toUnit.dimension {
+			return nil, fmt.Errorf("cannot convert %s (%s) to %s (%s)", fromName, fromUnit.dimension, toName, toUnit.dimension)
+		}
+		result = value * fromUnit.factor / toUnit.factor
+	}
+	
+	return map[string]interface{}{
+		"value":  value,
+		"from":   fromName,
+		"to":     toName,
+		"result": result,
+	}, nil
+}
+
+// normalizeUnit lower-cases a unit name and resolves aliases and plurals.
+// Names are case-insensitive, so "b" is a byte and bits are "bit".
+func normalizeUnit(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "°")
+	name = strings.NewReplacer("²", "2", "³", "3", " ", "_", "-", "_").Replace(name)
+	if _, ok := units[name]; ok {
+		return name
+	}
+	if alias, ok := unitAliases[name]; ok {
+		return alias
+	}
+	if alias, ok := unitAliases[strings.TrimSuffix(name, "s")]; ok {
+		return alias
+	}
+	return name
+}
+
+func isTemperatureUnit(name string) bool {
+	return name == "c" || name == "f" || name == "k"
+}
+
+func toKelvin(value float64, from string) float64 {
+	switch from {
+	case "c":
+		return value + 273.15
+	case "f":
+		return (value-32)*5/9 + 273.15
+	default:
+		return value
+	}
+}
+
+func fromKelvin(value float64, to string) float64 {
+	switch to {
+	case "c":
+		return value - 273.15
+	case "f":
+		return (value-273.15)*9/5 + 32
+	default:
+		return value
+	}
+}
\ No newline at end of file
@@ -0,0 +1,275 @@
+package tools
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const maxExpressionLength = 1000
+
+var calculatorConstants = map[string]float64{
+	"pi":  math.Pi,
+	"e":   math.E,
+	"tau": 2 * math.Pi,
+	"phi": math.Phi,
+}
+
+// calculatorFunctions maps function names to their arity and
+// implementation. An arity of -1 accepts one or more arguments.
+var calculatorFunctions = map[string]struct {
+	arity int
+	fn    func(args []float64) float64
+}{
+	"sqrt":  {1, func(a []float64) float64 { return math.Sqrt(a[0]) }},
+	"cbrt":  {1, func(a []float64) float64 { return math.Cbrt(a[0]) }},
+	"abs":   {1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"round": {1, func(a []float64) float64 { return math.Round(a[0]) }},
+	"floor": {1, func(a []float64) float64 { return math.Floor(a[0]) }},
+	"ceil":  {1, func(a []float64) float64 { return math.Ceil(a[0]) }},
+	"trunc": {1, func(a []float64) float64 { return math.Trunc(a[0]) }},
+	"exp":   {1, func(a []float64) float64 { return math.Exp(a[0]) }},
+	"ln":    {1, func(a []float64) float64 { return math.Log(a[0]) }},
+	"log":   {1, func(a []float64) float64 { return math.Log10(a[0]) }},
+	"log2":  {1, func(a []float64) float64 { return math.Log2(a[0]) }},
+	"sin":   {1, func(a []float64) float64 { return math.Sin(a[0]) }},
+	"cos":   {1, func(a []float64) float64 { return math.Cos(a[0]) }},
+	"tan":   {1, func(a []float64) float64 { return math.Tan(a[0]) }},
+	"asin":  {1, func(a []float64) float64 { return math.Asin(a[0]) }},
+	"acos":  {1, func(a []float64) float64 { return math.Acos(a[0]) }},
+	"atan":  {1, func(a []float64) float64 { return math.Atan(a[0]) }},
+	"pow":   {2, func(a []float64) float64 { return math.Pow(a[0], a[1]) }},
+	"hypot": {2, func(a []float64) float64 { return math.Hypot(a[0], a[1]) }},
+	"min": {-1, func(a []float64) float64 {
+		result := a[0]
+		for _, v := range a[1:] {
+			result = math.Min(result, v)
+		}
+		return result
+	}},
+	"max": {-1, func(a []float64) float64 {
+		result := a[0]
+		for _, v := range a[1:] {
+			result = math.Max(result, v)
+		}
+		return result
+	}},
+}
+
+// evaluateExpression computes an arithmetic expression with + - * / %, ^
+// for powers, parentheses, the constants above and the functions above.
+func evaluateExpression(expression string) (float64, error) {
+	if len(expression) > maxExpressionLength {
+		return 0, fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+	
+	p := &calculatorParser{src: expression}
+	p.next()
+	value, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok != "" {
+		return 0, fmt.Errorf("unexpected %q at offset %d", p.tok, p.start)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+type calculatorParser struct {
+	src   string
+	pos   int
+	start int
+	tok   string
+}
+
+func (p *calculatorParser) next() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n') {
+		p.pos++
+	}
+	p.start = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = ""
+		return
+	}
+	
+	c := p.src[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		// An exponent, as in 1.5e-3
+		if p.pos < len(p.src) && (p.src[p.pos] == 'e' || p.src[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.src) && (p.src[end] == '+' || p.src[end] == '-') {
+				end++
+			}
+			if end < len(p.src) && p.src[end] >= '0' && p.src[end] <= '9' {
+				for end < len(p.src) && p.src[end] >= '0' && p.src[end] <= '9' {
+					end++
+				}
+				p.pos = end
+			}
+		}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+	case c == '*' && p.pos+1 < len(p.src) && p.src[p.pos+1] == '*':
+		// ** is accepted as a power operator
+		p.pos += 2
+		p.tok = "^"
+		return
+	default:
+		p.pos++
+	}
+	p.tok = p.src[p.start:p.pos]
+}
+
+func (p *calculatorParser) expression() (float64, error) {
+	value, err := p.term()
+	if err != nil {
+		return 0, err
+	}
+	for p.tok == "+" || p.tok == "-" {
+		op := p.tok
+		p.next()
+		right, err := p.term()
+		if err != nil {
+			return 0, err
+		}
+		if op == "+" {
+			value += right
+		} else {
+			value -= right
+		}
+	}
+	return value, nil
+}
+
+func (p *calculatorParser) term() (float64, error) {
+	value, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	for p.tok == "*" || p.tok == "/" || p.tok == "%" {
+		op := p.tok
+		p.next()
+		right, err := p.unary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "*":
+			value *= right
+		case "/":
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			value /= right
+		case "%":
+			if right == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			value = math.Mod(value, right)
+		}
+	}
+	return value, nil
+}
+
+// unary binds more loosely than ^, so -2^2 is -4.
+func (p *calculatorParser) unary() (float64, error) {
+	switch p.tok {
+	case "-":
+		p.next()
+		value, err := p.unary()
+		return -value, err
+	case "+":
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *calculatorParser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil {
+		return 0, err
+	}
+	if p.tok != "^" {
+		return base, nil
+	}
+	p.next()
+	exponent, err := p.unary()
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exponent), nil
+}
+
+func (p *calculatorParser) primary() (float64, error) {
+	tok, start := p.tok, p.start
+	switch {
+	case tok == "":
+		return 0, fmt.Errorf("unexpected end of expression")
+	case tok == "(":
+		p.next()
+		value, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if p.tok != ")" {
+			return 0, fmt.Errorf("missing ) at offset %d", p.start)
+		}
+		p.next()
+		return value, nil
+	case tok[0] >= '0' && tok[0] <= '9' || tok[0] == '.':
+		value, err := strconv.ParseFloat(tok, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q at offset %d", tok, start)
+		}
+		p.next()
+		return value, nil
+	case isIdentChar(tok[0]):
+		name := strings.ToLower(tok)
+		p.next()
+		if p.tok != "(" {
+			value, ok := calculatorConstants[name]
+			if !ok {
+				return 0, fmt.Errorf("unknown constant %q at offset %d", tok, start)
+			}
+			return value, nil
+		}
+		
+		function, ok := calculatorFunctions[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown function %q at offset %d", tok, start)
+		}
+		p.next()
+		var args []float64
+		for p.tok != ")" {
+			if len(args) > 0 {
+				if p.tok != "," {
+					return 0, fmt.Errorf("expected , or ) at offset %d", p.start)
+				}
+				p.next()
+			}
+			arg, err := p.expression()
+			if err != nil {
+				return 0, err
+			}
+			args = append(args, arg)
+		}
+		p.next()
+		if function.arity >= 0 && len(args) != function.arity || len(args) == 0 {
+			return 0, fmt.Errorf("%s takes %d argument(s), got %d", name, maxInt(function.arity, 1), len(args))
+		}
+		return function.fn(args), nil
+	default:
+		return 0, fmt.Errorf("unexpected %q at offset %d", tok, start)
+	}
+}
\ No newline at end of file
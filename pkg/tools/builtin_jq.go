@@ -0,0 +1,1313 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxJQResults = 10000
+
+// jqFilter maps an input value to the values it produces.
+type jqFilter func(input interface{}) ([]interface{}, error)
+
+// compileJQ compiles a subset of jq: paths (.a, .a.b, ."key", .[0], .[1:3],
+// .[]), pipes, commas, literals, array and object construction, arithmetic,
+// comparisons, and/or, and the functions in jqFunctions.
+func compileJQ(expression string) (jqFilter, error) {
+	if len(expression) > maxExpressionLength {
+		return nil, fmt.Errorf("expression is longer than %d characters", maxExpressionLength)
+	}
+	
+	p := &jqParser{src: expression}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	filter, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != 0 {
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.tok.value, p.start)
+	}
+	return filter, nil
+}
+
+// runJQ applies a compiled filter, bounding how many values it may produce.
+func runJQ(filter jqFilter, input interface{}) ([]interface{}, error) {
+	results, err := filter(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > maxJQResults {
+		return nil, fmt.Errorf("expression produced more than %d results", maxJQResults)
+	}
+	return results, nil
+}
+
+type jqToken struct {
+	kind  byte // 'n' name, 'p' punctuation or operator, 's' string, '0' number, 0 end
+	value string
+}
+
+type jqParser struct {
+	src   string
+	pos   int
+	start int
+	tok   jqToken
+}
+
+func (p *jqParser) next() error {
+	for p.pos < len(p.src) && strings.ContainsRune(" \t\r\n", rune(p.src[p.pos])) {
+		p.pos++
+	}
+	p.start = p.pos
+	if p.pos >= len(p.src) {
+		p.tok = jqToken{}
+		return nil
+	}
+	
+	c := p.src[p.pos]
+	switch {
+	case c == '"':
+		end := p.pos + 1
+		for end < len(p.src) && p.src[end] != '"' {
+			if p.src[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(p.src) {
+			return fmt.Errorf("unterminated string at offset %d", p.start)
+		}
+		value, err := strconv.Unquote(p.src[p.pos : end+1])
+		if err != nil {
+			return fmt.Errorf("invalid string at offset %d", p.start)
+		}
+		p.pos = end + 1
+		p.tok = jqToken{kind: 's', value: value}
+	case c >= '0' && c <= '9':
+		for p.pos < len(p.src) && (p.src[p.pos] >= '0' && p.src[p.pos] <= '9' || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		p.tok = jqToken{kind: '0', value: p.src[p.start:p.pos]}
+	case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = jqToken{kind: 'n', value: p.src[p.start:p.pos]}
+	default:
+		for _, op := range []string{"==", "!=", "<=", ">=", "//"} {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += len(op)
+				p.tok = jqToken{kind: 'p', value: op}
+				return nil
+			}
+		}
+		if !strings.ContainsRune(".|,[]{}():<>+-*/%", rune(c)) {
+			return fmt.Errorf("unexpected character %q at offset %d", c, p.pos)
+		}
+		p.pos++
+		p.tok = jqToken{kind: 'p', value: string(c)}
+	}
+	return nil
+}
+
+// adjacentKey reports whether the dot just read is directly followed by a
+// key, as in .name, rather than being the identity as in ". and .b".
+func (p *jqParser) adjacentKey() bool {
+	if p.pos >= len(p.src) {
+		return false
+	}
+	c := p.src[p.pos]
+	return c == '"' || c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func (p *jqParser) is(value string) bool {
+	return p.tok.kind == 'p' && p.tok.value == value
+}
+
+func (p *jqParser) expect(value string) error {
+	if !p.is(value) {
+		return fmt.Errorf("expected %q at offset %d", value, p.start)
+	}
+	return p.next()
+}
+
+func (p *jqParser) pipe() (jqFilter, error) {
+	left, err := p.comma()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("|") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.comma()
+		if err != nil {
+			return nil, err
+		}
+		left = jqCompose(left, right)
+	}
+	return left, nil
+}
+
+func jqCompose(left, right jqFilter) jqFilter {
+	return func(input interface{}) ([]interface{}, error) {
+		values, err := left(input)
+		if err != nil {
+			return nil, err
+		}
+		var results []interface{}
+		for _, value := range values {
+			outputs, err := right(value)
+			if err != nil {
+				return nil, err
+			}
+			results = append(results, outputs...)
+			if len(results) > maxJQResults {
+				return nil, fmt.Errorf("expression produced more than %d results", maxJQResults)
+			}
+		}
+		return results, nil
+	}
+}
+
+func (p *jqParser) comma() (jqFilter, error) {
+	left, err := p.alternative()
+	if err != nil {
+		return nil, err
+	}
+	for p.is(",") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.alternative()
+		if err != nil {
+			return nil, err
+		}
+		first := left
+		left = func(input interface{}) ([]interface{}, error) {
+			a, err := first(input)
+			if err != nil {
+				return nil, err
+			}
+			b, err := right(input)
+			if err != nil {
+				return nil, err
+			}
+			return append(a, b...), nil
+		}
+	}
+	return left, nil
+}
+
+// alternative is a // b: the truthy values of a, or b when there are none.
+func (p *jqParser) alternative() (jqFilter, error) {
+	left, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("//") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		first := left
+		left = func(input interface{}) ([]interface{}, error) {
+			values, err := first(input)
+			if err == nil {
+				var truthy []interface{}
+				for _, value := range values {
+					if jqTruthy(value) {
+						truthy = append(truthy, value)
+					}
+				}
+				if len(truthy) > 0 {
+					return truthy, nil
+				}
+			}
+			return right(input)
+		}
+	}
+	return left, nil
+}
+
+func (p *jqParser) or() (jqFilter, error) {
+	return p.binary(p.and, map[string]func(a, b interface{}) (interface{}, error){
+		"or": func(a, b interface{}) (interface{}, error) { return jqTruthy(a) || jqTruthy(b), nil },
+	})
+}
+
+func (p *jqParser) and() (jqFilter, error) {
+	return p.binary(p.comparison, map[string]func(a, b interface{}) (interface{}, error){
+		"and": func(a, b interface{}) (interface{}, error) { return jqTruthy(a) && jqTruthy(b), nil },
+	})
+}
+
+func (p *jqParser) comparison() (jqFilter, error) {
+	return p.binary(p.additive, map[string]func(a, b interface{}) (interface{}, error){
+		"==": func(a, b interface{}) (interface{}, error) { return compareJSON(a, b) == 0, nil },
+		"!=": func(a, b interface{}) (interface{}, error) { return compareJSON(a, b) != 0, nil },
+		"<":  func(a, b interface{}) (interface{}, error) { return compareJSON(a, b) < 0, nil },
+		"<=": func(a, b interface{}) (interface{}, error) { return compareJSON(a, b) <= 0, nil },
+		">":  func(a, b interface{}) (interface{}, error) { return compareJSON(a, b) > 0, nil },
+		">=": func(a, b interface{}) (interface{}, error) { return compareJSON(a, b) >= 0, nil },
+	})
+}
+
+func (p *jqParser) additive() (jqFilter, error) {
+	return p.binary(p.multiplicative, map[string]func(a, b interface{}) (interface{}, error){
+		"+": jqAdd,
+		"-": jqSubtract,
+	})
+}
+
+func (p *jqParser) multiplicative() (jqFilter, error) {
+	return p.binary(p.postfix, map[string]func(a, b interface{}) (interface{}, error){
+		"*": func(a, b interface{}) (interface{}, error) { return jqArithmetic("*", a, b) },
+		"/": func(a, b interface{}) (interface{}, error) { return jqArithmetic("/", a, b) },
+		"%": func(a, b interface{}) (interface{}, error) { return jqArithmetic("%", a, b) },
+	})
+}
+
+// binary parses left-associative operators, applying them to every pair of
+// values their operands produce.
+func (p *jqParser) binary(operand func() (jqFilter, error), ops map[string]func(a, b interface{}) (interface{}, error)) (jqFilter, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.kind == 'p' || p.tok.kind == 'n' {
+		op, ok := ops[p.tok.value]
+		if !ok {
+			break
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		first := left
+		left = func(input interface{}) ([]interface{}, error) {
+			as, err := first(input)
+			if err != nil {
+				return nil, err
+			}
+			bs, err := right(input)
+			if err != nil {
+				return nil, err
+			}
+			results := make([]interface{}, 0, len(as)*len(bs))
+			for _, b := range bs {
+				for _, a := range as {
+					value, err := op(a, b)
+					if err != nil {
+						return nil, err
+					}
+					results = append(results, value)
+				}
+			}
+			return results, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *jqParser) postfix() (jqFilter, error) {
+	filter, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var suffix jqFilter
+		switch {
+		case p.is(".") && p.adjacentKey():
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if suffix, err = p.field(); err != nil {
+				return nil, err
+			}
+		case p.is("["):
+			if suffix, err = p.index(); err != nil {
+				return nil, err
+			}
+		default:
+			return filter, nil
+		}
+		filter = jqCompose(filter, suffix)
+	}
+}
+
+func (p *jqParser) primary() (jqFilter, error) {
+	switch {
+	case p.is("."):
+		key := p.adjacentKey()
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		switch {
+		case key:
+			return p.field()
+		case p.is("["):
+			return p.index()
+		}
+		return func(input interface{}) ([]interface{}, error) {
+			return []interface{}{input}, nil
+		}, nil
+	case p.tok.kind == '0':
+		value, err := strconv.ParseFloat(p.tok.value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", p.tok.value)
+		}
+		return jqConstant(value), p.next()
+	case p.tok.kind == 's':
+		value := p.tok.value
+		return jqConstant(value), p.next()
+	case p.is("-"):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		operand, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		return jqCompose(operand, func(input interface{}) ([]interface{}, error) {
+			value, err := jqArithmetic("*", input, -1.0)
+			return []interface{}{value}, err
+		}), nil
+	case p.is("("):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		filter, err := p.pipe()
+		if err != nil {
+			return nil, err
+		}
+		return filter, p.expect(")")
+	case p.is("["):
+		return p.array()
+	case p.is("{"):
+		return p.object()
+	case p.tok.kind == 'n':
+		return p.function()
+	case p.tok.kind == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected %q at offset %d", p.tok.value, p.start)
+	}
+}
+
+func jqConstant(value interface{}) jqFilter {
+	return func(interface{}) ([]interface{}, error) {
+		return []interface{}{value}, nil
+	}
+}
+
+// field parses the key after a dot. A missing key, or a key of null,
+// gives null.
+func (p *jqParser) field() (jqFilter, error) {
+	if p.tok.kind != 'n' && p.tok.kind != 's' {
+		return nil, fmt.Errorf("expected a key at offset %d", p.start)
+	}
+	key := p.tok.value
+	return func(input interface{}) ([]interface{}, error) {
+		switch v := input.(type) {
+		case nil:
+			return []interface{}{nil}, nil
+		case map[string]interface{}:
+			return []interface{}{v[key]}, nil
+		default:
+			return nil, fmt.Errorf("cannot index %s with %q", jqType(input), key)
+		}
+	}, p.next()
+}
+
+// index parses [], [n], ["key"] and [from:to] after a value.
+func (p *jqParser) index() (jqFilter, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	if p.is("]") {
+		return jqIterate, p.next()
+	}
+	
+	var from, to jqFilter
+	var err error
+	if !p.is(":") {
+		if from, err = p.pipe(); err != nil {
+			return nil, err
+		}
+	}
+	if !p.is(":") {
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		return func(input interface{}) ([]interface{}, error) {
+			keys, err := from(input)
+			if err != nil {
+				return nil, err
+			}
+			results := make([]interface{}, 0, len(keys))
+			for _, key := range keys {
+				value, err := jqIndex(input, key)
+				if err != nil {
+					return nil, err
+				}
+				results = append(results, value)
+			}
+			return results, nil
+		}, nil
+	}
+	
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if !p.is("]") {
+		if to, err = p.pipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return func(input interface{}) ([]interface{}, error) {
+		bound := func(filter jqFilter) (*int, error) {
+			if filter == nil {
+				return nil, nil
+			}
+			values, err := filter(input)
+			if err != nil || len(values) != 1 {
+				return nil, fmt.Errorf("slice bounds must be single numbers")
+			}
+			n, ok := values[0].(float64)
+			if !ok {
+				return nil, fmt.Errorf("slice bounds must be single numbers")
+			}
+			i := int(n)
+			return &i, nil
+		}
+		start, err := bound(from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := bound(to)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jqSlice(input, start, end)
+		return []interface{}{value}, err
+	}, nil
+}
+
+func jqIterate(input interface{}) ([]interface{}, error) {
+	switch v := input.(type) {
+	case []interface{}:
+		return append([]interface{}{}, v...), nil
+	case map[string]interface{}:
+		keys := sortedMapKeys(v)
+		results := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			results = append(results, v[key])
+		}
+		return results, nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over %s", jqType(input))
+	}
+}
+
+func jqIndex(input, key interface{}) (interface{}, error) {
+	switch v := input.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("cannot index object with %s", jqType(key))
+		}
+		return v[name], nil
+	case []interface{}:
+		n, ok := key.(float64)
+		if !ok {
+			return nil, fmt.Errorf("cannot index array with %s", jqType(key))
+		}
+		i := int(n)
+		if i < 0 {
+			i += len(v)
+		}
+		if i < 0 || i >= len(v) {
+			return nil, nil
+		}
+		return v[i], nil
+	default:
+		return nil, fmt.Errorf("cannot index %s", jqType(input))
+	}
+}
+
+func jqSlice(input interface{}, start, end *int) (interface{}, error) {
+	var length int
+	switch v := input.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		length = len(v)
+	case string:
+		length = len([]rune(v))
+	default:
+		return nil, fmt.Errorf("cannot slice %s", jqType(input))
+	}
+	
+	clamp := func(bound *int, fallback int) int {
+		if bound == nil {
+			return fallback
+		}
+		i := *bound
+		if i < 0 {
+			i += length
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > length {
+			return length
+		}
+		return i
+	}
+	from, to := clamp(start, 0), clamp(end, length)
+	if to < from {
+		to = from
+	}
+	
+	if s, ok := input.(string); ok {
+		return string([]rune(s)[from:to]), nil
+	}
+	return append([]interface{}{}, input.([]interface{})[from:to]...), nil
+}
+
+func (p *jqParser) array() (jqFilter, error) {
+	if err := p.expect("["); err != nil {
+		return nil, err
+	}
+	if p.is("]") {
+		return func(interface{}) ([]interface{}, error) {
+			return []interface{}{[]interface{}{}}, nil
+		}, p.next()
+	}
+	items, err := p.pipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return func(input interface{}) ([]interface{}, error) {
+		values, err := items(input)
+		if err != nil {
+			return nil, err
+		}
+		if values == nil {
+			values = []interface{}{}
+		}
+		return []interface{}{values}, nil
+	}, nil
+}
+
+// object parses {a, "b": f, c: g}; a bare key takes the input's value for
+// that key. Each value must produce exactly one result.
+func (p *jqParser) object() (jqFilter, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+	
+	type entry struct {
+		key   string
+		value jqFilter
+	}
+	var entries []entry
+	for !p.is("}") {
+		if len(entries) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		if p.tok.kind != 'n' && p.tok.kind != 's' {
+			return nil, fmt.Errorf("expected a key at offset %d", p.start)
+		}
+		key := p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		
+		var value jqFilter
+		if p.is(":") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			var err error
+			if value, err = p.alternative(); err != nil {
+				return nil, err
+			}
+		} else {
+			value = func(input interface{}) ([]interface{}, error) {
+				field, err := jqIndex(input, key)
+				return []interface{}{field}, err
+			}
+		}
+		entries = append(entries, entry{key: key, value: value})
+	}
+	
+	return func(input interface{}) ([]interface{}, error) {
+		object := make(map[string]interface{}, len(entries))
+		for _, entry := range entries {
+			values, err := entry.value(input)
+			if err != nil {
+				return nil, err
+			}
+			if len(values) != 1 {
+				return nil, fmt.Errorf("value for %q must produce one result, got %d", entry.key, len(values))
+			}
+			object[entry.key] = values[0]
+		}
+		return []interface{}{object}, nil
+	}, p.next()
+}
+
+func (p *jqParser) function() (jqFilter, error) {
+	name := p.tok.value
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	
+	switch name {
+	case "true":
+		return jqConstant(true), nil
+	case "false":
+		return jqConstant(false), nil
+	case "null":
+		return jqConstant(nil), nil
+	case "empty":
+		return func(interface{}) ([]interface{}, error) { return nil, nil }, nil
+	}
+	
+	var arg jqFilter
+	if p.is("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var err error
+		if arg, err = p.pipe(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	
+	if fn, ok := jqFilterFunctions[name]; ok {
+		if arg == nil {
+			return nil, fmt.Errorf("%s requires an argument", name)
+		}
+		return func(input interface{}) ([]interface{}, error) {
+			return fn(input, arg)
+		}, nil
+	}
+	
+	fn, ok := jqFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s", name)
+	}
+	if arg != nil {
+		return nil, fmt.Errorf("%s takes no argument", name)
+	}
+	return func(input interface{}) ([]interface{}, error) {
+		value, err := fn(input)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{value}, nil
+	}, nil
+}
+
+// jqFunctions take no argument and produce one value.
+var jqFunctions = map[string]func(input interface{}) (interface{}, error){
+	"length": func(input interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case nil:
+			return 0.0, nil
+		case string:
+			return float64(len([]rune(v))), nil
+		case []interface{}:
+			return float64(len(v)), nil
+		case map[string]interface{}:
+			return float64(len(v)), nil
+		case float64:
+			return math.Abs(v), nil
+		default:
+			return nil, fmt.Errorf("%s has no length", jqType(input))
+		}
+	},
+	"keys": func(input interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case map[string]interface{}:
+			keys := make([]interface{}, 0, len(v))
+			for _, key := range sortedMapKeys(v) {
+				keys = append(keys, key)
+			}
+			return keys, nil
+		case []interface{}:
+			keys := make([]interface{}, len(v))
+			for i := range v {
+				keys[i] = float64(i)
+			}
+			return keys, nil
+		default:
+			return nil, fmt.Errorf("%s has no keys", jqType(input))
+		}
+	},
+	"type": func(input interface{}) (interface{}, error) {
+		return jqType(input), nil
+	},
+	"not": func(input interface{}) (interface{}, error) {
+		return !jqTruthy(input), nil
+	},
+	"sort": func(input interface{}) (interface{}, error) {
+		items, err := jqArray(input, "sort")
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(items, func(i, j int) bool { return compareJSON(items[i], items[j]) < 0 })
+		return items, nil
+	},
+	"unique": func(input interface{}) (interface{}, error) {
+		items, err := jqArray(input, "unique")
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(items, func(i, j int) bool { return compareJSON(items[i], items[j]) < 0 })
+		unique := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			if len(unique) == 0 || compareJSON(unique[len(unique)-1], item) != 0 {
+				unique = append(unique, item)
+			}
+		}
+		return unique, nil
+	},
+	"reverse": func(input interface{}) (interface{}, error) {
+		if s, ok := input.(string); ok {
+			runes := []rune(s)
+			for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+				runes[i], runes[j] = runes[j], runes[i]
+			}
+			return string(runes), nil
+		}
+		items, err := jqArray(input, "reverse")
+		if err != nil {
+			return nil, err
+		}
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+		return items, nil
+	},
+	"first": func(input interface{}) (interface{}, error) {
+		return jqIndex(input, 0.0)
+	},
+	"last": func(input interface{}) (interface{}, error) {
+		return jqIndex(input, -1.0)
+	},
+	"min": func(input interface{}) (interface{}, error) {
+		return jqExtreme(input, "min", -1)
+	},
+	"max": func(input interface{}) (interface{}, error) {
+		return jqExtreme(input, "max", 1)
+	},
+	"add": func(input interface{}) (interface{}, error) {
+		items, err := jqArray(input, "add")
+		if err != nil {
+			return nil, err
+		}
+		var sum interface{}
+		for _, item := range items {
+			if sum, err = jqAdd(sum, item); err != nil {
+				return nil, err
+			}
+		}
+		return sum, nil
+	},
+	"flatten": func(input interface{}) (interface{}, error) {
+		items, err := jqArray(input, "flatten")
+		if err != nil {
+			return nil, err
+		}
+		var flatten func(items []interface{}) []interface{}
+		flatten = func(items []interface{}) []interface{} {
+			flat := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				if nested, ok := item.([]interface{}); ok {
+					flat = append(flat, flatten(nested)...)
+				} else {
+					flat = append(flat, item)
+				}
+			}
+			return flat
+		}
+		return flatten(items), nil
+	},
+	"to_entries": func(input interface{}) (interface{}, error) {
+		object, ok := input.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("to_entries needs an object, got %s", jqType(input))
+		}
+		entries := make([]interface{}, 0, len(object))
+		for _, key := range sortedMapKeys(object) {
+			entries = append(entries, map[string]interface{}{"key": key, "value": object[key]})
+		}
+		return entries, nil
+	},
+	"from_entries": func(input interface{}) (interface{}, error) {
+		items, err := jqArray(input, "from_entries")
+		if err != nil {
+			return nil, err
+		}
+		object := make(map[string]interface{}, len(items))
+		for _, item := range items {
+			entry, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("from_entries needs objects with key and value")
+			}
+			key := entry["key"]
+			if key == nil {
+				key = entry["name"]
+			}
+			switch k := key.(type) {
+			case string:
+				object[k] = entry["value"]
+			case float64:
+				object[strconv.FormatFloat(k, 'f', -1, 64)] = entry["value"]
+			default:
+				return nil, fmt.Errorf("from_entries needs string keys")
+			}
+		}
+		return object, nil
+	},
+	"tostring": func(input interface{}) (interface{}, error) {
+		if s, ok := input.(string); ok {
+			return s, nil
+		}
+		data, err := json.Marshal(input)
+		return string(data), err
+	},
+	"tonumber": func(input interface{}) (interface{}, error) {
+		switch v := input.(type) {
+		case float64:
+			return v, nil
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse %q as a number", v)
+			}
+			return n, nil
+		default:
+			return nil, fmt.Errorf("cannot convert %s to a number", jqType(input))
+		}
+	},
+	"ascii_downcase": func(input interface{}) (interface{}, error) {
+		s, ok := input.(string)
+		if !ok {
+			return nil, fmt.Errorf("ascii_downcase needs a string, got %s", jqType(input))
+		}
+		return strings.ToLower(s), nil
+	},
+	"ascii_upcase": func(input interface{}) (interface{}, error) {
+		s, ok := input.(string)
+		if !ok {
+			return nil, fmt.Errorf("ascii_upcase needs a string, got %s", jqType(input))
+		}
+		return strings.ToUpper(s), nil
+	},
+}
+
+// jqFilterFunctions take a filter argument, evaluated against the input or
+// against each element.
+var jqFilterFunctions = map[string]func(input interface{}, arg jqFilter) ([]interface{}, error){
+	"select": func(input interface{}, arg jqFilter) ([]interface{}, error) {
+		conditions, err := arg(input)
+		if err != nil {
+			return nil, err
+		}
+		var results []interface{}
+		for _, condition := range conditions {
+			if jqTruthy(condition) {
+				results = append(results, input)
+			}
+		}
+		return results, nil
+	},
+	"map": func(input interface{}, arg jqFilter) ([]interface{}, error) {
+		items, err := jqIterate(input)
+		if err != nil {
+			return nil, err
+		}
+		mapped := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			values, err := arg(item)
+			if err != nil {
+				return nil, err
+			}
+			mapped = append(mapped, values...)
+		}
+		return []interface{}{mapped}, nil
+	},
+	"sort_by": func(input interface{}, arg jqFilter) ([]interface{}, error) {
+		items, err := jqArray(input, "sort_by")
+		if err != nil {
+			return nil, err
+		}
+		keys, err := jqKeys(items, arg)
+		if err != nil {
+			return nil, err
+		}
+		indexes := make([]int, len(items))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		sort.SliceStable(indexes, func(i, j int) bool { return compareJSON(keys[indexes[i]], keys[indexes[j]]) < 0 })
+		sorted := make([]interface{}, len(items))
+		for i, index := range indexes {
+			sorted[i] = items[index]
+		}
+		return []interface{}{sorted}, nil
+	},
+	"group_by": func(input interface{}, arg jqFilter) ([]interface{}, error) {
+		items, err := jqArray(input, "group_by")
+		if err != nil {
+			return nil, err
+		}
+		keys, err := jqKeys(items, arg)
+		if err != nil {
+			return nil, err
+		}
+		indexes := make([]int, len(items))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		sort.SliceStable(indexes, func(i, j int) bool { return compareJSON(keys[indexes[i]], keys[indexes[j]]) < 0 })
+		groups := []interface{}{}
+		for i, index := range indexes {
+			if i == 0 || compareJSON(keys[indexes[i-1]], keys[index]) != 0 {
+				groups = append(groups, []interface{}{})
+			}
+			last := len(groups) - 1
+			groups[last] = append(groups[last].([]interface{}), items[index])
+		}
+		return []interface{}{groups}, nil
+	},
+	"has": func(input interface{}, arg jqFilter) ([]interface{}, error) {
+		keys, err := arg(input)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]interface{}, 0, len(keys))
+		for _, key := range keys {
+			switch v := input.(type) {
+			case map[string]interface{}:
+				name, _ := key.(string)
+				_, ok := v[name]
+				results = append(results, ok)
+			case []interface{}:
+				n, _ := key.(float64)
+				results = append(results, n >= 0 && int(n) < len(v))
+			default:
+				return nil, fmt.Errorf("cannot check whether %s has a key", jqType(input))
+			}
+		}
+		return results, nil
+	},
+	"join": func(input interface{}, arg jqFilter) ([]interface{}, error) {
+		items, err := jqArray(input, "join")
+		if err != nil {
+			return nil, err
+		}
+		separators, err := arg(input)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]interface{}, 0, len(separators))
+		for _, separator := range separators {
+			sep, ok := separator.(string)
+			if !ok {
+				return nil, fmt.Errorf("join needs a string separator")
+			}
+			parts := make([]string, len(items))
+			for i, item := range items {
+				switch v := item.(type) {
+				case nil:
+				case string:
+					parts[i] = v
+				case float64, bool:
+					data, _ := json.Marshal(v)
+					parts[i] = string(data)
+				default:
+					return nil, fmt.Errorf("cannot join %s", jqType(item))
+				}
+			}
+			results = append(results, strings.Join(parts, sep))
+		}
+		return results, nil
+	},
+	"split": func(input interface{}, arg jqFilter) ([]interface{}, error) {
+		s, ok := input.(string)
+		if !ok {
+			return nil, fmt.Errorf("split needs a string, got %s", jqType(input))
+		}
+		separators, err := arg(input)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]interface{}, 0, len(separators))
+		for _, separator := range separators {
+			sep, ok := separator.(string)
+			if !ok {
+				return nil, fmt.Errorf("split needs a string separator")
+			}
+			parts := strings.Split(s, sep)
+			items := make([]interface{}, len(parts))
+			for i, part := range parts {
+				items[i] = part
+			}
+			results = append(results, items)
+		}
+		return results, nil
+	},
+}
+
+func jqArray(input interface{}, name string) ([]interface{}, error) {
+	items, ok := input.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s needs an array, got %s", name, jqType(input))
+	}
+	return append([]interface{}{}, items...), nil
+}
+
+// jqKeys evaluates a filter against each item, collecting all its values
+// into one key per item.
+func jqKeys(items []interface{}, arg jqFilter) ([]interface{}, error) {
+	keys := make([]interface{}, len(items))
+	for i, item := range items {
+		values, err := arg(item)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = values
+	}
+	return keys, nil
+}
+
+func jqExtreme(input interface{}, name string, sign int) (interface{}, error) {
+	items, err := jqArray(input, name)
+	if err != nil {
+		return nil, err
+	}
+	var best interface{}
+	for i, item := range items {
+		if i == 0 || compareJSON(item, best)*sign > 0 {
+			best = item
+		}
+	}
+	return best, nil
+}
+
+func jqAdd(a, b interface{}) (interface{}, error) {
+	if a == nil {
+		return b, nil
+	}
+	if b == nil {
+		return a, nil
+	}
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x + y, nil
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return x + y, nil
+		}
+	case []interface{}:
+		if y, ok := b.([]interface{}); ok {
+			return append(append([]interface{}{}, x...), y...), nil
+		}
+	case map[string]interface{}:
+		if y, ok := b.(map[string]interface{}); ok {
+			merged := make(map[string]interface{}, len(x)+len(y))
+			for key, value := range x {
+				merged[key] = value
+			}
+			for key, value := range y {
+				merged[key] = value
+			}
+			return merged, nil
+		}
+	}
+	return nil, fmt.Errorf("cannot add %s and %s", jqType(a), jqType(b))
+}
+
+func jqSubtract(a, b interface{}) (interface{}, error) {
+	if x, ok := a.([]interface{}); ok {
+		y, ok := b.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("cannot subtract %s from array", jqType(b))
+		}
+		remaining := make([]interface{}, 0, len(x))
+		for _, item := range x {
+			removed := false
+			for _, other := range y {
+				if compareJSON(item, other) == 0 {
+					removed = true
+					break
+				}
+			}
+			if !removed {
+				remaining = append(remaining, item)
+			}
+		}
+		return remaining, nil
+	}
+	return jqArithmetic("-", a, b)
+}
+
+func jqArithmetic(op string, a, b interface{}) (interface{}, error) {
+	x, ok1 := a.(float64)
+	y, ok2 := b.(float64)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("cannot apply %s to %s and %s", op, jqType(a), jqType(b))
+	}
+	switch op {
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	default:
+		if int64(y) == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return float64(int64(x) % int64(y)), nil
+	}
+}
+
+func jqTruthy(value interface{}) bool {
+	return value != nil && value != false
+}
+
+func jqType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return reflect.TypeOf(value).String()
+	}
+}
+
+// compareJSON orders values as jq does: null, false, true, numbers,
+// strings, arrays, then objects.
+func compareJSON(a, b interface{}) int {
+	rank := func(value interface{}) int {
+		switch v := value.(type) {
+		case nil:
+			return 0
+		case bool:
+			if v {
+				return 2
+			}
+			return 1
+		case float64:
+			return 3
+		case string:
+			return 4
+		case []interface{}:
+			return 5
+		default:
+			return 6
+		}
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra - rb
+	}
+	
+	switch x := a.(type) {
+	case float64:
+		y := b.(float64)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case []interface{}:
+		y := b.([]interface{})
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compareJSON(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return len(x) - len(y)
+	case map[string]interface{}:
+		y, _ := b.(map[string]interface{})
+		xKeys, yKeys := sortedMapKeys(x), sortedMapKeys(y)
+		if c := compareJSON(stringsToJSON(xKeys), stringsToJSON(yKeys)); c != 0 {
+			return c
+		}
+		for _, key := range xKeys {
+			if c := compareJSON(x[key], y[key]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+	return 0
+}
+
+func stringsToJSON(values []string) []interface{} {
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+	return items
+}
+
+func sortedMapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
\ No newline at end of file
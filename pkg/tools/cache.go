@@ -0,0 +1,127 @@
+package tools
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// defaultResultCacheEntries bounds the results kept across all tools.
+const defaultResultCacheEntries = 1000
+
+type conversationKey struct{}
+
+// WithConversation scopes cached tool results to a conversation, so a
+// result is only reused by later calls in the same one.
+func WithConversation(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, conversationKey{}, id)
+}
+
+// ConversationFrom returns the conversation set with WithConversation.
+func ConversationFrom(ctx context.Context) string {
+	id, _ := ctx.Value(conversationKey{}).(string)
+	return id
+}
+
+// resultCache keeps successful tool results until their TTL passes,
+// evicting the least recently used when full.
+type resultCache struct {
+	maxEntries int
+	ll         *list.List
+	items      map[string]*list.Element
+	mu         sync.Mutex
+}
+
+type resultCacheEntry struct {
+	key       string
+	result    *Result
+	expiresAt time.Time
+}
+
+func newResultCache(maxEntries int) *resultCache {
+	return &resultCache{
+		maxEntries: maxEntries,
+		ll:         list.New(),
+		items:      make(map[string]*list.Element),
+	}
+}
+
+// resultCacheKey hashes the conversation, tool and arguments. Maps marshal
+// with sorted keys, so equal arguments give equal keys.
+func resultCacheKey(ctx context.Context, key Key, args map[string]interface{}) (string, bool) {
+	data, err := json.Marshal(struct {
+		Conversation string                 `json:"conversation,omitempty"`
+		Scope        string                 `json:"scope"`
+		Tool         string                 `json:"tool"`
+		Args         map[string]interface{} `json:"args"`
+	}{ConversationFrom(ctx), key.Scope, key.Name, args})
+	if err != nil {
+		return "", false
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), true
+}
+
+func (c *resultCache) get(key string) (*Result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	elem, exists := c.items[key]
+	if !exists {
+		return nil, false
+	}
+	
+	entry := elem.Value.(*resultCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+	
+	c.ll.MoveToFront(elem)
+	result := copyResult(entry.result)
+	result.Metadata["cached"] = true
+	return result, true
+}
+
+func (c *resultCache) set(key string, result *Result, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	stored := copyResult(result)
+	expiresAt := time.Now().Add(ttl)
+	if elem, exists := c.items[key]; exists {
+		entry := elem.Value.(*resultCacheEntry)
+		entry.result = stored
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(elem)
+		return
+	}
+	
+	c.items[key] = c.ll.PushFront(&resultCacheEntry{
+		key:       key,
+		result:    stored,
+		expiresAt: expiresAt,
+	})
+	
+	for c.ll.Len() > c.maxEntries {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*resultCacheEntry).key)
+	}
+}
+
+// copyResult copies a result and its metadata, which callers may add to.
+// The data is shared and treated as read-only.
+func copyResult(result *Result) *Result {
+	copied := *result
+	copied.Metadata = make(map[string]interface{}, len(result.Metadata)+1)
+	for k, v := range result.Metadata {
+		copied.Metadata[k] = v
+	}
+	return &copied
+}
\ No newline at end of file
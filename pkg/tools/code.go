@@ -0,0 +1,381 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCodeMaxFiles    = 10
+	defaultCodeMaxFileSize = 10 << 20
+)
+
+// codeLanguage describes how to run a snippet in one language.
+type codeLanguage struct {
+	image  string
+	source string
+	run    []string
+	env    []string
+}
+
+var codeLanguages = map[string]codeLanguage{
+	"python": {
+		image:  "python:3.12-slim",
+		source: "main.py",
+		run:    []string{"python", "main.py"},
+	},
+	"go": {
+		image:  "golang:1.22-alpine",
+		source: "main.go",
+		run:    []string{"go", "run", "main.go"},
+		// The root filesystem is read-only, so the build cache lives in /tmp
+		env: []string{"GOCACHE=/tmp/go-cache", "GOPATH=/tmp/go", "GOFLAGS=-mod=mod", "CGO_ENABLED=0"},
+	},
+}
+
+// CodeTool runs Python or Go snippets in an ephemeral container. The
+// container has no network unless configured otherwise, a read-only root
+// filesystem, no capabilities, and memory, CPU and process limits. Files
+// the snippet writes to its working directory are returned as files.
+type CodeTool struct {
+	config      *Config
+	docker      string
+	runtime     string
+	network     string
+	user        string
+	memory      string
+	cpus        string
+	pidsLimit   string
+	tmpfsSize   string
+	images      map[string]string
+	languages   []string
+	maxOutput   int64
+	maxFiles    int
+	maxFileSize int64
+	timeout     time.Duration
+}
+
+func NewCodeTool(config *Config) (*CodeTool, error) {
+	docker := config.Config["docker"]
+	if docker == "" {
+		docker = "docker"
+	}
+	if _, err := exec.LookPath(docker); err != nil {
+		return nil, fmt.Errorf("container runtime %s not found: %w", docker, err)
+	}
+	
+	images := make(map[string]string, len(codeLanguages))
+	var languages []string
+	allowed := config.Config["languages"]
+	for name, language := range codeLanguages {
+		if allowed != "" && !containsString(splitList(allowed), name) {
+			continue
+		}
+		images[name] = language.image
+		if image := config.Config[name+"_image"]; image != "" {
+			images[name] = image
+		}
+		languages = append(languages, name)
+	}
+	if len(languages) == 0 {
+		return nil, fmt.Errorf("no supported languages in %q", allowed)
+	}
+	sort.Strings(languages)
+	
+	maxOutput, err := intConfig(config.Config, "max_output", defaultExecMaxOutput)
+	if err != nil {
+		return nil, err
+	}
+	maxFiles, err := intConfig(config.Config, "max_files", defaultCodeMaxFiles)
+	if err != nil {
+		return nil, err
+	}
+	maxFileSize, err := intConfig(config.Config, "max_file_size", defaultCodeMaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	
+	timeout := 60 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	return &CodeTool{
+		config:      config,
+		docker:      docker,
+		runtime:     config.Config["runtime"],
+		network:     configOrDefault(config.Config, "network", "none"),
+		user:        configOrDefault(config.Config, "user", "65534:65534"),
+		memory:      configOrDefault(config.Config, "memory", "512m"),
+		cpus:        configOrDefault(config.Config, "cpus", "1"),
+		pidsLimit:   configOrDefault(config.Config, "pids_limit", "128"),
+		tmpfsSize:   configOrDefault(config.Config, "tmpfs_size", "256m"),
+		images:      images,
+		languages:   languages,
+		maxOutput:   int64(maxOutput),
+		maxFiles:    maxFiles,
+		maxFileSize: int64(maxFileSize),
+		timeout:     timeout,
+	}, nil
+}
+
+func (t *CodeTool) Name() string {
+	return t.config.Name
+}
+
+func (t *CodeTool) Type() string {
+	return "code"
+}
+
+func (t *CodeTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = fmt.Sprintf("Run a %s program in a sandbox and return its exit code, stdout and stderr. "+
+			"Files the program writes to its working directory are returned.", strings.Join(t.languages, " or "))
+		if t.network == "none" {
+			description += " The sandbox has no network access."
+		}
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"language": map[string]interface{}{
+					"type": "string",
+					"enum": t.languages,
+				},
+				"code": map[string]interface{}{
+					"type":        "string",
+					"description": "The complete program source",
+				},
+			},
+			"required": []string{"language", "code"},
+		},
+	}
+}
+
+func (t *CodeTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	name, _ := args["language"].(string)
+	code, _ := args["code"].(string)
+	image, ok := t.images[name]
+	if !ok {
+		return &Result{Error: fmt.Sprintf("unsupported language %q, expected one of %s", name, strings.Join(t.languages, ", "))}, nil
+	}
+	if code == "" {
+		return &Result{Error: "code is required"}, nil
+	}
+	language := codeLanguages[name]
+	
+	workDir, err := os.MkdirTemp("", "goagents-code-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	
+	// The container runs as an unprivileged user that must be able to
+	// write its output next to the source
+	if err := os.Chmod(workDir, 0o777); err != nil {
+		return nil, fmt.Errorf("failed to prepare workspace: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(workDir, language.source), []byte(code), 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write source: %w", err)
+	}
+	
+	container, err := containerName()
+	if err != nil {
+		return nil, err
+	}
+	
+	runCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	
+	cmd := exec.CommandContext(runCtx, t.docker, t.runArgs(container, workDir, image, language)...)
+	cmd.Cancel = func() error {
+		// Killing the CLI would leave the container running
+		exec.Command(t.docker, "kill", container).Run()
+		return cmd.Process.Kill()
+	}
+	cmd.WaitDelay = 5 * time.Second
+	
+	stdout := &cappedBuffer{limit: t.maxOutput}
+	stderr := &cappedBuffer{limit: t.maxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	
+	start := time.Now()
+	runErr := cmd.Run()
+	duration := time.Since(start)
+	
+	exitCode := 0
+	if cmd.ProcessState != nil {
+		exitCode = cmd.ProcessState.ExitCode()
+	}
+	
+	// docker run exits with 125 when the container could not be started
+	if exitCode == 125 && runCtx.Err() == nil {
+		return &Result{Error: fmt.Sprintf("failed to start sandbox: %s", strings.TrimSpace(stderr.String()))}, nil
+	}
+	
+	produced, err := t.collectFiles(ctx, workDir, language.source)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	data := map[string]interface{}{
+		"language":    name,
+		"exit_code":   exitCode,
+		"stdout":      stdout.String(),
+		"stderr":      stderr.String(),
+		"truncated":   stdout.truncated || stderr.truncated,
+		"files":       produced,
+		"duration_ms": duration.Milliseconds(),
+	}
+	
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded):
+		return &Result{Data: data, Error: fmt.Sprintf("code timed out after %s", t.timeout)}, nil
+	case exitCode != 0:
+		return &Result{Data: data, Error: fmt.Sprintf("code exited with status %d", exitCode)}, nil
+	case runErr != nil:
+		return &Result{Data: data, Error: fmt.Sprintf("code failed: %v", runErr)}, nil
+	}
+	
+	return &Result{Data: data}, nil
+}
+
+func (t *CodeTool) runArgs(container, workDir, image string, language codeLanguage) []string {
+	args := []string{
+		"run", "--rm", "--name", container,
+		"--network", t.network,
+		"--user", t.user,
+		"--memory", t.memory,
+		"--memory-swap", t.memory,
+		"--cpus", t.cpus,
+		"--pids-limit", t.pidsLimit,
+		"--read-only",
+		"--tmpfs", "/tmp:rw,exec,size=" + t.tmpfsSize,
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--volume", workDir + ":/workspace:rw",
+		"--workdir", "/workspace",
+		"--env", "HOME=/tmp",
+	}
+	if t.runtime != "" {
+		args = append(args, "--runtime", t.runtime)
+	}
+	for _, env := range language.env {
+		args = append(args, "--env", env)
+	}
+	
+	args = append(args, image)
+	return append(args, language.run...)
+}
+
+// collectFiles returns the regular files the program left in its working
+// directory, stored in the file store when one is configured.
+func (t *CodeTool) collectFiles(ctx context.Context, workDir, source string) ([]map[string]interface{}, error) {
+	produced := make([]map[string]interface{}, 0)
+	
+	err := filepath.WalkDir(workDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Symlinks are skipped so the program cannot point at host files
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		
+		rel, err := filepath.Rel(workDir, path)
+		if err != nil || rel == source {
+			return err
+		}
+		if len(produced) >= t.maxFiles {
+			return filepath.SkipAll
+		}
+		
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		file := map[string]interface{}{
+			"name": filepath.ToSlash(rel),
+			"size": info.Size(),
+		}
+		if info.Size() > t.maxFileSize {
+			file["error"] = fmt.Sprintf("file is larger than %d bytes", t.maxFileSize)
+			produced = append(produced, file)
+			return nil
+		}
+		
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		mimeType := mime.TypeByExtension(filepath.Ext(rel))
+		if mimeType == "" {
+			mimeType = http.DetectContentType(data)
+		}
+		file["mime_type"] = mimeType
+		
+		if t.config.Files != nil {
+			stored, err := t.config.Files.Put(ctx, filepath.Base(rel), mimeType, data)
+			if err != nil {
+				file["error"] = err.Error()
+			} else {
+				file["type"] = "file"
+				file["file_id"] = stored.ID
+			}
+		}
+		
+		produced = append(produced, file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect output files: %w", err)
+	}
+	
+	return produced, nil
+}
+
+func (t *CodeTool) Close() error {
+	return nil
+}
+
+func containerName() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate container name: %w", err)
+	}
+	return "goagents-code-" + hex.EncodeToString(b), nil
+}
+
+func configOrDefault(config map[string]string, key, fallback string) string {
+	if value := config[key]; value != "" {
+		return value
+	}
+	return fallback
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
\ No newline at end of file
@@ -0,0 +1,57 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultMaxConcurrency is how many tool calls a Manager runs at once
+// unless SetMaxConcurrency says otherwise.
+const DefaultMaxConcurrency = 64
+
+// semaphore bounds how many calls run at once. A nil semaphore imposes no
+// bound.
+type semaphore chan struct{}
+
+func newSemaphore(n int) semaphore {
+	if n <= 0 {
+		return nil
+	}
+	return make(semaphore, n)
+}
+
+// acquire waits for a free slot until ctx is done.
+func (s semaphore) acquire(ctx context.Context) error {
+	if s == nil {
+		return nil
+	}
+	select {
+	case s <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s semaphore) release() {
+	if s != nil {
+		<-s
+	}
+}
+
+// acquireSlots takes a slot for the tool, then one from the shared pool,
+// so calls queued behind a busy tool do not hold slots other tools could
+// use. The returned function gives both back.
+func acquireSlots(ctx context.Context, key Key, limit, workers semaphore) (func(), error) {
+	if err := limit.acquire(ctx); err != nil {
+		return nil, fmt.Errorf("tool %s is busy: %w", key.Name, err)
+	}
+	if err := workers.acquire(ctx); err != nil {
+		limit.release()
+		return nil, fmt.Errorf("no free tool workers: %w", err)
+	}
+	return func() {
+		workers.release()
+		limit.release()
+	}, nil
+}
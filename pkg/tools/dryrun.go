@@ -0,0 +1,102 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DryRunner is implemented by tools that can describe the call they would
+// make for a set of arguments without making it. Arguments are checked as
+// in Execute, so a call that would be refused fails in a dry run as well.
+type DryRunner interface {
+	DryRun(ctx context.Context, args map[string]interface{}) (*Result, error)
+}
+
+type dryRunKey struct{}
+
+// WithDryRun makes the tool calls made with ctx dry runs: they return what
+// would be called instead of calling it.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, true)
+}
+
+// IsDryRun reports whether ctx was set up with WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// dryRun describes the call tool would make for args. Tools that cannot
+// tell are described by their arguments alone.
+func dryRun(ctx context.Context, tool Tool, args map[string]interface{}) (*Result, error) {
+	var result *Result
+	if runner, ok := tool.(DryRunner); ok {
+		var err error
+		result, err = runner.DryRun(ctx, args)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		result = &Result{Data: map[string]interface{}{"args": args}}
+	}
+	
+	if result.Metadata == nil {
+		result.Metadata = make(map[string]interface{})
+	}
+	result.Metadata["dry_run"] = true
+	result.Metadata["tool"] = tool.Name()
+	result.Metadata["tool_type"] = tool.Type()
+	return result, nil
+}
+
+const redactedValue = "[redacted]"
+
+// credentialHeaders are left out of dry run results.
+var credentialHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "X-Api-Key"}
+
+// describeRequest reports the method, URL, headers and body of an HTTP
+// request a dry run would have sent. Credentials are redacted, including
+// the API key of auth wherever it was put.
+func describeRequest(req *http.Request, auth *AuthConfig) *Result {
+	headers := req.Header.Clone()
+	for _, name := range credentialHeaders {
+		if headers.Get(name) != "" {
+			headers.Set(name, redactedValue)
+		}
+	}
+	
+	target := req.URL.String()
+	if auth != nil {
+		for _, secret := range []string{auth.Token, auth.APIKey, auth.Secret} {
+			if secret == "" {
+				continue
+			}
+			target = strings.ReplaceAll(target, secret, redactedValue)
+			target = strings.ReplaceAll(target, url.QueryEscape(secret), redactedValue)
+			for name, values := range headers {
+				for i, value := range values {
+					if strings.Contains(value, secret) {
+						headers[name][i] = redactedValue
+					}
+				}
+			}
+		}
+	}
+	
+	data := map[string]interface{}{
+		"method":  req.Method,
+		"url":     target,
+		"headers": headers,
+	}
+	if req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			payload, _ := io.ReadAll(body)
+			body.Close()
+			data["body"] = string(payload)
+		}
+	}
+	return &Result{Data: data}
+}
@@ -0,0 +1,692 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	_ "github.com/emersion/go-message/charset"
+	"github.com/emersion/go-message/mail"
+)
+
+const (
+	defaultEmailResults = 20
+	maxEmailResults     = 100
+	// maxEmailBody bounds the message text returned by read
+	maxEmailBody = 256 * 1024
+)
+
+// EmailTool sends mail over SMTP and searches and reads it over IMAP. Only
+// the folders in the folders config entry can be read, and when
+// allowed_recipients is set mail can only be sent to those addresses and
+// domains.
+type EmailTool struct {
+	config            *Config
+	smtpAddr          string
+	imapAddr          string
+	username          string
+	password          string
+	from              *mail.Address
+	folders           []string
+	allowedRecipients []string
+	insecure          bool
+	maxResults        int
+	timeout           time.Duration
+}
+
+// emailSummary is a message as listed by search.
+type emailSummary struct {
+	UID       uint32    `json:"uid"`
+	MessageID string    `json:"message_id,omitempty"`
+	From      string    `json:"from"`
+	To        []string  `json:"to,omitempty"`
+	Subject   string    `json:"subject"`
+	Date      time.Time `json:"date"`
+	Seen      bool      `json:"seen"`
+}
+
+func NewEmailTool(config *Config) (*EmailTool, error) {
+	smtpAddr := config.Config["smtp_addr"]
+	imapAddr := config.Config["imap_addr"]
+	if smtpAddr == "" && imapAddr == "" {
+		return nil, fmt.Errorf("email tool requires smtp_addr, imap_addr or both")
+	}
+	for _, addr := range []string{smtpAddr, imapAddr} {
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid address %q: %w", addr, err)
+		}
+	}
+	
+	var from *mail.Address
+	if smtpAddr != "" {
+		var err error
+		from, err = mail.ParseAddress(config.Config["from"])
+		if err != nil {
+			return nil, fmt.Errorf("email tool requires a valid from address to send: %w", err)
+		}
+	}
+	
+	var password string
+	if config.Auth != nil {
+		password = config.Auth.Secret
+		if password == "" {
+			password = config.Auth.Token
+		}
+	}
+	
+	insecure := false
+	if value := config.Config["insecure"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid insecure: %w", err)
+		}
+		insecure = parsed
+	}
+	
+	maxResults, err := intConfig(config.Config, "max_results", defaultEmailResults)
+	if err != nil {
+		return nil, err
+	}
+	if maxResults > maxEmailResults {
+		return nil, fmt.Errorf("max_results may not exceed %d", maxEmailResults)
+	}
+	
+	var allowedRecipients []string
+	for _, recipient := range splitList(config.Config["allowed_recipients"]) {
+		allowedRecipients = append(allowedRecipients, strings.ToLower(recipient))
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	return &EmailTool{
+		config:            config,
+		smtpAddr:          smtpAddr,
+		imapAddr:          imapAddr,
+		username:          config.Config["username"],
+		password:          password,
+		from:              from,
+		folders:           splitList(configOrDefault(config.Config, "folders", "INBOX")),
+		allowedRecipients: allowedRecipients,
+		insecure:          insecure,
+		maxResults:        maxResults,
+		timeout:           timeout,
+	}, nil
+}
+
+func (t *EmailTool) Name() string {
+	return t.config.Name
+}
+
+func (t *EmailTool) Type() string {
+	return "email"
+}
+
+// actions lists what the tool can do with the servers it has.
+func (t *EmailTool) actions() []string {
+	var actions []string
+	if t.smtpAddr != "" {
+		actions = append(actions, "send")
+	}
+	if t.imapAddr != "" {
+		actions = append(actions, "search", "read")
+	}
+	return actions
+}
+
+func (t *EmailTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		var parts []string
+		if t.smtpAddr != "" {
+			parts = append(parts, "send sends an email from "+t.from.Address)
+		}
+		if t.imapAddr != "" {
+			parts = append(parts, "search lists messages in a folder, newest first", "read returns one message by UID. Folders: "+strings.Join(t.folders, ", "))
+		}
+		description = "Work with email: " + strings.Join(parts, "; ") + "."
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"action": map[string]interface{}{
+					"type": "string",
+					"enum": t.actions(),
+				},
+				"to": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "For send, the recipients",
+				},
+				"cc": map[string]interface{}{
+					"type":        "array",
+					"items":       map[string]interface{}{"type": "string"},
+					"description": "For send, the copied recipients",
+				},
+				"subject": map[string]interface{}{
+					"type":        "string",
+					"description": "For send, the subject; for search, text the subject contains",
+				},
+				"body": map[string]interface{}{
+					"type":        "string",
+					"description": "For send, the plain text body",
+				},
+				"in_reply_to": map[string]interface{}{
+					"type":        "string",
+					"description": "For send, the Message-ID being answered, to keep the thread",
+				},
+				"folder": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("For search and read, the folder (default: %s)", t.folders[0]),
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "For search, text anywhere in the message",
+				},
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "For search, text the sender contains",
+				},
+				"since": map[string]interface{}{
+					"type":        "string",
+					"description": "For search, only messages on or after this date (YYYY-MM-DD)",
+				},
+				"unseen": map[string]interface{}{
+					"type":        "boolean",
+					"description": "For search, only unread messages",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("For search, how many messages to return, at most %d", t.maxResults),
+				},
+				"uid": map[string]interface{}{
+					"type":        "integer",
+					"description": "For read, the message UID from search",
+				},
+				"mark_seen": map[string]interface{}{
+					"type":        "boolean",
+					"description": "For read, mark the message as read",
+				},
+			},
+			"required": []string{"action"},
+		},
+	}
+}
+
+func (t *EmailTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	action, _ := args["action"].(string)
+	if !containsString(t.actions(), action) {
+		return &Result{Error: fmt.Sprintf("action %q is not available; available actions: %s", action, strings.Join(t.actions(), ", "))}, nil
+	}
+	
+	start := time.Now()
+	var result *Result
+	switch action {
+	case "send":
+		result = t.send(args)
+	case "search":
+		result = t.search(args)
+	case "read":
+		result = t.read(args)
+	}
+	
+	if result.Error == "" {
+		result.Metadata = map[string]interface{}{
+			"action":      action,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}
+	}
+	return result, nil
+}
+
+func (t *EmailTool) send(args map[string]interface{}) *Result {
+	to, err := t.recipients(args, "to")
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	if len(to) == 0 {
+		return &Result{Error: "to is required"}
+	}
+	cc, err := t.recipients(args, "cc")
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	subject, _ := args["subject"].(string)
+	body, _ := args["body"].(string)
+	if strings.TrimSpace(body) == "" {
+		return &Result{Error: "body is required"}
+	}
+	
+	var header mail.Header
+	header.SetDate(time.Now())
+	header.SetAddressList("From", []*mail.Address{t.from})
+	header.SetAddressList("To", to)
+	if len(cc) > 0 {
+		header.SetAddressList("Cc", cc)
+	}
+	header.SetSubject(subject)
+	if err := header.GenerateMessageID(); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to generate message ID: %v", err)}
+	}
+	if inReplyTo, _ := args["in_reply_to"].(string); inReplyTo != "" {
+		id := strings.Trim(strings.TrimSpace(inReplyTo), "<>")
+		header.SetMsgIDList("In-Reply-To", []string{id})
+		header.SetMsgIDList("References", []string{id})
+	}
+	header.Set("Auto-Submitted", "auto-generated")
+	header.SetContentType("text/plain", map[string]string{"charset": "utf-8"})
+	
+	var buf bytes.Buffer
+	w, err := mail.CreateSingleInlineWriter(&buf, header)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to create message: %v", err)}
+	}
+	if _, err := io.WriteString(w, body); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to write message: %v", err)}
+	}
+	if err := w.Close(); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to write message: %v", err)}
+	}
+	
+	var addresses []string
+	for _, address := range append(to, cc...) {
+		addresses = append(addresses, address.Address)
+	}
+	if err := t.sendMail(addresses, buf.Bytes()); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to send: %v", err)}
+	}
+	
+	messageID, _ := header.MessageID()
+	return &Result{Data: map[string]interface{}{
+		"message_id": messageID,
+		"recipients": addresses,
+	}}
+}
+
+// recipients parses a list of addresses and checks each against the
+// allowed_recipients config entry.
+func (t *EmailTool) recipients(args map[string]interface{}, name string) ([]*mail.Address, error) {
+	var values []string
+	switch v := args[name].(type) {
+	case nil:
+	case string:
+		values = splitList(v)
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of addresses", name)
+			}
+			values = append(values, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a list of addresses", name)
+	}
+	
+	addresses := make([]*mail.Address, 0, len(values))
+	for _, value := range values {
+		address, err := mail.ParseAddress(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid address %q: %v", value, err)
+		}
+		if !t.recipientAllowed(address.Address) {
+			return nil, fmt.Errorf("recipient %s is not allowed", address.Address)
+		}
+		addresses = append(addresses, address)
+	}
+	return addresses, nil
+}
+
+// recipientAllowed matches an address against the allowlist, where entries
+// are addresses or @domains.
+func (t *EmailTool) recipientAllowed(address string) bool {
+	if len(t.allowedRecipients) == 0 {
+		return true
+	}
+	address = strings.ToLower(address)
+	for _, allowed := range t.allowedRecipients {
+		if allowed == address || strings.HasPrefix(allowed, "@") && strings.HasSuffix(address, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// sendMail delivers a message over SMTP. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it.
+func (t *EmailTool) sendMail(to []string, message []byte) error {
+	host, port, _ := net.SplitHostPort(t.smtpAddr)
+	
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: t.timeout}
+	if port == "465" && !t.insecure {
+		conn, err = tls.DialWithDialer(dialer, "tcp", t.smtpAddr, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", t.smtpAddr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", t.smtpAddr, err)
+	}
+	conn.SetDeadline(time.Now().Add(t.timeout))
+	
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer c.Close()
+	
+	if ok, _ := c.Extension("STARTTLS"); ok && !t.insecure && port != "465" {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return fmt.Errorf("STARTTLS failed: %w", err)
+		}
+	}
+	
+	if t.username != "" {
+		if err := c.Auth(smtp.PlainAuth("", t.username, t.password, host)); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	
+	if err := c.Mail(t.from.Address); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := c.Rcpt(recipient); err != nil {
+			return fmt.Errorf("recipient %s rejected: %w", recipient, err)
+		}
+	}
+	
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	
+	return c.Quit()
+}
+
+func (t *EmailTool) search(args map[string]interface{}) *Result {
+	limit, err := numberArg(args, "limit", float64(t.maxResults))
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	if limit < 1 || limit > float64(t.maxResults) || limit != math.Trunc(limit) {
+		return &Result{Error: fmt.Sprintf("limit must be a whole number from 1 to %d", t.maxResults)}
+	}
+	
+	criteria := imap.NewSearchCriteria()
+	if query, _ := args["query"].(string); query != "" {
+		criteria.Text = []string{query}
+	}
+	if from, _ := args["from"].(string); from != "" {
+		criteria.Header.Add("From", from)
+	}
+	if subject, _ := args["subject"].(string); subject != "" {
+		criteria.Header.Add("Subject", subject)
+	}
+	if since, _ := args["since"].(string); since != "" {
+		date, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			return &Result{Error: "since must be a date such as 2024-01-31"}
+		}
+		criteria.Since = date
+	}
+	if unseen, _ := args["unseen"].(bool); unseen {
+		criteria.WithoutFlags = []string{imap.SeenFlag}
+	}
+	
+	folder, err := t.folder(args)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	c, err := t.openFolder(folder, true)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	defer c.Logout()
+	
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("search failed: %v", err)}
+	}
+	total := len(uids)
+	
+	// UIDs grow with arrival, so the highest are the newest
+	sort.Slice(uids, func(i, j int) bool { return uids[i] > uids[j] })
+	if len(uids) > int(limit) {
+		uids = uids[:int(limit)]
+	}
+	
+	messages := make([]emailSummary, 0, len(uids))
+	if len(uids) > 0 {
+		seqset := new(imap.SeqSet)
+		seqset.AddNum(uids...)
+		fetched := make(chan *imap.Message, len(uids))
+		if err := c.UidFetch(seqset, []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, imap.FetchFlags}, fetched); err != nil {
+			return &Result{Error: fmt.Sprintf("failed to fetch messages: %v", err)}
+		}
+		for msg := range fetched {
+			messages = append(messages, summarizeEmail(msg))
+		}
+		sort.Slice(messages, func(i, j int) bool { return messages[i].UID > messages[j].UID })
+	}
+	
+	return &Result{Data: map[string]interface{}{
+		"folder":   folder,
+		"messages": messages,
+		"total":    total,
+	}}
+}
+
+func (t *EmailTool) read(args map[string]interface{}) *Result {
+	uid, err := numberArg(args, "uid", 0)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	if uid < 1 || uid > math.MaxUint32 || uid != math.Trunc(uid) {
+		return &Result{Error: "uid is required"}
+	}
+	markSeen, _ := args["mark_seen"].(bool)
+	
+	folder, err := t.folder(args)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	c, err := t.openFolder(folder, !markSeen)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	defer c.Logout()
+	
+	seqset := new(imap.SeqSet)
+	seqset.AddNum(uint32(uid))
+	section := &imap.BodySectionName{Peek: !markSeen}
+	fetched := make(chan *imap.Message, 1)
+	if err := c.UidFetch(seqset, []imap.FetchItem{imap.FetchUid, imap.FetchEnvelope, imap.FetchFlags, section.FetchItem()}, fetched); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to fetch message: %v", err)}
+	}
+	
+	msg := <-fetched
+	for range fetched {
+	}
+	if msg == nil {
+		return &Result{Error: fmt.Sprintf("message %d not found in %s", uint32(uid), folder)}
+	}
+	
+	data := map[string]interface{}{"message": summarizeEmail(msg)}
+	if body := msg.GetBody(section); body != nil {
+		text, attachments, truncated, err := readEmailBody(body)
+		if err != nil {
+			return &Result{Error: fmt.Sprintf("failed to parse message: %v", err)}
+		}
+		data["body"] = text
+		data["truncated"] = truncated
+		if len(attachments) > 0 {
+			data["attachments"] = attachments
+		}
+	}
+	if msg.Envelope != nil && len(msg.Envelope.Cc) > 0 {
+		data["cc"] = imapAddresses(msg.Envelope.Cc)
+	}
+	
+	return &Result{Data: data}
+}
+
+// folder returns the requested folder, or the first configured one, if it
+// is allowed.
+func (t *EmailTool) folder(args map[string]interface{}) (string, error) {
+	folder, _ := args["folder"].(string)
+	if folder == "" {
+		return t.folders[0], nil
+	}
+	for _, allowed := range t.folders {
+		// IMAP treats INBOX case-insensitively
+		if allowed == folder || strings.EqualFold(allowed, "INBOX") && strings.EqualFold(folder, "INBOX") {
+			return allowed, nil
+		}
+	}
+	return "", fmt.Errorf("folder %s is not allowed; allowed folders: %s", folder, strings.Join(t.folders, ", "))
+}
+
+func (t *EmailTool) openFolder(folder string, readOnly bool) (*client.Client, error) {
+	dialer := &net.Dialer{Timeout: t.timeout}
+	var c *client.Client
+	var err error
+	if t.insecure {
+		c, err = client.DialWithDialer(dialer, t.imapAddr)
+	} else {
+		c, err = client.DialWithDialerTLS(dialer, t.imapAddr, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", t.imapAddr, err)
+	}
+	c.Timeout = t.timeout
+	
+	if t.username != "" {
+		if err := c.Login(t.username, t.password); err != nil {
+			c.Logout()
+			return nil, fmt.Errorf("failed to log in: %w", err)
+		}
+	}
+	if _, err := c.Select(folder, readOnly); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("failed to select %s: %w", folder, err)
+	}
+	return c, nil
+}
+
+func summarizeEmail(msg *imap.Message) emailSummary {
+	summary := emailSummary{UID: msg.Uid}
+	for _, flag := range msg.Flags {
+		if flag == imap.SeenFlag {
+			summary.Seen = true
+		}
+	}
+	if envelope := msg.Envelope; envelope != nil {
+		summary.MessageID = strings.Trim(envelope.MessageId, "<>")
+		summary.Subject = envelope.Subject
+		summary.Date = envelope.Date
+		if from := imapAddresses(envelope.From); len(from) > 0 {
+			summary.From = from[0]
+		}
+		summary.To = imapAddresses(envelope.To)
+	}
+	return summary
+}
+
+func imapAddresses(addresses []*imap.Address) []string {
+	formatted := make([]string, 0, len(addresses))
+	for _, address := range addresses {
+		if address.PersonalName != "" {
+			formatted = append(formatted, fmt.Sprintf("%s <%s>", address.PersonalName, address.Address()))
+		} else {
+			formatted = append(formatted, address.Address())
+		}
+	}
+	return formatted
+}
+
+// readEmailBody returns a message's text, preferring the plain text part
+// and falling back to HTML with its tags removed, and the names of its
+// attachments.
+func readEmailBody(r io.Reader) (string, []string, bool, error) {
+	reader, err := mail.CreateReader(r)
+	if err != nil {
+		return "", nil, false, err
+	}
+	defer reader.Close()
+	
+	var plain, html string
+	var attachments []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, false, err
+		}
+		
+		switch header := part.Header.(type) {
+		case *mail.InlineHeader:
+			contentType, _, _ := header.ContentType()
+			body, err := io.ReadAll(io.LimitReader(part.Body, maxEmailBody+1))
+			if err != nil {
+				return "", nil, false, err
+			}
+			switch {
+			case (contentType == "" || contentType == "text/plain") && plain == "":
+				plain = string(body)
+			case contentType == "text/html" && html == "":
+				html = string(body)
+			}
+		case *mail.AttachmentHeader:
+			filename, _ := header.Filename()
+			attachments = append(attachments, filename)
+		}
+	}
+	
+	text := plain
+	if text == "" && html != "" {
+		text = strings.TrimSpace(htmlTagPattern.ReplaceAllString(html, " "))
+	}
+	truncated := false
+	if len(text) > maxEmailBody {
+		text, truncated = text[:maxEmailBody], true
+	}
+	return text, attachments, truncated, nil
+}
+
+func (t *EmailTool) Close() error {
+	return nil
+}
\ No newline at end of file
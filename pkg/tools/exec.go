@@ -0,0 +1,373 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	defaultExecPath      = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+	defaultExecMaxOutput = 1 << 20
+)
+
+var templateFieldPattern = regexp.MustCompile(`\{\{[^}]*?\.([A-Za-z_][A-Za-z0-9_]*)[^}]*\}\}`)
+
+// ExecConfig holds the settings of an exec tool after the exec policy has
+// been applied. Zero limits are not enforced.
+type ExecConfig struct {
+	WorkingDir      string
+	AllowedCommands []string
+	AllowedDirs     []string
+	MemoryLimit     int64
+	CPUTime         time.Duration
+	MaxOutput       int64
+}
+
+// ExecTool runs an allowlisted command. Each element of the command and its
+// args is a text/template rendered against the call's arguments into exactly
+// one argv entry; no shell is involved, so arguments cannot inject commands.
+type ExecTool struct {
+	config    *Config
+	exec      *ExecConfig
+	path      string
+	templates []*template.Template
+	// leading marks the arguments that start with a template action, which
+	// a value starting with '-' would turn into an option
+	leading []bool
+	fields  []string
+	// paths are the fields declared to name files, which must stay in the
+	// working directory
+	paths   []string
+	env     []string
+	timeout time.Duration
+}
+
+func NewExecTool(config *Config) (*ExecTool, error) {
+	if config.Exec == nil {
+		return nil, fmt.Errorf("exec tools are disabled by policy")
+	}
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("command is required for exec tool")
+	}
+	
+	command := config.Command[0]
+	if !containsString(config.Exec.AllowedCommands, command) {
+		return nil, fmt.Errorf("command %s is not allowed", command)
+	}
+	
+	dir, err := filepath.EvalSymlinks(config.Exec.WorkingDir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid working_dir: %w", err)
+	}
+	if !withinDirs(config.Exec.AllowedDirs, dir) {
+		return nil, fmt.Errorf("working_dir %s is outside the allowed dirs", dir)
+	}
+	
+	env := []string{"HOME=" + dir}
+	pathEnv := defaultExecPath
+	for key, value := range config.Env {
+		if key == "PATH" {
+			pathEnv = value
+			continue
+		}
+		env = append(env, key+"="+value)
+	}
+	env = append(env, "PATH="+pathEnv)
+	
+	path, err := lookPath(command, pathEnv)
+	if err != nil {
+		return nil, fmt.Errorf("command %s not found: %w", command, err)
+	}
+	
+	argv := append(append([]string{}, config.Command[1:]...), config.Args...)
+	templates := make([]*template.Template, len(argv))
+	leading := make([]bool, len(argv))
+	fieldSet := make(map[string]bool)
+	for i, arg := range argv {
+		tmpl, err := template.New(fmt.Sprintf("arg%d", i)).Option("missingkey=error").Parse(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid argument template %q: %w", arg, err)
+		}
+		templates[i] = tmpl
+		leading[i] = strings.HasPrefix(arg, "{{")
+		for _, match := range templateFieldPattern.FindAllStringSubmatch(arg, -1) {
+			fieldSet[match[1]] = true
+		}
+	}
+	
+	fields := make([]string, 0, len(fieldSet))
+	for field := range fieldSet {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	
+	paths := splitList(config.Config["paths"])
+	for _, field := range paths {
+		if !fieldSet[field] {
+			return nil, fmt.Errorf("path %s is not a field of the command's templates", field)
+		}
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	execConfig := *config.Exec
+	execConfig.WorkingDir = dir
+	if execConfig.MaxOutput <= 0 {
+		execConfig.MaxOutput = defaultExecMaxOutput
+	}
+	
+	return &ExecTool{
+		config:    config,
+		exec:      &execConfig,
+		path:      path,
+		templates: templates,
+		leading:   leading,
+		fields:    fields,
+		paths:     paths,
+		env:       env,
+		timeout:   timeout,
+	}, nil
+}
+
+func (t *ExecTool) Name() string {
+	return t.config.Name
+}
+
+func (t *ExecTool) Type() string {
+	return "exec"
+}
+
+// Definition describes the command's template fields as string arguments.
+func (t *ExecTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = fmt.Sprintf("Run %s", strings.Join(t.config.Command, " "))
+	}
+	
+	properties := make(map[string]interface{}, len(t.fields))
+	for _, field := range t.fields {
+		properties[field] = map[string]interface{}{"type": "string"}
+	}
+	for _, field := range t.paths {
+		properties[field] = map[string]interface{}{
+			"type":        "string",
+			"description": "A path within " + t.exec.WorkingDir,
+		}
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   t.fields,
+		},
+	}
+}
+
+func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	argv, errResult := t.render(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+	
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	
+	cmd := limitedCommand(ctx, t.exec, t.path, argv)
+	cmd.Dir = t.exec.WorkingDir
+	cmd.Env = t.env
+	cmd.WaitDelay = 2 * time.Second
+	
+	stdout := &cappedBuffer{limit: t.exec.MaxOutput}
+	stderr := &cappedBuffer{limit: t.exec.MaxOutput}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	
+	start := time.Now()
+	err := cmd.Run()
+	duration := time.Since(start)
+	
+	data := map[string]interface{}{
+		"command":     append([]string{t.path}, argv...),
+		"exit_code":   cmd.ProcessState.ExitCode(),
+		"stdout":      stdout.String(),
+		"stderr":      stderr.String(),
+		"truncated":   stdout.truncated || stderr.truncated,
+		"duration_ms": duration.Milliseconds(),
+	}
+	
+	if err != nil {
+		result := &Result{Data: data, Error: fmt.Sprintf("command failed: %v", err)}
+		var exitErr *exec.ExitError
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			result.Error = fmt.Sprintf("command timed out after %s", t.timeout)
+		case errors.As(err, &exitErr) && exitErr.Exited():
+			result.Error = fmt.Sprintf("command exited with status %d", exitErr.ExitCode())
+		}
+		return result, nil
+	}
+	
+	return &Result{Data: data}, nil
+}
+
+// DryRun renders and checks the command Execute would run for args.
+func (t *ExecTool) DryRun(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	argv, errResult := t.render(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+	
+	return &Result{
+		Data: map[string]interface{}{
+			"command":     append([]string{t.path}, argv...),
+			"working_dir": t.exec.WorkingDir,
+		},
+	}, nil
+}
+
+// render fills the argument templates in with args. Literal text is
+// trusted; the values filled in come from the model and are checked.
+func (t *ExecTool) render(args map[string]interface{}) ([]string, *Result) {
+	for _, field := range t.paths {
+		if value, ok := args[field]; ok {
+			if err := t.checkPath(fmt.Sprint(value)); err != nil {
+				return nil, &Result{Error: err.Error()}
+			}
+		}
+	}
+	
+	argv := make([]string, len(t.templates))
+	for i, tmpl := range t.templates {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, args); err != nil {
+			return nil, &Result{Error: fmt.Sprintf("failed to render arguments: %v", err)}
+		}
+		argv[i] = buf.String()
+		
+		// A value at the start of an argument must not make it an option
+		if t.leading[i] && strings.HasPrefix(argv[i], "-") {
+			return nil, &Result{Error: fmt.Sprintf("argument %q must not start with '-'", argv[i])}
+		}
+	}
+	return argv, nil
+}
+
+// checkPath rejects the value of a path field that names a path outside
+// the working directory. A path that does not exist yet is checked through
+// its deepest existing ancestor, since the command would create it wherever
+// a symlink there leads. '..' is refused, as the command resolves it after
+// the symlinks before it rather than lexically.
+func (t *ExecTool) checkPath(value string) error {
+	for _, part := range strings.Split(filepath.ToSlash(value), "/") {
+		if part == ".." {
+			return fmt.Errorf("path %q must not contain '..'", value)
+		}
+	}
+	
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(t.exec.WorkingDir, path)
+	}
+	existing := path
+	var missing []string
+	for {
+		if _, err := os.Lstat(existing); err == nil {
+			break
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			break
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+	resolved, err := filepath.EvalSymlinks(existing)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path %q: %w", value, err)
+	}
+	path = filepath.Join(append([]string{resolved}, missing...)...)
+	if !withinDirs([]string{t.exec.WorkingDir}, path) {
+		return fmt.Errorf("path %q is outside the working directory", value)
+	}
+	
+	return nil
+}
+
+func (t *ExecTool) Close() error {
+	return nil
+}
+
+// cappedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a chatty command cannot exhaust memory or block on a full pipe.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - int64(b.buf.Len()); remaining < int64(len(p)) {
+		b.truncated = true
+		if remaining > 0 {
+			b.buf.Write(p[:remaining])
+		}
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *cappedBuffer) String() string {
+	return b.buf.String()
+}
+
+// lookPath resolves name against pathEnv rather than the server's PATH.
+func lookPath(name, pathEnv string) (string, error) {
+	if strings.Contains(name, "/") {
+		return exec.LookPath(name)
+	}
+	for _, dir := range filepath.SplitList(pathEnv) {
+		if path, err := exec.LookPath(filepath.Join(dir, name)); err == nil {
+			return path, nil
+		}
+	}
+	return "", os.ErrNotExist
+}
+
+func withinDirs(dirs []string, dir string) bool {
+	dir = filepath.Clean(dir)
+	for _, root := range dirs {
+		if resolved, err := filepath.EvalSymlinks(root); err == nil {
+			root = resolved
+		}
+		rel, err := filepath.Rel(filepath.Clean(root), dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExecCheckPath(t *testing.T) {
+	work, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	outside, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(work, "data"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(work, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(work, "data"), filepath.Join(work, "inside")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(outside, "missing"), filepath.Join(work, "dangling")); err != nil {
+		t.Fatal(err)
+	}
+	
+	tool := &ExecTool{exec: &ExecConfig{WorkingDir: work}}
+	tests := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{name: "relative file", value: "data/report.csv"},
+		{name: "new nested file", value: "data/new/deeper/file.txt"},
+		{name: "absolute inside", value: filepath.Join(work, "data")},
+		{name: "symlink inside", value: "inside/new.txt"},
+		{name: "dot-dot", value: "data/../../etc/passwd", err: "must not contain '..'"},
+		{name: "dot-dot through symlink", value: "link/../data", err: "must not contain '..'"},
+		{name: "absolute outside", value: "/etc/passwd", err: "outside the working directory"},
+		{name: "symlink out", value: "link", err: "outside the working directory"},
+		{name: "new file through symlink", value: "link/newfile", err: "outside the working directory"},
+		{name: "new dir through symlink", value: "link/a/b/c", err: "outside the working directory"},
+		{name: "dangling symlink", value: "dangling", err: "failed to resolve"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tool.checkPath(tt.value)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("checkPath(%q) = %v; want nil", tt.value, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("checkPath(%q) = %v; want error containing %q", tt.value, err, tt.err)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,42 @@
+//go:build !windows
+
+package tools
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"syscall"
+)
+
+// limitSetter applies resource limits with the shell's ulimit builtin and
+// then execs the command, so the limits are in place before it starts. The
+// command and its arguments are passed as positional parameters and are
+// never parsed by the shell.
+const limitSetter = `ulimit -v "$1" && ulimit -t "$2" && shift 2 && exec "$@"`
+
+func limitedCommand(ctx context.Context, config *ExecConfig, path string, argv []string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if config.MemoryLimit > 0 || config.CPUTime > 0 {
+		memory, cpu := "unlimited", "unlimited"
+		if config.MemoryLimit > 0 {
+			memory = fmt.Sprint(config.MemoryLimit / 1024)
+		}
+		if config.CPUTime > 0 {
+			cpu = fmt.Sprint(int64(math.Ceil(config.CPUTime.Seconds())))
+		}
+		args := append([]string{"-c", limitSetter, "goagents-exec", memory, cpu, path}, argv...)
+		cmd = exec.CommandContext(ctx, "/bin/sh", args...)
+	} else {
+		cmd = exec.CommandContext(ctx, path, argv...)
+	}
+	
+	// Run in its own process group so a timeout also kills its children
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	
+	return cmd
+}
\ No newline at end of file
@@ -0,0 +1,14 @@
+//go:build windows
+
+package tools
+
+import (
+	"context"
+	"os/exec"
+)
+
+// limitedCommand enforces only the timeout on Windows; memory and CPU time
+// limits are not applied.
+func limitedCommand(ctx context.Context, config *ExecConfig, path string, argv []string) *exec.Cmd {
+	return exec.CommandContext(ctx, path, argv...)
+}
\ No newline at end of file
@@ -0,0 +1,469 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultFilesystemMaxFileSize = 1 << 20
+	defaultFilesystemMaxEntries  = 1000
+)
+
+var filesystemOperations = []string{"read", "write", "list", "stat"}
+
+// FilesystemTool reads and writes files under a root directory. Paths are
+// relative to the root and are resolved through symlinks before use, so
+// neither ".." nor a link can reach outside it. Paths matching a deny
+// pattern are refused and left out of listings.
+type FilesystemTool struct {
+	config      *Config
+	root        string
+	operations  []string
+	deny        []string
+	maxFileSize int64
+	maxEntries  int
+}
+
+func NewFilesystemTool(config *Config) (*FilesystemTool, error) {
+	if config.Config["root"] == "" {
+		return nil, fmt.Errorf("root is required for filesystem tool")
+	}
+	root, err := filepath.Abs(config.Config["root"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("invalid root: %w", err)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("root %s is not a directory", root)
+	}
+	
+	operations := filesystemOperations
+	if allowed := config.Config["operations"]; allowed != "" {
+		operations = splitList(allowed)
+		for _, operation := range operations {
+			if !containsString(filesystemOperations, operation) {
+				return nil, fmt.Errorf("unsupported operation %q, expected one of %s", operation, strings.Join(filesystemOperations, ", "))
+			}
+		}
+	}
+	
+	deny := splitList(config.Config["deny"])
+	for _, pattern := range deny {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid deny pattern %q: %w", pattern, err)
+		}
+	}
+	
+	maxFileSize, err := intConfig(config.Config, "max_file_size", defaultFilesystemMaxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	maxEntries, err := intConfig(config.Config, "max_entries", defaultFilesystemMaxEntries)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &FilesystemTool{
+		config:      config,
+		root:        root,
+		operations:  operations,
+		deny:        deny,
+		maxFileSize: int64(maxFileSize),
+		maxEntries:  maxEntries,
+	}, nil
+}
+
+func (t *FilesystemTool) Name() string {
+	return t.config.Name
+}
+
+func (t *FilesystemTool) Type() string {
+	return "filesystem"
+}
+
+func (t *FilesystemTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = fmt.Sprintf("Work with files in a directory. Operations: %s. "+
+			"Paths are relative to the directory; use \".\" for the directory itself.", strings.Join(t.operations, ", "))
+	}
+	
+	properties := map[string]interface{}{
+		"operation": map[string]interface{}{
+			"type": "string",
+			"enum": t.operations,
+		},
+		"path": map[string]interface{}{
+			"type":        "string",
+			"description": "Path relative to the directory",
+		},
+	}
+	if containsString(t.operations, "write") {
+		properties["content"] = map[string]interface{}{
+			"type":        "string",
+			"description": "Text to write, for the write operation",
+		}
+		properties["append"] = map[string]interface{}{
+			"type":        "boolean",
+			"description": "Append to the file instead of replacing it",
+		}
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{"operation", "path"},
+		},
+	}
+}
+
+func (t *FilesystemTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	operation, _ := args["operation"].(string)
+	name, _ := args["path"].(string)
+	if !containsString(t.operations, operation) {
+		return &Result{Error: fmt.Sprintf("unsupported operation %q, expected one of %s", operation, strings.Join(t.operations, ", "))}, nil
+	}
+	if name == "" {
+		return &Result{Error: "path is required"}, nil
+	}
+	
+	rel, err := t.relativePath(name)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	var data map[string]interface{}
+	switch operation {
+	case "read":
+		data, err = t.read(ctx, rel)
+	case "write":
+		content, _ := args["content"].(string)
+		appendContent, _ := args["append"].(bool)
+		data, err = t.write(rel, content, appendContent)
+	case "list":
+		data, err = t.list(rel)
+	case "stat":
+		data, err = t.stat(rel)
+	}
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	return &Result{Data: data}, nil
+}
+
+// relativePath cleans a requested path into a slash separated path relative
+// to the root, refusing paths that climb out of it or are denied.
+func (t *FilesystemTool) relativePath(name string) (string, error) {
+	name = filepath.ToSlash(name)
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("path %q must be relative", name)
+	}
+	rel := path.Clean(name)
+	if rel == ".." || strings.HasPrefix(rel, "../") {
+		return "", fmt.Errorf("path %q is outside the root", name)
+	}
+	if t.denied(rel) {
+		return "", fmt.Errorf("path %q is denied", name)
+	}
+	return rel, nil
+}
+
+// denied reports whether a relative path matches a deny pattern. Patterns
+// with a slash are matched against the path and each of its parents;
+// patterns without one against every element, so ".git" covers everything
+// inside a .git directory.
+func (t *FilesystemTool) denied(rel string) bool {
+	if rel == "." {
+		return false
+	}
+	elements := strings.Split(rel, "/")
+	for _, pattern := range t.deny {
+		for i := range elements {
+			subject := elements[i]
+			if strings.Contains(pattern, "/") {
+				subject = strings.Join(elements[:i+1], "/")
+			}
+			if matched, _ := path.Match(pattern, subject); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolve returns the real location of an existing path, checking that it
+// is still inside the root once symlinks are followed.
+func (t *FilesystemTool) resolve(rel string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(filepath.Join(t.root, filepath.FromSlash(rel)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("%s does not exist", rel)
+		}
+		return "", fmt.Errorf("failed to resolve %s: %w", rel, err)
+	}
+	if !withinDirs([]string{t.root}, resolved) {
+		return "", fmt.Errorf("path %q is outside the root", rel)
+	}
+	if target, err := filepath.Rel(t.root, resolved); err == nil && t.denied(filepath.ToSlash(target)) {
+		return "", fmt.Errorf("path %q is denied", rel)
+	}
+	return resolved, nil
+}
+
+func (t *FilesystemTool) read(ctx context.Context, rel string) (map[string]interface{}, error) {
+	resolved, err := t.resolve(rel)
+	if err != nil {
+		return nil, err
+	}
+	
+	file, err := os.Open(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", rel, err)
+	}
+	defer file.Close()
+	
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", rel, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", rel)
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", rel)
+	}
+	if info.Size() > t.maxFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rel, t.maxFileSize)
+	}
+	
+	// The size is checked again while reading in case the file grew
+	content, err := io.ReadAll(io.LimitReader(file, t.maxFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	if int64(len(content)) > t.maxFileSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", rel, t.maxFileSize)
+	}
+	
+	data := map[string]interface{}{
+		"path": rel,
+		"size": len(content),
+	}
+	if utf8.Valid(content) {
+		data["content"] = string(content)
+		return data, nil
+	}
+	
+	mimeType := mime.TypeByExtension(path.Ext(rel))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(content)
+	}
+	binary := map[string]interface{}{
+		"type":      "binary",
+		"name":      path.Base(rel),
+		"mime_type": mimeType,
+		"size":      len(content),
+	}
+	if t.config.Files != nil {
+		stored, err := t.config.Files.Put(ctx, path.Base(rel), mimeType, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", rel, err)
+		}
+		binary["type"] = "file"
+		binary["file_id"] = stored.ID
+	} else {
+		binary["base64"] = base64.StdEncoding.EncodeToString(content)
+	}
+	data["file"] = binary
+	return data, nil
+}
+
+func (t *FilesystemTool) write(rel, content string, appendContent bool) (map[string]interface{}, error) {
+	if rel == "." {
+		return nil, fmt.Errorf("cannot write to the root directory")
+	}
+	
+	parent, err := t.makeParent(path.Dir(rel))
+	if err != nil {
+		return nil, err
+	}
+	target := filepath.Join(parent, path.Base(rel))
+	
+	// An existing file may be a symlink, which must stay inside the root
+	var existing int64
+	if _, err := os.Lstat(target); err == nil {
+		resolved, err := t.resolve(rel)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat %s: %w", rel, err)
+		}
+		if !info.Mode().IsRegular() {
+			return nil, fmt.Errorf("%s is not a regular file", rel)
+		}
+		target = resolved
+		existing = info.Size()
+	}
+	
+	size := int64(len(content))
+	if appendContent {
+		size += existing
+	}
+	if size > t.maxFileSize {
+		return nil, fmt.Errorf("%s would be larger than %d bytes", rel, t.maxFileSize)
+	}
+	
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendContent {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	file, err := os.OpenFile(target, flags, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", rel, err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", rel, err)
+	}
+	
+	return map[string]interface{}{
+		"path":     rel,
+		"size":     size,
+		"appended": appendContent,
+	}, nil
+}
+
+// makeParent creates the directories leading to a file being written. The
+// deepest one that already exists is resolved first so that a symlinked
+// directory cannot lead the new ones outside the root.
+func (t *FilesystemTool) makeParent(rel string) (string, error) {
+	existing := rel
+	var missing []string
+	for existing != "." {
+		if _, err := os.Lstat(filepath.Join(t.root, filepath.FromSlash(existing))); err == nil {
+			break
+		}
+		missing = append([]string{path.Base(existing)}, missing...)
+		existing = path.Dir(existing)
+	}
+	
+	dir, err := t.resolve(existing)
+	if err != nil {
+		return "", err
+	}
+	if len(missing) == 0 {
+		return dir, nil
+	}
+	
+	dir = filepath.Join(append([]string{dir}, missing...)...)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", rel, err)
+	}
+	return dir, nil
+}
+
+func (t *FilesystemTool) list(rel string) (map[string]interface{}, error) {
+	resolved, err := t.resolve(rel)
+	if err != nil {
+		return nil, err
+	}
+	
+	dirEntries, err := os.ReadDir(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", rel, err)
+	}
+	
+	entries := make([]map[string]interface{}, 0, len(dirEntries))
+	truncated := false
+	for _, entry := range dirEntries {
+		if t.denied(path.Join(rel, entry.Name())) {
+			continue
+		}
+		if len(entries) >= t.maxEntries {
+			truncated = true
+			break
+		}
+		
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, map[string]interface{}{
+			"name":     entry.Name(),
+			"type":     fileType(info.Mode()),
+			"size":     info.Size(),
+			"modified": info.ModTime().UTC().Format(time.RFC3339),
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i]["name"].(string) < entries[j]["name"].(string)
+	})
+	
+	return map[string]interface{}{
+		"path":      rel,
+		"entries":   entries,
+		"truncated": truncated,
+	}, nil
+}
+
+func (t *FilesystemTool) stat(rel string) (map[string]interface{}, error) {
+	resolved, err := t.resolve(rel)
+	if err != nil {
+		return nil, err
+	}
+	
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", rel, err)
+	}
+	
+	return map[string]interface{}{
+		"path":     rel,
+		"type":     fileType(info.Mode()),
+		"size":     info.Size(),
+		"mode":     info.Mode().Perm().String(),
+		"modified": info.ModTime().UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (t *FilesystemTool) Close() error {
+	return nil
+}
+
+func fileType(mode fs.FileMode) string {
+	switch {
+	case mode.IsDir():
+		return "directory"
+	case mode.IsRegular():
+		return "file"
+	case mode&fs.ModeSymlink != 0:
+		return "symlink"
+	default:
+		return "other"
+	}
+}
\ No newline at end of file
@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilesystemPaths(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	files := map[string]string{
+		"notes.txt":       "notes",
+		"docs/guide.md":   "guide",
+		".git/config":     "[core]",
+		"keys/server.pem": "key",
+		"secrets/token":   "token",
+	}
+	for name, content := range files {
+		writeTestFile(t, filepath.Join(root, name), content)
+	}
+	writeTestFile(t, filepath.Join(outside, "secret.txt"), "outside")
+	for link, target := range map[string]string{
+		"out":      outside,
+		"outfile":  filepath.Join(outside, "secret.txt"),
+		"dangling": filepath.Join(outside, "missing.txt"),
+		"gitdir":   filepath.Join(root, ".git"),
+		"guide":    filepath.Join(root, "docs", "guide.md"),
+	} {
+		if err := os.Symlink(target, filepath.Join(root, link)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	
+	tool, err := NewFilesystemTool(&Config{
+		Name:   "files",
+		Config: map[string]string{"root": root, "deny": ".git, *.pem, secrets/*"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	
+	tests := []struct {
+		name      string
+		operation string
+		path      string
+		err       string
+	}{
+		{name: "file", operation: "read", path: "notes.txt"},
+		{name: "nested file", operation: "read", path: "docs/guide.md"},
+		{name: "dot-dot inside", operation: "read", path: "docs/../notes.txt"},
+		{name: "symlink inside", operation: "read", path: "guide"},
+		{name: "new file", operation: "write", path: "docs/new/page.md"},
+		{name: "dot-dot", operation: "read", path: "../secret.txt", err: "outside the root"},
+		{name: "nested dot-dot", operation: "read", path: "docs/../../secret.txt", err: "outside the root"},
+		{name: "absolute", operation: "read", path: "/etc/passwd", err: "must be relative"},
+		{name: "absolute outside", operation: "read", path: filepath.Join(outside, "secret.txt"), err: "must be relative"},
+		{name: "symlinked directory", operation: "read", path: "out/secret.txt", err: "outside the root"},
+		{name: "symlinked file", operation: "read", path: "outfile", err: "outside the root"},
+		{name: "list symlinked directory", operation: "list", path: "out", err: "outside the root"},
+		{name: "write through symlinked directory", operation: "write", path: "out/new.txt", err: "outside the root"},
+		{name: "create through symlinked directory", operation: "write", path: "out/sub/new.txt", err: "outside the root"},
+		{name: "write symlinked file", operation: "write", path: "outfile", err: "outside the root"},
+		{name: "write dangling symlink", operation: "write", path: "dangling", err: "does not exist"},
+		{name: "denied directory", operation: "read", path: ".git/config", err: "denied"},
+		{name: "denied extension", operation: "read", path: "keys/server.pem", err: "denied"},
+		{name: "denied path", operation: "stat", path: "secrets/token", err: "denied"},
+		{name: "denied write", operation: "write", path: "docs/new.pem", err: "denied"},
+		{name: "symlink to denied directory", operation: "read", path: "gitdir/config", err: "denied"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tool.Execute(context.Background(), map[string]interface{}{
+				"operation": tt.operation,
+				"path":      tt.path,
+				"content":   "written",
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.err == "" {
+				if result.Error != "" {
+					t.Fatalf("%s %s = %q; want success", tt.operation, tt.path, result.Error)
+				}
+				return
+			}
+			if !strings.Contains(result.Error, tt.err) {
+				t.Fatalf("%s %s = %q; want error containing %q", tt.operation, tt.path, result.Error, tt.err)
+			}
+		})
+	}
+	
+	if content, err := os.ReadFile(filepath.Join(outside, "secret.txt")); err != nil || string(content) != "outside" {
+		t.Errorf("file outside the root changed: %q, %v", content, err)
+	}
+	if _, err := os.Stat(filepath.Join(outside, "new.txt")); err == nil {
+		t.Errorf("file created outside the root")
+	}
+	
+	// Denied entries are left out of listings
+	result, err := tool.Execute(context.Background(), map[string]interface{}{"operation": "list", "path": "."})
+	if err != nil || result.Error != "" {
+		t.Fatalf("list . = %v, %v", result, err)
+	}
+	for _, entry := range result.Data.(map[string]interface{})["entries"].([]map[string]interface{}) {
+		if name := entry["name"]; name == ".git" {
+			t.Errorf("list . includes denied entry %v", name)
+		}
+	}
+}
+
+func writeTestFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
\ No newline at end of file
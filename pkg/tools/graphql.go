@@ -0,0 +1,1256 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultGraphQLMaxDepth = 10
+	// maxGraphQLSummary bounds the root fields listed in the tool description
+	maxGraphQLSummary = 4000
+)
+
+const graphQLIntrospectionQuery = `query IntrospectionQuery {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    types {
+      kind name description
+      fields(includeDeprecated: false) { name description args { name type { ...TypeRef } defaultValue } type { ...TypeRef } }
+      inputFields { name type { ...TypeRef } defaultValue }
+      enumValues(includeDeprecated: false) { name }
+      possibleTypes { name }
+    }
+  }
+}
+fragment TypeRef on __Type {
+  kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } }
+}`
+
+// GraphQLTool runs queries against a GraphQL endpoint. The schema is read by
+// introspection so the model can see what it may ask for. Queries are
+// parsed before they are sent: selections deeper than max_depth are refused,
+// variables are checked against their declared types, and mutations are
+// refused unless the read_only config entry is "false".
+type GraphQLTool struct {
+	config        *Config
+	client        *http.Client
+	readOnly      bool
+	introspection bool
+	maxDepth      int
+	schema        *graphQLSchema
+	mu            sync.Mutex
+}
+
+type graphQLSchema struct {
+	QueryType    *graphQLNamed           `json:"queryType"`
+	MutationType *graphQLNamed           `json:"mutationType"`
+	Types        []*graphQLType          `json:"types"`
+	byName       map[string]*graphQLType `json:"-"`
+}
+
+type graphQLNamed struct {
+	Name string `json:"name"`
+}
+
+type graphQLType struct {
+	Kind          string              `json:"kind"`
+	Name          string              `json:"name"`
+	Description   string              `json:"description"`
+	Fields        []graphQLField      `json:"fields"`
+	InputFields   []graphQLInputValue `json:"inputFields"`
+	EnumValues    []graphQLNamed      `json:"enumValues"`
+	PossibleTypes []graphQLNamed      `json:"possibleTypes"`
+}
+
+type graphQLField struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Args        []graphQLInputValue `json:"args"`
+	Type        *graphQLTypeRef     `json:"type"`
+}
+
+type graphQLInputValue struct {
+	Name         string          `json:"name"`
+	Type         *graphQLTypeRef `json:"type"`
+	DefaultValue *string         `json:"defaultValue"`
+}
+
+type graphQLTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	OfType *graphQLTypeRef `json:"ofType"`
+}
+
+func NewGraphQLTool(config *Config) (*GraphQLTool, error) {
+	if config.URL == "" {
+		return nil, fmt.Errorf("URL is required for GraphQL tool")
+	}
+	
+	readOnly := true
+	if value := config.Config["read_only"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read_only: %w", err)
+		}
+		readOnly = parsed
+	}
+	
+	introspection := true
+	if value := config.Config["introspection"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid introspection: %w", err)
+		}
+		introspection = parsed
+	}
+	
+	maxDepth, err := intConfig(config.Config, "max_depth", defaultGraphQLMaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	t := &GraphQLTool{
+		config:        config,
+		client:        &http.Client{Timeout: timeout},
+		readOnly:      readOnly,
+		introspection: introspection,
+		maxDepth:      maxDepth,
+	}
+	
+	// A server that is down at deploy time should not keep the agent from
+	// starting; the schema is fetched again when it is asked for
+	if introspection {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		t.loadSchema(ctx)
+		cancel()
+	}
+	
+	return t, nil
+}
+
+func (t *GraphQLTool) Name() string {
+	return t.config.Name
+}
+
+func (t *GraphQLTool) Type() string {
+	return "graphql"
+}
+
+func (t *GraphQLTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = "Run a GraphQL query against " + t.config.URL + "."
+		if t.readOnly {
+			description += " Mutations are not allowed."
+		}
+		t.mu.Lock()
+		schema := t.schema
+		t.mu.Unlock()
+		if schema != nil {
+			description += " " + schema.summary(!t.readOnly)
+		}
+		if t.introspection {
+			description += " Use command \"schema\" to see the full schema or one type."
+		}
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"command": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"query", "schema"},
+					"description": "query (default) runs a document; schema describes types",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "A GraphQL document",
+				},
+				"variables": map[string]interface{}{
+					"type":        "object",
+					"description": "Values for the document's variables",
+				},
+				"operation_name": map[string]interface{}{
+					"type":        "string",
+					"description": "The operation to run when the document has several",
+				},
+				"type": map[string]interface{}{
+					"type":        "string",
+					"description": "Limit schema to one type",
+				},
+			},
+		},
+	}
+}
+
+func (t *GraphQLTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	command, _ := args["command"].(string)
+	switch command {
+	case "", "query":
+		query, _ := args["query"].(string)
+		if strings.TrimSpace(query) == "" {
+			return &Result{Error: "query is required"}, nil
+		}
+		variables, _ := args["variables"].(map[string]interface{})
+		operationName, _ := args["operation_name"].(string)
+		return t.query(ctx, query, variables, operationName), nil
+	case "schema":
+		typeName, _ := args["type"].(string)
+		return t.describe(ctx, typeName), nil
+	default:
+		return &Result{Error: fmt.Sprintf("unknown command: %s", command)}, nil
+	}
+}
+
+// DryRun checks a query as Execute does and describes the request that
+// would send it.
+func (t *GraphQLTool) DryRun(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	command, _ := args["command"].(string)
+	switch command {
+	case "", "query":
+		query, _ := args["query"].(string)
+		if strings.TrimSpace(query) == "" {
+			return &Result{Error: "query is required"}, nil
+		}
+		variables, _ := args["variables"].(map[string]interface{})
+		operationName, _ := args["operation_name"].(string)
+		payload, metadata, errResult := t.prepare(query, variables, operationName)
+		if errResult != nil {
+			return errResult, nil
+		}
+		
+		req, err := t.newRequest(ctx, payload)
+		if err != nil {
+			return &Result{Error: err.Error()}, nil
+		}
+		result := describeRequest(req, t.config.Auth)
+		result.Metadata = metadata
+		return result, nil
+	case "schema":
+		req, err := t.newRequest(ctx, map[string]interface{}{"query": graphQLIntrospectionQuery})
+		if err != nil {
+			return &Result{Error: err.Error()}, nil
+		}
+		return describeRequest(req, t.config.Auth), nil
+	default:
+		return &Result{Error: fmt.Sprintf("unknown command: %s", command)}, nil
+	}
+}
+
+func (t *GraphQLTool) query(ctx context.Context, query string, variables map[string]interface{}, operationName string) *Result {
+	payload, metadata, errResult := t.prepare(query, variables, operationName)
+	if errResult != nil {
+		return errResult
+	}
+	
+	start := time.Now()
+	response, err := t.post(ctx, payload)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	metadata["duration_ms"] = time.Since(start).Milliseconds()
+	
+	if len(response.Errors) == 0 {
+		return &Result{Data: response.Data, Metadata: metadata}
+	}
+	
+	messages := make([]string, 0, len(response.Errors))
+	for _, graphQLError := range response.Errors {
+		messages = append(messages, graphQLError.Message)
+	}
+	if response.Data == nil {
+		return &Result{Error: "GraphQL errors: " + strings.Join(messages, "; "), Metadata: metadata}
+	}
+	
+	// A partial result keeps its data alongside the errors
+	return &Result{
+		Data: map[string]interface{}{
+			"data":   response.Data,
+			"errors": response.Errors,
+		},
+		Metadata: metadata,
+	}
+}
+
+// prepare checks a query against the tool's limits and returns the request
+// payload for it, with metadata describing the operation.
+func (t *GraphQLTool) prepare(query string, variables map[string]interface{}, operationName string) (map[string]interface{}, map[string]interface{}, *Result) {
+	doc, err := parseGraphQL(query)
+	if err != nil {
+		return nil, nil, &Result{Error: fmt.Sprintf("invalid query: %v", err)}
+	}
+	
+	operation, err := doc.operation(operationName)
+	if err != nil {
+		return nil, nil, &Result{Error: err.Error()}
+	}
+	switch {
+	case operation.kind == "subscription":
+		return nil, nil, &Result{Error: "subscriptions are not supported"}
+	case operation.kind == "mutation" && t.readOnly:
+		return nil, nil, &Result{Error: "mutations are not allowed in read-only mode"}
+	}
+	
+	depth, err := doc.depth(operation.selections, nil)
+	if err != nil {
+		return nil, nil, &Result{Error: fmt.Sprintf("invalid query: %v", err)}
+	}
+	if depth > t.maxDepth {
+		return nil, nil, &Result{Error: fmt.Sprintf("query depth %d exceeds the limit of %d", depth, t.maxDepth)}
+	}
+	
+	t.mu.Lock()
+	schema := t.schema
+	t.mu.Unlock()
+	if err := operation.validateVariables(variables, schema); err != nil {
+		return nil, nil, &Result{Error: err.Error()}
+	}
+	
+	payload := map[string]interface{}{"query": query}
+	if len(variables) > 0 {
+		payload["variables"] = variables
+	}
+	if operationName != "" {
+		payload["operationName"] = operationName
+	}
+	
+	metadata := map[string]interface{}{
+		"operation": operation.kind,
+		"depth":     depth,
+	}
+	return payload, metadata, nil
+}
+
+type graphQLResponse struct {
+	Data   interface{} `json:"data"`
+	Errors []struct {
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path,omitempty"`
+	} `json:"errors,omitempty"`
+}
+
+func (t *GraphQLTool) newRequest(ctx context.Context, payload map[string]interface{}) (*http.Request, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.config.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/graphql-response+json, application/json")
+	req.Header.Set("User-Agent", "goagents/1.0")
+	applyRequestAuth(req, t.config)
+	return req, nil
+}
+
+func (t *GraphQLTool) post(ctx context.Context, payload map[string]interface{}) (*graphQLResponse, error) {
+	req, err := t.newRequest(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	
+	// GraphQL over HTTP reports query errors with 4xx statuses and a body
+	// in the usual shape, so only give up when there is no such body
+	var response graphQLResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil || (response.Data == nil && len(response.Errors) == 0) {
+		if resp.StatusCode >= 400 {
+			return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(responseBody))
+		}
+		return nil, fmt.Errorf("invalid GraphQL response: %s", string(responseBody))
+	}
+	
+	return &response, nil
+}
+
+// loadSchema introspects the endpoint. The caller must not hold t.mu.
+func (t *GraphQLTool) loadSchema(ctx context.Context) (*graphQLSchema, error) {
+	response, err := t.post(ctx, map[string]interface{}{"query": graphQLIntrospectionQuery})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("introspection failed: %s", response.Errors[0].Message)
+	}
+	
+	var wrapper struct {
+		Schema *graphQLSchema `json:"__schema"`
+	}
+	data, err := json.Marshal(response.Data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read introspection result: %w", err)
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("failed to read introspection result: %w", err)
+	}
+	if wrapper.Schema == nil {
+		return nil, fmt.Errorf("introspection returned no schema")
+	}
+	
+	schema := wrapper.Schema
+	schema.byName = make(map[string]*graphQLType, len(schema.Types))
+	for _, schemaType := range schema.Types {
+		schema.byName[schemaType.Name] = schemaType
+	}
+	
+	t.mu.Lock()
+	t.schema = schema
+	t.mu.Unlock()
+	return schema, nil
+}
+
+func (t *GraphQLTool) describe(ctx context.Context, typeName string) *Result {
+	if !t.introspection {
+		return &Result{Error: "introspection is disabled for this tool"}
+	}
+	
+	t.mu.Lock()
+	schema := t.schema
+	t.mu.Unlock()
+	if schema == nil {
+		var err error
+		if schema, err = t.loadSchema(ctx); err != nil {
+			return &Result{Error: err.Error()}
+		}
+	}
+	
+	if typeName != "" {
+		schemaType, ok := schema.byName[typeName]
+		if !ok {
+			return &Result{Error: fmt.Sprintf("unknown type: %s", typeName)}
+		}
+		return &Result{Data: map[string]interface{}{"schema": schemaType.sdl()}}
+	}
+	
+	var sdl []string
+	for _, schemaType := range schema.Types {
+		if !strings.HasPrefix(schemaType.Name, "__") && !isBuiltinScalar(schemaType.Name) {
+			sdl = append(sdl, schemaType.sdl())
+		}
+	}
+	
+	data := map[string]interface{}{
+		"schema": strings.Join(sdl, "\n\n"),
+	}
+	if schema.QueryType != nil {
+		data["query_type"] = schema.QueryType.Name
+	}
+	if schema.MutationType != nil {
+		data["mutation_type"] = schema.MutationType.Name
+	}
+	return &Result{Data: data}
+}
+
+func (t *GraphQLTool) Close() error {
+	return nil
+}
+
+// summary lists the root fields for the tool description.
+func (s *graphQLSchema) summary(mutations bool) string {
+	var parts []string
+	roots := []struct {
+		label string
+		named *graphQLNamed
+	}{{"Queries", s.QueryType}}
+	if mutations {
+		roots = append(roots, struct {
+			label string
+			named *graphQLNamed
+		}{"Mutations", s.MutationType})
+	}
+	
+	for _, root := range roots {
+		if root.named == nil || s.byName[root.named.Name] == nil {
+			continue
+		}
+		fields := make([]string, 0)
+		for _, field := range s.byName[root.named.Name].Fields {
+			fields = append(fields, field.signature())
+		}
+		parts = append(parts, root.label+": "+strings.Join(fields, "; ")+".")
+	}
+	
+	summary := strings.Join(parts, " ")
+	if len(summary) > maxGraphQLSummary {
+		summary = summary[:maxGraphQLSummary] + "..."
+	}
+	return summary
+}
+
+func (f *graphQLField) signature() string {
+	signature := f.Name
+	if len(f.Args) > 0 {
+		args := make([]string, 0, len(f.Args))
+		for _, arg := range f.Args {
+			args = append(args, arg.sdl())
+		}
+		signature += "(" + strings.Join(args, ", ") + ")"
+	}
+	return signature + ": " + f.Type.String()
+}
+
+func (v *graphQLInputValue) sdl() string {
+	sdl := v.Name + ": " + v.Type.String()
+	if v.DefaultValue != nil {
+		sdl += " = " + *v.DefaultValue
+	}
+	return sdl
+}
+
+// sdl renders a type in schema definition language.
+func (t *graphQLType) sdl() string {
+	var b strings.Builder
+	if t.Description != "" {
+		b.WriteString(strconv.Quote(t.Description) + "\n")
+	}
+	
+	switch t.Kind {
+	case "OBJECT", "INTERFACE":
+		keyword := "type"
+		if t.Kind == "INTERFACE" {
+			keyword = "interface"
+		}
+		b.WriteString(keyword + " " + t.Name + " {\n")
+		for _, field := range t.Fields {
+			if field.Description != "" {
+				b.WriteString("  # " + strings.ReplaceAll(field.Description, "\n", " ") + "\n")
+			}
+			b.WriteString("  " + field.signature() + "\n")
+		}
+		b.WriteString("}")
+	case "INPUT_OBJECT":
+		b.WriteString("input " + t.Name + " {\n")
+		for _, field := range t.InputFields {
+			b.WriteString("  " + field.sdl() + "\n")
+		}
+		b.WriteString("}")
+	case "ENUM":
+		values := make([]string, 0, len(t.EnumValues))
+		for _, value := range t.EnumValues {
+			values = append(values, value.Name)
+		}
+		b.WriteString("enum " + t.Name + " { " + strings.Join(values, " ") + " }")
+	case "UNION":
+		members := make([]string, 0, len(t.PossibleTypes))
+		for _, member := range t.PossibleTypes {
+			members = append(members, member.Name)
+		}
+		b.WriteString("union " + t.Name + " = " + strings.Join(members, " | "))
+	default:
+		b.WriteString("scalar " + t.Name)
+	}
+	return b.String()
+}
+
+func (r *graphQLTypeRef) String() string {
+	if r == nil {
+		return "?"
+	}
+	switch r.Kind {
+	case "NON_NULL":
+		return r.OfType.String() + "!"
+	case "LIST":
+		return "[" + r.OfType.String() + "]"
+	default:
+		return r.Name
+	}
+}
+
+func isBuiltinScalar(name string) bool {
+	switch name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+// graphQLDocument is the part of a parsed document needed to check it:
+// its operations and fragments with their selection structure.
+type graphQLDocument struct {
+	operations []*graphQLOperation
+	fragments  map[string]*graphQLSelectionSet
+}
+
+type graphQLOperation struct {
+	kind       string
+	name       string
+	variables  []graphQLVariable
+	selections *graphQLSelectionSet
+}
+
+type graphQLVariable struct {
+	name       string
+	varType    *graphQLVarType
+	hasDefault bool
+}
+
+// graphQLVarType is a variable's declared type, such as [Int!]!.
+type graphQLVarType struct {
+	name    string
+	elem    *graphQLVarType
+	nonNull bool
+}
+
+type graphQLSelectionSet struct {
+	// fields holds each field's sub-selection, nil for leaf fields
+	fields  []*graphQLSelectionSet
+	inline  []*graphQLSelectionSet
+	spreads []string
+}
+
+func (d *graphQLDocument) operation(name string) (*graphQLOperation, error) {
+	if len(d.operations) == 0 {
+		return nil, fmt.Errorf("document has no operation")
+	}
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, fmt.Errorf("operation_name is required when the document has several operations")
+		}
+		return d.operations[0], nil
+	}
+	for _, operation := range d.operations {
+		if operation.name == name {
+			return operation, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown operation: %s", name)
+}
+
+// depth returns how deeply a selection set nests fields, following
+// fragment spreads.
+func (d *graphQLDocument) depth(set *graphQLSelectionSet, visiting []string) (int, error) {
+	if set == nil {
+		return 0, nil
+	}
+	
+	deepest := 0
+	for _, child := range set.fields {
+		depth, err := d.depth(child, visiting)
+		if err != nil {
+			return 0, err
+		}
+		deepest = maxInt(deepest, depth+1)
+	}
+	for _, child := range set.inline {
+		depth, err := d.depth(child, visiting)
+		if err != nil {
+			return 0, err
+		}
+		deepest = maxInt(deepest, depth)
+	}
+	for _, name := range set.spreads {
+		fragment, ok := d.fragments[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown fragment %s", name)
+		}
+		if containsString(visiting, name) {
+			return 0, fmt.Errorf("fragment %s spreads itself", name)
+		}
+		depth, err := d.depth(fragment, append(visiting[:len(visiting):len(visiting)], name))
+		if err != nil {
+			return 0, err
+		}
+		deepest = maxInt(deepest, depth)
+	}
+	return deepest, nil
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// validateVariables checks supplied values against the operation's
+// variable definitions, and against input types when the schema is known.
+func (o *graphQLOperation) validateVariables(values map[string]interface{}, schema *graphQLSchema) error {
+	declared := make(map[string]bool, len(o.variables))
+	for _, variable := range o.variables {
+		declared[variable.name] = true
+		value, ok := values[variable.name]
+		if !ok && (variable.hasDefault || !variable.varType.nonNull) {
+			continue
+		}
+		if err := validateGraphQLValue(value, variable.varType, "$"+variable.name, schema); err != nil {
+			return err
+		}
+	}
+	
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !declared[name] {
+			return fmt.Errorf("variable $%s is not declared by the operation", name)
+		}
+	}
+	return nil
+}
+
+func validateGraphQLValue(value interface{}, varType *graphQLVarType, path string, schema *graphQLSchema) error {
+	if value == nil {
+		if varType.nonNull {
+			return fmt.Errorf("%s is required", path)
+		}
+		return nil
+	}
+	
+	if varType.elem != nil {
+		items, ok := value.([]interface{})
+		if !ok {
+			// A single value is accepted for a list of one
+			return validateGraphQLValue(value, varType.elem, path, schema)
+		}
+		for i, item := range items {
+			if err := validateGraphQLValue(item, varType.elem, fmt.Sprintf("%s[%d]", path, i), schema); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	
+	switch varType.name {
+	case "Int":
+		if n, ok := value.(float64); !ok || n != math.Trunc(n) || n > math.MaxInt32 || n < math.MinInt32 {
+			return fmt.Errorf("%s must be an Int", path)
+		}
+		return nil
+	case "Float":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s must be a Float", path)
+		}
+		return nil
+	case "String":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s must be a String", path)
+		}
+		return nil
+	case "Boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s must be a Boolean", path)
+		}
+		return nil
+	case "ID":
+		switch v := value.(type) {
+		case string:
+			return nil
+		case float64:
+			if v == math.Trunc(v) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be an ID", path)
+	}
+	
+	if schema == nil {
+		return nil
+	}
+	schemaType, ok := schema.byName[varType.name]
+	if !ok {
+		return fmt.Errorf("%s has unknown type %s", path, varType.name)
+	}
+	
+	switch schemaType.Kind {
+	case "ENUM":
+		for _, enumValue := range schemaType.EnumValues {
+			if value == enumValue.Name {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be one of the %s values", path, schemaType.Name)
+	case "INPUT_OBJECT":
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be a %s object", path, schemaType.Name)
+		}
+		known := make(map[string]bool, len(schemaType.InputFields))
+		for _, field := range schemaType.InputFields {
+			known[field.Name] = true
+			fieldType := field.Type.varType()
+			fieldValue, present := fields[field.Name]
+			if !present && (field.DefaultValue != nil || !fieldType.nonNull) {
+				continue
+			}
+			if err := validateGraphQLValue(fieldValue, fieldType, path+"."+field.Name, schema); err != nil {
+				return err
+			}
+		}
+		for name := range fields {
+			if !known[name] {
+				return fmt.Errorf("%s has no field %s", path, name)
+			}
+		}
+		return nil
+	case "SCALAR":
+		return nil
+	default:
+		return fmt.Errorf("%s has type %s, which is not an input type", path, schemaType.Name)
+	}
+}
+
+func (r *graphQLTypeRef) varType() *graphQLVarType {
+	if r == nil {
+		return &graphQLVarType{}
+	}
+	switch r.Kind {
+	case "NON_NULL":
+		inner := r.OfType.varType()
+		inner.nonNull = true
+		return inner
+	case "LIST":
+		return &graphQLVarType{elem: r.OfType.varType()}
+	default:
+		return &graphQLVarType{name: r.Name}
+	}
+}
+
+type graphQLToken struct {
+	kind  byte // 'n' name, 'p' punctuator, 's' string, '0' number, 0 end
+	value string
+}
+
+// graphQLParser reads executable documents: operations and fragments.
+// Values are skipped, since only the document's shape is checked.
+type graphQLParser struct {
+	src string
+	pos int
+	tok graphQLToken
+}
+
+func parseGraphQL(src string) (*graphQLDocument, error) {
+	p := &graphQLParser{src: src}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	
+	doc := &graphQLDocument{fragments: make(map[string]*graphQLSelectionSet)}
+	for p.tok.kind != 0 {
+		switch {
+		case p.tok.kind == 'p' && p.tok.value == "{":
+			selections, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &graphQLOperation{kind: "query", selections: selections})
+		case p.tok.kind == 'n' && (p.tok.value == "query" || p.tok.value == "mutation" || p.tok.value == "subscription"):
+			operation, err := p.operation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, operation)
+		case p.tok.kind == 'n' && p.tok.value == "fragment":
+			name, selections, err := p.fragment()
+			if err != nil {
+				return nil, err
+			}
+			doc.fragments[name] = selections
+		default:
+			return nil, fmt.Errorf("unexpected %q at offset %d", p.tok.value, p.pos)
+		}
+	}
+	return doc, nil
+}
+
+func (p *graphQLParser) operation() (*graphQLOperation, error) {
+	operation := &graphQLOperation{kind: p.tok.value}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind == 'n' {
+		operation.name = p.tok.value
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	
+	if p.isPunct("(") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		for !p.isPunct(")") {
+			variable, err := p.variableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			operation.variables = append(operation.variables, variable)
+		}
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	
+	if err := p.directives(); err != nil {
+		return nil, err
+	}
+	selections, err := p.selectionSet()
+	if err != nil {
+		return nil, err
+	}
+	operation.selections = selections
+	return operation, nil
+}
+
+func (p *graphQLParser) variableDefinition() (graphQLVariable, error) {
+	var variable graphQLVariable
+	if err := p.expectPunct("$"); err != nil {
+		return variable, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return variable, err
+	}
+	variable.name = name
+	if err := p.expectPunct(":"); err != nil {
+		return variable, err
+	}
+	if variable.varType, err = p.typeReference(); err != nil {
+		return variable, err
+	}
+	if p.isPunct("=") {
+		if err := p.next(); err != nil {
+			return variable, err
+		}
+		if err := p.skipValue(); err != nil {
+			return variable, err
+		}
+		variable.hasDefault = true
+	}
+	return variable, p.directives()
+}
+
+func (p *graphQLParser) typeReference() (*graphQLVarType, error) {
+	var varType *graphQLVarType
+	if p.isPunct("[") {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		elem, err := p.typeReference()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return nil, err
+		}
+		varType = &graphQLVarType{elem: elem}
+	} else {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		varType = &graphQLVarType{name: name}
+	}
+	
+	if p.isPunct("!") {
+		varType.nonNull = true
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+	}
+	return varType, nil
+}
+
+func (p *graphQLParser) fragment() (string, *graphQLSelectionSet, error) {
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	name, err := p.expectName()
+	if err != nil {
+		return "", nil, err
+	}
+	if p.tok.kind != 'n' || p.tok.value != "on" {
+		return "", nil, fmt.Errorf("expected \"on\" after fragment %s", name)
+	}
+	if err := p.next(); err != nil {
+		return "", nil, err
+	}
+	if _, err := p.expectName(); err != nil {
+		return "", nil, err
+	}
+	if err := p.directives(); err != nil {
+		return "", nil, err
+	}
+	selections, err := p.selectionSet()
+	return name, selections, err
+}
+
+func (p *graphQLParser) selectionSet() (*graphQLSelectionSet, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+	
+	set := &graphQLSelectionSet{}
+	for !p.isPunct("}") {
+		if p.tok.kind == 0 {
+			return nil, fmt.Errorf("unterminated selection set")
+		}
+		
+		if p.isPunct("...") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if p.tok.kind == 'n' && p.tok.value != "on" {
+				set.spreads = append(set.spreads, p.tok.value)
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+				if err := p.directives(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if p.tok.kind == 'n' {
+				if err := p.next(); err != nil {
+					return nil, err
+				}
+				if _, err := p.expectName(); err != nil {
+					return nil, err
+				}
+			}
+			if err := p.directives(); err != nil {
+				return nil, err
+			}
+			inline, err := p.selectionSet()
+			if err != nil {
+				return nil, err
+			}
+			set.inline = append(set.inline, inline)
+			continue
+		}
+		
+		if _, err := p.expectName(); err != nil {
+			return nil, err
+		}
+		// An alias is followed by the field's real name
+		if p.isPunct(":") {
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+			if _, err := p.expectName(); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.arguments(); err != nil {
+			return nil, err
+		}
+		if err := p.directives(); err != nil {
+			return nil, err
+		}
+		
+		var child *graphQLSelectionSet
+		if p.isPunct("{") {
+			var err error
+			if child, err = p.selectionSet(); err != nil {
+				return nil, err
+			}
+		}
+		set.fields = append(set.fields, child)
+	}
+	
+	return set, p.next()
+}
+
+func (p *graphQLParser) arguments() error {
+	if !p.isPunct("(") {
+		return nil
+	}
+	if err := p.next(); err != nil {
+		return err
+	}
+	for !p.isPunct(")") {
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return err
+		}
+		if err := p.skipValue(); err != nil {
+			return err
+		}
+	}
+	return p.next()
+}
+
+func (p *graphQLParser) directives() error {
+	for p.isPunct("@") {
+		if err := p.next(); err != nil {
+			return err
+		}
+		if _, err := p.expectName(); err != nil {
+			return err
+		}
+		if err := p.arguments(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *graphQLParser) skipValue() error {
+	switch {
+	case p.isPunct("$"):
+		if err := p.next(); err != nil {
+			return err
+		}
+		_, err := p.expectName()
+		return err
+	case p.isPunct("["):
+		if err := p.next(); err != nil {
+			return err
+		}
+		for !p.isPunct("]") {
+			if p.tok.kind == 0 {
+				return fmt.Errorf("unterminated list")
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+		}
+		return p.next()
+	case p.isPunct("{"):
+		if err := p.next(); err != nil {
+			return err
+		}
+		for !p.isPunct("}") {
+			if _, err := p.expectName(); err != nil {
+				return err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return err
+			}
+			if err := p.skipValue(); err != nil {
+				return err
+			}
+		}
+		return p.next()
+	case p.tok.kind == 'n' || p.tok.kind == 's' || p.tok.kind == '0':
+		return p.next()
+	default:
+		return fmt.Errorf("expected a value at offset %d", p.pos)
+	}
+}
+
+func (p *graphQLParser) isPunct(value string) bool {
+	return p.tok.kind == 'p' && p.tok.value == value
+}
+
+func (p *graphQLParser) expectPunct(value string) error {
+	if !p.isPunct(value) {
+		return fmt.Errorf("expected %q at offset %d", value, p.pos)
+	}
+	return p.next()
+}
+
+func (p *graphQLParser) expectName() (string, error) {
+	if p.tok.kind != 'n' {
+		return "", fmt.Errorf("expected a name at offset %d", p.pos)
+	}
+	name := p.tok.value
+	return name, p.next()
+}
+
+// next reads the following token, skipping whitespace, commas and comments.
+func (p *graphQLParser) next() error {
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' {
+			p.pos++
+		} else if c == '#' {
+			for p.pos < len(p.src) && p.src[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if strings.HasPrefix(p.src[p.pos:], "\uFEFF") {
+			p.pos += len("\uFEFF")
+		} else {
+			break
+		}
+	}
+	if p.pos >= len(p.src) {
+		p.tok = graphQLToken{}
+		return nil
+	}
+	
+	start := p.pos
+	c := p.src[p.pos]
+	switch {
+	case strings.HasPrefix(p.src[p.pos:], "..."):
+		p.pos += 3
+		p.tok = graphQLToken{kind: 'p', value: "..."}
+	case strings.ContainsRune("!$&()=:@[]{}|", rune(c)):
+		p.pos++
+		p.tok = graphQLToken{kind: 'p', value: string(c)}
+	case c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		for p.pos < len(p.src) && isIdentChar(p.src[p.pos]) {
+			p.pos++
+		}
+		p.tok = graphQLToken{kind: 'n', value: p.src[start:p.pos]}
+	case c == '-' || (c >= '0' && c <= '9'):
+		p.pos++
+		for p.pos < len(p.src) && (isIdentChar(p.src[p.pos]) || strings.ContainsRune(".+-", rune(p.src[p.pos]))) {
+			p.pos++
+		}
+		p.tok = graphQLToken{kind: '0', value: p.src[start:p.pos]}
+	case strings.HasPrefix(p.src[p.pos:], `"""`):
+		end := strings.Index(strings.ReplaceAll(p.src[p.pos+3:], `\"""`, "xxxx"), `"""`)
+		if end < 0 {
+			return fmt.Errorf("unterminated block string at offset %d", start)
+		}
+		p.pos += end + 6
+		p.tok = graphQLToken{kind: 's', value: p.src[start:p.pos]}
+	case c == '"':
+		p.pos++
+		for p.pos < len(p.src) && p.src[p.pos] != '"' && p.src[p.pos] != '\n' {
+			if p.src[p.pos] == '\\' {
+				p.pos++
+			}
+			p.pos++
+		}
+		if p.pos >= len(p.src) || p.src[p.pos] != '"' {
+			return fmt.Errorf("unterminated string at offset %d", start)
+		}
+		p.pos++
+		p.tok = graphQLToken{kind: 's', value: p.src[start:p.pos]}
+	default:
+		return fmt.Errorf("unexpected character %q at offset %d", c, start)
+	}
+	return nil
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
\ No newline at end of file
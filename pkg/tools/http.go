@@ -3,17 +3,40 @@ package tools
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"path"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"github.com/goagents/goagents/pkg/files"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	defaultHTTPMaxResponseBytes = 10 << 20
+	defaultHTTPMaxPages         = 10
 )
 
+// HTTPTool calls an HTTP API on hosts the outbound policy allows. Responses
+// larger than max_response_bytes are cut off, and APIs that paginate with
+// Link headers or cursors can be followed so a call returns the items of
+// several pages.
 type HTTPTool struct {
-	config *Config
-	client *http.Client
+	config           *Config
+	client           *http.Client
+	maxResponseBytes int64
+	pagination       string
+	cursorParam      string
+	cursorPath       string
+	itemsPath        string
+	maxPages         int
 }
 
 func NewHTTPTool(config *Config) (*HTTPTool, error) {
@@ -26,11 +49,43 @@ func NewHTTPTool(config *Config) (*HTTPTool, error) {
 		timeout = config.Timeout
 	}
 	
+	maxResponseBytes, err := intConfig(config.Config, "max_response_bytes", defaultHTTPMaxResponseBytes)
+	if err != nil {
+		return nil, err
+	}
+	maxPages, err := intConfig(config.Config, "max_pages", defaultHTTPMaxPages)
+	if err != nil {
+		return nil, err
+	}
+	
+	pagination := strings.ToLower(config.Config["pagination"])
+	cursorParam := config.Config["cursor_param"]
+	switch pagination {
+	case "", "link":
+	case "cursor":
+		if config.Config["cursor_path"] == "" {
+			return nil, fmt.Errorf("cursor_path is required for cursor pagination")
+		}
+		if cursorParam == "" {
+			cursorParam = "cursor"
+		}
+	default:
+		return nil, fmt.Errorf("unsupported pagination: %q, expected link or cursor", pagination)
+	}
+	
 	return &HTTPTool{
 		config: config,
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:       timeout,
+			Transport:     config.Outbound.Transport(),
+			CheckRedirect: config.Outbound.CheckRedirect,
 		},
+		maxResponseBytes: int64(maxResponseBytes),
+		pagination:       pagination,
+		cursorParam:      cursorParam,
+		cursorPath:       config.Config["cursor_path"],
+		itemsPath:        config.Config["items_path"],
+		maxPages:         maxPages,
 	}, nil
 }
 
@@ -43,6 +98,34 @@ func (t *HTTPTool) Type() string {
 }
 
 func (t *HTTPTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	method, url, payload, errResult := t.requestArgs(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+	
+	resp, result := t.fetch(ctx, method, url, payload, t.maxResponseBytes)
+	if resp == nil || t.pagination == "" || result.Error != "" {
+		return result, nil
+	}
+	return t.paginate(ctx, method, payload, resp, result), nil
+}
+
+// DryRun describes the first request Execute would send for args.
+func (t *HTTPTool) DryRun(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	method, url, payload, errResult := t.requestArgs(args)
+	if errResult != nil {
+		return errResult, nil
+	}
+	
+	req, errResult := t.newRequest(ctx, method, url, payload)
+	if errResult != nil {
+		return errResult, nil
+	}
+	return describeRequest(req, t.config.Auth), nil
+}
+
+// requestArgs reads the method, URL and body of a call from its arguments.
+func (t *HTTPTool) requestArgs(args map[string]interface{}) (string, string, []byte, *Result) {
 	method := "POST"
 	if m, ok := args["method"].(string); ok {
 		method = strings.ToUpper(m)
@@ -53,82 +136,350 @@ func (t *HTTPTool) Execute(ctx context.Context, args map[string]interface{}) (*R
 		url = strings.TrimSuffix(url, "/") + "/" + strings.TrimPrefix(endpoint, "/")
 	}
 	
-	var body io.Reader
+	var payload []byte
 	if method != "GET" && method != "HEAD" {
 		if data, ok := args["data"]; ok {
 			jsonData, err := json.Marshal(data)
 			if err != nil {
-				return &Result{Error: fmt.Sprintf("failed to marshal request data: %v", err)}, nil
+				return "", "", nil, &Result{Error: fmt.Sprintf("failed to marshal request data: %v", err)}
 			}
-			body = bytes.NewReader(jsonData)
+			payload = jsonData
 		}
 	}
+	return method, url, payload, nil
+}
+
+// newRequest creates a request with the tool's headers and credentials,
+// refusing URLs the outbound policy does not allow.
+func (t *HTTPTool) newRequest(ctx context.Context, method, url string, payload []byte) (*http.Request, *Result) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
 	
 	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
-		return &Result{Error: fmt.Sprintf("failed to create request: %v", err)}, nil
+		return nil, &Result{Error: fmt.Sprintf("failed to create request: %v", err)}
+	}
+	if err := t.config.Outbound.CheckURL(req.URL); err != nil {
+		return nil, &Result{Error: err.Error()}
 	}
 	
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "goagents/1.0")
 	
-	// Add authentication
-	if t.config.Auth != nil {
-		switch t.config.Auth.Type {
-		case "bearer":
-			req.Header.Set("Authorization", "Bearer "+t.config.Auth.Token)
-		case "api_key":
-			req.Header.Set("X-API-Key", t.config.Auth.APIKey)
-		case "basic":
-			req.SetBasicAuth(t.config.Auth.APIKey, t.config.Auth.Secret)
-		}
-	}
-	
-	// Add custom headers from config
-	for key, value := range t.config.Config {
-		if strings.HasPrefix(key, "header_") {
-			headerName := strings.TrimPrefix(key, "header_")
-			req.Header.Set(headerName, value)
-		}
+	applyRequestAuth(req, t.config)
+	return req, nil
+}
+
+// fetch sends one request and converts its response, reading at most
+// maxBytes of the body. The response is nil when the request failed.
+func (t *HTTPTool) fetch(ctx context.Context, method, url string, payload []byte, maxBytes int64) (*http.Response, *Result) {
+	req, result := t.newRequest(ctx, method, url, payload)
+	if result != nil {
+		return nil, result
 	}
 	
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return &Result{Error: fmt.Sprintf("request failed: %v", err)}, nil
+		return nil, &Result{Error: fmt.Sprintf("request failed: %v", err)}
 	}
 	defer resp.Body.Close()
 	
-	responseBody, err := io.ReadAll(resp.Body)
+	return resp, httpResult(ctx, t.config.Files, resp, maxBytes, map[string]interface{}{
+		"status_code": resp.StatusCode,
+		"headers":     resp.Header,
+		"url":         url,
+		"method":      method,
+	})
+}
+
+// paginate follows the next page of a paginated response until there is
+// none, max_pages is reached or max_response_bytes has been read in total,
+// and returns the items of every page as one list. The metadata describes
+// the first page, with the total size and number of pages read and, when
+// pages were left unread, the link or cursor of the next one.
+func (t *HTTPTool) paginate(ctx context.Context, method string, payload []byte, resp *http.Response, result *Result) *Result {
+	items, ok := pageItems(result.Data, t.itemsPath)
+	if !ok {
+		return result
+	}
+	
+	metadata := result.Metadata
+	remaining := t.maxResponseBytes - int64(metadata["size"].(int))
+	pages := 1
+	for {
+		next, ok := t.nextPage(resp, result.Data)
+		if !ok {
+			break
+		}
+		if pages >= t.maxPages || remaining <= 0 {
+			if t.pagination == "link" {
+				metadata["next_page"] = next
+			} else {
+				metadata["next_cursor"] = lookupJSONPath(result.Data, t.cursorPath)
+			}
+			break
+		}
+		
+		resp, result = t.fetch(ctx, method, next, payload, remaining)
+		if resp == nil || result.Error != "" {
+			metadata["page_error"] = result.Error
+			break
+		}
+		pageData, ok := pageItems(result.Data, t.itemsPath)
+		if !ok {
+			metadata["truncated"] = true
+			break
+		}
+		items = append(items, pageData...)
+		remaining -= int64(result.Metadata["size"].(int))
+		pages++
+	}
+	
+	metadata["pages"] = pages
+	metadata["size"] = int(t.maxResponseBytes - remaining)
+	return &Result{Data: items, Metadata: metadata}
+}
+
+// nextPage returns the URL of the page after resp, taken from its Link
+// header or built from the cursor in its body.
+func (t *HTTPTool) nextPage(resp *http.Response, data interface{}) (string, bool) {
+	if t.pagination == "link" {
+		return nextLink(resp)
+	}
+	
+	var cursor string
+	switch value := lookupJSONPath(data, t.cursorPath).(type) {
+	case string:
+		cursor = value
+	case float64:
+		cursor = strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	if cursor == "" {
+		return "", false
+	}
+	
+	next := *resp.Request.URL
+	query := next.Query()
+	query.Set(t.cursorParam, cursor)
+	next.RawQuery = query.Encode()
+	return next.String(), true
+}
+
+// nextLink finds the rel="next" entry of a response's Link header, as in
+// <https://api.example.com/items?page=2>; rel="next", and resolves it
+// against the request URL.
+func nextLink(resp *http.Response) (string, bool) {
+	for _, header := range resp.Header.Values("Link") {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			for _, param := range parts[1:] {
+				name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+				if !found || !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				for _, rel := range strings.Fields(strings.Trim(strings.TrimSpace(value), `"`)) {
+					if strings.EqualFold(rel, "next") {
+						next, err := resp.Request.URL.Parse(strings.Trim(target, "<>"))
+						if err != nil {
+							return "", false
+						}
+						return next.String(), true
+					}
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// pageItems returns the list of items in a page: the value at path, or the
+// page itself when there is no path.
+func pageItems(data interface{}, path string) ([]interface{}, bool) {
+	items, ok := lookupJSONPath(data, path).([]interface{})
+	return items, ok
+}
+
+// lookupJSONPath follows a dot-separated path of object keys, such as
+// meta.next_cursor, through decoded JSON.
+func lookupJSONPath(data interface{}, path string) interface{} {
+	if path == "" {
+		return data
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		data = object[key]
+	}
+	return data
+}
+
+// httpResult turns a response into a tool result: JSON bodies are decoded,
+// other text is returned as a string and binary content is stored as a
+// file. At most maxBytes of the body are read; longer text is cut off and
+// marked truncated, and longer binary content is refused. The metadata
+// gains the response's content_type and the size read.
+func httpResult(ctx context.Context, store files.Store, resp *http.Response, maxBytes int64, metadata map[string]interface{}) *Result {
+	responseBody, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
 	if err != nil {
-		return &Result{Error: fmt.Sprintf("failed to read response: %v", err)}, nil
+		return &Result{Error: fmt.Sprintf("failed to read response: %v", err)}
+	}
+	truncated := int64(len(responseBody)) > maxBytes
+	if truncated {
+		responseBody = responseBody[:maxBytes]
+		metadata["truncated"] = true
 	}
 	
+	contentType := resp.Header.Get("Content-Type")
+	metadata["content_type"] = contentType
+	metadata["size"] = len(responseBody)
+	
 	if resp.StatusCode >= 400 {
 		return &Result{
 			Error: fmt.Sprintf("HTTP %d: %s", resp.StatusCode, string(responseBody)),
-		}, nil
+		}
+	}
+	
+	// Binary content such as PDFs and images would be mangled by a string
+	// conversion, so keep it in the file store and return a reference
+	if len(responseBody) > 0 && isBinaryContent(contentType, responseBody) {
+		if truncated {
+			return &Result{Error: fmt.Sprintf("response is larger than %d bytes", maxBytes), Metadata: metadata}
+		}
+		data, err := storeResponseFile(ctx, store, resp, responseBody)
+		if err != nil {
+			return &Result{Error: err.Error(), Metadata: metadata}
+		}
+		return &Result{Data: data, Metadata: metadata}
 	}
 	
 	var data interface{}
 	if len(responseBody) > 0 {
-		if err := json.Unmarshal(responseBody, &data); err != nil {
-			// If JSON parsing fails, return as string
-			data = string(responseBody)
-		}
+		data = decodeTextBody(contentType, responseBody, truncated)
 	}
 	
 	return &Result{
-		Data: data,
-		Metadata: map[string]interface{}{
-			"status_code": resp.StatusCode,
-			"headers":     resp.Header,
-			"url":         url,
-			"method":      method,
-		},
+		Data:     data,
+		Metadata: metadata,
+	}
+}
+
+// decodeTextBody decodes JSON bodies, and bodies without a Content-Type
+// that parse as JSON, and returns other text as a UTF-8 string.
+func decodeTextBody(contentType string, body []byte, truncated bool) interface{} {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	isJSON := mediaType == "" || mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+	if isJSON && !truncated {
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err == nil {
+			return data
+		}
+	}
+	
+	if contentType != "" {
+		if reader, err := charset.NewReader(bytes.NewReader(body), contentType); err == nil {
+			if text, err := io.ReadAll(reader); err == nil {
+				return string(text)
+			}
+		}
+	}
+	return string(body)
+}
+
+// isBinaryContent reports whether a response body is binary, judging by its
+// Content-Type or, when there is none, by whether it is valid UTF-8.
+func isBinaryContent(contentType string, body []byte) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "" {
+		return !utf8.Valid(body)
+	}
+	
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return false
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/javascript",
+		"application/x-www-form-urlencoded", "application/x-ndjson", "application/yaml":
+		return false
+	case "application/octet-stream":
+		return !utf8.Valid(body)
+	}
+	return true
+}
+
+// storeResponseFile saves a binary response and describes it with a file
+// reference. Without a store the content is returned base64 encoded.
+func storeResponseFile(ctx context.Context, store files.Store, resp *http.Response, body []byte) (map[string]interface{}, error) {
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" {
+		mimeType = http.DetectContentType(body)
+	}
+	
+	name := path.Base(resp.Request.URL.Path)
+	if _, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = path.Base(params["filename"])
+	}
+	if name == "/" || name == "." {
+		name = ""
+	}
+	
+	if store == nil {
+		return map[string]interface{}{
+			"type":      "binary",
+			"mime_type": mimeType,
+			"size":      len(body),
+			"base64":    base64.StdEncoding.EncodeToString(body),
+		}, nil
+	}
+	
+	file, err := store.Put(ctx, name, mimeType, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store response file: %w", err)
+	}
+	
+	return map[string]interface{}{
+		"type":      "file",
+		"file_id":   file.ID,
+		"name":      file.Name,
+		"mime_type": file.MimeType,
+		"size":      file.Size,
 	}, nil
 }
 
 func (t *HTTPTool) Close() error {
 	return nil
+}
+
+// applyRequestAuth adds the configured credentials and any header_* entries
+// from the tool config to an outgoing request.
+func applyRequestAuth(req *http.Request, config *Config) {
+	if config.Auth != nil {
+		switch config.Auth.Type {
+		case "bearer":
+			req.Header.Set("Authorization", "Bearer "+config.Auth.Token)
+		case "api_key":
+			req.Header.Set("X-API-Key", config.Auth.APIKey)
+		case "basic":
+			req.SetBasicAuth(config.Auth.APIKey, config.Auth.Secret)
+		}
+	}
+	
+	// Add custom headers from config
+	for key, value := range config.Config {
+		if strings.HasPrefix(key, "header_") {
+			headerName := strings.TrimPrefix(key, "header_")
+			req.Header.Set(headerName, value)
+		}
+	}
 }
\ No newline at end of file
@@ -0,0 +1,589 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultKubeListLimit = 50
+	maxKubeListLimit     = 500
+	defaultKubeTailLines = 100
+	maxKubeTailLines     = 5000
+	// maxKubeLogBytes bounds the log output the API server sends
+	maxKubeLogBytes = 256 << 10
+	// maxKubeResponse bounds the API response that is read
+	maxKubeResponse = 8 << 20
+)
+
+// kubeResource is where a resource lives in the Kubernetes API.
+type kubeResource struct {
+	apiPath    string
+	namespaced bool
+	// scalable resources have a scale subresource
+	scalable bool
+	// restartable resources roll their pods when the template changes
+	restartable bool
+}
+
+var kubeResources = map[string]kubeResource{
+	"pods":                     {apiPath: "api/v1", namespaced: true},
+	"services":                 {apiPath: "api/v1", namespaced: true},
+	"endpoints":                {apiPath: "api/v1", namespaced: true},
+	"configmaps":               {apiPath: "api/v1", namespaced: true},
+	"secrets":                  {apiPath: "api/v1", namespaced: true},
+	"events":                   {apiPath: "api/v1", namespaced: true},
+	"serviceaccounts":          {apiPath: "api/v1", namespaced: true},
+	"persistentvolumeclaims":   {apiPath: "api/v1", namespaced: true},
+	"persistentvolumes":        {apiPath: "api/v1"},
+	"nodes":                    {apiPath: "api/v1"},
+	"namespaces":               {apiPath: "api/v1"},
+	"deployments":              {apiPath: "apis/apps/v1", namespaced: true, scalable: true, restartable: true},
+	"statefulsets":             {apiPath: "apis/apps/v1", namespaced: true, scalable: true, restartable: true},
+	"daemonsets":               {apiPath: "apis/apps/v1", namespaced: true, restartable: true},
+	"replicasets":              {apiPath: "apis/apps/v1", namespaced: true, scalable: true},
+	"jobs":                     {apiPath: "apis/batch/v1", namespaced: true},
+	"cronjobs":                 {apiPath: "apis/batch/v1", namespaced: true},
+	"ingresses":                {apiPath: "apis/networking.k8s.io/v1", namespaced: true},
+	"networkpolicies":          {apiPath: "apis/networking.k8s.io/v1", namespaced: true},
+	"horizontalpodautoscalers": {apiPath: "apis/autoscaling/v2", namespaced: true},
+}
+
+// kubeResourceAliases maps the singular names and kubectl short names to
+// the plural resource.
+var kubeResourceAliases = map[string]string{
+	"pod":                     "pods",
+	"po":                      "pods",
+	"service":                 "services",
+	"svc":                     "services",
+	"endpoint":                "endpoints",
+	"ep":                      "endpoints",
+	"configmap":               "configmaps",
+	"cm":                      "configmaps",
+	"secret":                  "secrets",
+	"event":                   "events",
+	"ev":                      "events",
+	"serviceaccount":          "serviceaccounts",
+	"sa":                      "serviceaccounts",
+	"persistentvolumeclaim":   "persistentvolumeclaims",
+	"pvc":                     "persistentvolumeclaims",
+	"persistentvolume":        "persistentvolumes",
+	"pv":                      "persistentvolumes",
+	"node":                    "nodes",
+	"no":                      "nodes",
+	"namespace":               "namespaces",
+	"ns":                      "namespaces",
+	"deployment":              "deployments",
+	"deploy":                  "deployments",
+	"statefulset":             "statefulsets",
+	"sts":                     "statefulsets",
+	"daemonset":               "daemonsets",
+	"ds":                      "daemonsets",
+	"replicaset":              "replicasets",
+	"rs":                      "replicasets",
+	"job":                     "jobs",
+	"cronjob":                 "cronjobs",
+	"cj":                      "cronjobs",
+	"ingress":                 "ingresses",
+	"ing":                     "ingresses",
+	"networkpolicy":           "networkpolicies",
+	"netpol":                  "networkpolicies",
+	"horizontalpodautoscaler": "horizontalpodautoscalers",
+	"hpa":                     "horizontalpodautoscalers",
+}
+
+// kubeVerbs are the operations the tool offers. Writes are only allowed
+// when read_only is "false".
+var kubeVerbs = map[string]bool{
+	"get":     false,
+	"list":    false,
+	"logs":    false,
+	"delete":  true,
+	"scale":   true,
+	"restart": true,
+}
+
+var kubeNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// KubernetesTool reads from, and optionally changes, a Kubernetes cluster
+// through the API server. Like an RBAC role, the verbs, namespaces and
+// resources config entries list what the model may do; the defaults allow
+// get, list and logs on everything but secrets in the connection's
+// namespace. The credentials' own RBAC permissions still apply.
+type KubernetesTool struct {
+	config     *Config
+	conn       *kubeConnection
+	verbs      []string
+	namespaces []string
+	resources  []string
+	readOnly   bool
+}
+
+func NewKubernetesTool(config *Config) (*KubernetesTool, error) {
+	readOnly := true
+	if value := config.Config["read_only"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read_only: %w", err)
+		}
+		readOnly = parsed
+	}
+	
+	verbs := splitList(configOrDefault(config.Config, "verbs", "get,list,logs"))
+	for _, verb := range verbs {
+		write, ok := kubeVerbs[verb]
+		if !ok {
+			return nil, fmt.Errorf("unknown kubernetes verb %q", verb)
+		}
+		if write && readOnly {
+			return nil, fmt.Errorf("verb %s requires read_only to be false", verb)
+		}
+	}
+	
+	var resources []string
+	if value := config.Config["resources"]; value != "" {
+		for _, name := range splitList(value) {
+			resource, ok := resolveKubeResource(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown kubernetes resource %q", name)
+			}
+			resources = append(resources, resource)
+		}
+	} else {
+		// Secrets hold credentials, so they must be listed explicitly
+		for name := range kubeResources {
+			if name != "secrets" {
+				resources = append(resources, name)
+			}
+		}
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	conn, err := newKubeConnection(config.Config, timeout)
+	if err != nil {
+		return nil, err
+	}
+	
+	namespaces := splitList(config.Config["namespaces"])
+	if len(namespaces) == 0 {
+		namespaces = []string{conn.namespace}
+	}
+	
+	return &KubernetesTool{
+		config:     config,
+		conn:       conn,
+		verbs:      verbs,
+		namespaces: namespaces,
+		resources:  resources,
+		readOnly:   readOnly,
+	}, nil
+}
+
+func resolveKubeResource(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := kubeResourceAliases[name]; ok {
+		name = alias
+	}
+	_, ok := kubeResources[name]
+	return name, ok
+}
+
+func (t *KubernetesTool) Name() string {
+	return t.config.Name
+}
+
+func (t *KubernetesTool) Type() string {
+	return "kubernetes"
+}
+
+func (t *KubernetesTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		action := "Inspect"
+		if !t.readOnly {
+			action = "Inspect and manage"
+		}
+		description = fmt.Sprintf("%s a Kubernetes cluster. Allowed verbs: %s. Allowed namespaces: %s.",
+			action, strings.Join(t.verbs, ", "), strings.Join(t.namespaces, ", "))
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"verb": map[string]interface{}{
+					"type":        "string",
+					"enum":        t.verbs,
+					"description": "get one object, list objects, read pod logs, or change a workload",
+				},
+				"resource": map[string]interface{}{
+					"type":        "string",
+					"description": "Resource type, such as pods, deployments or events",
+				},
+				"name": map[string]interface{}{
+					"type":        "string",
+					"description": "Object name; required for every verb but list",
+				},
+				"namespace": map[string]interface{}{
+					"type":        "string",
+					"description": fmt.Sprintf("Namespace (default: %s)", t.conn.namespace),
+				},
+				"label_selector": map[string]interface{}{
+					"type":        "string",
+					"description": "For list, a label selector such as app=web",
+				},
+				"field_selector": map[string]interface{}{
+					"type":        "string",
+					"description": "For list, a field selector such as status.phase=Failed",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("For list, how many objects to return, at most %d", maxKubeListLimit),
+				},
+				"continue": map[string]interface{}{
+					"type":        "string",
+					"description": "For list, the continue token from the previous page",
+				},
+				"container": map[string]interface{}{
+					"type":        "string",
+					"description": "For logs, the container in a multi-container pod",
+				},
+				"tail_lines": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("For logs, how many lines from the end, at most %d", maxKubeTailLines),
+				},
+				"previous": map[string]interface{}{
+					"type":        "boolean",
+					"description": "For logs, read the previous, crashed container",
+				},
+				"replicas": map[string]interface{}{
+					"type":        "integer",
+					"description": "For scale, the new replica count",
+				},
+			},
+			"required": []string{"verb", "resource"},
+		},
+	}
+}
+
+func (t *KubernetesTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	verb, _ := args["verb"].(string)
+	if !containsString(t.verbs, verb) {
+		return &Result{Error: fmt.Sprintf("verb %q is not allowed; allowed verbs: %s", verb, strings.Join(t.verbs, ", "))}, nil
+	}
+	
+	resourceArg, _ := args["resource"].(string)
+	resourceName, ok := resolveKubeResource(resourceArg)
+	if !ok {
+		return &Result{Error: fmt.Sprintf("unknown resource %q", resourceArg)}, nil
+	}
+	if !containsString(t.resources, resourceName) {
+		return &Result{Error: fmt.Sprintf("resource %s is not allowed", resourceName)}, nil
+	}
+	resource := kubeResources[resourceName]
+	
+	name, _ := args["name"].(string)
+	if name == "" && verb != "list" {
+		return &Result{Error: fmt.Sprintf("name is required for %s", verb)}, nil
+	}
+	if name != "" && !kubeNamePattern.MatchString(name) {
+		return &Result{Error: fmt.Sprintf("invalid name %q", name)}, nil
+	}
+	
+	namespace := ""
+	if resource.namespaced {
+		namespace, _ = args["namespace"].(string)
+		if namespace == "" {
+			namespace = t.conn.namespace
+		}
+		if namespace != "*" && !kubeNamePattern.MatchString(namespace) {
+			return &Result{Error: fmt.Sprintf("invalid namespace %q", namespace)}, nil
+		}
+		if namespace == "*" && verb != "list" {
+			return &Result{Error: "namespace * is only allowed with list"}, nil
+		}
+		if !containsString(t.namespaces, namespace) && !containsString(t.namespaces, "*") {
+			return &Result{Error: fmt.Sprintf("namespace %s is not allowed; allowed namespaces: %s", namespace, strings.Join(t.namespaces, ", "))}, nil
+		}
+	} else if kubeVerbs[verb] {
+		return &Result{Error: fmt.Sprintf("%s is not supported for cluster-scoped resource %s", verb, resourceName)}, nil
+	}
+	
+	start := time.Now()
+	var result *Result
+	switch verb {
+	case "get":
+		result = t.get(ctx, resource, resourceName, namespace, name)
+	case "list":
+		result = t.list(ctx, resource, resourceName, namespace, args)
+	case "logs":
+		result = t.logs(ctx, resourceName, namespace, name, args)
+	case "delete":
+		result = t.delete(ctx, resource, resourceName, namespace, name)
+	case "scale":
+		result = t.scale(ctx, resource, resourceName, namespace, name, args)
+	case "restart":
+		result = t.restart(ctx, resource, resourceName, namespace, name)
+	}
+	
+	if result.Error == "" {
+		result.Metadata = map[string]interface{}{
+			"verb":        verb,
+			"resource":    resourceName,
+			"namespace":   namespace,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}
+	}
+	return result, nil
+}
+
+func (t *KubernetesTool) get(ctx context.Context, resource kubeResource, resourceName, namespace, name string) *Result {
+	body, err := t.do(ctx, http.MethodGet, kubePath(resource, resourceName, namespace, name), nil, nil, "")
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	var object map[string]interface{}
+	if err := json.Unmarshal(body, &object); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to parse response: %v", err)}
+	}
+	return &Result{Data: trimKubeObject(object)}
+}
+
+func (t *KubernetesTool) list(ctx context.Context, resource kubeResource, resourceName, namespace string, args map[string]interface{}) *Result {
+	limit, err := numberArg(args, "limit", defaultKubeListLimit)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	if limit < 1 || limit > maxKubeListLimit || limit != math.Trunc(limit) {
+		return &Result{Error: fmt.Sprintf("limit must be a whole number from 1 to %d", maxKubeListLimit)}
+	}
+	
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(int(limit)))
+	for arg, param := range map[string]string{
+		"label_selector": "labelSelector",
+		"field_selector": "fieldSelector",
+		"continue":       "continue",
+	} {
+		if value, _ := args[arg].(string); value != "" {
+			query.Set(param, value)
+		}
+	}
+	
+	if namespace == "*" {
+		namespace = ""
+	}
+	body, err := t.do(ctx, http.MethodGet, kubePath(resource, resourceName, namespace, ""), query, nil, "")
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	var list struct {
+		Metadata struct {
+			Continue string `json:"continue"`
+		} `json:"metadata"`
+		Items []map[string]interface{} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to parse response: %v", err)}
+	}
+	for _, item := range list.Items {
+		trimKubeObject(item)
+	}
+	
+	data := map[string]interface{}{
+		"items": list.Items,
+		"count": len(list.Items),
+	}
+	if list.Metadata.Continue != "" {
+		data["continue"] = list.Metadata.Continue
+	}
+	return &Result{Data: data}
+}
+
+func (t *KubernetesTool) logs(ctx context.Context, resourceName, namespace, name string, args map[string]interface{}) *Result {
+	if resourceName != "pods" {
+		return &Result{Error: "logs is only supported for pods"}
+	}
+	
+	tailLines, err := numberArg(args, "tail_lines", defaultKubeTailLines)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	if tailLines < 1 || tailLines > maxKubeTailLines || tailLines != math.Trunc(tailLines) {
+		return &Result{Error: fmt.Sprintf("tail_lines must be a whole number from 1 to %d", maxKubeTailLines)}
+	}
+	
+	query := url.Values{}
+	query.Set("tailLines", strconv.Itoa(int(tailLines)))
+	query.Set("limitBytes", strconv.Itoa(maxKubeLogBytes))
+	if container, _ := args["container"].(string); container != "" {
+		query.Set("container", container)
+	}
+	if previous, _ := args["previous"].(bool); previous {
+		query.Set("previous", "true")
+	}
+	
+	path := kubePath(kubeResources["pods"], "pods", namespace, name) + "/log"
+	body, err := t.do(ctx, http.MethodGet, path, query, nil, "")
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	return &Result{Data: string(body)}
+}
+
+func (t *KubernetesTool) delete(ctx context.Context, resource kubeResource, resourceName, namespace, name string) *Result {
+	query := url.Values{}
+	query.Set("propagationPolicy", "Background")
+	if _, err := t.do(ctx, http.MethodDelete, kubePath(resource, resourceName, namespace, name), query, nil, ""); err != nil {
+		return &Result{Error: err.Error()}
+	}
+	return &Result{Data: map[string]interface{}{"deleted": resourceName + "/" + name}}
+}
+
+func (t *KubernetesTool) scale(ctx context.Context, resource kubeResource, resourceName, namespace, name string, args map[string]interface{}) *Result {
+	if !resource.scalable {
+		return &Result{Error: fmt.Sprintf("scale is not supported for %s", resourceName)}
+	}
+	if args["replicas"] == nil {
+		return &Result{Error: "replicas is required for scale"}
+	}
+	replicas, err := numberArg(args, "replicas", 0)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	if replicas < 0 || replicas != math.Trunc(replicas) {
+		return &Result{Error: "replicas must be a whole number of 0 or more"}
+	}
+	
+	patch := map[string]interface{}{"spec": map[string]interface{}{"replicas": int(replicas)}}
+	path := kubePath(resource, resourceName, namespace, name) + "/scale"
+	if _, err := t.do(ctx, http.MethodPatch, path, nil, patch, "application/merge-patch+json"); err != nil {
+		return &Result{Error: err.Error()}
+	}
+	return &Result{Data: map[string]interface{}{"scaled": resourceName + "/" + name, "replicas": int(replicas)}}
+}
+
+// restart rolls a workload's pods the way kubectl rollout restart does, by
+// stamping the pod template with the current time.
+func (t *KubernetesTool) restart(ctx context.Context, resource kubeResource, resourceName, namespace, name string) *Result {
+	if !resource.restartable {
+		return &Result{Error: fmt.Sprintf("restart is not supported for %s", resourceName)}
+	}
+	
+	restartedAt := time.Now().UTC().Format(time.RFC3339)
+	patch := map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{
+						"kubectl.kubernetes.io/restartedAt": restartedAt,
+					},
+				},
+			},
+		},
+	}
+	if _, err := t.do(ctx, http.MethodPatch, kubePath(resource, resourceName, namespace, name), nil, patch, "application/merge-patch+json"); err != nil {
+		return &Result{Error: err.Error()}
+	}
+	return &Result{Data: map[string]interface{}{"restarted": resourceName + "/" + name, "restarted_at": restartedAt}}
+}
+
+func (t *KubernetesTool) do(ctx context.Context, method, path string, query url.Values, payload interface{}, contentType string) ([]byte, error) {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(data)
+	}
+	
+	endpoint := t.conn.server + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "goagents/1.0")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	
+	token, err := t.conn.token()
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	
+	resp, err := t.conn.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxKubeResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("kubernetes API returned HTTP %d: %s", resp.StatusCode, searchErrorMessage(respBody))
+	}
+	return respBody, nil
+}
+
+func (t *KubernetesTool) Close() error {
+	t.conn.client.CloseIdleConnections()
+	return nil
+}
+
+func kubePath(resource kubeResource, resourceName, namespace, name string) string {
+	path := "/" + resource.apiPath
+	if namespace != "" {
+		path += "/namespaces/" + namespace
+	}
+	path += "/" + resourceName
+	if name != "" {
+		path += "/" + name
+	}
+	return path
+}
+
+// trimKubeObject drops bookkeeping fields that are large and of no use to
+// a model.
+func trimKubeObject(object map[string]interface{}) map[string]interface{} {
+	metadata, ok := object["metadata"].(map[string]interface{})
+	if !ok {
+		return object
+	}
+	delete(metadata, "managedFields")
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		delete(annotations, "kubectl.kubernetes.io/last-applied-configuration")
+		if len(annotations) == 0 {
+			delete(metadata, "annotations")
+		}
+	}
+	return object
+}
\ No newline at end of file
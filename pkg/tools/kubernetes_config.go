@@ -0,0 +1,247 @@
+package tools
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// kubeConnection is how the kubernetes tool reaches an API server: its
+// address, a client carrying the TLS settings, and a source of bearer
+// tokens.
+type kubeConnection struct {
+	server    string
+	client    *http.Client
+	namespace string
+	// token returns the bearer token for a request. In-cluster tokens are
+	// rotated by the kubelet, so they are read from disk every time.
+	token func() (string, error)
+}
+
+// kubeconfig is the subset of a kubeconfig file the tool understands.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string                 `yaml:"token"`
+			TokenFile             string                 `yaml:"tokenFile"`
+			ClientCertificate     string                 `yaml:"client-certificate"`
+			ClientCertificateData string                 `yaml:"client-certificate-data"`
+			ClientKey             string                 `yaml:"client-key"`
+			ClientKeyData         string                 `yaml:"client-key-data"`
+			Exec                  map[string]interface{} `yaml:"exec"`
+			AuthProvider          map[string]interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			User      string `yaml:"user"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// newKubeConnection uses the kubeconfig config entry if set, the pod's
+// service account when running in a cluster, and otherwise $KUBECONFIG or
+// ~/.kube/config.
+func newKubeConnection(config map[string]string, timeout time.Duration) (*kubeConnection, error) {
+	path := config["kubeconfig"]
+	if path == "" && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return inClusterConnection(timeout)
+	}
+	if path == "" {
+		path = defaultKubeconfigPath()
+	}
+	return kubeconfigConnection(path, config["context"], timeout)
+}
+
+func defaultKubeconfigPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0]
+	}
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".kube", "config")
+}
+
+func inClusterConnection(timeout time.Duration) (*kubeConnection, error) {
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("service account CA contains no certificates")
+	}
+	
+	namespace := "default"
+	if data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace")); err == nil {
+		namespace = strings.TrimSpace(string(data))
+	}
+	
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if port == "" {
+		port = "443"
+	}
+	
+	return &kubeConnection{
+		server:    "https://" + net.JoinHostPort(host, port),
+		client:    kubeHTTPClient(&tls.Config{RootCAs: pool}, timeout),
+		namespace: namespace,
+		token:     tokenFromFile(filepath.Join(serviceAccountDir, "token")),
+	}, nil
+}
+
+func kubeconfigConnection(path, contextName string, timeout time.Duration) (*kubeConnection, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+	var cfg kubeconfig
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	// Relative file references are relative to the kubeconfig
+	dir := filepath.Dir(path)
+	resolve := func(file string) string {
+		if file == "" || filepath.IsAbs(file) {
+			return file
+		}
+		return filepath.Join(dir, file)
+	}
+	
+	if contextName == "" {
+		contextName = cfg.CurrentContext
+	}
+	var clusterName, userName, namespace string
+	found := false
+	for _, c := range cfg.Contexts {
+		if c.Name == contextName {
+			clusterName, userName, namespace = c.Context.Cluster, c.Context.User, c.Context.Namespace
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("context %q not found in kubeconfig", contextName)
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	
+	conn := &kubeConnection{namespace: namespace}
+	tlsConfig := &tls.Config{}
+	
+	found = false
+	for _, c := range cfg.Clusters {
+		if c.Name != clusterName {
+			continue
+		}
+		found = true
+		conn.server = strings.TrimSuffix(c.Cluster.Server, "/")
+		tlsConfig.InsecureSkipVerify = c.Cluster.InsecureSkipTLSVerify
+		tlsConfig.ServerName = c.Cluster.TLSServerName
+		ca, err := kubeconfigData(c.Cluster.CertificateAuthorityData, resolve(c.Cluster.CertificateAuthority))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load certificate authority: %w", err)
+		}
+		if ca != nil {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("certificate authority of cluster %s contains no certificates", clusterName)
+			}
+			tlsConfig.RootCAs = pool
+		}
+	}
+	if !found || conn.server == "" {
+		return nil, fmt.Errorf("cluster %q not found in kubeconfig", clusterName)
+	}
+	
+	conn.token = func() (string, error) { return "", nil }
+	for _, u := range cfg.Users {
+		if u.Name != userName {
+			continue
+		}
+		if u.User.Exec != nil || u.User.AuthProvider != nil {
+			return nil, fmt.Errorf("user %s uses a credential plugin, which is not supported; use a token or client certificate", userName)
+		}
+		
+		switch {
+		case u.User.Token != "":
+			token := u.User.Token
+			conn.token = func() (string, error) { return token, nil }
+		case u.User.TokenFile != "":
+			conn.token = tokenFromFile(resolve(u.User.TokenFile))
+		}
+		
+		cert, err := kubeconfigData(u.User.ClientCertificateData, resolve(u.User.ClientCertificate))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		key, err := kubeconfigData(u.User.ClientKeyData, resolve(u.User.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client key: %w", err)
+		}
+		if cert != nil && key != nil {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("invalid client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{pair}
+		}
+	}
+	
+	conn.client = kubeHTTPClient(tlsConfig, timeout)
+	return conn, nil
+}
+
+// kubeconfigData returns inline base64 data, or the contents of file.
+func kubeconfigData(data, file string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+func tokenFromFile(path string) func() (string, error) {
+	return func() (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read token: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	}
+}
+
+func kubeHTTPClient(tlsConfig *tls.Config, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
\ No newline at end of file
@@ -2,41 +2,115 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
+const (
+	// Streamable HTTP was introduced in 2025-03-26; stdio and legacy SSE
+	// servers are addressed with the older revision for compatibility.
+	mcpProtocolVersion     = "2024-11-05"
+	mcpHTTPProtocolVersion = "2025-03-26"
+	
+	mcpMaxRestartBackoff = 30 * time.Second
+	// A server that stays up this long is considered healthy again and its
+	// restart backoff is reset.
+	mcpStableUptime = time.Minute
+	// Messages sent on no call's behalf, such as cancellations and answers
+	// to the server's pings, are given up on after this long.
+	mcpNotifyTimeout = 5 * time.Second
+)
+
+var errMCPSessionClosed = errors.New("MCP session closed")
+
 type MCPTool struct {
 	config *Config
 	client *MCPClient
 }
 
+// MCPServerTool is a single tool discovered on an MCP server. It shares the
+// server connection of the MCPTool it was discovered from.
+type MCPServerTool struct {
+	server     *MCPTool
+	remoteName string
+	definition Definition
+}
+
+// MCPClient speaks JSON-RPC 2.0 to a Model Context Protocol server. The
+// server is connected lazily on first use and reconnected, with backoff,
+// after it exits unexpectedly.
 type MCPClient struct {
-	serverAddr string
-	timeout    time.Duration
+	serverAddr      string
+	timeout         time.Duration
+	protocolVersion string
+	dial            func(ctx context.Context) (mcpTransport, error)
+	onConnect       func(time.Duration)
+	
+	session   *mcpSession
+	restarts  int
+	nextStart time.Time
+	closed    bool
+	mu        sync.Mutex
+}
+
+// mcpTransport moves raw JSON-RPC messages between client and server.
+// Send may be called concurrently and gives up once ctx is done.
+type mcpTransport interface {
+	Send(ctx context.Context, message []byte) error
+	Receive() ([]byte, error)
+	Close() error
 }
 
 type MCPRequest struct {
-	ID     string                 `json:"id"`
-	Method string                 `json:"method"`
-	Params map[string]interface{} `json:"params"`
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
 }
 
 type MCPResponse struct {
-	ID     string      `json:"id"`
-	Result interface{} `json:"result,omitempty"`
-	Error  *MCPError   `json:"error,omitempty"`
+	JSONRPC string          `json:"jsonrpc"`
+	ID      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
 }
 
 type MCPError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
 	Data    interface{} `json:"data,omitempty"`
 }
 
+// mcpMessage is the union of requests, notifications and responses read
+// from the server.
+type mcpMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *MCPError       `json:"error,omitempty"`
+}
+
+type mcpSession struct {
+	transport  mcpTransport
+	nextID     int64
+	pending    map[int64]chan *MCPResponse
+	done       chan struct{}
+	err        error
+	startedAt  time.Time
+	serverInfo map[string]interface{}
+	mu         sync.Mutex
+}
+
 func NewMCPTool(config *Config) (*MCPTool, error) {
-	if config.Server == "" {
-		return nil, fmt.Errorf("server is required for MCP tool")
+	if config.Server == "" && len(config.Command) == 0 && config.URL == "" {
+		return nil, fmt.Errorf("server, command or url is required for MCP tool")
 	}
 	
 	timeout := 30 * time.Second
@@ -45,8 +119,51 @@ func NewMCPTool(config *Config) (*MCPTool, error) {
 	}
 	
 	client := &MCPClient{
-		serverAddr: config.Server,
-		timeout:    timeout,
+		serverAddr:      config.Server,
+		timeout:         timeout,
+		protocolVersion: mcpProtocolVersion,
+	}
+	
+	transport := config.Transport
+	if transport == "" {
+		transport = "stdio"
+		if config.URL != "" {
+			transport = "http"
+		}
+	}
+	
+	switch transport {
+	case "stdio":
+		command := config.Command
+		if len(command) == 0 {
+			command = []string{config.Server}
+		}
+		if client.serverAddr == "" {
+			client.serverAddr = command[0]
+		}
+		args := append(append([]string(nil), command[1:]...), config.Args...)
+		client.dial = func(ctx context.Context) (mcpTransport, error) {
+			return newStdioTransport(command[0], args, config.Env)
+		}
+	case "http", "streamable-http":
+		if config.URL == "" {
+			return nil, fmt.Errorf("url is required for MCP %s transport", transport)
+		}
+		client.serverAddr = config.URL
+		client.protocolVersion = mcpHTTPProtocolVersion
+		client.dial = func(ctx context.Context) (mcpTransport, error) {
+			return newStreamableHTTPTransport(config.URL, config), nil
+		}
+	case "sse":
+		if config.URL == "" {
+			return nil, fmt.Errorf("url is required for MCP sse transport")
+		}
+		client.serverAddr = config.URL
+		client.dial = func(ctx context.Context) (mcpTransport, error) {
+			return newSSETransport(ctx, config.URL, config)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported MCP transport: %s", config.Transport)
 	}
 	
 	return &MCPTool{
@@ -63,133 +180,570 @@ func (t *MCPTool) Type() string {
 	return "mcp"
 }
 
+// Execute calls a tool on the MCP server. args["name"] selects the server
+// tool and args["arguments"] holds its input; any other keys are passed as
+// arguments directly. args["method"] may be set to "tools/list" to list the
+// server's tools instead.
 func (t *MCPTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
-	method := "call_tool"
+	method, params := t.request(args)
+	return t.call(ctx, method, params), nil
+}
+
+// DryRun describes the JSON-RPC request Execute would send for args.
+func (t *MCPTool) DryRun(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	method, params := t.request(args)
+	return &Result{
+		Data: map[string]interface{}{
+			"server": t.client.serverAddr,
+			"method": method,
+			"params": params,
+		},
+	}, nil
+}
+
+// request reads the JSON-RPC method and params of a call from its
+// arguments.
+func (t *MCPTool) request(args map[string]interface{}) (string, interface{}) {
+	method := "tools/call"
 	if m, ok := args["method"].(string); ok {
 		method = m
 	}
 	
-	// Prepare MCP request
-	req := &MCPRequest{
-		ID:     fmt.Sprintf("req-%d", time.Now().UnixNano()),
-		Method: method,
-		Params: args,
+	switch method {
+	case "list_tools", "tools/list":
+		return "tools/list", nil
+	case "call_tool", "tools/call":
+		return "tools/call", t.callToolParams(args)
+	default:
+		return method, args["params"]
 	}
-	
-	resp, err := t.client.Call(ctx, req)
+}
+
+// call sends a request to the server and converts the reply into a Result.
+func (t *MCPTool) call(ctx context.Context, method string, params interface{}) *Result {
+	resp, err := t.client.Call(ctx, method, params)
 	if err != nil {
-		return &Result{Error: fmt.Sprintf("MCP call failed: %v", err)}, nil
+		return &Result{Error: fmt.Sprintf("MCP call failed: %v", err)}
 	}
 	
 	if resp.Error != nil {
 		return &Result{
 			Error: fmt.Sprintf("MCP error %d: %s", resp.Error.Code, resp.Error.Message),
-		}, nil
+		}
 	}
 	
-	return &Result{
-		Data: resp.Result,
+	var data map[string]interface{}
+	if err := json.Unmarshal(resp.Result, &data); err != nil {
+		return &Result{Error: fmt.Sprintf("invalid MCP result: %v", err)}
+	}
+	
+	result := &Result{
+		Data: data,
 		Metadata: map[string]interface{}{
-			"server": t.config.Server,
+			"server": t.client.serverAddr,
 			"method": method,
 			"id":     resp.ID,
 		},
-	}, nil
+	}
+	
+	// Tool-level failures are reported in the result rather than as
+	// JSON-RPC errors
+	if isError, _ := data["isError"].(bool); isError {
+		result.Error = mcpContentText(data)
+		if result.Error == "" {
+			result.Error = "MCP tool reported an error"
+		}
+	}
+	
+	return result
+}
+
+func (t *MCPTool) callToolParams(args map[string]interface{}) map[string]interface{} {
+	name, _ := args["name"].(string)
+	if name == "" {
+		name = t.config.Config["tool"]
+	}
+	
+	arguments, ok := args["arguments"].(map[string]interface{})
+	if !ok {
+		arguments = make(map[string]interface{})
+		for key, value := range args {
+			if key != "name" && key != "method" {
+				arguments[key] = value
+			}
+		}
+	}
+	
+	return map[string]interface{}{
+		"name":      name,
+		"arguments": arguments,
+	}
 }
 
 func (t *MCPTool) Close() error {
-	return nil
+	return t.client.Close()
 }
 
-func (c *MCPClient) Call(ctx context.Context, req *MCPRequest) (*MCPResponse, error) {
-	// For demo purposes, simulate MCP server communication
-	// In a real implementation, this would use the MCP protocol over stdio, HTTP, or WebSocket
-	
-	time.Sleep(100 * time.Millisecond) // Simulate network latency
-	
-	// Mock response based on method
-	var result interface{}
-	switch req.Method {
-	case "list_tools":
-		result = map[string]interface{}{
-			"tools": []map[string]interface{}{
-				{
-					"name":        "file_read",
-					"description": "Read a file from the filesystem",
-					"parameters": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"path": map[string]interface{}{
-								"type":        "string",
-								"description": "Path to the file to read",
-							},
-						},
-						"required": []string{"path"},
-					},
-				},
-				{
-					"name":        "web_search",
-					"description": "Search the web for information",
-					"parameters": map[string]interface{}{
-						"type": "object",
-						"properties": map[string]interface{}{
-							"query": map[string]interface{}{
-								"type":        "string",
-								"description": "Search query",
-							},
-						},
-						"required": []string{"query"},
-					},
+// Connect starts the server and performs the initialize handshake, unless
+// it is connected already.
+func (t *MCPTool) Connect(ctx context.Context) error {
+	return t.client.Connect(ctx)
+}
+
+// Disconnect stops the server until the tool is called again.
+func (t *MCPTool) Disconnect() error {
+	return t.client.Disconnect()
+}
+
+// OnConnect registers fn to be called with the time taken to start or
+// reconnect the server, including the initialize handshake.
+func (t *MCPTool) OnConnect(fn func(time.Duration)) {
+	t.client.mu.Lock()
+	defer t.client.mu.Unlock()
+	t.client.onConnect = fn
+}
+
+// Discover connects to the server and lists its tools, following pagination
+// cursors. Each tool can be registered on its own so models see the server's
+// real tool names and input schemas.
+func (t *MCPTool) Discover(ctx context.Context) ([]*MCPServerTool, error) {
+	var discovered []*MCPServerTool
+	cursor := ""
+	
+	for {
+		var params interface{}
+		if cursor != "" {
+			params = map[string]interface{}{"cursor": cursor}
+		}
+		
+		resp, err := t.client.Call(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Error != nil {
+			return nil, fmt.Errorf("MCP error %d: %s", resp.Error.Code, resp.Error.Message)
+		}
+		
+		var result struct {
+			Tools []struct {
+				Name        string                 `json:"name"`
+				Description string                 `json:"description"`
+				InputSchema map[string]interface{} `json:"inputSchema"`
+			} `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(resp.Result, &result); err != nil {
+			return nil, fmt.Errorf("invalid tools/list result: %w", err)
+		}
+		
+		for _, tool := range result.Tools {
+			discovered = append(discovered, &MCPServerTool{
+				server:     t,
+				remoteName: tool.Name,
+				definition: Definition{
+					Name:        tool.Name,
+					Description: tool.Description,
+					Parameters:  tool.InputSchema,
 				},
+			})
+		}
+		
+		if result.NextCursor == "" || result.NextCursor == cursor {
+			return discovered, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+func (t *MCPServerTool) Name() string {
+	return t.definition.Name
+}
+
+func (t *MCPServerTool) Type() string {
+	return "mcp"
+}
+
+func (t *MCPServerTool) Definition() Definition {
+	return t.definition
+}
+
+// Execute calls the tool on its server with args as the tool's arguments.
+func (t *MCPServerTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	
+	return t.server.call(ctx, "tools/call", map[string]interface{}{
+		"name":      t.remoteName,
+		"arguments": args,
+	}), nil
+}
+
+// DryRun describes the call Execute would make on the server for args.
+func (t *MCPServerTool) DryRun(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	if args == nil {
+		args = make(map[string]interface{})
+	}
+	
+	return &Result{
+		Data: map[string]interface{}{
+			"server": t.server.client.serverAddr,
+			"method": "tools/call",
+			"params": map[string]interface{}{
+				"name":      t.remoteName,
+				"arguments": args,
 			},
+		},
+	}, nil
+}
+
+// Close closes the shared server connection. Closing it again from a sibling
+// tool is a no-op.
+func (t *MCPServerTool) Close() error {
+	return t.server.Close()
+}
+
+// Connect connects the shared server. Connecting it again from a sibling
+// tool is a no-op.
+func (t *MCPServerTool) Connect(ctx context.Context) error {
+	return t.server.Connect(ctx)
+}
+
+// Disconnect stops the shared server until one of its tools is called
+// again.
+func (t *MCPServerTool) Disconnect() error {
+	return t.server.Disconnect()
+}
+
+// mcpContentText joins the text items of an MCP content list.
+func mcpContentText(result map[string]interface{}) string {
+	items, _ := result["content"].([]interface{})
+	
+	var texts []string
+	for _, item := range items {
+		content, ok := item.(map[string]interface{})
+		if !ok || content["type"] != "text" {
+			continue
 		}
-	case "call_tool":
-		toolName, _ := req.Params["name"].(string)
-		switch toolName {
-		case "file_read":
-			path, _ := req.Params["path"].(string)
-			result = map[string]interface{}{
-				"content": fmt.Sprintf("Mock file content for: %s", path),
-				"path":    path,
-			}
-		case "web_search":
-			query, _ := req.Params["query"].(string)
-			result = map[string]interface{}{
-				"results": []map[string]interface{}{
-					{
-						"title": "Mock Search Result 1",
-						"url":   "https://example.com/1",
-						"snippet": fmt.Sprintf("Mock result for query: %s", query),
-					},
-					{
-						"title": "Mock Search Result 2",
-						"url":   "https://example.com/2",
-						"snippet": fmt.Sprintf("Another mock result for: %s", query),
-					},
-				},
-				"query": query,
-			}
+		if text, ok := content["text"].(string); ok {
+			texts = append(texts, text)
+		}
+	}
+	
+	return strings.Join(texts, "\n")
+}
+
+func (c *MCPClient) Call(ctx context.Context, method string, params interface{}) (*MCPResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	
+	session, err := c.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	
+	return session.call(ctx, method, params)
+}
+
+// ServerInfo returns the serverInfo reported during initialization, or nil
+// if the server has not been connected yet.
+func (c *MCPClient) ServerInfo() map[string]interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if c.session == nil {
+		return nil
+	}
+	return c.session.serverInfo
+}
+
+func (c *MCPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	c.closed = true
+	if c.session == nil {
+		return nil
+	}
+	
+	err := c.session.close()
+	c.session = nil
+	return err
+}
+
+// Connect opens a session ahead of the first call.
+func (c *MCPClient) Connect(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	
+	_, err := c.connect(ctx)
+	return err
+}
+
+// Disconnect ends the session without closing the client: the next call
+// starts a new one.
+func (c *MCPClient) Disconnect() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if c.session == nil {
+		return nil
+	}
+	err := c.session.close()
+	c.session = nil
+	return err
+}
+
+// connect returns the live session, starting the server and performing the
+// initialize handshake if needed.
+func (c *MCPClient) connect(ctx context.Context) (*mcpSession, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	
+	if c.closed {
+		return nil, errMCPSessionClosed
+	}
+	
+	if c.session != nil {
+		select {
+		case <-c.session.done:
+			c.recordExit(c.session)
+			c.session = nil
 		default:
-			return &MCPResponse{
-				ID: req.ID,
-				Error: &MCPError{
-					Code:    -32601,
-					Message: fmt.Sprintf("Tool not found: %s", toolName),
-				},
-			}, nil
+			return c.session, nil
 		}
-	default:
-		return &MCPResponse{
-			ID: req.ID,
-			Error: &MCPError{
-				Code:    -32601,
-				Message: fmt.Sprintf("Method not found: %s", req.Method),
-			},
-		}, nil
 	}
 	
-	return &MCPResponse{
-		ID:     req.ID,
-		Result: result,
-	}, nil
+	if wait := time.Until(c.nextStart); wait > 0 {
+		return nil, fmt.Errorf("MCP server %s is restarting, retry in %s", c.serverAddr, wait.Round(time.Second))
+	}
+	
+	start := time.Now()
+	transport, err := c.dial(ctx)
+	if err != nil {
+		c.scheduleRestart()
+		return nil, fmt.Errorf("failed to start MCP server: %w", err)
+	}
+	
+	session := newMCPSession(transport)
+	if err := session.initialize(ctx, c.protocolVersion); err != nil {
+		session.close()
+		c.scheduleRestart()
+		return nil, fmt.Errorf("MCP initialize failed: %w", err)
+	}
+	
+	c.session = session
+	if c.onConnect != nil {
+		c.onConnect(time.Since(start))
+	}
+	return session, nil
+}
+
+// recordExit updates the restart backoff after a session ended on its own.
+func (c *MCPClient) recordExit(session *mcpSession) {
+	session.close()
+	if time.Since(session.startedAt) >= mcpStableUptime {
+		c.restarts = 0
+	}
+	c.scheduleRestart()
+}
+
+func (c *MCPClient) scheduleRestart() {
+	backoff := time.Duration(0)
+	if c.restarts > 0 {
+		backoff = time.Second << (c.restarts - 1)
+		if backoff > mcpMaxRestartBackoff {
+			backoff = mcpMaxRestartBackoff
+		}
+	}
+	c.restarts++
+	c.nextStart = time.Now().Add(backoff)
+}
+
+func newMCPSession(transport mcpTransport) *mcpSession {
+	session := &mcpSession{
+		transport: transport,
+		pending:   make(map[int64]chan *MCPResponse),
+		done:      make(chan struct{}),
+		startedAt: time.Now(),
+	}
+	
+	go session.readLoop()
+	return session
+}
+
+func (s *mcpSession) initialize(ctx context.Context, protocolVersion string) error {
+	resp, err := s.call(ctx, "initialize", map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "goagents",
+			"version": "1.0.0",
+		},
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("%d: %s", resp.Error.Code, resp.Error.Message)
+	}
+	
+	var result struct {
+		ServerInfo map[string]interface{} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(resp.Result, &result); err != nil {
+		return fmt.Errorf("invalid initialize result: %w", err)
+	}
+	s.serverInfo = result.ServerInfo
+	
+	return s.notify(ctx, "notifications/initialized", nil)
+}
+
+func (s *mcpSession) call(ctx context.Context, method string, params interface{}) (*MCPResponse, error) {
+	id := atomic.AddInt64(&s.nextID, 1)
+	responseCh := make(chan *MCPResponse, 1)
+	
+	s.mu.Lock()
+	if s.err != nil {
+		s.mu.Unlock()
+		return nil, s.err
+	}
+	s.pending[id] = responseCh
+	s.mu.Unlock()
+	
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, id)
+		s.mu.Unlock()
+	}()
+	
+	if err := s.send(ctx, &MCPRequest{JSONRPC: "2.0", ID: id, Method: method, Params: params}); err != nil {
+		return nil, err
+	}
+	
+	select {
+	case resp := <-responseCh:
+		return resp, nil
+	case <-s.done:
+		return nil, s.exitErr()
+	case <-ctx.Done():
+		// Let the server stop working on a request nobody is waiting for
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), mcpNotifyTimeout)
+			defer cancel()
+			s.notify(ctx, "notifications/cancelled", map[string]interface{}{"requestId": id})
+		}()
+		return nil, ctx.Err()
+	}
+}
+
+func (s *mcpSession) notify(ctx context.Context, method string, params interface{}) error {
+	return s.send(ctx, &MCPRequest{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func (s *mcpSession) send(ctx context.Context, message interface{}) error {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal MCP message: %w", err)
+	}
+	
+	if err := s.exitErr(); err != nil {
+		return err
+	}
+	
+	if err := s.transport.Send(ctx, data); err != nil {
+		return fmt.Errorf("failed to send MCP message: %w", err)
+	}
+	return nil
+}
+
+func (s *mcpSession) readLoop() {
+	for {
+		data, err := s.transport.Receive()
+		if err != nil {
+			s.fail(fmt.Errorf("MCP server connection lost: %w", err))
+			return
+		}
+		
+		var message mcpMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			// Servers occasionally write non-protocol output; skip it
+			continue
+		}
+		
+		switch {
+		case message.Method != "" && len(message.ID) > 0:
+			// Answered aside, as sending may wait on the server
+			go s.handleServerRequest(&message)
+		case message.Method != "":
+			// Notifications such as logging or progress are not used yet
+		default:
+			s.deliver(&message)
+		}
+	}
+}
+
+func (s *mcpSession) deliver(message *mcpMessage) {
+	var id int64
+	if err := json.Unmarshal(message.ID, &id); err != nil {
+		return
+	}
+	
+	s.mu.Lock()
+	responseCh, exists := s.pending[id]
+	s.mu.Unlock()
+	if !exists {
+		return
+	}
+	
+	responseCh <- &MCPResponse{
+		JSONRPC: message.JSONRPC,
+		ID:      id,
+		Result:  message.Result,
+		Error:   message.Error,
+	}
+}
+
+// handleServerRequest answers requests initiated by the server. Only ping is
+// supported since the client advertises no capabilities.
+func (s *mcpSession) handleServerRequest(message *mcpMessage) {
+	response := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      message.ID,
+	}
+	
+	if message.Method == "ping" {
+		response["result"] = map[string]interface{}{}
+	} else {
+		response["error"] = &MCPError{
+			Code:    -32601,
+			Message: fmt.Sprintf("Method not found: %s", message.Method),
+		}
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), mcpNotifyTimeout)
+	defer cancel()
+	s.send(ctx, response)
+}
+
+func (s *mcpSession) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	close(s.done)
+}
+
+func (s *mcpSession) exitErr() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *mcpSession) close() error {
+	s.fail(errMCPSessionClosed)
+	return s.transport.Close()
 }
\ No newline at end of file
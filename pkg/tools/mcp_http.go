@@ -0,0 +1,357 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const mcpSessionHeader = "Mcp-Session-Id"
+
+// httpTransportBase holds the plumbing shared by the Streamable HTTP and
+// legacy SSE transports: an inbox of received messages and a terminal error.
+type httpTransportBase struct {
+	config   *Config
+	client   *http.Client
+	incoming chan []byte
+	ctx      context.Context
+	cancel   context.CancelFunc
+	err      error
+	errOnce  sync.Once
+}
+
+func newHTTPTransportBase(config *Config) httpTransportBase {
+	ctx, cancel := context.WithCancel(context.Background())
+	return httpTransportBase{
+		config: config,
+		// No client timeout: SSE streams stay open for the whole session.
+		// Other requests end with the call that sent them.
+		client:   &http.Client{},
+		incoming: make(chan []byte, 32),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+func (t *httpTransportBase) Receive() ([]byte, error) {
+	select {
+	case message := <-t.incoming:
+		return message, nil
+	case <-t.ctx.Done():
+		return nil, t.err
+	}
+}
+
+func (t *httpTransportBase) fail(err error) {
+	t.errOnce.Do(func() {
+		t.err = err
+		t.cancel()
+	})
+}
+
+func (t *httpTransportBase) push(message []byte) {
+	select {
+	case t.incoming <- message:
+	case <-t.ctx.Done():
+	}
+}
+
+// requestContext returns the context of a request sent on behalf of ctx,
+// which is also done once the transport closes.
+func (t *httpTransportBase) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(t.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+func (t *httpTransportBase) newRequest(ctx context.Context, method, target string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	req.Header.Set("User-Agent", "goagents/1.0")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	applyRequestAuth(req, t.config)
+	
+	return req, nil
+}
+
+// streamableHTTPTransport implements the MCP Streamable HTTP transport.
+// Every message is POSTed to one endpoint; responses arrive either as a JSON
+// body or as an SSE stream on the POST response.
+type streamableHTTPTransport struct {
+	httpTransportBase
+	url       string
+	sessionID string
+	mu        sync.Mutex
+}
+
+func newStreamableHTTPTransport(endpoint string, config *Config) *streamableHTTPTransport {
+	return &streamableHTTPTransport{
+		httpTransportBase: newHTTPTransportBase(config),
+		url:               endpoint,
+	}
+}
+
+func (t *streamableHTTPTransport) Send(ctx context.Context, message []byte) error {
+	ctx, cancel := t.requestContext(ctx)
+	streaming := false
+	defer func() {
+		if !streaming {
+			cancel()
+		}
+	}()
+	
+	req, err := t.newRequest(ctx, http.MethodPost, t.url, message)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID != "" {
+		req.Header.Set(mcpSessionHeader, sessionID)
+	}
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	
+	if id := resp.Header.Get(mcpSessionHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	
+	switch {
+	case resp.StatusCode == http.StatusNotFound && sessionID != "":
+		resp.Body.Close()
+		// The server dropped our session; force a fresh initialize
+		err := errors.New("MCP session expired")
+		t.fail(err)
+		return err
+	case resp.StatusCode >= 400:
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	case resp.StatusCode == http.StatusAccepted:
+		resp.Body.Close()
+		return nil
+	}
+	
+	// An SSE response is read until the server ends it or the call that
+	// sent the request is over
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		streaming = true
+		go func() {
+			defer cancel()
+			defer resp.Body.Close()
+			readSSE(resp.Body, func(event, data string) {
+				if event == "" || event == "message" {
+					t.push([]byte(data))
+				}
+			})
+		}()
+		return nil
+	}
+	
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, mcpMaxMessageSize+1))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > mcpMaxMessageSize {
+		return fmt.Errorf("MCP response exceeds %d bytes", mcpMaxMessageSize)
+	}
+	
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil
+	}
+	
+	// A batch response carries several messages in one array
+	if body[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return fmt.Errorf("invalid MCP response: %w", err)
+		}
+		for _, message := range batch {
+			t.push(message)
+		}
+		return nil
+	}
+	
+	t.push(body)
+	return nil
+}
+
+// Close ends the server-side session, if any, and stops all streams.
+func (t *streamableHTTPTransport) Close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	
+	if sessionID != "" && t.ctx.Err() == nil {
+		ctx, cancel := context.WithTimeout(t.ctx, 5*time.Second)
+		req, err := t.newRequest(ctx, http.MethodDelete, t.url, nil)
+		if err == nil {
+			req.Header.Set(mcpSessionHeader, sessionID)
+			if resp, err := t.client.Do(req); err == nil {
+				resp.Body.Close()
+			}
+		}
+		cancel()
+	}
+	
+	t.fail(errMCPSessionClosed)
+	return nil
+}
+
+// sseTransport implements the legacy HTTP+SSE transport (protocol version
+// 2024-11-05): a long-lived GET stream delivers server messages and
+// announces the endpoint that client messages are POSTed to.
+type sseTransport struct {
+	httpTransportBase
+	endpoint string
+}
+
+func newSSETransport(ctx context.Context, streamURL string, config *Config) (*sseTransport, error) {
+	t := &sseTransport{
+		httpTransportBase: newHTTPTransportBase(config),
+	}
+	
+	req, err := t.newRequest(t.ctx, http.MethodGet, streamURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.cancel()
+		return nil, fmt.Errorf("failed to open SSE stream: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		t.cancel()
+		return nil, fmt.Errorf("failed to open SSE stream: HTTP %d", resp.StatusCode)
+	}
+	
+	base, err := url.Parse(streamURL)
+	if err != nil {
+		resp.Body.Close()
+		t.cancel()
+		return nil, fmt.Errorf("invalid SSE URL: %w", err)
+	}
+	
+	endpointCh := make(chan string, 1)
+	go func() {
+		defer resp.Body.Close()
+		err := readSSE(resp.Body, func(event, data string) {
+			switch event {
+			case "endpoint":
+				if ref, err := url.Parse(strings.TrimSpace(data)); err == nil {
+					select {
+					case endpointCh <- base.ResolveReference(ref).String():
+					default:
+					}
+				}
+			case "", "message":
+				t.push([]byte(data))
+			}
+		})
+		if err == nil {
+			err = errors.New("SSE stream closed by server")
+		}
+		t.fail(err)
+	}()
+	
+	select {
+	case t.endpoint = <-endpointCh:
+		return t, nil
+	case <-t.ctx.Done():
+		return nil, fmt.Errorf("SSE stream ended before endpoint event: %w", t.err)
+	case <-ctx.Done():
+		t.fail(ctx.Err())
+		return nil, fmt.Errorf("timed out waiting for SSE endpoint: %w", ctx.Err())
+	}
+}
+
+func (t *sseTransport) Send(ctx context.Context, message []byte) error {
+	ctx, cancel := t.requestContext(ctx)
+	defer cancel()
+	
+	req, err := t.newRequest(ctx, http.MethodPost, t.endpoint, message)
+	if err != nil {
+		return err
+	}
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (t *sseTransport) Close() error {
+	t.fail(errMCPSessionClosed)
+	return nil
+}
+
+// readSSE parses a text/event-stream body and calls handle for each event.
+// It returns nil at EOF.
+func readSSE(r io.Reader, handle func(event, data string)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), mcpMaxMessageSize)
+	
+	var event string
+	var data []string
+	for scanner.Scan() {
+		line := scanner.Text()
+		
+		switch {
+		case line == "":
+			if len(data) > 0 {
+				handle(event, strings.Join(data, "\n"))
+			}
+			event, data = "", nil
+		case strings.HasPrefix(line, ":"):
+			// Comment, used by servers as keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	
+	return scanner.Err()
+}
\ No newline at end of file
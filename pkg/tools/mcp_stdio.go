@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+const (
+	mcpMaxMessageSize = 16 * 1024 * 1024
+	mcpStderrTail     = 4096
+	mcpShutdownGrace  = 5 * time.Second
+)
+
+// stdioTransport runs an MCP server as a child process and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout.
+type stdioTransport struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  *bufio.Scanner
+	stderr  *tailBuffer
+	exited  chan struct{}
+	waitErr error
+	once    sync.Once
+	// writeMu keeps concurrent messages from interleaving on stdin
+	writeMu sync.Mutex
+}
+
+func newStdioTransport(command string, args []string, env map[string]string) (*stdioTransport, error) {
+	cmd := exec.Command(command, args...)
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open stdin: %w", err)
+	}
+	
+	// cmd.StdoutPipe is closed by Wait, possibly before everything has been
+	// read, so stdout is copied through a pipe that is closed afterwards
+	stdout, stdoutWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	
+	stderr := &tailBuffer{limit: mcpStderrTail}
+	cmd.Stderr = stderr
+	
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		return nil, fmt.Errorf("failed to start %s: %w", command, err)
+	}
+	
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), mcpMaxMessageSize)
+	
+	transport := &stdioTransport{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: scanner,
+		stderr: stderr,
+		exited: make(chan struct{}),
+	}
+	
+	go func() {
+		transport.waitErr = cmd.Wait()
+		stdoutWriter.Close()
+		close(transport.exited)
+	}()
+	
+	return transport, nil
+}
+
+func (t *stdioTransport) Send(ctx context.Context, message []byte) error {
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, err := t.stdin.Write(append(message, '\n'))
+	return err
+}
+
+// Receive must only be called from a single goroutine.
+func (t *stdioTransport) Receive() ([]byte, error) {
+	for t.stdout.Scan() {
+		line := bytes.TrimSpace(t.stdout.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		return append([]byte(nil), line...), nil
+	}
+	
+	if err := t.stdout.Err(); err != nil {
+		return nil, err
+	}
+	
+	// stdout closes when the process exits; report why
+	<-t.exited
+	if tail := t.stderr.String(); tail != "" {
+		return nil, fmt.Errorf("server exited (%v): %s", t.waitErr, tail)
+	}
+	return nil, fmt.Errorf("server exited: %v", t.waitErr)
+}
+
+// Close closes stdin, which asks the server to exit, and kills it if it is
+// still running after a grace period.
+func (t *stdioTransport) Close() error {
+	t.once.Do(func() {
+		t.stdin.Close()
+		
+		select {
+		case <-t.exited:
+		case <-time.After(mcpShutdownGrace):
+			t.cmd.Process.Kill()
+			<-t.exited
+		}
+	})
+	return nil
+}
+
+// tailBuffer keeps the last limit bytes written to it.
+type tailBuffer struct {
+	limit int
+	buf   []byte
+	mu    sync.Mutex
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.limit {
+		b.buf = b.buf[len(b.buf)-b.limit:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(bytes.TrimSpace(b.buf))
+}
\ No newline at end of file
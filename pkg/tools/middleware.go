@@ -0,0 +1,29 @@
+package tools
+
+import "context"
+
+// ExecuteFunc runs one tool call.
+type ExecuteFunc func(ctx context.Context, args map[string]interface{}) (*Result, error)
+
+// ToolMiddleware wraps the Execute call of every tool run through a
+// Manager, such as to redact arguments, write an audit log, rate limit or
+// refuse calls by policy. The key and tool are passed so middleware can
+// behave differently per agent, tool or tool type.
+type ToolMiddleware interface {
+	WrapExecute(key Key, tool Tool, next ExecuteFunc) ExecuteFunc
+}
+
+// ToolMiddlewareFunc adapts a plain function to ToolMiddleware.
+type ToolMiddlewareFunc func(key Key, tool Tool, next ExecuteFunc) ExecuteFunc
+
+func (f ToolMiddlewareFunc) WrapExecute(key Key, tool Tool, next ExecuteFunc) ExecuteFunc {
+	return f(key, tool, next)
+}
+
+// wrapExecute applies middleware so that the first entry is the outermost.
+func wrapExecute(key Key, tool Tool, execute ExecuteFunc, middleware []ToolMiddleware) ExecuteFunc {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		execute = middleware[i].WrapExecute(key, tool, execute)
+	}
+	return execute
+}
\ No newline at end of file
@@ -0,0 +1,636 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultObjectMaxSize    = 1 << 20
+	defaultObjectListLimit  = 100
+	maxObjectListLimit      = 1000
+	defaultPresignExpiry    = 15 * time.Minute
+	defaultMaxPresignExpiry = time.Hour
+	// maxPresignExpiry is the longest a SigV4 presigned URL can be valid
+	maxPresignExpiry = 7 * 24 * time.Hour
+)
+
+var objectStoreOperations = []string{"get", "put", "list", "presign"}
+
+// ObjectStoreTool reads, writes, lists and presigns objects in an S3 or GCS
+// bucket. Reads are limited to keys under the prefixes config entry and
+// writes to keys under write_prefixes, which is empty unless set, so a
+// tool cannot write anywhere by default.
+type ObjectStoreTool struct {
+	config           *Config
+	client           *http.Client
+	signer           *sigv4Signer
+	endpoint         *url.URL
+	bucket           string
+	pathStyle        bool
+	operations       []string
+	prefixes         []string
+	writePrefixes    []string
+	maxObjectSize    int64
+	maxPresignExpiry time.Duration
+}
+
+// objectStoreDefaults are the endpoint and signing region of each provider.
+var objectStoreDefaults = map[string]struct {
+	endpoint string
+	region   string
+}{
+	"s3":  {endpoint: "https://s3.%s.amazonaws.com", region: "us-east-1"},
+	"gcs": {endpoint: "https://storage.googleapis.com", region: "auto"},
+}
+
+// objectListing is the part of a ListObjectsV2 response the tool returns.
+type objectListing struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+		ETag         string    `xml:"ETag"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+func NewObjectStoreTool(config *Config) (*ObjectStoreTool, error) {
+	provider := configOrDefault(config.Config, "provider", "s3")
+	defaults, ok := objectStoreDefaults[provider]
+	if !ok {
+		return nil, fmt.Errorf("unsupported object store provider %q, expected s3 or gcs", provider)
+	}
+	bucket := config.Config["bucket"]
+	if bucket == "" {
+		return nil, fmt.Errorf("bucket is required for objectstore tool")
+	}
+	region := configOrDefault(config.Config, "region", defaults.region)
+	
+	signer := &sigv4Signer{region: region}
+	if config.Auth != nil {
+		signer.accessKey = config.Auth.APIKey
+		signer.secretKey = config.Auth.Secret
+		signer.sessionToken = config.Auth.Token
+	}
+	if signer.accessKey == "" && provider == "s3" {
+		signer.accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		signer.secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		signer.sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	if signer.accessKey == "" || signer.secretKey == "" {
+		return nil, fmt.Errorf("objectstore tool requires an access key in auth.api_key and its secret in auth.secret")
+	}
+	
+	// Buckets are addressed by host on AWS, and by path on custom
+	// endpoints such as MinIO and on GCS
+	rawEndpoint := config.URL
+	pathStyle := rawEndpoint != "" || provider == "gcs" || strings.Contains(bucket, ".")
+	if rawEndpoint == "" {
+		rawEndpoint = defaults.endpoint
+		if provider == "s3" {
+			rawEndpoint = fmt.Sprintf(defaults.endpoint, region)
+		}
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(rawEndpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint %q", rawEndpoint)
+	}
+	if value := config.Config["path_style"]; value != "" {
+		pathStyle, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path_style: %w", err)
+		}
+	}
+	
+	operations := objectStoreOperations
+	if allowed := config.Config["operations"]; allowed != "" {
+		operations = splitList(allowed)
+		for _, operation := range operations {
+			if !containsString(objectStoreOperations, operation) {
+				return nil, fmt.Errorf("unsupported operation %q, expected one of %s", operation, strings.Join(objectStoreOperations, ", "))
+			}
+		}
+	}
+	
+	prefixes := splitList(config.Config["prefixes"])
+	if len(prefixes) == 0 {
+		prefixes = []string{""}
+	}
+	writePrefixes := splitList(config.Config["write_prefixes"])
+	
+	maxObjectSize, err := intConfig(config.Config, "max_object_size", defaultObjectMaxSize)
+	if err != nil {
+		return nil, err
+	}
+	
+	maxExpiry := defaultMaxPresignExpiry
+	if value := config.Config["max_presign_expiry"]; value != "" {
+		maxExpiry, err = time.ParseDuration(value)
+		if err != nil || maxExpiry <= 0 || maxExpiry > maxPresignExpiry {
+			return nil, fmt.Errorf("invalid max_presign_expiry: %q, expected a duration up to %s", value, maxPresignExpiry)
+		}
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	return &ObjectStoreTool{
+		config:           config,
+		client:           &http.Client{Timeout: timeout},
+		signer:           signer,
+		endpoint:         endpoint,
+		bucket:           bucket,
+		pathStyle:        pathStyle,
+		operations:       operations,
+		prefixes:         prefixes,
+		writePrefixes:    writePrefixes,
+		maxObjectSize:    int64(maxObjectSize),
+		maxPresignExpiry: maxExpiry,
+	}, nil
+}
+
+func (t *ObjectStoreTool) Name() string {
+	return t.config.Name
+}
+
+func (t *ObjectStoreTool) Type() string {
+	return "objectstore"
+}
+
+func (t *ObjectStoreTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = fmt.Sprintf("Work with objects in the %s bucket. Operations: %s. Readable prefixes: %s.",
+			t.bucket, strings.Join(t.operations, ", "), describePrefixes(t.prefixes))
+		if len(t.writePrefixes) > 0 {
+			description += " Writable prefixes: " + describePrefixes(t.writePrefixes) + "."
+		}
+	}
+	
+	properties := map[string]interface{}{
+		"operation": map[string]interface{}{
+			"type": "string",
+			"enum": t.operations,
+		},
+		"key": map[string]interface{}{
+			"type":        "string",
+			"description": "Object key, for get, put and presign",
+		},
+	}
+	if containsString(t.operations, "list") {
+		properties["prefix"] = map[string]interface{}{
+			"type":        "string",
+			"description": "For list, the key prefix to list under; keys are grouped at the next /",
+		}
+		properties["limit"] = map[string]interface{}{
+			"type":        "integer",
+			"description": fmt.Sprintf("For list, how many keys to return (default %d, at most %d)", defaultObjectListLimit, maxObjectListLimit),
+		}
+		properties["continuation_token"] = map[string]interface{}{
+			"type":        "string",
+			"description": "For list, the token from a previous truncated listing",
+		}
+	}
+	if containsString(t.operations, "put") {
+		properties["content"] = map[string]interface{}{
+			"type":        "string",
+			"description": "For put, the text to store",
+		}
+		properties["file_id"] = map[string]interface{}{
+			"type":        "string",
+			"description": "For put, a stored file to upload instead of content",
+		}
+		properties["content_type"] = map[string]interface{}{
+			"type":        "string",
+			"description": "For put, the object's MIME type (default: from the key's extension)",
+		}
+	}
+	if containsString(t.operations, "presign") {
+		properties["method"] = map[string]interface{}{
+			"type":        "string",
+			"enum":        []string{"GET", "PUT"},
+			"description": "For presign, whether the URL downloads or uploads the object",
+		}
+		properties["expires_in"] = map[string]interface{}{
+			"type":        "integer",
+			"description": fmt.Sprintf("For presign, seconds until the URL expires (default %d, at most %d)", int(defaultPresignExpiry.Seconds()), int(t.maxPresignExpiry.Seconds())),
+		}
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   []string{"operation"},
+		},
+	}
+}
+
+func describePrefixes(prefixes []string) string {
+	quoted := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		if prefix == "" {
+			return "the whole bucket"
+		}
+		quoted[i] = strconv.Quote(prefix)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func (t *ObjectStoreTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	operation, _ := args["operation"].(string)
+	if !containsString(t.operations, operation) {
+		return &Result{Error: fmt.Sprintf("unsupported operation %q, expected one of %s", operation, strings.Join(t.operations, ", "))}, nil
+	}
+	
+	key, _ := args["key"].(string)
+	if operation != "list" {
+		if key == "" {
+			return &Result{Error: "key is required"}, nil
+		}
+		if !utf8.ValidString(key) || len(key) > 1024 {
+			return &Result{Error: "key must be valid UTF-8 of at most 1024 bytes"}, nil
+		}
+	}
+	
+	start := time.Now()
+	var data map[string]interface{}
+	var err error
+	switch operation {
+	case "get":
+		data, err = t.get(ctx, key)
+	case "put":
+		data, err = t.put(ctx, key, args)
+	case "list":
+		data, err = t.list(ctx, args)
+	case "presign":
+		data, err = t.presign(key, args)
+	}
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	return &Result{
+		Data: data,
+		Metadata: map[string]interface{}{
+			"bucket":      t.bucket,
+			"operation":   operation,
+			"duration_ms": time.Since(start).Milliseconds(),
+		},
+	}, nil
+}
+
+// checkKey rejects keys with empty, "." or ".." segments, which the store
+// or a proxy in front of it may resolve as a path, escaping the prefixes
+// the key was checked against. A trailing slash, as list prefixes and
+// folder markers have, is allowed.
+func checkKey(key string) error {
+	if key == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+		switch segment {
+		case "", ".", "..":
+			return fmt.Errorf("key %q must not have empty, \".\" or \"..\" segments", key)
+		}
+	}
+	return nil
+}
+
+// allowedKey reports whether key falls under one of prefixes.
+func allowedKey(prefixes []string, key string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *ObjectStoreTool) checkRead(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if !allowedKey(t.prefixes, key) {
+		return fmt.Errorf("key %q is outside the readable prefixes %s", key, describePrefixes(t.prefixes))
+	}
+	return nil
+}
+
+func (t *ObjectStoreTool) checkWrite(key string) error {
+	if len(t.writePrefixes) == 0 {
+		return fmt.Errorf("writing is not enabled for this bucket")
+	}
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if !allowedKey(t.writePrefixes, key) {
+		return fmt.Errorf("key %q is outside the writable prefixes %s", key, describePrefixes(t.writePrefixes))
+	}
+	return nil
+}
+
+// objectURL addresses an object, or the bucket when key is empty.
+func (t *ObjectStoreTool) objectURL(key string, query url.Values) *url.URL {
+	u := *t.endpoint
+	escaped := sigv4Escape(key, false)
+	if t.pathStyle {
+		escaped = sigv4Escape(t.bucket, true) + "/" + escaped
+	} else {
+		u.Host = t.bucket + "." + u.Host
+	}
+	u.RawPath = t.endpoint.EscapedPath() + "/" + escaped
+	u.Path, _ = url.PathUnescape(u.RawPath)
+	if query != nil {
+		u.RawQuery = canonicalQuery(query)
+	}
+	return &u
+}
+
+// do sends a signed request and returns the response if it succeeded.
+func (t *ObjectStoreTool) do(ctx context.Context, method string, u *url.URL, body []byte, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.Header.Set("User-Agent", "goagents/1.0")
+	
+	payloadHash := emptyPayloadHash
+	if len(body) > 0 {
+		payloadHash = sha256Hex(body)
+	}
+	t.signer.sign(req, payloadHash, time.Now())
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return nil, fmt.Errorf("%s", objectStoreErrorMessage(resp.StatusCode, errBody))
+	}
+	return resp, nil
+}
+
+// objectStoreErrorMessage reads the code and message from an S3 style XML
+// error body.
+func objectStoreErrorMessage(status int, body []byte) string {
+	var s3Error struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	if xml.Unmarshal(body, &s3Error) == nil && s3Error.Code != "" {
+		if s3Error.Message == "" {
+			return fmt.Sprintf("HTTP %d: %s", status, s3Error.Code)
+		}
+		return fmt.Sprintf("HTTP %d: %s: %s", status, s3Error.Code, s3Error.Message)
+	}
+	if status == http.StatusNotFound {
+		return "HTTP 404: object not found"
+	}
+	message, _ := truncateText(strings.TrimSpace(string(body)), 500)
+	return fmt.Sprintf("HTTP %d: %s", status, message)
+}
+
+func (t *ObjectStoreTool) get(ctx context.Context, key string) (map[string]interface{}, error) {
+	if err := t.checkRead(key); err != nil {
+		return nil, err
+	}
+	
+	resp, err := t.do(ctx, http.MethodGet, t.objectURL(key, nil), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	if resp.ContentLength > t.maxObjectSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", key, t.maxObjectSize)
+	}
+	// The size is checked again while reading in case no length was sent
+	content, err := io.ReadAll(io.LimitReader(resp.Body, t.maxObjectSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	if int64(len(content)) > t.maxObjectSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", key, t.maxObjectSize)
+	}
+	
+	mimeType := resp.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "binary/octet-stream" {
+		mimeType = http.DetectContentType(content)
+	}
+	data := map[string]interface{}{
+		"key":          key,
+		"size":         len(content),
+		"content_type": mimeType,
+		"etag":         strings.Trim(resp.Header.Get("ETag"), `"`),
+	}
+	if modified := resp.Header.Get("Last-Modified"); modified != "" {
+		data["last_modified"] = modified
+	}
+	if utf8.Valid(content) {
+		data["content"] = string(content)
+		return data, nil
+	}
+	
+	binary := map[string]interface{}{
+		"type":      "binary",
+		"name":      path.Base(key),
+		"mime_type": mimeType,
+		"size":      len(content),
+	}
+	if t.config.Files != nil {
+		stored, err := t.config.Files.Put(ctx, path.Base(key), mimeType, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store %s: %w", key, err)
+		}
+		binary["type"] = "file"
+		binary["file_id"] = stored.ID
+	} else {
+		binary["base64"] = base64.StdEncoding.EncodeToString(content)
+	}
+	data["file"] = binary
+	return data, nil
+}
+
+func (t *ObjectStoreTool) put(ctx context.Context, key string, args map[string]interface{}) (map[string]interface{}, error) {
+	if err := t.checkWrite(key); err != nil {
+		return nil, err
+	}
+	
+	contentType, _ := args["content_type"].(string)
+	var body []byte
+	if fileID, _ := args["file_id"].(string); fileID != "" {
+		if t.config.Files == nil {
+			return nil, fmt.Errorf("file_id cannot be used because no file store is configured")
+		}
+		file, data, err := t.config.Files.Get(ctx, fileID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file %s: %w", fileID, err)
+		}
+		body = data
+		if contentType == "" {
+			contentType = file.MimeType
+		}
+	} else {
+		content, ok := args["content"].(string)
+		if !ok {
+			return nil, fmt.Errorf("content or file_id is required for put")
+		}
+		body = []byte(content)
+	}
+	if int64(len(body)) > t.maxObjectSize {
+		return nil, fmt.Errorf("object is larger than %d bytes", t.maxObjectSize)
+	}
+	if contentType == "" {
+		contentType = mime.TypeByExtension(path.Ext(key))
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	
+	header := http.Header{}
+	header.Set("Content-Type", contentType)
+	resp, err := t.do(ctx, http.MethodPut, t.objectURL(key, nil), body, header)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	
+	return map[string]interface{}{
+		"key":          key,
+		"size":         len(body),
+		"content_type": contentType,
+		"etag":         strings.Trim(resp.Header.Get("ETag"), `"`),
+	}, nil
+}
+
+func (t *ObjectStoreTool) list(ctx context.Context, args map[string]interface{}) (map[string]interface{}, error) {
+	prefix, ok := args["prefix"].(string)
+	if !ok {
+		prefix = t.prefixes[0]
+	}
+	if err := t.checkRead(prefix); err != nil {
+		return nil, err
+	}
+	
+	limit, err := numberArg(args, "limit", defaultObjectListLimit)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > maxObjectListLimit || limit != math.Trunc(limit) {
+		return nil, fmt.Errorf("limit must be a whole number from 1 to %d", maxObjectListLimit)
+	}
+	
+	query := url.Values{}
+	query.Set("list-type", "2")
+	query.Set("prefix", prefix)
+	query.Set("delimiter", "/")
+	query.Set("max-keys", strconv.Itoa(int(limit)))
+	if token, _ := args["continuation_token"].(string); token != "" {
+		query.Set("continuation-token", token)
+	}
+	
+	resp, err := t.do(ctx, http.MethodGet, t.objectURL("", query), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	var listing objectListing
+	if err := xml.NewDecoder(io.LimitReader(resp.Body, 16<<20)).Decode(&listing); err != nil {
+		return nil, fmt.Errorf("failed to parse listing: %w", err)
+	}
+	
+	objects := make([]map[string]interface{}, 0, len(listing.Contents))
+	for _, object := range listing.Contents {
+		objects = append(objects, map[string]interface{}{
+			"key":           object.Key,
+			"size":          object.Size,
+			"last_modified": object.LastModified,
+			"etag":          strings.Trim(object.ETag, `"`),
+		})
+	}
+	prefixes := make([]string, 0, len(listing.CommonPrefixes))
+	for _, common := range listing.CommonPrefixes {
+		prefixes = append(prefixes, common.Prefix)
+	}
+	
+	data := map[string]interface{}{
+		"prefix":    prefix,
+		"objects":   objects,
+		"prefixes":  prefixes,
+		"truncated": listing.IsTruncated,
+	}
+	if listing.IsTruncated {
+		data["continuation_token"] = listing.NextContinuationToken
+	}
+	return data, nil
+}
+
+func (t *ObjectStoreTool) presign(key string, args map[string]interface{}) (map[string]interface{}, error) {
+	method, _ := args["method"].(string)
+	method = strings.ToUpper(method)
+	switch method {
+	case "", http.MethodGet:
+		method = http.MethodGet
+		if err := t.checkRead(key); err != nil {
+			return nil, err
+		}
+	case http.MethodPut:
+		if err := t.checkWrite(key); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("method must be GET or PUT")
+	}
+	
+	expiresIn, err := numberArg(args, "expires_in", defaultPresignExpiry.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	maxSeconds := t.maxPresignExpiry.Seconds()
+	if expiresIn < 1 || expiresIn > maxSeconds || expiresIn != math.Trunc(expiresIn) {
+		return nil, fmt.Errorf("expires_in must be a whole number of seconds from 1 to %d", int(maxSeconds))
+	}
+	expires := time.Duration(expiresIn) * time.Second
+	
+	now := time.Now()
+	return map[string]interface{}{
+		"key":        key,
+		"method":     method,
+		"url":        t.signer.presign(method, t.objectURL(key, nil), expires, now),
+		"expires_at": now.Add(expires).UTC(),
+	}, nil
+}
+
+func (t *ObjectStoreTool) Close() error {
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,72 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestObjectStoreKeys(t *testing.T) {
+	tool := &ObjectStoreTool{
+		prefixes:      []string{"builds/", "reports/"},
+		writePrefixes: []string{"reports/agents/"},
+	}
+	tests := []struct {
+		name  string
+		key   string
+		write bool
+		err   string
+	}{
+		{name: "key under prefix", key: "reports/2024/q1.csv"},
+		{name: "list prefix", key: "reports/"},
+		{name: "key under second prefix", key: "builds/app.tar.gz"},
+		{name: "write under prefix", key: "reports/agents/summary.md", write: true},
+		{name: "write list prefix", key: "reports/agents/", write: true},
+		{name: "empty segment", key: "reports//a", err: "segments"},
+		{name: "leading slash", key: "/reports/a", err: "segments"},
+		{name: "dot segment", key: "reports/./a", err: "segments"},
+		{name: "dot-dot segment", key: "reports/../secrets/a", err: "segments"},
+		{name: "trailing dot-dot", key: "reports/..", err: "segments"},
+		{name: "only dot-dot", key: "..", err: "segments"},
+		{name: "write dot-dot", key: "reports/agents/../../secrets/a", write: true, err: "segments"},
+		{name: "near-miss sibling", key: "reports-private/a", err: "outside the readable prefixes"},
+		{name: "near-miss without slash", key: "reports", err: "outside the readable prefixes"},
+		{name: "near-miss case", key: "Reports/a", err: "outside the readable prefixes"},
+		{name: "near-miss short", key: "report", err: "outside the readable prefixes"},
+		{name: "other prefix", key: "secrets/a", err: "outside the readable prefixes"},
+		{name: "write under read prefix", key: "reports/q1.csv", write: true, err: "outside the writable prefixes"},
+		{name: "write near-miss", key: "reports/agents-old/a", write: true, err: "outside the writable prefixes"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			check := tool.checkRead
+			if tt.write {
+				check = tool.checkWrite
+			}
+			err := check(tt.key)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("check(%q) = %v; want nil", tt.key, err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("check(%q) = %v; want error containing %q", tt.key, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestObjectStoreWriteDisabled(t *testing.T) {
+	tool := &ObjectStoreTool{prefixes: []string{""}}
+	if err := tool.checkWrite("a"); err == nil || !strings.Contains(err.Error(), "not enabled") {
+		t.Fatalf("checkWrite without write prefixes = %v; want writing disabled", err)
+	}
+	// The whole bucket is readable without prefixes, but keys are still checked
+	if err := tool.checkRead("any/key"); err != nil {
+		t.Fatalf("checkRead(%q) = %v; want nil", "any/key", err)
+	}
+	if err := tool.checkRead("a/../b"); err == nil {
+		t.Fatalf("checkRead(%q) = nil; want error", "a/../b")
+	}
+}
\ No newline at end of file
@@ -0,0 +1,735 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	maxOpenAPIRefDepth      = 8
+	maxOpenAPIDescription   = 1024
+	maxOpenAPIToolName      = 64
+	defaultOpenAPIMaxSpec   = 10 << 20
+	openAPIBodyParameter    = "body"
+	openAPIAltBodyParameter = "request_body"
+)
+
+var (
+	openAPIMethods       = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+	openAPINameCleaner   = regexp.MustCompile(`[^a-zA-Z0-9-]+`)
+	openAPIPathParameter = regexp.MustCompile(`\{([^}]+)\}`)
+)
+
+// OpenAPITool turns the operations of an OpenAPI 3 or Swagger 2 document
+// into tools. The engine registers each operation as a tool of its own;
+// called directly, the tool runs the operation named by its "operation"
+// argument.
+type OpenAPITool struct {
+	config           *Config
+	client           *http.Client
+	baseURL          string
+	apiKey           *openAPIKeyScheme
+	maxResponseBytes int64
+	operations       []*OpenAPIOperationTool
+}
+
+// OpenAPIOperationTool calls one operation of an OpenAPI document.
+type OpenAPIOperationTool struct {
+	api         *OpenAPITool
+	method      string
+	path        string
+	operationID string
+	parameters  []openAPIParameter
+	body        *openAPIBody
+	definition  Definition
+}
+
+type openAPIParameter struct {
+	name     string
+	in       string
+	property string
+}
+
+type openAPIBody struct {
+	contentType string
+	property    string
+	// form is set when the body is sent form encoded, field by field
+	form bool
+}
+
+// openAPIKeyScheme says where an API key goes, from the document's apiKey
+// security scheme.
+type openAPIKeyScheme struct {
+	name string
+	in   string
+}
+
+type openAPIDocument struct {
+	root     map[string]interface{}
+	location *url.URL
+}
+
+func NewOpenAPITool(config *Config) (*OpenAPITool, error) {
+	location := config.Config["spec"]
+	if location == "" {
+		return nil, fmt.Errorf("spec is required for openapi tool")
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	client := &http.Client{Timeout: timeout}
+	
+	maxResponseBytes, err := intConfig(config.Config, "max_response_bytes", defaultHTTPMaxResponseBytes)
+	if err != nil {
+		return nil, err
+	}
+	
+	doc, err := loadOpenAPIDocument(client, location)
+	if err != nil {
+		return nil, err
+	}
+	
+	baseURL := config.URL
+	if baseURL == "" {
+		if baseURL, err = doc.baseURL(); err != nil {
+			return nil, err
+		}
+	}
+	
+	t := &OpenAPITool{
+		config:           config,
+		client:           client,
+		baseURL:          strings.TrimSuffix(baseURL, "/"),
+		apiKey:           doc.apiKeyScheme(config.Config["security_scheme"]),
+		maxResponseBytes: int64(maxResponseBytes),
+	}
+	
+	if err := t.buildOperations(doc, splitList(config.Config["operations"])); err != nil {
+		return nil, err
+	}
+	if len(t.operations) == 0 {
+		return nil, fmt.Errorf("no operations found in %s", location)
+	}
+	
+	return t, nil
+}
+
+func loadOpenAPIDocument(client *http.Client, location string) (*openAPIDocument, error) {
+	var data []byte
+	var locationURL *url.URL
+	
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		parsed, err := url.Parse(location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid spec URL: %w", err)
+		}
+		resp, err := client.Get(location)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch spec: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("failed to fetch spec: HTTP %d", resp.StatusCode)
+		}
+		if data, err = io.ReadAll(io.LimitReader(resp.Body, defaultOpenAPIMaxSpec)); err != nil {
+			return nil, fmt.Errorf("failed to fetch spec: %w", err)
+		}
+		locationURL = parsed
+	} else {
+		var err error
+		if data, err = os.ReadFile(location); err != nil {
+			return nil, fmt.Errorf("failed to read spec: %w", err)
+		}
+	}
+	
+	// YAML is a superset of JSON, so one decoder handles both formats
+	var root map[string]interface{}
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse spec: %w", err)
+	}
+	if root["openapi"] == nil && root["swagger"] == nil {
+		return nil, fmt.Errorf("%s is not an OpenAPI or Swagger document", location)
+	}
+	
+	return &openAPIDocument{root: root, location: locationURL}, nil
+}
+
+// baseURL returns the API's address from the document's first server, or
+// from host, basePath and schemes in Swagger 2.
+func (d *openAPIDocument) baseURL() (string, error) {
+	var base string
+	if servers, _ := d.root["servers"].([]interface{}); len(servers) > 0 {
+		server, _ := servers[0].(map[string]interface{})
+		base, _ = server["url"].(string)
+		variables, _ := server["variables"].(map[string]interface{})
+		for name, variable := range variables {
+			fields, _ := variable.(map[string]interface{})
+			base = strings.ReplaceAll(base, "{"+name+"}", fmt.Sprint(fields["default"]))
+		}
+	} else if d.root["swagger"] != nil {
+		host, _ := d.root["host"].(string)
+		basePath, _ := d.root["basePath"].(string)
+		scheme := "https"
+		if schemes, _ := d.root["schemes"].([]interface{}); len(schemes) > 0 {
+			scheme = fmt.Sprint(schemes[0])
+		} else if d.location != nil {
+			scheme = d.location.Scheme
+		}
+		if host == "" && d.location != nil {
+			host = d.location.Host
+		}
+		if host != "" {
+			base = scheme + "://" + host + basePath
+		}
+	}
+	
+	// Servers may be relative to the document's own URL
+	if base == "" || !strings.Contains(base, "://") {
+		if d.location == nil {
+			return "", fmt.Errorf("spec does not give an absolute server URL; set url on the tool")
+		}
+		relative, err := url.Parse(base)
+		if err != nil {
+			return "", fmt.Errorf("invalid server URL %q: %w", base, err)
+		}
+		base = d.location.ResolveReference(relative).String()
+	}
+	
+	return base, nil
+}
+
+// apiKeyScheme returns the named apiKey security scheme, or the first one
+// in the document when name is empty.
+func (d *openAPIDocument) apiKeyScheme(name string) *openAPIKeyScheme {
+	schemes, _ := d.lookup("#/components/securitySchemes").(map[string]interface{})
+	if schemes == nil {
+		schemes, _ = d.root["securityDefinitions"].(map[string]interface{})
+	}
+	
+	names := make([]string, 0, len(schemes))
+	for schemeName := range schemes {
+		names = append(names, schemeName)
+	}
+	sort.Strings(names)
+	
+	for _, schemeName := range names {
+		if name != "" && schemeName != name {
+			continue
+		}
+		scheme, _ := d.resolve(schemes[schemeName]).(map[string]interface{})
+		if scheme["type"] != "apiKey" {
+			continue
+		}
+		keyName, _ := scheme["name"].(string)
+		in, _ := scheme["in"].(string)
+		if keyName != "" && in != "" {
+			return &openAPIKeyScheme{name: keyName, in: in}
+		}
+	}
+	return nil
+}
+
+// lookup follows a local JSON pointer such as #/components/schemas/Pet.
+func (d *openAPIDocument) lookup(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	var current interface{} = d.root
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		fields, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = fields[token]
+	}
+	return current
+}
+
+// resolve returns value with its $refs replaced by what they point at. A
+// schema that refers back to itself is cut off there, as are references
+// that are external, missing or too deep; all become open objects.
+func (d *openAPIDocument) resolve(value interface{}, refs ...string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			target := d.lookup(ref)
+			if target == nil || containsString(refs, ref) || len(refs) >= maxOpenAPIRefDepth {
+				return map[string]interface{}{"type": "object"}
+			}
+			return d.resolve(target, append(refs[:len(refs):len(refs)], ref)...)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved[key] = d.resolve(item, refs...)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved[i] = d.resolve(item, refs...)
+		}
+		return resolved
+	default:
+		return value
+	}
+}
+
+func (t *OpenAPITool) buildOperations(doc *openAPIDocument, allowed []string) error {
+	paths, _ := doc.root["paths"].(map[string]interface{})
+	pathNames := make([]string, 0, len(paths))
+	for pathName := range paths {
+		pathNames = append(pathNames, pathName)
+	}
+	sort.Strings(pathNames)
+	
+	names := make(map[string]bool)
+	for _, pathName := range pathNames {
+		pathItem, _ := doc.resolve(paths[pathName]).(map[string]interface{})
+		for _, method := range openAPIMethods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			
+			operationID, _ := operation["operationId"].(string)
+			if operationID == "" {
+				operationID = method + "_" + pathName
+			}
+			if len(allowed) > 0 && !containsString(allowed, operationID) {
+				continue
+			}
+			
+			tool, err := t.buildOperation(doc, method, pathName, operationID, pathItem, operation)
+			if err != nil {
+				return fmt.Errorf("operation %s: %w", operationID, err)
+			}
+			if names[tool.definition.Name] {
+				return fmt.Errorf("operation %s: duplicate tool name %s", operationID, tool.definition.Name)
+			}
+			names[tool.definition.Name] = true
+			t.operations = append(t.operations, tool)
+		}
+	}
+	
+	return nil
+}
+
+func (t *OpenAPITool) buildOperation(doc *openAPIDocument, method, pathName, operationID string, pathItem, operation map[string]interface{}) (*OpenAPIOperationTool, error) {
+	tool := &OpenAPIOperationTool{
+		api:         t,
+		method:      strings.ToUpper(method),
+		path:        pathName,
+		operationID: operationID,
+	}
+	
+	properties := make(map[string]interface{})
+	var required []string
+	
+	// Operation parameters override path-level ones with the same name and
+	// location
+	declared := make(map[string]map[string]interface{})
+	var order []string
+	for _, source := range []interface{}{pathItem["parameters"], operation["parameters"]} {
+		list, _ := source.([]interface{})
+		for _, item := range list {
+			parameter, _ := doc.resolve(item).(map[string]interface{})
+			name, _ := parameter["name"].(string)
+			in, _ := parameter["in"].(string)
+			if name == "" || in == "" {
+				continue
+			}
+			key := in + ":" + name
+			if declared[key] == nil {
+				order = append(order, key)
+			}
+			declared[key] = parameter
+		}
+	}
+	
+	var formFields bool
+	for _, key := range order {
+		parameter := declared[key]
+		name := parameter["name"].(string)
+		in := parameter["in"].(string)
+		
+		switch in {
+		case "body":
+			tool.body = &openAPIBody{contentType: "application/json", property: openAPIBodyParameter}
+			properties[openAPIBodyParameter] = schemaWithDescription(parameter["schema"], parameter["description"])
+			if isTrue(parameter["required"]) {
+				required = append(required, openAPIBodyParameter)
+			}
+			continue
+		case "formData":
+			formFields = true
+		case "path", "query", "header", "cookie":
+		default:
+			continue
+		}
+		
+		tool.parameters = append(tool.parameters, openAPIParameter{name: name, in: in, property: name})
+		properties[name] = schemaWithDescription(parameterSchema(parameter), parameter["description"])
+		if in == "path" || isTrue(parameter["required"]) {
+			required = append(required, name)
+		}
+	}
+	if formFields {
+		tool.body = &openAPIBody{contentType: "application/x-www-form-urlencoded", form: true}
+	}
+	
+	if requestBody, ok := doc.resolve(operation["requestBody"]).(map[string]interface{}); ok {
+		contentType, schema := requestBodySchema(requestBody)
+		if contentType != "" {
+			property := openAPIBodyParameter
+			if properties[property] != nil {
+				property = openAPIAltBodyParameter
+			}
+			tool.body = &openAPIBody{
+				contentType: contentType,
+				property:    property,
+				form:        contentType == "application/x-www-form-urlencoded",
+			}
+			properties[property] = schemaWithDescription(schema, requestBody["description"])
+			if isTrue(requestBody["required"]) {
+				required = append(required, property)
+			}
+		}
+	}
+	
+	parameters := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		parameters["required"] = required
+	}
+	
+	tool.definition = Definition{
+		Name:        openAPIToolName(t.config.Name, operationID),
+		Description: operationDescription(tool.method, pathName, operation),
+		Parameters:  parameters,
+	}
+	return tool, nil
+}
+
+// parameterSchema returns a parameter's schema. Swagger 2 puts the schema
+// keywords on the parameter itself.
+func parameterSchema(parameter map[string]interface{}) interface{} {
+	if schema, ok := parameter["schema"]; ok {
+		return schema
+	}
+	schema := make(map[string]interface{})
+	for _, key := range []string{"type", "format", "items", "enum", "default", "minimum", "maximum", "pattern", "minLength", "maxLength"} {
+		if value, ok := parameter[key]; ok {
+			schema[key] = value
+		}
+	}
+	if schema["type"] == "file" {
+		schema["type"] = "string"
+	}
+	return schema
+}
+
+// requestBodySchema picks the content type the tool will send: JSON if the
+// operation accepts it, then form encoding, then any other type as text.
+func requestBodySchema(requestBody map[string]interface{}) (string, interface{}) {
+	content, _ := requestBody["content"].(map[string]interface{})
+	if len(content) == 0 {
+		return "", nil
+	}
+	
+	contentTypes := make([]string, 0, len(content))
+	for contentType := range content {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+	
+	schemaOf := func(contentType string) interface{} {
+		media, _ := content[contentType].(map[string]interface{})
+		if schema, ok := media["schema"]; ok {
+			return schema
+		}
+		return map[string]interface{}{}
+	}
+	
+	for _, contentType := range contentTypes {
+		if contentType == "application/json" || strings.HasSuffix(contentType, "+json") {
+			return contentType, schemaOf(contentType)
+		}
+	}
+	if _, ok := content["application/x-www-form-urlencoded"]; ok {
+		return "application/x-www-form-urlencoded", schemaOf("application/x-www-form-urlencoded")
+	}
+	return contentTypes[0], map[string]interface{}{"type": "string"}
+}
+
+func schemaWithDescription(schema, description interface{}) map[string]interface{} {
+	fields, _ := schema.(map[string]interface{})
+	result := make(map[string]interface{}, len(fields)+1)
+	for key, value := range fields {
+		result[key] = value
+	}
+	if text, ok := description.(string); ok && text != "" && result["description"] == nil {
+		result["description"] = text
+	}
+	return result
+}
+
+func operationDescription(method, pathName string, operation map[string]interface{}) string {
+	summary, _ := operation["summary"].(string)
+	details, _ := operation["description"].(string)
+	
+	parts := []string{method + " " + pathName}
+	for _, part := range []string{summary, details} {
+		if part = strings.TrimSpace(part); part != "" && !containsString(parts, part) {
+			parts = append(parts, part)
+		}
+	}
+	description := strings.Join(parts, ". ")
+	if len(description) > maxOpenAPIDescription {
+		description = description[:maxOpenAPIDescription]
+	}
+	return description
+}
+
+// openAPIToolName prefixes an operation with the tool's name and keeps to
+// the characters and length model providers accept.
+func openAPIToolName(prefix, operationID string) string {
+	name := openAPINameCleaner.ReplaceAllString(prefix+"_"+operationID, "_")
+	name = strings.Trim(name, "_")
+	if len(name) > maxOpenAPIToolName {
+		name = name[:maxOpenAPIToolName]
+	}
+	return name
+}
+
+func isTrue(value interface{}) bool {
+	b, _ := value.(bool)
+	return b
+}
+
+func (t *OpenAPITool) Name() string {
+	return t.config.Name
+}
+
+func (t *OpenAPITool) Type() string {
+	return "openapi"
+}
+
+// Operations returns one tool per operation in the document.
+func (t *OpenAPITool) Operations() []*OpenAPIOperationTool {
+	return t.operations
+}
+
+// Execute runs the operation named by args["operation"], either its
+// operationId or its tool name, with the remaining arguments.
+func (t *OpenAPITool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	name, _ := args["operation"].(string)
+	for _, operation := range t.operations {
+		if operation.operationID == name || operation.definition.Name == name {
+			return operation.Execute(ctx, args)
+		}
+	}
+	return &Result{Error: fmt.Sprintf("unknown operation %q", name)}, nil
+}
+
+func (t *OpenAPITool) Close() error {
+	return nil
+}
+
+func (t *OpenAPIOperationTool) Name() string {
+	return t.definition.Name
+}
+
+func (t *OpenAPIOperationTool) Type() string {
+	return "openapi"
+}
+
+func (t *OpenAPIOperationTool) Definition() Definition {
+	return t.definition
+}
+
+func (t *OpenAPIOperationTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	req, err := t.buildRequest(ctx, args)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	resp, err := t.api.client.Do(req)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("request failed: %v", err)}, nil
+	}
+	defer resp.Body.Close()
+	
+	return httpResult(ctx, t.api.config.Files, resp, t.api.maxResponseBytes, map[string]interface{}{
+		"status_code": resp.StatusCode,
+		"headers":     resp.Header,
+		"url":         req.URL.String(),
+		"method":      t.method,
+		"operation":   t.operationID,
+	}), nil
+}
+
+// DryRun describes the request Execute would send for args.
+func (t *OpenAPIOperationTool) DryRun(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	req, err := t.buildRequest(ctx, args)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	result := describeRequest(req, t.api.config.Auth)
+	result.Metadata = map[string]interface{}{"operation": t.operationID}
+	return result, nil
+}
+
+func (t *OpenAPIOperationTool) buildRequest(ctx context.Context, args map[string]interface{}) (*http.Request, error) {
+	requestPath := t.path
+	query := url.Values{}
+	form := url.Values{}
+	headers := http.Header{}
+	var cookies []*http.Cookie
+	
+	for _, parameter := range t.parameters {
+		value, ok := args[parameter.property]
+		if !ok || value == nil {
+			if parameter.in == "path" {
+				return nil, fmt.Errorf("missing path parameter %s", parameter.name)
+			}
+			continue
+		}
+		
+		values := parameterValues(value)
+		switch parameter.in {
+		case "path":
+			requestPath = strings.ReplaceAll(requestPath, "{"+parameter.name+"}", url.PathEscape(strings.Join(values, ",")))
+		case "query":
+			query[parameter.name] = append(query[parameter.name], values...)
+		case "header":
+			headers.Set(parameter.name, strings.Join(values, ","))
+		case "cookie":
+			cookies = append(cookies, &http.Cookie{Name: parameter.name, Value: strings.Join(values, ",")})
+		case "formData":
+			form[parameter.name] = append(form[parameter.name], values...)
+		}
+	}
+	if match := openAPIPathParameter.FindString(requestPath); match != "" {
+		return nil, fmt.Errorf("missing path parameter %s", strings.Trim(match, "{}"))
+	}
+	
+	var body io.Reader
+	if t.body != nil {
+		switch {
+		case t.body.property == "":
+			if len(form) > 0 {
+				body = strings.NewReader(form.Encode())
+			}
+		case args[t.body.property] == nil:
+		case t.body.form:
+			fields, ok := args[t.body.property].(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("%s must be an object", t.body.property)
+			}
+			for key, value := range fields {
+				form[key] = append(form[key], parameterValues(value)...)
+			}
+			body = strings.NewReader(form.Encode())
+		case strings.Contains(t.body.contentType, "json"):
+			data, err := json.Marshal(args[t.body.property])
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal request body: %w", err)
+			}
+			body = bytes.NewReader(data)
+		default:
+			body = strings.NewReader(fmt.Sprint(args[t.body.property]))
+		}
+	}
+	
+	// The API key may belong in the query string, which must be complete
+	// before the request is created
+	if scheme := t.api.apiKey; scheme != nil && scheme.in == "query" && t.hasAPIKey() {
+		query.Set(scheme.name, t.api.config.Auth.APIKey)
+	}
+	
+	target := t.api.baseURL + requestPath
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, t.method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	
+	req.Header.Set("User-Agent", "goagents/1.0")
+	req.Header.Set("Accept", "application/json, */*;q=0.8")
+	if body != nil {
+		req.Header.Set("Content-Type", t.body.contentType)
+	}
+	for name, values := range headers {
+		req.Header[name] = values
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	
+	applyRequestAuth(req, t.api.config)
+	if scheme := t.api.apiKey; scheme != nil && t.hasAPIKey() {
+		// Send the key where the document's security scheme expects it
+		req.Header.Del("X-API-Key")
+		switch scheme.in {
+		case "header":
+			req.Header.Set(scheme.name, t.api.config.Auth.APIKey)
+		case "cookie":
+			req.AddCookie(&http.Cookie{Name: scheme.name, Value: t.api.config.Auth.APIKey})
+		}
+	}
+	
+	return req, nil
+}
+
+func (t *OpenAPIOperationTool) hasAPIKey() bool {
+	auth := t.api.config.Auth
+	return auth != nil && auth.Type == "api_key"
+}
+
+func (t *OpenAPIOperationTool) Close() error {
+	return nil
+}
+
+// parameterValues formats an argument for a URL, header or form. Lists
+// become one value per item.
+func parameterValues(value interface{}) []string {
+	switch v := value.(type) {
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, parameterValues(item)...)
+		}
+		return values
+	case float64:
+		return []string{strconv.FormatFloat(v, 'f', -1, 64)}
+	case string:
+		return []string{v}
+	case map[string]interface{}:
+		data, _ := json.Marshal(v)
+		return []string{string(data)}
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
\ No newline at end of file
@@ -0,0 +1,158 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrOutboundBlocked is returned when a tool tries to reach a host the
+// outbound policy does not allow.
+var ErrOutboundBlocked = errors.New("outbound request blocked")
+
+// Cloud instance metadata services hand out credentials, so they are
+// blocked even when private addresses are allowed.
+var (
+	metadataHostnames = []string{"metadata", "metadata.google.internal", "metadata.goog"}
+	metadataAddrs     = []netip.Addr{
+		netip.MustParseAddr("169.254.169.254"), // AWS, GCP, Azure, OpenStack
+		netip.MustParseAddr("169.254.170.2"),   // AWS ECS task metadata
+		netip.MustParseAddr("100.100.100.200"), // Alibaba Cloud
+		netip.MustParseAddr("fd00:ec2::254"),   // AWS over IPv6
+	}
+	// sharedAddressSpace is the carrier-grade NAT range, which reaches
+	// internal networks much like the private ranges do
+	sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+)
+
+// OutboundPolicy decides which hosts tools that fetch URLs may connect to.
+// Loopback, private, link-local and other non-public addresses are refused
+// unless AllowPrivate is set, and metadata endpoints always are. Hosts must
+// match AllowedHosts, when it is not empty, and no DeniedHosts; patterns use
+// shell glob syntax such as "*.example.com". Addresses are checked again
+// when connecting, after DNS resolution, so a public name that resolves to
+// a private address is refused as well.
+type OutboundPolicy struct {
+	AllowPrivate bool
+	AllowedHosts []string
+	DeniedHosts  []string
+	// Parent must allow a host too; cluster policies narrow the server's
+	Parent *OutboundPolicy
+}
+
+// CheckURL reports whether u may be fetched. A nil policy allows any URL.
+func (p *OutboundPolicy) CheckURL(u *url.URL) error {
+	if p == nil {
+		return nil
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrOutboundBlocked, u.Scheme)
+	}
+	return p.checkHost(u.Hostname())
+}
+
+func (p *OutboundPolicy) checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrOutboundBlocked)
+	}
+	
+	for policy := p; policy != nil; policy = policy.Parent {
+		if pattern, ok := matchHost(policy.DeniedHosts, host); ok {
+			return fmt.Errorf("%w: host %s is denied by pattern %q", ErrOutboundBlocked, host, pattern)
+		}
+		if len(policy.AllowedHosts) > 0 {
+			if _, ok := matchHost(policy.AllowedHosts, host); !ok {
+				return fmt.Errorf("%w: host %s is not allowed", ErrOutboundBlocked, host)
+			}
+		}
+	}
+	
+	for _, name := range metadataHostnames {
+		if host == name {
+			return fmt.Errorf("%w: %s is a metadata endpoint", ErrOutboundBlocked, host)
+		}
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return p.checkAddr(netip.IPv6Loopback())
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		return p.checkAddr(addr)
+	}
+	return nil
+}
+
+// checkAddr refuses metadata endpoints and, unless every policy in the
+// chain allows them, addresses that are not publicly routable.
+func (p *OutboundPolicy) checkAddr(addr netip.Addr) error {
+	addr = addr.Unmap().WithZone("")
+	for _, metadata := range metadataAddrs {
+		if addr == metadata {
+			return fmt.Errorf("%w: %s is a metadata endpoint", ErrOutboundBlocked, addr)
+		}
+	}
+	
+	if isPublicAddr(addr) {
+		return nil
+	}
+	for policy := p; policy != nil; policy = policy.Parent {
+		if !policy.AllowPrivate {
+			return fmt.Errorf("%w: %s is not a public address", ErrOutboundBlocked, addr)
+		}
+	}
+	return nil
+}
+
+func isPublicAddr(addr netip.Addr) bool {
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// Transport returns an HTTP transport that refuses connections to
+// addresses the policy does not allow, whatever name they were dialed by.
+func (p *OutboundPolicy) Transport() http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if p == nil {
+		return transport
+	}
+	
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return fmt.Errorf("%w: unexpected address %s", ErrOutboundBlocked, address)
+			}
+			return p.checkAddr(addrPort.Addr())
+		},
+	}
+	transport.DialContext = dialer.DialContext
+	// A proxy would make the connection on the tool's behalf, out of reach
+	// of the dial check
+	transport.Proxy = nil
+	return transport
+}
+
+// CheckRedirect applies the policy to every redirect an HTTP client follows.
+func (p *OutboundPolicy) CheckRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return fmt.Errorf("stopped after 10 redirects")
+	}
+	return p.CheckURL(req.URL)
+}
+
+func matchHost(patterns []string, host string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), host); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
\ No newline at end of file
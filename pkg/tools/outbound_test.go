@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOutboundCheckURL(t *testing.T) {
+	public := &OutboundPolicy{}
+	private := &OutboundPolicy{AllowPrivate: true}
+	tests := []struct {
+		name    string
+		policy  *OutboundPolicy
+		url     string
+		blocked bool
+	}{
+		{name: "public address", policy: public, url: "https://93.184.216.34/"},
+		{name: "public name", policy: public, url: "https://example.com/"},
+		{name: "nil policy", policy: nil, url: "http://127.0.0.1/"},
+		{name: "unsupported scheme", policy: public, url: "file:///etc/passwd", blocked: true},
+		{name: "missing host", policy: public, url: "http:///path", blocked: true},
+		{name: "loopback", policy: public, url: "http://127.0.0.1:8080/", blocked: true},
+		{name: "localhost", policy: public, url: "http://localhost/", blocked: true},
+		{name: "localhost subdomain", policy: public, url: "http://app.localhost/", blocked: true},
+		{name: "trailing dot", policy: public, url: "http://LOCALHOST./", blocked: true},
+		{name: "ipv6 loopback", policy: public, url: "http://[::1]/", blocked: true},
+		{name: "private class a", policy: public, url: "http://10.1.2.3/", blocked: true},
+		{name: "private class b", policy: public, url: "http://172.16.0.1/", blocked: true},
+		{name: "private class c", policy: public, url: "http://192.168.1.1/", blocked: true},
+		{name: "link local", policy: public, url: "http://169.254.1.1/", blocked: true},
+		{name: "shared address space", policy: public, url: "http://100.64.0.1/", blocked: true},
+		{name: "unspecified", policy: public, url: "http://0.0.0.0/", blocked: true},
+		{name: "ipv6 unique local", policy: public, url: "http://[fc00::1]/", blocked: true},
+		{name: "ipv4-mapped private", policy: public, url: "http://[::ffff:10.0.0.1]/", blocked: true},
+		{name: "ipv4-mapped loopback", policy: public, url: "http://[::ffff:127.0.0.1]/", blocked: true},
+		{name: "private allowed", policy: private, url: "http://10.1.2.3/"},
+		{name: "metadata address", policy: private, url: "http://169.254.169.254/latest/meta-data/", blocked: true},
+		{name: "ecs metadata", policy: private, url: "http://169.254.170.2/", blocked: true},
+		{name: "ipv4-mapped metadata", policy: private, url: "http://[::ffff:169.254.169.254]/", blocked: true},
+		{name: "ipv6 metadata", policy: private, url: "http://[fd00:ec2::254]/", blocked: true},
+		{name: "metadata name", policy: private, url: "http://metadata.google.internal/", blocked: true},
+		{name: "denied host", policy: &OutboundPolicy{DeniedHosts: []string{"*.internal.example.com"}}, url: "https://db.internal.example.com/", blocked: true},
+		{name: "not allowed host", policy: &OutboundPolicy{AllowedHosts: []string{"api.example.com"}}, url: "https://example.org/", blocked: true},
+		{name: "allowed host", policy: &OutboundPolicy{AllowedHosts: []string{"*.example.com"}}, url: "https://API.example.com/"},
+		{name: "parent denies private", policy: &OutboundPolicy{AllowPrivate: true, Parent: public}, url: "http://10.1.2.3/", blocked: true},
+		{name: "parent denies host", policy: &OutboundPolicy{Parent: &OutboundPolicy{DeniedHosts: []string{"example.org"}}}, url: "https://example.org/", blocked: true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = tt.policy.CheckURL(u)
+			if tt.blocked != errors.Is(err, ErrOutboundBlocked) {
+				t.Fatalf("CheckURL(%s) = %v; blocked want %v", tt.url, err, tt.blocked)
+			}
+			if !tt.blocked && err != nil {
+				t.Fatalf("CheckURL(%s) = %v; want nil", tt.url, err)
+			}
+		})
+	}
+}
+
+// TestOutboundTransport checks the address a name resolves to when the
+// connection is made, so a name that passed the URL check cannot be
+// pointed at a private address afterwards.
+func TestOutboundTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byName := "http://localhost:" + u.Port() + "/"
+	
+	tests := []struct {
+		name    string
+		policy  *OutboundPolicy
+		url     string
+		blocked bool
+	}{
+		{name: "address", policy: &OutboundPolicy{}, url: server.URL, blocked: true},
+		{name: "name resolved at dial time", policy: &OutboundPolicy{}, url: byName, blocked: true},
+		{name: "private allowed", policy: &OutboundPolicy{AllowPrivate: true}, url: byName},
+		{name: "parent refuses private", policy: &OutboundPolicy{AllowPrivate: true, Parent: &OutboundPolicy{}}, url: byName, blocked: true},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The URL check is skipped to reach the dial check
+			client := &http.Client{Transport: tt.policy.Transport()}
+			resp, err := client.Get(tt.url)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if tt.blocked != errors.Is(err, ErrOutboundBlocked) {
+				t.Fatalf("GET %s = %v; blocked want %v", tt.url, err, tt.blocked)
+			}
+			if !tt.blocked && err != nil {
+				t.Fatalf("GET %s = %v; want nil", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestOutboundRedirect(t *testing.T) {
+	policy := &OutboundPolicy{AllowPrivate: true, DeniedHosts: []string{"*.blocked.example"}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/local":
+			http.Redirect(w, r, "/ok", http.StatusFound)
+		default:
+			http.Redirect(w, r, r.URL.Query().Get("to"), http.StatusFound)
+		}
+	}))
+	defer server.Close()
+	
+	tests := []struct {
+		name    string
+		target  string
+		blocked bool
+	}{
+		{name: "same host", target: "/local"},
+		{name: "metadata address", target: "/?to=" + url.QueryEscape("http://169.254.169.254/latest/meta-data/"), blocked: true},
+		{name: "metadata name", target: "/?to=" + url.QueryEscape("http://metadata.google.internal/"), blocked: true},
+		{name: "denied host", target: "/?to=" + url.QueryEscape("http://api.blocked.example/"), blocked: true},
+		{name: "unsupported scheme", target: "/?to=" + url.QueryEscape("ftp://example.com/"), blocked: true},
+	}
+	
+	client := &http.Client{Transport: policy.Transport(), CheckRedirect: policy.CheckRedirect}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := client.Get(server.URL + tt.target)
+			if err == nil {
+				resp.Body.Close()
+			}
+			if tt.blocked != errors.Is(err, ErrOutboundBlocked) {
+				t.Fatalf("GET %s = %v; blocked want %v", tt.target, err, tt.blocked)
+			}
+			if !tt.blocked && err != nil {
+				t.Fatalf("GET %s = %v; want nil", tt.target, err)
+			}
+		})
+	}
+}
\ No newline at end of file
@@ -0,0 +1,248 @@
+package tools
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/goagents/goagents/pkg/toolplugin"
+	"github.com/hashicorp/go-hclog"
+	plugin "github.com/hashicorp/go-plugin"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// PluginTool runs an external program speaking the toolplugin protocol.
+// The program is started when the tool is created, so that it can
+// describe itself, and restarted with backoff after it exits.
+type PluginTool struct {
+	config     *Config
+	command    []string
+	checksum   []byte
+	timeout    time.Duration
+	definition Definition
+	
+	client    *plugin.Client
+	tool      toolplugin.Tool
+	startedAt time.Time
+	restarts  int
+	nextStart time.Time
+	closed    bool
+	mu        sync.Mutex
+}
+
+func NewPluginTool(config *Config) (*PluginTool, error) {
+	if len(config.Command) == 0 {
+		return nil, fmt.Errorf("command is required for plugin tool")
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	var checksum []byte
+	if value := config.Config["sha256"]; value != "" {
+		var err error
+		checksum, err = hex.DecodeString(value)
+		if err != nil || len(checksum) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256: %q", value)
+		}
+	}
+	
+	t := &PluginTool{
+		config:   config,
+		command:  append(append([]string(nil), config.Command...), config.Args...),
+		checksum: checksum,
+		timeout:  timeout,
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	t.mu.Lock()
+	tool, err := t.connect()
+	t.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	
+	description, err := tool.Describe(ctx)
+	if err != nil {
+		t.Close()
+		return nil, fmt.Errorf("failed to describe plugin %s: %w", t.command[0], err)
+	}
+	t.definition = Definition{
+		Name:        config.Name,
+		Description: description.Description,
+		Parameters:  description.Parameters,
+	}
+	if value := config.Config["description"]; value != "" {
+		t.definition.Description = value
+	}
+	return t, nil
+}
+
+func (t *PluginTool) Name() string {
+	return t.config.Name
+}
+
+func (t *PluginTool) Type() string {
+	return "plugin"
+}
+
+func (t *PluginTool) Definition() Definition {
+	return t.definition
+}
+
+func (t *PluginTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	t.mu.Lock()
+	tool, err := t.connect()
+	t.mu.Unlock()
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	
+	start := time.Now()
+	result, err := tool.Execute(ctx, args)
+	if err != nil {
+		if status.Code(err) == codes.Unavailable {
+			// the plugin died mid-call; make the next call restart it
+			t.mu.Lock()
+			if t.tool == tool {
+				t.client.Kill()
+			}
+			t.mu.Unlock()
+		}
+		return &Result{Error: fmt.Sprintf("plugin call failed: %v", err)}, nil
+	}
+	
+	metadata := result.Metadata
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	metadata["duration_ms"] = time.Since(start).Milliseconds()
+	return &Result{
+		Data:     result.Data,
+		Error:    result.Error,
+		Metadata: metadata,
+	}, nil
+}
+
+func (t *PluginTool) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	t.closed = true
+	if t.client != nil {
+		t.client.Kill()
+		t.client = nil
+		t.tool = nil
+	}
+	return nil
+}
+
+// connect returns the running plugin, starting it if needed. The caller
+// holds t.mu.
+func (t *PluginTool) connect() (toolplugin.Tool, error) {
+	if t.closed {
+		return nil, fmt.Errorf("plugin %s is closed", t.config.Name)
+	}
+	
+	if t.client != nil {
+		if !t.client.Exited() {
+			return t.tool, nil
+		}
+		t.recordExit()
+	}
+	
+	if wait := time.Until(t.nextStart); wait > 0 {
+		return nil, fmt.Errorf("plugin %s is restarting, retry in %s", t.config.Name, wait.Round(time.Second))
+	}
+	
+	cmd := exec.Command(t.command[0], t.command[1:]...)
+	// go-plugin appends the engine's environment
+	for key, value := range t.config.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	
+	stderr := &tailBuffer{limit: mcpStderrTail}
+	clientConfig := &plugin.ClientConfig{
+		HandshakeConfig:  toolplugin.Handshake,
+		VersionedPlugins: toolplugin.Plugins(nil),
+		Cmd:              cmd,
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		StartTimeout:     t.timeout,
+		Stderr:           stderr,
+		Logger:           hclog.NewNullLogger(),
+	}
+	if t.checksum != nil {
+		clientConfig.SecureConfig = &plugin.SecureConfig{
+			Checksum: t.checksum,
+			Hash:     sha256.New(),
+		}
+	}
+	
+	client := plugin.NewClient(clientConfig)
+	tool, err := dispensePlugin(client)
+	if err != nil {
+		client.Kill()
+		t.scheduleRestart()
+		if tail := stderr.String(); tail != "" {
+			return nil, fmt.Errorf("failed to start plugin %s: %w: %s", t.command[0], err, tail)
+		}
+		return nil, fmt.Errorf("failed to start plugin %s: %w", t.command[0], err)
+	}
+	
+	t.client = client
+	t.tool = tool
+	t.startedAt = time.Now()
+	return tool, nil
+}
+
+func dispensePlugin(client *plugin.Client) (toolplugin.Tool, error) {
+	rpcClient, err := client.Client()
+	if err != nil {
+		return nil, err
+	}
+	raw, err := rpcClient.Dispense(toolplugin.PluginName)
+	if err != nil {
+		return nil, err
+	}
+	tool, ok := raw.(toolplugin.Tool)
+	if !ok {
+		return nil, fmt.Errorf("plugin does not implement the tool protocol")
+	}
+	return tool, nil
+}
+
+// recordExit updates the restart backoff after the plugin exited. The
+// caller holds t.mu.
+func (t *PluginTool) recordExit() {
+	t.client.Kill()
+	t.client = nil
+	t.tool = nil
+	if time.Since(t.startedAt) >= mcpStableUptime {
+		t.restarts = 0
+	}
+	t.scheduleRestart()
+}
+
+func (t *PluginTool) scheduleRestart() {
+	backoff := time.Duration(0)
+	if t.restarts > 0 {
+		backoff = time.Second << (t.restarts - 1)
+		if backoff > mcpMaxRestartBackoff {
+			backoff = mcpMaxRestartBackoff
+		}
+	}
+	t.restarts++
+	t.nextStart = time.Now().Add(backoff)
+}
\ No newline at end of file
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	maxRetryAttempts       = 10
+	defaultRetryBackoff    = time.Second
+	defaultRetryMaxBackoff = 30 * time.Second
+	// deadlineGrace lets a tool report its own timeout, often with partial
+	// output, before Manager.Execute gives up on it
+	deadlineGrace = 5 * time.Second
+)
+
+// defaultRetryablePattern matches the errors tools report for transient
+// failures: timeouts, dropped connections, rate limits and server errors.
+var defaultRetryablePattern = regexp.MustCompile(`(?i)\bHTTP (408|429|5\d\d)\b|time(d)? ?out|deadline exceeded|connection (refused|reset)|broken pipe|\bEOF\b|temporar|unavailable|too many requests|rate.?limit`)
+
+// RetryPolicy controls how Manager.Execute runs a tool: how many attempts
+// a failing call gets, how long to wait between them, which errors are
+// worth retrying and how long each attempt may take.
+type RetryPolicy struct {
+	// MaxAttempts includes the first call, so 1 disables retries
+	MaxAttempts int
+	// Backoff is the wait before the second attempt, doubling after each
+	// further one up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Timeout bounds each attempt. An attempt still running a short grace
+	// period later is abandoned, even if the tool ignores its context.
+	// Zero leaves attempts to the tool's own timeouts.
+	Timeout time.Duration
+	// RetryOn matches the errors that are retried
+	RetryOn *regexp.Regexp
+}
+
+// ParseRetryPolicy reads a policy from a tool's retry_attempts,
+// retry_backoff, retry_max_backoff and retry_on config keys, with timeout
+// as the per-attempt deadline. It returns nil when the tool needs neither
+// retries nor a deadline.
+func ParseRetryPolicy(config map[string]string, timeout time.Duration) (*RetryPolicy, error) {
+	policy := &RetryPolicy{
+		MaxAttempts: 1,
+		Backoff:     defaultRetryBackoff,
+		MaxBackoff:  defaultRetryMaxBackoff,
+		Timeout:     timeout,
+		RetryOn:     defaultRetryablePattern,
+	}
+	
+	if value := config["retry_attempts"]; value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 || attempts > maxRetryAttempts {
+			return nil, fmt.Errorf("invalid retry_attempts: %q, expected 1 to %d", value, maxRetryAttempts)
+		}
+		policy.MaxAttempts = attempts
+	}
+	for key, target := range map[string]*time.Duration{
+		"retry_backoff":     &policy.Backoff,
+		"retry_max_backoff": &policy.MaxBackoff,
+	} {
+		if value := config[key]; value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration < 0 {
+				return nil, fmt.Errorf("invalid %s: %q", key, value)
+			}
+			*target = duration
+		}
+	}
+	if value := config["retry_on"]; value != "" {
+		pattern, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retry_on: %w", err)
+		}
+		policy.RetryOn = pattern
+	}
+	
+	if policy.MaxAttempts == 1 && policy.Timeout <= 0 {
+		return nil, nil
+	}
+	return policy, nil
+}
+
+// execute runs the tool until an attempt succeeds, fails with an error
+// that is not retryable, or the attempts run out. A nil policy runs the
+// tool once.
+func (p *RetryPolicy) execute(ctx context.Context, tool Tool, args map[string]interface{}) (*Result, error) {
+	if p == nil {
+		return tool.Execute(ctx, args)
+	}
+	
+	for attempt := 1; ; attempt++ {
+		result, err := p.attempt(ctx, tool, args)
+		if attempt >= p.MaxAttempts || !p.retryable(result, err) {
+			if result != nil && attempt > 1 {
+				result = copyResult(result)
+				result.Metadata["attempts"] = attempt
+			}
+			return result, err
+		}
+		
+		select {
+		case <-time.After(p.backoff(attempt)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// attempt runs the tool once within the policy's deadline. A tool that
+// overruns it is abandoned with its context cancelled.
+func (p *RetryPolicy) attempt(ctx context.Context, tool Tool, args map[string]interface{}) (*Result, error) {
+	if p.Timeout <= 0 {
+		return tool.Execute(ctx, args)
+	}
+	
+	attemptCtx, cancel := context.WithTimeout(ctx, p.Timeout+deadlineGrace)
+	defer cancel()
+	
+	type outcome struct {
+		result *Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := tool.Execute(attemptCtx, args)
+		done <- outcome{result, err}
+	}()
+	
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-attemptCtx.Done():
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return &Result{
+			Error:    fmt.Sprintf("tool %s timed out after %s", tool.Name(), p.Timeout),
+			Metadata: map[string]interface{}{"timed_out": true},
+		}, nil
+	}
+}
+
+func (p *RetryPolicy) retryable(result *Result, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return result != nil && result.Error != "" && p.RetryOn.MatchString(result.Error)
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	backoff := p.Backoff << (attempt - 1)
+	if backoff > p.MaxBackoff || backoff < 0 {
+		backoff = p.MaxBackoff
+	}
+	return backoff
+}
\ No newline at end of file
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// Schema validates a tool call's arguments against the JSON schema the
+// model was given for the tool.
+type Schema struct {
+	schema *jsonschema.Schema
+}
+
+// CompileSchema compiles a tool's parameters schema. The schema must
+// describe an object, since arguments are always passed as one.
+func CompileSchema(parameters map[string]interface{}) (*Schema, error) {
+	if schemaType, ok := parameters["type"]; ok && schemaType != "object" {
+		return nil, fmt.Errorf("parameters must describe an object, not %v", schemaType)
+	}
+	
+	data, err := json.Marshal(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode parameters: %w", err)
+	}
+	
+	compiler := jsonschema.NewCompiler()
+	if err := compiler.AddResource("mem:///parameters.json", bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("invalid parameters schema: %w", err)
+	}
+	schema, err := compiler.Compile("mem:///parameters.json")
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters schema: %w", err)
+	}
+	return &Schema{schema: schema}, nil
+}
+
+// Validate checks args against the schema. The error lists every problem
+// found, each with the path of the offending argument, so the model can
+// correct the call.
+func (s *Schema) Validate(args map[string]interface{}) error {
+	if s == nil {
+		return nil
+	}
+	
+	// Arguments are normalized through JSON so that Go values such as ints
+	// are checked the same way as the decoded model output
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("arguments are not valid JSON: %w", err)
+	}
+	if args == nil {
+		value = map[string]interface{}{}
+	}
+	
+	err = s.schema.Validate(value)
+	var validationErr *jsonschema.ValidationError
+	if !errors.As(err, &validationErr) {
+		return err
+	}
+	
+	var problems []string
+	for _, cause := range leafErrors(validationErr) {
+		location := cause.InstanceLocation
+		if location == "" {
+			location = "/"
+		}
+		problems = append(problems, location+": "+cause.Message)
+	}
+	return errors.New(strings.Join(problems, "; "))
+}
+
+// leafErrors returns the most specific causes of a validation error.
+func leafErrors(err *jsonschema.ValidationError) []*jsonschema.ValidationError {
+	if len(err.Causes) == 0 {
+		return []*jsonschema.ValidationError{err}
+	}
+	var leaves []*jsonschema.ValidationError
+	for _, cause := range err.Causes {
+		leaves = append(leaves, leafErrors(cause)...)
+	}
+	return leaves
+}
\ No newline at end of file
@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ErrSecretNotFound is returned when a secret reference names a value that
+// does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretLookup reads the value under key at path in a secret store.
+type SecretLookup func(path, key string) (string, error)
+
+// SecretResolver resolves secret references in tool auth settings, so
+// tokens can be kept out of cluster specs. A reference is one of
+//
+//	env://NAME            the environment variable NAME
+//	file:///path/to/file  the contents of a file, such as a mounted secret
+//	vault://path#key      the value under key at path in Vault
+//
+// Any other value is used as it is.
+type SecretResolver struct {
+	// Vault looks up vault:// references; without it they fail to resolve
+	Vault SecretLookup
+}
+
+// IsSecretRef reports whether value is a secret reference.
+func IsSecretRef(value string) bool {
+	for _, prefix := range []string{"env://", "file://", "vault://"} {
+		if strings.HasPrefix(value, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the secret value references, or value itself when it
+// is not a reference.
+func (r *SecretResolver) Resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, "env://"):
+		name := strings.TrimPrefix(value, "env://")
+		secret, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("%w: environment variable %s is not set", ErrSecretNotFound, name)
+		}
+		return secret, nil
+	case strings.HasPrefix(value, "file://"):
+		path := strings.TrimPrefix(value, "file://")
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: file %s does not exist", ErrSecretNotFound, path)
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		// Secret files usually end with a newline the value does not have
+		return strings.TrimRight(string(data), "\r\n"), nil
+	case strings.HasPrefix(value, "vault://"):
+		path, key, _ := strings.Cut(strings.TrimPrefix(value, "vault://"), "#")
+		if path == "" {
+			return "", fmt.Errorf("invalid secret reference %q: missing path", value)
+		}
+		if r == nil || r.Vault == nil {
+			return "", fmt.Errorf("cannot resolve %q: no vault is configured", value)
+		}
+		return r.Vault(path, key)
+	}
+	return value, nil
+}
+
+// HasSecretRefs reports whether any auth value is a secret reference.
+func (a *AuthConfig) HasSecretRefs() bool {
+	return a != nil && (IsSecretRef(a.Token) || IsSecretRef(a.APIKey) || IsSecretRef(a.Secret))
+}
+
+// ResolveAuth returns a copy of auth with its secret references resolved.
+func (r *SecretResolver) ResolveAuth(auth *AuthConfig) (*AuthConfig, error) {
+	if auth == nil {
+		return nil, nil
+	}
+	
+	resolved := *auth
+	for field, value := range map[string]*string{
+		"token":   &resolved.Token,
+		"api_key": &resolved.APIKey,
+		"secret":  &resolved.Secret,
+	} {
+		secret, err := r.Resolve(*value)
+		if err != nil {
+			return nil, fmt.Errorf("auth %s: %w", field, err)
+		}
+		*value = secret
+	}
+	return &resolved, nil
+}
\ No newline at end of file
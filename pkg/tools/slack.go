@@ -0,0 +1,469 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultSlackAPIURL      = "https://slack.com/api"
+	defaultSlackHistory     = 20
+	maxSlackHistory         = 200
+	maxSlackMessageLength   = 40000
+	maxSlackResponse        = 4 << 20
+	maxSlackChannelListPage = 20
+)
+
+// slackActions are the operations the tool offers.
+var slackActions = []string{"post_message", "read_history", "lookup_user"}
+
+var slackChannelIDPattern = regexp.MustCompile(`^[CGD][A-Z0-9]{6,}$`)
+
+// SlackTool posts messages, reads channel history and looks up users through
+// the Slack Web API with a bot token. The actions config entry limits what
+// the model may do, and channels limits where it may read and post.
+type SlackTool struct {
+	config         *Config
+	client         *http.Client
+	baseURL        string
+	token          string
+	actions        []string
+	channels       []string
+	defaultChannel string
+	
+	// channelIDs maps channel names to IDs, loaded on first use
+	channelIDs map[string]string
+	mu         sync.Mutex
+}
+
+func NewSlackTool(config *Config) (*SlackTool, error) {
+	var token string
+	if config.Auth != nil {
+		token = config.Auth.Token
+		if token == "" {
+			token = config.Auth.APIKey
+		}
+	}
+	if token == "" {
+		return nil, fmt.Errorf("slack tool requires auth.token")
+	}
+	
+	baseURL := defaultSlackAPIURL
+	if config.URL != "" {
+		if _, err := url.Parse(config.URL); err != nil {
+			return nil, fmt.Errorf("invalid URL: %w", err)
+		}
+		baseURL = strings.TrimSuffix(config.URL, "/")
+	}
+	
+	actions := splitList(configOrDefault(config.Config, "actions", strings.Join(slackActions, ",")))
+	for _, action := range actions {
+		if !containsString(slackActions, action) {
+			return nil, fmt.Errorf("unknown slack action %q", action)
+		}
+	}
+	
+	var channels []string
+	for _, channel := range splitList(config.Config["channels"]) {
+		channels = append(channels, strings.TrimPrefix(channel, "#"))
+	}
+	
+	timeout := 15 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	return &SlackTool{
+		config:         config,
+		client:         &http.Client{Timeout: timeout},
+		baseURL:        baseURL,
+		token:          token,
+		actions:        actions,
+		channels:       channels,
+		defaultChannel: strings.TrimPrefix(config.Config["default_channel"], "#"),
+	}, nil
+}
+
+func (t *SlackTool) Name() string {
+	return t.config.Name
+}
+
+func (t *SlackTool) Type() string {
+	return "slack"
+}
+
+func (t *SlackTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = "Work with Slack: post_message sends a message, read_history reads a channel or thread, lookup_user finds a user by ID or email."
+		if len(t.channels) > 0 {
+			description += " Allowed channels: " + strings.Join(t.channels, ", ") + "."
+		}
+	}
+	
+	channelDescription := "Channel name or ID"
+	if t.defaultChannel != "" {
+		channelDescription += " (default: " + t.defaultChannel + ")"
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"action": map[string]interface{}{
+					"type": "string",
+					"enum": t.actions,
+				},
+				"channel": map[string]interface{}{
+					"type":        "string",
+					"description": channelDescription,
+				},
+				"text": map[string]interface{}{
+					"type":        "string",
+					"description": "For post_message, the message in Slack mrkdwn",
+				},
+				"thread_ts": map[string]interface{}{
+					"type":        "string",
+					"description": "Timestamp of a thread's parent message, to reply in or read that thread",
+				},
+				"limit": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("For read_history, how many messages to return, at most %d", maxSlackHistory),
+				},
+				"oldest": map[string]interface{}{
+					"type":        "string",
+					"description": "For read_history, only messages after this timestamp",
+				},
+				"user": map[string]interface{}{
+					"type":        "string",
+					"description": "For lookup_user, a user ID such as U012AB3CD",
+				},
+				"email": map[string]interface{}{
+					"type":        "string",
+					"description": "For lookup_user, the user's email address",
+				},
+			},
+			"required": []string{"action"},
+		},
+	}
+}
+
+func (t *SlackTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	action, _ := args["action"].(string)
+	if !containsString(t.actions, action) {
+		return &Result{Error: fmt.Sprintf("action %q is not allowed; allowed actions: %s", action, strings.Join(t.actions, ", "))}, nil
+	}
+	
+	start := time.Now()
+	var result *Result
+	switch action {
+	case "post_message":
+		result = t.postMessage(ctx, args)
+	case "read_history":
+		result = t.readHistory(ctx, args)
+	case "lookup_user":
+		result = t.lookupUser(ctx, args)
+	}
+	
+	if result.Error == "" {
+		result.Metadata = map[string]interface{}{
+			"action":      action,
+			"duration_ms": time.Since(start).Milliseconds(),
+		}
+	}
+	return result, nil
+}
+
+func (t *SlackTool) postMessage(ctx context.Context, args map[string]interface{}) *Result {
+	text, _ := args["text"].(string)
+	if strings.TrimSpace(text) == "" {
+		return &Result{Error: "text is required"}
+	}
+	if len(text) > maxSlackMessageLength {
+		return &Result{Error: fmt.Sprintf("text is longer than %d characters", maxSlackMessageLength)}
+	}
+	
+	channel, err := t.channel(ctx, args)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	params := url.Values{}
+	params.Set("channel", channel)
+	params.Set("text", text)
+	if threadTS, _ := args["thread_ts"].(string); threadTS != "" {
+		params.Set("thread_ts", threadTS)
+	}
+	
+	var resp struct {
+		Channel string `json:"channel"`
+		TS      string `json:"ts"`
+	}
+	if err := t.call(ctx, "chat.postMessage", params, &resp); err != nil {
+		return &Result{Error: err.Error()}
+	}
+	return &Result{Data: map[string]interface{}{"channel": resp.Channel, "ts": resp.TS}}
+}
+
+func (t *SlackTool) readHistory(ctx context.Context, args map[string]interface{}) *Result {
+	limit, err := numberArg(args, "limit", defaultSlackHistory)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	if limit < 1 || limit > maxSlackHistory || limit != math.Trunc(limit) {
+		return &Result{Error: fmt.Sprintf("limit must be a whole number from 1 to %d", maxSlackHistory)}
+	}
+	
+	channel, err := t.channel(ctx, args)
+	if err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	params := url.Values{}
+	params.Set("channel", channel)
+	params.Set("limit", strconv.Itoa(int(limit)))
+	if oldest, _ := args["oldest"].(string); oldest != "" {
+		params.Set("oldest", oldest)
+	}
+	method := "conversations.history"
+	if threadTS, _ := args["thread_ts"].(string); threadTS != "" {
+		method = "conversations.replies"
+		params.Set("ts", threadTS)
+	}
+	
+	var resp struct {
+		Messages []struct {
+			TS         string `json:"ts"`
+			User       string `json:"user"`
+			BotID      string `json:"bot_id"`
+			Text       string `json:"text"`
+			ThreadTS   string `json:"thread_ts"`
+			ReplyCount int    `json:"reply_count"`
+			Subtype    string `json:"subtype"`
+		} `json:"messages"`
+		HasMore bool `json:"has_more"`
+	}
+	if err := t.call(ctx, method, params, &resp); err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	messages := make([]map[string]interface{}, 0, len(resp.Messages))
+	for _, m := range resp.Messages {
+		message := map[string]interface{}{"ts": m.TS, "text": m.Text}
+		for key, value := range map[string]string{"user": m.User, "bot_id": m.BotID, "thread_ts": m.ThreadTS, "subtype": m.Subtype} {
+			if value != "" {
+				message[key] = value
+			}
+		}
+		if m.ReplyCount > 0 {
+			message["reply_count"] = m.ReplyCount
+		}
+		messages = append(messages, message)
+	}
+	
+	return &Result{Data: map[string]interface{}{
+		"channel":  channel,
+		"messages": messages,
+		"has_more": resp.HasMore,
+	}}
+}
+
+func (t *SlackTool) lookupUser(ctx context.Context, args map[string]interface{}) *Result {
+	user, _ := args["user"].(string)
+	email, _ := args["email"].(string)
+	
+	params := url.Values{}
+	method := "users.info"
+	switch {
+	case user != "":
+		params.Set("user", strings.TrimPrefix(user, "@"))
+	case email != "":
+		method = "users.lookupByEmail"
+		params.Set("email", email)
+	default:
+		return &Result{Error: "user or email is required"}
+	}
+	
+	var resp struct {
+		User struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			RealName string `json:"real_name"`
+			TZ       string `json:"tz"`
+			IsBot    bool   `json:"is_bot"`
+			IsAdmin  bool   `json:"is_admin"`
+			Deleted  bool   `json:"deleted"`
+			Profile  struct {
+				DisplayName string `json:"display_name"`
+				Title       string `json:"title"`
+				Email       string `json:"email"`
+				StatusText  string `json:"status_text"`
+			} `json:"profile"`
+		} `json:"user"`
+	}
+	if err := t.call(ctx, method, params, &resp); err != nil {
+		return &Result{Error: err.Error()}
+	}
+	
+	u := resp.User
+	return &Result{Data: map[string]interface{}{
+		"id":           u.ID,
+		"name":         u.Name,
+		"real_name":    u.RealName,
+		"display_name": u.Profile.DisplayName,
+		"title":        u.Profile.Title,
+		"email":        u.Profile.Email,
+		"status":       u.Profile.StatusText,
+		"timezone":     u.TZ,
+		"is_bot":       u.IsBot,
+		"is_admin":     u.IsAdmin,
+		"deleted":      u.Deleted,
+	}}
+}
+
+// channel returns the ID of the requested or default channel, checked
+// against the channels allowlist.
+func (t *SlackTool) channel(ctx context.Context, args map[string]interface{}) (string, error) {
+	channel, _ := args["channel"].(string)
+	channel = strings.TrimPrefix(strings.TrimSpace(channel), "#")
+	if channel == "" {
+		channel = t.defaultChannel
+	}
+	if channel == "" {
+		return "", fmt.Errorf("channel is required")
+	}
+	
+	id := channel
+	if !slackChannelIDPattern.MatchString(channel) {
+		var err error
+		if id, err = t.channelID(ctx, channel); err != nil {
+			return "", err
+		}
+	}
+	
+	if len(t.channels) == 0 {
+		return id, nil
+	}
+	for _, allowed := range t.channels {
+		if allowed == id {
+			return id, nil
+		}
+		if !slackChannelIDPattern.MatchString(allowed) {
+			if allowedID, err := t.channelID(ctx, allowed); err == nil && allowedID == id {
+				return id, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("channel %s is not allowed; allowed channels: %s", channel, strings.Join(t.channels, ", "))
+}
+
+// channelID resolves a channel name, listing the channels the bot can see
+// the first time a name is needed.
+func (t *SlackTool) channelID(ctx context.Context, name string) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if t.channelIDs == nil {
+		channelIDs := make(map[string]string)
+		cursor := ""
+		for page := 0; page < maxSlackChannelListPage; page++ {
+			params := url.Values{}
+			params.Set("types", "public_channel,private_channel")
+			params.Set("exclude_archived", "true")
+			params.Set("limit", "1000")
+			if cursor != "" {
+				params.Set("cursor", cursor)
+			}
+			
+			var resp struct {
+				Channels []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"channels"`
+				ResponseMetadata struct {
+					NextCursor string `json:"next_cursor"`
+				} `json:"response_metadata"`
+			}
+			if err := t.call(ctx, "conversations.list", params, &resp); err != nil {
+				return "", fmt.Errorf("failed to list channels: %w", err)
+			}
+			for _, c := range resp.Channels {
+				channelIDs[c.Name] = c.ID
+			}
+			if cursor = resp.ResponseMetadata.NextCursor; cursor == "" {
+				break
+			}
+		}
+		t.channelIDs = channelIDs
+	}
+	
+	id, ok := t.channelIDs[name]
+	if !ok {
+		return "", fmt.Errorf("channel %s not found", name)
+	}
+	return id, nil
+}
+
+// call invokes a Web API method. Slack reports most failures with status
+// 200 and "ok": false, and rate limits with 429 and Retry-After.
+func (t *SlackTool) call(ctx context.Context, method string, params url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/"+method, strings.NewReader(params.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	req.Header.Set("User-Agent", "goagents/1.0")
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", method, err)
+	}
+	defer resp.Body.Close()
+	
+	if resp.StatusCode == http.StatusTooManyRequests {
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			return fmt.Errorf("%s was rate limited; retry after %s seconds", method, retryAfter)
+		}
+		return fmt.Errorf("%s was rate limited", method)
+	}
+	
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSlackResponse))
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("%s returned HTTP %d: %s", method, resp.StatusCode, searchErrorMessage(body))
+	}
+	
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &status); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+	if !status.OK {
+		return fmt.Errorf("%s failed: %s", method, status.Error)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", method, err)
+	}
+	return nil
+}
+
+func (t *SlackTool) Close() error {
+	return nil
+}
\ No newline at end of file
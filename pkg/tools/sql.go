@@ -0,0 +1,644 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+const defaultSQLMaxRows = 1000
+
+// sqlDrivers maps the configured driver name to the database/sql driver.
+var sqlDrivers = map[string]string{
+	"postgres": "postgres",
+	"mysql":    "mysql",
+	"sqlite":   "sqlite",
+}
+
+// readOnlyKeywords are the statements allowed in read-only mode. The
+// transaction, and on Postgres and SQLite the connection, is also opened
+// read-only; this check gives a clear error before the database is
+// involved.
+var readOnlyKeywords = map[string]bool{
+	"SELECT":   true,
+	"WITH":     true,
+	"SHOW":     true,
+	"EXPLAIN":  true,
+	"DESCRIBE": true,
+	"DESC":     true,
+	"VALUES":   true,
+	"TABLE":    true,
+}
+
+// SQLTool runs queries against a Postgres, MySQL or SQLite database. It is
+// read-only unless the read_only config entry is "false".
+type SQLTool struct {
+	config   *Config
+	db       *sql.DB
+	driver   string
+	readOnly bool
+	maxRows  int
+	timeout  time.Duration
+}
+
+func NewSQLTool(config *Config) (*SQLTool, error) {
+	driver := config.Config["driver"]
+	driverName, ok := sqlDrivers[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SQL driver %q (expected postgres, mysql or sqlite)", driver)
+	}
+	
+	dsn := config.Config["dsn"]
+	if dsn == "" {
+		return nil, fmt.Errorf("dsn is required for SQL tool")
+	}
+	
+	var err error
+	readOnly := true
+	if value := config.Config["read_only"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid read_only: %w", err)
+		}
+		readOnly = parsed
+	}
+	
+	switch {
+	case driver == "mysql":
+		// Each query must run one statement, which MySQL only guarantees
+		// when the connection does not allow several
+		parsed, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dsn: %w", err)
+		}
+		if parsed.MultiStatements {
+			return nil, fmt.Errorf("multiStatements is not supported by the SQL tool")
+		}
+	case readOnly && driver == "postgres":
+		// A transaction the statement manages to end is followed by others
+		// that are read-only too
+		dsn, err = postgresReadOnlyDSN(dsn)
+		if err != nil {
+			return nil, err
+		}
+	case readOnly && driver == "sqlite":
+		// SQLite ignores read-only transactions, so enforce it on the connection
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "_pragma=query_only(1)"
+	}
+	
+	maxRows, err := intConfig(config.Config, "max_rows", defaultSQLMaxRows)
+	if err != nil {
+		return nil, err
+	}
+	maxOpen, err := intConfig(config.Config, "max_open_conns", 5)
+	if err != nil {
+		return nil, err
+	}
+	maxIdle, err := intConfig(config.Config, "max_idle_conns", 2)
+	if err != nil {
+		return nil, err
+	}
+	
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
+	db.SetConnMaxLifetime(30 * time.Minute)
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	return &SQLTool{
+		config:   config,
+		db:       db,
+		driver:   driver,
+		readOnly: readOnly,
+		maxRows:  maxRows,
+		timeout:  timeout,
+	}, nil
+}
+
+func (t *SQLTool) Name() string {
+	return t.config.Name
+}
+
+func (t *SQLTool) Type() string {
+	return "sql"
+}
+
+func (t *SQLTool) Definition() Definition {
+	access := "read-only"
+	if !t.readOnly {
+		access = "read-write"
+	}
+	
+	description := t.config.Config["description"]
+	if description == "" {
+		description = fmt.Sprintf("Query a %s %s database. Use command \"schema\" to list its tables and columns.", access, t.driver)
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"command": map[string]interface{}{
+					"type":        "string",
+					"enum":        []string{"query", "schema"},
+					"description": "query (default) runs a statement; schema describes tables",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "A single SQL statement",
+				},
+				"params": map[string]interface{}{
+					"type":        "array",
+					"description": "Values for the statement's placeholders",
+				},
+				"table": map[string]interface{}{
+					"type":        "string",
+					"description": "Limit schema to one table",
+				},
+			},
+		},
+	}
+}
+
+func (t *SQLTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	
+	command, _ := args["command"].(string)
+	switch command {
+	case "", "query":
+		query, _ := args["query"].(string)
+		if strings.TrimSpace(query) == "" {
+			return &Result{Error: "query is required"}, nil
+		}
+		params, _ := args["params"].([]interface{})
+		return t.query(ctx, query, params), nil
+	case "schema":
+		table, _ := args["table"].(string)
+		return t.schema(ctx, table), nil
+	default:
+		return &Result{Error: fmt.Sprintf("unknown command: %s", command)}, nil
+	}
+}
+
+// DryRun checks a statement as Execute does and describes it without
+// running it.
+func (t *SQLTool) DryRun(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	command, _ := args["command"].(string)
+	switch command {
+	case "", "query":
+		query, _ := args["query"].(string)
+		if strings.TrimSpace(query) == "" {
+			return &Result{Error: "query is required"}, nil
+		}
+		params, _ := args["params"].([]interface{})
+		keyword, errResult := t.checkStatement(query)
+		if errResult != nil {
+			return errResult, nil
+		}
+		return &Result{
+			Data: map[string]interface{}{
+				"driver":    t.driver,
+				"statement": keyword,
+				"query":     query,
+				"params":    params,
+				"read_only": t.readOnly,
+			},
+		}, nil
+	case "schema":
+		table, _ := args["table"].(string)
+		return &Result{
+			Data: map[string]interface{}{
+				"driver": t.driver,
+				"schema": table,
+			},
+		}, nil
+	default:
+		return &Result{Error: fmt.Sprintf("unknown command: %s", command)}, nil
+	}
+}
+
+// checkStatement returns the keyword a statement starts with, refusing
+// statements the tool does not allow.
+func (t *SQLTool) checkStatement(query string) (string, *Result) {
+	keyword, err := statementKeyword(query, t.driver)
+	if err != nil {
+		return "", &Result{Error: err.Error()}
+	}
+	if t.readOnly && !readOnlyKeywords[keyword] {
+		return "", &Result{Error: fmt.Sprintf("%s statements are not allowed in read-only mode", keyword)}
+	}
+	return keyword, nil
+}
+
+func (t *SQLTool) query(ctx context.Context, query string, params []interface{}) *Result {
+	keyword, errResult := t.checkStatement(query)
+	if errResult != nil {
+		return errResult
+	}
+	
+	isQuery := readOnlyKeywords[keyword] || keyword == "PRAGMA"
+	
+	tx, err := t.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: t.readOnly})
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to begin transaction: %v", err)}
+	}
+	defer tx.Rollback()
+	
+	// Statements run prepared, so Postgres runs no more than one of them
+	// even if the check missed others: lib/pq sends a query without
+	// parameters as a simple query, executing every statement. The SQLite
+	// driver runs each statement of its input in turn, prepared or not, so
+	// there the check alone holds it to one. MySQL runs one statement per
+	// query on connections without multiStatements, and cannot prepare
+	// some read statements such as SHOW.
+	var stmt *sql.Stmt
+	if t.driver != "mysql" {
+		stmt, err = tx.PrepareContext(ctx, query)
+		if err != nil {
+			return &Result{Error: fmt.Sprintf("failed to prepare statement: %v", err)}
+		}
+		defer stmt.Close()
+	}
+	
+	start := time.Now()
+	if !isQuery {
+		var res sql.Result
+		if stmt != nil {
+			res, err = stmt.ExecContext(ctx, params...)
+		} else {
+			res, err = tx.ExecContext(ctx, query, params...)
+		}
+		if err != nil {
+			return &Result{Error: fmt.Sprintf("statement failed: %v", err)}
+		}
+		if err := tx.Commit(); err != nil {
+			return &Result{Error: fmt.Sprintf("failed to commit: %v", err)}
+		}
+		affected, _ := res.RowsAffected()
+		return &Result{
+			Data: map[string]interface{}{"rows_affected": affected},
+			Metadata: map[string]interface{}{
+				"duration_ms": time.Since(start).Milliseconds(),
+			},
+		}
+	}
+	
+	var rows *sql.Rows
+	if stmt != nil {
+		rows, err = stmt.QueryContext(ctx, params...)
+	} else {
+		rows, err = tx.QueryContext(ctx, query, params...)
+	}
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("query failed: %v", err)}
+	}
+	defer rows.Close()
+	
+	columns, records, truncated, err := scanRows(rows, t.maxRows)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to read rows: %v", err)}
+	}
+	rows.Close()
+	
+	// Queries can still write, e.g. data-modifying CTEs in Postgres
+	if !t.readOnly {
+		if err := tx.Commit(); err != nil {
+			return &Result{Error: fmt.Sprintf("failed to commit: %v", err)}
+		}
+	}
+	
+	return &Result{
+		Data: map[string]interface{}{
+			"columns":   columns,
+			"rows":      records,
+			"row_count": len(records),
+			"truncated": truncated,
+		},
+		Metadata: map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+			"max_rows":    t.maxRows,
+		},
+	}
+}
+
+func (t *SQLTool) schema(ctx context.Context, table string) *Result {
+	var query string
+	var params []interface{}
+	switch t.driver {
+	case "postgres":
+		query = `SELECT table_schema, table_name, column_name, data_type, is_nullable = 'YES'
+			FROM information_schema.columns
+			WHERE table_schema NOT IN ('pg_catalog', 'information_schema')`
+		if table != "" {
+			query += ` AND table_name = $1`
+			params = append(params, table)
+		}
+		query += ` ORDER BY table_schema, table_name, ordinal_position`
+	case "mysql":
+		query = `SELECT table_schema, table_name, column_name, column_type, is_nullable = 'YES'
+			FROM information_schema.columns
+			WHERE table_schema = DATABASE()`
+		if table != "" {
+			query += ` AND table_name = ?`
+			params = append(params, table)
+		}
+		query += ` ORDER BY table_name, ordinal_position`
+	case "sqlite":
+		query = `SELECT 'main', m.name, p.name, p.type, p."notnull" = 0
+			FROM sqlite_master m JOIN pragma_table_info(m.name) p
+			WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite_%'`
+		if table != "" {
+			query += ` AND m.name = ?`
+			params = append(params, table)
+		}
+		query += ` ORDER BY m.name, p.cid`
+	}
+	
+	rows, err := t.db.QueryContext(ctx, query, params...)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to read schema: %v", err)}
+	}
+	defer rows.Close()
+	
+	type column struct {
+		Name     string `json:"name"`
+		Type     string `json:"type"`
+		Nullable bool   `json:"nullable"`
+	}
+	type tableInfo struct {
+		Schema  string   `json:"schema"`
+		Name    string   `json:"name"`
+		Columns []column `json:"columns"`
+	}
+	
+	tables := make([]*tableInfo, 0)
+	for rows.Next() {
+		var schema, name string
+		var col column
+		if err := rows.Scan(&schema, &name, &col.Name, &col.Type, &col.Nullable); err != nil {
+			return &Result{Error: fmt.Sprintf("failed to read schema: %v", err)}
+		}
+		if len(tables) == 0 || tables[len(tables)-1].Schema != schema || tables[len(tables)-1].Name != name {
+			tables = append(tables, &tableInfo{Schema: schema, Name: name})
+		}
+		current := tables[len(tables)-1]
+		current.Columns = append(current.Columns, col)
+	}
+	if err := rows.Err(); err != nil {
+		return &Result{Error: fmt.Sprintf("failed to read schema: %v", err)}
+	}
+	
+	return &Result{
+		Data: map[string]interface{}{
+			"tables": tables,
+		},
+	}
+}
+
+func (t *SQLTool) Close() error {
+	return t.db.Close()
+}
+
+// scanRows reads up to maxRows rows as column name to value maps.
+func scanRows(rows *sql.Rows, maxRows int) ([]string, []map[string]interface{}, bool, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	
+	records := make([]map[string]interface{}, 0)
+	truncated := false
+	for rows.Next() {
+		if len(records) >= maxRows {
+			truncated = true
+			break
+		}
+		
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, nil, false, err
+		}
+		
+		record := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			// Drivers return text columns as bytes
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			record[column] = values[i]
+		}
+		records = append(records, record)
+	}
+	
+	return columns, records, truncated, rows.Err()
+}
+
+// statementKeyword returns the statement's first keyword, upper-cased, and
+// rejects input holding more than one statement.
+func statementKeyword(query, driver string) (string, error) {
+	sanitized, err := sanitizeSQL(query, driver)
+	if err != nil {
+		return "", err
+	}
+	query = strings.TrimSpace(sanitized)
+	query = strings.TrimSuffix(query, ";")
+	if strings.Contains(query, ";") {
+		return "", fmt.Errorf("only a single statement is allowed")
+	}
+	
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", fmt.Errorf("query is empty")
+	}
+	return strings.ToUpper(strings.TrimLeft(fields[0], "(")), nil
+}
+
+// sanitizeSQL removes comments and blanks out the contents of quoted
+// literals and identifiers, so what remains can be checked for keywords and
+// statement separators. It follows the quoting of the driver's dialect:
+// Postgres dollar quotes, E'...' escape strings and nested comments, MySQL
+// backslash escapes and # comments, and SQLite [] identifiers. Input it
+// cannot follow to the end, such as an unterminated literal, is refused
+// rather than guessed at.
+func sanitizeSQL(query, driver string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--") && (driver != "mysql" || i+2 == len(query) || isSQLSpace(query[i+2])),
+			c == '#' && driver == "mysql":
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end
+			out.WriteByte(' ')
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if driver == "mysql" && strings.HasPrefix(query[i:], "/*!") {
+				// MySQL runs what these comments hold
+				return "", fmt.Errorf("executable comments are not allowed")
+			}
+			end, ok := blockCommentEnd(query, i, driver == "postgres")
+			if !ok {
+				return "", fmt.Errorf("unterminated comment")
+			}
+			i = end
+			out.WriteByte(' ')
+		case c == '$' && driver == "postgres" && (i == 0 || !isSQLIdentChar(query[i-1])):
+			tag := dollarTag(query[i:])
+			if tag == "" {
+				out.WriteByte(c)
+				i++
+				continue
+			}
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated dollar-quoted string")
+			}
+			out.WriteString(tag + strings.Repeat("_", end) + tag)
+			i += len(tag) + end + len(tag)
+		case c == '\'' || c == '"' || c == '`' || (c == '[' && driver == "sqlite"):
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			// Backslashes escape in MySQL strings, and in Postgres only in
+			// E'...' strings
+			escapes := (driver == "mysql" && c != '`') ||
+				(driver == "postgres" && c == '\'' && i > 0 && (query[i-1] == 'E' || query[i-1] == 'e') && (i == 1 || !isSQLIdentChar(query[i-2])))
+			end, ok := quoteEnd(query, i, closing, escapes)
+			if !ok {
+				return "", fmt.Errorf("unterminated quoted string")
+			}
+			out.WriteByte(c)
+			out.WriteString(strings.Repeat("_", end-i-1))
+			out.WriteByte(closing)
+			i = end + 1
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.String(), nil
+}
+
+// quoteEnd returns the index of the quote closing the one at start. A
+// doubled quote stands for itself, and so does an escaped one when
+// backslashes escape.
+func quoteEnd(query string, start int, closing byte, escapes bool) (int, bool) {
+	for i := start + 1; i < len(query); i++ {
+		switch {
+		case escapes && query[i] == '\\':
+			i++
+		case query[i] == closing:
+			if closing != ']' && i+1 < len(query) && query[i+1] == closing {
+				i++
+				continue
+			}
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// blockCommentEnd returns the index just after the /* */ comment at start.
+// Postgres comments nest.
+func blockCommentEnd(query string, start int, nested bool) (int, bool) {
+	depth := 0
+	for i := start; i+1 < len(query); i++ {
+		switch {
+		case query[i] == '/' && query[i+1] == '*':
+			if depth == 0 || nested {
+				depth++
+			}
+			i++
+		case query[i] == '*' && query[i+1] == '/':
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// dollarTag returns the Postgres dollar quote, such as $$ or $body$, that
+// query starts with, or empty when it starts with something else, such as
+// the $1 placeholder.
+func dollarTag(query string) string {
+	for i := 1; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '$':
+			return query[:i+1]
+		case c == '_' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		case c >= '0' && c <= '9' && i > 1:
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+func isSQLIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= 0x80 || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isSQLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+// postgresReadOnlyDSN has the server make every transaction of the
+// connection read-only by default.
+func postgresReadOnlyDSN(dsn string) (string, error) {
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " default_transaction_read_only=on", nil
+	}
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid dsn: %w", err)
+	}
+	values := parsed.Query()
+	values.Set("default_transaction_read_only", "on")
+	parsed.RawQuery = values.Encode()
+	return parsed.String(), nil
+}
+
+func intConfig(config map[string]string, key string, fallback int) (int, error) {
+	value := config[key]
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s: %q", key, value)
+	}
+	return n, nil
+}
\ No newline at end of file
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatementKeyword(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  string
+		query   string
+		keyword string
+		err     string
+	}{
+		{name: "select", driver: "postgres", query: "SELECT 1", keyword: "SELECT"},
+		{name: "trailing separator", driver: "postgres", query: "select 1;", keyword: "SELECT"},
+		{name: "separator in literal", driver: "postgres", query: "SELECT ';'", keyword: "SELECT"},
+		{name: "doubled quote", driver: "postgres", query: "SELECT 'it''s; fine'", keyword: "SELECT"},
+		{name: "placeholder", driver: "postgres", query: "SELECT * FROM t WHERE id = $1", keyword: "SELECT"},
+		{name: "dollar quote", driver: "postgres", query: "SELECT $$;$$", keyword: "SELECT"},
+		{name: "tagged dollar quote", driver: "postgres", query: "SELECT $body$ $$; $body$", keyword: "SELECT"},
+		{name: "escape string", driver: "postgres", query: `SELECT E'a\'; b'`, keyword: "SELECT"},
+		{name: "nested comment", driver: "postgres", query: "/* a /* b */ ; */ SELECT 1", keyword: "SELECT"},
+		{name: "leading comment", driver: "postgres", query: "-- DELETE\nSELECT 1", keyword: "SELECT"},
+		{name: "two statements", driver: "postgres", query: "SELECT 1; DROP TABLE t", err: "single statement"},
+		{name: "dollar quote hiding quote", driver: "postgres", query: "SELECT $$'$$; DROP TABLE t; --'", err: "single statement"},
+		{name: "escape string hiding quote", driver: "postgres", query: `SELECT E'a\'' ; COMMIT; DROP TABLE t; -- '`, err: "single statement"},
+		{name: "backslash in standard string", driver: "postgres", query: `SELECT 'a\'; DROP TABLE t; -- '`, err: "single statement"},
+		{name: "identifier ending in e", driver: "postgres", query: `SELECT name'a\'; DROP TABLE t; -- '`, err: "single statement"},
+		{name: "unterminated dollar quote", driver: "postgres", query: "SELECT $$; DROP TABLE t", err: "unterminated"},
+		{name: "unterminated literal", driver: "postgres", query: "SELECT '; DROP TABLE t", err: "unterminated"},
+		{name: "unterminated comment", driver: "postgres", query: "SELECT 1 /* ; DROP TABLE t", err: "unterminated"},
+		{name: "empty", driver: "postgres", query: " -- nothing", err: "empty"},
+		{name: "mysql backslash escape", driver: "mysql", query: `SELECT 'a\'; b'`, keyword: "SELECT"},
+		{name: "mysql hash comment", driver: "mysql", query: "SELECT 1 # '\n; DROP TABLE t", err: "single statement"},
+		{name: "mysql dashes without space", driver: "mysql", query: "SELECT 1 --'; DROP TABLE t; '", keyword: "SELECT"},
+		{name: "mysql executable comment", driver: "mysql", query: "SELECT 1 /*! ; DROP TABLE t */", err: "executable comments"},
+		{name: "sqlite bracket identifier", driver: "sqlite", query: "SELECT [a;b] FROM t", keyword: "SELECT"},
+		{name: "sqlite backslash", driver: "sqlite", query: `SELECT 'a\'; DROP TABLE t; -- '`, err: "single statement"},
+	}
+	
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keyword, err := statementKeyword(tt.query, tt.driver)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("statementKeyword(%q) = %q, %v; want error containing %q", tt.query, keyword, err, tt.err)
+				}
+				return
+			}
+			if err != nil || keyword != tt.keyword {
+				t.Fatalf("statementKeyword(%q) = %q, %v; want %q", tt.query, keyword, err, tt.keyword)
+			}
+		})
+	}
+}
+
+func TestPostgresReadOnlyDSN(t *testing.T) {
+	tests := []struct {
+		dsn  string
+		want string
+	}{
+		{dsn: "postgres://u@db/app?sslmode=require", want: "postgres://u@db/app?default_transaction_read_only=on&sslmode=require"},
+		{dsn: "host=db dbname=app", want: "host=db dbname=app default_transaction_read_only=on"},
+	}
+	
+	for _, tt := range tests {
+		got, err := postgresReadOnlyDSN(tt.dsn)
+		if err != nil || got != tt.want {
+			t.Errorf("postgresReadOnlyDSN(%q) = %q, %v; want %q", tt.dsn, got, err, tt.want)
+		}
+	}
+}
\ No newline at end of file
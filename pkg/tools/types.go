@@ -3,7 +3,10 @@ package tools
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
+
+	"github.com/goagents/goagents/pkg/files"
 )
 
 type Tool interface {
@@ -13,6 +16,28 @@ type Tool interface {
 	Close() error
 }
 
+// Definition describes a tool to a model: its name, what it does and a JSON
+// schema for its arguments.
+type Definition struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Parameters  map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Describer is implemented by tools that can describe their own arguments,
+// such as tools discovered on an MCP server.
+type Describer interface {
+	Definition() Definition
+}
+
+// Connector is implemented by tools that hold a connection, such as to an
+// MCP server. Connect opens it ahead of the first call; Disconnect releases
+// it while the tool is not needed, and the next call opens it again.
+type Connector interface {
+	Connect(ctx context.Context) error
+	Disconnect() error
+}
+
 type Result struct {
 	Data     interface{}            `json:"data"`
 	Error    string                 `json:"error,omitempty"`
@@ -20,16 +45,31 @@ type Result struct {
 }
 
 type Config struct {
-	Type     string            `json:"type"`
-	Name     string            `json:"name"`
-	URL      string            `json:"url,omitempty"`
-	Endpoint string            `json:"endpoint,omitempty"`
-	Server   string            `json:"server,omitempty"`
-	Auth     *AuthConfig       `json:"auth,omitempty"`
-	Config   map[string]string `json:"config,omitempty"`
-	Timeout  time.Duration     `json:"timeout,omitempty"`
+	Type      string            `json:"type"`
+	Name      string            `json:"name"`
+	URL       string            `json:"url,omitempty"`
+	Endpoint  string            `json:"endpoint,omitempty"`
+	Server    string            `json:"server,omitempty"`
+	Transport string            `json:"transport,omitempty"`
+	Command   []string          `json:"command,omitempty"`
+	Args      []string          `json:"args,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Auth      *AuthConfig       `json:"auth,omitempty"`
+	Config    map[string]string `json:"config,omitempty"`
+	Timeout   time.Duration     `json:"timeout,omitempty"`
+	// Exec is set for exec tools once the exec policy allows them
+	Exec *ExecConfig `json:"-"`
+	// Files stores binary responses so they can be returned by reference
+	Files files.Store `json:"-"`
+	// Embed is set for vector_search tools to embed text with a provider
+	Embed EmbedFunc `json:"-"`
+	// Outbound limits the hosts http and browser tools may reach
+	Outbound *OutboundPolicy `json:"-"`
 }
 
+// EmbedFunc turns texts into vectors, one per text, in order.
+type EmbedFunc func(ctx context.Context, texts []string) ([][]float32, error)
+
 type AuthConfig struct {
 	Type   string `json:"type"`
 	Token  string `json:"token,omitempty"`
@@ -37,43 +77,296 @@ type AuthConfig struct {
 	Secret string `json:"secret,omitempty"`
 }
 
+// Key identifies a registered tool. Tools are scoped, usually to the agent
+// that uses them, so agents can each have their own tool of the same name.
+type Key struct {
+	Scope string
+	Name  string
+}
+
 type Manager struct {
-	tools map[string]Tool
+	tools    map[Key]Tool
+	guards   map[Key]*InjectionGuard
+	ttls     map[Key]time.Duration
+	cache    *resultCache
+	policies map[Key]*RetryPolicy
+	schemas  map[Key]*Schema
+	limits   map[Key]semaphore
+	dryRuns  map[Key]bool
+	// workers bounds the calls running at once across all tools
+	workers semaphore
+	// middleware wraps every call, the first entry outermost
+	middleware []ToolMiddleware
+	mu         sync.RWMutex
 }
 
 func NewManager() *Manager {
 	return &Manager{
-		tools: make(map[string]Tool),
+		tools:    make(map[Key]Tool),
+		guards:   make(map[Key]*InjectionGuard),
+		ttls:     make(map[Key]time.Duration),
+		cache:    newResultCache(defaultResultCacheEntries),
+		policies: make(map[Key]*RetryPolicy),
+		schemas:  make(map[Key]*Schema),
+		limits:   make(map[Key]semaphore),
+		dryRuns:  make(map[Key]bool),
+		workers:  newSemaphore(DefaultMaxConcurrency),
 	}
 }
 
-func (m *Manager) RegisterTool(tool Tool) {
-	m.tools[tool.Name()] = tool
+// SetMaxConcurrency bounds how many tool calls run at once across all
+// tools. Further calls wait for a free worker until their context is done.
+// Zero or less removes the bound.
+func (m *Manager) SetMaxConcurrency(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.workers = newSemaphore(n)
 }
 
-func (m *Manager) GetTool(name string) (Tool, bool) {
-	tool, exists := m.tools[name]
+// Use appends middleware to the chain every call goes through.
+func (m *Manager) Use(middleware ...ToolMiddleware) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.middleware = append(m.middleware, middleware...)
+}
+
+// RegisterTool adds a tool to a scope under its own name, replacing any
+// tool of that name already there.
+func (m *Manager) RegisterTool(scope string, tool Tool) Key {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	key := Key{Scope: scope, Name: tool.Name()}
+	m.tools[key] = tool
+	return key
+}
+
+// Replace swaps the registered tool of key for tool and returns the one it
+// replaced. Nothing is registered when key has no tool, such as when its
+// scope was removed in the meantime.
+func (m *Manager) Replace(key Key, tool Tool) (Tool, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	old, ok := m.tools[key]
+	if ok {
+		m.tools[key] = tool
+	}
+	return old, ok
+}
+
+// RemoveTool unregisters the tool of key, without closing it, and returns
+// it.
+func (m *Manager) RemoveTool(key Key) (Tool, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	tool, ok := m.tools[key]
+	delete(m.tools, key)
+	delete(m.guards, key)
+	delete(m.ttls, key)
+	delete(m.policies, key)
+	delete(m.schemas, key)
+	delete(m.limits, key)
+	delete(m.dryRuns, key)
+	return tool, ok
+}
+
+// DryRun makes every call of the tool a dry run when enabled, such as to
+// try agents against production tools without side effects.
+func (m *Manager) DryRun(key Key, enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if enabled {
+		m.dryRuns[key] = true
+	} else {
+		delete(m.dryRuns, key)
+	}
+}
+
+// Guard screens every result of the tool for prompt injection before
+// Execute returns it.
+func (m *Manager) Guard(key Key, guard *InjectionGuard) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.guards[key] = guard
+}
+
+// Cache keeps successful results of the tool for ttl, so a repeated call
+// with the same arguments in the same conversation is answered without
+// running the tool. Only idempotent tools should be cached.
+func (m *Manager) Cache(key Key, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if ttl > 0 {
+		m.ttls[key] = ttl
+	} else {
+		delete(m.ttls, key)
+	}
+}
+
+// Retry sets how Execute retries the tool and bounds each attempt. A nil
+// policy runs the tool once with no deadline of its own.
+func (m *Manager) Retry(key Key, policy *RetryPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if policy != nil {
+		m.policies[key] = policy
+	} else {
+		delete(m.policies, key)
+	}
+}
+
+// Validate checks the arguments of every call to the tool against schema
+// before it runs. Calls that do not match fail without running the tool.
+func (m *Manager) Validate(key Key, schema *Schema) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if schema != nil {
+		m.schemas[key] = schema
+	} else {
+		delete(m.schemas, key)
+	}
+}
+
+// Limit bounds how many calls of the tool run at once, such as to protect
+// the service behind it. Zero or less removes the bound.
+func (m *Manager) Limit(key Key, maxConcurrency int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	if maxConcurrency > 0 {
+		m.limits[key] = newSemaphore(maxConcurrency)
+	} else {
+		delete(m.limits, key)
+	}
+}
+
+func (m *Manager) GetTool(key Key) (Tool, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	tool, exists := m.tools[key]
 	return tool, exists
 }
 
-func (m *Manager) ListTools() []Tool {
-	tools := make([]Tool, 0, len(m.tools))
-	for _, tool := range m.tools {
-		tools = append(tools, tool)
+// ListTools returns the tools registered in a scope.
+func (m *Manager) ListTools(scope string) []Tool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
+	tools := make([]Tool, 0)
+	for key, tool := range m.tools {
+		if key.Scope == scope {
+			tools = append(tools, tool)
+		}
 	}
 	return tools
 }
 
-func (m *Manager) Execute(ctx context.Context, name string, args map[string]interface{}) (*Result, error) {
-	tool, exists := m.tools[name]
+// Execute runs a tool call through the middleware chain, then the argument
+// schema and result cache. Calls that are not cached wait for a free
+// worker and a free slot of the tool, then run with the retry policy and
+// prompt injection guard.
+func (m *Manager) Execute(ctx context.Context, key Key, args map[string]interface{}) (*Result, error) {
+	m.mu.RLock()
+	tool, exists := m.tools[key]
+	middleware := m.middleware
+	m.mu.RUnlock()
 	if !exists {
-		return &Result{Error: "tool not found: " + name}, nil
+		return &Result{Error: "tool not found: " + key.Name}, nil
+	}
+	
+	execute := func(ctx context.Context, args map[string]interface{}) (*Result, error) {
+		return m.execute(ctx, key, tool, args)
+	}
+	return wrapExecute(key, tool, execute, middleware)(ctx, args)
+}
+
+func (m *Manager) execute(ctx context.Context, key Key, tool Tool, args map[string]interface{}) (*Result, error) {
+	m.mu.RLock()
+	guard, ttl, policy, schema := m.guards[key], m.ttls[key], m.policies[key], m.schemas[key]
+	limit, workers, dryRunOnly := m.limits[key], m.workers, m.dryRuns[key]
+	m.mu.RUnlock()
+	
+	if err := schema.Validate(args); err != nil {
+		return &Result{
+			Error:    fmt.Sprintf("invalid arguments for tool %s: %v", key.Name, err),
+			Metadata: map[string]interface{}{"invalid_arguments": true},
+		}, nil
+	}
+	
+	// Dry runs have no side effects to retry, limit or cache
+	if dryRunOnly || IsDryRun(ctx) {
+		return dryRun(ctx, tool, args)
+	}
+	
+	cacheKey, cacheable := "", false
+	if ttl > 0 {
+		cacheKey, cacheable = resultCacheKey(ctx, key, args)
+		if cacheable {
+			if cached, ok := m.cache.get(cacheKey); ok {
+				return cached, nil
+			}
+		}
+	}
+	
+	release, err := acquireSlots(ctx, key, limit, workers)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
 	}
+	result, err := policy.execute(ctx, tool, args)
+	release()
+	if err != nil {
+		return nil, err
+	}
+	result = guard.Inspect(result)
+	if cacheable && result.Error == "" {
+		m.cache.set(cacheKey, result, ttl)
+	}
+	return result, nil
+}
+
+// RemoveScope closes and unregisters every tool in a scope, such as when
+// the agent owning them is deleted.
+func (m *Manager) RemoveScope(scope string) error {
+	m.mu.Lock()
+	var removed []Tool
+	for key, tool := range m.tools {
+		if key.Scope != scope {
+			continue
+		}
+		removed = append(removed, tool)
+		delete(m.tools, key)
+		delete(m.guards, key)
+		delete(m.ttls, key)
+		delete(m.policies, key)
+		delete(m.schemas, key)
+		delete(m.limits, key)
+		delete(m.dryRuns, key)
+	}
+	m.mu.Unlock()
 	
-	return tool.Execute(ctx, args)
+	var firstErr error
+	for _, tool := range removed {
+		if err := tool.Close(); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("failed to close tool %s: %w", tool.Name(), err)
+		}
+	}
+	return firstErr
 }
 
 func (m *Manager) Close() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	
 	for _, tool := range m.tools {
 		if err := tool.Close(); err != nil {
 			return err
@@ -90,6 +383,38 @@ func CreateTool(config *Config) (Tool, error) {
 		return NewWebSocketTool(config)
 	case "mcp":
 		return NewMCPTool(config)
+	case "exec":
+		return NewExecTool(config)
+	case "sql":
+		return NewSQLTool(config)
+	case "browser":
+		return NewBrowserTool(config)
+	case "code":
+		return NewCodeTool(config)
+	case "filesystem":
+		return NewFilesystemTool(config)
+	case "openapi":
+		return NewOpenAPITool(config)
+	case "graphql":
+		return NewGraphQLTool(config)
+	case "builtin":
+		return NewBuiltinTool(config)
+	case "web_search":
+		return NewWebSearchTool(config)
+	case "vector_search":
+		return NewVectorSearchTool(config)
+	case "kubernetes":
+		return NewKubernetesTool(config)
+	case "slack":
+		return NewSlackTool(config)
+	case "email":
+		return NewEmailTool(config)
+	case "objectstore":
+		return NewObjectStoreTool(config)
+	case "plugin":
+		return NewPluginTool(config)
+	case "wasm":
+		return NewWasmTool(config)
 	default:
 		return nil, fmt.Errorf("unsupported tool type: %s", config.Type)
 	}
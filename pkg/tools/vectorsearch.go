@@ -0,0 +1,703 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultVectorResults = 5
+	maxVectorResults     = 50
+	// maxVectorResponse bounds the store response that is read
+	maxVectorResponse = 8 << 20
+	// embedBatchSize is how many documents the memory store embeds per call
+	embedBatchSize = 100
+)
+
+// VectorMatch is one chunk returned by a vector store, in the same form for
+// every store. Score is the store's similarity, where higher is closer.
+type VectorMatch struct {
+	ID       string                 `json:"id"`
+	Score    float64                `json:"score"`
+	Content  string                 `json:"content"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// vectorStore finds the chunks nearest to a query vector.
+type vectorStore interface {
+	search(ctx context.Context, vector []float32, topK int) ([]VectorMatch, error)
+	close() error
+}
+
+var vectorStores = map[string]func(t *VectorSearchTool) (vectorStore, error){
+	"pgvector": newPGVectorStore,
+	"qdrant":   newQdrantStore,
+	"milvus":   newMilvusStore,
+	"memory":   newMemoryVectorStore,
+}
+
+// VectorSearchTool embeds a query with a provider and returns the closest
+// chunks from a vector store, so agents can retrieve documents without a
+// custom HTTP tool. The store is set by the store config entry; the engine
+// supplies Embed from the provider and embedding_model config entries.
+type VectorSearchTool struct {
+	config       *Config
+	client       *http.Client
+	storeName    string
+	store        vectorStore
+	embed        EmbedFunc
+	topK         int
+	minScore     float64
+	contentField string
+}
+
+func NewVectorSearchTool(config *Config) (*VectorSearchTool, error) {
+	storeName := strings.ToLower(config.Config["store"])
+	newStore, ok := vectorStores[storeName]
+	if !ok {
+		return nil, fmt.Errorf("vector_search tool requires store to be one of: %s", strings.Join(VectorStoreNames(), ", "))
+	}
+	if config.Embed == nil {
+		return nil, fmt.Errorf("vector_search tool requires an embedding provider")
+	}
+	
+	topK, err := intConfig(config.Config, "top_k", defaultVectorResults)
+	if err != nil {
+		return nil, err
+	}
+	if topK > maxVectorResults {
+		return nil, fmt.Errorf("top_k may not exceed %d", maxVectorResults)
+	}
+	
+	var minScore float64
+	if value := config.Config["min_score"]; value != "" {
+		minScore, err = strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid min_score: %w", err)
+		}
+	}
+	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	t := &VectorSearchTool{
+		config:       config,
+		client:       &http.Client{Timeout: timeout},
+		storeName:    storeName,
+		embed:        config.Embed,
+		topK:         topK,
+		minScore:     minScore,
+		contentField: configOrDefault(config.Config, "content_field", "content"),
+	}
+	
+	t.store, err = newStore(t)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// VectorStoreNames lists the supported stores in alphabetical order.
+func VectorStoreNames() []string {
+	names := make([]string, 0, len(vectorStores))
+	for name := range vectorStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *VectorSearchTool) Name() string {
+	return t.config.Name
+}
+
+func (t *VectorSearchTool) Type() string {
+	return "vector_search"
+}
+
+func (t *VectorSearchTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = "Search a document collection by meaning. Returns the most relevant chunks with their similarity scores."
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "What to look for, in natural language",
+				},
+				"top_k": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("How many chunks to return, at most %d", t.topK),
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+}
+
+func (t *VectorSearchTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	query, _ := args["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return &Result{Error: "query is required"}, nil
+	}
+	
+	topK, err := numberArg(args, "top_k", float64(t.topK))
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	if topK < 1 || topK > float64(t.topK) || topK != math.Trunc(topK) {
+		return &Result{Error: fmt.Sprintf("top_k must be a whole number from 1 to %d", t.topK)}, nil
+	}
+	
+	start := time.Now()
+	vectors, err := t.embed(ctx, []string{query})
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to embed query: %v", err)}, nil
+	}
+	if len(vectors) == 0 || len(vectors[0]) == 0 {
+		return &Result{Error: "embedding provider returned no vector for the query"}, nil
+	}
+	
+	matches, err := t.store.search(ctx, vectors[0], int(topK))
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("%s search failed: %v", t.storeName, err)}, nil
+	}
+	
+	results := make([]VectorMatch, 0, len(matches))
+	for _, match := range matches {
+		if match.Score < t.minScore {
+			continue
+		}
+		results = append(results, match)
+		if len(results) == int(topK) {
+			break
+		}
+	}
+	
+	return &Result{
+		Data: map[string]interface{}{
+			"query":   query,
+			"matches": results,
+		},
+		Metadata: map[string]interface{}{
+			"store":       t.storeName,
+			"count":       len(results),
+			"duration_ms": time.Since(start).Milliseconds(),
+		},
+	}, nil
+}
+
+func (t *VectorSearchTool) Close() error {
+	return t.store.close()
+}
+
+// apiKey returns the tool's api_key, or its token.
+func (t *VectorSearchTool) apiKey() string {
+	if t.config.Auth == nil {
+		return ""
+	}
+	if t.config.Auth.APIKey != "" {
+		return t.config.Auth.APIKey
+	}
+	return t.config.Auth.Token
+}
+
+// postJSON sends payload to endpoint and returns the response body, or an
+// error carrying the store's message for responses of 400 and above.
+func (t *VectorSearchTool) postJSON(ctx context.Context, endpoint string, payload interface{}, headers map[string]string) ([]byte, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "goagents/1.0")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxVectorResponse))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, vectorErrorMessage(respBody))
+	}
+	return respBody, nil
+}
+
+// vectorErrorMessage also understands Qdrant's {"status": {"error": ...}}.
+func vectorErrorMessage(body []byte) string {
+	var payload struct {
+		Status struct {
+			Error string `json:"error"`
+		} `json:"status"`
+	}
+	if json.Unmarshal(body, &payload) == nil && payload.Status.Error != "" {
+		return payload.Status.Error
+	}
+	return searchErrorMessage(body)
+}
+
+// storeURL returns the tool's URL, which HTTP stores require.
+func (t *VectorSearchTool) storeURL() (string, error) {
+	if t.config.URL == "" {
+		return "", fmt.Errorf("URL is required for %s vector search", t.storeName)
+	}
+	if _, err := url.Parse(t.config.URL); err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	return strings.TrimSuffix(t.config.URL, "/"), nil
+}
+
+func matchID(id interface{}) string {
+	switch v := id.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// splitContent takes the content field out of a store's fields, leaving the
+// rest as metadata.
+func splitContent(fields map[string]interface{}, contentField string) (string, map[string]interface{}) {
+	content, _ := fields[contentField].(string)
+	metadata := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if key != contentField {
+			metadata[key] = value
+		}
+	}
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	return content, metadata
+}
+
+var sqlIdentifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// pgVectorStore queries a Postgres table with the pgvector extension by
+// cosine distance.
+type pgVectorStore struct {
+	db    *sql.DB
+	query string
+}
+
+func newPGVectorStore(t *VectorSearchTool) (vectorStore, error) {
+	dsn := t.config.Config["dsn"]
+	if dsn == "" {
+		return nil, fmt.Errorf("dsn is required for pgvector vector search")
+	}
+	
+	identifiers := map[string]string{
+		"table":            t.config.Config["table"],
+		"id_column":        configOrDefault(t.config.Config, "id_column", "id"),
+		"content_field":    t.contentField,
+		"embedding_column": configOrDefault(t.config.Config, "embedding_column", "embedding"),
+		"metadata_column":  t.config.Config["metadata_column"],
+	}
+	if identifiers["table"] == "" {
+		return nil, fmt.Errorf("table is required for pgvector vector search")
+	}
+	for key, identifier := range identifiers {
+		if identifier != "" && !sqlIdentifierPattern.MatchString(identifier) {
+			return nil, fmt.Errorf("invalid %s %q", key, identifier)
+		}
+	}
+	
+	metadata := "NULL"
+	if identifiers["metadata_column"] != "" {
+		metadata = identifiers["metadata_column"] + "::text"
+	}
+	embedding := identifiers["embedding_column"]
+	query := fmt.Sprintf(
+		"SELECT %s::text, %s, %s, 1 - (%s <=> $1::vector) AS score FROM %s ORDER BY %s <=> $1::vector LIMIT $2",
+		identifiers["id_column"], identifiers["content_field"], metadata, embedding, identifiers["table"], embedding)
+		
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(5)
+	db.SetMaxIdleConns(2)
+	db.SetConnMaxLifetime(30 * time.Minute)
+	
+	return &pgVectorStore{db: db, query: query}, nil
+}
+
+func (s *pgVectorStore) search(ctx context.Context, vector []float32, topK int) ([]VectorMatch, error) {
+	rows, err := s.db.QueryContext(ctx, s.query, formatVector(vector), topK)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	
+	var matches []VectorMatch
+	for rows.Next() {
+		var match VectorMatch
+		var content, metadata sql.NullString
+		if err := rows.Scan(&match.ID, &content, &metadata, &match.Score); err != nil {
+			return nil, err
+		}
+		match.Content = content.String
+		if metadata.Valid && metadata.String != "" {
+			if err := json.Unmarshal([]byte(metadata.String), &match.Metadata); err != nil {
+				match.Metadata = map[string]interface{}{"value": metadata.String}
+			}
+		}
+		matches = append(matches, match)
+	}
+	return matches, rows.Err()
+}
+
+func (s *pgVectorStore) close() error {
+	return s.db.Close()
+}
+
+// formatVector writes a vector in pgvector's text form, [1,2,3].
+func formatVector(vector []float32) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	for i, value := range vector {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatFloat(float64(value), 'g', -1, 32))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// qdrantStore searches a Qdrant collection through its REST API. Each
+// point's payload holds the chunk's content and metadata.
+type qdrantStore struct {
+	tool       *VectorSearchTool
+	endpoint   string
+	vectorName string
+}
+
+func newQdrantStore(t *VectorSearchTool) (vectorStore, error) {
+	baseURL, err := t.storeURL()
+	if err != nil {
+		return nil, err
+	}
+	collection := t.config.Config["collection"]
+	if collection == "" {
+		return nil, fmt.Errorf("collection is required for qdrant vector search")
+	}
+	
+	return &qdrantStore{
+		tool:       t,
+		endpoint:   baseURL + "/collections/" + url.PathEscape(collection) + "/points/search",
+		vectorName: t.config.Config["vector_name"],
+	}, nil
+}
+
+func (s *qdrantStore) search(ctx context.Context, vector []float32, topK int) ([]VectorMatch, error) {
+	var query interface{} = vector
+	if s.vectorName != "" {
+		query = map[string]interface{}{"name": s.vectorName, "vector": vector}
+	}
+	
+	headers := map[string]string{}
+	if apiKey := s.tool.apiKey(); apiKey != "" {
+		headers["api-key"] = apiKey
+	}
+	
+	body, err := s.tool.postJSON(ctx, s.endpoint, map[string]interface{}{
+		"vector":       query,
+		"limit":        topK,
+		"with_payload": true,
+	}, headers)
+	if err != nil {
+		return nil, err
+	}
+	
+	var resp struct {
+		Result []struct {
+			ID      interface{}            `json:"id"`
+			Score   float64                `json:"score"`
+			Payload map[string]interface{} `json:"payload"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	
+	matches := make([]VectorMatch, 0, len(resp.Result))
+	for _, point := range resp.Result {
+		content, metadata := splitContent(point.Payload, s.tool.contentField)
+		matches = append(matches, VectorMatch{
+			ID:       matchID(point.ID),
+			Score:    point.Score,
+			Content:  content,
+			Metadata: metadata,
+		})
+	}
+	return matches, nil
+}
+
+func (s *qdrantStore) close() error {
+	return nil
+}
+
+// milvusStore searches a Milvus collection through the v2 REST API. The
+// collection's metric should be COSINE or IP so that higher distances are
+// closer matches.
+type milvusStore struct {
+	tool            *VectorSearchTool
+	endpoint        string
+	collection      string
+	database        string
+	embeddingColumn string
+}
+
+func newMilvusStore(t *VectorSearchTool) (vectorStore, error) {
+	baseURL, err := t.storeURL()
+	if err != nil {
+		return nil, err
+	}
+	collection := t.config.Config["collection"]
+	if collection == "" {
+		return nil, fmt.Errorf("collection is required for milvus vector search")
+	}
+	
+	return &milvusStore{
+		tool:            t,
+		endpoint:        baseURL + "/v2/vectordb/entities/search",
+		collection:      collection,
+		database:        t.config.Config["database"],
+		embeddingColumn: t.config.Config["embedding_column"],
+	}, nil
+}
+
+func (s *milvusStore) search(ctx context.Context, vector []float32, topK int) ([]VectorMatch, error) {
+	payload := map[string]interface{}{
+		"collectionName": s.collection,
+		"data":           [][]float32{vector},
+		"limit":          topK,
+		"outputFields":   []string{"*"},
+	}
+	if s.database != "" {
+		payload["dbName"] = s.database
+	}
+	if s.embeddingColumn != "" {
+		payload["annsField"] = s.embeddingColumn
+	}
+	
+	headers := map[string]string{}
+	if apiKey := s.tool.apiKey(); apiKey != "" {
+		headers["Authorization"] = "Bearer " + apiKey
+	}
+	
+	body, err := s.tool.postJSON(ctx, s.endpoint, payload, headers)
+	if err != nil {
+		return nil, err
+	}
+	
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var resp struct {
+		Code    int                      `json:"code"`
+		Message string                   `json:"message"`
+		Data    []map[string]interface{} `json:"data"`
+	}
+	if err := decoder.Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	// Milvus reports errors with status 200 and a non-zero code
+	if resp.Code != 0 {
+		return nil, fmt.Errorf("code %d: %s", resp.Code, resp.Message)
+	}
+	
+	matches := make([]VectorMatch, 0, len(resp.Data))
+	for _, hit := range resp.Data {
+		match := VectorMatch{ID: matchID(hit["id"])}
+		if distance, ok := hit["distance"].(json.Number); ok {
+			match.Score, _ = distance.Float64()
+		}
+		fields := make(map[string]interface{}, len(hit))
+		for key, value := range hit {
+			if key != "id" && key != "distance" && key != s.embeddingColumn {
+				fields[key] = value
+			}
+		}
+		match.Content, match.Metadata = splitContent(fields, s.tool.contentField)
+		matches = append(matches, match)
+	}
+	return matches, nil
+}
+
+func (s *milvusStore) close() error {
+	return nil
+}
+
+// vectorDocument is a chunk in the memory store's file.
+type vectorDocument struct {
+	ID        string                 `json:"id"`
+	Content   string                 `json:"content"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Embedding []float32              `json:"embedding,omitempty"`
+}
+
+// memoryVectorStore holds the chunks from a JSON file in memory and ranks
+// them by cosine similarity. Chunks without an embedding are embedded with
+// the tool's provider on the first search.
+type memoryVectorStore struct {
+	embed     EmbedFunc
+	documents []vectorDocument
+	embedded  bool
+	mu        sync.Mutex
+}
+
+func newMemoryVectorStore(t *VectorSearchTool) (vectorStore, error) {
+	path := t.config.Config["path"]
+	if path == "" {
+		return nil, fmt.Errorf("path is required for memory vector search")
+	}
+	
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+	var documents []vectorDocument
+	if err := json.Unmarshal(data, &documents); err != nil {
+		return nil, fmt.Errorf("failed to parse documents: %w", err)
+	}
+	for i := range documents {
+		if documents[i].ID == "" {
+			documents[i].ID = strconv.Itoa(i)
+		}
+	}
+	
+	return &memoryVectorStore{embed: t.embed, documents: documents}, nil
+}
+
+func (s *memoryVectorStore) search(ctx context.Context, vector []float32, topK int) ([]VectorMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	
+	if err := s.embedDocuments(ctx); err != nil {
+		return nil, err
+	}
+	
+	matches := make([]VectorMatch, 0, len(s.documents))
+	for _, document := range s.documents {
+		if len(document.Embedding) != len(vector) {
+			return nil, fmt.Errorf("document %s has %d dimensions, query has %d", document.ID, len(document.Embedding), len(vector))
+		}
+		matches = append(matches, VectorMatch{
+			ID:       document.ID,
+			Score:    CosineSimilarity(vector, document.Embedding),
+			Content:  document.Content,
+			Metadata: document.Metadata,
+		})
+	}
+	
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].Score > matches[j].Score })
+	if len(matches) > topK {
+		matches = matches[:topK]
+	}
+	return matches, nil
+}
+
+// embedDocuments fills in missing embeddings. A failed batch is retried on
+// the next search.
+func (s *memoryVectorStore) embedDocuments(ctx context.Context) error {
+	if s.embedded {
+		return nil
+	}
+	
+	var pending []int
+	for i, document := range s.documents {
+		if len(document.Embedding) == 0 {
+			pending = append(pending, i)
+		}
+	}
+	
+	for start := 0; start < len(pending); start += embedBatchSize {
+		end := start + embedBatchSize
+		if end > len(pending) {
+			end = len(pending)
+		}
+		batch := pending[start:end]
+		texts := make([]string, len(batch))
+		for i, index := range batch {
+			texts[i] = s.documents[index].Content
+		}
+		
+		vectors, err := s.embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed documents: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return fmt.Errorf("embedding provider returned %d vectors for %d documents", len(vectors), len(texts))
+		}
+		for i, index := range batch {
+			s.documents[index].Embedding = vectors[i]
+		}
+	}
+	
+	s.embedded = true
+	return nil
+}
+
+func (s *memoryVectorStore) close() error {
+	return nil
+}
+
+// CosineSimilarity scores how close two vectors point, from -1 to 1. Vectors
+// of zero length score 0.
+func CosineSimilarity(a, b []float32) float64 {
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
\ No newline at end of file
@@ -0,0 +1,268 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/goagents/goagents/pkg/config"
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	defaultWasmMaxMemory = 64 << 20
+	wasmPageSize         = 64 << 10
+	// maxWasmOutput bounds what a module may print to stdout and stderr,
+	// which is kept for error messages only
+	maxWasmOutput = 64 << 10
+)
+
+// WasmTool runs a tool compiled to WebAssembly, inside the engine and
+// sandboxed: a module sees only the memory it is given and, through WASI,
+// a clock and random numbers, but no files, network or environment.
+//
+// A module exports its memory, alloc(size i32) i32, which returns where
+// the engine may write size bytes, and execute(ptr i32, len i32) i64,
+// which takes the call's arguments as a JSON object at ptr and returns the
+// location of its JSON result, {"data": ..., "error": "..."}, as the
+// pointer in the upper 32 bits and the length in the lower ones. It may
+// export describe() i64, returning {"description": ..., "parameters": ...}
+// the same way. Each call runs in a fresh instance of the module.
+type WasmTool struct {
+	config     *Config
+	runtime    wazero.Runtime
+	module     wazero.CompiledModule
+	timeout    time.Duration
+	definition Definition
+}
+
+// wasmResult is what a module's execute returns.
+type wasmResult struct {
+	Data  interface{} `json:"data"`
+	Error string      `json:"error"`
+}
+
+func NewWasmTool(cfg *Config) (*WasmTool, error) {
+	path := cfg.Config["module"]
+	if path == "" {
+		return nil, fmt.Errorf("module is required for wasm tool")
+	}
+	binary, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wasm module: %w", err)
+	}
+	if value := cfg.Config["sha256"]; value != "" {
+		checksum, err := hex.DecodeString(value)
+		if err != nil || len(checksum) != sha256.Size {
+			return nil, fmt.Errorf("invalid sha256: %q", value)
+		}
+		if sum := sha256.Sum256(binary); !bytes.Equal(sum[:], checksum) {
+			return nil, fmt.Errorf("wasm module %s does not match its sha256", path)
+		}
+	}
+	
+	maxMemory := int64(defaultWasmMaxMemory)
+	if value := cfg.Config["max_memory"]; value != "" {
+		maxMemory, err = config.ParseByteSize(value)
+		if err != nil || maxMemory < wasmPageSize {
+			return nil, fmt.Errorf("invalid max_memory: %q", value)
+		}
+	}
+	
+	timeout := 30 * time.Second
+	if cfg.Timeout > 0 {
+		timeout = cfg.Timeout
+	}
+	
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	
+	// Calls that run out of time are stopped where they are
+	runtime := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(maxMemory/wasmPageSize)).
+		WithCloseOnContextDone(true))
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, runtime); err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to provide WASI: %w", err)
+	}
+	module, err := runtime.CompileModule(ctx, binary)
+	if err != nil {
+		runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile wasm module: %w", err)
+	}
+	for _, name := range []string{"alloc", "execute"} {
+		if _, ok := module.ExportedFunctions()[name]; !ok {
+			runtime.Close(ctx)
+			return nil, fmt.Errorf("wasm module %s does not export %s", path, name)
+		}
+	}
+	
+	t := &WasmTool{
+		config:  cfg,
+		runtime: runtime,
+		module:  module,
+		timeout: timeout,
+		definition: Definition{
+			Name:        cfg.Name,
+			Description: fmt.Sprintf("Run the %s tool", cfg.Name),
+			Parameters:  map[string]interface{}{"type": "object"},
+		},
+	}
+	if _, ok := module.ExportedFunctions()["describe"]; ok {
+		if err := t.describe(ctx); err != nil {
+			t.Close()
+			return nil, err
+		}
+	}
+	if value := cfg.Config["description"]; value != "" {
+		t.definition.Description = value
+	}
+	if value := cfg.Config["parameters"]; value != "" {
+		var parameters map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &parameters); err != nil {
+			t.Close()
+			return nil, fmt.Errorf("invalid parameters: %w", err)
+		}
+		t.definition.Parameters = parameters
+	}
+	return t, nil
+}
+
+// describe reads the description and parameters the module gives itself.
+func (t *WasmTool) describe(ctx context.Context) error {
+	var description struct {
+		Description string                 `json:"description"`
+		Parameters  map[string]interface{} `json:"parameters"`
+	}
+	output, err := t.call(ctx, "describe", nil)
+	if err == nil {
+		err = json.Unmarshal(output, &description)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to describe wasm module: %w", err)
+	}
+	if description.Description != "" {
+		t.definition.Description = description.Description
+	}
+	if description.Parameters != nil {
+		t.definition.Parameters = description.Parameters
+	}
+	return nil
+}
+
+func (t *WasmTool) Name() string {
+	return t.config.Name
+}
+
+func (t *WasmTool) Type() string {
+	return "wasm"
+}
+
+func (t *WasmTool) Definition() Definition {
+	return t.definition
+}
+
+func (t *WasmTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	
+	input, err := json.Marshal(args)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to encode arguments: %v", err)}, nil
+	}
+	
+	start := time.Now()
+	output, err := t.call(ctx, "execute", input)
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	var result wasmResult
+	if err := json.Unmarshal(output, &result); err != nil {
+		return &Result{Error: fmt.Sprintf("wasm module returned invalid JSON: %v", err)}, nil
+	}
+	return &Result{
+		Data:  result.Data,
+		Error: result.Error,
+		Metadata: map[string]interface{}{
+			"duration_ms": time.Since(start).Milliseconds(),
+		},
+	}, nil
+}
+
+// call runs an exported function in a fresh instance of the module,
+// passing input, when there is any, as a pointer and length, and returns
+// the bytes the function's packed result points to.
+func (t *WasmTool) call(ctx context.Context, function string, input []byte) ([]byte, error) {
+	output := &cappedBuffer{limit: maxWasmOutput}
+	instance, err := t.runtime.InstantiateModule(ctx, t.module, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStdout(output).
+		WithStderr(output).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader))
+	if err != nil {
+		return nil, wasmError("failed to start wasm module", err, output)
+	}
+	defer instance.Close(context.Background())
+	
+	var params []uint64
+	if input != nil {
+		results, err := instance.ExportedFunction("alloc").Call(ctx, uint64(len(input)))
+		if err != nil {
+			return nil, wasmError("wasm alloc failed", err, output)
+		}
+		ptr := uint32(results[0])
+		if !instance.Memory().Write(ptr, input) {
+			return nil, fmt.Errorf("wasm alloc returned memory out of range")
+		}
+		params = []uint64{uint64(ptr), uint64(len(input))}
+	}
+	
+	results, err := instance.ExportedFunction(function).Call(ctx, params...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("wasm %s timed out: %w", function, ctx.Err())
+		}
+		return nil, wasmError("wasm "+function+" failed", err, output)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("wasm %s returned no result", function)
+	}
+	ptr, size := uint32(results[0]>>32), uint32(results[0])
+	data, ok := instance.Memory().Read(ptr, size)
+	if !ok {
+		return nil, fmt.Errorf("wasm %s returned memory out of range", function)
+	}
+	// The instance's memory goes away when it is closed
+	return append([]byte(nil), data...), nil
+}
+
+// wasmError describes a failed call with what the module printed, if
+// anything.
+func wasmError(message string, err error, output *cappedBuffer) error {
+	var exit *sys.ExitError
+	if errors.As(err, &exit) {
+		err = fmt.Errorf("module exited with code %d", exit.ExitCode())
+	}
+	if printed := strings.TrimSpace(output.String()); printed != "" {
+		return fmt.Errorf("%s: %v: %s", message, err, printed)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
+func (t *WasmTool) Close() error {
+	return t.runtime.Close(context.Background())
+}
\ No newline at end of file
@@ -0,0 +1,475 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSearchResults = 5
+	maxSearchResults     = 20
+	// maxSearchResponse bounds the engine response that is read
+	maxSearchResponse = 4 << 20
+)
+
+// SearchResult is one web search hit, in the same form for every engine.
+type SearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet"`
+}
+
+// searchEngine builds a request for one search API and reads its results.
+type searchEngine struct {
+	baseURL string
+	// needsKey is false for engines such as SearxNG that run without one
+	needsKey bool
+	request  func(ctx context.Context, t *WebSearchTool, query string, count int) (*http.Request, error)
+	parse    func(body []byte) ([]SearchResult, error)
+}
+
+var searchEngines = map[string]searchEngine{
+	"brave": {
+		baseURL:  "https://api.search.brave.com/res/v1/web/search",
+		needsKey: true,
+		request:  braveRequest,
+		parse:    parseBraveResults,
+	},
+	"serpapi": {
+		baseURL:  "https://serpapi.com/search.json",
+		needsKey: true,
+		request:  serpAPIRequest,
+		parse:    parseSerpAPIResults,
+	},
+	"tavily": {
+		baseURL:  "https://api.tavily.com/search",
+		needsKey: true,
+		request:  tavilyRequest,
+		parse:    parseTavilyResults,
+	},
+	"searxng": {
+		request: searxNGRequest,
+		parse:   parseSearxNGResults,
+	},
+}
+
+// WebSearchTool searches the web through one of the engines above and
+// returns normalized results. The engine is set by the engine config entry
+// and authenticated with the tool's api_key or token; config.URL overrides
+// the engine's endpoint and is required for SearxNG, which is self-hosted.
+type WebSearchTool struct {
+	config     *Config
+	client     *http.Client
+	engineName string
+	engine     searchEngine
+	endpoint   string
+	apiKey     string
+	maxResults int
+	safeSearch bool
+}
+
+func NewWebSearchTool(config *Config) (*WebSearchTool, error) {
+	engineName := strings.ToLower(config.Config["engine"])
+	engine, ok := searchEngines[engineName]
+	if !ok {
+		return nil, fmt.Errorf("web_search tool requires engine to be one of: %s", strings.Join(SearchEngineNames(), ", "))
+	}
+	
+	endpoint := engine.baseURL
+	if config.URL != "" {
+		endpoint = config.URL
+	}
+	if endpoint == "" {
+		return nil, fmt.Errorf("URL is required for %s web search", engineName)
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	
+	var apiKey string
+	if config.Auth != nil {
+		apiKey = config.Auth.APIKey
+		if apiKey == "" {
+			apiKey = config.Auth.Token
+		}
+	}
+	if engine.needsKey && apiKey == "" {
+		return nil, fmt.Errorf("%s web search requires auth.api_key", engineName)
+	}
+	
+	maxResults, err := intConfig(config.Config, "max_results", defaultSearchResults)
+	if err != nil {
+		return nil, err
+	}
+	if maxResults > maxSearchResults {
+		return nil, fmt.Errorf("max_results may not exceed %d", maxSearchResults)
+	}
+	
+	safeSearch := true
+	if value := config.Config["safe_search"]; value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid safe_search: %w", err)
+		}
+		safeSearch = parsed
+	}
+	
+	timeout := 15 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	return &WebSearchTool{
+		config:     config,
+		client:     &http.Client{Timeout: timeout},
+		engineName: engineName,
+		engine:     engine,
+		endpoint:   endpoint,
+		apiKey:     apiKey,
+		maxResults: maxResults,
+		safeSearch: safeSearch,
+	}, nil
+}
+
+// SearchEngineNames lists the supported engines in alphabetical order.
+func SearchEngineNames() []string {
+	names := make([]string, 0, len(searchEngines))
+	for name := range searchEngines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (t *WebSearchTool) Name() string {
+	return t.config.Name
+}
+
+func (t *WebSearchTool) Type() string {
+	return "web_search"
+}
+
+func (t *WebSearchTool) Definition() Definition {
+	description := t.config.Config["description"]
+	if description == "" {
+		description = "Search the web. Returns the title, URL and a snippet of each result."
+	}
+	
+	return Definition{
+		Name:        t.config.Name,
+		Description: description,
+		Parameters: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "What to search for",
+				},
+				"max_results": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("How many results to return, at most %d", t.maxResults),
+				},
+			},
+			"required": []string{"query"},
+		},
+	}
+}
+
+func (t *WebSearchTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
+	query, _ := args["query"].(string)
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return &Result{Error: "query is required"}, nil
+	}
+	
+	count, err := numberArg(args, "max_results", float64(t.maxResults))
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	if count < 1 || count > float64(t.maxResults) || count != math.Trunc(count) {
+		return &Result{Error: fmt.Sprintf("max_results must be a whole number from 1 to %d", t.maxResults)}, nil
+	}
+	
+	req, err := t.engine.request(ctx, t, query, int(count))
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to create request: %v", err)}, nil
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "goagents/1.0")
+	
+	start := time.Now()
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("search failed: %v", err)}, nil
+	}
+	defer resp.Body.Close()
+	
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSearchResponse))
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to read response: %v", err)}, nil
+	}
+	if resp.StatusCode >= 400 {
+		return &Result{Error: fmt.Sprintf("%s returned HTTP %d: %s", t.engineName, resp.StatusCode, searchErrorMessage(body))}, nil
+	}
+	
+	results, err := t.engine.parse(body)
+	if err != nil {
+		return &Result{Error: fmt.Sprintf("failed to parse %s response: %v", t.engineName, err)}, nil
+	}
+	results = normalizeSearchResults(results, int(count))
+	
+	return &Result{
+		Data: map[string]interface{}{
+			"query":   query,
+			"results": results,
+		},
+		Metadata: map[string]interface{}{
+			"engine":      t.engineName,
+			"count":       len(results),
+			"duration_ms": time.Since(start).Milliseconds(),
+		},
+	}, nil
+}
+
+func (t *WebSearchTool) Close() error {
+	return nil
+}
+
+// normalizeSearchResults cleans up snippets, drops results without a URL
+// or seen before, and keeps at most count.
+func normalizeSearchResults(results []SearchResult, count int) []SearchResult {
+	normalized := make([]SearchResult, 0, count)
+	seen := make(map[string]bool)
+	for _, result := range results {
+		if result.URL == "" || seen[result.URL] {
+			continue
+		}
+		seen[result.URL] = true
+		result.Title = cleanSnippet(result.Title)
+		result.Snippet = cleanSnippet(result.Snippet)
+		normalized = append(normalized, result)
+		if len(normalized) == count {
+			break
+		}
+	}
+	return normalized
+}
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// cleanSnippet removes the highlighting markup some engines add and
+// collapses whitespace.
+func cleanSnippet(s string) string {
+	s = html.UnescapeString(htmlTagPattern.ReplaceAllString(s, ""))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// searchErrorMessage picks the message out of an engine's JSON error body,
+// falling back to the body itself.
+func searchErrorMessage(body []byte) string {
+	var payload map[string]interface{}
+	if json.Unmarshal(body, &payload) == nil {
+		for _, key := range []string{"error", "detail", "message"} {
+			switch v := payload[key].(type) {
+			case string:
+				return v
+			case map[string]interface{}:
+				for _, inner := range []string{"error", "detail", "message"} {
+					if message, ok := v[inner].(string); ok {
+						return message
+					}
+				}
+			}
+		}
+	}
+	message, _ := truncateText(strings.TrimSpace(string(body)), 500)
+	return message
+}
+
+func braveRequest(ctx context.Context, t *WebSearchTool, query string, count int) (*http.Request, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("count", strconv.Itoa(count))
+	if t.safeSearch {
+		params.Set("safesearch", "strict")
+	} else {
+		params.Set("safesearch", "off")
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, withQuery(t.endpoint, params), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Subscription-Token", t.apiKey)
+	return req, nil
+}
+
+func parseBraveResults(body []byte) ([]SearchResult, error) {
+	var resp struct {
+		Web struct {
+			Results []struct {
+				Title       string `json:"title"`
+				URL         string `json:"url"`
+				Description string `json:"description"`
+			} `json:"results"`
+		} `json:"web"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	
+	results := make([]SearchResult, 0, len(resp.Web.Results))
+	for _, r := range resp.Web.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Description})
+	}
+	return results, nil
+}
+
+func serpAPIRequest(ctx context.Context, t *WebSearchTool, query string, count int) (*http.Request, error) {
+	params := url.Values{}
+	params.Set("engine", configOrDefault(t.config.Config, "serpapi_engine", "google"))
+	params.Set("q", query)
+	params.Set("num", strconv.Itoa(count))
+	params.Set("api_key", t.apiKey)
+	if t.safeSearch {
+		params.Set("safe", "active")
+	} else {
+		params.Set("safe", "off")
+	}
+	
+	return http.NewRequestWithContext(ctx, http.MethodGet, withQuery(t.endpoint, params), nil)
+}
+
+func parseSerpAPIResults(body []byte) ([]SearchResult, error) {
+	var resp struct {
+		Error          string `json:"error"`
+		OrganicResults []struct {
+			Title   string `json:"title"`
+			Link    string `json:"link"`
+			Snippet string `json:"snippet"`
+		} `json:"organic_results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	// SerpAPI reports searches without results as an error with status 200
+	if resp.Error != "" && !strings.Contains(resp.Error, "hasn't returned any results") {
+		return nil, fmt.Errorf("%s", resp.Error)
+	}
+	
+	results := make([]SearchResult, 0, len(resp.OrganicResults))
+	for _, r := range resp.OrganicResults {
+		results = append(results, SearchResult{Title: r.Title, URL: r.Link, Snippet: r.Snippet})
+	}
+	return results, nil
+}
+
+func tavilyRequest(ctx context.Context, t *WebSearchTool, query string, count int) (*http.Request, error) {
+	payload, err := json.Marshal(map[string]interface{}{
+		"query":        query,
+		"max_results":  count,
+		"search_depth": configOrDefault(t.config.Config, "search_depth", "basic"),
+	})
+	if err != nil {
+		return nil, err
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	return req, nil
+}
+
+func parseTavilyResults(body []byte) ([]SearchResult, error) {
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	
+	results := make([]SearchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// searxNGRequest queries a SearxNG instance, which must have the json
+// format enabled in its settings.
+func searxNGRequest(ctx context.Context, t *WebSearchTool, query string, count int) (*http.Request, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("format", "json")
+	if t.safeSearch {
+		params.Set("safesearch", "2")
+	} else {
+		params.Set("safesearch", "0")
+	}
+	
+	endpoint := t.endpoint
+	if u, err := url.Parse(endpoint); err == nil && !strings.HasSuffix(u.Path, "/search") {
+		endpoint = strings.TrimSuffix(endpoint, "/") + "/search"
+	}
+	
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, withQuery(endpoint, params), nil)
+	if err != nil {
+		return nil, err
+	}
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+	return req, nil
+}
+
+func parseSearxNGResults(body []byte) ([]SearchResult, error) {
+	var resp struct {
+		Results []struct {
+			Title   string `json:"title"`
+			URL     string `json:"url"`
+			Content string `json:"content"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+	
+	results := make([]SearchResult, 0, len(resp.Results))
+	for _, r := range resp.Results {
+		results = append(results, SearchResult{Title: r.Title, URL: r.URL, Snippet: r.Content})
+	}
+	return results, nil
+}
+
+// withQuery adds params to endpoint, keeping any query it already has.
+func withQuery(endpoint string, params url.Values) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
\ No newline at end of file
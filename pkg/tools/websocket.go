@@ -2,18 +2,91 @@ package tools
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const (
+	defaultWebSocketPingInterval = 30 * time.Second
+	defaultWebSocketMaxMessage   = 1 << 20
+	defaultWebSocketBuffer       = 100
+	defaultWebSocketWait         = 5 * time.Second
+	maxWebSocketBackoff          = 30 * time.Second
+)
+
+// websocketOptions are config keys that configure the tool or the engine
+// rather than being sent as handshake headers.
+var websocketOptions = map[string]bool{
+	"subprotocol":        true,
+	"ping_interval":      true,
+	"max_message_size":   true,
+	"reconnect":          true,
+	"id_field":           true,
+	"subscription_field": true,
+	"buffer_size":        true,
+	"description":        true,
+	"cache_ttl":          true,
+	"max_concurrency":    true,
+	"prompt_injection":   true,
+	"retry_attempts":     true,
+	"retry_backoff":      true,
+	"retry_max_backoff":  true,
+	"retry_on":           true,
+	"dry_run":            true,
+}
+
+// WebSocketTool keeps a connection to a WebSocket server open and
+// multiplexes calls over it. Every outgoing message carries an id, and a
+// background read pump hands each incoming frame to the request with that
+// id, to the subscription it belongs to or, failing both, to a buffer of
+// unsolicited messages, so servers that push frames of their own do not
+// break request/reply calls. The connection is kept alive with pings and,
+// while there are subscriptions, re-established with backoff when it drops.
+//
+// Calls choose what to do with their "action" argument: request, the
+// default, sends the other arguments and waits for the reply; subscribe,
+// poll and unsubscribe manage subscriptions and read what they received.
 type WebSocketTool struct {
-	config *Config
-	conn   *websocket.Conn
-	mu     sync.RWMutex
+	config            *Config
+	timeout           time.Duration
+	pingInterval      time.Duration
+	maxMessageSize    int64
+	reconnect         bool
+	idField           string
+	subscriptionField string
+	bufferSize        int
+	subprotocols      []string
+	
+	mu            sync.Mutex
+	conn          *websocket.Conn
+	pending       map[string]chan websocketReply
+	subscriptions map[string]*websocketSubscription
+	unsolicited   *messageBuffer
+	reconnecting  bool
+	closed        bool
+	nextID        uint64
+	// writeMu serializes writes, which the connection allows one at a time
+	writeMu sync.Mutex
+}
+
+type websocketReply struct {
+	message interface{}
+	err     error
+}
+
+// websocketSubscription is an open subscription. Its subscribe message is
+// sent again after a reconnect.
+type websocketSubscription struct {
+	message map[string]interface{}
+	buffer  *messageBuffer
 }
 
 func NewWebSocketTool(config *Config) (*WebSocketTool, error) {
@@ -21,8 +94,58 @@ func NewWebSocketTool(config *Config) (*WebSocketTool, error) {
 		return nil, fmt.Errorf("endpoint is required for WebSocket tool")
 	}
 	
+	timeout := 30 * time.Second
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+	
+	pingInterval := defaultWebSocketPingInterval
+	if value := config.Config["ping_interval"]; value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid ping_interval: %q", value)
+		}
+		pingInterval = parsed
+	}
+	
+	maxMessageSize, err := intConfig(config.Config, "max_message_size", defaultWebSocketMaxMessage)
+	if err != nil {
+		return nil, err
+	}
+	bufferSize, err := intConfig(config.Config, "buffer_size", defaultWebSocketBuffer)
+	if err != nil {
+		return nil, err
+	}
+	
+	reconnect := true
+	if value := config.Config["reconnect"]; value != "" {
+		if reconnect, err = strconv.ParseBool(value); err != nil {
+			return nil, fmt.Errorf("invalid reconnect: %q", value)
+		}
+	}
+	
+	idField := config.Config["id_field"]
+	if idField == "" {
+		idField = "id"
+	}
+	subscriptionField := config.Config["subscription_field"]
+	if subscriptionField == "" {
+		subscriptionField = "subscription"
+	}
+	
 	return &WebSocketTool{
-		config: config,
+		config:            config,
+		timeout:           timeout,
+		pingInterval:      pingInterval,
+		maxMessageSize:    int64(maxMessageSize),
+		reconnect:         reconnect,
+		idField:           idField,
+		subscriptionField: subscriptionField,
+		bufferSize:        bufferSize,
+		subprotocols:      splitList(config.Config["subprotocol"]),
+		pending:           make(map[string]chan websocketReply),
+		subscriptions:     make(map[string]*websocketSubscription),
+		unsolicited:       newMessageBuffer(bufferSize),
 	}, nil
 }
 
@@ -35,86 +158,236 @@ func (t *WebSocketTool) Type() string {
 }
 
 func (t *WebSocketTool) Execute(ctx context.Context, args map[string]interface{}) (*Result, error) {
-	if err := t.ensureConnected(ctx); err != nil {
-		return &Result{Error: fmt.Sprintf("failed to connect: %v", err)}, nil
+	action, _ := args["action"].(string)
+	
+	var data interface{}
+	var err error
+	switch action {
+	case "", "request":
+		data, err = t.request(ctx, withoutKeys(args, "action"))
+	case "subscribe":
+		data, err = t.subscribe(ctx, args)
+	case "poll":
+		data, err = t.poll(ctx, args)
+	case "unsubscribe":
+		data, err = t.unsubscribe(ctx, args)
+	default:
+		err = fmt.Errorf("unknown action %q, expected request, subscribe, poll or unsubscribe", action)
+	}
+	if err != nil {
+		return &Result{Error: err.Error()}, nil
+	}
+	
+	return &Result{
+		Data: data,
+		Metadata: map[string]interface{}{
+			"endpoint": t.config.Endpoint,
+			"tool":     t.config.Name,
+		},
+	}, nil
+}
+
+// request sends data and waits for the frame that carries the same id.
+func (t *WebSocketTool) request(ctx context.Context, data map[string]interface{}) (interface{}, error) {
+	id := t.newID("msg")
+	replyCh := make(chan websocketReply, 1)
+	
+	t.mu.Lock()
+	t.pending[id] = replyCh
+	t.mu.Unlock()
+	defer func() {
+		t.mu.Lock()
+		delete(t.pending, id)
+		t.mu.Unlock()
+	}()
+	
+	if err := t.send(ctx, map[string]interface{}{
+		t.idField: id,
+		"type":    "request",
+		"data":    data,
+	}); err != nil {
+		return nil, err
 	}
 	
-	// Prepare message
+	responseCtx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	
+	select {
+	case <-responseCtx.Done():
+		return nil, fmt.Errorf("request timeout")
+	case reply := <-replyCh:
+		if reply.err != nil {
+			return nil, fmt.Errorf("failed to read response: %w", reply.err)
+		}
+		return reply.message, nil
+	}
+}
+
+// subscribe opens a subscription and returns the messages it receives
+// within the wait. The subscription stays open for later polls.
+func (t *WebSocketTool) subscribe(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	id := t.newID("sub")
 	message := map[string]interface{}{
-		"id":   fmt.Sprintf("msg-%d", time.Now().UnixNano()),
-		"type": "request",
-		"data": args,
+		t.idField: id,
+		"type":    "subscribe",
+		"data":    withoutKeys(args, "action", "wait", "max_messages"),
+	}
+	subscription := &websocketSubscription{
+		message: message,
+		buffer:  newMessageBuffer(t.bufferSize),
 	}
 	
 	t.mu.Lock()
-	err := t.conn.WriteJSON(message)
+	t.subscriptions[id] = subscription
 	t.mu.Unlock()
 	
-	if err != nil {
-		return &Result{Error: fmt.Sprintf("failed to send message: %v", err)}, nil
+	if err := t.send(ctx, message); err != nil {
+		t.mu.Lock()
+		delete(t.subscriptions, id)
+		t.mu.Unlock()
+		return nil, err
 	}
 	
-	// Wait for response
-	timeout := 30 * time.Second
-	if t.config.Timeout > 0 {
-		timeout = t.config.Timeout
+	return t.collect(ctx, id, subscription.buffer, args)
+}
+
+// poll returns the messages a subscription, or without one the
+// connection, received since the last poll, waiting for the first if there
+// are none yet.
+func (t *WebSocketTool) poll(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	id, _ := args["subscription"].(string)
+	if id == "" {
+		if err := t.ensureConnected(ctx); err != nil {
+			return nil, fmt.Errorf("failed to connect: %w", err)
+		}
+		return t.collect(ctx, "", t.unsolicited, args)
+	}
+	
+	t.mu.Lock()
+	subscription, ok := t.subscriptions[id]
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown subscription %q", id)
+	}
+	return t.collect(ctx, id, subscription.buffer, args)
+}
+
+func (t *WebSocketTool) unsubscribe(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	id, _ := args["subscription"].(string)
+	
+	t.mu.Lock()
+	_, ok := t.subscriptions[id]
+	delete(t.subscriptions, id)
+	t.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown subscription %q", id)
+	}
+	
+	if err := t.send(ctx, map[string]interface{}{
+		t.idField: t.newID("msg"),
+		"type":    "unsubscribe",
+		"data":    map[string]interface{}{t.subscriptionField: id},
+	}); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"subscription": id, "unsubscribed": true}, nil
+}
+
+// collect drains a buffer, waiting up to the wait argument, in seconds,
+// for a first message when it is empty.
+func (t *WebSocketTool) collect(ctx context.Context, id string, buffer *messageBuffer, args map[string]interface{}) (interface{}, error) {
+	wait, err := numberArg(args, "wait", defaultWebSocketWait.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	maxMessages, err := numberArg(args, "max_messages", float64(t.bufferSize))
+	if err != nil {
+		return nil, err
 	}
 	
-	responseCtx, cancel := context.WithTimeout(ctx, timeout)
+	timeout := time.Duration(wait * float64(time.Second))
+	if timeout > t.timeout {
+		timeout = t.timeout
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 	
-	responseCh := make(chan map[string]interface{}, 1)
-	errorCh := make(chan error, 1)
+	messages, dropped := buffer.take(waitCtx, int(maxMessages))
+	result := map[string]interface{}{
+		"messages": messages,
+	}
+	if id != "" {
+		result["subscription"] = id
+	}
+	if dropped > 0 {
+		result["dropped"] = dropped
+	}
+	return result, nil
+}
+
+func (t *WebSocketTool) newID(prefix string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	
-	go func() {
-		t.mu.RLock()
-		conn := t.conn
-		t.mu.RUnlock()
-		
-		if conn == nil {
-			errorCh <- fmt.Errorf("connection closed")
-			return
-		}
-		
-		var response map[string]interface{}
-		if err := conn.ReadJSON(&response); err != nil {
-			errorCh <- err
-			return
-		}
-		
-		responseCh <- response
-	}()
+	t.nextID++
+	return fmt.Sprintf("%s-%d-%d", prefix, time.Now().UnixNano(), t.nextID)
+}
+
+func (t *WebSocketTool) send(ctx context.Context, message map[string]interface{}) error {
+	if err := t.ensureConnected(ctx); err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
 	
-	select {
-	case <-responseCtx.Done():
-		return &Result{Error: "request timeout"}, nil
-	case err := <-errorCh:
-		return &Result{Error: fmt.Sprintf("failed to read response: %v", err)}, nil
-	case response := <-responseCh:
-		return &Result{
-			Data: response,
-			Metadata: map[string]interface{}{
-				"endpoint": t.config.Endpoint,
-				"tool":     t.config.Name,
-			},
-		}, nil
+	t.mu.Lock()
+	conn := t.conn
+	t.mu.Unlock()
+	if conn == nil {
+		return fmt.Errorf("failed to send message: connection closed")
 	}
+	
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	
+	conn.SetWriteDeadline(time.Now().Add(t.timeout))
+	if err := conn.WriteJSON(message); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	return nil
 }
 
 func (t *WebSocketTool) ensureConnected(ctx context.Context) error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return fmt.Errorf("tool is closed")
+	}
+	if t.conn != nil {
+		t.mu.Unlock()
+		return nil
+	}
+	t.mu.Unlock()
+	
+	conn, err := t.dial(ctx)
+	if err != nil {
+		return err
+	}
+	
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	
-	if t.conn != nil {
-		// Check if connection is still alive
-		if err := t.conn.WriteMessage(websocket.PingMessage, nil); err == nil {
-			return nil
+	if t.closed || t.conn != nil {
+		// Closed meanwhile, or another call connected first
+		conn.Close()
+		if t.closed {
+			return fmt.Errorf("tool is closed")
 		}
-		// Connection is dead, close and reconnect
-		t.conn.Close()
-		t.conn = nil
+		return nil
 	}
-	
+	t.attach(conn)
+	return nil
+}
+
+func (t *WebSocketTool) dial(ctx context.Context) (*websocket.Conn, error) {
 	headers := http.Header{}
 	
 	// Add authentication
@@ -129,29 +402,182 @@ func (t *WebSocketTool) ensureConnected(ctx context.Context) error {
 	
 	// Add custom headers from config
 	for key, value := range t.config.Config {
-		if key == "subprotocol" {
-			continue // Handle subprotocols separately
+		if websocketOptions[key] {
+			continue
 		}
-		headers.Set(key, value)
+		headers.Set(strings.TrimPrefix(key, "header_"), value)
 	}
 	
 	dialer := websocket.Dialer{
 		HandshakeTimeout: 10 * time.Second,
+		Subprotocols:     t.subprotocols,
 	}
 	
 	conn, _, err := dialer.DialContext(ctx, t.config.Endpoint, headers)
 	if err != nil {
-		return fmt.Errorf("failed to dial WebSocket: %w", err)
+		return nil, fmt.Errorf("failed to dial WebSocket: %w", err)
 	}
+	return conn, nil
+}
+
+// attach makes conn the tool's connection and starts its read pump and
+// keepalive. The caller holds t.mu.
+func (t *WebSocketTool) attach(conn *websocket.Conn) {
+	conn.SetReadLimit(t.maxMessageSize)
+	
+	// A connection that answers no ping within two intervals is dead
+	pongWait := 2 * t.pingInterval
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
 	
 	t.conn = conn
-	return nil
+	done := make(chan struct{})
+	go t.readPump(conn, done)
+	go t.keepAlive(conn, done)
+}
+
+func (t *WebSocketTool) readPump(conn *websocket.Conn, done chan struct{}) {
+	defer close(done)
+	
+	for {
+		messageType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.disconnect(conn, err)
+			return
+		}
+		// Any frame shows the connection is alive
+		conn.SetReadDeadline(time.Now().Add(2 * t.pingInterval))
+		
+		var message interface{}
+		if messageType == websocket.BinaryMessage {
+			message = map[string]interface{}{"binary": base64.StdEncoding.EncodeToString(data)}
+		} else if err := json.Unmarshal(data, &message); err != nil {
+			message = string(data)
+		}
+		t.route(message)
+	}
+}
+
+// route hands a frame to the request waiting for its id, or to the
+// subscription it names or was opened by, or keeps it as unsolicited.
+func (t *WebSocketTool) route(message interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if object, ok := message.(map[string]interface{}); ok {
+		id := messageField(object, t.idField)
+		if replyCh, ok := t.pending[id]; ok && id != "" {
+			replyCh <- websocketReply{message: message}
+			delete(t.pending, id)
+			return
+		}
+		for _, key := range []string{messageField(object, t.subscriptionField), id} {
+			if subscription, ok := t.subscriptions[key]; ok && key != "" {
+				subscription.buffer.add(message)
+				return
+			}
+		}
+	}
+	t.unsolicited.add(message)
+}
+
+func (t *WebSocketTool) keepAlive(conn *websocket.Conn, done chan struct{}) {
+	ticker := time.NewTicker(t.pingInterval)
+	defer ticker.Stop()
+	
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+				// The read pump notices the failure and disconnects
+				conn.Close()
+				return
+			}
+		}
+	}
+}
+
+// disconnect drops a failed connection, failing the requests waiting on
+// it, and reconnects in the background while there are subscriptions.
+func (t *WebSocketTool) disconnect(conn *websocket.Conn, cause error) {
+	conn.Close()
+	
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	
+	if t.conn != conn {
+		return
+	}
+	t.conn = nil
+	for id, replyCh := range t.pending {
+		replyCh <- websocketReply{err: cause}
+		delete(t.pending, id)
+	}
+	
+	if t.reconnect && !t.closed && !t.reconnecting && len(t.subscriptions) > 0 {
+		t.reconnecting = true
+		go t.reconnectLoop()
+	}
+}
+
+// reconnectLoop dials until it succeeds, waiting twice as long after each
+// failure, then opens every subscription again.
+func (t *WebSocketTool) reconnectLoop() {
+	defer func() {
+		t.mu.Lock()
+		t.reconnecting = false
+		t.mu.Unlock()
+	}()
+	
+	backoff := 500 * time.Millisecond
+	for {
+		t.mu.Lock()
+		stop := t.closed || len(t.subscriptions) == 0
+		t.mu.Unlock()
+		if stop {
+			return
+		}
+		
+		ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+		err := t.ensureConnected(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+		
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxWebSocketBackoff {
+			backoff = maxWebSocketBackoff
+		}
+	}
+	
+	t.mu.Lock()
+	var messages []map[string]interface{}
+	for _, subscription := range t.subscriptions {
+		messages = append(messages, subscription.message)
+	}
+	t.mu.Unlock()
+	
+	for _, message := range messages {
+		ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+		t.send(ctx, message)
+		cancel()
+	}
 }
 
 func (t *WebSocketTool) Close() error {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 	
+	t.closed = true
+	for id, replyCh := range t.pending {
+		replyCh <- websocketReply{err: fmt.Errorf("tool is closed")}
+		delete(t.pending, id)
+	}
 	if t.conn != nil {
 		err := t.conn.Close()
 		t.conn = nil
@@ -159,4 +585,81 @@ func (t *WebSocketTool) Close() error {
 	}
 	
 	return nil
+}
+
+// messageField reads a correlation field, which servers send as a string
+// or a number.
+func messageField(message map[string]interface{}, field string) string {
+	switch value := message[field].(type) {
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return ""
+}
+
+func withoutKeys(args map[string]interface{}, keys ...string) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(args))
+	for key, value := range args {
+		filtered[key] = value
+	}
+	for _, key := range keys {
+		delete(filtered, key)
+	}
+	return filtered
+}
+
+// messageBuffer keeps the most recent messages up to a limit, counting the
+// older ones it had to drop.
+type messageBuffer struct {
+	mu       sync.Mutex
+	messages []interface{}
+	limit    int
+	dropped  int
+	// arrived is closed, and replaced, when a message is added
+	arrived chan struct{}
+}
+
+func newMessageBuffer(limit int) *messageBuffer {
+	return &messageBuffer{limit: limit, arrived: make(chan struct{})}
+}
+
+func (b *messageBuffer) add(message interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	
+	if len(b.messages) >= b.limit {
+		b.messages = b.messages[1:]
+		b.dropped++
+	}
+	b.messages = append(b.messages, message)
+	close(b.arrived)
+	b.arrived = make(chan struct{})
+}
+
+// take removes up to max messages, waiting until ctx is done for one to
+// arrive if there are none, and reports how many were dropped since the
+// last take.
+func (b *messageBuffer) take(ctx context.Context, max int) ([]interface{}, int) {
+	b.mu.Lock()
+	if len(b.messages) == 0 {
+		arrived := b.arrived
+		b.mu.Unlock()
+		select {
+		case <-arrived:
+		case <-ctx.Done():
+		}
+		b.mu.Lock()
+	}
+	defer b.mu.Unlock()
+	
+	if max <= 0 || max > len(b.messages) {
+		max = len(b.messages)
+	}
+	messages := append([]interface{}{}, b.messages[:max]...)
+	b.messages = b.messages[max:]
+	dropped := b.dropped
+	b.dropped = 0
+	return messages, dropped
 }
\ No newline at end of file
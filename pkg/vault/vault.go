@@ -0,0 +1,290 @@
+// Package vault keeps provider API keys that tenants bring themselves. Each
+// namespace has its own data key, which is stored wrapped by the server's
+// master key; credentials are sealed with their namespace's data key.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("credential not found")
+
+// Credential is a tenant's API keys for one provider.
+type Credential struct {
+	Provider      string    `json:"provider"`
+	APIKey        string    `json:"api_key"`
+	BackupAPIKeys []string  `json:"backup_api_keys,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// APIKeys returns the primary key followed by the backups.
+func (c *Credential) APIKeys() []string {
+	return append([]string{c.APIKey}, c.BackupAPIKeys...)
+}
+
+type namespaceRecord struct {
+	// DataKey is the namespace's key, sealed with the master key
+	DataKey     []byte            `json:"data_key"`
+	Credentials map[string][]byte `json:"credentials"`
+}
+
+// Vault holds the sealed credentials of every namespace. When path is set
+// the vault is persisted there after every change; otherwise it lives in
+// memory only.
+type Vault struct {
+	master     cipher.AEAD
+	path       string
+	namespaces map[string]*namespaceRecord
+	mu         sync.RWMutex
+}
+
+// New opens a vault sealed by masterKey, a base64 encoded 32 byte key.
+func New(masterKey, path string) (*Vault, error) {
+	master, err := newAEAD(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	
+	v := &Vault{
+		master:     master,
+		path:       path,
+		namespaces: make(map[string]*namespaceRecord),
+	}
+	
+	if path != "" {
+		if err := v.load(); err != nil {
+			return nil, err
+		}
+	}
+	
+	return v, nil
+}
+
+// ValidateMasterKey reports whether key is usable as a master key.
+func ValidateMasterKey(key string) error {
+	_, err := newAEAD(key)
+	return err
+}
+
+func newAEAD(encodedKey string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil {
+		return nil, fmt.Errorf("master key must be base64 encoded: %w", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("master key must be 32 bytes, got %d", len(key))
+	}
+	return aeadFromKey(key)
+}
+
+func aeadFromKey(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Put stores or replaces a namespace's credential for a provider.
+func (v *Vault) Put(namespace string, credential *Credential) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	
+	record, ok := v.namespaces[namespace]
+	if !ok {
+		dataKey := make([]byte, 32)
+		if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+			return fmt.Errorf("failed to generate data key: %w", err)
+		}
+		sealedKey, err := seal(v.master, dataKey, []byte(namespace))
+		if err != nil {
+			return err
+		}
+		record = &namespaceRecord{
+			DataKey:     sealedKey,
+			Credentials: make(map[string][]byte),
+		}
+	}
+	
+	aead, err := v.namespaceAEAD(namespace, record)
+	if err != nil {
+		return err
+	}
+	
+	stored := *credential
+	stored.UpdatedAt = time.Now().UTC()
+	plaintext, err := json.Marshal(&stored)
+	if err != nil {
+		return err
+	}
+	sealed, err := seal(aead, plaintext, credentialAD(namespace, credential.Provider))
+	if err != nil {
+		return err
+	}
+	
+	record.Credentials[credential.Provider] = sealed
+	v.namespaces[namespace] = record
+	
+	return v.save()
+}
+
+// Get returns a namespace's credential for a provider.
+func (v *Vault) Get(namespace, provider string) (*Credential, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	
+	record, ok := v.namespaces[namespace]
+	if !ok || record.Credentials[provider] == nil {
+		return nil, fmt.Errorf("%w: %s in namespace %s", ErrNotFound, provider, namespace)
+	}
+	
+	aead, err := v.namespaceAEAD(namespace, record)
+	if err != nil {
+		return nil, err
+	}
+	
+	plaintext, err := open(aead, record.Credentials[provider], credentialAD(namespace, provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential: %w", err)
+	}
+	
+	var credential Credential
+	if err := json.Unmarshal(plaintext, &credential); err != nil {
+		return nil, fmt.Errorf("failed to decode credential: %w", err)
+	}
+	return &credential, nil
+}
+
+// Delete removes a namespace's credential for a provider. The namespace's
+// data key is discarded along with its last credential.
+func (v *Vault) Delete(namespace, provider string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	
+	record, ok := v.namespaces[namespace]
+	if !ok || record.Credentials[provider] == nil {
+		return fmt.Errorf("%w: %s in namespace %s", ErrNotFound, provider, namespace)
+	}
+	
+	delete(record.Credentials, provider)
+	if len(record.Credentials) == 0 {
+		delete(v.namespaces, namespace)
+	}
+	
+	return v.save()
+}
+
+// Providers lists the providers a namespace has credentials for.
+func (v *Vault) Providers(namespace string) []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	
+	record, ok := v.namespaces[namespace]
+	if !ok {
+		return []string{}
+	}
+	
+	names := make([]string, 0, len(record.Credentials))
+	for name := range record.Credentials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (v *Vault) namespaceAEAD(namespace string, record *namespaceRecord) (cipher.AEAD, error) {
+	dataKey, err := open(v.master, record.DataKey, []byte(namespace))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key for namespace %s: %w", namespace, err)
+	}
+	return aeadFromKey(dataKey)
+}
+
+// credentialAD binds a sealed credential to its namespace and provider so
+// it cannot be moved to another tenant in the vault file.
+func credentialAD(namespace, provider string) []byte {
+	return []byte(namespace + "/" + provider)
+}
+
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func (v *Vault) load() error {
+	data, err := os.ReadFile(v.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read vault: %w", err)
+	}
+	
+	if err := json.Unmarshal(data, &v.namespaces); err != nil {
+		return fmt.Errorf("failed to parse vault: %w", err)
+	}
+	
+	// Fail at startup rather than on a tenant's first request if the
+	// vault was sealed with a different master key
+	for namespace, record := range v.namespaces {
+		if _, err := v.namespaceAEAD(namespace, record); err != nil {
+			return err
+		}
+	}
+	
+	return nil
+}
+
+// save writes the vault atomically. The caller must hold the write lock.
+func (v *Vault) save() error {
+	if v.path == "" {
+		return nil
+	}
+	
+	data, err := json.Marshal(v.namespaces)
+	if err != nil {
+		return fmt.Errorf("failed to encode vault: %w", err)
+	}
+	
+	tmp, err := os.CreateTemp(filepath.Dir(v.path), ".vault-*")
+	if err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), v.path); err != nil {
+		return fmt.Errorf("failed to write vault: %w", err)
+	}
+	
+	return nil
+}
\ No newline at end of file